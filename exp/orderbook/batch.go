@@ -92,23 +92,32 @@ func (tx *orderBookBatchedUpdates) apply(ledger uint32) error {
 	for _, operation := range tx.operations {
 		switch operation.operationType {
 		case addOfferOperationType:
+			// an update may change the assets traded by the offer
+			var previous *tradingPair
+			if pair, ok := tx.orderbook.tradingPairForOffer[operation.offerID]; ok {
+				previous = &pair
+			}
 			if err := tx.orderbook.addOffer(*operation.offer); err != nil {
 				panic(errors.Wrap(err, "could not apply update in batch"))
 			}
+			tx.orderbook.touchOffer(operation.offerID, previous, ledger)
 		case removeOfferOperationType:
 			if pair, ok := tx.orderbook.tradingPairForOffer[operation.offerID]; !ok {
 				continue
 			} else {
 				reallocatePairs[pair] = struct{}{}
 			}
+			tx.orderbook.touchOffer(operation.offerID, nil, ledger)
 			if err := tx.orderbook.removeOffer(operation.offerID); err != nil {
 				panic(errors.Wrap(err, "could not apply update in batch"))
 			}
 
 		case addLiquidityPoolOperationType:
 			tx.orderbook.addPool(*operation.liquidityPool)
+			tx.orderbook.touchPool(*operation.liquidityPool, ledger)
 
 		case removeLiquidityPoolOperationType:
+			tx.orderbook.touchPool(*operation.liquidityPool, ledger)
 			tx.orderbook.removePool(*operation.liquidityPool)
 
 		default:
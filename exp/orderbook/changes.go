@@ -0,0 +1,196 @@
+package orderbook
+
+import (
+	"github.com/hcnet/go/xdr"
+)
+
+// touchOffer records that the assets of the given offer, if it's present in
+// the graph, and the assets of the previous version of the offer, if it was
+// updated, were updated in the given ledger.
+func (graph *OrderBookGraph) touchOffer(offerID xdr.Int64, previous *tradingPair, ledger uint32) {
+	if pair, ok := graph.tradingPairForOffer[offerID]; ok {
+		graph.assetModifiedAt[pair.buyingAsset] = ledger
+		graph.assetModifiedAt[pair.sellingAsset] = ledger
+	}
+	if previous != nil {
+		graph.assetModifiedAt[previous.buyingAsset] = ledger
+		graph.assetModifiedAt[previous.sellingAsset] = ledger
+	}
+}
+
+// touchPool records that the assets of the given liquidity pool, if they are
+// present in the graph, were updated in the given ledger.
+func (graph *OrderBookGraph) touchPool(pool xdr.LiquidityPoolEntry, ledger uint32) {
+	assetA, assetB := getPoolAssets(pool)
+	for _, asset := range []xdr.Asset{assetA, assetB} {
+		if id, ok := graph.assetStringToID[asset.String()]; ok {
+			graph.assetModifiedAt[id] = ledger
+		}
+	}
+}
+
+// PathsChangedSince returns false if none of the offers and liquidity pools
+// which can be part of the payment paths from `sourceAssets` to
+// `destinationAsset`, as found by FindPathsWithConstraints with the same
+// parameters, were updated after the given ledger. In that case, searching
+// the payment paths again returns the same result.
+func (graph *OrderBookGraph) PathsChangedSince(
+	ledger uint32,
+	maxPathLength int,
+	destinationAsset xdr.Asset,
+	sourceAssets []xdr.Asset,
+	includePools bool,
+	constraints PathConstraints,
+) bool {
+	graph.lock.RLock()
+	defer graph.lock.RUnlock()
+
+	return graph.venuesChangedSince(
+		ledger, maxPathLength, destinationAsset, sourceAssets, includePools, constraints,
+		graph.venuesForSellingAsset, graph.venuesForBuyingAsset,
+	)
+}
+
+// FixedPathsChangedSince returns false if none of the offers and liquidity
+// pools which can be part of the payment paths from `sourceAsset` to
+// `destinationAssets`, as found by FindFixedPathsWithConstraints with the same
+// parameters, were updated after the given ledger. In that case, searching
+// the payment paths again returns the same result.
+func (graph *OrderBookGraph) FixedPathsChangedSince(
+	ledger uint32,
+	maxPathLength int,
+	sourceAsset xdr.Asset,
+	destinationAssets []xdr.Asset,
+	includePools bool,
+	constraints PathConstraints,
+) bool {
+	graph.lock.RLock()
+	defer graph.lock.RUnlock()
+
+	return graph.venuesChangedSince(
+		ledger, maxPathLength, sourceAsset, destinationAssets, includePools, constraints,
+		graph.venuesForBuyingAsset, graph.venuesForSellingAsset,
+	)
+}
+
+// venuesChangedSince returns true if a venue updated after the given ledger
+// can be part of a payment path of at most maxPathLength hops from the start
+// asset to one of the target assets. The search trades from an asset using
+// `venues`, `reverseVenues` are the same venues indexed by the asset they
+// trade to.
+//
+// Any update of a venue marks both of its assets. If a path used, or now
+// uses, updated venues, the first one trades from an updated asset reachable
+// from the start asset and the last one trades to an updated asset from which
+// a target asset is reachable, in less than maxPathLength hops in total. The
+// walks from both ends don't explore the assets and venues excluded by the
+// constraints.
+func (graph *OrderBookGraph) venuesChangedSince(
+	ledger uint32,
+	maxPathLength int,
+	startAsset xdr.Asset,
+	targetAssets []xdr.Asset,
+	includePools bool,
+	constraints PathConstraints,
+	venues []edgeSet,
+	reverseVenues []edgeSet,
+) bool {
+	if ledger == 0 || graph.lastLedger < ledger {
+		// the graph was reset since the given ledger
+		return true
+	}
+	if graph.lastLedger == ledger || constraints.excludesAsset(startAsset) {
+		return false
+	}
+
+	// the assets may have been removed from the graph, searching for paths
+	// is cheap in that case
+	start, ok := graph.assetStringToID[startAsset.String()]
+	if !ok {
+		return true
+	}
+	endpoints := map[int32]bool{start: true}
+	var targets []int32
+	for _, asset := range targetAssets {
+		if constraints.excludesAsset(asset) {
+			continue
+		}
+		if id, ok := graph.assetStringToID[asset.String()]; ok && !endpoints[id] {
+			endpoints[id] = true
+			targets = append(targets, id)
+		}
+	}
+	if len(targets) == 0 {
+		return true
+	}
+
+	walk := changeWalk{
+		graph:        graph,
+		ledger:       ledger,
+		constraints:  graph.resolveConstraints(constraints),
+		includePools: includePools,
+		endpoints:    endpoints,
+	}
+	fromStart := walk.hopsToUpdatedAsset([]int32{start}, venues, maxPathLength-1)
+	if fromStart < 0 {
+		return false
+	}
+	toTarget := walk.hopsToUpdatedAsset(targets, reverseVenues, maxPathLength-1-fromStart)
+	return toTarget >= 0
+}
+
+// changeWalk walks the graph like the path finding search does.
+type changeWalk struct {
+	graph        *OrderBookGraph
+	ledger       uint32
+	constraints  searchConstraints
+	includePools bool
+	// endpoints are the start and target assets, which are explored even
+	// if they are not in the included assets.
+	endpoints map[int32]bool
+}
+
+// hopsToUpdatedAsset returns the least number of hops, at most maxHops, from
+// the given assets to an asset updated after the ledger of the walk, or -1 if
+// there isn't any.
+func (walk changeWalk) hopsToUpdatedAsset(from []int32, venues []edgeSet, maxHops int) int {
+	visited := map[int32]bool{}
+	for _, asset := range from {
+		visited[asset] = true
+	}
+	frontier := from
+	for hops := 0; hops <= maxHops && len(frontier) > 0; hops++ {
+		var next []int32
+		for _, asset := range frontier {
+			if walk.graph.assetModifiedAt[asset] > walk.ledger {
+				return hops
+			}
+			for _, e := range venues[asset] {
+				if !visited[e.key] && walk.allowsAsset(e.key) && walk.allowsVenues(e.value) {
+					visited[e.key] = true
+					next = append(next, e.key)
+				}
+			}
+		}
+		frontier = next
+	}
+	return -1
+}
+
+func (walk changeWalk) allowsAsset(asset int32) bool {
+	if walk.constraints.excludedAssets[asset] {
+		return false
+	}
+	return walk.constraints.includedAssets == nil ||
+		walk.constraints.includedAssets[asset] ||
+		walk.endpoints[asset]
+}
+
+func (walk changeWalk) allowsVenues(venues Venues) bool {
+	if len(venues.offers) > 0 && !walk.constraints.excludeOffers {
+		return true
+	}
+	pool := venues.pool
+	return walk.includePools && pool.Body.ConstantProduct != nil &&
+		!walk.constraints.excludedPools[pool.LiquidityPoolId]
+}
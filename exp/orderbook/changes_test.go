@@ -0,0 +1,134 @@
+package orderbook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hcnet/go/xdr"
+)
+
+func TestFixedPathsChangedSince(t *testing.T) {
+	graph := NewOrderBookGraph()
+	graph.AddLiquidityPools(
+		makePool(usdAsset, eurAsset, 1000, 1000),
+		makePool(eurAsset, chfAsset, 1000, 1000),
+		makePool(chfAsset, yenAsset, 1000, 1000),
+	)
+	require.NoError(t, graph.Apply(1))
+	changedSince := func(ledger uint32, maxPathLength int, sourceAsset xdr.Asset, destinationAsset xdr.Asset) bool {
+		return graph.FixedPathsChangedSince(
+			ledger, maxPathLength, sourceAsset, []xdr.Asset{destinationAsset}, true, PathConstraints{},
+		)
+	}
+
+	assert.True(t, changedSince(0, 3, usdAsset, eurAsset))
+	assert.False(t, changedSince(1, 3, usdAsset, eurAsset))
+
+	graph.AddLiquidityPools(makePool(chfAsset, yenAsset, 2000, 500))
+	require.NoError(t, graph.Apply(2))
+
+	// the CHF/YEN pool is reachable from USD but it can't be part of a path
+	// to EUR
+	assert.False(t, changedSince(1, 3, usdAsset, eurAsset))
+	assert.True(t, changedSince(1, 3, usdAsset, yenAsset))
+	assert.False(t, changedSince(1, 2, usdAsset, yenAsset))
+	assert.True(t, changedSince(1, 1, chfAsset, yenAsset))
+	assert.False(t, changedSince(2, 3, usdAsset, yenAsset))
+
+	graph.AddLiquidityPools(makePool(eurAsset, chfAsset, 2000, 500))
+	require.NoError(t, graph.Apply(3))
+
+	assert.True(t, changedSince(2, 3, usdAsset, yenAsset))
+	for _, constraints := range []PathConstraints{
+		{ExcludedAssets: []xdr.Asset{chfAsset}},
+		{IncludedAssets: []xdr.Asset{eurAsset}},
+	} {
+		assert.False(t, graph.FixedPathsChangedSince(
+			2, 3, usdAsset, []xdr.Asset{yenAsset}, true, constraints,
+		), constraints)
+	}
+	// only the liquidity pools were updated
+	assert.False(t, graph.FixedPathsChangedSince(
+		2, 3, usdAsset, []xdr.Asset{yenAsset}, false, PathConstraints{},
+	))
+
+	// updating an offer to trade other assets changes the paths of both
+	// trading pairs
+	graph.AddOffers(eurOffer)
+	require.NoError(t, graph.Apply(4))
+	assert.True(t, changedSince(3, 1, eurAsset, nativeAsset))
+	assert.False(t, changedSince(4, 1, eurAsset, nativeAsset))
+
+	chfOffer := eurOffer
+	chfOffer.Buying = chfAsset
+	graph.AddOffers(chfOffer)
+	require.NoError(t, graph.Apply(5))
+	assert.True(t, changedSince(4, 1, eurAsset, nativeAsset))
+	assert.True(t, changedSince(4, 1, chfAsset, nativeAsset))
+	assert.False(t, changedSince(4, 1, usdAsset, eurAsset))
+
+	// the paths are searched again when the assets are not in the graph
+	assert.True(t, changedSince(4, 1, usdAsset, xdr.MustNewCreditAsset("GBP", issuer.Address())))
+	graph.Clear()
+	graph.AddLiquidityPools(makePool(usdAsset, eurAsset, 1000, 1000))
+	require.NoError(t, graph.Apply(2))
+	assert.True(t, changedSince(4, 3, usdAsset, eurAsset))
+	assert.True(t, changedSince(1, 3, usdAsset, eurAsset))
+	assert.False(t, changedSince(2, 3, usdAsset, eurAsset))
+}
+
+func TestPathsChangedSince(t *testing.T) {
+	graph := NewOrderBookGraph()
+	graph.AddLiquidityPools(
+		makePool(usdAsset, eurAsset, 1000, 1000),
+		makePool(eurAsset, chfAsset, 1000, 1000),
+		makePool(chfAsset, yenAsset, 1000, 1000),
+	)
+	require.NoError(t, graph.Apply(1))
+	changedSince := func(ledger uint32, maxPathLength int, destinationAsset xdr.Asset, sourceAssets ...xdr.Asset) bool {
+		return graph.PathsChangedSince(
+			ledger, maxPathLength, destinationAsset, sourceAssets, true, PathConstraints{},
+		)
+	}
+
+	assert.True(t, changedSince(0, 3, eurAsset, usdAsset))
+	assert.False(t, changedSince(1, 3, eurAsset, usdAsset))
+
+	graph.AddLiquidityPools(makePool(chfAsset, yenAsset, 2000, 500))
+	require.NoError(t, graph.Apply(2))
+
+	// the CHF/YEN pool is reachable from EUR but it can't be part of a path
+	// from USD
+	assert.False(t, changedSince(1, 3, eurAsset, usdAsset))
+	assert.True(t, changedSince(1, 3, eurAsset, usdAsset, yenAsset))
+	assert.True(t, changedSince(1, 3, usdAsset, yenAsset))
+	assert.False(t, changedSince(1, 2, usdAsset, yenAsset))
+	assert.False(t, graph.PathsChangedSince(
+		1, 3, usdAsset, []xdr.Asset{yenAsset}, true, PathConstraints{IncludedAssets: []xdr.Asset{eurAsset}},
+	))
+	assert.False(t, graph.PathsChangedSince(
+		1, 3, usdAsset, []xdr.Asset{yenAsset}, true, PathConstraints{ExcludedAssets: []xdr.Asset{chfAsset}},
+	))
+	assert.False(t, graph.PathsChangedSince(
+		1, 3, usdAsset, []xdr.Asset{yenAsset}, false, PathConstraints{},
+	))
+
+	// eurOffer sells XLM for EUR
+	graph.AddOffers(eurOffer)
+	require.NoError(t, graph.Apply(3))
+
+	assert.True(t, changedSince(2, 1, nativeAsset, eurAsset))
+	assert.True(t, changedSince(2, 2, nativeAsset, usdAsset))
+	assert.False(t, changedSince(2, 1, nativeAsset, usdAsset))
+	assert.False(t, changedSince(2, 3, yenAsset, chfAsset))
+
+	graph.RemoveOffer(eurOffer.OfferId)
+	require.NoError(t, graph.Apply(4))
+
+	assert.False(t, changedSince(3, 1, usdAsset, eurAsset))
+	// XLM is not in the graph anymore
+	assert.True(t, changedSince(3, 1, nativeAsset, eurAsset))
+	assert.False(t, changedSince(3, 3, yenAsset, chfAsset))
+}
@@ -0,0 +1,129 @@
+package orderbook
+
+import (
+	"github.com/hcnet/go/xdr"
+)
+
+// PathConstraints restricts the assets and venues explored by the path
+// finding search. The zero value doesn't restrict the search.
+type PathConstraints struct {
+	// ExcludedAssets are never part of a payment path, not even as source or
+	// destination asset.
+	ExcludedAssets []xdr.Asset
+	// IncludedAssets, if not empty, are the only assets which can be interior
+	// nodes of a payment path.
+	IncludedAssets []xdr.Asset
+	// ExcludedPools are never traded with.
+	ExcludedPools []xdr.PoolId
+	// ExcludeOffers restricts the search to liquidity pools.
+	ExcludeOffers bool
+}
+
+func (constraints PathConstraints) empty() bool {
+	return len(constraints.ExcludedAssets) == 0 &&
+		len(constraints.IncludedAssets) == 0 &&
+		len(constraints.ExcludedPools) == 0 &&
+		!constraints.ExcludeOffers
+}
+
+// searchConstraints are PathConstraints resolved against the assets of the
+// order book graph.
+type searchConstraints struct {
+	excludedAssets map[int32]bool
+	// includedAssets is nil when any asset can be an interior node.
+	includedAssets map[int32]bool
+	excludedPools  map[xdr.PoolId]bool
+	excludeOffers  bool
+}
+
+// resolveConstraints must be called with the graph lock held.
+func (graph *OrderBookGraph) resolveConstraints(constraints PathConstraints) searchConstraints {
+	resolved := searchConstraints{
+		excludedAssets: map[int32]bool{},
+		excludedPools:  map[xdr.PoolId]bool{},
+		excludeOffers:  constraints.ExcludeOffers,
+	}
+	for _, asset := range constraints.ExcludedAssets {
+		if id, ok := graph.assetStringToID[asset.String()]; ok {
+			resolved.excludedAssets[id] = true
+		}
+	}
+	if len(constraints.IncludedAssets) > 0 {
+		resolved.includedAssets = map[int32]bool{}
+		for _, asset := range constraints.IncludedAssets {
+			if id, ok := graph.assetStringToID[asset.String()]; ok {
+				resolved.includedAssets[id] = true
+			}
+		}
+	}
+	for _, poolID := range constraints.ExcludedPools {
+		resolved.excludedPools[poolID] = true
+	}
+	return resolved
+}
+
+// constrainedSearchState is a searchState which never explores the venues
+// excluded by the constraints.
+type constrainedSearchState struct {
+	searchState
+	constraints searchConstraints
+	// venuesCache holds the venues of the assets visited so far.
+	venuesCache map[int32]edgeSet
+}
+
+// constrain wraps the given state so that the search respects the
+// constraints. The state is returned as is when there are no constraints.
+func (graph *OrderBookGraph) constrain(state searchState, constraints PathConstraints) searchState {
+	if constraints.empty() {
+		return state
+	}
+	return &constrainedSearchState{
+		searchState: state,
+		constraints: graph.resolveConstraints(constraints),
+		venuesCache: map[int32]edgeSet{},
+	}
+}
+
+func (state *constrainedSearchState) venues(currentAsset int32) edgeSet {
+	if cached, ok := state.venuesCache[currentAsset]; ok {
+		return cached
+	}
+
+	edges := state.searchState.venues(currentAsset)
+	allowed := make(edgeSet, 0, len(edges))
+	for _, e := range edges {
+		if state.constraints.excludedAssets[e.key] {
+			continue
+		}
+		if state.constraints.includedAssets != nil &&
+			!state.constraints.includedAssets[e.key] &&
+			!state.isTerminalNode(e.key) {
+			continue
+		}
+
+		venues := e.value
+		if state.constraints.excludeOffers {
+			venues.offers = nil
+		}
+		if pool := venues.pool; pool.Body.ConstantProduct != nil &&
+			state.constraints.excludedPools[pool.LiquidityPoolId] {
+			venues.pool = liquidityPool{}
+		}
+		if len(venues.offers) == 0 && venues.pool.Body.ConstantProduct == nil {
+			continue
+		}
+		allowed = append(allowed, edge{key: e.key, value: venues})
+	}
+	state.venuesCache[currentAsset] = allowed
+	return allowed
+}
+
+// excludesAsset returns true if the constraints exclude the given asset.
+func (constraints PathConstraints) excludesAsset(asset xdr.Asset) bool {
+	for _, excluded := range constraints.ExcludedAssets {
+		if excluded.Equals(asset) {
+			return true
+		}
+	}
+	return false
+}
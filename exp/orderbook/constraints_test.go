@@ -0,0 +1,103 @@
+package orderbook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hcnet/go/xdr"
+)
+
+func TestPathConstraints(t *testing.T) {
+	// the USD/EUR pool is small enough for the path through CHF to deliver
+	// more EUR
+	usdEurPool := makePool(usdAsset, eurAsset, 100, 100)
+	graph := NewOrderBookGraph()
+	graph.AddLiquidityPools(
+		usdEurPool,
+		makePool(usdAsset, chfAsset, 1000, 1000),
+		makePool(chfAsset, eurAsset, 1000, 1000),
+	)
+	graph.AddOffers(eurOffer)
+	require.NoError(t, graph.Apply(1))
+
+	interiorNodes := func(paths []Path) [][]string {
+		result := [][]string{}
+		for _, path := range paths {
+			result = append(result, path.InteriorNodes)
+		}
+		return result
+	}
+	findFixedPaths := func(constraints PathConstraints) []Path {
+		paths, _, err := graph.FindFixedPathsWithConstraints(
+			context.TODO(), 3, usdAsset, 100, []xdr.Asset{eurAsset}, 5, true, constraints,
+		)
+		require.NoError(t, err)
+		return paths
+	}
+
+	assert.ElementsMatch(t, [][]string{
+		{},
+		{chfAsset.String()},
+	}, interiorNodes(findFixedPaths(PathConstraints{})))
+
+	assert.Equal(t, [][]string{{}}, interiorNodes(findFixedPaths(PathConstraints{
+		ExcludedAssets: []xdr.Asset{chfAsset},
+	})))
+	assert.Equal(t, [][]string{{}}, interiorNodes(findFixedPaths(PathConstraints{
+		IncludedAssets: []xdr.Asset{yenAsset},
+	})))
+	assert.ElementsMatch(t, [][]string{
+		{},
+		{chfAsset.String()},
+	}, interiorNodes(findFixedPaths(PathConstraints{
+		IncludedAssets: []xdr.Asset{chfAsset},
+	})))
+	assert.Equal(t, [][]string{{chfAsset.String()}}, interiorNodes(findFixedPaths(PathConstraints{
+		ExcludedPools: []xdr.PoolId{usdEurPool.LiquidityPoolId},
+	})))
+	assert.Empty(t, findFixedPaths(PathConstraints{
+		ExcludedAssets: []xdr.Asset{eurAsset},
+	}))
+
+	// eurOffer is the only venue trading EUR for XLM
+	paths, _, err := graph.FindPathsWithConstraints(
+		context.TODO(), 3, nativeAsset, 100, nil,
+		[]xdr.Asset{eurAsset, usdAsset}, []xdr.Int64{0, 0}, false, 5, true, PathConstraints{},
+	)
+	require.NoError(t, err)
+	assert.Len(t, paths, 2)
+
+	paths, _, err = graph.FindPathsWithConstraints(
+		context.TODO(), 3, nativeAsset, 100, nil,
+		[]xdr.Asset{eurAsset, usdAsset}, []xdr.Int64{0, 0}, false, 5, true, PathConstraints{
+			ExcludedAssets: []xdr.Asset{usdAsset},
+		},
+	)
+	require.NoError(t, err)
+	if assert.Len(t, paths, 1) {
+		assert.Equal(t, eurAsset.String(), paths[0].SourceAsset)
+	}
+
+	paths, _, err = graph.FindPathsWithConstraints(
+		context.TODO(), 3, nativeAsset, 100, nil,
+		[]xdr.Asset{eurAsset, usdAsset}, []xdr.Int64{0, 0}, false, 5, true, PathConstraints{
+			ExcludeOffers: true,
+		},
+	)
+	require.NoError(t, err)
+	assert.Empty(t, paths)
+
+	// the parts of split payments respect the constraints too
+	splitPaths, _, err := graph.FindFixedSplitPaths(
+		context.TODO(), 3, usdAsset, 500, []xdr.Asset{eurAsset}, true, PathConstraints{
+			ExcludedAssets: []xdr.Asset{chfAsset},
+		},
+	)
+	require.NoError(t, err)
+	if assert.Len(t, splitPaths, 1) {
+		assert.Equal(t, [][]string{{}}, interiorNodes(splitPaths[0].Paths))
+	}
+}
@@ -71,6 +71,9 @@ type OrderBookGraph struct {
 	// venuesForSellingAsset maps an asset to all of its *selling* opportunities,
 	// which may be offers (sorted by price) or a liquidity pools.
 	venuesForSellingAsset []edgeSet
+	// assetModifiedAt maps an asset id to the last ledger in which an offer or
+	// a liquidity pool trading the asset was updated.
+	assetModifiedAt []uint32
 	// liquidityPools associates a particular asset pair (in "asset order", see
 	// xdr.Asset.LessThan) with a liquidity pool.
 	liquidityPools map[tradingPair]xdr.LiquidityPoolEntry
@@ -514,6 +517,7 @@ func (graph *OrderBookGraph) Clear() {
 	graph.vacantIDs = []int32{}
 	graph.venuesForSellingAsset = []edgeSet{}
 	graph.venuesForBuyingAsset = []edgeSet{}
+	graph.assetModifiedAt = []uint32{}
 	graph.tradingPairForOffer = map[xdr.Int64]tradingPair{}
 	graph.liquidityPools = map[tradingPair]xdr.LiquidityPoolEntry{}
 	graph.batchedUpdates = graph.batch()
@@ -550,6 +554,7 @@ func (graph *OrderBookGraph) getOrCreateAssetID(asset xdr.Asset) int32 {
 		graph.idToAssetString = append(graph.idToAssetString, assetString)
 		graph.venuesForBuyingAsset = append(graph.venuesForBuyingAsset, nil)
 		graph.venuesForSellingAsset = append(graph.venuesForSellingAsset, nil)
+		graph.assetModifiedAt = append(graph.assetModifiedAt, 0)
 	}
 
 	graph.assetStringToID[assetString] = id
@@ -692,10 +697,31 @@ func (graph *OrderBookGraph) FindPaths(
 	validateSourceBalance bool,
 	maxAssetsPerPath int,
 	includePools bool,
+) ([]Path, uint32, error) {
+	return graph.FindPathsWithConstraints(
+		ctx, maxPathLength, destinationAsset, destinationAmount, sourceAccountID, sourceAssets, sourceAssetBalances,
+		validateSourceBalance, maxAssetsPerPath, includePools, PathConstraints{},
+	)
+}
+
+// FindPathsWithConstraints is FindPaths with a search which doesn't explore
+// the assets and venues excluded by `constraints`.
+func (graph *OrderBookGraph) FindPathsWithConstraints(
+	ctx context.Context,
+	maxPathLength int,
+	destinationAsset xdr.Asset,
+	destinationAmount xdr.Int64,
+	sourceAccountID *xdr.AccountId,
+	sourceAssets []xdr.Asset,
+	sourceAssetBalances []xdr.Int64,
+	validateSourceBalance bool,
+	maxAssetsPerPath int,
+	includePools bool,
+	constraints PathConstraints,
 ) ([]Path, uint32, error) {
 	paths, lastLedger, err := graph.findPathsWithLock(
 		ctx, maxPathLength, destinationAsset, destinationAmount, sourceAccountID, sourceAssets, sourceAssetBalances,
-		validateSourceBalance, includePools,
+		validateSourceBalance, includePools, constraints,
 	)
 	if err != nil {
 		return nil, lastLedger, errors.Wrap(err, "could not determine paths")
@@ -719,13 +745,20 @@ func (graph *OrderBookGraph) findPathsWithLock(
 	sourceAssetBalances []xdr.Int64,
 	validateSourceBalance bool,
 	includePools bool,
+	constraints PathConstraints,
 ) ([]Path, uint32, error) {
 	graph.lock.RLock()
 	defer graph.lock.RUnlock()
 
+	if constraints.excludesAsset(destinationAsset) {
+		return []Path{}, graph.lastLedger, nil
+	}
 	destinationAssetString := destinationAsset.String()
 	sourceAssetsMap := make(map[int32]xdr.Int64, len(sourceAssets))
 	for i, sourceAsset := range sourceAssets {
+		if constraints.excludesAsset(sourceAsset) {
+			continue
+		}
 		sourceAssetString := sourceAsset.String()
 		sourceAssetID, ok := graph.assetStringToID[sourceAssetString]
 		if !ok {
@@ -749,7 +782,7 @@ func (graph *OrderBookGraph) findPathsWithLock(
 	}
 	err := search(
 		ctx,
-		searchState,
+		graph.constrain(searchState, constraints),
 		maxPathLength,
 		destinationAssetID,
 		destinationAmount,
@@ -790,9 +823,27 @@ func (graph *OrderBookGraph) FindFixedPaths(
 	destinationAssets []xdr.Asset,
 	maxAssetsPerPath int,
 	includePools bool,
+) ([]Path, uint32, error) {
+	return graph.FindFixedPathsWithConstraints(
+		ctx, maxPathLength, sourceAsset, amountToSpend, destinationAssets, maxAssetsPerPath, includePools,
+		PathConstraints{},
+	)
+}
+
+// FindFixedPathsWithConstraints is FindFixedPaths with a search which doesn't
+// explore the assets and venues excluded by `constraints`.
+func (graph *OrderBookGraph) FindFixedPathsWithConstraints(
+	ctx context.Context,
+	maxPathLength int,
+	sourceAsset xdr.Asset,
+	amountToSpend xdr.Int64,
+	destinationAssets []xdr.Asset,
+	maxAssetsPerPath int,
+	includePools bool,
+	constraints PathConstraints,
 ) ([]Path, uint32, error) {
 	paths, lastLedger, err := graph.findFixedPathsWithLock(
-		ctx, maxPathLength, sourceAsset, amountToSpend, destinationAssets, includePools,
+		ctx, maxPathLength, sourceAsset, amountToSpend, destinationAssets, includePools, constraints,
 	)
 	if err != nil {
 		return nil, lastLedger, errors.Wrap(err, "could not determine paths")
@@ -813,12 +864,19 @@ func (graph *OrderBookGraph) findFixedPathsWithLock(
 	amountToSpend xdr.Int64,
 	destinationAssets []xdr.Asset,
 	includePools bool,
+	constraints PathConstraints,
 ) ([]Path, uint32, error) {
 	graph.lock.RLock()
 	defer graph.lock.RUnlock()
 
+	if constraints.excludesAsset(sourceAsset) {
+		return []Path{}, graph.lastLedger, nil
+	}
 	target := make(map[int32]bool, len(destinationAssets))
 	for _, destinationAsset := range destinationAssets {
+		if constraints.excludesAsset(destinationAsset) {
+			continue
+		}
 		destinationAssetString := destinationAsset.String()
 		destinationAssetID, ok := graph.assetStringToID[destinationAssetString]
 		if !ok {
@@ -842,7 +900,7 @@ func (graph *OrderBookGraph) findFixedPathsWithLock(
 	}
 	err := search(
 		ctx,
-		searchState,
+		graph.constrain(searchState, constraints),
 		maxPathLength,
 		sourceAssetID,
 		amountToSpend,
@@ -1071,6 +1071,7 @@ func TestConsumeOffersForSellingAsset(t *testing.T) {
 				testCase.ignoreOffersFrom,
 				testCase.currentAssetAmount,
 				0,
+				nil,
 			)
 			if err != testCase.err {
 				t.Fatalf("expected error %v but got %v", testCase.err, err)
@@ -1173,6 +1174,7 @@ func TestConsumeOffersForBuyingAsset(t *testing.T) {
 			result, err := consumeOffersForBuyingAsset(
 				testCase.offers,
 				testCase.currentAssetAmount,
+				nil,
 			)
 			assert.Equal(t, testCase.err, err)
 			if err == nil {
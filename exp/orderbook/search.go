@@ -317,7 +317,7 @@ func (state *sellingGraphSearchState) consumeOffers(
 	offers []xdr.OfferEntry,
 ) (xdr.Int64, error) {
 	nextAmount, err := consumeOffersForSellingAsset(
-		offers, state.ignoreOffersFrom, currentAssetAmount, currentBestAmount)
+		offers, state.ignoreOffersFrom, currentAssetAmount, currentBestAmount, nil)
 
 	return positiveMin(currentBestAmount, nextAmount), err
 }
@@ -398,7 +398,7 @@ func (state *buyingGraphSearchState) consumeOffers(
 	currentBestAmount xdr.Int64,
 	offers []xdr.OfferEntry,
 ) (xdr.Int64, error) {
-	nextAmount, err := consumeOffersForBuyingAsset(offers, currentAssetAmount)
+	nextAmount, err := consumeOffersForBuyingAsset(offers, currentAssetAmount, nil)
 
 	return ordered.Max(nextAmount, currentBestAmount), err
 }
@@ -415,11 +415,16 @@ func (state *buyingGraphSearchState) consumePool(
 	return makeTrade(pool, currentAsset, tradeTypeDeposit, currentAssetAmount)
 }
 
+// offerFillFunc is called with every offer consumed by a trade and the amount
+// of its selling asset taken from it.
+type offerFillFunc func(offer xdr.OfferEntry, amountSold xdr.Int64)
+
 func consumeOffersForSellingAsset(
 	offers []xdr.OfferEntry,
 	ignoreOffersFrom *xdr.AccountId,
 	currentAssetAmount xdr.Int64,
 	currentBestAmount xdr.Int64,
+	onFill offerFillFunc,
 ) (xdr.Int64, error) {
 	if len(offers) == 0 {
 		return 0, errEmptyOffers
@@ -450,6 +455,9 @@ func consumeOffersForSellingAsset(
 		}
 
 		totalConsumed += xdr.Int64(buyingUnitsFromOffer)
+		if onFill != nil {
+			onFill(offers[i], xdr.Int64(sellingUnitsFromOffer))
+		}
 
 		// For sell-state, we are aiming to *minimize* the amount of the source
 		// assets we need to get to the destination, so if we exceed the best
@@ -476,6 +484,7 @@ func consumeOffersForSellingAsset(
 func consumeOffersForBuyingAsset(
 	offers []xdr.OfferEntry,
 	currentAssetAmount xdr.Int64,
+	onFill offerFillFunc,
 ) (xdr.Int64, error) {
 	if len(offers) == 0 {
 		return 0, errEmptyOffers
@@ -505,6 +514,9 @@ func consumeOffersForBuyingAsset(
 			amountSoldXDR := xdr.Int64(amountSold)
 			if amountSoldXDR <= offers[i].Amount {
 				totalConsumed += amountSoldXDR
+				if onFill != nil {
+					onFill(offers[i], amountSoldXDR)
+				}
 				return totalConsumed, nil
 			}
 		} else if err != price.ErrOverflow {
@@ -527,6 +539,9 @@ func consumeOffersForBuyingAsset(
 
 		totalConsumed += xdr.Int64(sellingUnitsFromOffer)
 		currentAssetAmount -= xdr.Int64(buyingUnitsFromOffer)
+		if onFill != nil {
+			onFill(offers[i], xdr.Int64(sellingUnitsFromOffer))
+		}
 
 		if currentAssetAmount == 0 {
 			return totalConsumed, nil
@@ -0,0 +1,520 @@
+package orderbook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/xdr"
+)
+
+// splitPaymentParts is the number of parts a split payment is divided into.
+// Every part is routed through the best path left by the previous parts.
+const splitPaymentParts = 10
+
+var errNoLiquidity = errors.New("not enough liquidity to route the payment")
+
+// SplitPath represents a payment divided between several payment paths.
+//
+// The sub-payments in Paths are meant to be submitted in order, as path
+// payment operations of a single transaction: the amounts of every
+// sub-payment take into account the liquidity consumed by the previous ones.
+type SplitPath struct {
+	SourceAsset       string
+	SourceAmount      xdr.Int64
+	DestinationAsset  string
+	DestinationAmount xdr.Int64
+
+	Paths []Path
+}
+
+// liquidityOverlay records the liquidity consumed from the order book graph by
+// the parts of a split payment without modifying the graph.
+type liquidityOverlay struct {
+	// offerAmounts maps the ids of the consumed offers to their remaining
+	// amount.
+	offerAmounts map[xdr.Int64]xdr.Int64
+	// poolReserves maps the ids of the consumed pools to their reserves after
+	// the trades.
+	poolReserves map[xdr.PoolId]xdr.LiquidityPoolEntryConstantProduct
+	// venues caches the venues of the assets visited since the overlay last
+	// changed.
+	venues map[int32]edgeSet
+}
+
+func newLiquidityOverlay() *liquidityOverlay {
+	overlay := &liquidityOverlay{}
+	overlay.reset()
+	return overlay
+}
+
+// reset discards all the consumed liquidity.
+func (overlay *liquidityOverlay) reset() {
+	overlay.offerAmounts = map[xdr.Int64]xdr.Int64{}
+	overlay.poolReserves = map[xdr.PoolId]xdr.LiquidityPoolEntryConstantProduct{}
+	overlay.venues = map[int32]edgeSet{}
+}
+
+// adjust returns the venues of the given asset minus the consumed liquidity.
+func (overlay *liquidityOverlay) adjust(asset int32, edges edgeSet) edgeSet {
+	if len(overlay.offerAmounts) == 0 && len(overlay.poolReserves) == 0 {
+		return edges
+	}
+	if adjusted, ok := overlay.venues[asset]; ok {
+		return adjusted
+	}
+
+	adjusted := make(edgeSet, 0, len(edges))
+	for _, e := range edges {
+		venues := Venues{
+			offers: overlay.adjustOffers(e.value.offers),
+			pool:   overlay.adjustPool(e.value.pool),
+		}
+		if len(venues.offers) == 0 && venues.pool.Body.ConstantProduct == nil {
+			continue
+		}
+		adjusted = append(adjusted, edge{key: e.key, value: venues})
+	}
+	overlay.venues[asset] = adjusted
+	return adjusted
+}
+
+func (overlay *liquidityOverlay) adjustOffers(offers []xdr.OfferEntry) []xdr.OfferEntry {
+	var adjusted []xdr.OfferEntry
+	for i, offer := range offers {
+		remaining, ok := overlay.offerAmounts[offer.OfferId]
+		if !ok {
+			if adjusted != nil {
+				adjusted = append(adjusted, offer)
+			}
+			continue
+		}
+
+		// Only copy the offers once one of them has been consumed.
+		if adjusted == nil {
+			adjusted = append(make([]xdr.OfferEntry, 0, len(offers)), offers[:i]...)
+		}
+		if remaining > 0 {
+			offer.Amount = remaining
+			adjusted = append(adjusted, offer)
+		}
+	}
+
+	if adjusted == nil {
+		return offers
+	}
+	return adjusted
+}
+
+func (overlay *liquidityOverlay) adjustPool(pool liquidityPool) liquidityPool {
+	if pool.Body.ConstantProduct == nil {
+		return pool
+	}
+	if reserves, ok := overlay.poolReserves[pool.LiquidityPoolId]; ok {
+		pool.Body.ConstantProduct = &reserves
+	}
+	return pool
+}
+
+// consumeOffer takes amountSold from the given offer, as seen through the
+// overlay.
+func (overlay *liquidityOverlay) consumeOffer(offer xdr.OfferEntry, amountSold xdr.Int64) {
+	overlay.offerAmounts[offer.OfferId] = offer.Amount - amountSold
+	overlay.venues = map[int32]edgeSet{}
+}
+
+// tradeWithPool deposits amountIn of the asset in the given pool, as seen
+// through the overlay, and withdraws amountOut of the other asset.
+func (overlay *liquidityOverlay) tradeWithPool(pool liquidityPool, asset int32, amountIn, amountOut xdr.Int64) {
+	reserves := *pool.Body.ConstantProduct
+	if pool.assetA == asset {
+		reserves.ReserveA += amountIn
+		reserves.ReserveB -= amountOut
+	} else {
+		reserves.ReserveB += amountIn
+		reserves.ReserveA -= amountOut
+	}
+	overlay.poolReserves[pool.LiquidityPoolId] = reserves
+	overlay.venues = map[int32]edgeSet{}
+}
+
+// splitSearchState wraps the search state routing a part of a split payment.
+// The search sees the venues of the order book graph minus the liquidity
+// consumed by the previous parts and only keeps the best path.
+type splitSearchState struct {
+	searchState
+	overlay          *liquidityOverlay
+	strictSend       bool
+	ignoreOffersFrom *xdr.AccountId
+
+	bestPath   []int32
+	bestAmount xdr.Int64
+}
+
+func (state *splitSearchState) venues(currentAsset int32) edgeSet {
+	return state.overlay.adjust(currentAsset, state.searchState.venues(currentAsset))
+}
+
+func (state *splitSearchState) appendToPaths(
+	path []int32,
+	currentAsset int32,
+	currentAssetAmount xdr.Int64,
+) {
+	better := state.bestPath == nil ||
+		state.betterPathAmount(state.bestAmount, currentAssetAmount) ||
+		(currentAssetAmount == state.bestAmount && len(path) < len(state.bestPath))
+	if better {
+		state.bestPath = append(state.bestPath[:0], path...)
+		state.bestAmount = currentAssetAmount
+	}
+}
+
+// route finds the best path for amount given the liquidity consumed so far.
+// The path starts with the start asset of the search.
+func (state *splitSearchState) route(
+	ctx context.Context,
+	base searchState,
+	maxPathLength int,
+	start int32,
+	amount xdr.Int64,
+) ([]int32, error) {
+	state.searchState = base
+	state.bestPath = nil
+	state.bestAmount = 0
+	if err := search(ctx, state, maxPathLength, start, amount); err != nil {
+		return nil, err
+	}
+	return state.bestPath, nil
+}
+
+// execute trades amount along the given path, in the order of the search,
+// picking the better of the pool and the offers of every hop like the search
+// does. The consumed liquidity is recorded in the overlay. It returns the
+// amount of the last asset of the path.
+func (state *splitSearchState) execute(path []int32, amount xdr.Int64) (xdr.Int64, error) {
+	for i := 0; i+1 < len(path); i++ {
+		currentAsset, nextAsset := path[i], path[i+1]
+		edges := state.venues(currentAsset)
+		j := edges.find(nextAsset)
+		if j < 0 {
+			return 0, errNoLiquidity
+		}
+		venues := edges[j].value
+
+		poolAmount := xdr.Int64(0)
+		if pool := venues.pool; state.considerPools() && pool.Body.ConstantProduct != nil {
+			if result, err := state.consumePool(pool, currentAsset, amount); err == nil {
+				poolAmount = result
+			}
+		}
+
+		type fill struct {
+			offer      xdr.OfferEntry
+			amountSold xdr.Int64
+		}
+		var fills []fill
+		onFill := func(offer xdr.OfferEntry, amountSold xdr.Int64) {
+			fills = append(fills, fill{offer, amountSold})
+		}
+		offersAmount := xdr.Int64(-1)
+		if len(venues.offers) > 0 {
+			var err error
+			if state.strictSend {
+				offersAmount, err = consumeOffersForBuyingAsset(venues.offers, amount, onFill)
+			} else {
+				offersAmount, err = consumeOffersForSellingAsset(
+					venues.offers, state.ignoreOffersFrom, amount, 0, onFill)
+			}
+			if err != nil {
+				offersAmount = -1
+			}
+		}
+
+		switch {
+		case poolAmount > 0 && (offersAmount <= 0 || state.betterPathAmount(offersAmount, poolAmount)):
+			if state.strictSend {
+				state.overlay.tradeWithPool(venues.pool, currentAsset, amount, poolAmount)
+			} else {
+				state.overlay.tradeWithPool(venues.pool, nextAsset, poolAmount, amount)
+			}
+			amount = poolAmount
+		case offersAmount > 0:
+			for _, f := range fills {
+				state.overlay.consumeOffer(f.offer, f.amountSold)
+			}
+			amount = offersAmount
+		default:
+			return 0, errNoLiquidity
+		}
+	}
+	return amount, nil
+}
+
+// splitRoute is a sub-payment of a split payment. path is in the order of the
+// search.
+type splitRoute struct {
+	path   []int32
+	amount xdr.Int64
+	result xdr.Int64
+}
+
+// splitAmount divides amount into at most splitPaymentParts positive parts.
+func splitAmount(amount xdr.Int64) []xdr.Int64 {
+	parts := xdr.Int64(splitPaymentParts)
+	if amount < parts {
+		parts = amount
+	}
+	if parts <= 0 {
+		return nil
+	}
+
+	result := make([]xdr.Int64, parts)
+	for i := range result {
+		result[i] = amount / parts
+	}
+	result[len(result)-1] += amount % parts
+	return result
+}
+
+// splitPayment routes every part of amount through the best path left by the
+// previous parts. newState returns the search state of a part.
+//
+// The parts using the same path are merged into a single sub-payment and the
+// sub-payments are executed again in order so that their amounts match the
+// ones of consecutive path payments. It returns nil if a part can't be routed.
+func (state *splitSearchState) splitPayment(
+	ctx context.Context,
+	maxPathLength int,
+	start int32,
+	amount xdr.Int64,
+	newState func(part xdr.Int64) searchState,
+) ([]splitRoute, error) {
+	var routes []splitRoute
+	routeIndex := map[string]int{}
+	for _, part := range splitAmount(amount) {
+		path, err := state.route(ctx, newState(part), maxPathLength, start, part)
+		if err != nil {
+			return nil, err
+		}
+		if path == nil {
+			return nil, nil
+		}
+		if _, err := state.execute(path, part); err != nil {
+			return nil, nil
+		}
+
+		key := pathKey(path)
+		if i, ok := routeIndex[key]; ok {
+			routes[i].amount += part
+			continue
+		}
+		routeIndex[key] = len(routes)
+		routes = append(routes, splitRoute{
+			path:   append([]int32(nil), path...),
+			amount: part,
+		})
+	}
+
+	state.overlay.reset()
+	for i := range routes {
+		result, err := state.execute(routes[i].path, routes[i].amount)
+		if err != nil {
+			return nil, nil
+		}
+		routes[i].result = result
+	}
+	return routes, nil
+}
+
+func pathKey(path []int32) string {
+	return fmt.Sprint(path)
+}
+
+// FindSplitPaths returns, for each source asset, a strict receive payment of
+// `destinationAmount` of `destinationAsset` divided between several payment
+// paths so that the total amount of the source asset spent is minimized.
+//
+// The parameters match the ones of FindPaths. Source assets which can't pay
+// for the whole amount are omitted.
+func (graph *OrderBookGraph) FindSplitPaths(
+	ctx context.Context,
+	maxPathLength int,
+	destinationAsset xdr.Asset,
+	destinationAmount xdr.Int64,
+	sourceAccountID *xdr.AccountId,
+	sourceAssets []xdr.Asset,
+	sourceAssetBalances []xdr.Int64,
+	validateSourceBalance bool,
+	includePools bool,
+	constraints PathConstraints,
+) ([]SplitPath, uint32, error) {
+	graph.lock.RLock()
+	defer graph.lock.RUnlock()
+
+	splitPaths := []SplitPath{}
+	if constraints.excludesAsset(destinationAsset) {
+		return splitPaths, graph.lastLedger, nil
+	}
+	destinationAssetString := destinationAsset.String()
+	destinationAssetID, ok := graph.assetStringToID[destinationAssetString]
+	if !ok {
+		return splitPaths, graph.lastLedger, nil
+	}
+
+	visited := map[int32]bool{}
+	for i, sourceAsset := range sourceAssets {
+		sourceAssetID, ok := graph.assetStringToID[sourceAsset.String()]
+		if !ok || visited[sourceAssetID] || constraints.excludesAsset(sourceAsset) {
+			continue
+		}
+		visited[sourceAssetID] = true
+		balance := sourceAssetBalances[i]
+
+		state := &splitSearchState{
+			overlay:          newLiquidityOverlay(),
+			ignoreOffersFrom: sourceAccountID,
+		}
+		routes, err := state.splitPayment(
+			ctx, maxPathLength, destinationAssetID, destinationAmount,
+			func(part xdr.Int64) searchState {
+				// The source balance is checked against the amount of the
+				// whole payment below.
+				return graph.constrain(&sellingGraphSearchState{
+					graph:                  graph,
+					destinationAssetString: destinationAssetString,
+					destinationAssetAmount: part,
+					ignoreOffersFrom:       sourceAccountID,
+					targetAssets:           map[int32]xdr.Int64{sourceAssetID: balance},
+					paths:                  []Path{},
+					includePools:           includePools,
+				}, constraints)
+			},
+		)
+		if err != nil {
+			return nil, graph.lastLedger, errors.Wrap(err, "could not determine paths")
+		}
+		if routes == nil {
+			continue
+		}
+
+		splitPath := SplitPath{
+			SourceAsset:       sourceAsset.String(),
+			DestinationAsset:  destinationAssetString,
+			DestinationAmount: destinationAmount,
+		}
+		for _, route := range routes {
+			interiorNodes := []int32{}
+			if len(route.path) > 2 {
+				interiorNodes = append(interiorNodes, route.path[1:len(route.path)-1]...)
+				reversePath(interiorNodes)
+			}
+			splitPath.SourceAmount += route.result
+			splitPath.Paths = append(splitPath.Paths, Path{
+				SourceAsset:       splitPath.SourceAsset,
+				SourceAmount:      route.result,
+				DestinationAsset:  destinationAssetString,
+				DestinationAmount: route.amount,
+				InteriorNodes:     assetIDsToAssetStrings(graph, interiorNodes),
+			})
+		}
+		if validateSourceBalance && splitPath.SourceAmount > balance {
+			continue
+		}
+		splitPaths = append(splitPaths, splitPath)
+	}
+
+	sort.Slice(splitPaths, func(i, j int) bool {
+		return splitPaths[i].SourceAsset < splitPaths[j].SourceAsset
+	})
+	return splitPaths, graph.lastLedger, nil
+}
+
+// FindFixedSplitPaths returns, for each destination asset, a strict send
+// payment of `amountToSpend` of `sourceAsset` divided between several payment
+// paths so that the total amount of the destination asset received is
+// maximized.
+//
+// The parameters match the ones of FindFixedPaths. Destination assets which
+// can't be reached with the whole amount are omitted.
+func (graph *OrderBookGraph) FindFixedSplitPaths(
+	ctx context.Context,
+	maxPathLength int,
+	sourceAsset xdr.Asset,
+	amountToSpend xdr.Int64,
+	destinationAssets []xdr.Asset,
+	includePools bool,
+	constraints PathConstraints,
+) ([]SplitPath, uint32, error) {
+	graph.lock.RLock()
+	defer graph.lock.RUnlock()
+
+	splitPaths := []SplitPath{}
+	if constraints.excludesAsset(sourceAsset) {
+		return splitPaths, graph.lastLedger, nil
+	}
+	sourceAssetString := sourceAsset.String()
+	sourceAssetID, ok := graph.assetStringToID[sourceAssetString]
+	if !ok {
+		return splitPaths, graph.lastLedger, nil
+	}
+
+	visited := map[int32]bool{}
+	for _, destinationAsset := range destinationAssets {
+		destinationAssetID, ok := graph.assetStringToID[destinationAsset.String()]
+		if !ok || visited[destinationAssetID] || constraints.excludesAsset(destinationAsset) {
+			continue
+		}
+		visited[destinationAssetID] = true
+
+		state := &splitSearchState{
+			overlay:    newLiquidityOverlay(),
+			strictSend: true,
+		}
+		routes, err := state.splitPayment(
+			ctx, maxPathLength, sourceAssetID, amountToSpend,
+			func(part xdr.Int64) searchState {
+				return graph.constrain(&buyingGraphSearchState{
+					graph:             graph,
+					sourceAssetString: sourceAssetString,
+					sourceAssetAmount: part,
+					targetAssets:      map[int32]bool{destinationAssetID: true},
+					paths:             []Path{},
+					includePools:      includePools,
+				}, constraints)
+			},
+		)
+		if err != nil {
+			return nil, graph.lastLedger, errors.Wrap(err, "could not determine paths")
+		}
+		if routes == nil {
+			continue
+		}
+
+		splitPath := SplitPath{
+			SourceAsset:      sourceAssetString,
+			SourceAmount:     amountToSpend,
+			DestinationAsset: destinationAsset.String(),
+		}
+		for _, route := range routes {
+			interiorNodes := []int32{}
+			if len(route.path) > 2 {
+				interiorNodes = route.path[1 : len(route.path)-1]
+			}
+			splitPath.DestinationAmount += route.result
+			splitPath.Paths = append(splitPath.Paths, Path{
+				SourceAsset:       sourceAssetString,
+				SourceAmount:      route.amount,
+				DestinationAsset:  splitPath.DestinationAsset,
+				DestinationAmount: route.result,
+				InteriorNodes:     assetIDsToAssetStrings(graph, interiorNodes),
+			})
+		}
+		splitPaths = append(splitPaths, splitPath)
+	}
+
+	sort.Slice(splitPaths, func(i, j int) bool {
+		return splitPaths[i].DestinationAsset < splitPaths[j].DestinationAsset
+	})
+	return splitPaths, graph.lastLedger, nil
+}
@@ -0,0 +1,163 @@
+package orderbook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hcnet/go/xdr"
+)
+
+func TestSplitAmount(t *testing.T) {
+	assert.Equal(t, []xdr.Int64{2, 2, 2, 2, 2, 2, 2, 2, 2, 7}, splitAmount(25))
+	assert.Equal(t, []xdr.Int64{1, 1, 1}, splitAmount(3))
+	assert.Empty(t, splitAmount(0))
+}
+
+func sumPaths(paths []Path) (xdr.Int64, xdr.Int64) {
+	var sourceAmount, destinationAmount xdr.Int64
+	for _, path := range paths {
+		sourceAmount += path.SourceAmount
+		destinationAmount += path.DestinationAmount
+	}
+	return sourceAmount, destinationAmount
+}
+
+func TestFindFixedSplitPaths(t *testing.T) {
+	graph := NewOrderBookGraph()
+	graph.AddLiquidityPools(
+		makePool(usdAsset, eurAsset, 1000, 1000),
+		makePool(usdAsset, chfAsset, 1000, 1000),
+		makePool(chfAsset, eurAsset, 1000, 1000),
+	)
+	require.NoError(t, graph.Apply(1))
+
+	paths, _, err := graph.FindFixedPaths(
+		context.TODO(), 3, usdAsset, 500, []xdr.Asset{eurAsset}, 5, true,
+	)
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+	bestPath := paths[0]
+
+	splitPaths, lastLedger, err := graph.FindFixedSplitPaths(
+		context.TODO(), 3, usdAsset, 500, []xdr.Asset{eurAsset, yenAsset}, true,
+		PathConstraints{},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), lastLedger)
+	require.Len(t, splitPaths, 1)
+
+	splitPath := splitPaths[0]
+	assert.Equal(t, usdAsset.String(), splitPath.SourceAsset)
+	assert.Equal(t, xdr.Int64(500), splitPath.SourceAmount)
+	assert.Equal(t, eurAsset.String(), splitPath.DestinationAsset)
+	// the payment is divided between the pool of the trading pair and the
+	// path through CHF which delivers more than any single path
+	require.Len(t, splitPath.Paths, 2)
+	assert.Empty(t, splitPath.Paths[0].InteriorNodes)
+	assert.Equal(t, []string{chfAsset.String()}, splitPath.Paths[1].InteriorNodes)
+	sourceAmount, destinationAmount := sumPaths(splitPath.Paths)
+	assert.Equal(t, splitPath.SourceAmount, sourceAmount)
+	assert.Equal(t, splitPath.DestinationAmount, destinationAmount)
+	assert.Greater(t, int64(splitPath.DestinationAmount), int64(bestPath.DestinationAmount))
+
+	// the graph is not modified
+	for _, pool := range graph.LiquidityPools() {
+		assert.Equal(t, xdr.Int64(1000), pool.Body.MustConstantProduct().ReserveA)
+		assert.Equal(t, xdr.Int64(1000), pool.Body.MustConstantProduct().ReserveB)
+	}
+
+	// without pools only the offers can be used
+	splitPaths, _, err = graph.FindFixedSplitPaths(
+		context.TODO(), 3, usdAsset, 500, []xdr.Asset{eurAsset}, false,
+		PathConstraints{},
+	)
+	require.NoError(t, err)
+	assert.Empty(t, splitPaths)
+}
+
+func TestFindFixedSplitPathsConsumesOffersOnce(t *testing.T) {
+	graph := NewOrderBookGraph()
+	graph.AddOffers(eurOffer)
+	require.NoError(t, graph.Apply(1))
+
+	// eurOffer sells 500 XLM for EUR at a price of 1, the parts of the payment
+	// share its liquidity
+	splitPaths, _, err := graph.FindFixedSplitPaths(
+		context.TODO(), 3, eurAsset, 500, []xdr.Asset{nativeAsset}, true,
+		PathConstraints{},
+	)
+	require.NoError(t, err)
+	require.Len(t, splitPaths, 1)
+	assert.Equal(t, []Path{{
+		SourceAsset:       eurAsset.String(),
+		SourceAmount:      500,
+		DestinationAsset:  nativeAsset.String(),
+		DestinationAmount: 500,
+		InteriorNodes:     []string{},
+	}}, splitPaths[0].Paths)
+
+	splitPaths, _, err = graph.FindFixedSplitPaths(
+		context.TODO(), 3, eurAsset, 501, []xdr.Asset{nativeAsset}, true,
+		PathConstraints{},
+	)
+	require.NoError(t, err)
+	assert.Empty(t, splitPaths)
+}
+
+func TestFindSplitPaths(t *testing.T) {
+	graph := NewOrderBookGraph()
+	graph.AddLiquidityPools(
+		makePool(usdAsset, eurAsset, 1000, 1000),
+		makePool(usdAsset, chfAsset, 1000, 1000),
+		makePool(chfAsset, eurAsset, 1000, 1000),
+	)
+	require.NoError(t, graph.Apply(1))
+
+	paths, _, err := graph.FindPaths(
+		context.TODO(), 3, eurAsset, 300, nil,
+		[]xdr.Asset{usdAsset}, []xdr.Int64{0}, false, 5, true,
+	)
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+	bestPath := paths[0]
+
+	splitPaths, _, err := graph.FindSplitPaths(
+		context.TODO(), 3, eurAsset, 300, nil,
+		[]xdr.Asset{usdAsset, yenAsset}, []xdr.Int64{0, 0}, false, true,
+		PathConstraints{},
+	)
+	require.NoError(t, err)
+	require.Len(t, splitPaths, 1)
+
+	splitPath := splitPaths[0]
+	assert.Equal(t, usdAsset.String(), splitPath.SourceAsset)
+	assert.Equal(t, eurAsset.String(), splitPath.DestinationAsset)
+	assert.Equal(t, xdr.Int64(300), splitPath.DestinationAmount)
+	require.Len(t, splitPath.Paths, 2)
+	assert.Empty(t, splitPath.Paths[0].InteriorNodes)
+	assert.Equal(t, []string{chfAsset.String()}, splitPath.Paths[1].InteriorNodes)
+	sourceAmount, destinationAmount := sumPaths(splitPath.Paths)
+	assert.Equal(t, splitPath.SourceAmount, sourceAmount)
+	assert.Equal(t, splitPath.DestinationAmount, destinationAmount)
+	assert.Less(t, int64(splitPath.SourceAmount), int64(bestPath.SourceAmount))
+
+	// the source balance must cover the whole payment
+	splitPaths, _, err = graph.FindSplitPaths(
+		context.TODO(), 3, eurAsset, 300, nil,
+		[]xdr.Asset{usdAsset}, []xdr.Int64{splitPath.SourceAmount}, true, true,
+		PathConstraints{},
+	)
+	require.NoError(t, err)
+	assert.Len(t, splitPaths, 1)
+
+	splitPaths, _, err = graph.FindSplitPaths(
+		context.TODO(), 3, eurAsset, 300, nil,
+		[]xdr.Asset{usdAsset}, []xdr.Int64{splitPath.SourceAmount - 1}, true, true,
+		PathConstraints{},
+	)
+	require.NoError(t, err)
+	assert.Empty(t, splitPaths)
+}
@@ -111,6 +111,13 @@ func (b *S3ArchiveBackend) Size(pth string) (int64, error) {
 }
 
 func (b *S3ArchiveBackend) PutFile(pth string, in io.ReadCloser) error {
+	return b.PutFileWithACL(pth, in, s3.ObjectCannedACLPublicRead)
+}
+
+// PutFileWithACL uploads a file with the given canned ACL. When acl is empty,
+// the object is uploaded without ACL, which is required by buckets with ACLs
+// disabled.
+func (b *S3ArchiveBackend) PutFileWithACL(pth string, in io.ReadCloser, acl string) error {
 	var buf bytes.Buffer
 	_, err := buf.ReadFrom(in)
 	in.Close()
@@ -121,9 +128,11 @@ func (b *S3ArchiveBackend) PutFile(pth string, in io.ReadCloser) error {
 	params := &s3.PutObjectInput{
 		Bucket: aws.String(b.bucket),
 		Key:    aws.String(key),
-		ACL:    aws.String(s3.ObjectCannedACLPublicRead),
 		Body:   bytes.NewReader(buf.Bytes()),
 	}
+	if acl != "" {
+		params.ACL = aws.String(acl)
+	}
 	req, _ := b.svc.PutObjectRequest(params)
 	if b.unsignedRequests {
 		req.Handlers.Sign.Clear() // makes this request unsigned
@@ -140,7 +149,7 @@ func (b *S3ArchiveBackend) PutFile(pth string, in io.ReadCloser) error {
 func (b *S3ArchiveBackend) ListFiles(pth string) (chan string, chan error) {
 	prefix := path.Join(b.prefix, pth)
 	ch := make(chan string)
-	errs := make(chan error)
+	errs := make(chan error, 1)
 
 	params := &s3.ListObjectsInput{
 		Bucket:  aws.String(b.bucket),
@@ -179,6 +188,7 @@ func (b *S3ArchiveBackend) ListFiles(pth string) (chan string, chan error) {
 				logResp(req.HTTPResponse)
 				if err != nil {
 					errs <- err
+					break
 				}
 			} else {
 				break
@@ -279,6 +279,52 @@ type OrderBookSummary struct {
 	Buying  Asset        `json:"counter"`
 }
 
+// OrderBookSnapshot represents the order book of a trading pair at the end of
+// a ledger which changed its offers or liquidity pool.
+type OrderBookSnapshot struct {
+	Ledger  int32        `json:"ledger"`
+	Bids    []PriceLevel `json:"bids"`
+	Asks    []PriceLevel `json:"asks"`
+	Selling Asset        `json:"base"`
+	Buying  Asset        `json:"counter"`
+	// MidPrice is the geometric mean of the best ask and bid prices,
+	// including the liquidity pool of the pair. It's empty when a side of
+	// the order book is empty.
+	MidPrice string               `json:"mid_price,omitempty"`
+	Depth    []OrderBookDepthBand `json:"depth"`
+}
+
+// OrderBookDepthBand represents the cumulative depth of an order book, its
+// offers and liquidity pool, within a price band around its mid price.
+type OrderBookDepthBand struct {
+	// BasisPoints is the width of the band: it includes the asks priced at
+	// most mid_price * (1 + bps / 10000) and the bids priced at least
+	// mid_price / (1 + bps / 10000).
+	BasisPoints int32 `json:"bps"`
+	// AskAmount is the amount of the base asset sold by the asks, for
+	// AskValue of the counter asset.
+	AskAmount string `json:"ask_amount"`
+	AskValue  string `json:"ask_value"`
+	// BidAmount is the amount of the counter asset sold by the bids, for
+	// BidValue of the base asset.
+	BidAmount string `json:"bid_amount"`
+	BidValue  string `json:"bid_value"`
+}
+
+// OrderBookDepth represents the depth of an order book at the end of the
+// last ledger of a period of time which changed it.
+type OrderBookDepth struct {
+	Timestamp int64                `json:"timestamp,string"`
+	Ledger    int32                `json:"ledger"`
+	MidPrice  string               `json:"mid_price,omitempty"`
+	Depth     []OrderBookDepthBand `json:"depth"`
+}
+
+// PagingToken implementation for hal.Pageable. Not actually used
+func (res OrderBookDepth) PagingToken() string {
+	return strconv.FormatInt(res.Timestamp, 10)
+}
+
 // Path represents a single payment path.
 type Path struct {
 	SourceAssetType        string  `json:"source_asset_type"`
@@ -297,6 +343,26 @@ func (p Path) PagingToken() string {
 	return ""
 }
 
+// SplitPath represents a payment divided between several payment paths. The
+// sub-payments in Paths are meant to be submitted in order, as path payment
+// operations of a single transaction.
+type SplitPath struct {
+	SourceAssetType        string `json:"source_asset_type"`
+	SourceAssetCode        string `json:"source_asset_code,omitempty"`
+	SourceAssetIssuer      string `json:"source_asset_issuer,omitempty"`
+	SourceAmount           string `json:"source_amount"`
+	DestinationAssetType   string `json:"destination_asset_type"`
+	DestinationAssetCode   string `json:"destination_asset_code,omitempty"`
+	DestinationAssetIssuer string `json:"destination_asset_issuer,omitempty"`
+	DestinationAmount      string `json:"destination_amount"`
+	Paths                  []Path `json:"paths"`
+}
+
+// stub implementation to satisfy pageable interface
+func (p SplitPath) PagingToken() string {
+	return ""
+}
+
 // Price represents a price for an offer
 type Price base.Price
 
@@ -629,6 +695,122 @@ type TransactionResultCodes struct {
 	OperationCodes       []string `json:"operations,omitempty"`
 }
 
+// AsyncTransactionSubmissionResponse represents the response of the
+// asynchronous transaction submission endpoint, TxStatus is the status returned
+// by hcnet-core.
+type AsyncTransactionSubmissionResponse struct {
+	Hash     string `json:"hash"`
+	TxStatus string `json:"tx_status"`
+}
+
+// TransactionStatus represents the status of a submitted transaction: pending,
+// ingested, failed or expired.
+type TransactionStatus struct {
+	Hash        string                  `json:"hash"`
+	Status      string                  `json:"status"`
+	Ledger      int32                   `json:"ledger,omitempty"`
+	ResultXdr   string                  `json:"result_xdr,omitempty"`
+	ResultCodes *TransactionResultCodes `json:"result_codes,omitempty"`
+}
+
+// AccountsBatchRequest is the body of a request loading multiple accounts.
+type AccountsBatchRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// AccountsBatch represents the accounts loaded by a batch request, in the
+// order they were requested. Accounts which don't exist are listed in Missing.
+type AccountsBatch struct {
+	Accounts []Account `json:"accounts"`
+	Missing  []string  `json:"missing"`
+}
+
+// AssetHolder represents an account holding an asset in a trust line or a
+// liquidity pool holding it in its reserves. Authorization flags are only set
+// for accounts.
+type AssetHolder struct {
+	ID                                string `json:"id"`
+	PT                                string `json:"paging_token"`
+	Type                              string `json:"type"`
+	Balance                           string `json:"balance"`
+	IsAuthorized                      *bool  `json:"is_authorized,omitempty"`
+	IsAuthorizedToMaintainLiabilities *bool  `json:"is_authorized_to_maintain_liabilities,omitempty"`
+	IsClawbackEnabled                 *bool  `json:"is_clawback_enabled,omitempty"`
+}
+
+// PagingToken implementation for hal.Pageable
+func (h AssetHolder) PagingToken() string {
+	return h.PT
+}
+
+// AccountBalances represents the native and trust line balances of an account
+// at the end of a past ledger. Only the balance and asset fields of each
+// balance are set.
+type AccountBalances struct {
+	AccountID string    `json:"account_id"`
+	Ledger    int32     `json:"ledger"`
+	Balances  []Balance `json:"balances"`
+}
+
+// Sponsorship represents a ledger entry sponsored by an account. The fields
+// identifying the entry depend on its type.
+type Sponsorship struct {
+	PT                 string `json:"paging_token"`
+	Type               string `json:"type"`
+	AccountID          string `json:"account_id,omitempty"`
+	Signer             string `json:"signer,omitempty"`
+	Asset              string `json:"asset,omitempty"`
+	LiquidityPoolID    string `json:"liquidity_pool_id,omitempty"`
+	OfferID            int64  `json:"offer_id,omitempty,string"`
+	DataName           string `json:"data_name,omitempty"`
+	BalanceID          string `json:"balance_id,omitempty"`
+	ReserveCount       int64  `json:"reserve_count"`
+	Reserve            string `json:"reserve"`
+	LastModifiedLedger uint32 `json:"last_modified_ledger"`
+}
+
+// PagingToken implementation for hal.Pageable
+func (res Sponsorship) PagingToken() string {
+	return res.PT
+}
+
+// SponsorshipTotal represents the number of entries of a type sponsored by an
+// account and the reserves committed to them.
+type SponsorshipTotal struct {
+	Type         string `json:"type"`
+	Count        int64  `json:"count"`
+	ReserveCount int64  `json:"reserve_count"`
+	Reserve      string `json:"reserve"`
+}
+
+// AccountSponsorships represents a page of the ledger entries sponsored by an
+// account with the totals of all the sponsored entries by type. Reserves are
+// computed with the base reserve of the latest ledger.
+type AccountSponsorships struct {
+	Links struct {
+		Self    hal.Link `json:"self"`
+		Next    hal.Link `json:"next"`
+		Prev    hal.Link `json:"prev"`
+		Effects hal.Link `json:"effects"`
+	} `json:"_links"`
+	Embedded struct {
+		Records []Sponsorship `json:"records"`
+	} `json:"_embedded"`
+	Totals            []SponsorshipTotal `json:"totals"`
+	TotalReserveCount int64              `json:"total_reserve_count"`
+	TotalReserve      string             `json:"total_reserve"`
+}
+
+// TransactionSimulation represents the predicted result of submitting a
+// transaction, as returned by the transaction simulation endpoint.
+type TransactionSimulation struct {
+	Hash        string                 `json:"hash"`
+	Successful  bool                   `json:"successful"`
+	Ledger      int32                  `json:"ledger"`
+	FeeCharged  int64                  `json:"fee_charged,string"`
+	ResultCodes TransactionResultCodes `json:"result_codes"`
+}
+
 // KeyTypeFromAddress converts the version byte of the provided strkey encoded
 // value (for example an account id or a signer key) and returns the appropriate
 // aurora-specific type name.
@@ -797,6 +979,25 @@ func (res ClaimableBalance) PagingToken() string {
 type Claimant struct {
 	Destination string             `json:"destination"`
 	Predicate   xdr.ClaimPredicate `json:"predicate"`
+	// ClaimableWindow is the current or next period of time during which the
+	// predicate is satisfied, it is nil when the predicate can't be satisfied
+	// after the latest ledger.
+	ClaimableWindow *ClaimableWindow `json:"claimable_window,omitempty"`
+}
+
+// ClaimableWindow is a period of time during which a claimant can claim a
+// claimable balance: ledgers closed at or after Start and before End. Start
+// is nil when the period started at or before the latest ledger, End is nil
+// when the period doesn't end.
+type ClaimableWindow struct {
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+// Contains returns true if a ledger closed at closedAt is in the window.
+func (w ClaimableWindow) Contains(closedAt time.Time) bool {
+	return (w.Start == nil || !closedAt.Before(*w.Start)) &&
+		(w.End == nil || closedAt.Before(*w.End))
 }
 
 // LiquidityPool represents a liquidity pool
@@ -837,6 +1038,48 @@ type LiquidityPoolReserve struct {
 	Amount string `json:"amount"`
 }
 
+// LiquidityPoolHistory represents the state of a liquidity pool at the end of
+// a period of time and the trades of the pool during the period.
+type LiquidityPoolHistory struct {
+	Timestamp       int64                  `json:"timestamp,string"`
+	Ledger          int32                  `json:"ledger"`
+	Deleted         bool                   `json:"deleted,omitempty"`
+	TotalTrustlines uint64                 `json:"total_trustlines,string"`
+	TotalShares     string                 `json:"total_shares"`
+	Reserves        []LiquidityPoolReserve `json:"reserves"`
+	// Price is the price of the first reserve in units of the second reserve
+	// at the end of the period.
+	Price string `json:"price,omitempty"`
+	// OpenPrice is the price at the end of the previous period.
+	OpenPrice string `json:"open_price,omitempty"`
+	// PriceRatio is Price divided by OpenPrice.
+	PriceRatio string `json:"price_ratio,omitempty"`
+	// ImpermanentLoss is the relative loss of value, caused by the price
+	// change during the period, of the shares of the pool compared to
+	// holding the reserves.
+	ImpermanentLoss string                `json:"impermanent_loss,omitempty"`
+	TradeCount      int64                 `json:"trade_count,string"`
+	Volume          []LiquidityPoolVolume `json:"volume"`
+}
+
+// PagingToken implementation for hal.Pageable. Not actually used
+func (res LiquidityPoolHistory) PagingToken() string {
+	return strconv.FormatInt(res.Timestamp, 10)
+}
+
+// LiquidityPoolVolume represents the amounts of an asset traded with a
+// liquidity pool.
+type LiquidityPoolVolume struct {
+	Asset string `json:"asset"`
+	// AmountIn is the amount of the asset sold to the pool.
+	AmountIn string `json:"amount_in"`
+	// AmountOut is the amount of the other asset bought from the pool in
+	// exchange.
+	AmountOut string `json:"amount_out"`
+	// FeeRevenue is the part of AmountIn collected as fee by the pool.
+	FeeRevenue string `json:"fee_revenue"`
+}
+
 type AssetFilterConfig struct {
 	Whitelist    []string `json:"whitelist"`
 	Enabled      *bool    `json:"enabled"`
@@ -849,6 +1092,59 @@ type AccountFilterConfig struct {
 	LastModified int64    `json:"last_modified,omitempty"`
 }
 
+// WebhookSubscription is the admin representation of a webhook subscription.
+// Operations matching all the filters of a subscription, and their effects,
+// are delivered to its URL after each ingested ledger.
+type WebhookSubscription struct {
+	ID  int64  `json:"id"`
+	URL string `json:"url"`
+	// Secret is the key used to sign deliveries, it is only returned when
+	// the subscription is created.
+	Secret         string   `json:"secret,omitempty"`
+	AccountID      string   `json:"account_id,omitempty"`
+	Asset          string   `json:"asset,omitempty"`
+	OperationTypes []string `json:"operation_types"`
+	// Cursor is the paging token of the last operation delivered, "now"
+	// starts deliveries from the latest ingested ledger.
+	Cursor        string     `json:"cursor"`
+	Failures      int        `json:"failures"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// APIKey is the admin representation of an API key. Requests sending the key
+// in the X-API-Key header or the api_key query parameter are rate limited by
+// the quotas of the key instead of by remote IP address.
+type APIKey struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// Key is only returned when the API key is created.
+	Key string `json:"key,omitempty"`
+	// PerSecondLimit and PerHourLimit are the request quotas of the key, 0
+	// is unlimited.
+	PerSecondLimit int `json:"per_second_limit"`
+	PerHourLimit   int `json:"per_hour_limit"`
+	// Weights is the number of requests counted against the quotas for each
+	// request of a route class: default, paths or streams (for each ledger
+	// sent by a stream). Classes missing from Weights use the default
+	// weights of Aurora.
+	Weights   map[string]int `json:"weights"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// APIKeyUsage is the number of requests made with an API key, by route class,
+// since the Aurora instance serving the admin endpoint started.
+type APIKeyUsage struct {
+	ID       int64            `json:"id"`
+	Requests map[string]int64 `json:"requests"`
+	// Limited is the number of requests rejected because a quota of the key
+	// was exceeded.
+	Limited map[string]int64 `json:"limited"`
+}
+
 func (f *AccountFilterConfig) UnmarshalJSON(data []byte) error {
 	type accountFilterConfig AccountFilterConfig
 	var config = accountFilterConfig{}
@@ -888,3 +1184,34 @@ func (f *AssetFilterConfig) UnmarshalJSON(data []byte) error {
 	*f = AssetFilterConfig(config)
 	return nil
 }
+
+// IngestionStatus represents the status of the ingestion state machine
+// exposed on the admin port.
+type IngestionStatus struct {
+	State              string                     `json:"state"`
+	StateEnteredAt     time.Time                  `json:"state_entered_at"`
+	TimeInStateSeconds float64                    `json:"time_in_state_seconds"`
+	Paused             bool                       `json:"paused"`
+	CurrentLedger      uint32                     `json:"current_ledger"`
+	Transitions        []IngestionStateTransition `json:"transitions"`
+	Ledgers            []IngestionLedgerStatus    `json:"ledgers"`
+}
+
+// IngestionStateTransition represents a single transition of the ingestion
+// state machine.
+type IngestionStateTransition struct {
+	From            string    `json:"from"`
+	To              string    `json:"to"`
+	At              time.Time `json:"at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// IngestionLedgerStatus represents timings of a single ledger processed by
+// the ingestion state machine.
+type IngestionLedgerStatus struct {
+	Sequence                  uint32             `json:"sequence"`
+	ProcessedAt               time.Time          `json:"processed_at"`
+	DurationSeconds           float64            `json:"duration_seconds"`
+	ProcessorDurationsSeconds map[string]float64 `json:"processor_durations_seconds"`
+}
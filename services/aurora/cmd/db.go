@@ -96,7 +96,12 @@ func migrate(dir schema.MigrateDir, count int) error {
 		return err
 	}
 
-	numMigrationsRun, err := schema.Migrate(dbConn.DB.DB, dir, count)
+	var numMigrationsRun int
+	if dir == schema.MigrateUp && migrateConcurrentIndexes {
+		numMigrationsRun, err = schema.MigrateUpConcurrentIndexes(dbConn.DB.DB, count)
+	} else {
+		numMigrationsRun, err = schema.Migrate(dbConn.DB.DB, dir, count)
+	}
 	if err != nil {
 		return err
 	}
@@ -187,6 +192,60 @@ var dbMigrateStatusCmd = &cobra.Command{
 	},
 }
 
+var dbMigratePlanCmd = &cobra.Command{
+	Use:   "plan [COUNT]",
+	Short: "print pending db schema migrations",
+	Long: "prints the pending upwards schema migrations with the tables they touch, their estimated row " +
+		"counts and the locks taken by each statement. Lock-heavy statements are flagged.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireAndSetFlags(aurora.DatabaseURLFlagName); err != nil {
+			return err
+		}
+
+		// Only allow invocations with 0-1 args.
+		if len(args) > 1 {
+			return ErrUsage{cmd}
+		}
+
+		count := 0
+		if len(args) == 1 {
+			var err error
+			count, err = strconv.Atoi(args[0])
+			if err != nil {
+				log.Println(err)
+				return ErrUsage{cmd}
+			}
+		}
+
+		dbConn, err := db.Open("postgres", config.DatabaseURL)
+		if err != nil {
+			return err
+		}
+
+		plan, err := schema.Plan(dbConn.DB.DB, count)
+		if err != nil {
+			return err
+		}
+
+		fmt.Print(plan)
+		return nil
+	},
+}
+
+var migrateConcurrentIndexes bool
+
+var dbMigrateUpCmdOpts = support.ConfigOptions{
+	{
+		Name:        "concurrent-indexes",
+		ConfigKey:   &migrateConcurrentIndexes,
+		OptType:     types.Bool,
+		Required:    false,
+		FlagDefault: false,
+		Usage: "[optional] if this flag is set, indexes which can safely be created CONCURRENTLY " +
+			"(see aurora db migrate plan) are created before running the migrations, without blocking writes",
+	},
+}
+
 var dbMigrateUpCmd = &cobra.Command{
 	Use:   "up [COUNT]",
 	Short: "run upwards db schema migrations",
@@ -195,6 +254,9 @@ var dbMigrateUpCmd = &cobra.Command{
 		if err := requireAndSetFlags(aurora.DatabaseURLFlagName, aurora.IngestFlagName); err != nil {
 			return err
 		}
+		if err := dbMigrateUpCmdOpts.SetValues(); err != nil {
+			return err
+		}
 
 		// Only allow invocations with 0-1 args.
 		if len(args) > 1 {
@@ -230,6 +292,129 @@ var dbReapCmd = &cobra.Command{
 	},
 }
 
+var dbRestoreHistoryCmd = &cobra.Command{
+	Use:   "restore-history [Start sequence number] [End sequence number]",
+	Short: "restores history data archived by the reaper",
+	Long: "restores history data of ledgers between X and Y sequence number (closed intervals) from the archive " +
+		"written by the reaper (see --history-retention-archive-url). Restored ledgers which are not retained " +
+		"by the retention policies are removed (and archived) again by the next reaper run.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireAndSetFlags(aurora.DatabaseURLFlagName, aurora.HistoryRetentionArchiveURLFlagName); err != nil {
+			return err
+		}
+		for _, flag := range flags {
+			if flag.Name == "history-retention-archive-s3-region" || flag.Name == "history-retention-archive-s3-endpoint" {
+				if err := flag.SetValue(); err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(args) != 2 {
+			return ErrUsage{cmd}
+		}
+
+		argsInt32 := make([]int32, 2)
+		for i, arg := range args {
+			seq, err := strconv.ParseUint(arg, 10, 31)
+			if err != nil {
+				cmd.Usage()
+				return fmt.Errorf(`invalid sequence number "%s"`, arg)
+			}
+			argsInt32[i] = int32(seq)
+		}
+		if argsInt32[0] > argsInt32[1] {
+			return fmt.Errorf("start sequence %d is greater than end sequence %d", argsInt32[0], argsInt32[1])
+		}
+
+		count, err := runDBRestoreHistory(*config, argsInt32[0], argsInt32[1])
+		if err != nil {
+			return err
+		}
+		hlog.Infof("Restored %d rows", count)
+		return nil
+	},
+}
+
+func runDBRestoreHistory(config aurora.Config, start, end int32) (int64, error) {
+	archiver, err := aurora.NewHistoryRetentionArchiver(config)
+	if err != nil {
+		return 0, err
+	}
+
+	auroraSession, err := db.Open("postgres", config.DatabaseURL)
+	if err != nil {
+		return 0, err
+	}
+	defer auroraSession.Close()
+	q := &history.Q{SessionInterface: auroraSession}
+
+	if err = q.Begin(); err != nil {
+		return 0, err
+	}
+	defer q.Rollback()
+
+	count, err := archiver.Restore(context.Background(), q, start, end)
+	if err != nil {
+		return 0, err
+	}
+	return count, q.Commit()
+}
+
+var partitionSize uint32
+
+var dbPartitionHistoryCmdOpts = support.ConfigOptions{
+	{
+		Name:        "partition-size",
+		ConfigKey:   &partitionSize,
+		OptType:     types.Uint32,
+		Required:    false,
+		FlagDefault: uint32(1000000),
+		Usage:       "[optional] number of ledgers in each partition",
+	},
+}
+
+var dbPartitionHistoryCmd = &cobra.Command{
+	Use:   "partition-history",
+	Short: "partitions history tables by ledger sequence",
+	Long: "converts the operations, effects, transactions and participants history tables into tables range " +
+		"partitioned by ledger sequence. Existing rows are kept in a legacy partition. Ingestion then creates " +
+		"partitions ahead of the ingested ledgers and the reaper drops partitions older than the retention " +
+		"policies instead of deleting their rows. Tables are locked and scanned, Aurora should be stopped while it runs.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := requireAndSetFlags(aurora.DatabaseURLFlagName); err != nil {
+			return err
+		}
+		if err := dbPartitionHistoryCmdOpts.SetValues(); err != nil {
+			return err
+		}
+		if len(args) != 0 {
+			return ErrUsage{cmd}
+		}
+
+		auroraSession, err := db.Open("postgres", config.DatabaseURL)
+		if err != nil {
+			return err
+		}
+		defer auroraSession.Close()
+		q := &history.Q{SessionInterface: auroraSession}
+
+		if err = q.Begin(); err != nil {
+			return err
+		}
+		defer q.Rollback()
+
+		if err = q.PartitionHistoryTables(context.Background(), partitionSize); err != nil {
+			return err
+		}
+		if err = q.Commit(); err != nil {
+			return err
+		}
+		hlog.Info("History tables partitioned successfully!")
+		return nil
+	},
+}
+
 var dbReingestCmd = &cobra.Command{
 	Use:   "reingest",
 	Short: "reingest commands",
@@ -523,9 +708,17 @@ func init() {
 	if err := dbFillGapsCmdOpts.Init(dbFillGapsCmd); err != nil {
 		log.Fatal(err.Error())
 	}
+	if err := dbPartitionHistoryCmdOpts.Init(dbPartitionHistoryCmd); err != nil {
+		log.Fatal(err.Error())
+	}
+	if err := dbMigrateUpCmdOpts.Init(dbMigrateUpCmd); err != nil {
+		log.Fatal(err.Error())
+	}
 
 	viper.BindPFlags(dbReingestRangeCmd.PersistentFlags())
 	viper.BindPFlags(dbFillGapsCmd.PersistentFlags())
+	viper.BindPFlags(dbPartitionHistoryCmd.PersistentFlags())
+	viper.BindPFlags(dbMigrateUpCmd.PersistentFlags())
 
 	RootCmd.AddCommand(dbCmd)
 	dbCmd.AddCommand(
@@ -533,11 +726,14 @@ func init() {
 		dbMigrateCmd,
 		dbReapCmd,
 		dbReingestCmd,
+		dbRestoreHistoryCmd,
+		dbPartitionHistoryCmd,
 		dbDetectGapsCmd,
 		dbFillGapsCmd,
 	)
 	dbMigrateCmd.AddCommand(
 		dbMigrateDownCmd,
+		dbMigratePlanCmd,
 		dbMigrateRedoCmd,
 		dbMigrateStatusCmd,
 		dbMigrateUpCmd,
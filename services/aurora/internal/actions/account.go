@@ -172,7 +172,24 @@ func (handler GetAccountsHandler) GetResourcePage(
 		}
 	}
 
-	accounts := make([]hal.Pageable, 0, len(records))
+	resources, err := loadAccountResources(ctx, historyQ, records)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]hal.Pageable, 0, len(resources))
+	for _, resource := range resources {
+		accounts = append(accounts, resource)
+	}
+
+	return accounts, nil
+}
+
+// loadAccountResources loads the signers, trust lines, data entries and last
+// modified ledgers of the given accounts with batched queries and returns
+// their resources in the same order.
+func loadAccountResources(ctx context.Context, historyQ *history.Q, records []history.AccountEntry) ([]protocol.Account, error) {
+	accounts := make([]protocol.Account, 0, len(records))
 
 	if len(records) == 0 {
 		// early return
@@ -184,17 +201,17 @@ func (handler GetAccountsHandler) GetResourcePage(
 		accountIDs = append(accountIDs, record.AccountID)
 	}
 
-	signers, err := handler.loadSigners(ctx, historyQ, accountIDs)
+	signers, err := loadSigners(ctx, historyQ, accountIDs)
 	if err != nil {
 		return nil, err
 	}
 
-	trustlines, err := handler.loadTrustlines(ctx, historyQ, accountIDs)
+	trustlines, err := loadTrustlines(ctx, historyQ, accountIDs)
 	if err != nil {
 		return nil, err
 	}
 
-	data, err := handler.loadData(ctx, historyQ, accountIDs)
+	data, err := loadData(ctx, historyQ, accountIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -225,7 +242,7 @@ func (handler GetAccountsHandler) GetResourcePage(
 	return accounts, nil
 }
 
-func (handler GetAccountsHandler) loadData(ctx context.Context, historyQ *history.Q, accounts []string) (map[string][]history.Data, error) {
+func loadData(ctx context.Context, historyQ *history.Q, accounts []string) (map[string][]history.Data, error) {
 	data := make(map[string][]history.Data)
 
 	records, err := historyQ.GetAccountDataByAccountsID(ctx, accounts)
@@ -240,7 +257,7 @@ func (handler GetAccountsHandler) loadData(ctx context.Context, historyQ *histor
 	return data, nil
 }
 
-func (handler GetAccountsHandler) loadTrustlines(ctx context.Context, historyQ *history.Q, accounts []string) (map[string][]history.TrustLine, error) {
+func loadTrustlines(ctx context.Context, historyQ *history.Q, accounts []string) (map[string][]history.TrustLine, error) {
 	trustLines := make(map[string][]history.TrustLine)
 
 	records, err := historyQ.GetSortedTrustLinesByAccountIDs(ctx, accounts)
@@ -255,7 +272,7 @@ func (handler GetAccountsHandler) loadTrustlines(ctx context.Context, historyQ *
 	return trustLines, nil
 }
 
-func (handler GetAccountsHandler) loadSigners(ctx context.Context, historyQ *history.Q, accounts []string) (map[string][]history.AccountSigner, error) {
+func loadSigners(ctx context.Context, historyQ *history.Q, accounts []string) (map[string][]history.AccountSigner, error) {
 	signers := make(map[string][]history.AccountSigner)
 
 	records, err := historyQ.SignersForAccounts(ctx, accounts)
@@ -0,0 +1,225 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/hcnet/go/amount"
+	protocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/protocols/aurora/base"
+	auroraContext "github.com/hcnet/go/services/aurora/internal/context"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/support/render/problem"
+	"github.com/hcnet/go/xdr"
+)
+
+// AccountBalancesQuery query struct for the /accounts/{account_id}/balances
+// end-point
+type AccountBalancesQuery struct {
+	AccountID string `schema:"account_id" valid:"accountID"`
+	Ledger    uint32 `schema:"ledger" valid:"-"`
+	At        string `schema:"at" valid:"-"`
+}
+
+// Validate runs custom validations.
+func (q AccountBalancesQuery) Validate() error {
+	if (q.Ledger == 0) == (q.At == "") {
+		return problem.MakeInvalidFieldProblem(
+			"ledger",
+			errors.New("exactly one of ledger and at is required"),
+		)
+	}
+	if _, err := q.AtTime(); err != nil {
+		return problem.MakeInvalidFieldProblem(
+			"at",
+			errors.New("at must be a RFC 3339 timestamp, ex. 2021-10-01T00:00:00Z"),
+		)
+	}
+	return nil
+}
+
+// AtTime returns the parsed at parameter or zero time if it's not set.
+func (q AccountBalancesQuery) AtTime() (time.Time, error) {
+	if q.At == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, q.At)
+}
+
+// GetAccountBalancesHandler is the action handler for the
+// /accounts/{account_id}/balances endpoint
+type GetAccountBalancesHandler struct{}
+
+// GetResource returns the balances of an account at the end of the ledger
+// given by the ledger parameter or, when the at parameter is given, at the
+// end of the latest ledger closed before that time. Balances are
+// reconstructed by reverting the balance changes recorded after the ledger
+// from the current state.
+func (handler GetAccountBalancesHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	qp := AccountBalancesQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	sequence := qp.Ledger
+	if qp.At != "" {
+		at, _ := qp.AtTime()
+		var closedBefore int32
+		closedBefore, err = historyQ.LatestLedgerClosedBefore(ctx, at)
+		if err != nil {
+			return nil, errors.Wrap(err, "loading ledger closed before at")
+		}
+		if closedBefore == 0 {
+			return nil, problem.MakeInvalidFieldProblem(
+				"at",
+				errors.New("no ingested ledger was closed before the given time"),
+			)
+		}
+		sequence = uint32(closedBefore)
+	}
+
+	if err = checkAccountBalancesAvailable(ctx, historyQ, sequence); err != nil {
+		return nil, err
+	}
+
+	balances, err := accountBalancesAt(ctx, historyQ, qp.AccountID, sequence)
+	if err != nil {
+		return nil, err
+	}
+	native, ok := balances[balanceAsset{assetType: xdr.AssetTypeAssetTypeNative}]
+	if !ok {
+		return nil, problem.NotFound
+	}
+
+	resource := protocol.AccountBalances{
+		AccountID: qp.AccountID,
+		Ledger:    int32(sequence),
+		Balances:  make([]protocol.Balance, 0, len(balances)),
+	}
+	for asset, balance := range balances {
+		if asset.assetType == xdr.AssetTypeAssetTypeNative {
+			continue
+		}
+		resource.Balances = append(resource.Balances, protocol.Balance{
+			Balance: amount.StringFromInt64(balance),
+			Asset: base.Asset{
+				Type:   xdr.AssetTypeToString[asset.assetType],
+				Code:   asset.assetCode,
+				Issuer: asset.assetIssuer,
+			},
+		})
+	}
+	sort.Slice(resource.Balances, func(i, j int) bool {
+		a, b := resource.Balances[i], resource.Balances[j]
+		if a.Code != b.Code {
+			return a.Code < b.Code
+		}
+		return a.Issuer < b.Issuer
+	})
+	// the native balance is listed last like in the account resource
+	resource.Balances = append(resource.Balances, protocol.Balance{
+		Balance: amount.StringFromInt64(native),
+		Asset:   base.Asset{Type: xdr.AssetTypeToString[xdr.AssetTypeAssetTypeNative]},
+	})
+
+	return resource, nil
+}
+
+// checkAccountBalancesAvailable returns a bad request problem if the balances
+// at the end of the given ledger can't be reconstructed from the recorded
+// balance history.
+func checkAccountBalancesAvailable(ctx context.Context, historyQ *history.Q, sequence uint32) error {
+	latest, err := historyQ.GetLastLedgerIngestNonBlocking(ctx)
+	if err != nil {
+		return errors.Wrap(err, "loading last ingested ledger")
+	}
+	if sequence > latest {
+		return problem.MakeInvalidFieldProblem(
+			"ledger",
+			fmt.Errorf("ledger %d was not ingested yet, the latest ingested ledger is %d", sequence, latest),
+		)
+	}
+	if sequence == latest {
+		return nil
+	}
+
+	elder, err := historyQ.ElderAccountBalanceLedger(ctx)
+	if err != nil {
+		return errors.Wrap(err, "loading elder account balance ledger")
+	}
+	// the balances at the end of the ledger preceding the first recorded
+	// changes are their previous balances
+	if elder == 0 || sequence+1 < elder {
+		return problem.MakeInvalidFieldProblem(
+			"ledger",
+			fmt.Errorf("balance history is not available for ledger %d", sequence),
+		)
+	}
+	return nil
+}
+
+type balanceAsset struct {
+	assetType   xdr.AssetType
+	assetCode   string
+	assetIssuer string
+}
+
+// accountBalancesAt returns the balances of an account at the end of the given
+// ledger keyed by asset.
+func accountBalancesAt(ctx context.Context, historyQ *history.Q, accountID string, sequence uint32) (map[balanceAsset]int64, error) {
+	balances := map[balanceAsset]int64{}
+
+	account, err := historyQ.GetAccountByID(ctx, accountID)
+	switch {
+	case historyQ.NoRows(err):
+	case err != nil:
+		return nil, errors.Wrap(err, "loading account")
+	default:
+		balances[balanceAsset{assetType: xdr.AssetTypeAssetTypeNative}] = account.Balance
+	}
+
+	trustLines, err := historyQ.GetSortedTrustLinesByAccountID(ctx, accountID)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading trust lines")
+	}
+	for _, trustLine := range trustLines {
+		if trustLine.AssetType == xdr.AssetTypeAssetTypePoolShare {
+			continue
+		}
+		balances[balanceAsset{
+			assetType:   trustLine.AssetType,
+			assetCode:   trustLine.AssetCode,
+			assetIssuer: trustLine.AssetIssuer,
+		}] = trustLine.Balance
+	}
+
+	changes, err := historyQ.FirstAccountBalanceChangesAfter(ctx, accountID, sequence)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading account balance changes")
+	}
+	for _, change := range changes {
+		asset := balanceAsset{
+			assetType:   change.AssetType,
+			assetCode:   change.AssetCode,
+			assetIssuer: change.AssetIssuer,
+		}
+		// a null previous balance means the entry didn't exist at the end
+		// of the ledger
+		if change.PreviousBalance.Valid {
+			balances[asset] = change.PreviousBalance.Int64
+		} else {
+			delete(balances, asset)
+		}
+	}
+	return balances, nil
+}
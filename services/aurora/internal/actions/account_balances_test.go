@@ -0,0 +1,117 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/services/aurora/internal/test"
+	"github.com/hcnet/go/support/render/problem"
+	"github.com/hcnet/go/toid"
+	"github.com/hcnet/go/xdr"
+)
+
+func TestAccountBalancesQueryValidate(t *testing.T) {
+	for _, testCase := range []struct {
+		name  string
+		query AccountBalancesQuery
+		field string
+	}{
+		{"ledger", AccountBalancesQuery{Ledger: 10}, ""},
+		{"at", AccountBalancesQuery{At: "2021-10-01T00:00:00Z"}, ""},
+		{"missing", AccountBalancesQuery{}, "ledger"},
+		{"both", AccountBalancesQuery{Ledger: 10, At: "2021-10-01T00:00:00Z"}, "ledger"},
+		{"invalid at", AccountBalancesQuery{At: "2021-10-01"}, "at"},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := testCase.query.Validate()
+			if testCase.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+			p, ok := err.(*problem.P)
+			if assert.True(t, ok) {
+				assert.Equal(t, testCase.field, p.Extras["invalid_field"])
+			}
+		})
+	}
+}
+
+func TestAccountBalancesAt(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &history.Q{tt.AuroraSession()}
+
+	tt.Assert.NoError(q.UpsertAccounts(tt.Ctx, []history.AccountEntry{account1, account2}))
+	tt.Assert.NoError(q.UpsertTrustLines(tt.Ctx, []history.TrustLine{eurTrustLine, usdTrustLine}))
+
+	native := balanceAsset{assetType: xdr.AssetTypeAssetTypeNative}
+	eur := balanceAsset{assetType: euro.Type, assetCode: "EUR", assetIssuer: trustLineIssuer}
+	usdAsset := balanceAsset{assetType: usd.Type, assetCode: "USD", assetIssuer: trustLineIssuer}
+	batch := q.NewAccountBalanceBatchInsertBuilder(10)
+	tt.Assert.NoError(batch.Add(tt.Ctx,
+		// the native balance changed twice after ledger 10
+		history.AccountBalance{
+			LedgerToid:      toid.New(11, 0, 0).ToInt64(),
+			AccountID:       accountOne,
+			AssetType:       native.assetType,
+			Balance:         null.IntFrom(25000),
+			PreviousBalance: null.IntFrom(30000),
+		},
+		history.AccountBalance{
+			LedgerToid:      toid.New(12, 0, 0).ToInt64(),
+			AccountID:       accountOne,
+			AssetType:       native.assetType,
+			Balance:         null.IntFrom(account1.Balance),
+			PreviousBalance: null.IntFrom(25000),
+		},
+		// the EUR trust line was created after ledger 10
+		history.AccountBalance{
+			LedgerToid:  toid.New(11, 0, 0).ToInt64(),
+			AccountID:   accountOne,
+			AssetType:   eur.assetType,
+			AssetCode:   eur.assetCode,
+			AssetIssuer: eur.assetIssuer,
+			Balance:     null.IntFrom(eurTrustLine.Balance),
+		},
+		// the USD trust line was removed after ledger 10
+		history.AccountBalance{
+			LedgerToid:      toid.New(12, 0, 0).ToInt64(),
+			AccountID:       accountOne,
+			AssetType:       usdAsset.assetType,
+			AssetCode:       usdAsset.assetCode,
+			AssetIssuer:     usdAsset.assetIssuer,
+			PreviousBalance: null.IntFrom(500),
+		},
+		// changes until ledger 10 are not reverted
+		history.AccountBalance{
+			LedgerToid:      toid.New(10, 0, 0).ToInt64(),
+			AccountID:       accountTwo,
+			AssetType:       native.assetType,
+			Balance:         null.IntFrom(account2.Balance),
+			PreviousBalance: null.IntFrom(1),
+		},
+	))
+	tt.Assert.NoError(batch.Exec(tt.Ctx))
+
+	balances, err := accountBalancesAt(tt.Ctx, q, accountOne, 10)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(map[balanceAsset]int64{native: 30000, usdAsset: 500}, balances)
+
+	balances, err = accountBalancesAt(tt.Ctx, q, accountOne, 11)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(map[balanceAsset]int64{
+		native:   25000,
+		eur:      eurTrustLine.Balance,
+		usdAsset: 500,
+	}, balances)
+
+	// the balances of accounts without changes after the ledger are their
+	// current balances
+	balances, err = accountBalancesAt(tt.Ctx, q, accountTwo, 10)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(map[balanceAsset]int64{native: account2.Balance, usdAsset: usdTrustLine.Balance}, balances)
+}
@@ -0,0 +1,105 @@
+package actions
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	protocol "github.com/hcnet/go/protocols/aurora"
+	auroraContext "github.com/hcnet/go/services/aurora/internal/context"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/support/render/problem"
+)
+
+// MaxAccountsBatchSize is the maximum number of accounts which can be loaded
+// by a single /accounts/batch request.
+const MaxAccountsBatchSize = 200
+
+// maxAccountsBatchBodySize is the maximum size in bytes of the body of an
+// /accounts/batch request, it's large enough for MaxAccountsBatchSize
+// account IDs.
+const maxAccountsBatchBodySize = 32 * 1024
+
+// GetAccountsBatchHandler is the action handler for the /accounts/batch
+// endpoint
+type GetAccountsBatchHandler struct{}
+
+// GetResource returns the accounts with the IDs listed in the JSON request
+// body. Accounts which don't exist are reported in the missing list instead
+// of failing the request.
+func (handler GetAccountsBatchHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ids, err := accountsBatchIDs(r)
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := r.Context()
+	records, err := historyQ.GetAccountsByIDs(ctx, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading account records")
+	}
+	byID := make(map[string]history.AccountEntry, len(records))
+	for _, record := range records {
+		byID[record.AccountID] = record
+	}
+
+	batch := protocol.AccountsBatch{Missing: []string{}}
+	ordered := make([]history.AccountEntry, 0, len(records))
+	for _, id := range ids {
+		if record, ok := byID[id]; ok {
+			ordered = append(ordered, record)
+		} else {
+			batch.Missing = append(batch.Missing, id)
+		}
+	}
+
+	batch.Accounts, err = loadAccountResources(ctx, historyQ, ordered)
+	if err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// accountsBatchIDs returns the deduplicated account IDs of a batch request.
+func accountsBatchIDs(r *http.Request) ([]string, error) {
+	var request protocol.AccountsBatchRequest
+	body := http.MaxBytesReader(nil, r.Body, maxAccountsBatchBodySize)
+	if err := json.NewDecoder(body).Decode(&request); err != nil {
+		return nil, problem.NewProblemWithInvalidField(
+			problem.BadRequest,
+			"reason",
+			fmt.Errorf("invalid json for accounts batch %v", err.Error()),
+		)
+	}
+
+	if len(request.IDs) == 0 {
+		return nil, problem.MakeInvalidFieldProblem("ids", errors.New("at least one account ID is required"))
+	}
+
+	if len(request.IDs) > MaxAccountsBatchSize {
+		return nil, problem.MakeInvalidFieldProblem(
+			"ids",
+			fmt.Errorf("at most %d account IDs can be requested", MaxAccountsBatchSize),
+		)
+	}
+
+	ids := make([]string, 0, len(request.IDs))
+	seen := map[string]bool{}
+	for _, id := range request.IDs {
+		if !isAccountID(id) {
+			return nil, problem.MakeInvalidFieldProblem("ids", fmt.Errorf("invalid account ID %q", id))
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,67 @@
+package actions
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	protocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/services/aurora/internal/test"
+	"github.com/hcnet/go/support/render/problem"
+)
+
+func TestGetAccountsBatchHandler(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+
+	q := &history.Q{SessionInterface: tt.AuroraSession()}
+	tt.Assert.NoError(q.UpsertAccounts(tt.Ctx, []history.AccountEntry{account1, account2}))
+	missing := "GCXKG6RN4ONIEPCMNFB732A436Z5PNDSRLGWK7GBLCMQLIFO4S7EYWVU"
+
+	r := makeRequest(t, map[string]string{}, map[string]string{}, q)
+	r.Body = ioutil.NopCloser(strings.NewReader(
+		`{"ids": ["` + accountTwo + `", "` + missing + `", "` + accountOne + `", "` + accountTwo + `"]}`,
+	))
+	resource, err := GetAccountsBatchHandler{}.GetResource(httptest.NewRecorder(), r)
+	tt.Assert.NoError(err)
+
+	batch := resource.(protocol.AccountsBatch)
+	tt.Assert.Len(batch.Accounts, 2)
+	tt.Assert.Equal(accountTwo, batch.Accounts[0].ID)
+	tt.Assert.Equal(accountOne, batch.Accounts[1].ID)
+	tt.Assert.Equal([]string{missing}, batch.Missing)
+}
+
+func TestAccountsBatchIDs(t *testing.T) {
+	request := func(body string) error {
+		r := httptest.NewRequest("POST", "/accounts/batch", strings.NewReader(body))
+		_, err := accountsBatchIDs(r)
+		return err
+	}
+
+	assert.NoError(t, request(`{"ids": ["`+accountOne+`"]}`))
+
+	ids := make([]string, MaxAccountsBatchSize+1)
+	for i := range ids {
+		ids[i] = `"` + accountOne + `"`
+	}
+	assert.NoError(t, request(`{"ids": [`+strings.Join(ids[:MaxAccountsBatchSize], ",")+`]}`))
+
+	for _, body := range []string{
+		`{`,
+		`{"ids": []}`,
+		`{"ids": ["GABC"]}`,
+		// duplicates are counted before being removed
+		`{"ids": [` + strings.Join(ids, ",") + `]}`,
+		`{"ids": ["` + accountOne + `"], "padding": "` + strings.Repeat("a", maxAccountsBatchBodySize) + `"}`,
+	} {
+		err := request(body)
+		_, ok := err.(*problem.P)
+		assert.True(t, ok, body)
+	}
+}
@@ -0,0 +1,286 @@
+package actions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	hProtocol "github.com/hcnet/go/protocols/aurora"
+	auroraContext "github.com/hcnet/go/services/aurora/internal/context"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/support/render/problem"
+)
+
+// APIKeyUsageTracker tracks the usage of the API keys by the requests served
+// by Aurora.
+type APIKeyUsageTracker interface {
+	// APIKeyUsage returns the usage of an API key since Aurora started.
+	APIKeyUsage(id int64) hProtocol.APIKeyUsage
+	// ReloadAPIKeys makes the changes to API keys take effect immediately.
+	ReloadAPIKeys()
+}
+
+// APIKeysHandler manages API keys, these admin HTTP endpoints are documented
+// in services/aurora/internal/httpx/static/admin_oapi.yml
+type APIKeysHandler struct {
+	UsageTracker APIKeyUsageTracker
+}
+
+// GetAPIKeys returns all API keys.
+func (handler APIKeysHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	keys, err := historyQ.APIKeys(r.Context())
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	responsePayload := make([]hProtocol.APIKey, 0, len(keys))
+	for _, key := range keys {
+		responsePayload = append(responsePayload, handler.apiKeyResource(key, ""))
+	}
+	enc := json.NewEncoder(w)
+	if err = enc.Encode(responsePayload); err != nil {
+		problem.Render(r.Context(), w, err)
+	}
+}
+
+// GetAPIKey returns the API key with the id in the path.
+func (handler APIKeysHandler) GetAPIKey(w http.ResponseWriter, r *http.Request) {
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	id, err := handler.apiKeyID(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	key, err := historyQ.APIKeyByID(r.Context(), id)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	if err = enc.Encode(handler.apiKeyResource(key, "")); err != nil {
+		problem.Render(r.Context(), w, err)
+	}
+}
+
+// CreateAPIKey generates an API key, the key is only returned in the response
+// of this endpoint, only its hash is stored.
+func (handler APIKeysHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	keyRequest, err := handler.apiKeyRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	key, err := handler.apiKeyRow(keyRequest, history.APIKey{})
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+	rawKey := hex.EncodeToString(secret)
+	key.KeyHash = history.HashAPIKey(rawKey)
+
+	key, err = historyQ.InsertAPIKey(r.Context(), key)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+	handler.UsageTracker.ReloadAPIKeys()
+
+	w.WriteHeader(http.StatusCreated)
+	enc := json.NewEncoder(w)
+	if err = enc.Encode(handler.apiKeyResource(key, rawKey)); err != nil {
+		problem.Render(r.Context(), w, err)
+	}
+}
+
+// UpdateAPIKey replaces the name, the quotas and the weights of the API key
+// with the id in the path.
+func (handler APIKeysHandler) UpdateAPIKey(w http.ResponseWriter, r *http.Request) {
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	id, err := handler.apiKeyID(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	keyRequest, err := handler.apiKeyRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	key, err := handler.apiKeyRow(keyRequest, history.APIKey{ID: id})
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	key, err = historyQ.UpdateAPIKey(r.Context(), key)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+	handler.UsageTracker.ReloadAPIKeys()
+
+	enc := json.NewEncoder(w)
+	if err = enc.Encode(handler.apiKeyResource(key, "")); err != nil {
+		problem.Render(r.Context(), w, err)
+	}
+}
+
+// DeleteAPIKey removes the API key with the id in the path.
+func (handler APIKeysHandler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	id, err := handler.apiKeyID(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	if err = historyQ.DeleteAPIKey(r.Context(), id); err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+	handler.UsageTracker.ReloadAPIKeys()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAPIKeyUsage returns the usage of the API key with the id in the path
+// since this Aurora instance started.
+func (handler APIKeysHandler) GetAPIKeyUsage(w http.ResponseWriter, r *http.Request) {
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	id, err := handler.apiKeyID(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	if _, err = historyQ.APIKeyByID(r.Context(), id); err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	if err = enc.Encode(handler.UsageTracker.APIKeyUsage(id)); err != nil {
+		problem.Render(r.Context(), w, err)
+	}
+}
+
+func (handler APIKeysHandler) apiKeyID(r *http.Request) (int64, error) {
+	param, _ := getURLParam(r, "id")
+	id, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return 0, problem.NewProblemWithInvalidField(problem.BadRequest, "id", fmt.Errorf("invalid api key id"))
+	}
+	return id, nil
+}
+
+func (handler APIKeysHandler) apiKeyRequest(r *http.Request) (hProtocol.APIKey, error) {
+	var keyRequest hProtocol.APIKey
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&keyRequest); err != nil {
+		p := problem.NewProblemWithInvalidField(problem.BadRequest, "reason", fmt.Errorf("invalid json for api key %v", err.Error()))
+		return hProtocol.APIKey{}, p
+	}
+	return keyRequest, nil
+}
+
+// apiKeyRow validates an API key request and applies it to an API key row.
+func (handler APIKeysHandler) apiKeyRow(keyRequest hProtocol.APIKey, key history.APIKey) (history.APIKey, error) {
+	if strings.TrimSpace(keyRequest.Name) == "" {
+		return key, problem.NewProblemWithInvalidField(problem.BadRequest, "name", fmt.Errorf("name is required"))
+	}
+	key.Name = keyRequest.Name
+
+	if keyRequest.PerSecondLimit < 0 {
+		return key, problem.NewProblemWithInvalidField(problem.BadRequest, "per_second_limit", fmt.Errorf("per_second_limit can't be negative"))
+	}
+	if keyRequest.PerHourLimit < 0 {
+		return key, problem.NewProblemWithInvalidField(problem.BadRequest, "per_hour_limit", fmt.Errorf("per_hour_limit can't be negative"))
+	}
+	key.PerSecondLimit = keyRequest.PerSecondLimit
+	key.PerHourLimit = keyRequest.PerHourLimit
+
+	key.Weights = history.APIKeyWeights{}
+	for class, weight := range keyRequest.Weights {
+		if !handler.isRouteClass(class) {
+			return key, problem.NewProblemWithInvalidField(problem.BadRequest, "weights", fmt.Errorf("unknown route class %s, route classes are %s", class, strings.Join(history.RouteClasses, ", ")))
+		}
+		if weight < 0 {
+			return key, problem.NewProblemWithInvalidField(problem.BadRequest, "weights", fmt.Errorf("weight of route class %s can't be negative", class))
+		}
+		key.Weights[class] = weight
+	}
+	return key, nil
+}
+
+func (handler APIKeysHandler) isRouteClass(class string) bool {
+	for _, routeClass := range history.RouteClasses {
+		if routeClass == class {
+			return true
+		}
+	}
+	return false
+}
+
+func (handler APIKeysHandler) apiKeyResource(key history.APIKey, rawKey string) hProtocol.APIKey {
+	resource := hProtocol.APIKey{
+		ID:             key.ID,
+		Name:           key.Name,
+		Key:            rawKey,
+		PerSecondLimit: key.PerSecondLimit,
+		PerHourLimit:   key.PerHourLimit,
+		Weights:        map[string]int{},
+		CreatedAt:      key.CreatedAt,
+		UpdatedAt:      key.UpdatedAt,
+	}
+	for class, weight := range key.Weights {
+		resource.Weights[class] = weight
+	}
+	return resource
+}
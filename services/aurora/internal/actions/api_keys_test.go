@@ -0,0 +1,117 @@
+package actions
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	hProtocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/services/aurora/internal/test"
+)
+
+type testAPIKeyUsageTracker struct {
+	reloads int
+}
+
+func (tracker *testAPIKeyUsageTracker) APIKeyUsage(id int64) hProtocol.APIKeyUsage {
+	return hProtocol.APIKeyUsage{ID: id, Requests: map[string]int64{"default": 3}, Limited: map[string]int64{}}
+}
+
+func (tracker *testAPIKeyUsageTracker) ReloadAPIKeys() {
+	tracker.reloads++
+}
+
+func TestAPIKeysHandler(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+
+	q := &history.Q{SessionInterface: tt.AuroraSession()}
+	tracker := &testAPIKeyUsageTracker{}
+	handler := APIKeysHandler{UsageTracker: tracker}
+
+	request := func(body string, routeParams map[string]string) *http.Request {
+		r := makeRequest(t, map[string]string{}, routeParams, q)
+		r.Body = ioutil.NopCloser(strings.NewReader(body))
+		return r
+	}
+	decode := func(recorder *httptest.ResponseRecorder, dest interface{}) {
+		raw, err := ioutil.ReadAll(recorder.Result().Body)
+		tt.Require.NoError(err)
+		tt.Require.NoError(json.Unmarshal(raw, dest))
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.CreateAPIKey(recorder, request(`{
+		"name": "partner",
+		"per_second_limit": 10,
+		"per_hour_limit": 1000,
+		"weights": {"paths": 20}
+	}`, map[string]string{}))
+	tt.Assert.Equal(http.StatusCreated, recorder.Code)
+	var created hProtocol.APIKey
+	decode(recorder, &created)
+	tt.Assert.Len(created.Key, 64)
+	tt.Assert.Equal("partner", created.Name)
+	tt.Assert.Equal(map[string]int{"paths": 20}, created.Weights)
+	tt.Assert.Equal(1, tracker.reloads)
+
+	key, err := q.APIKeyByID(tt.Ctx, created.ID)
+	tt.Require.NoError(err)
+	tt.Assert.Equal(history.HashAPIKey(created.Key), key.KeyHash)
+
+	id := map[string]string{"id": strconv.FormatInt(created.ID, 10)}
+
+	recorder = httptest.NewRecorder()
+	handler.UpdateAPIKey(recorder, request(`{"name": "renamed", "per_hour_limit": 5}`, id))
+	tt.Assert.Equal(http.StatusOK, recorder.Code)
+	var updated hProtocol.APIKey
+	decode(recorder, &updated)
+	tt.Assert.Empty(updated.Key)
+	tt.Assert.Equal("renamed", updated.Name)
+	tt.Assert.Equal(0, updated.PerSecondLimit)
+	tt.Assert.Equal(5, updated.PerHourLimit)
+	tt.Assert.Empty(updated.Weights)
+
+	recorder = httptest.NewRecorder()
+	handler.GetAPIKeys(recorder, request("", map[string]string{}))
+	var keys []hProtocol.APIKey
+	decode(recorder, &keys)
+	tt.Assert.Equal([]hProtocol.APIKey{updated}, keys)
+
+	recorder = httptest.NewRecorder()
+	handler.GetAPIKeyUsage(recorder, request("", id))
+	tt.Assert.Equal(http.StatusOK, recorder.Code)
+	var usage hProtocol.APIKeyUsage
+	decode(recorder, &usage)
+	tt.Assert.Equal(created.ID, usage.ID)
+	tt.Assert.Equal(map[string]int64{"default": 3}, usage.Requests)
+
+	for _, body := range []string{
+		`{"name": ""}`,
+		`{"name": "partner", "per_second_limit": -1}`,
+		`{"name": "partner", "weights": {"unknown": 1}}`,
+		`{"name": "partner", "weights": {"paths": -1}}`,
+		`{`,
+	} {
+		recorder = httptest.NewRecorder()
+		handler.CreateAPIKey(recorder, request(body, map[string]string{}))
+		tt.Assert.Equal(http.StatusBadRequest, recorder.Code, body)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.DeleteAPIKey(recorder, request("", id))
+	tt.Assert.Equal(http.StatusNoContent, recorder.Code)
+
+	_, err = q.APIKeyByID(tt.Ctx, created.ID)
+	tt.Assert.True(q.NoRows(err))
+
+	recorder = httptest.NewRecorder()
+	handler.GetAPIKeyUsage(recorder, request("", id))
+	tt.Assert.Equal(http.StatusNotFound, recorder.Code)
+}
@@ -18,30 +18,18 @@ import (
 	"github.com/hcnet/go/xdr"
 )
 
+// AssetStatsQuery query struct for the /assets end-point
+type AssetStatsQuery struct {
+	AssetCode   string `schema:"asset_code" valid:"assetCode~asset_code is not a valid asset code,optional"`
+	AssetIssuer string `schema:"asset_issuer" valid:"accountID~asset_issuer is not a valid asset issuer,optional"`
+}
+
 // AssetStatsHandler is the action handler for the /asset endpoint
 type AssetStatsHandler struct {
 	LedgerState *ledger.State
 }
 
-func (handler AssetStatsHandler) validateAssetParams(code, issuer string, pq db2.PageQuery) error {
-	if code != "" {
-		if !xdr.ValidAssetCode.MatchString(code) {
-			return problem.MakeInvalidFieldProblem(
-				"asset_code",
-				fmt.Errorf("%s is not a valid asset code", code),
-			)
-		}
-	}
-
-	if issuer != "" {
-		if _, err := xdr.AddressToAccountId(issuer); err != nil {
-			return problem.MakeInvalidFieldProblem(
-				"asset_issuer",
-				fmt.Errorf("%s is not a valid asset issuer", issuer),
-			)
-		}
-	}
-
+func (handler AssetStatsHandler) validateCursor(pq db2.PageQuery) error {
 	if pq.Cursor != "" {
 		parts := strings.SplitN(pq.Cursor, "_", 3)
 		if len(parts) != 3 {
@@ -117,13 +105,8 @@ func (handler AssetStatsHandler) GetResourcePage(
 ) ([]hal.Pageable, error) {
 	ctx := r.Context()
 
-	code, err := getString(r, "asset_code")
-	if err != nil {
-		return nil, err
-	}
-
-	issuer, err := getString(r, "asset_issuer")
-	if err != nil {
+	qp := AssetStatsQuery{}
+	if err := getParams(&qp, r); err != nil {
 		return nil, err
 	}
 
@@ -132,7 +115,7 @@ func (handler AssetStatsHandler) GetResourcePage(
 		return nil, err
 	}
 
-	if err = handler.validateAssetParams(code, issuer, pq); err != nil {
+	if err = handler.validateCursor(pq); err != nil {
 		return nil, err
 	}
 
@@ -141,7 +124,7 @@ func (handler AssetStatsHandler) GetResourcePage(
 		return nil, err
 	}
 
-	assetStats, err := historyQ.GetAssetStats(ctx, code, issuer, pq)
+	assetStats, err := historyQ.GetAssetStats(ctx, qp.AssetCode, qp.AssetIssuer, pq)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,137 @@
+package actions
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/hcnet/go/amount"
+	protocol "github.com/hcnet/go/protocols/aurora"
+	auroraContext "github.com/hcnet/go/services/aurora/internal/context"
+	"github.com/hcnet/go/services/aurora/internal/db2"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/services/aurora/internal/ledger"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/support/render/hal"
+	"github.com/hcnet/go/support/render/problem"
+	"github.com/hcnet/go/xdr"
+)
+
+// AssetHoldersQuery query struct for the /assets/{asset}/holders end-point
+type AssetHoldersQuery struct {
+	AssetFilter   string `schema:"asset" valid:"asset"`
+	MinBalance    string `schema:"min_balance" valid:"amount,optional"`
+	Authorization string `schema:"authorization" valid:"-"`
+}
+
+// URITemplate returns a rfc6570 URI template the query struct
+func (q AssetHoldersQuery) URITemplate() string {
+	return "/assets/{asset}/holders{?min_balance,authorization,cursor,limit,order}"
+}
+
+// Validate runs custom validations.
+func (q AssetHoldersQuery) Validate() error {
+	if q.AssetFilter == "native" {
+		return problem.MakeInvalidFieldProblem(
+			"asset",
+			errors.New("native is not held in trust lines, use an issued asset"),
+		)
+	}
+	switch q.Authorization {
+	case "",
+		history.AuthorizationAuthorized,
+		history.AuthorizationAuthorizedToMaintainLiabilities,
+		history.AuthorizationUnauthorized:
+	default:
+		return problem.MakeInvalidFieldProblem(
+			"authorization",
+			errors.New("authorization must be authorized, authorized_to_maintain_liabilities or unauthorized"),
+		)
+	}
+	return nil
+}
+
+func (q AssetHoldersQuery) historyQuery(pq db2.PageQuery) history.AssetHoldersQuery {
+	parts := strings.Split(q.AssetFilter, ":")
+	query := history.AssetHoldersQuery{
+		PageQuery:     pq,
+		Asset:         xdr.MustNewCreditAsset(parts[0], parts[1]),
+		Authorization: q.Authorization,
+	}
+	if q.MinBalance != "" {
+		query.MinBalance = int64(amount.MustParse(q.MinBalance))
+	}
+	return query
+}
+
+// GetAssetHoldersHandler is the action handler for the /assets/{asset}/holders
+// endpoint
+type GetAssetHoldersHandler struct {
+	LedgerState *ledger.State
+}
+
+// GetResourcePage returns a page of the accounts and liquidity pools holding
+// the asset ordered by balance.
+func (handler GetAssetHoldersHandler) GetResourcePage(
+	w HeaderWriter,
+	r *http.Request,
+) ([]hal.Pageable, error) {
+	pq, err := GetPageQuery(handler.LedgerState, r, DisableCursorValidation)
+	if err != nil {
+		return nil, err
+	}
+	query, err := assetHoldersQueryFromRequest(r, pq)
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := historyQ.GetAssetHolders(r.Context(), query)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading asset holders")
+	}
+
+	holders := make([]hal.Pageable, 0, len(records))
+	for _, record := range records {
+		holders = append(holders, newAssetHolder(record))
+	}
+	return holders, nil
+}
+
+func assetHoldersQueryFromRequest(r *http.Request, pq db2.PageQuery) (history.AssetHoldersQuery, error) {
+	qp := AssetHoldersQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return history.AssetHoldersQuery{}, err
+	}
+
+	query := qp.historyQuery(pq)
+	if _, _, err := query.Cursor(); err != nil {
+		return history.AssetHoldersQuery{}, problem.MakeInvalidFieldProblem(
+			"cursor",
+			errors.New("The first part should be a balance and the second part should be an account or liquidity pool ID"),
+		)
+	}
+	return query, nil
+}
+
+func newAssetHolder(record history.AssetHolder) protocol.AssetHolder {
+	holder := protocol.AssetHolder{
+		ID:      record.HolderID,
+		PT:      record.PagingToken(),
+		Type:    record.HolderType,
+		Balance: amount.StringFromInt64(record.Balance),
+	}
+	if record.HolderType == history.AssetHolderAccount {
+		flags := xdr.TrustLineFlags(record.Flags)
+		isAuthorized := flags.IsAuthorized()
+		isAuthorizedToMaintainLiabilities := flags.IsAuthorizedToMaintainLiabilitiesFlag()
+		isClawbackEnabled := flags.IsClawbackEnabledFlag()
+		holder.IsAuthorized = &isAuthorized
+		holder.IsAuthorizedToMaintainLiabilities = &isAuthorizedToMaintainLiabilities
+		holder.IsClawbackEnabled = &isClawbackEnabled
+	}
+	return holder
+}
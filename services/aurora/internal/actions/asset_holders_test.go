@@ -0,0 +1,50 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	protocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+)
+
+func TestAssetHoldersQueryValidate(t *testing.T) {
+	issuer := "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H"
+	assert.NoError(t, AssetHoldersQuery{AssetFilter: "USD:" + issuer}.Validate())
+	assert.NoError(t, AssetHoldersQuery{
+		AssetFilter:   "USD:" + issuer,
+		Authorization: history.AuthorizationAuthorizedToMaintainLiabilities,
+	}.Validate())
+	assert.Error(t, AssetHoldersQuery{AssetFilter: "native"}.Validate())
+	assert.Error(t, AssetHoldersQuery{AssetFilter: "USD:" + issuer, Authorization: "all"}.Validate())
+}
+
+func TestNewAssetHolder(t *testing.T) {
+	isTrue, isFalse := true, false
+	assert.Equal(t, protocol.AssetHolder{
+		ID:                                "GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB",
+		PT:                                "12345-GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB",
+		Type:                              history.AssetHolderAccount,
+		Balance:                           "0.0012345",
+		IsAuthorized:                      &isTrue,
+		IsAuthorizedToMaintainLiabilities: &isFalse,
+		IsClawbackEnabled:                 &isTrue,
+	}, newAssetHolder(history.AssetHolder{
+		HolderType: history.AssetHolderAccount,
+		HolderID:   "GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB",
+		Balance:    12345,
+		Flags:      5,
+	}))
+
+	assert.Equal(t, protocol.AssetHolder{
+		ID:      "abcdef",
+		PT:      "10000000-abcdef",
+		Type:    history.AssetHolderLiquidityPool,
+		Balance: "1.0000000",
+	}, newAssetHolder(history.AssetHolder{
+		HolderType: history.AssetHolderLiquidityPool,
+		HolderID:   "abcdef",
+		Balance:    10000000,
+	}))
+}
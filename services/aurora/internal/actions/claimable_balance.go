@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/hcnet/go/protocols/aurora"
 	protocol "github.com/hcnet/go/protocols/aurora"
@@ -42,6 +43,10 @@ func (handler GetClaimableBalanceByIDHandler) GetResource(w HeaderWriter, r *htt
 	if err != nil {
 		return nil, err
 	}
+	_, latestClosedAt, err := historyQ.LatestLedgerSequenceClosedAt(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "LatestLedgerSequenceClosedAt error")
+	}
 	ledger := &history.Ledger{}
 	err = historyQ.LedgerBySequence(ctx,
 		ledger,
@@ -54,7 +59,7 @@ func (handler GetClaimableBalanceByIDHandler) GetResource(w HeaderWriter, r *htt
 	}
 
 	var resource protocol.ClaimableBalance
-	err = resourceadapter.PopulateClaimableBalance(ctx, &resource, cb, ledger)
+	err = resourceadapter.PopulateClaimableBalance(ctx, &resource, cb, ledger, latestClosedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -67,6 +72,18 @@ type ClaimableBalancesQuery struct {
 	AssetFilter    string `schema:"asset" valid:"asset,optional"`
 	SponsorFilter  string `schema:"sponsor" valid:"accountID,optional"`
 	ClaimantFilter string `schema:"claimant" valid:"accountID,optional"`
+	ClaimableNow   bool   `schema:"claimable_now" valid:"-"`
+}
+
+// Validate runs custom validations.
+func (q ClaimableBalancesQuery) Validate() error {
+	if q.ClaimableNow && q.ClaimantFilter == "" {
+		return problem.MakeInvalidFieldProblem(
+			"claimable_now",
+			errors.New("claimable_now requires a claimant filter"),
+		)
+	}
+	return nil
 }
 
 func (q ClaimableBalancesQuery) asset() *xdr.Asset {
@@ -144,7 +161,11 @@ func (handler GetClaimableBalancesHandler) GetResourcePage(
 		return nil, err
 	}
 
-	claimableBalances, err := getClaimableBalancesPage(ctx, historyQ, query)
+	claimableNowBy := ""
+	if qp.ClaimableNow {
+		claimableNowBy = qp.ClaimantFilter
+	}
+	claimableBalances, err := getClaimableBalancesPage(ctx, historyQ, query, claimableNowBy)
 	if err != nil {
 		return nil, err
 	}
@@ -152,32 +173,72 @@ func (handler GetClaimableBalancesHandler) GetResourcePage(
 	return claimableBalances, nil
 }
 
-func getClaimableBalancesPage(ctx context.Context, historyQ *history.Q, query history.ClaimableBalancesQuery) ([]hal.Pageable, error) {
-	records, err := historyQ.GetClaimableBalances(ctx, query)
+// maxClaimableNowQueries is the maximum number of pages of claimable balances
+// loaded from the DB to fill a page of balances which are claimable now.
+const maxClaimableNowQueries = 10
+
+// getClaimableBalancesPage loads a page of claimable balances, when
+// claimableNowBy is set only the balances which can be claimed by that
+// claimant at the close time of the latest ledger are included and the
+// balances are loaded until the page is full. At most maxClaimableNowQueries
+// pages are loaded, the page can then be partial and the next page continues
+// after the last balance loaded, which is set as the scanned cursor.
+func getClaimableBalancesPage(ctx context.Context, historyQ *history.Q, query history.ClaimableBalancesQuery, claimableNowBy string) ([]hal.Pageable, error) {
+	_, latestClosedAt, err := historyQ.LatestLedgerSequenceClosedAt(ctx)
 	if err != nil {
-		return nil, err
-	}
-
-	ledgerCache := history.LedgerCache{}
-	for _, record := range records {
-		ledgerCache.Queue(int32(record.LastModifiedLedger))
-	}
-	if err := ledgerCache.Load(ctx, historyQ); err != nil {
-		return nil, errors.Wrap(err, "failed to load ledger batch")
+		return nil, errors.Wrap(err, "LatestLedgerSequenceClosedAt error")
 	}
 
 	var claimableBalances []hal.Pageable
-	for _, record := range records {
-		var response aurora.ClaimableBalance
+	for queries := 1; ; queries++ {
+		records, err := historyQ.GetClaimableBalances(ctx, query)
+		if err != nil {
+			return nil, err
+		}
 
-		var ledger *history.Ledger
-		if l, ok := ledgerCache.Records[int32(record.LastModifiedLedger)]; ok {
-			ledger = &l
+		ledgerCache := history.LedgerCache{}
+		for _, record := range records {
+			ledgerCache.Queue(int32(record.LastModifiedLedger))
+		}
+		if err := ledgerCache.Load(ctx, historyQ); err != nil {
+			return nil, errors.Wrap(err, "failed to load ledger batch")
+		}
+
+		for _, record := range records {
+			var response aurora.ClaimableBalance
+
+			var ledger *history.Ledger
+			if l, ok := ledgerCache.Records[int32(record.LastModifiedLedger)]; ok {
+				ledger = &l
+			}
+
+			resourceadapter.PopulateClaimableBalance(ctx, &response, record, ledger, latestClosedAt)
+			query.PageQuery.Cursor = response.PagingToken()
+			if claimableNowBy != "" && !isClaimableNowBy(response, claimableNowBy, latestClosedAt) {
+				continue
+			}
+
+			claimableBalances = append(claimableBalances, response)
+			if uint64(len(claimableBalances)) == query.PageQuery.Limit {
+				return claimableBalances, nil
+			}
 		}
 
-		resourceadapter.PopulateClaimableBalance(ctx, &response, record, ledger)
-		claimableBalances = append(claimableBalances, response)
+		if claimableNowBy == "" || uint64(len(records)) < query.PageQuery.Limit {
+			return claimableBalances, nil
+		}
+		if queries == maxClaimableNowQueries {
+			SetScannedCursor(ctx, query.PageQuery.Cursor)
+			return claimableBalances, nil
+		}
 	}
+}
 
-	return claimableBalances, nil
+func isClaimableNowBy(balance aurora.ClaimableBalance, claimant string, latestClosedAt time.Time) bool {
+	for _, c := range balance.Claimants {
+		if c.Destination == claimant && c.ClaimableWindow != nil && c.ClaimableWindow.Contains(latestClosedAt) {
+			return true
+		}
+	}
+	return false
 }
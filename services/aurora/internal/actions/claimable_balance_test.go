@@ -3,6 +3,7 @@ package actions
 import (
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/guregu/null"
 	protocol "github.com/hcnet/go/protocols/aurora"
@@ -526,6 +527,166 @@ func TestGetClaimableBalances(t *testing.T) {
 	tt.Assert.Len(response, 2)
 }
 
+func TestGetClaimableBalancesClaimableNow(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &history.Q{SessionInterface: tt.AuroraSession()}
+
+	closeTime := time.Now().Unix()
+	_, err := q.InsertLedger(tt.Ctx, xdr.LedgerHeaderHistoryEntry{
+		Header: xdr.LedgerHeader{
+			LedgerSeq: 1235,
+			ScpValue: xdr.HcnetValue{
+				CloseTime: xdr.TimePoint(closeTime),
+			},
+		},
+	}, 0, 0, 0, 0, 0)
+	tt.Assert.NoError(err)
+
+	claimant := "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML"
+	expired := xdr.Int64(closeTime - 10)
+	notExpired := xdr.Int64(closeTime + 10)
+	predicates := []xdr.ClaimPredicate{
+		{Type: xdr.ClaimPredicateTypeClaimPredicateBeforeAbsoluteTime, AbsBefore: &expired},
+		{Type: xdr.ClaimPredicateTypeClaimPredicateBeforeAbsoluteTime, AbsBefore: &notExpired},
+		{Type: xdr.ClaimPredicateTypeClaimPredicateBeforeAbsoluteTime, AbsBefore: &expired},
+		{Type: xdr.ClaimPredicateTypeClaimPredicateUnconditional},
+	}
+	var hCBs []history.ClaimableBalance
+	claimantsInsertBuilder := q.NewClaimableBalanceClaimantBatchInsertBuilder(10)
+	for i, predicate := range predicates {
+		cb := buildClaimableBalance(tt, xdr.Hash{byte(i + 1)}, claimant, 1235, nil)
+		cb.Claimants[0].Predicate = predicate
+		hCBs = append(hCBs, cb)
+		tt.Assert.NoError(claimantsInsertBuilder.Add(tt.Ctx, history.ClaimableBalanceClaimant{
+			BalanceID:          cb.BalanceID,
+			Destination:        claimant,
+			LastModifiedLedger: cb.LastModifiedLedger,
+		}))
+	}
+	tt.Assert.NoError(q.UpsertClaimableBalances(tt.Ctx, hCBs))
+	tt.Assert.NoError(claimantsInsertBuilder.Exec(tt.Ctx))
+
+	handler := GetClaimableBalancesHandler{}
+	response, err := handler.GetResourcePage(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{"claimant": claimant},
+		map[string]string{},
+		q,
+	))
+	tt.Assert.NoError(err)
+	tt.Assert.Len(response, 4)
+	tt.Assert.Nil(response[0].(protocol.ClaimableBalance).Claimants[0].ClaimableWindow)
+	window := response[1].(protocol.ClaimableBalance).Claimants[0].ClaimableWindow
+	tt.Assert.Nil(window.Start)
+	tt.Assert.Equal(int64(notExpired), window.End.Unix())
+
+	// the balances are loaded until the page is full
+	response, err = handler.GetResourcePage(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{"claimant": claimant, "claimable_now": "true", "limit": "1"},
+		map[string]string{},
+		q,
+	))
+	tt.Assert.NoError(err)
+	tt.Assert.Len(response, 1)
+	tt.Assert.Equal(hCBs[1].BalanceID, response[0].(protocol.ClaimableBalance).BalanceID)
+
+	response, err = handler.GetResourcePage(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{
+			"claimant":      claimant,
+			"claimable_now": "true",
+			"limit":         "1",
+			"cursor":        response[0].PagingToken(),
+		},
+		map[string]string{},
+		q,
+	))
+	tt.Assert.NoError(err)
+	tt.Assert.Len(response, 1)
+	tt.Assert.Equal(hCBs[3].BalanceID, response[0].(protocol.ClaimableBalance).BalanceID)
+
+	_, err = handler.GetResourcePage(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{"claimable_now": "true"},
+		map[string]string{},
+		q,
+	))
+	tt.Assert.Error(err)
+}
+
+func TestGetClaimableBalancesClaimableNowScanLimit(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &history.Q{SessionInterface: tt.AuroraSession()}
+
+	closeTime := time.Now().Unix()
+	_, err := q.InsertLedger(tt.Ctx, xdr.LedgerHeaderHistoryEntry{
+		Header: xdr.LedgerHeader{
+			LedgerSeq: 1235,
+			ScpValue: xdr.HcnetValue{
+				CloseTime: xdr.TimePoint(closeTime),
+			},
+		},
+	}, 0, 0, 0, 0, 0)
+	tt.Assert.NoError(err)
+
+	claimant := "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML"
+	expired := xdr.Int64(closeTime - 10)
+	var hCBs []history.ClaimableBalance
+	claimantsInsertBuilder := q.NewClaimableBalanceClaimantBatchInsertBuilder(10)
+	for i := 0; i <= maxClaimableNowQueries; i++ {
+		cb := buildClaimableBalance(tt, xdr.Hash{byte(i + 1)}, claimant, 1235, nil)
+		cb.Claimants[0].Predicate = xdr.ClaimPredicate{
+			Type:      xdr.ClaimPredicateTypeClaimPredicateBeforeAbsoluteTime,
+			AbsBefore: &expired,
+		}
+		hCBs = append(hCBs, cb)
+		tt.Assert.NoError(claimantsInsertBuilder.Add(tt.Ctx, history.ClaimableBalanceClaimant{
+			BalanceID:          cb.BalanceID,
+			Destination:        claimant,
+			LastModifiedLedger: cb.LastModifiedLedger,
+		}))
+	}
+	tt.Assert.NoError(q.UpsertClaimableBalances(tt.Ctx, hCBs))
+	tt.Assert.NoError(claimantsInsertBuilder.Exec(tt.Ctx))
+
+	// the page is partial when the balances loaded can't be claimed now, the
+	// next page continues after the last balance loaded
+	handler := GetClaimableBalancesHandler{}
+	r := makeRequest(
+		t,
+		map[string]string{"claimant": claimant, "claimable_now": "true", "limit": "1"},
+		map[string]string{},
+		q,
+	)
+	ctx, scanned := WithScannedCursor(r.Context())
+	response, err := handler.GetResourcePage(httptest.NewRecorder(), r.WithContext(ctx))
+	tt.Assert.NoError(err)
+	tt.Assert.Empty(response)
+	tt.Assert.Equal("1235-"+hCBs[maxClaimableNowQueries-1].BalanceID, scanned.PagingToken)
+
+	r = makeRequest(
+		t,
+		map[string]string{
+			"claimant":      claimant,
+			"claimable_now": "true",
+			"limit":         "1",
+			"cursor":        scanned.PagingToken,
+		},
+		map[string]string{},
+		q,
+	)
+	ctx, scanned = WithScannedCursor(r.Context())
+	response, err = handler.GetResourcePage(httptest.NewRecorder(), r.WithContext(ctx))
+	tt.Assert.NoError(err)
+	tt.Assert.Empty(response)
+	tt.Assert.Empty(scanned.PagingToken)
+}
+
 func TestCursorAndOrderValidation(t *testing.T) {
 	tt := test.Start(t)
 	defer tt.Finish()
@@ -576,7 +737,7 @@ func TestCursorAndOrderValidation(t *testing.T) {
 
 func TestClaimableBalancesQueryURLTemplate(t *testing.T) {
 	tt := assert.New(t)
-	expected := "/claimable_balances{?asset,sponsor,claimant,cursor,limit,order}"
+	expected := "/claimable_balances{?asset,sponsor,claimant,claimable_now,cursor,limit,order}"
 	q := ClaimableBalancesQuery{}
 	tt.Equal(expected, q.URITemplate())
 }
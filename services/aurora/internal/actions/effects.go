@@ -16,11 +16,14 @@ import (
 
 // EffectsQuery query struct for effects end-points
 type EffectsQuery struct {
+	TimeRange       `valid:"optional"`
+	TypeFilter      `valid:"optional"`
 	AccountID       string `schema:"account_id" valid:"accountID,optional"`
 	OperationID     uint64 `schema:"op_id" valid:"-"`
 	LiquidityPoolID string `schema:"liquidity_pool_id" valid:"sha256,optional"`
 	TxHash          string `schema:"tx_id" valid:"transactionHash,optional"`
 	LedgerID        uint32 `schema:"ledger_id" valid:"-"`
+	Sponsor         string `schema:"sponsor" valid:"accountID,optional"`
 }
 
 // Validate runs extra validations on query parameters
@@ -31,6 +34,7 @@ func (qp EffectsQuery) Validate() error {
 		qp.LiquidityPoolID,
 		qp.TxHash,
 		qp.LedgerID,
+		qp.Sponsor,
 	)
 
 	if err != nil {
@@ -40,10 +44,25 @@ func (qp EffectsQuery) Validate() error {
 	if count > 1 {
 		return problem.MakeInvalidFieldProblem(
 			"filters",
-			errors.New("Use a single filter for effects, you can only use one of account_id, op_id, tx_id or ledger_id"),
+			errors.New("Use a single filter for effects, you can only use one of account_id, op_id, tx_id, ledger_id or sponsor"),
 		)
 	}
-	return nil
+
+	types, err := qp.EffectTypes()
+	if err != nil {
+		return err
+	}
+
+	// Effects of a liquidity pool are paged by prefetching the ids of the
+	// pool operations so further filtering would return incomplete pages.
+	if qp.LiquidityPoolID != "" && (len(types) > 0 || qp.TimeRange.IsSet()) {
+		return problem.MakeInvalidFieldProblem(
+			"liquidity_pool_id",
+			errors.New("liquidity_pool_id cannot be combined with type, start_time or end_time"),
+		)
+	}
+
+	return qp.TimeRange.Validate()
 }
 
 type GetEffectsHandler struct {
@@ -108,6 +127,20 @@ func loadEffectRecords(ctx context.Context, hq *history.Q, qp EffectsQuery, pq d
 		effects.ForLedger(ctx, int32(qp.LedgerID))
 	case qp.TxHash != "":
 		effects.ForTransaction(ctx, qp.TxHash)
+	case qp.Sponsor != "":
+		effects.ForSponsor(qp.Sponsor)
+	}
+
+	if qp.TimeRange.IsSet() {
+		start, end, err := qp.LedgerRange(ctx, hq)
+		if err != nil {
+			return nil, err
+		}
+		effects.ForLedgerRange(start, end)
+	}
+
+	if types, _ := qp.EffectTypes(); len(types) > 0 {
+		effects.ForTypes(types)
 	}
 
 	var result []history.Effect
@@ -56,6 +56,30 @@ func SetLastLedgerHeader(w HeaderWriter, lastLedger uint32) {
 	w.Header().Set(LastLedgerHeaderName, strconv.FormatUint(uint64(lastLedger), 10))
 }
 
+var scannedCursorContextKey = auroraContext.CtxKey("scanned_cursor")
+
+// ScannedCursor is the paging token of the last record scanned by a page
+// action which filters the records it loads. When the action stops scanning
+// before the page is full, the next page must continue after it rather than
+// after the last record of the page.
+type ScannedCursor struct {
+	PagingToken string
+}
+
+// WithScannedCursor returns a copy of ctx in which page actions can set the
+// scanned cursor of the page with SetScannedCursor.
+func WithScannedCursor(ctx context.Context) (context.Context, *ScannedCursor) {
+	cursor := &ScannedCursor{}
+	return context.WithValue(ctx, &scannedCursorContextKey, cursor), cursor
+}
+
+// SetScannedCursor sets the scanned cursor of the page loaded with ctx.
+func SetScannedCursor(ctx context.Context, pagingToken string) {
+	if cursor, ok := ctx.Value(&scannedCursorContextKey).(*ScannedCursor); ok {
+		cursor.PagingToken = pagingToken
+	}
+}
+
 // getCursor retrieves a string from either the URLParams, form or query string.
 // This method uses the priority (URLParams, Form, Query).
 func getCursor(ledgerState *ledger.State, r *http.Request, name string) (string, error) {
@@ -0,0 +1,208 @@
+package actions
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/services/aurora/internal/resourceadapter"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/support/render/problem"
+	"github.com/hcnet/go/support/time"
+	"github.com/hcnet/go/xdr"
+)
+
+// TimeRange query struct for the start_time and end_time query parameters
+// of history end-points. Both are milliseconds since epoch and are
+// translated to a range of ledgers using the ledger close times.
+type TimeRange struct {
+	StartTime time.Millis `schema:"start_time" valid:"-"`
+	EndTime   time.Millis `schema:"end_time" valid:"-"`
+}
+
+// Validate checks the time range is not empty.
+func (q TimeRange) Validate() error {
+	if !q.StartTime.IsNil() && !q.EndTime.IsNil() && q.EndTime <= q.StartTime {
+		return problem.MakeInvalidFieldProblem(
+			"end_time",
+			errors.New("end_time must be greater than start_time"),
+		)
+	}
+	return nil
+}
+
+// IsSet returns true if start_time or end_time was provided.
+func (q TimeRange) IsSet() bool {
+	return !q.StartTime.IsNil() || !q.EndTime.IsNil()
+}
+
+// LedgerRange returns the range [start, end) of ledger sequences closed at or
+// after start_time and before end_time.
+func (q TimeRange) LedgerRange(ctx context.Context, historyQ *history.Q) (int32, int32, error) {
+	start, end := int32(0), int32(math.MaxInt32)
+	if !q.StartTime.IsNil() {
+		closedBefore, err := historyQ.LatestLedgerClosedBefore(ctx, q.StartTime.ToTime())
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "loading ledger closed before start_time")
+		}
+		start = closedBefore + 1
+	}
+	if !q.EndTime.IsNil() {
+		closedBefore, err := historyQ.LatestLedgerClosedBefore(ctx, q.EndTime.ToTime())
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "loading ledger closed before end_time")
+		}
+		end = closedBefore + 1
+	}
+	return start, end, nil
+}
+
+// TypeFilter query struct for the type query parameter of history end-points.
+// The parameter can be repeated and each value can hold a comma separated
+// list of types.
+type TypeFilter struct {
+	Types []string `schema:"type" valid:"-"`
+}
+
+// typeNames returns the deduplicated type names in the filter.
+func (q TypeFilter) typeNames() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, value := range q.Types {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// OperationTypes returns the operation types in the filter.
+func (q TypeFilter) OperationTypes() ([]xdr.OperationType, error) {
+	var types []xdr.OperationType
+	for _, name := range q.typeNames() {
+		operationType, ok := operationTypeByName(name)
+		if !ok {
+			return nil, problem.MakeInvalidFieldProblem(
+				"type",
+				fmt.Errorf("unknown operation type %q", name),
+			)
+		}
+		types = append(types, operationType)
+	}
+	return types, nil
+}
+
+// EffectTypes returns the effect types in the filter.
+func (q TypeFilter) EffectTypes() ([]history.EffectType, error) {
+	var types []history.EffectType
+	for _, name := range q.typeNames() {
+		effectType, ok := effectTypeByName(name)
+		if !ok {
+			return nil, problem.MakeInvalidFieldProblem(
+				"type",
+				fmt.Errorf("unknown effect type %q", name),
+			)
+		}
+		types = append(types, effectType)
+	}
+	return types, nil
+}
+
+// MemoFilter query struct for the memo and memo_type query parameters of
+// transactions and payments end-points.
+type MemoFilter struct {
+	Memo     string `schema:"memo" valid:"-"`
+	MemoType string `schema:"memo_type" valid:"-"`
+}
+
+// IsSet returns true if memo or memo_type was provided.
+func (q MemoFilter) IsSet() bool {
+	return q.Memo != "" || q.MemoType != ""
+}
+
+// XDRMemo parses the memo in the filter. Ids are decimal numbers and hashes
+// (memo_type hash or return) can be hex or base64 encoded.
+func (q MemoFilter) XDRMemo() (xdr.Memo, error) {
+	if q.MemoType == "" {
+		return xdr.Memo{}, problem.MakeInvalidFieldProblem(
+			"memo_type",
+			errors.New("memo_type is required when filtering by memo"),
+		)
+	}
+	if q.Memo == "" {
+		return xdr.Memo{}, problem.MakeInvalidFieldProblem(
+			"memo",
+			errors.New("memo is required when filtering by memo_type"),
+		)
+	}
+
+	switch q.MemoType {
+	case "text":
+		if len(q.Memo) > 28 {
+			return xdr.Memo{}, problem.MakeInvalidFieldProblem(
+				"memo",
+				errors.New("text memos can't be longer than 28 bytes"),
+			)
+		}
+		return xdr.MemoText(q.Memo), nil
+	case "id":
+		id, err := strconv.ParseUint(q.Memo, 10, 64)
+		if err != nil {
+			return xdr.Memo{}, problem.MakeInvalidFieldProblem(
+				"memo",
+				errors.New("id memos must be unsigned 64-bit integers"),
+			)
+		}
+		return xdr.MemoID(id), nil
+	case "hash", "return":
+		hash, ok := parseMemoHash(q.Memo)
+		if !ok {
+			return xdr.Memo{}, problem.MakeInvalidFieldProblem(
+				"memo",
+				errors.New("hash and return memos must be 32 bytes encoded in hex or base64"),
+			)
+		}
+		if q.MemoType == "hash" {
+			return xdr.MemoHash(hash), nil
+		}
+		return xdr.MemoRetHash(hash), nil
+	default:
+		return xdr.Memo{}, problem.MakeInvalidFieldProblem(
+			"memo_type",
+			errors.New("memo_type must be text, id, hash or return"),
+		)
+	}
+}
+
+func parseMemoHash(value string) (xdr.Hash, bool) {
+	var hash xdr.Hash
+	decode := base64.StdEncoding.DecodeString
+	if len(value) == hex.EncodedLen(len(hash)) {
+		decode = hex.DecodeString
+	}
+	raw, err := decode(value)
+	if err != nil || len(raw) != len(hash) {
+		return hash, false
+	}
+	copy(hash[:], raw)
+	return hash, true
+}
+
+func effectTypeByName(name string) (history.EffectType, bool) {
+	for effectType, typeName := range resourceadapter.EffectTypeNames {
+		if typeName == name {
+			return effectType, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,125 @@
+package actions
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/xdr"
+)
+
+func TestTypeFilter(t *testing.T) {
+	filter := TypeFilter{Types: []string{"payment,create_account", "payment", " path_payment_strict_send "}}
+	types, err := filter.OperationTypes()
+	assert.NoError(t, err)
+	assert.Equal(t, []xdr.OperationType{
+		xdr.OperationTypePayment,
+		xdr.OperationTypeCreateAccount,
+		xdr.OperationTypePathPaymentStrictSend,
+	}, types)
+
+	_, err = TypeFilter{Types: []string{"account_credited"}}.OperationTypes()
+	assert.Error(t, err)
+
+	effectTypes, err := TypeFilter{Types: []string{"account_credited", "account_debited"}}.EffectTypes()
+	assert.NoError(t, err)
+	assert.Equal(t, []history.EffectType{history.EffectAccountCredited, history.EffectAccountDebited}, effectTypes)
+
+	_, err = TypeFilter{Types: []string{"payment"}}.EffectTypes()
+	assert.Error(t, err)
+
+	types, err = TypeFilter{}.OperationTypes()
+	assert.NoError(t, err)
+	assert.Empty(t, types)
+}
+
+func TestTimeRangeValidate(t *testing.T) {
+	assert.NoError(t, TimeRange{}.Validate())
+	assert.NoError(t, TimeRange{StartTime: 1000}.Validate())
+	assert.NoError(t, TimeRange{EndTime: 1000}.Validate())
+	assert.NoError(t, TimeRange{StartTime: 1000, EndTime: 2000}.Validate())
+	assert.Error(t, TimeRange{StartTime: 2000, EndTime: 2000}.Validate())
+	assert.Error(t, TimeRange{StartTime: 2000, EndTime: 1000}.Validate())
+
+	assert.False(t, TimeRange{}.IsSet())
+	assert.True(t, TimeRange{EndTime: 1000}.IsSet())
+}
+
+func TestHistoryFiltersValidate(t *testing.T) {
+	poolID := "cafebabedeadbeef000000000000000000000000000000000000000000000000"
+	assert.NoError(t, EffectsQuery{
+		AccountID:  "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H",
+		TimeRange:  TimeRange{StartTime: 1000},
+		TypeFilter: TypeFilter{Types: []string{"account_credited"}},
+	}.Validate())
+	assert.NoError(t, EffectsQuery{LiquidityPoolID: poolID}.Validate())
+	assert.Error(t, EffectsQuery{
+		LiquidityPoolID: poolID,
+		TypeFilter:      TypeFilter{Types: []string{"account_credited"}},
+	}.Validate())
+	assert.Error(t, EffectsQuery{
+		LiquidityPoolID: poolID,
+		TimeRange:       TimeRange{EndTime: 1000},
+	}.Validate())
+
+	assert.NoError(t, OperationsQuery{TypeFilter: TypeFilter{Types: []string{"payment"}}}.Validate())
+	assert.Error(t, OperationsQuery{TypeFilter: TypeFilter{Types: []string{"unknown"}}}.Validate())
+	assert.Error(t, TransactionsQuery{TimeRange: TimeRange{StartTime: 2, EndTime: 1}}.Validate())
+}
+
+func TestHistoryFiltersParams(t *testing.T) {
+	r := httptest.NewRequest("GET", "/operations?type=payment&type=create_account,payment&start_time=1000&end_time=2000", nil)
+	qp := OperationsQuery{}
+	assert.NoError(t, getParams(&qp, r))
+	types, err := qp.OperationTypes()
+	assert.NoError(t, err)
+	assert.Equal(t, []xdr.OperationType{xdr.OperationTypePayment, xdr.OperationTypeCreateAccount}, types)
+	assert.Equal(t, TimeRange{StartTime: 1000, EndTime: 2000}, qp.TimeRange)
+
+	r = httptest.NewRequest("GET", "/effects?type=trade&start_time=2000&end_time=1000", nil)
+	assert.Error(t, getParams(&EffectsQuery{}, r))
+}
+
+func TestMemoFilter(t *testing.T) {
+	var hash xdr.Hash
+	for i := range hash {
+		hash[i] = 1
+	}
+
+	for _, testCase := range []struct {
+		filter   MemoFilter
+		expected xdr.Memo
+	}{
+		{MemoFilter{MemoType: "text", Memo: "hello"}, xdr.MemoText("hello")},
+		{MemoFilter{MemoType: "id", Memo: "123"}, xdr.MemoID(123)},
+		{MemoFilter{MemoType: "hash", Memo: "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE="}, xdr.MemoHash(hash)},
+		{MemoFilter{MemoType: "hash", Memo: strings.Repeat("01", 32)}, xdr.MemoHash(hash)},
+		{MemoFilter{MemoType: "return", Memo: strings.Repeat("01", 32)}, xdr.MemoRetHash(hash)},
+	} {
+		memo, err := testCase.filter.XDRMemo()
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expected, memo)
+	}
+
+	for _, filter := range []MemoFilter{
+		{Memo: "hello"},
+		{MemoType: "text"},
+		{MemoType: "none", Memo: "hello"},
+		{MemoType: "text", Memo: strings.Repeat("a", 29)},
+		{MemoType: "id", Memo: "-1"},
+		{MemoType: "id", Memo: "abc"},
+		{MemoType: "hash", Memo: "0101"},
+		{MemoType: "return", Memo: "AQEB"},
+	} {
+		_, err := filter.XDRMemo()
+		assert.Error(t, err, filter)
+	}
+
+	assert.False(t, MemoFilter{}.IsSet())
+	assert.Error(t, TransactionsQuery{MemoFilter: MemoFilter{Memo: "123"}}.Validate())
+	assert.NoError(t, TransactionsQuery{MemoFilter: MemoFilter{MemoType: "id", Memo: "123"}}.Validate())
+	assert.Error(t, OperationsQuery{MemoFilter: MemoFilter{MemoType: "id", Memo: "x"}}.Validate())
+}
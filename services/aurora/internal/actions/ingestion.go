@@ -0,0 +1,95 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	hProtocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/services/aurora/internal/ingest"
+	"github.com/hcnet/go/support/render/problem"
+)
+
+// IngestionController controls the ingestion state machine of a running
+// Aurora instance.
+type IngestionController interface {
+	Status() ingest.Status
+	TriggerStateRebuild(ctx context.Context) error
+	Pause()
+	Resume()
+}
+
+// these admin HTTP endpoints are documented in services/aurora/internal/httpx/static/admin_oapi.yml
+type IngestionAdminHandler struct {
+	Ingester IngestionController
+}
+
+func (handler IngestionAdminHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	handler.renderStatus(w, r)
+}
+
+func (handler IngestionAdminHandler) TriggerStateRebuild(w http.ResponseWriter, r *http.Request) {
+	if err := handler.Ingester.TriggerStateRebuild(r.Context()); err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+	handler.renderStatus(w, r)
+}
+
+func (handler IngestionAdminHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	handler.Ingester.Pause()
+	handler.renderStatus(w, r)
+}
+
+func (handler IngestionAdminHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	handler.Ingester.Resume()
+	handler.renderStatus(w, r)
+}
+
+func (handler IngestionAdminHandler) renderStatus(w http.ResponseWriter, r *http.Request) {
+	responsePayload := handler.statusResource(handler.Ingester.Status(), time.Now())
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(responsePayload); err != nil {
+		problem.Render(r.Context(), w, err)
+	}
+}
+
+func (handler IngestionAdminHandler) statusResource(status ingest.Status, now time.Time) hProtocol.IngestionStatus {
+	resource := hProtocol.IngestionStatus{
+		State:          status.State,
+		StateEnteredAt: status.StateEnteredAt,
+		Paused:         status.Paused,
+		CurrentLedger:  status.CurrentLedger,
+		Transitions:    make([]hProtocol.IngestionStateTransition, 0, len(status.Transitions)),
+		Ledgers:        make([]hProtocol.IngestionLedgerStatus, 0, len(status.Ledgers)),
+	}
+	if !status.StateEnteredAt.IsZero() {
+		resource.TimeInStateSeconds = now.Sub(status.StateEnteredAt).Seconds()
+	}
+
+	for _, transition := range status.Transitions {
+		resource.Transitions = append(resource.Transitions, hProtocol.IngestionStateTransition{
+			From:            transition.From,
+			To:              transition.To,
+			At:              transition.At,
+			DurationSeconds: transition.Duration.Seconds(),
+			Error:           transition.Error,
+		})
+	}
+
+	for _, ledger := range status.Ledgers {
+		processorDurations := make(map[string]float64, len(ledger.ProcessorDurations))
+		for name, duration := range ledger.ProcessorDurations {
+			processorDurations[name] = duration.Seconds()
+		}
+		resource.Ledgers = append(resource.Ledgers, hProtocol.IngestionLedgerStatus{
+			Sequence:                  ledger.Sequence,
+			ProcessedAt:               ledger.ProcessedAt,
+			DurationSeconds:           ledger.Duration.Seconds(),
+			ProcessorDurationsSeconds: processorDurations,
+		})
+	}
+
+	return resource
+}
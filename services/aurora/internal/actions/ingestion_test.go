@@ -0,0 +1,126 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	hProtocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/services/aurora/internal/ingest"
+	"github.com/hcnet/go/support/errors"
+)
+
+type mockIngestionController struct {
+	mock.Mock
+}
+
+func (m *mockIngestionController) Status() ingest.Status {
+	args := m.Called()
+	return args.Get(0).(ingest.Status)
+}
+
+func (m *mockIngestionController) TriggerStateRebuild(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *mockIngestionController) Pause() {
+	m.Called()
+}
+
+func (m *mockIngestionController) Resume() {
+	m.Called()
+}
+
+func TestIngestionAdminHandlerGetStatus(t *testing.T) {
+	enteredAt := time.Now().Add(-time.Minute)
+	controller := &mockIngestionController{}
+	controller.On("Status").Return(ingest.Status{
+		State:          "resume(latestSuccessfullyProcessedLedger=100)",
+		StateEnteredAt: enteredAt,
+		CurrentLedger:  101,
+		Transitions: []ingest.StateTransition{
+			{
+				From:     "start",
+				To:       "resume(latestSuccessfullyProcessedLedger=100)",
+				At:       enteredAt,
+				Duration: 2 * time.Second,
+				Error:    "my error",
+			},
+		},
+		Ledgers: []ingest.LedgerStatus{
+			{
+				Sequence:           100,
+				ProcessedAt:        enteredAt,
+				Duration:           time.Second,
+				ProcessorDurations: map[string]time.Duration{"processors.EffectProcessor": 500 * time.Millisecond},
+			},
+		},
+	}).Once()
+
+	handler := IngestionAdminHandler{Ingester: controller}
+	recorder := httptest.NewRecorder()
+	handler.GetStatus(recorder, httptest.NewRequest(http.MethodGet, "/ingestion/status", nil))
+
+	resp := recorder.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	raw, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	var status hProtocol.IngestionStatus
+	require.NoError(t, json.Unmarshal(raw, &status))
+	assert.Equal(t, "resume(latestSuccessfullyProcessedLedger=100)", status.State)
+	assert.Equal(t, uint32(101), status.CurrentLedger)
+	assert.False(t, status.Paused)
+	assert.True(t, status.TimeInStateSeconds >= 60)
+	require.Len(t, status.Transitions, 1)
+	assert.Equal(t, "start", status.Transitions[0].From)
+	assert.Equal(t, float64(2), status.Transitions[0].DurationSeconds)
+	assert.Equal(t, "my error", status.Transitions[0].Error)
+	require.Len(t, status.Ledgers, 1)
+	assert.Equal(t, uint32(100), status.Ledgers[0].Sequence)
+	assert.Equal(t, map[string]float64{"processors.EffectProcessor": 0.5}, status.Ledgers[0].ProcessorDurationsSeconds)
+	controller.AssertExpectations(t)
+}
+
+func TestIngestionAdminHandlerPauseResume(t *testing.T) {
+	controller := &mockIngestionController{}
+	controller.On("Pause").Once()
+	controller.On("Status").Return(ingest.Status{State: "start", Paused: true}).Once()
+	controller.On("Resume").Once()
+	controller.On("Status").Return(ingest.Status{State: "start"}).Once()
+
+	handler := IngestionAdminHandler{Ingester: controller}
+
+	recorder := httptest.NewRecorder()
+	handler.Pause(recorder, httptest.NewRequest(http.MethodPost, "/ingestion/pause", nil))
+	var status hProtocol.IngestionStatus
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.True(t, status.Paused)
+
+	recorder = httptest.NewRecorder()
+	handler.Resume(recorder, httptest.NewRequest(http.MethodPost, "/ingestion/resume", nil))
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.False(t, status.Paused)
+	controller.AssertExpectations(t)
+}
+
+func TestIngestionAdminHandlerTriggerStateRebuildError(t *testing.T) {
+	controller := &mockIngestionController{}
+	controller.On("TriggerStateRebuild", mock.Anything).Return(errors.New("db error")).Once()
+
+	handler := IngestionAdminHandler{Ingester: controller}
+	recorder := httptest.NewRecorder()
+	handler.TriggerStateRebuild(recorder, httptest.NewRequest(http.MethodPost, "/ingestion/rebuild", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+	controller.AssertExpectations(t)
+}
@@ -3,16 +3,21 @@ package actions
 import (
 	"context"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	gTime "time"
 
 	protocol "github.com/hcnet/go/protocols/aurora"
 	auroraContext "github.com/hcnet/go/services/aurora/internal/context"
+	"github.com/hcnet/go/services/aurora/internal/db2"
 	"github.com/hcnet/go/services/aurora/internal/db2/history"
 	"github.com/hcnet/go/services/aurora/internal/ledger"
 	"github.com/hcnet/go/services/aurora/internal/resourceadapter"
 	"github.com/hcnet/go/support/errors"
 	"github.com/hcnet/go/support/render/hal"
 	"github.com/hcnet/go/support/render/problem"
+	"github.com/hcnet/go/support/time"
 	"github.com/hcnet/go/xdr"
 )
 
@@ -168,3 +173,195 @@ func (handler GetLiquidityPoolsHandler) getLiquidityPoolsPage(ctx context.Contex
 
 	return liquidityPools, nil
 }
+
+// liquidityPoolHistoryResolutions are the allowed resolutions, in
+// milliseconds, of the history of liquidity pools.
+var liquidityPoolHistoryResolutions = map[uint64]bool{
+	uint64(gTime.Hour / gTime.Millisecond):      true,
+	uint64(24 * gTime.Hour / gTime.Millisecond): true,
+}
+
+// LiquidityPoolHistoryQuery query struct for liquidity_pools/id/history end-point
+type LiquidityPoolHistoryQuery struct {
+	ID               string      `schema:"liquidity_pool_id" valid:"sha256"`
+	StartTimeFilter  time.Millis `schema:"start_time" valid:"-"`
+	EndTimeFilter    time.Millis `schema:"end_time" valid:"-"`
+	ResolutionFilter uint64      `schema:"resolution" valid:"-"`
+}
+
+// Validate runs validations on LiquidityPoolHistoryQuery
+func (q LiquidityPoolHistoryQuery) Validate() error {
+	if !liquidityPoolHistoryResolutions[q.ResolutionFilter] {
+		return problem.MakeInvalidFieldProblem(
+			"resolution",
+			errors.New("illegal or missing resolution. "+
+				"allowed resolutions are: 1 hour (3600000) and 1 day (86400000)"),
+		)
+	}
+	if !q.EndTimeFilter.IsNil() && q.EndTimeFilter <= q.StartTimeFilter {
+		return problem.MakeInvalidFieldProblem(
+			"end_time",
+			errors.New("end time must be greater than the start time"),
+		)
+	}
+	return nil
+}
+
+// GetLiquidityPoolHistoryHandler is the action handler for the history of a
+// liquidity pool: the state of the pool at the end of every period of time
+// during which it changed, with the price change and trade volumes of the
+// period.
+type GetLiquidityPoolHistoryHandler struct {
+	LedgerState *ledger.State
+}
+
+// GetResource returns a page of the history of a liquidity pool.
+func (handler GetLiquidityPoolHistoryHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	pq, err := GetPageQuery(handler.LedgerState, r, DisableCursorValidation)
+	if err != nil {
+		return nil, err
+	}
+	qp := LiquidityPoolHistoryQuery{}
+	if err = getParams(&qp, r); err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	// the pool is found in its snapshots so that the history of deleted
+	// pools is served too
+	latest, err := historyQ.LatestLiquidityPoolSnapshot(ctx, qp.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolution := int64(qp.ResolutionFilter)
+	query := history.LiquidityPoolHistoryQuery{
+		PoolID:     qp.ID,
+		Resolution: resolution,
+		// buckets are only returned when they are entirely in the time range
+		StartTime: qp.StartTimeFilter.RoundUp(resolution).ToInt64(),
+		Order:     pq.Order,
+		Limit:     pq.Limit,
+	}
+	var records []hal.Pageable
+	if !qp.EndTimeFilter.IsNil() {
+		query.EndTime = qp.EndTimeFilter.RoundDown(resolution).ToInt64()
+		if query.EndTime <= query.StartTime {
+			return handler.buildPage(r, qp, pq, records)
+		}
+	}
+
+	buckets, err := historyQ.GetLiquidityPoolHistory(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load liquidity pool history")
+	}
+	records, err = handler.populateBuckets(ctx, historyQ, [2]xdr.Asset{latest.AssetA, latest.AssetB}, query, buckets)
+	if err != nil {
+		return nil, err
+	}
+	return handler.buildPage(r, qp, pq, records)
+}
+
+func (handler GetLiquidityPoolHistoryHandler) populateBuckets(
+	ctx context.Context,
+	historyQ *history.Q,
+	reserveAssets [2]xdr.Asset,
+	query history.LiquidityPoolHistoryQuery,
+	buckets []history.LiquidityPoolHistoryBucket,
+) ([]hal.Pageable, error) {
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+
+	// the price at the opening of a bucket is the price at the closing of
+	// the previous one, the opening of the oldest bucket is loaded separately
+	chronological := make([]history.LiquidityPoolHistoryBucket, len(buckets))
+	copy(chronological, buckets)
+	sort.Slice(chronological, func(i, j int) bool {
+		return chronological[i].Timestamp < chronological[j].Timestamp
+	})
+	oldest, newest := chronological[0], chronological[len(chronological)-1]
+	opens := map[int64]*history.LiquidityPoolSnapshot{}
+	previous, err := historyQ.LiquidityPoolSnapshotBefore(ctx, query.PoolID, oldest.Timestamp)
+	if err == nil {
+		opens[oldest.Timestamp] = &previous
+	} else if !historyQ.NoRows(err) {
+		return nil, errors.Wrap(err, "could not load liquidity pool snapshot")
+	}
+	for i := 1; i < len(chronological); i++ {
+		opens[chronological[i].Timestamp] = &chronological[i-1].LiquidityPoolSnapshot
+	}
+
+	volumesQuery := query
+	volumesQuery.StartTime = oldest.Timestamp
+	volumesQuery.EndTime = newest.Timestamp + query.Resolution
+	volumes, err := historyQ.GetLiquidityPoolTradeVolumes(ctx, volumesQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load liquidity pool trade volumes")
+	}
+	volumesByBucket := map[int64][]history.LiquidityPoolTradeVolume{}
+	for _, volume := range volumes {
+		volumesByBucket[volume.Timestamp] = append(volumesByBucket[volume.Timestamp], volume)
+	}
+
+	assets := map[int64]string{}
+	for _, asset := range reserveAssets {
+		id, err := historyQ.GetAssetID(ctx, asset)
+		if historyQ.NoRows(err) {
+			// the asset was never traded
+			continue
+		} else if err != nil {
+			return nil, errors.Wrap(err, "could not load asset id")
+		}
+		assets[id] = asset.StringCanonical()
+	}
+
+	var records []hal.Pageable
+	for _, bucket := range buckets {
+		var res protocol.LiquidityPoolHistory
+		err = resourceadapter.PopulateLiquidityPoolHistory(
+			ctx, &res, reserveAssets, bucket, opens[bucket.Timestamp], volumesByBucket[bucket.Timestamp], assets,
+		)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, res)
+	}
+	return records, nil
+}
+
+// buildPage builds a custom hal page for this handler, the next page is
+// selected by time like the pages of trade aggregations.
+func (handler GetLiquidityPoolHistoryHandler) buildPage(r *http.Request, qp LiquidityPoolHistoryQuery, pq db2.PageQuery, records []hal.Pageable) (hal.Page, error) {
+	page := hal.Page{
+		Cursor: pq.Cursor,
+		Order:  pq.Order,
+		Limit:  pq.Limit,
+	}
+	page.Init()
+	for _, record := range records {
+		page.Add(record)
+	}
+
+	newURL := FullURL(r.Context())
+	q := newURL.Query()
+	page.Links.Self = hal.NewLink(newURL.String())
+
+	if len(records) == 0 {
+		page.Links.Next = page.Links.Self
+		return page, nil
+	}
+	last := records[len(records)-1].(protocol.LiquidityPoolHistory)
+	if page.Order == db2.OrderAscending {
+		q.Set("start_time", strconv.FormatInt(last.Timestamp+int64(qp.ResolutionFilter), 10))
+	} else {
+		q.Set("end_time", strconv.FormatInt(last.Timestamp, 10))
+	}
+	newURL.RawQuery = q.Encode()
+	page.Links.Next = hal.NewLink(newURL.String())
+	return page, nil
+}
@@ -0,0 +1,106 @@
+package actions
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// QueryParam describes a parameter decoded by getParams into a query struct,
+// it's used to generate the OpenAPI document of the API.
+type QueryParam struct {
+	Name        string
+	Description string
+	// Type is the OpenAPI type of the parameter: string, integer, boolean or
+	// array (of strings).
+	Type      string
+	Format    string
+	Pattern   string
+	Enum      []string
+	MinLength int
+	MaxLength int
+	Required  bool
+}
+
+// validatorParams maps the custom validators registered in govalidator to the
+// constraints they enforce.
+var validatorParams = map[string]QueryParam{
+	"accountID":          {Pattern: "^G[A-Z2-7]{55}$"},
+	"amount":             {Pattern: "^[0-9]+(\\.[0-9]{1,7})?$"},
+	"asset":              {Pattern: "^(native|[a-zA-Z0-9]{1,12}:G[A-Z2-7]{55})$"},
+	"assetCode":          {Pattern: "^[a-zA-Z0-9]{1,12}$"},
+	"assetType":          {Enum: []string{"native", "credit_alphanum4", "credit_alphanum12"}},
+	"claimableBalanceID": {Pattern: "^[0-9a-fA-F]{72}$"},
+	"sha256":             {Pattern: "^[0-9a-fA-F]{64}$"},
+	"tradeType":          {Enum: []string{"all", "orderbook", "liquidity_pool"}},
+	"transactionHash":    {Pattern: "^[0-9a-f]{64}$"},
+}
+
+// QueryParams returns the parameters of a query struct, including the ones of
+// its embedded query structs, in the order they are declared.
+func QueryParams(query interface{}) []QueryParam {
+	return queryParams(reflect.TypeOf(query))
+}
+
+func queryParams(qt reflect.Type) []QueryParam {
+	var params []QueryParam
+	for i := 0; i < qt.NumField(); i++ {
+		f := qt.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			params = append(params, queryParams(f.Type)...)
+			continue
+		}
+
+		name, ok := f.Tag.Lookup("schema")
+		if !ok || name == "-" {
+			continue
+		}
+		param := QueryParam{Name: strings.Split(name, ",")[0]}
+		switch f.Type.Kind() {
+		case reflect.Bool:
+			param.Type = "boolean"
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			param.Type = "integer"
+			param.Format = "int" + strconv.Itoa(f.Type.Bits())
+		case reflect.Slice:
+			param.Type = "array"
+		default:
+			param.Type = "string"
+		}
+		if message, ok := customTagsErrorMessages[param.Name]; ok {
+			param.Description = message
+		}
+		for _, validator := range strings.Split(f.Tag.Get("valid"), ",") {
+			applyValidator(&param, validator)
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+func applyValidator(param *QueryParam, validator string) {
+	validator = strings.Split(validator, "~")[0]
+	switch {
+	case validator == "required":
+		param.Required = true
+	case strings.HasPrefix(validator, "in(") && strings.HasSuffix(validator, ")"):
+		param.Enum = strings.Split(validator[len("in("):len(validator)-1], "|")
+	case strings.HasPrefix(validator, "length(") && strings.HasSuffix(validator, ")"):
+		bounds := strings.Split(validator[len("length("):len(validator)-1], "|")
+		if len(bounds) == 2 {
+			param.MinLength, _ = strconv.Atoi(bounds[0])
+			param.MaxLength, _ = strconv.Atoi(bounds[1])
+		}
+	default:
+		constraints, ok := validatorParams[validator]
+		if !ok {
+			return
+		}
+		param.Pattern = constraints.Pattern
+		param.Enum = constraints.Enum
+		if message, ok := customTagsErrorMessages[validator]; ok {
+			param.Description = message
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryParams(t *testing.T) {
+	params := QueryParams(OperationsQuery{})
+	byName := map[string]QueryParam{}
+	for _, param := range params {
+		byName[param.Name] = param
+	}
+
+	assert.Equal(t, QueryParam{
+		Name:     "join",
+		Type:     "string",
+		Enum:     []string{"transactions"},
+		Required: false,
+	}, byName["join"])
+	assert.Equal(t, QueryParam{
+		Name:        "account_id",
+		Description: customTagsErrorMessages["accountID"],
+		Type:        "string",
+		Pattern:     "^G[A-Z2-7]{55}$",
+	}, byName["account_id"])
+	assert.Equal(t, QueryParam{
+		Name:        "ledger_id",
+		Description: customTagsErrorMessages["ledger_id"],
+		Type:        "integer",
+		Format:      "int32",
+	}, byName["ledger_id"])
+	assert.Equal(t, "boolean", byName["include_failed"].Type)
+	assert.Equal(t, "integer", byName["start_time"].Type)
+	assert.Equal(t, "array", byName["type"].Type)
+	assert.Equal(t, "join", params[0].Name)
+
+	params = QueryParams(AccountDataQuery{})
+	assert.Equal(t, QueryParam{Name: "key", Type: "string", MinLength: 1, MaxLength: 64}, params[1])
+	assert.False(t, params[0].Required)
+
+	params = QueryParams(ClaimableBalanceQuery{})
+	assert.True(t, params[0].Required)
+	assert.Equal(t, "^[0-9a-fA-F]{72}$", params[0].Pattern)
+}
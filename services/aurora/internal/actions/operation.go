@@ -29,6 +29,9 @@ func (qp Joinable) IncludeTransactions() bool {
 // OperationsQuery query struct for operations end-points
 type OperationsQuery struct {
 	Joinable                  `valid:"optional"`
+	TimeRange                 `valid:"optional"`
+	TypeFilter                `valid:"optional"`
+	MemoFilter                `valid:"optional"`
 	AccountID                 string `schema:"account_id" valid:"accountID,optional"`
 	ClaimableBalanceID        string `schema:"claimable_balance_id" valid:"claimableBalanceID,optional"`
 	LiquidityPoolID           string `schema:"liquidity_pool_id" valid:"sha256,optional"`
@@ -58,7 +61,17 @@ func (qp OperationsQuery) Validate() error {
 		)
 	}
 
-	return nil
+	if _, err := qp.OperationTypes(); err != nil {
+		return err
+	}
+
+	if qp.MemoFilter.IsSet() {
+		if _, err := qp.XDRMemo(); err != nil {
+			return err
+		}
+	}
+
+	return qp.TimeRange.Validate()
 }
 
 // GetOperationsHandler is the action handler for all end-points returning a list of operations.
@@ -106,6 +119,24 @@ func (handler GetOperationsHandler) GetResourcePage(w HeaderWriter, r *http.Requ
 	case qp.TransactionHash != "":
 		query.ForTransaction(ctx, qp.TransactionHash)
 	}
+
+	if qp.TimeRange.IsSet() {
+		start, end, err := qp.LedgerRange(ctx, historyQ)
+		if err != nil {
+			return nil, err
+		}
+		query.ForLedgerRange(start, end)
+	}
+
+	if types, _ := qp.OperationTypes(); len(types) > 0 {
+		query.ForTypes(types)
+	}
+
+	if qp.MemoFilter.IsSet() {
+		memo, _ := qp.XDRMemo()
+		query.ForMemo(memo)
+	}
+
 	// When querying operations for transaction return both successful
 	// and failed operations. We assume that because the user is querying
 	// this specific transactions, they knows its status.
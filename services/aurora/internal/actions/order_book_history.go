@@ -0,0 +1,248 @@
+package actions
+
+import (
+	"net/http"
+	"strconv"
+	gTime "time"
+
+	protocol "github.com/hcnet/go/protocols/aurora"
+	auroraContext "github.com/hcnet/go/services/aurora/internal/context"
+	"github.com/hcnet/go/services/aurora/internal/db2"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/services/aurora/internal/ledger"
+	"github.com/hcnet/go/services/aurora/internal/resourceadapter"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/support/render/hal"
+	"github.com/hcnet/go/support/render/problem"
+	"github.com/hcnet/go/support/time"
+	"github.com/hcnet/go/xdr"
+)
+
+// orderBookDepthResolutions are the allowed resolutions, in milliseconds, of
+// the depth charts of order books.
+var orderBookDepthResolutions = map[uint64]bool{
+	uint64(gTime.Minute / gTime.Millisecond):    true,
+	uint64(gTime.Hour / gTime.Millisecond):      true,
+	uint64(24 * gTime.Hour / gTime.Millisecond): true,
+}
+
+// orderBookPair returns the selling and buying assets of an order book, both
+// of them are required.
+func orderBookPair(q SellingBuyingAssetQueryParams) (xdr.Asset, xdr.Asset, error) {
+	selling, err := q.Selling()
+	if err != nil {
+		return xdr.Asset{}, xdr.Asset{}, err
+	}
+	if selling == nil {
+		return xdr.Asset{}, xdr.Asset{}, problem.MakeInvalidFieldProblem(
+			"selling",
+			errors.New("Missing required field"),
+		)
+	}
+	buying, err := q.Buying()
+	if err != nil {
+		return xdr.Asset{}, xdr.Asset{}, err
+	}
+	if buying == nil {
+		return xdr.Asset{}, xdr.Asset{}, problem.MakeInvalidFieldProblem(
+			"buying",
+			errors.New("Missing required field"),
+		)
+	}
+	if selling.Equals(*buying) {
+		return xdr.Asset{}, xdr.Asset{}, problem.MakeInvalidFieldProblem(
+			"buying",
+			errors.New("buying and selling assets must be different"),
+		)
+	}
+	return *selling, *buying, nil
+}
+
+// orderBookSnapshotPair returns the base and counter assets under which the
+// snapshots of the order book of selling and buying are recorded, and
+// whether the snapshots must be reversed.
+func orderBookSnapshotPair(selling, buying xdr.Asset) (string, string, bool) {
+	if buying.LessThan(selling) {
+		return buying.StringCanonical(), selling.StringCanonical(), true
+	}
+	return selling.StringCanonical(), buying.StringCanonical(), false
+}
+
+// OrderBookHistoryQuery query struct for the /order_book/history end-point
+type OrderBookHistoryQuery struct {
+	SellingBuyingAssetQueryParams `valid:"-"`
+	Ledger                        uint32 `schema:"ledger" valid:"-"`
+}
+
+// Validate runs validations on OrderBookHistoryQuery
+func (q OrderBookHistoryQuery) Validate() error {
+	if err := q.SellingBuyingAssetQueryParams.Validate(); err != nil {
+		return err
+	}
+	_, _, err := orderBookPair(q.SellingBuyingAssetQueryParams)
+	return err
+}
+
+// GetOrderBookHistoryHandler is the action handler for the
+// /order_book/history end-point: the order book of a trading pair at the end
+// of a past ledger, or of the latest ledger which changed it.
+type GetOrderBookHistoryHandler struct{}
+
+// GetResource returns the snapshot of an order book at the end of a ledger.
+func (handler GetOrderBookHistoryHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	qp := OrderBookHistoryQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+	selling, buying, err := orderBookPair(qp.SellingBuyingAssetQueryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	base, counter, reverse := orderBookSnapshotPair(selling, buying)
+	snapshot, err := historyQ.GetOrderBookSnapshot(ctx, base, counter, qp.Ledger)
+	if err != nil {
+		return nil, err
+	}
+	if reverse {
+		snapshot = snapshot.Reverse()
+	}
+
+	var res protocol.OrderBookSnapshot
+	if err := resourceadapter.PopulateOrderBookSnapshot(ctx, &res, snapshot, selling, buying); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// OrderBookDepthQuery query struct for the /order_book/depth end-point
+type OrderBookDepthQuery struct {
+	SellingBuyingAssetQueryParams `valid:"-"`
+	StartTimeFilter               time.Millis `schema:"start_time" valid:"-"`
+	EndTimeFilter                 time.Millis `schema:"end_time" valid:"-"`
+	ResolutionFilter              uint64      `schema:"resolution" valid:"-"`
+}
+
+// Validate runs validations on OrderBookDepthQuery
+func (q OrderBookDepthQuery) Validate() error {
+	if err := q.SellingBuyingAssetQueryParams.Validate(); err != nil {
+		return err
+	}
+	if _, _, err := orderBookPair(q.SellingBuyingAssetQueryParams); err != nil {
+		return err
+	}
+	if !orderBookDepthResolutions[q.ResolutionFilter] {
+		return problem.MakeInvalidFieldProblem(
+			"resolution",
+			errors.New("illegal or missing resolution. "+
+				"allowed resolutions are: 1 minute (60000), 1 hour (3600000) and 1 day (86400000)"),
+		)
+	}
+	if !q.EndTimeFilter.IsNil() && q.EndTimeFilter <= q.StartTimeFilter {
+		return problem.MakeInvalidFieldProblem(
+			"end_time",
+			errors.New("end time must be greater than the start time"),
+		)
+	}
+	return nil
+}
+
+// GetOrderBookDepthHandler is the action handler for the /order_book/depth
+// end-point: the depth chart of a trading pair, with the mid price and the
+// cumulative depth at price bands at the end of every period of time during
+// which its order book changed.
+type GetOrderBookDepthHandler struct {
+	LedgerState *ledger.State
+}
+
+// GetResource returns a page of the depth chart of an order book.
+func (handler GetOrderBookDepthHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	pq, err := GetPageQuery(handler.LedgerState, r, DisableCursorValidation)
+	if err != nil {
+		return nil, err
+	}
+	qp := OrderBookDepthQuery{}
+	if err = getParams(&qp, r); err != nil {
+		return nil, err
+	}
+	selling, buying, err := orderBookPair(qp.SellingBuyingAssetQueryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	base, counter, reverse := orderBookSnapshotPair(selling, buying)
+	resolution := int64(qp.ResolutionFilter)
+	query := history.OrderBookDepthQuery{
+		BaseAsset:    base,
+		CounterAsset: counter,
+		Resolution:   resolution,
+		// buckets are only returned when they are entirely in the time range
+		StartTime: qp.StartTimeFilter.RoundUp(resolution).ToInt64(),
+		Order:     pq.Order,
+		Limit:     pq.Limit,
+	}
+	var records []hal.Pageable
+	if !qp.EndTimeFilter.IsNil() {
+		query.EndTime = qp.EndTimeFilter.RoundDown(resolution).ToInt64()
+		if query.EndTime <= query.StartTime {
+			return handler.buildPage(r, qp, pq, records)
+		}
+	}
+
+	buckets, err := historyQ.GetOrderBookDepth(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load order book depth")
+	}
+	for _, bucket := range buckets {
+		if reverse {
+			bucket.OrderBookSnapshot = bucket.Reverse()
+		}
+		var res protocol.OrderBookDepth
+		resourceadapter.PopulateOrderBookDepth(ctx, &res, bucket)
+		records = append(records, res)
+	}
+	return handler.buildPage(r, qp, pq, records)
+}
+
+// buildPage builds a custom hal page for this handler, the next page is
+// selected by time like the pages of trade aggregations.
+func (handler GetOrderBookDepthHandler) buildPage(r *http.Request, qp OrderBookDepthQuery, pq db2.PageQuery, records []hal.Pageable) (hal.Page, error) {
+	page := hal.Page{
+		Cursor: pq.Cursor,
+		Order:  pq.Order,
+		Limit:  pq.Limit,
+	}
+	page.Init()
+	for _, record := range records {
+		page.Add(record)
+	}
+
+	newURL := FullURL(r.Context())
+	q := newURL.Query()
+	page.Links.Self = hal.NewLink(newURL.String())
+
+	if len(records) == 0 {
+		page.Links.Next = page.Links.Self
+		return page, nil
+	}
+	last := records[len(records)-1].(protocol.OrderBookDepth)
+	if page.Order == db2.OrderAscending {
+		q.Set("start_time", strconv.FormatInt(last.Timestamp+int64(qp.ResolutionFilter), 10))
+	} else {
+		q.Set("end_time", strconv.FormatInt(last.Timestamp, 10))
+	}
+	newURL.RawQuery = q.Encode()
+	page.Links.Next = hal.NewLink(newURL.String())
+	return page, nil
+}
@@ -0,0 +1,138 @@
+package actions
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+
+	protocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/services/aurora/internal/ledger"
+	"github.com/hcnet/go/services/aurora/internal/test"
+	"github.com/hcnet/go/support/render/hal"
+	"github.com/hcnet/go/support/render/problem"
+	"github.com/hcnet/go/toid"
+)
+
+func TestOrderBookDepthQueryValidation(t *testing.T) {
+	for _, testCase := range []struct {
+		name  string
+		query map[string]string
+		field string
+	}{
+		{
+			"missing selling asset",
+			map[string]string{"buying": "native", "resolution": "60000"},
+			"selling",
+		},
+		{
+			"missing buying asset",
+			map[string]string{"selling": "native", "resolution": "60000"},
+			"buying",
+		},
+		{
+			"same assets",
+			map[string]string{"selling": "native", "buying": "native", "resolution": "60000"},
+			"buying",
+		},
+		{
+			"invalid resolution",
+			map[string]string{"selling": "native", "buying": usdAsset.StringCanonical(), "resolution": "1000"},
+			"resolution",
+		},
+		{
+			"end time before start time",
+			map[string]string{
+				"selling":    "native",
+				"buying":     usdAsset.StringCanonical(),
+				"resolution": "60000",
+				"start_time": "120000",
+				"end_time":   "60000",
+			},
+			"end_time",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			handler := GetOrderBookDepthHandler{LedgerState: &ledger.State{}}
+			_, err := handler.GetResource(httptest.NewRecorder(), makeRequest(t, testCase.query, map[string]string{}, nil))
+			assert.IsType(t, &problem.P{}, err)
+			p := err.(*problem.P)
+			assert.Equal(t, "bad_request", p.Type)
+			assert.Equal(t, testCase.field, p.Extras["invalid_field"])
+		})
+	}
+}
+
+func TestGetOrderBookHistory(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &history.Q{SessionInterface: tt.AuroraSession()}
+
+	closedAt := time.Date(2022, 1, 1, 0, 10, 0, 0, time.UTC)
+	snapshot := history.OrderBookSnapshot{
+		LedgerToid:   toid.New(10, 0, 0).ToInt64(),
+		BaseAsset:    "native",
+		CounterAsset: usdAsset.StringCanonical(),
+		Asks:         history.OrderBookLevels{{Pricen: 4, Priced: 1, Pricef: "4.0000000", Amount: "1.0000000"}},
+		Bids:         history.OrderBookLevels{{Pricen: 1, Priced: 1, Pricef: "1.0000000", Amount: "2.0000000"}},
+		MidPrice:     null.FloatFrom(2),
+		Depth:        history.OrderBookDepth{},
+	}
+	tt.Assert.NoError(q.InsertOrderBookSnapshots(tt.Ctx, []history.OrderBookSnapshot{snapshot}, closedAt))
+
+	handler := GetOrderBookHistoryHandler{}
+	response, err := handler.GetResource(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{"selling": "native", "buying": usdAsset.StringCanonical()},
+		map[string]string{},
+		q,
+	))
+	tt.Assert.NoError(err)
+	resource := response.(protocol.OrderBookSnapshot)
+	tt.Assert.Equal(int32(10), resource.Ledger)
+	tt.Assert.Equal("native", resource.Selling.Type)
+	tt.Assert.Equal("4.0000000", resource.Asks[0].Price)
+	tt.Assert.Equal("2.0000000", resource.MidPrice)
+
+	// the order book of the reversed pair
+	response, err = handler.GetResource(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{"selling": usdAsset.StringCanonical(), "buying": "native", "ledger": "10"},
+		map[string]string{},
+		q,
+	))
+	tt.Assert.NoError(err)
+	resource = response.(protocol.OrderBookSnapshot)
+	tt.Assert.Equal("USD", resource.Selling.Code)
+	tt.Assert.Equal("1.0000000", resource.Asks[0].Price)
+	tt.Assert.Equal("2.0000000", resource.Asks[0].Amount)
+	tt.Assert.Equal("0.2500000", resource.Bids[0].Price)
+	tt.Assert.Equal("0.5000000", resource.MidPrice)
+
+	_, err = handler.GetResource(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{"selling": "native", "buying": usdAsset.StringCanonical(), "ledger": "9"},
+		map[string]string{},
+		q,
+	))
+	tt.Assert.True(q.NoRows(err))
+
+	depthHandler := GetOrderBookDepthHandler{LedgerState: &ledger.State{}}
+	response, err = depthHandler.GetResource(httptest.NewRecorder(), makeRequest(
+		t,
+		map[string]string{"selling": "native", "buying": usdAsset.StringCanonical(), "resolution": "3600000"},
+		map[string]string{},
+		q,
+	))
+	tt.Assert.NoError(err)
+	page := response.(hal.Page)
+	tt.Assert.Len(page.Embedded.Records, 1)
+	bucket := page.Embedded.Records[0].(protocol.OrderBookDepth)
+	tt.Assert.Equal(closedAt.Truncate(time.Hour).UnixMilli(), bucket.Timestamp)
+	tt.Assert.Equal(int32(10), bucket.Ledger)
+	tt.Assert.Equal("2.0000000", bucket.MidPrice)
+}
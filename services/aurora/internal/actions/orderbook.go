@@ -60,6 +60,17 @@ var invalidOrderBook = problem.P{
 		"as buying_asset_code and buying_asset_issuer if buying_asset_type is not 'native'",
 }
 
+// OrderBookQuery query struct for the /order_book end-point
+type OrderBookQuery struct {
+	SellingAssetType   string `schema:"selling_asset_type" valid:"assetType,required"`
+	SellingAssetCode   string `schema:"selling_asset_code" valid:"length(1|12)"`
+	SellingAssetIssuer string `schema:"selling_asset_issuer" valid:"accountID"`
+	BuyingAssetType    string `schema:"buying_asset_type" valid:"assetType,required"`
+	BuyingAssetCode    string `schema:"buying_asset_code" valid:"length(1|12)"`
+	BuyingAssetIssuer  string `schema:"buying_asset_issuer" valid:"accountID"`
+	Limit              uint64 `schema:"limit" valid:"-"`
+}
+
 // GetOrderbookHandler is the action handler for the /order_book endpoint
 type GetOrderbookHandler struct {
 }
@@ -82,6 +93,9 @@ func convertPriceLevels(src []history.PriceLevel) []protocol.PriceLevel {
 
 // GetResource implements the /order_book endpoint
 func (handler GetOrderbookHandler) GetResource(w HeaderWriter, r *http.Request) (StreamableObjectResponse, error) {
+	if err := getParams(&OrderBookQuery{}, r); err != nil {
+		return nil, invalidOrderBook
+	}
 	selling, err := getAsset(r, "selling_")
 	if err != nil {
 		return nil, invalidOrderBook
@@ -3,8 +3,10 @@ package actions
 import (
 	"context"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/hcnet/go/amount"
 	"github.com/hcnet/go/protocols/aurora"
@@ -38,6 +40,8 @@ type StrictReceivePathsQuery struct {
 	DestinationAssetIssuer string `schema:"destination_asset_issuer" valid:"accountID,optional"`
 	DestinationAssetCode   string `schema:"destination_asset_code" valid:"-"`
 	DestinationAmount      string `schema:"destination_amount" valid:"amount"`
+	PathConstraintsQueryParams
+	Split bool `schema:"split" valid:"-"`
 }
 
 // Assets returns a list of xdr.Asset
@@ -100,7 +104,91 @@ func (q StrictReceivePathsQuery) Validate() error {
 		)
 	}
 
-	return nil
+	return q.PathConstraintsQueryParams.Validate()
+}
+
+// PathConstraintsQueryParams query struct for the parameters restricting the
+// assets and venues of payment paths
+type PathConstraintsQueryParams struct {
+	ExcludeAssets     string `schema:"exclude_assets" valid:"-"`
+	IncludeOnlyAssets string `schema:"include_only_assets" valid:"-"`
+	ExcludePools      string `schema:"exclude_pools" valid:"-"`
+	Venues            string `schema:"venues" valid:"-"`
+	MaxHops           uint   `schema:"max_hops" valid:"-"`
+}
+
+// Values of the venues parameter
+const (
+	PathVenuesOrderBook = "orderbook"
+	PathVenuesPools     = "pools"
+	PathVenuesBoth      = "both"
+)
+
+// Validate runs custom validations.
+func (q PathConstraintsQueryParams) Validate() error {
+	_, err := q.Constraints()
+	return err
+}
+
+// Constraints returns the restrictions on the assets and venues of the
+// payment paths.
+func (q PathConstraintsQueryParams) Constraints() (paths.Constraints, error) {
+	var constraints paths.Constraints
+	var err error
+
+	constraints.ExcludedAssets, err = xdr.BuildAssets(q.ExcludeAssets)
+	if err != nil {
+		return paths.Constraints{}, problem.MakeInvalidFieldProblem("exclude_assets", err)
+	}
+	constraints.IncludedAssets, err = xdr.BuildAssets(q.IncludeOnlyAssets)
+	if err != nil {
+		return paths.Constraints{}, problem.MakeInvalidFieldProblem("include_only_assets", err)
+	}
+
+	if q.ExcludePools != "" {
+		for _, id := range strings.Split(q.ExcludePools, ",") {
+			var poolID xdr.PoolId
+			decoded, err := hex.DecodeString(id)
+			if err != nil || len(decoded) != len(poolID) {
+				return paths.Constraints{}, problem.MakeInvalidFieldProblem(
+					"exclude_pools",
+					fmt.Errorf("%s is not a valid liquidity pool id", id),
+				)
+			}
+			copy(poolID[:], decoded)
+			constraints.ExcludedPools = append(constraints.ExcludedPools, poolID)
+		}
+	}
+
+	switch q.Venues {
+	case "", PathVenuesBoth:
+	case PathVenuesOrderBook:
+		constraints.ExcludePools = true
+	case PathVenuesPools:
+		constraints.ExcludeOffers = true
+	default:
+		return paths.Constraints{}, problem.MakeInvalidFieldProblem(
+			"venues",
+			fmt.Errorf("must be one of %s, %s or %s", PathVenuesOrderBook, PathVenuesPools, PathVenuesBoth),
+		)
+	}
+
+	return constraints, nil
+}
+
+// MaxPathLength returns the maximum length of the payment paths given the
+// maximum allowed by the server.
+func (q PathConstraintsQueryParams) MaxPathLength(limit uint) (uint, error) {
+	if q.MaxHops == 0 {
+		return limit, nil
+	}
+	if limit > 0 && q.MaxHops > limit {
+		return 0, problem.MakeInvalidFieldProblem(
+			"max_hops",
+			fmt.Errorf("must be at most %d", limit),
+		)
+	}
+	return q.MaxHops, nil
 }
 
 // SourceAssetsOrSourceAccountProblem custom error where source assets or account is required
@@ -122,10 +210,16 @@ func (handler FindPathsHandler) GetResource(w HeaderWriter, r *http.Request) (in
 		return nil, err
 	}
 
+	maxPathLength, err := qp.MaxPathLength(handler.MaxPathLength)
+	if err != nil {
+		return nil, err
+	}
+
 	query := paths.Query{}
 	query.DestinationAmount = qp.Amount()
 	sourceAccount := qp.SourceAccount
 	query.SourceAssets, _ = qp.Assets()
+	query.Constraints, _ = qp.Constraints()
 
 	if len(query.SourceAssets) > handler.MaxAssetsParamLength {
 		return nil, problem.MakeInvalidFieldProblem(
@@ -138,7 +232,7 @@ func (handler FindPathsHandler) GetResource(w HeaderWriter, r *http.Request) (in
 		sourceAccount := xdr.MustAddress(sourceAccount)
 		query.SourceAccount = &sourceAccount
 		query.ValidateSourceBalance = true
-		query.SourceAssets, query.SourceAssetBalances, err = assetsForAddress(r, query.SourceAccount.Address())
+		query.SourceAssets, query.SourceAssetBalances, _, err = assetsForAddress(r, query.SourceAccount.Address())
 		if err != nil {
 			return nil, err
 		}
@@ -160,10 +254,25 @@ func (handler FindPathsHandler) GetResource(w HeaderWriter, r *http.Request) (in
 		return nil, errors.Wrap(err, "error in rollback")
 	}
 
+	if qp.Split {
+		splitRecords := []paths.SplitPath{}
+		if len(query.SourceAssets) > 0 {
+			var lastIngestedLedger uint32
+			splitRecords, lastIngestedLedger, err = handler.PathFinder.FindSplitPaths(ctx, query, maxPathLength)
+			if err = pathFinderProblem(err); err != nil {
+				return nil, err
+			}
+			if handler.SetLastLedgerHeader {
+				SetLastLedgerHeader(w, lastIngestedLedger)
+			}
+		}
+		return renderSplitPaths(ctx, splitRecords)
+	}
+
 	records := []paths.Path{}
 	if len(query.SourceAssets) > 0 {
 		var lastIngestedLedger uint32
-		records, lastIngestedLedger, err = handler.PathFinder.Find(ctx, query, handler.MaxPathLength)
+		records, lastIngestedLedger, err = handler.PathFinder.Find(ctx, query, maxPathLength)
 		switch err {
 		case simplepath.ErrEmptyInMemoryOrderBook:
 			return nil, auroraProblem.StillIngesting
@@ -186,6 +295,38 @@ func (handler FindPathsHandler) GetResource(w HeaderWriter, r *http.Request) (in
 	return renderPaths(ctx, records)
 }
 
+// ChangedSince returns false if the strict receive paths of the request are
+// the same as in the given ledger. The paths of a source account depend on its
+// balances so they change whenever the account or its trust lines are updated.
+// It must be called in a repeatable read transaction when the request has a
+// source account.
+func (handler FindPathsHandler) ChangedSince(r *http.Request, ledger uint32) (bool, error) {
+	qp := StrictReceivePathsQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return false, err
+	}
+
+	maxPathLength, err := qp.MaxPathLength(handler.MaxPathLength)
+	if err != nil {
+		return false, err
+	}
+
+	query := paths.Query{DestinationAsset: qp.DestinationAsset()}
+	query.SourceAssets, _ = qp.Assets()
+	query.Constraints, _ = qp.Constraints()
+	if qp.SourceAccount != "" {
+		var lastModifiedLedger uint32
+		query.SourceAssets, _, lastModifiedLedger, err = assetsForAddress(r, qp.SourceAccount)
+		if err != nil {
+			return false, err
+		}
+		if accountChangedSince(lastModifiedLedger, ledger) {
+			return true, nil
+		}
+	}
+	return handler.PathFinder.PathsChangedSince(ledger, query, maxPathLength), nil
+}
+
 func renderPaths(ctx context.Context, records []paths.Path) (hal.BasePage, error) {
 	var page hal.BasePage
 	page.Init()
@@ -199,6 +340,31 @@ func renderPaths(ctx context.Context, records []paths.Path) (hal.BasePage, error
 	return page, nil
 }
 
+// pathFinderProblem converts the errors of the path finder into problems.
+func pathFinderProblem(err error) error {
+	switch err {
+	case simplepath.ErrEmptyInMemoryOrderBook:
+		return auroraProblem.StillIngesting
+	case paths.ErrRateLimitExceeded:
+		return auroraProblem.ServerOverCapacity
+	default:
+		return err
+	}
+}
+
+func renderSplitPaths(ctx context.Context, records []paths.SplitPath) (hal.BasePage, error) {
+	var page hal.BasePage
+	page.Init()
+	for _, p := range records {
+		var res aurora.SplitPath
+		if err := resourceadapter.PopulateSplitPath(ctx, &res, p); err != nil {
+			return hal.BasePage{}, err
+		}
+		page.Add(res)
+	}
+	return page, nil
+}
+
 // FindFixedPathsHandler is the http handler for the find fixed payment paths endpoint
 // Fixed payment paths are payment paths where both the source and destination asset are fixed
 type FindFixedPathsHandler struct {
@@ -225,6 +391,8 @@ type FindFixedPathsQuery struct {
 	SourceAssetIssuer  string `schema:"source_asset_issuer" valid:"accountID,optional"`
 	SourceAssetCode    string `schema:"source_asset_code" valid:"-"`
 	SourceAmount       string `schema:"source_amount" valid:"amount"`
+	PathConstraintsQueryParams
+	Split bool `schema:"split" valid:"-"`
 }
 
 // URITemplate returns a rfc6570 URI template for the query struct
@@ -258,7 +426,7 @@ func (q FindFixedPathsQuery) Validate() error {
 		)
 	}
 
-	return nil
+	return q.PathConstraintsQueryParams.Validate()
 }
 
 // Assets returns a list of xdr.Asset
@@ -300,8 +468,14 @@ func (handler FindFixedPathsHandler) GetResource(w HeaderWriter, r *http.Request
 		return nil, err
 	}
 
+	maxPathLength, err := qp.MaxPathLength(handler.MaxPathLength)
+	if err != nil {
+		return nil, err
+	}
+
 	destinationAccount := qp.DestinationAccount
 	destinationAssets, _ := qp.Assets()
+	constraints, _ := qp.Constraints()
 
 	if len(destinationAssets) > handler.MaxAssetsParamLength {
 		return nil, problem.MakeInvalidFieldProblem(
@@ -311,7 +485,7 @@ func (handler FindFixedPathsHandler) GetResource(w HeaderWriter, r *http.Request
 	}
 
 	if destinationAccount != "" {
-		destinationAssets, _, err = assetsForAddress(r, destinationAccount)
+		destinationAssets, _, _, err = assetsForAddress(r, destinationAccount)
 		if err != nil {
 			return nil, err
 		}
@@ -332,6 +506,28 @@ func (handler FindFixedPathsHandler) GetResource(w HeaderWriter, r *http.Request
 	sourceAsset := qp.SourceAsset()
 	amountToSpend := qp.Amount()
 
+	if qp.Split {
+		splitRecords := []paths.SplitPath{}
+		if len(destinationAssets) > 0 {
+			var lastIngestedLedger uint32
+			splitRecords, lastIngestedLedger, err = handler.PathFinder.FindFixedSplitPaths(
+				ctx,
+				sourceAsset,
+				amountToSpend,
+				destinationAssets,
+				maxPathLength,
+				constraints,
+			)
+			if err = pathFinderProblem(err); err != nil {
+				return nil, err
+			}
+			if handler.SetLastLedgerHeader {
+				SetLastLedgerHeader(w, lastIngestedLedger)
+			}
+		}
+		return renderSplitPaths(ctx, splitRecords)
+	}
+
 	records := []paths.Path{}
 	if len(destinationAssets) > 0 {
 		var lastIngestedLedger uint32
@@ -340,7 +536,8 @@ func (handler FindFixedPathsHandler) GetResource(w HeaderWriter, r *http.Request
 			sourceAsset,
 			amountToSpend,
 			destinationAssets,
-			handler.MaxPathLength,
+			maxPathLength,
+			constraints,
 		)
 		switch err {
 		case simplepath.ErrEmptyInMemoryOrderBook:
@@ -364,34 +561,78 @@ func (handler FindFixedPathsHandler) GetResource(w HeaderWriter, r *http.Request
 	return renderPaths(ctx, records)
 }
 
-func assetsForAddress(r *http.Request, addy string) ([]xdr.Asset, []xdr.Int64, error) {
+// ChangedSince returns false if the strict send paths of the request are the
+// same as in the given ledger. The paths to a destination account depend on
+// its trust lines so they change whenever the account or its trust lines are
+// updated. It must be called in a repeatable read transaction when the request
+// has a destination account.
+func (handler FindFixedPathsHandler) ChangedSince(r *http.Request, ledger uint32) (bool, error) {
+	qp := FindFixedPathsQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return false, err
+	}
+
+	maxPathLength, err := qp.MaxPathLength(handler.MaxPathLength)
+	if err != nil {
+		return false, err
+	}
+
+	destinationAssets, _ := qp.Assets()
+	constraints, _ := qp.Constraints()
+	if qp.DestinationAccount != "" {
+		var lastModifiedLedger uint32
+		destinationAssets, _, lastModifiedLedger, err = assetsForAddress(r, qp.DestinationAccount)
+		if err != nil {
+			return false, err
+		}
+		if accountChangedSince(lastModifiedLedger, ledger) {
+			return true, nil
+		}
+	}
+	return handler.PathFinder.FixedPathsChangedSince(
+		ledger, qp.SourceAsset(), destinationAssets, maxPathLength, constraints,
+	), nil
+}
+
+// accountChangedSince returns true if an account, last modified in
+// `lastModifiedLedger`, may have changed since the payment paths were found in
+// the given ledger of the in-memory order book. The account may have been
+// loaded from the database before it was updated in that ledger.
+func accountChangedSince(lastModifiedLedger, ledger uint32) bool {
+	return lastModifiedLedger >= ledger
+}
+
+// assetsForAddress returns the assets held by an account, their balances and
+// the last ledger in which the account or its trust lines were updated.
+func assetsForAddress(r *http.Request, addy string) ([]xdr.Asset, []xdr.Int64, uint32, error) {
 	historyQ, err := auroraContext.HistoryQFromRequest(r)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "could not obtain historyQ from request")
+		return nil, nil, 0, errors.Wrap(err, "could not obtain historyQ from request")
 	}
 	if historyQ.SessionInterface.GetTx() == nil {
-		return nil, nil, errors.New("cannot be called outside of a transaction")
+		return nil, nil, 0, errors.New("cannot be called outside of a transaction")
 	}
 	if opts := historyQ.SessionInterface.GetTxOptions(); opts == nil || !opts.ReadOnly || opts.Isolation != sql.LevelRepeatableRead {
-		return nil, nil, errors.New("should only be called in a repeatable read transaction")
+		return nil, nil, 0, errors.New("should only be called in a repeatable read transaction")
 	}
 
 	var account history.AccountEntry
 	account, err = historyQ.GetAccountByID(r.Context(), addy)
 	if historyQ.NoRows(err) {
-		return []xdr.Asset{}, []xdr.Int64{}, nil
+		return []xdr.Asset{}, []xdr.Int64{}, 0, nil
 	} else if err != nil {
-		return nil, nil, errors.Wrap(err, "could not fetch account")
+		return nil, nil, 0, errors.Wrap(err, "could not fetch account")
 	}
 
 	var trustlines []history.TrustLine
 	trustlines, err = historyQ.GetSortedTrustLinesByAccountID(r.Context(), addy)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "could not fetch trustlines for account")
+		return nil, nil, 0, errors.Wrap(err, "could not fetch trustlines for account")
 	}
 
 	var assets []xdr.Asset
 	var balances []xdr.Int64
+	lastModifiedLedger := account.LastModifiedLedger
 
 	for _, trustline := range trustlines {
 		// Ignore pool share assets because pool shares are not transferable and cannot be traded.
@@ -402,13 +643,16 @@ func assetsForAddress(r *http.Request, addy string) ([]xdr.Asset, []xdr.Int64, e
 		var asset xdr.Asset
 		asset, err = xdr.NewCreditAsset(trustline.AssetCode, trustline.AssetIssuer)
 		if err != nil {
-			return nil, nil, errors.Wrap(err, "invalid trustline asset")
+			return nil, nil, 0, errors.Wrap(err, "invalid trustline asset")
 		}
 		assets = append(assets, asset)
 		balances = append(balances, xdr.Int64(trustline.Balance))
+		if trustline.LastModifiedLedger > lastModifiedLedger {
+			lastModifiedLedger = trustline.LastModifiedLedger
+		}
 	}
 	assets = append(assets, xdr.MustNewNativeAsset())
 	balances = append(balances, xdr.Int64(account.Balance))
 
-	return assets, balances, nil
+	return assets, balances, lastModifiedLedger, nil
 }
@@ -2,12 +2,16 @@ package actions
 
 import (
 	"context"
+	"database/sql"
 	"net/http"
 	"testing"
 
 	auroraContext "github.com/hcnet/go/services/aurora/internal/context"
 	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/services/aurora/internal/paths"
 	"github.com/hcnet/go/services/aurora/internal/test"
+	"github.com/hcnet/go/support/render/problem"
+	"github.com/hcnet/go/xdr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -24,12 +28,170 @@ func TestAssetsForAddressRequiresTransaction(t *testing.T) {
 		q,
 	)
 
-	_, _, err := assetsForAddress(r.WithContext(ctx), "GCATOZ7YJV2FANQQLX47TIV6P7VMPJCEEJGQGR6X7TONPKBN3UCLKEIS")
+	_, _, _, err := assetsForAddress(r.WithContext(ctx), "GCATOZ7YJV2FANQQLX47TIV6P7VMPJCEEJGQGR6X7TONPKBN3UCLKEIS")
 	assert.EqualError(t, err, "cannot be called outside of a transaction")
 
 	assert.NoError(t, q.Begin())
 	defer q.Rollback()
 
-	_, _, err = assetsForAddress(r.WithContext(ctx), "GCATOZ7YJV2FANQQLX47TIV6P7VMPJCEEJGQGR6X7TONPKBN3UCLKEIS")
+	_, _, _, err = assetsForAddress(r.WithContext(ctx), "GCATOZ7YJV2FANQQLX47TIV6P7VMPJCEEJGQGR6X7TONPKBN3UCLKEIS")
 	assert.EqualError(t, err, "should only be called in a repeatable read transaction")
 }
+
+func TestPathConstraintsQueryParams(t *testing.T) {
+	usd := xdr.MustNewCreditAsset("USD", "GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN")
+	poolID := xdr.PoolId{0xca, 0xfe}
+
+	constraints, err := PathConstraintsQueryParams{}.Constraints()
+	assert.NoError(t, err)
+	assert.Equal(t, paths.Constraints{}, constraints)
+
+	constraints, err = PathConstraintsQueryParams{
+		ExcludeAssets:     "native",
+		IncludeOnlyAssets: "USD:GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN",
+		ExcludePools:      xdr.Hash(poolID).HexString(),
+		Venues:            PathVenuesOrderBook,
+	}.Constraints()
+	assert.NoError(t, err)
+	assert.Equal(t, paths.Constraints{
+		ExcludedAssets: []xdr.Asset{xdr.MustNewNativeAsset()},
+		IncludedAssets: []xdr.Asset{usd},
+		ExcludedPools:  []xdr.PoolId{poolID},
+		ExcludePools:   true,
+	}, constraints)
+
+	constraints, err = PathConstraintsQueryParams{Venues: PathVenuesPools}.Constraints()
+	assert.NoError(t, err)
+	assert.Equal(t, paths.Constraints{ExcludeOffers: true}, constraints)
+
+	for _, testCase := range []struct {
+		query PathConstraintsQueryParams
+		field string
+	}{
+		{PathConstraintsQueryParams{ExcludeAssets: "USD"}, "exclude_assets"},
+		{PathConstraintsQueryParams{IncludeOnlyAssets: "native,EUR"}, "include_only_assets"},
+		{PathConstraintsQueryParams{ExcludePools: "cafebabe"}, "exclude_pools"},
+		{PathConstraintsQueryParams{Venues: "amm"}, "venues"},
+	} {
+		err = testCase.query.Validate()
+		if assert.IsType(t, &problem.P{}, err) {
+			assert.Equal(t, testCase.field, err.(*problem.P).Extras["invalid_field"])
+		}
+	}
+
+	maxPathLength, err := PathConstraintsQueryParams{}.MaxPathLength(3)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), maxPathLength)
+	maxPathLength, err = PathConstraintsQueryParams{MaxHops: 2}.MaxPathLength(3)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), maxPathLength)
+	_, err = PathConstraintsQueryParams{MaxHops: 4}.MaxPathLength(3)
+	assert.EqualError(t, err, "problem: bad_request")
+}
+
+func TestPathsChangedSince(t *testing.T) {
+	usd := xdr.MustNewCreditAsset("USD", "GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN")
+	constraints, err := PathConstraintsQueryParams{}.Constraints()
+	assert.NoError(t, err)
+	poolsOnly, err := PathConstraintsQueryParams{Venues: PathVenuesPools}.Constraints()
+	assert.NoError(t, err)
+	finder := &paths.MockFinder{}
+	finder.On("PathsChangedSince", uint32(5), paths.Query{
+		DestinationAsset: xdr.MustNewNativeAsset(),
+		SourceAssets:     []xdr.Asset{usd},
+		Constraints:      poolsOnly,
+	}, uint(3)).Return(false).Once()
+	finder.On("FixedPathsChangedSince", uint32(5), usd, []xdr.Asset{xdr.MustNewNativeAsset()}, uint(2), constraints).
+		Return(true).Once()
+	defer finder.AssertExpectations(t)
+
+	strictReceive := FindPathsHandler{MaxPathLength: 3, PathFinder: finder}
+	changed, err := strictReceive.ChangedSince(makeRequest(t, map[string]string{
+		"destination_asset_type": "native",
+		"destination_amount":     "10",
+		"source_assets":          "USD:GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN",
+		"venues":                 PathVenuesPools,
+	}, nil, nil), 5)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	strictSend := FindFixedPathsHandler{MaxPathLength: 3, PathFinder: finder}
+	changed, err = strictSend.ChangedSince(makeRequest(t, map[string]string{
+		"source_asset_type":   "credit_alphanum4",
+		"source_asset_code":   "USD",
+		"source_asset_issuer": "GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN",
+		"source_amount":       "10",
+		"destination_assets":  "native",
+		"max_hops":            "2",
+	}, nil, nil), 5)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	_, err = strictSend.ChangedSince(makeRequest(t, map[string]string{
+		"source_asset_type": "native",
+	}, nil, nil), 5)
+	assert.Error(t, err)
+}
+
+func TestPathsChangedSinceAccountUpdate(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &history.Q{SessionInterface: tt.AuroraSession()}
+
+	address := "GCATOZ7YJV2FANQQLX47TIV6P7VMPJCEEJGQGR6X7TONPKBN3UCLKEIS"
+	usd := xdr.MustNewCreditAsset("USD", "GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN")
+	tt.Assert.NoError(q.UpsertAccounts(tt.Ctx, []history.AccountEntry{{
+		AccountID:          address,
+		Balance:            100,
+		LastModifiedLedger: 4,
+	}}))
+	tt.Assert.NoError(q.UpsertTrustLines(tt.Ctx, []history.TrustLine{{
+		AccountID:          address,
+		AssetType:          xdr.AssetTypeAssetTypeCreditAlphanum4,
+		AssetCode:          "USD",
+		AssetIssuer:        "GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN",
+		Balance:            10,
+		LedgerKey:          "usd-trustline",
+		LastModifiedLedger: 6,
+	}}))
+	tt.Assert.NoError(q.BeginTx(&sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	}))
+	defer q.Rollback()
+
+	constraints, err := PathConstraintsQueryParams{}.Constraints()
+	tt.Assert.NoError(err)
+	finder := &paths.MockFinder{}
+	finder.On("PathsChangedSince", uint32(7), paths.Query{
+		DestinationAsset: xdr.MustNewNativeAsset(),
+		SourceAssets:     []xdr.Asset{usd, xdr.MustNewNativeAsset()},
+		Constraints:      constraints,
+	}, uint(3)).Return(false).Once()
+	defer finder.AssertExpectations(t)
+
+	strictReceive := FindPathsHandler{MaxPathLength: 3, PathFinder: finder}
+	request := makeRequest(t, map[string]string{
+		"destination_asset_type": "native",
+		"destination_amount":     "10",
+		"source_account":         address,
+	}, nil, q)
+	changed, err := strictReceive.ChangedSince(request, 7)
+	tt.Assert.NoError(err)
+	tt.Assert.False(changed)
+
+	// the balances of the source account were updated
+	changed, err = strictReceive.ChangedSince(request, 6)
+	tt.Assert.NoError(err)
+	tt.Assert.True(changed)
+
+	strictSend := FindFixedPathsHandler{MaxPathLength: 3, PathFinder: finder}
+	changed, err = strictSend.ChangedSince(makeRequest(t, map[string]string{
+		"source_asset_type":   "native",
+		"source_amount":       "10",
+		"destination_account": address,
+	}, nil, q), 5)
+	tt.Assert.NoError(err)
+	tt.Assert.True(changed)
+}
@@ -0,0 +1,68 @@
+package actions
+
+import (
+	"net/http"
+
+	"github.com/hcnet/go/protocols/aurora"
+	auroraContext "github.com/hcnet/go/services/aurora/internal/context"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/services/aurora/internal/txsub"
+	"github.com/hcnet/go/support/errors"
+)
+
+// SimulateTransactionHandler predicts the result of submitting a transaction
+// using the ingested ledger state, the transaction is not submitted to the
+// network.
+type SimulateTransactionHandler struct {
+	NetworkPassphrase string
+}
+
+// GetResource returns the predicted result codes of the transaction in the
+// `tx` form value.
+func (handler SimulateTransactionHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	if err := validateBodyType(r); err != nil {
+		return nil, err
+	}
+
+	raw, err := getString(r, "tx")
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := extractEnvelopeInfo(raw, handler.NetworkPassphrase)
+	if err != nil {
+		return nil, transactionMalformedProblem(raw)
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := r.Context()
+	lastIngestedLedger, err := historyQ.GetLastLedgerIngestNonBlocking(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get last ingested ledger")
+	}
+	var ledger history.Ledger
+	if err = historyQ.LedgerBySequence(ctx, &ledger, int32(lastIngestedLedger)); err != nil {
+		return nil, errors.Wrap(err, "could not load last ingested ledger")
+	}
+
+	result, err := txsub.SimulateTransaction(ctx, historyQ, ledger, handler.NetworkPassphrase, info.parsed)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not simulate transaction")
+	}
+
+	return aurora.TransactionSimulation{
+		Hash:       info.hash,
+		Successful: result.Successful(),
+		Ledger:     int32(lastIngestedLedger),
+		FeeCharged: result.FeeCharged,
+		ResultCodes: aurora.TransactionResultCodes{
+			TransactionCode:      result.Code,
+			InnerTransactionCode: result.InnerCode,
+			OperationCodes:       result.OperationCodes,
+		},
+	}, nil
+}
@@ -0,0 +1,34 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hcnet/go/network"
+	hProblem "github.com/hcnet/go/services/aurora/internal/render/problem"
+	"github.com/hcnet/go/support/render/problem"
+)
+
+func TestSimulateTransactionMalformedTx(t *testing.T) {
+	handler := SimulateTransactionHandler{NetworkPassphrase: network.TestNetworkPassphrase}
+
+	r := httptest.NewRequest("POST", "https://aurora.hcnet.org/transactions/simulate", nil)
+	w := httptest.NewRecorder()
+	_, err := handler.GetResource(w, r)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, err.(*problem.P).Status)
+	assert.Equal(t, "Transaction Malformed", err.(*problem.P).Title)
+}
+
+func TestSimulateTransactionUnsupportedMediaType(t *testing.T) {
+	handler := SimulateTransactionHandler{NetworkPassphrase: network.TestNetworkPassphrase}
+
+	r := httptest.NewRequest("POST", "https://aurora.hcnet.org/transactions/simulate", nil)
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	_, err := handler.GetResource(w, r)
+	assert.Equal(t, &hProblem.UnsupportedMediaType, err)
+}
@@ -0,0 +1,101 @@
+package actions
+
+import (
+	"net/http"
+
+	"github.com/hcnet/go/amount"
+	protocol "github.com/hcnet/go/protocols/aurora"
+	auroraContext "github.com/hcnet/go/services/aurora/internal/context"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/services/aurora/internal/ledger"
+	"github.com/hcnet/go/services/aurora/internal/resourceadapter"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/support/render/hal"
+	"github.com/hcnet/go/support/render/problem"
+)
+
+// AccountSponsorshipsQuery query struct for accounts/{account_id}/sponsorships end-point
+type AccountSponsorshipsQuery struct {
+	AccountID string `schema:"account_id" valid:"accountID"`
+}
+
+// GetAccountSponsorshipsHandler is the action handler for the ledger entries
+// sponsored by an account.
+type GetAccountSponsorshipsHandler struct {
+	LedgerState *ledger.State
+}
+
+// GetResource returns a page of the entries sponsored by an account with the
+// totals of all its sponsored entries.
+func (handler GetAccountSponsorshipsHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	pq, err := GetPageQuery(handler.LedgerState, r, DisableCursorValidation)
+	if err != nil {
+		return nil, err
+	}
+	qp := AccountSponsorshipsQuery{}
+	if err = getParams(&qp, r); err != nil {
+		return nil, err
+	}
+
+	query := history.SponsorshipsQuery{
+		PageQuery: pq,
+		Sponsor:   qp.AccountID,
+	}
+	if _, _, err = query.Cursor(); err != nil {
+		return nil, problem.MakeInvalidFieldProblem(
+			"cursor",
+			errors.New("The cursor should be the type of the entry and the key of the entry separated by a colon"),
+		)
+	}
+
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	baseReserve, err := historyQ.LatestLedgerBaseReserve(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load base reserve")
+	}
+	entries, err := historyQ.GetSponsoredEntries(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load sponsored entries")
+	}
+	totals, err := historyQ.GetSponsorshipTotals(ctx, qp.AccountID)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load sponsorship totals")
+	}
+
+	page := hal.Page{
+		Cursor: pq.Cursor,
+		Order:  pq.Order,
+		Limit:  pq.Limit,
+	}
+	page.Init()
+	var resource protocol.AccountSponsorships
+	resource.Embedded.Records = []protocol.Sponsorship{}
+	for _, entry := range entries {
+		var sponsorship protocol.Sponsorship
+		resourceadapter.PopulateSponsorship(ctx, &sponsorship, entry, baseReserve)
+		resource.Embedded.Records = append(resource.Embedded.Records, sponsorship)
+		page.Add(sponsorship)
+	}
+	page.FullURL = FullURL(ctx)
+	page.PopulateLinks()
+	resource.Links.Self = page.Links.Self
+	resource.Links.Next = page.Links.Next
+	resource.Links.Prev = page.Links.Prev
+	lb := hal.LinkBuilder{Base: auroraContext.BaseURL(ctx)}
+	resource.Links.Effects = lb.Link("/effects?sponsor=" + qp.AccountID)
+
+	resource.Totals = []protocol.SponsorshipTotal{}
+	for _, total := range totals {
+		var res protocol.SponsorshipTotal
+		resourceadapter.PopulateSponsorshipTotal(&res, total, baseReserve)
+		resource.Totals = append(resource.Totals, res)
+		resource.TotalReserveCount += total.ReserveCount
+	}
+	resource.TotalReserve = amount.StringFromInt64(resource.TotalReserveCount * int64(baseReserve))
+
+	return resource, nil
+}
@@ -57,7 +57,8 @@ func extractEnvelopeInfo(raw string, passphrase string) (envelopeInfo, error) {
 	return result, nil
 }
 
-func (handler SubmitTransactionHandler) validateBodyType(r *http.Request) error {
+// validateBodyType checks that transactions are submitted as form values.
+func validateBodyType(r *http.Request) error {
 	c := r.Header.Get("Content-Type")
 	if c == "" {
 		return nil
@@ -74,6 +75,22 @@ func (handler SubmitTransactionHandler) validateBodyType(r *http.Request) error
 	return nil
 }
 
+func transactionMalformedProblem(raw string) *problem.P {
+	return &problem.P{
+		Type:   "transaction_malformed",
+		Title:  "Transaction Malformed",
+		Status: http.StatusBadRequest,
+		Detail: "Aurora could not decode the transaction envelope in this " +
+			"request. A transaction should be an XDR TransactionEnvelope struct " +
+			"encoded using base64.  The envelope read from this request is " +
+			"echoed in the `extras.envelope_xdr` field of this response for your " +
+			"convenience.",
+		Extras: map[string]interface{}{
+			"envelope_xdr": raw,
+		},
+	}
+}
+
 func (handler SubmitTransactionHandler) response(r *http.Request, info envelopeInfo, result txsub.Result) (hal.Pageable, error) {
 	if result.Err == nil {
 		var resource aurora.Transaction
@@ -124,7 +141,7 @@ func (handler SubmitTransactionHandler) response(r *http.Request, info envelopeI
 }
 
 func (handler SubmitTransactionHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
-	if err := handler.validateBodyType(r); err != nil {
+	if err := validateBodyType(r); err != nil {
 		return nil, err
 	}
 
@@ -135,19 +152,7 @@ func (handler SubmitTransactionHandler) GetResource(w HeaderWriter, r *http.Requ
 
 	info, err := extractEnvelopeInfo(raw, handler.NetworkPassphrase)
 	if err != nil {
-		return nil, &problem.P{
-			Type:   "transaction_malformed",
-			Title:  "Transaction Malformed",
-			Status: http.StatusBadRequest,
-			Detail: "Aurora could not decode the transaction envelope in this " +
-				"request. A transaction should be an XDR TransactionEnvelope struct " +
-				"encoded using base64.  The envelope read from this request is " +
-				"echoed in the `extras.envelope_xdr` field of this response for your " +
-				"convenience.",
-			Extras: map[string]interface{}{
-				"envelope_xdr": raw,
-			},
-		}
+		return nil, transactionMalformedProblem(raw)
 	}
 
 	coreState := handler.GetCoreState()
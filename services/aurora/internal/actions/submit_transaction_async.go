@@ -0,0 +1,151 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hcnet/go/protocols/aurora"
+	proto "github.com/hcnet/go/protocols/hcnetcore"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	hProblem "github.com/hcnet/go/services/aurora/internal/render/problem"
+	"github.com/hcnet/go/services/aurora/internal/resourceadapter"
+	"github.com/hcnet/go/services/aurora/internal/txsub"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/support/render/problem"
+	"github.com/hcnet/go/xdr"
+)
+
+type AsyncNetworkSubmitter interface {
+	SubmitAsync(ctx context.Context, rawTx string, envelope xdr.TransactionEnvelope, hash string) txsub.SubmissionResult
+}
+
+// AsyncSubmitTransactionHandler submits transactions to hcnet-core and
+// responds as soon as hcnet-core accepted or rejected them, without waiting
+// for the transactions to be included in a ledger.
+type AsyncSubmitTransactionHandler struct {
+	Submitter         AsyncNetworkSubmitter
+	NetworkPassphrase string
+	CoreStateGetter
+}
+
+// GetResource submits the transaction in the `tx` form value and returns the
+// status of the submission returned by hcnet-core.
+func (handler AsyncSubmitTransactionHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	if err := validateBodyType(r); err != nil {
+		return nil, err
+	}
+
+	raw, err := getString(r, "tx")
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := extractEnvelopeInfo(raw, handler.NetworkPassphrase)
+	if err != nil {
+		return nil, transactionMalformedProblem(raw)
+	}
+
+	coreState := handler.GetCoreState()
+	if !coreState.Synced {
+		return nil, hProblem.StaleHistory
+	}
+
+	result := handler.Submitter.SubmitAsync(r.Context(), info.raw, info.parsed, info.hash)
+	if failed, ok := result.Err.(*txsub.FailedTransactionError); ok {
+		rcr := aurora.TransactionResultCodes{}
+		resourceadapter.PopulateTransactionResultCodes(
+			r.Context(),
+			info.hash,
+			&rcr,
+			failed,
+		)
+
+		return nil, &problem.P{
+			Type:   "transaction_failed",
+			Title:  "Transaction Failed",
+			Status: http.StatusBadRequest,
+			Detail: "The transaction was rejected by the hcnet network. " +
+				"The `extras.result_codes` field on this response contains further " +
+				"details.  Descriptions of each code can be found at: " +
+				"https://developers.hcnet.org/api/errors/http-status-codes/aurora-specific/transaction-failed/",
+			Extras: map[string]interface{}{
+				"hash":         info.hash,
+				"tx_status":    result.Status,
+				"envelope_xdr": info.raw,
+				"result_xdr":   failed.ResultXDR,
+				"result_codes": rcr,
+			},
+		}
+	}
+	if result.Err != nil {
+		return nil, errors.Wrap(result.Err, "could not submit transaction")
+	}
+
+	if result.Status == proto.TXStatusTryAgainLater {
+		return nil, &problem.P{
+			Type:   "transaction_try_again_later",
+			Title:  "Transaction Try Again Later",
+			Status: http.StatusServiceUnavailable,
+			Detail: "The hcnet network could not accept the transaction at this time. " +
+				"Submit the transaction again later.",
+			Extras: map[string]interface{}{
+				"hash":      info.hash,
+				"tx_status": result.Status,
+			},
+		}
+	}
+
+	return aurora.AsyncTransactionSubmissionResponse{
+		Hash:     info.hash,
+		TxStatus: result.Status,
+	}, nil
+}
+
+type TransactionStatusGetter interface {
+	Status(ctx context.Context, hash string) (txsub.TransactionStatus, history.Transaction, error)
+}
+
+// GetTransactionStatusHandler returns the status of submitted transactions.
+type GetTransactionStatusHandler struct {
+	StatusGetter TransactionStatusGetter
+}
+
+// GetResource returns the status of the transaction with the hash in the path:
+// pending, ingested, failed or expired.
+func (handler GetTransactionStatusHandler) GetResource(w HeaderWriter, r *http.Request) (interface{}, error) {
+	ctx := r.Context()
+	qp := TransactionQuery{}
+	if err := getParams(&qp, r); err != nil {
+		return nil, err
+	}
+
+	status, tx, err := handler.StatusGetter.Status(ctx, qp.TransactionHash)
+	if err == txsub.ErrNoResults {
+		return nil, problem.NotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get transaction status")
+	}
+
+	resource := aurora.TransactionStatus{
+		Hash:   qp.TransactionHash,
+		Status: string(status),
+	}
+	if status == txsub.TransactionStatusIngested || status == txsub.TransactionStatusFailed {
+		resource.Ledger = tx.LedgerSequence
+		resource.ResultXdr = tx.TxResult
+	}
+	if status == txsub.TransactionStatusFailed {
+		resource.ResultCodes = &aurora.TransactionResultCodes{}
+		err = resourceadapter.PopulateTransactionResultCodes(
+			ctx,
+			qp.TransactionHash,
+			resource.ResultCodes,
+			&txsub.FailedTransactionError{ResultXDR: tx.TxResult},
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not populate result codes")
+		}
+	}
+	return resource, nil
+}
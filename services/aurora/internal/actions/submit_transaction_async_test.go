@@ -0,0 +1,159 @@
+package actions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hcnet/go/network"
+	"github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/services/aurora/internal/corestate"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/services/aurora/internal/txsub"
+	"github.com/hcnet/go/support/render/problem"
+	"github.com/hcnet/go/xdr"
+)
+
+const asyncTestTransaction = "AAAAAAGUcmKO5465JxTSLQOQljwk2SfqAJmZSG6JH6wtqpwhAAABLAAAAAAAAAABAAAAAAAAAAEAAAALaGVsbG8gd29ybGQAAAAAAwAAAAAAAAAAAAAAABbxCy3mLg3hiTqX4VUEEp60pFOrJNxYM1JtxXTwXhY2AAAAAAvrwgAAAAAAAAAAAQAAAAAW8Qst5i4N4Yk6l+FVBBKetKRTqyTcWDNSbcV08F4WNgAAAAAN4Lazj4x61AAAAAAAAAAFAAAAAAAAAAAAAAAAAAAAAQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABLaqcIQAAAEBKwqWy3TaOxoGnfm9eUjfTRBvPf34dvDA0Nf+B8z4zBob90UXtuCqmQqwMCyH+okOI3c05br3khkH0yP4kCwcE"
+
+type asyncSubmitterMock struct {
+	mock.Mock
+}
+
+func (m *asyncSubmitterMock) SubmitAsync(ctx context.Context, rawTx string, envelope xdr.TransactionEnvelope, hash string) txsub.SubmissionResult {
+	a := m.Called(hash)
+	return a.Get(0).(txsub.SubmissionResult)
+}
+
+func asyncSubmissionRequest(t *testing.T) *http.Request {
+	form := url.Values{}
+	form.Set("tx", asyncTestTransaction)
+	request, err := http.NewRequest(
+		"POST",
+		"https://aurora.hcnet.org/transactions_async",
+		strings.NewReader(form.Encode()),
+	)
+	require.NoError(t, err)
+	request.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	return request
+}
+
+func TestAsyncSubmitTransaction(t *testing.T) {
+	coreState := &coreStateGetterMock{}
+	coreState.On("GetCoreState").Return(corestate.State{Synced: true})
+
+	info, err := extractEnvelopeInfo(asyncTestTransaction, network.PublicNetworkPassphrase)
+	require.NoError(t, err)
+
+	for _, testCase := range []struct {
+		name           string
+		result         txsub.SubmissionResult
+		expectedStatus int
+		expectedType   string
+	}{
+		{
+			name:   "pending",
+			result: txsub.SubmissionResult{Status: "PENDING"},
+		},
+		{
+			name:   "duplicate",
+			result: txsub.SubmissionResult{Status: "DUPLICATE"},
+		},
+		{
+			name:           "error",
+			result:         txsub.SubmissionResult{Status: "ERROR", Err: txsub.ErrBadSequence},
+			expectedStatus: http.StatusBadRequest,
+			expectedType:   "transaction_failed",
+		},
+		{
+			name:           "try again later",
+			result:         txsub.SubmissionResult{Status: "TRY_AGAIN_LATER"},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedType:   "transaction_try_again_later",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			submitter := &asyncSubmitterMock{}
+			submitter.On("SubmitAsync", info.hash).Return(testCase.result).Once()
+			handler := AsyncSubmitTransactionHandler{
+				Submitter:         submitter,
+				NetworkPassphrase: network.PublicNetworkPassphrase,
+				CoreStateGetter:   coreState,
+			}
+
+			resource, err := handler.GetResource(httptest.NewRecorder(), asyncSubmissionRequest(t))
+			submitter.AssertExpectations(t)
+			if testCase.expectedStatus == 0 {
+				assert.NoError(t, err)
+				assert.Equal(t, aurora.AsyncTransactionSubmissionResponse{
+					Hash:     info.hash,
+					TxStatus: testCase.result.Status,
+				}, resource)
+				return
+			}
+
+			p, ok := err.(*problem.P)
+			require.True(t, ok)
+			assert.Equal(t, testCase.expectedStatus, p.Status)
+			assert.Equal(t, testCase.expectedType, p.Type)
+			assert.Equal(t, testCase.result.Status, p.Extras["tx_status"])
+		})
+	}
+}
+
+type statusGetterMock struct {
+	mock.Mock
+}
+
+func (m *statusGetterMock) Status(ctx context.Context, hash string) (txsub.TransactionStatus, history.Transaction, error) {
+	a := m.Called(hash)
+	return a.Get(0).(txsub.TransactionStatus), a.Get(1).(history.Transaction), a.Error(2)
+}
+
+func TestGetTransactionStatus(t *testing.T) {
+	hash := "2374e99349b9ef7dba9a5db3339b78fda8f34777b1af33ba468ad5c0df946d4d"
+	failedTx := history.Transaction{
+		TransactionWithoutLedger: history.TransactionWithoutLedger{
+			TransactionHash: hash,
+			LedgerSequence:  12,
+			TxResult:        txsub.ErrBadSequence.ResultXDR,
+		},
+	}
+
+	getter := &statusGetterMock{}
+	handler := GetTransactionStatusHandler{StatusGetter: getter}
+	request := func() *http.Request {
+		return makeRequest(t, map[string]string{}, map[string]string{"tx_id": hash}, nil)
+	}
+
+	getter.On("Status", hash).Return(txsub.TransactionStatusPending, history.Transaction{}, nil).Once()
+	resource, err := handler.GetResource(httptest.NewRecorder(), request())
+	assert.NoError(t, err)
+	assert.Equal(t, aurora.TransactionStatus{Hash: hash, Status: "pending"}, resource)
+
+	getter.On("Status", hash).Return(txsub.TransactionStatusFailed, failedTx, nil).Once()
+	resource, err = handler.GetResource(httptest.NewRecorder(), request())
+	assert.NoError(t, err)
+	assert.Equal(t, aurora.TransactionStatus{
+		Hash:        hash,
+		Status:      "failed",
+		Ledger:      12,
+		ResultXdr:   txsub.ErrBadSequence.ResultXDR,
+		ResultCodes: &aurora.TransactionResultCodes{TransactionCode: "tx_bad_seq"},
+	}, resource)
+
+	getter.On("Status", hash).Return(txsub.TransactionStatus(""), history.Transaction{}, txsub.ErrNoResults).Once()
+	_, err = handler.GetResource(httptest.NewRecorder(), request())
+	assert.Equal(t, problem.NotFound, err)
+
+	_, err = handler.GetResource(httptest.NewRecorder(), makeRequest(t, map[string]string{}, map[string]string{"tx_id": "abc"}, nil))
+	assert.Error(t, err)
+	getter.AssertExpectations(t)
+}
@@ -57,6 +57,8 @@ func (handler GetTransactionByHashHandler) GetResource(w HeaderWriter, r *http.R
 
 // TransactionsQuery query struct for transactions end-points
 type TransactionsQuery struct {
+	TimeRange                 `valid:"optional"`
+	MemoFilter                `valid:"optional"`
 	AccountID                 string `schema:"account_id" valid:"accountID,optional"`
 	ClaimableBalanceID        string `schema:"claimable_balance_id" valid:"claimableBalanceID,optional"`
 	LiquidityPoolID           string `schema:"liquidity_pool_id" valid:"sha256,optional"`
@@ -84,7 +86,13 @@ func (qp TransactionsQuery) Validate() error {
 		)
 	}
 
-	return nil
+	if qp.MemoFilter.IsSet() {
+		if _, err := qp.XDRMemo(); err != nil {
+			return err
+		}
+	}
+
+	return qp.TimeRange.Validate()
 }
 
 // GetTransactionsHandler is the action handler for all end-points returning a list of transactions.
@@ -154,6 +162,19 @@ func loadTransactionRecords(ctx context.Context, hq *history.Q, qp TransactionsQ
 		txs.ForLedger(ctx, int32(qp.LedgerID))
 	}
 
+	if qp.TimeRange.IsSet() {
+		start, end, err := qp.LedgerRange(ctx, hq)
+		if err != nil {
+			return nil, err
+		}
+		txs.ForLedgerRange(start, end)
+	}
+
+	if qp.MemoFilter.IsSet() {
+		memo, _ := qp.XDRMemo()
+		txs.ForMemo(memo)
+	}
+
 	if qp.IncludeFailedTransactions {
 		txs.IncludeFailed()
 	}
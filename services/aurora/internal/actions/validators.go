@@ -23,6 +23,7 @@ func init() {
 	govalidator.TagMap["accountID"] = isAccountID
 	govalidator.TagMap["amount"] = isAmount
 	govalidator.TagMap["assetType"] = isAssetType
+	govalidator.TagMap["assetCode"] = isAssetCode
 	govalidator.TagMap["asset"] = isAsset
 	govalidator.TagMap["claimableBalanceID"] = isClaimableBalanceID
 	govalidator.TagMap["transactionHash"] = isTransactionHash
@@ -35,6 +36,7 @@ var customTagsErrorMessages = map[string]string{
 	"amount":               "Amount must be positive",
 	"asset":                "Asset must be the string \"native\" or a string of the form \"Code:IssuerAccountID\" for issued assets.",
 	"assetType":            "Asset type must be native, credit_alphanum4 or credit_alphanum12",
+	"assetCode":            "Asset code must contain between 1 and 12 alphanumeric characters",
 	"bool":                 "Filter should be true or false",
 	"claimable_balance_id": "Claimable Balance ID must be the hex-encoded XDR representation of a Claimable Balance ID",
 	"ledger_id":            "Ledger ID must be an integer higher than 0",
@@ -123,6 +125,10 @@ func getErrorFieldMessage(err error) (string, string) {
 	return field, message
 }
 
+func isAssetCode(str string) bool {
+	return xdr.ValidAssetCode.MatchString(str)
+}
+
 func isAssetType(str string) bool {
 	if _, err := assets.Parse(str); err != nil {
 		return false
@@ -0,0 +1,313 @@
+package actions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/guregu/null"
+	"github.com/lib/pq"
+
+	hProtocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/protocols/aurora/operations"
+	auroraContext "github.com/hcnet/go/services/aurora/internal/context"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/services/aurora/internal/ledger"
+	"github.com/hcnet/go/strkey"
+	"github.com/hcnet/go/support/render/problem"
+	"github.com/hcnet/go/toid"
+	"github.com/hcnet/go/xdr"
+)
+
+// WebhookSubscriptionsHandler manages webhook subscriptions, these admin HTTP
+// endpoints are documented in services/aurora/internal/httpx/static/admin_oapi.yml
+type WebhookSubscriptionsHandler struct {
+	LedgerState *ledger.State
+}
+
+// GetSubscriptions returns all webhook subscriptions.
+func (handler WebhookSubscriptionsHandler) GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	subscriptions, err := historyQ.WebhookSubscriptions(r.Context())
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	responsePayload := make([]hProtocol.WebhookSubscription, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		responsePayload = append(responsePayload, handler.subscriptionResource(subscription, false))
+	}
+	enc := json.NewEncoder(w)
+	if err = enc.Encode(responsePayload); err != nil {
+		problem.Render(r.Context(), w, err)
+	}
+}
+
+// GetSubscription returns the webhook subscription with the id in the path.
+func (handler WebhookSubscriptionsHandler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	id, err := handler.subscriptionID(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	subscription, err := historyQ.WebhookSubscriptionByID(r.Context(), id)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	if err = enc.Encode(handler.subscriptionResource(subscription, false)); err != nil {
+		problem.Render(r.Context(), w, err)
+	}
+}
+
+// CreateSubscription creates a webhook subscription. A signing secret is
+// generated when the request does not contain one, the secret is only
+// returned in the response of this endpoint.
+func (handler WebhookSubscriptionsHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	subscriptionRequest, err := handler.subscriptionRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+	if subscriptionRequest.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err = rand.Read(secret); err != nil {
+			problem.Render(r.Context(), w, err)
+			return
+		}
+		subscriptionRequest.Secret = hex.EncodeToString(secret)
+	}
+
+	subscription, err := handler.subscriptionRow(subscriptionRequest, history.WebhookSubscription{})
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	subscription, err = historyQ.InsertWebhookSubscription(r.Context(), subscription)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	enc := json.NewEncoder(w)
+	if err = enc.Encode(handler.subscriptionResource(subscription, true)); err != nil {
+		problem.Render(r.Context(), w, err)
+	}
+}
+
+// UpdateSubscription replaces the URL and the filters of the webhook
+// subscription with the id in the path. The secret and the cursor are kept
+// when they are not set in the request. Delivery failures are reset so that
+// the next delivery is attempted immediately.
+func (handler WebhookSubscriptionsHandler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	id, err := handler.subscriptionID(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	subscriptionRequest, err := handler.subscriptionRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	existing, err := historyQ.WebhookSubscriptionByID(r.Context(), id)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	subscription, err := handler.subscriptionRow(subscriptionRequest, existing)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	subscription, err = historyQ.UpdateWebhookSubscription(r.Context(), subscription)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	if err = enc.Encode(handler.subscriptionResource(subscription, false)); err != nil {
+		problem.Render(r.Context(), w, err)
+	}
+}
+
+// DeleteSubscription removes the webhook subscription with the id in the path.
+func (handler WebhookSubscriptionsHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	historyQ, err := auroraContext.HistoryQFromRequest(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	id, err := handler.subscriptionID(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	if err = historyQ.DeleteWebhookSubscription(r.Context(), id); err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (handler WebhookSubscriptionsHandler) subscriptionID(r *http.Request) (int64, error) {
+	param, _ := getURLParam(r, "id")
+	id, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return 0, problem.NewProblemWithInvalidField(problem.BadRequest, "id", fmt.Errorf("invalid webhook subscription id"))
+	}
+	return id, nil
+}
+
+func (handler WebhookSubscriptionsHandler) subscriptionRequest(r *http.Request) (hProtocol.WebhookSubscription, error) {
+	var subscriptionRequest hProtocol.WebhookSubscription
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&subscriptionRequest); err != nil {
+		p := problem.NewProblemWithInvalidField(problem.BadRequest, "reason", fmt.Errorf("invalid json for webhook subscription %v", err.Error()))
+		return hProtocol.WebhookSubscription{}, p
+	}
+	return subscriptionRequest, nil
+}
+
+// subscriptionRow validates a subscription request and applies it to an
+// existing subscription row.
+func (handler WebhookSubscriptionsHandler) subscriptionRow(
+	subscriptionRequest hProtocol.WebhookSubscription,
+	subscription history.WebhookSubscription,
+) (history.WebhookSubscription, error) {
+	u, err := url.Parse(subscriptionRequest.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return subscription, problem.NewProblemWithInvalidField(problem.BadRequest, "url", fmt.Errorf("url must be an absolute http or https URL"))
+	}
+	subscription.URL = subscriptionRequest.URL
+	if subscriptionRequest.Secret != "" {
+		subscription.Secret = subscriptionRequest.Secret
+	}
+
+	subscription.AccountID = null.String{}
+	if subscriptionRequest.AccountID != "" {
+		if _, err = strkey.Decode(strkey.VersionByteAccountID, subscriptionRequest.AccountID); err != nil {
+			return subscription, problem.NewProblemWithInvalidField(problem.BadRequest, "account_id", fmt.Errorf("invalid account id"))
+		}
+		subscription.AccountID = null.StringFrom(subscriptionRequest.AccountID)
+	}
+
+	subscription.Asset = null.String{}
+	if subscriptionRequest.Asset != "" {
+		assets, err := xdr.BuildAssets(subscriptionRequest.Asset)
+		if err != nil || len(assets) != 1 {
+			return subscription, problem.NewProblemWithInvalidField(problem.BadRequest, "asset", fmt.Errorf("asset must be native or in the form code:issuer"))
+		}
+		subscription.Asset = null.StringFrom(assets[0].StringCanonical())
+	}
+
+	subscription.OperationTypes = pq.Int64Array{}
+	for _, name := range subscriptionRequest.OperationTypes {
+		operationType, ok := operationTypeByName(name)
+		if !ok {
+			return subscription, problem.NewProblemWithInvalidField(problem.BadRequest, "operation_types", fmt.Errorf("unknown operation type %s", name))
+		}
+		subscription.OperationTypes = append(subscription.OperationTypes, int64(operationType))
+	}
+
+	switch subscriptionRequest.Cursor {
+	case "":
+		if subscription.ID == 0 {
+			subscription.Cursor = handler.latestCursor()
+		}
+	case "now":
+		subscription.Cursor = handler.latestCursor()
+	default:
+		cursor, err := strconv.ParseInt(subscriptionRequest.Cursor, 10, 64)
+		if err != nil || cursor < 0 {
+			return subscription, problem.NewProblemWithInvalidField(problem.BadRequest, "cursor", fmt.Errorf("cursor must be now or a paging token"))
+		}
+		subscription.Cursor = cursor
+	}
+
+	return subscription, nil
+}
+
+// latestCursor returns the paging token following the operations of the latest
+// ingested ledger.
+func (handler WebhookSubscriptionsHandler) latestCursor() int64 {
+	return toid.AfterLedger(handler.LedgerState.CurrentStatus().HistoryLatest).ToInt64()
+}
+
+func (handler WebhookSubscriptionsHandler) subscriptionResource(
+	subscription history.WebhookSubscription,
+	withSecret bool,
+) hProtocol.WebhookSubscription {
+	resource := hProtocol.WebhookSubscription{
+		ID:             subscription.ID,
+		URL:            subscription.URL,
+		AccountID:      subscription.AccountID.String,
+		Asset:          subscription.Asset.String,
+		OperationTypes: []string{},
+		Cursor:         strconv.FormatInt(subscription.Cursor, 10),
+		Failures:       subscription.Failures,
+		LastError:      subscription.LastError.String,
+		CreatedAt:      subscription.CreatedAt,
+		UpdatedAt:      subscription.UpdatedAt,
+	}
+	if withSecret {
+		resource.Secret = subscription.Secret
+	}
+	for _, operationType := range subscription.OperationTypes {
+		resource.OperationTypes = append(resource.OperationTypes, operations.TypeNames[xdr.OperationType(operationType)])
+	}
+	if subscription.NextAttemptAt.Valid {
+		nextAttemptAt := subscription.NextAttemptAt.Time
+		resource.NextAttemptAt = &nextAttemptAt
+	}
+	return resource
+}
+
+func operationTypeByName(name string) (xdr.OperationType, bool) {
+	for operationType, typeName := range operations.TypeNames {
+		if typeName == name {
+			return operationType, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,98 @@
+package actions
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	hProtocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/services/aurora/internal/ledger"
+	"github.com/hcnet/go/services/aurora/internal/test"
+)
+
+func TestWebhookSubscriptionsHandler(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+
+	q := &history.Q{SessionInterface: tt.AuroraSession()}
+	ledgerState := &ledger.State{}
+	ledgerState.SetAuroraStatus(ledger.AuroraStatus{HistoryLatest: 3})
+	handler := WebhookSubscriptionsHandler{LedgerState: ledgerState}
+
+	request := func(body string, routeParams map[string]string) *http.Request {
+		r := makeRequest(t, map[string]string{}, routeParams, q)
+		r.Body = ioutil.NopCloser(strings.NewReader(body))
+		return r
+	}
+	decode := func(recorder *httptest.ResponseRecorder, dest interface{}) {
+		raw, err := ioutil.ReadAll(recorder.Result().Body)
+		tt.Require.NoError(err)
+		tt.Require.NoError(json.Unmarshal(raw, dest))
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.CreateSubscription(recorder, request(`{
+		"url": "https://example.com/hook",
+		"account_id": "GAXMF43TGZHW3QN3REOUA2U5PW5BTARXGGYJ3JIFHW3YT6QRKRL3CPPU",
+		"asset": "USD:GAXMF43TGZHW3QN3REOUA2U5PW5BTARXGGYJ3JIFHW3YT6QRKRL3CPPU",
+		"operation_types": ["payment", "path_payment_strict_send"]
+	}`, map[string]string{}))
+	tt.Assert.Equal(http.StatusCreated, recorder.Code)
+	var created hProtocol.WebhookSubscription
+	decode(recorder, &created)
+	tt.Assert.Len(created.Secret, 64)
+	tt.Assert.Equal("https://example.com/hook", created.URL)
+	tt.Assert.Equal("USD:GAXMF43TGZHW3QN3REOUA2U5PW5BTARXGGYJ3JIFHW3YT6QRKRL3CPPU", created.Asset)
+	tt.Assert.Equal([]string{"payment", "path_payment_strict_send"}, created.OperationTypes)
+	// deliveries start after the latest ingested ledger
+	tt.Assert.Equal("17179869183", created.Cursor)
+
+	id := map[string]string{"id": strconv.FormatInt(created.ID, 10)}
+
+	recorder = httptest.NewRecorder()
+	handler.UpdateSubscription(recorder, request(`{"url": "http://example.com/other", "cursor": "12"}`, id))
+	tt.Assert.Equal(http.StatusOK, recorder.Code)
+	var updated hProtocol.WebhookSubscription
+	decode(recorder, &updated)
+	tt.Assert.Empty(updated.Secret)
+	tt.Assert.Equal("http://example.com/other", updated.URL)
+	tt.Assert.Empty(updated.AccountID)
+	tt.Assert.Empty(updated.OperationTypes)
+	tt.Assert.Equal("12", updated.Cursor)
+
+	subscription, err := q.WebhookSubscriptionByID(tt.Ctx, created.ID)
+	tt.Require.NoError(err)
+	tt.Assert.Equal(created.Secret, subscription.Secret)
+
+	recorder = httptest.NewRecorder()
+	handler.GetSubscriptions(recorder, request("", map[string]string{}))
+	var subscriptions []hProtocol.WebhookSubscription
+	decode(recorder, &subscriptions)
+	tt.Assert.Equal([]hProtocol.WebhookSubscription{updated}, subscriptions)
+
+	for _, body := range []string{
+		`{"url": "ftp://example.com"}`,
+		`{"url": "https://example.com", "account_id": "GABC"}`,
+		`{"url": "https://example.com", "asset": "USD"}`,
+		`{"url": "https://example.com", "operation_types": ["unknown"]}`,
+		`{"url": "https://example.com", "cursor": "latest"}`,
+		`{`,
+	} {
+		recorder = httptest.NewRecorder()
+		handler.CreateSubscription(recorder, request(body, map[string]string{}))
+		tt.Assert.Equal(http.StatusBadRequest, recorder.Code, body)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.DeleteSubscription(recorder, request("", id))
+	tt.Assert.Equal(http.StatusNoContent, recorder.Code)
+
+	_, err = q.WebhookSubscriptionByID(tt.Ctx, created.ID)
+	tt.Assert.True(q.NoRows(err))
+}
@@ -85,7 +85,7 @@ func TestPathActionsLimitExceeded(t *testing.T) {
 	finder := paths.MockFinder{}
 	finder.On("Find", mock.Anything, mock.Anything, uint(3)).
 		Return([]paths.Path{}, uint32(0), paths.ErrRateLimitExceeded).Times(2)
-	finder.On("FindFixedPaths", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+	finder.On("FindFixedPaths", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Return([]paths.Path{}, uint32(0), paths.ErrRateLimitExceeded).Times(1)
 
 	rh := mockPathFindingClient(
@@ -141,7 +141,7 @@ func TestPathActionsStillIngesting(t *testing.T) {
 	finder := paths.MockFinder{}
 	finder.On("Find", mock.Anything, mock.Anything, uint(3)).
 		Return([]paths.Path{}, uint32(0), simplepath.ErrEmptyInMemoryOrderBook).Times(2)
-	finder.On("FindFixedPaths", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+	finder.On("FindFixedPaths", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Return([]paths.Path{}, uint32(0), simplepath.ErrEmptyInMemoryOrderBook).Times(1)
 
 	rh := mockPathFindingClient(
@@ -635,7 +635,7 @@ func TestPathActionsStrictSend(t *testing.T) {
 	// withSourceAssetsBalance := true
 	sourceAsset := xdr.MustNewCreditAsset("USD", "GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN")
 
-	finder.On("FindFixedPaths", mock.Anything, sourceAsset, xdr.Int64(100000000), mock.Anything, uint(3)).Return([]paths.Path{}, uint32(1234), nil).Run(func(args mock.Arguments) {
+	finder.On("FindFixedPaths", mock.Anything, sourceAsset, xdr.Int64(100000000), mock.Anything, uint(3), paths.Constraints{}).Return([]paths.Path{}, uint32(1234), nil).Run(func(args mock.Arguments) {
 		destinationAssets := args.Get(3).([]xdr.Asset)
 		for _, asset := range destinationAssets {
 			var assetType, code, issuer string
@@ -684,6 +684,87 @@ func TestPathActionsStrictSend(t *testing.T) {
 	finder.AssertExpectations(t)
 }
 
+func TestPathActionsSplit(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	assertions := &test.Assertions{tt.Assert}
+
+	usd := xdr.MustNewCreditAsset("USD", "GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN")
+	splitPath := paths.SplitPath{
+		Source:            usd.String(),
+		SourceAmount:      100000000,
+		Destination:       "native",
+		DestinationAmount: 300000000,
+		Paths: []paths.Path{
+			{
+				Path:              []string{},
+				Source:            usd.String(),
+				SourceAmount:      60000000,
+				Destination:       "native",
+				DestinationAmount: 190000000,
+			},
+			{
+				Path:              []string{xdr.MustNewCreditAsset("EUR", "GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN").String()},
+				Source:            usd.String(),
+				SourceAmount:      40000000,
+				Destination:       "native",
+				DestinationAmount: 110000000,
+			},
+		},
+	}
+	finder := paths.MockFinder{}
+	finder.On("FindFixedSplitPaths", mock.Anything, usd, xdr.Int64(100000000), []xdr.Asset{xdr.MustNewNativeAsset()}, uint(3), paths.Constraints{}).
+		Return([]paths.SplitPath{splitPath}, uint32(1234), nil).Once()
+	finder.On("FindSplitPaths", mock.Anything, mock.Anything, uint(3)).
+		Return([]paths.SplitPath{}, uint32(0), paths.ErrRateLimitExceeded).Once()
+
+	rh := mockPathFindingClient(
+		tt,
+		&finder,
+		3,
+		tt.AuroraSession(),
+	)
+
+	q := make(url.Values)
+	q.Add("source_asset_issuer", "GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN")
+	q.Add("source_asset_type", "credit_alphanum4")
+	q.Add("source_asset_code", "USD")
+	q.Add("source_amount", "10")
+	q.Add("destination_assets", "native")
+	q.Add("split", "true")
+
+	w := rh.Get("/paths/strict-send?" + q.Encode())
+	assertions.Equal(http.StatusOK, w.Code)
+	assertions.Equal("1234", w.Header().Get(actions.LastLedgerHeaderName))
+	records := []aurora.SplitPath{}
+	assertions.PageOf(1, w.Body)
+	tt.UnmarshalPage(w.Body, &records)
+	if tt.Assert.Len(records, 1) {
+		tt.Assert.Equal("10.0000000", records[0].SourceAmount)
+		tt.Assert.Equal("30.0000000", records[0].DestinationAmount)
+		if tt.Assert.Len(records[0].Paths, 2) {
+			tt.Assert.Equal("6.0000000", records[0].Paths[0].SourceAmount)
+			tt.Assert.Equal("11.0000000", records[0].Paths[1].DestinationAmount)
+			tt.Assert.Equal("EUR", records[0].Paths[1].Path[0].Code)
+		}
+	}
+
+	q = make(url.Values)
+	q.Add("source_assets", "native")
+	q.Add("destination_asset_issuer", "GDSBCQO34HWPGUGQSP3QBFEXVTSR2PW46UIGTHVWGWJGQKH3AFNHXHXN")
+	q.Add("destination_asset_type", "credit_alphanum4")
+	q.Add("destination_asset_code", "USD")
+	q.Add("destination_amount", "10")
+	q.Add("split", "true")
+
+	w = rh.Get("/paths/strict-receive?" + q.Encode())
+	assertions.Equal(auroraProblem.ServerOverCapacity.Status, w.Code)
+	assertions.Problem(w.Body, auroraProblem.ServerOverCapacity)
+
+	finder.AssertExpectations(t)
+}
+
 func assetsToURLParam(xdrAssets []xdr.Asset) string {
 	var assets []string
 	for _, xdrAsset := range xdrAssets {
@@ -708,6 +789,12 @@ func TestFindFixedPathsQueryQueryURLTemplate(t *testing.T) {
 		"source_asset_issuer",
 		"source_asset_code",
 		"source_amount",
+		"exclude_assets",
+		"include_only_assets",
+		"exclude_pools",
+		"venues",
+		"max_hops",
+		"split",
 	}
 	expected := "/paths/strict-send{?" + strings.Join(params, ",") + "}"
 	qp := actions.FindFixedPathsQuery{}
@@ -724,6 +811,12 @@ func TestStrictReceivePathsQueryURLTemplate(t *testing.T) {
 		"destination_asset_issuer",
 		"destination_asset_code",
 		"destination_amount",
+		"exclude_assets",
+		"include_only_assets",
+		"exclude_pools",
+		"venues",
+		"max_hops",
+		"split",
 	}
 	expected := "/paths/strict-receive{?" + strings.Join(params, ",") + "}"
 	qp := actions.StrictReceivePathsQuery{}
@@ -24,6 +24,7 @@ import (
 	"github.com/hcnet/go/services/aurora/internal/paths"
 	"github.com/hcnet/go/services/aurora/internal/reap"
 	"github.com/hcnet/go/services/aurora/internal/txsub"
+	"github.com/hcnet/go/services/aurora/internal/webhooks"
 	"github.com/hcnet/go/support/app"
 	"github.com/hcnet/go/support/db"
 	"github.com/hcnet/go/support/errors"
@@ -47,8 +48,10 @@ type App struct {
 	paths           paths.Finder
 	ingester        ingest.System
 	reaper          *reap.System
+	webhooks        *webhooks.System
 	ticks           *time.Ticker
 	ledgerState     *ledger.State
+	ledgerNotifier  *ledger.Notifier
 
 	// metrics
 	prometheusRegistry *prometheus.Registry
@@ -114,6 +117,14 @@ func (a *App) Serve() error {
 		}()
 	}
 
+	if a.webhooks != nil {
+		wg.Add(1)
+		go func() {
+			a.webhooks.Run()
+			wg.Done()
+		}()
+	}
+
 	// configure shutdown signal handler
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
@@ -181,6 +192,9 @@ func (a *App) waitForDone() {
 	if a.reaper != nil {
 		a.reaper.Shutdown()
 	}
+	if a.webhooks != nil {
+		a.webhooks.Shutdown()
+	}
 	a.ticks.Stop()
 }
 
@@ -494,7 +508,14 @@ func (a *App) init() error {
 	initSubmissionSystem(a)
 
 	// reaper
-	a.reaper = reap.New(a.config.HistoryRetentionCount, a.AuroraSession(), a.ledgerState)
+	initReaper(a)
+
+	initLedgerNotifier(a)
+
+	if a.config.EnableWebhooks {
+		// webhooks
+		initWebhooks(a)
+	}
 
 	// go metrics
 	initGoMetrics(a)
@@ -511,6 +532,12 @@ func (a *App) init() error {
 	// txsub.metrics
 	initTxSubMetrics(a)
 
+	// reap.metrics
+	initReapMetrics(a)
+
+	// webhooks.metrics
+	initWebhooksMetrics(a)
+
 	routerConfig := httpx.RouterConfig{
 		DBSession:               a.historyQ.SessionInterface,
 		TxSubmitter:             a.submitter,
@@ -518,6 +545,7 @@ func (a *App) init() error {
 		BehindCloudflare:        a.config.BehindCloudflare,
 		BehindAWSLoadBalancer:   a.config.BehindAWSLoadBalancer,
 		SSEUpdateFrequency:      a.config.SSEUpdateFrequency,
+		LedgerNotifier:          a.ledgerNotifier,
 		StaleThreshold:          a.config.StaleThreshold,
 		ConnectionTimeout:       a.config.ConnectionTimeout,
 		NetworkPassphrase:       a.config.NetworkPassphrase,
@@ -537,7 +565,15 @@ func (a *App) init() error {
 			},
 			cache: newHealthCache(healthCacheTTL),
 		},
-		EnableIngestionFiltering: a.config.EnableIngestionFiltering,
+		EnableIngestionFiltering:  a.config.EnableIngestionFiltering,
+		MaxWebSocketSubscriptions: a.config.MaxWebSocketSubscriptions,
+		EnableWebhooks:            a.config.EnableWebhooks,
+		EnableAPIKeys:             a.config.EnableAPIKeys,
+		HistoryRetention:          a.config.HistoryRetentionCount > 0 || len(a.config.HistoryRetentionPolicies) > 0,
+	}
+
+	if a.ingester != nil {
+		routerConfig.Ingester = a.ingester
 	}
 
 	if a.primaryHistoryQ != nil {
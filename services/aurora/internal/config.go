@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/hcnet/go/ingest/ledgerbackend"
+	"github.com/hcnet/go/services/aurora/internal/reap"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stellar/throttled"
@@ -46,6 +47,19 @@ type Config struct {
 	LogLevel           logrus.Level
 	LogFile            string
 
+	// MaxWebSocketSubscriptions is the maximum number of streams a single
+	// WebSocket connection can subscribe to. 0 disables the WebSocket
+	// endpoint.
+	MaxWebSocketSubscriptions uint
+
+	// EnableWebhooks enables the delivery of webhooks and the admin endpoints
+	// managing webhook subscriptions.
+	EnableWebhooks bool
+
+	// EnableAPIKeys rate limits requests sending an API key by the quotas of
+	// the key and enables the admin endpoints managing API keys.
+	EnableAPIKeys bool
+
 	// MaxPathLength is the maximum length of the path returned by `/paths` endpoint.
 	MaxPathLength uint
 	// MaxAssetsPerPathRequest is the maximum number of assets considered for `/paths/strict-send` and `/paths/strict-receive`
@@ -79,6 +93,24 @@ type Config struct {
 	// determining a "retention duration", each ledger roughly corresponds to 10
 	// seconds of real time.
 	HistoryRetentionCount uint
+	// HistoryRetentionPolicies contains per table group retention policies
+	// which override HistoryRetentionCount, ex. to retain effects and
+	// operations for a limited time but keep trades and ledgers forever.
+	HistoryRetentionPolicies map[string]reap.RetentionPolicy
+	// HistoryRetentionReapBatchSize is the maximum number of rows removed
+	// from a history table in a single query by the reaper.
+	HistoryRetentionReapBatchSize uint
+	// HistoryRetentionArchiveURL is the URL (file:// or s3://) where the
+	// reaper writes history rows before removing them. Empty disables
+	// archiving.
+	HistoryRetentionArchiveURL string
+	// HistoryRetentionArchiveS3Region and HistoryRetentionArchiveS3Endpoint
+	// configure the S3 (or S3-compatible) store of HistoryRetentionArchiveURL.
+	HistoryRetentionArchiveS3Region   string
+	HistoryRetentionArchiveS3Endpoint string
+	// HistoryRetentionArchiveS3ACL is the canned ACL of the files uploaded to
+	// the S3 store of HistoryRetentionArchiveURL, none when empty.
+	HistoryRetentionArchiveS3ACL string
 	// StaleThreshold represents the number of ledgers a history database may be
 	// out-of-date by before aurora begins to respond with an error to history
 	// requests.
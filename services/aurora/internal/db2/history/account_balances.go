@@ -0,0 +1,101 @@
+package history
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/guregu/null"
+
+	"github.com/hcnet/go/support/db"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/toid"
+	"github.com/hcnet/go/xdr"
+)
+
+// AccountBalance is a row of data from the `history_account_balances` table.
+// Each row records how a native or trust line balance of an account changed
+// in a ledger. Native balances have an empty asset code and issuer.
+type AccountBalance struct {
+	LedgerToid      int64         `db:"ledger_toid"`
+	AccountID       string        `db:"account_id"`
+	AssetType       xdr.AssetType `db:"asset_type"`
+	AssetCode       string        `db:"asset_code"`
+	AssetIssuer     string        `db:"asset_issuer"`
+	Balance         null.Int      `db:"balance"`
+	PreviousBalance null.Int      `db:"previous_balance"`
+}
+
+// AccountBalanceBatchInsertBuilder is used to insert balance changes into the
+// history_account_balances table
+type AccountBalanceBatchInsertBuilder interface {
+	Add(ctx context.Context, entries ...AccountBalance) error
+	Exec(ctx context.Context) error
+}
+
+// QAccountBalances defines balance history related queries.
+type QAccountBalances interface {
+	NewAccountBalanceBatchInsertBuilder(maxBatchSize int) AccountBalanceBatchInsertBuilder
+}
+
+// accountBalanceBatchInsertBuilder is a simple wrapper around db.BatchInsertBuilder
+type accountBalanceBatchInsertBuilder struct {
+	builder db.BatchInsertBuilder
+}
+
+// NewAccountBalanceBatchInsertBuilder constructs a new AccountBalanceBatchInsertBuilder instance
+func (q *Q) NewAccountBalanceBatchInsertBuilder(maxBatchSize int) AccountBalanceBatchInsertBuilder {
+	return &accountBalanceBatchInsertBuilder{
+		builder: db.BatchInsertBuilder{
+			Table:        q.GetTable("history_account_balances"),
+			MaxBatchSize: maxBatchSize,
+		},
+	}
+}
+
+// Add adds new balance changes to the batch
+func (i *accountBalanceBatchInsertBuilder) Add(ctx context.Context, entries ...AccountBalance) error {
+	for _, entry := range entries {
+		if err := i.builder.RowStruct(ctx, entry); err != nil {
+			return errors.Wrap(err, "failed to add account balance")
+		}
+	}
+	return nil
+}
+
+// Exec flushes all outstanding balance changes to the database
+func (i *accountBalanceBatchInsertBuilder) Exec(ctx context.Context) error {
+	return i.builder.Exec(ctx)
+}
+
+// FirstAccountBalanceChangesAfter returns, for every asset held by the
+// account, the first balance change recorded after the given ledger. The
+// previous balance of each returned row is the balance the account had at the
+// end of that ledger.
+func (q *Q) FirstAccountBalanceChangesAfter(ctx context.Context, accountID string, ledger uint32) ([]AccountBalance, error) {
+	var balances []AccountBalance
+	sql := sq.Select(`DISTINCT ON (asset_type, asset_code, asset_issuer)
+			ledger_toid,
+			account_id,
+			asset_type,
+			asset_code,
+			asset_issuer,
+			balance,
+			previous_balance`).
+		From("history_account_balances").
+		Where(sq.Eq{"account_id": accountID}).
+		Where(sq.GtOrEq{"ledger_toid": toid.New(int32(ledger+1), 0, 0).ToInt64()}).
+		OrderBy("asset_type", "asset_code", "asset_issuer", "ledger_toid ASC")
+	err := q.Select(ctx, &balances, sql)
+	return balances, err
+}
+
+// ElderAccountBalanceLedger returns the sequence of the oldest ledger with
+// recorded balance changes or 0 if the balance history is empty.
+func (q *Q) ElderAccountBalanceLedger(ctx context.Context) (uint32, error) {
+	var ledgerToid int64
+	err := q.GetRaw(ctx, &ledgerToid, `SELECT COALESCE(MIN(ledger_toid), 0) FROM history_account_balances`)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(toid.Parse(ledgerToid).LedgerSequence), nil
+}
@@ -0,0 +1,69 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/guregu/null"
+
+	"github.com/hcnet/go/services/aurora/internal/test"
+	"github.com/hcnet/go/toid"
+	"github.com/hcnet/go/xdr"
+)
+
+func TestAccountBalances(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{SessionInterface: tt.AuroraSession()}
+
+	elder, err := q.ElderAccountBalanceLedger(tt.Ctx)
+	tt.Require.NoError(err)
+	tt.Assert.Equal(uint32(0), elder)
+
+	account := "GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB"
+	native := func(ledger int32, previous, balance null.Int) AccountBalance {
+		return AccountBalance{
+			LedgerToid:      toid.New(ledger, 0, 0).ToInt64(),
+			AccountID:       account,
+			AssetType:       xdr.AssetTypeAssetTypeNative,
+			Balance:         balance,
+			PreviousBalance: previous,
+		}
+	}
+	usd := AccountBalance{
+		LedgerToid:  toid.New(12, 0, 0).ToInt64(),
+		AccountID:   account,
+		AssetType:   xdr.AssetTypeAssetTypeCreditAlphanum4,
+		AssetCode:   "USD",
+		AssetIssuer: account,
+		Balance:     null.IntFrom(10),
+	}
+	other := native(11, null.Int{}, null.IntFrom(5))
+	other.AccountID = "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H"
+
+	builder := q.NewAccountBalanceBatchInsertBuilder(2)
+	tt.Require.NoError(builder.Add(tt.Ctx,
+		native(10, null.Int{}, null.IntFrom(100)),
+		native(11, null.IntFrom(100), null.IntFrom(90)),
+		native(13, null.IntFrom(90), null.IntFrom(80)),
+		usd,
+		other,
+	))
+	tt.Require.NoError(builder.Exec(tt.Ctx))
+
+	elder, err = q.ElderAccountBalanceLedger(tt.Ctx)
+	tt.Require.NoError(err)
+	tt.Assert.Equal(uint32(10), elder)
+
+	changes, err := q.FirstAccountBalanceChangesAfter(tt.Ctx, account, 9)
+	tt.Require.NoError(err)
+	tt.Assert.Equal([]AccountBalance{native(10, null.Int{}, null.IntFrom(100)), usd}, changes)
+
+	changes, err = q.FirstAccountBalanceChangesAfter(tt.Ctx, account, 12)
+	tt.Require.NoError(err)
+	tt.Assert.Equal([]AccountBalance{native(13, null.IntFrom(90), null.IntFrom(80))}, changes)
+
+	changes, err = q.FirstAccountBalanceChangesAfter(tt.Ctx, account, 13)
+	tt.Require.NoError(err)
+	tt.Assert.Empty(changes)
+}
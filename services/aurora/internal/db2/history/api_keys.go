@@ -0,0 +1,140 @@
+package history
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/hcnet/go/support/errors"
+)
+
+const apiKeysTableName = "api_keys"
+
+// Route classes of the requests weighted by APIKeyWeights.
+const (
+	RouteClassDefault = "default"
+	RouteClassPaths   = "paths"
+	RouteClassStreams = "streams"
+)
+
+// RouteClasses are all the route classes.
+var RouteClasses = []string{RouteClassDefault, RouteClassPaths, RouteClassStreams}
+
+// HashAPIKey returns the hash of an API key stored in the `api_keys` table.
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// APIKeyWeights maps route classes to the number of requests counted against
+// the quotas of an API key for each request of the class.
+type APIKeyWeights map[string]int
+
+func (w APIKeyWeights) Value() (driver.Value, error) {
+	if w == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(w)
+}
+
+func (w *APIKeyWeights) Scan(src interface{}) error {
+	source, ok := src.([]byte)
+	if !ok {
+		return errors.New("Type assertion .([]byte) failed.")
+	}
+
+	return json.Unmarshal(source, &w)
+}
+
+// APIKey is a row of data from the `api_keys` table. Requests authenticated
+// with the key are rate limited by its quotas instead of by remote IP address.
+type APIKey struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+	// KeyHash is the hex encoded sha256 hash of the key.
+	KeyHash string `db:"key_hash"`
+	// PerSecondLimit and PerHourLimit are the request quotas of the key, 0
+	// is unlimited.
+	PerSecondLimit int           `db:"per_second_limit"`
+	PerHourLimit   int           `db:"per_hour_limit"`
+	Weights        APIKeyWeights `db:"weights"`
+	CreatedAt      time.Time     `db:"created_at"`
+	UpdatedAt      time.Time     `db:"updated_at"`
+}
+
+var selectAPIKey = sq.Select(
+	"id", "name", "key_hash", "per_second_limit", "per_hour_limit", "weights",
+	"created_at", "updated_at",
+).From(apiKeysTableName)
+
+// APIKeys returns all API keys ordered by id.
+func (q *Q) APIKeys(ctx context.Context) ([]APIKey, error) {
+	var keys []APIKey
+	err := q.Select(ctx, &keys, selectAPIKey.OrderBy("id asc"))
+	return keys, err
+}
+
+// APIKeyByID returns the API key with the given id, or sql.ErrNoRows if it
+// does not exist.
+func (q *Q) APIKeyByID(ctx context.Context, id int64) (APIKey, error) {
+	var key APIKey
+	err := q.Get(ctx, &key, selectAPIKey.Where("id = ?", id))
+	return key, err
+}
+
+// InsertAPIKey creates an API key and returns it.
+func (q *Q) InsertAPIKey(ctx context.Context, key APIKey) (APIKey, error) {
+	var id int64
+	sql := sq.Insert(apiKeysTableName).SetMap(map[string]interface{}{
+		"name":             key.Name,
+		"key_hash":         key.KeyHash,
+		"per_second_limit": key.PerSecondLimit,
+		"per_hour_limit":   key.PerHourLimit,
+		"weights":          key.Weights,
+	}).Suffix("RETURNING id")
+	if err := q.Get(ctx, &id, sql); err != nil {
+		return APIKey{}, errors.Wrap(err, "could not insert api key")
+	}
+	return q.APIKeyByID(ctx, id)
+}
+
+// UpdateAPIKey updates the name, the quotas and the weights of an API key,
+// the key itself can't be changed. It returns sql.ErrNoRows if the API key
+// does not exist.
+func (q *Q) UpdateAPIKey(ctx context.Context, key APIKey) (APIKey, error) {
+	sqlUpdate := sq.Update(apiKeysTableName).SetMap(map[string]interface{}{
+		"name":             key.Name,
+		"per_second_limit": key.PerSecondLimit,
+		"per_hour_limit":   key.PerHourLimit,
+		"weights":          key.Weights,
+		"updated_at":       sq.Expr("NOW()"),
+	}).Where("id = ?", key.ID)
+
+	rowCnt, err := q.checkForError(sqlUpdate, ctx)
+	if err != nil {
+		return APIKey{}, err
+	}
+	if rowCnt < 1 {
+		return APIKey{}, sql.ErrNoRows
+	}
+	return q.APIKeyByID(ctx, key.ID)
+}
+
+// DeleteAPIKey removes an API key. It returns sql.ErrNoRows if the API key
+// does not exist.
+func (q *Q) DeleteAPIKey(ctx context.Context, id int64) error {
+	rowCnt, err := q.checkForError(sq.Delete(apiKeysTableName).Where("id = ?", id), ctx)
+	if err != nil {
+		return err
+	}
+	if rowCnt < 1 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
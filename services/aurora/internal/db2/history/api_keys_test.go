@@ -0,0 +1,58 @@
+package history
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/hcnet/go/services/aurora/internal/test"
+)
+
+func TestAPIKeys(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{SessionInterface: tt.AuroraSession()}
+
+	first, err := q.InsertAPIKey(tt.Ctx, APIKey{
+		Name:           "first",
+		KeyHash:        strings.Repeat("a", 64),
+		PerSecondLimit: 10,
+		PerHourLimit:   1000,
+		Weights:        APIKeyWeights{"paths": 20},
+	})
+	tt.Require.NoError(err)
+	tt.Assert.Equal("first", first.Name)
+	tt.Assert.Equal(APIKeyWeights{"paths": 20}, first.Weights)
+
+	second, err := q.InsertAPIKey(tt.Ctx, APIKey{
+		Name:    "second",
+		KeyHash: strings.Repeat("b", 64),
+	})
+	tt.Require.NoError(err)
+	tt.Assert.Equal(APIKeyWeights{}, second.Weights)
+
+	_, err = q.InsertAPIKey(tt.Ctx, APIKey{Name: "duplicate", KeyHash: strings.Repeat("a", 64)})
+	tt.Assert.Error(err)
+
+	keys, err := q.APIKeys(tt.Ctx)
+	tt.Require.NoError(err)
+	tt.Assert.Equal([]APIKey{first, second}, keys)
+
+	second.PerSecondLimit = 5
+	second.Weights = APIKeyWeights{"streams": 2}
+	second.KeyHash = strings.Repeat("c", 64)
+	updated, err := q.UpdateAPIKey(tt.Ctx, second)
+	tt.Require.NoError(err)
+	tt.Assert.Equal(5, updated.PerSecondLimit)
+	tt.Assert.Equal(APIKeyWeights{"streams": 2}, updated.Weights)
+	// the key can't be changed
+	tt.Assert.Equal(strings.Repeat("b", 64), updated.KeyHash)
+
+	tt.Require.NoError(q.DeleteAPIKey(tt.Ctx, first.ID))
+	_, err = q.APIKeyByID(tt.Ctx, first.ID)
+	tt.Assert.Equal(sql.ErrNoRows, err)
+	tt.Assert.Equal(sql.ErrNoRows, q.DeleteAPIKey(tt.Ctx, first.ID))
+	_, err = q.UpdateAPIKey(tt.Ctx, first)
+	tt.Assert.Equal(sql.ErrNoRows, err)
+}
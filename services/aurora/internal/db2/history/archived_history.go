@@ -0,0 +1,151 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/hcnet/go/support/errors"
+)
+
+// HistoryRangeColumn returns the column containing the toid of each row of
+// the given history table and false if the table is not a history table.
+func HistoryRangeColumn(table string) (string, bool) {
+	if column, ok := historyRangeTables[table]; ok {
+		return column, true
+	}
+	column, ok := historySnapshotTables[table]
+	return column, ok
+}
+
+// HistoryTableColumns returns the names of the columns of a history table in
+// their ordinal order.
+func (q *Q) HistoryTableColumns(ctx context.Context, table string) ([]string, error) {
+	if _, ok := HistoryRangeColumn(table); !ok {
+		return nil, errors.Errorf("unknown history table %s", table)
+	}
+
+	var columns []string
+	err := q.SelectRaw(ctx, &columns, `
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ?
+		ORDER BY ordinal_position
+	`, table)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not load columns of %s", table)
+	}
+	if len(columns) == 0 {
+		return nil, errors.Errorf("table %s not found", table)
+	}
+	return columns, nil
+}
+
+// StreamHistoryRange calls fn with every row of the history table with toid
+// in [start, end), ordered by toid. Values are in the postgres text format,
+// NULL values are nil. The row is only valid until fn returns.
+func (q *Q) StreamHistoryRange(
+	ctx context.Context,
+	table string,
+	columns []string,
+	start, end int64,
+	fn func(row []*string) error,
+) error {
+	column, ok := HistoryRangeColumn(table)
+	if !ok {
+		return errors.Errorf("unknown history table %s", table)
+	}
+
+	selected := make([]string, len(columns))
+	for i, name := range columns {
+		selected[i] = pq.QuoteIdentifier(name) + "::text"
+	}
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s >= ? AND %s < ? ORDER BY %s",
+		strings.Join(selected, ", "), table, column, column, column,
+	)
+
+	rows, err := q.QueryRaw(ctx, query, start, end)
+	if err != nil {
+		return errors.Wrapf(err, "could not query %s", table)
+	}
+	defer rows.Close()
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err = rows.Scan(dest...); err != nil {
+			return errors.Wrapf(err, "could not scan %s row", table)
+		}
+		row := make([]*string, len(columns))
+		for i, value := range values {
+			if value.Valid {
+				row[i] = &values[i].String
+			}
+		}
+		if err = fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// CopyHistoryRows inserts rows into a history table using COPY. Values must
+// be in the postgres text format, NULL values are nil. next must return
+// io.EOF when there are no more rows. It must be called in a transaction.
+func (q *Q) CopyHistoryRows(
+	ctx context.Context,
+	table string,
+	columns []string,
+	next func() ([]*string, error),
+) (int64, error) {
+	if _, ok := HistoryRangeColumn(table); !ok {
+		return 0, errors.Errorf("unknown history table %s", table)
+	}
+	tx := q.GetTx()
+	if tx == nil {
+		return 0, errors.New("cannot be called outside of a transaction")
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(table, columns...))
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not prepare copy into %s", table)
+	}
+	defer stmt.Close()
+
+	var count int64
+	args := make([]interface{}, len(columns))
+	for {
+		row, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		for i, value := range row {
+			if value == nil {
+				args[i] = nil
+			} else {
+				args[i] = *value
+			}
+		}
+		if _, err = stmt.ExecContext(ctx, args...); err != nil {
+			return 0, errors.Wrapf(err, "could not copy row into %s", table)
+		}
+		count++
+	}
+
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		return 0, errors.Wrapf(err, "could not copy rows into %s", table)
+	}
+	return count, nil
+}
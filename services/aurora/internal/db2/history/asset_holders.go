@@ -0,0 +1,170 @@
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/hcnet/go/services/aurora/internal/db2"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/xdr"
+)
+
+// Asset holder types
+const (
+	AssetHolderAccount       = "account"
+	AssetHolderLiquidityPool = "liquidity_pool"
+)
+
+// Trust line authorization filters of AssetHoldersQuery
+const (
+	AuthorizationAuthorized                      = "authorized"
+	AuthorizationAuthorizedToMaintainLiabilities = "authorized_to_maintain_liabilities"
+	AuthorizationUnauthorized                    = "unauthorized"
+)
+
+// AssetHolder is an account holding an asset in a trust line or a liquidity
+// pool holding an asset in its reserves.
+type AssetHolder struct {
+	HolderType string `db:"holder_type"`
+	HolderID   string `db:"holder_id"`
+	Balance    int64  `db:"balance"`
+	// Flags are the trust line flags, always 0 for liquidity pools
+	Flags uint32 `db:"flags"`
+}
+
+// PagingToken returns a cursor for this asset holder
+func (h AssetHolder) PagingToken() string {
+	return fmt.Sprintf("%d-%s", h.Balance, h.HolderID)
+}
+
+// AssetHoldersQuery is a helper struct to configure queries to asset holders
+type AssetHoldersQuery struct {
+	PageQuery  db2.PageQuery
+	Asset      xdr.Asset
+	MinBalance int64
+	// Authorization filters account holders by the authorization flags of
+	// their trust lines. Liquidity pools are excluded when it's set.
+	Authorization string
+}
+
+// Cursor validates and returns the query page cursor
+func (q AssetHoldersQuery) Cursor() (int64, string, error) {
+	if q.PageQuery.Cursor == "" {
+		return 0, "", nil
+	}
+
+	parts := strings.SplitN(q.PageQuery.Cursor, "-", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return 0, "", errors.New("Invalid cursor")
+	}
+	balance, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || balance < 0 {
+		return 0, "", errors.New("Invalid cursor - first value should be a balance")
+	}
+	return balance, parts[1], nil
+}
+
+// GetAssetHolders returns a page of the accounts and liquidity pools holding
+// an asset ordered by balance.
+func (q *Q) GetAssetHolders(ctx context.Context, query AssetHoldersQuery) ([]AssetHolder, error) {
+	balanceCursor, idCursor, err := query.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	var assetType, code, issuer string
+	query.Asset.MustExtract(&assetType, &code, &issuer)
+
+	holders := sq.Select(
+		"'"+AssetHolderAccount+"' AS holder_type",
+		"account_id AS holder_id",
+		"balance",
+		"flags",
+	).From("trust_lines").Where(map[string]interface{}{
+		"asset_type":   int32(query.Asset.Type),
+		"asset_code":   code,
+		"asset_issuer": issuer,
+	})
+	if query.MinBalance > 0 {
+		holders = holders.Where("balance >= ?", query.MinBalance)
+	}
+
+	authorized := uint32(xdr.TrustLineFlagsAuthorizedFlag)
+	maintainLiabilities := uint32(xdr.TrustLineFlagsAuthorizedToMaintainLiabilitiesFlag)
+	switch query.Authorization {
+	case "":
+		pools, err := selectAssetReserves(query.Asset, query.MinBalance)
+		if err != nil {
+			return nil, err
+		}
+		sqlStr, args, err := pools.Prefix("(").Suffix(")").ToSql()
+		if err != nil {
+			return nil, errors.Wrap(err, "could not construct liquidity pools query")
+		}
+		holders = holders.Prefix("(").Suffix(") UNION ALL "+sqlStr, args...)
+	case AuthorizationAuthorized:
+		holders = holders.Where("flags & ? != 0", authorized)
+	case AuthorizationAuthorizedToMaintainLiabilities:
+		holders = holders.Where("flags & ? = ?", authorized|maintainLiabilities, maintainLiabilities)
+	case AuthorizationUnauthorized:
+		holders = holders.Where("flags & ? = 0", authorized|maintainLiabilities)
+	default:
+		return nil, errors.Errorf("invalid authorization: %s", query.Authorization)
+	}
+
+	sql := sq.Select("holder_type", "holder_id", "balance", "flags").
+		FromSelect(holders, "holders")
+	switch query.PageQuery.Order {
+	case db2.OrderAscending:
+		if idCursor != "" {
+			sql = sql.Where(sq.Expr("(balance, holder_id) > (?, ?)", balanceCursor, idCursor))
+		}
+		sql = sql.OrderBy("balance asc, holder_id asc")
+	case db2.OrderDescending:
+		if idCursor != "" {
+			sql = sql.Where(sq.Expr("(balance, holder_id) < (?, ?)", balanceCursor, idCursor))
+		}
+		sql = sql.OrderBy("balance desc, holder_id desc")
+	default:
+		return nil, errors.Errorf("invalid order: %s", query.PageQuery.Order)
+	}
+
+	var results []AssetHolder
+	if err := q.Select(ctx, &results, sql.Limit(query.PageQuery.Limit)); err != nil {
+		return nil, errors.Wrap(err, "could not run select query")
+	}
+	return results, nil
+}
+
+// selectAssetReserves selects the liquidity pools holding the asset in their
+// reserves.
+func selectAssetReserves(asset xdr.Asset, minBalance int64) (sq.SelectBuilder, error) {
+	assetB64, err := xdr.MarshalBase64(asset)
+	if err != nil {
+		return sq.SelectBuilder{}, err
+	}
+	contains, err := json.Marshal([]map[string]string{{"asset": assetB64}})
+	if err != nil {
+		return sq.SelectBuilder{}, err
+	}
+
+	sql := sq.Select(
+		"'"+AssetHolderLiquidityPool+"' AS holder_type",
+		"lp.id AS holder_id",
+		"(reserve.value->>'reserve')::bigint AS balance",
+		"0 AS flags",
+	).
+		From("liquidity_pools lp, jsonb_array_elements(lp.asset_reserves) reserve").
+		Where("lp.deleted = ?", false).
+		Where("lp.asset_reserves @> ?::jsonb", string(contains)).
+		Where("reserve.value->>'asset' = ?", assetB64)
+	if minBalance > 0 {
+		sql = sql.Where("(reserve.value->>'reserve')::bigint >= ?", minBalance)
+	}
+	return sql, nil
+}
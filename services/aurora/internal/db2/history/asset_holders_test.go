@@ -0,0 +1,64 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/hcnet/go/services/aurora/internal/db2"
+	"github.com/hcnet/go/services/aurora/internal/test"
+	"github.com/hcnet/go/xdr"
+)
+
+func TestGetAssetHolders(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{SessionInterface: tt.AuroraSession()}
+
+	usd := xdr.MustNewCreditAsset("USD", trustLineIssuer)
+	richer := usdTrustLine2
+	richer.Balance = 20000
+	richer.Flags = uint32(xdr.TrustLineFlagsAuthorizedFlag)
+	tt.Assert.NoError(q.UpsertTrustLines(tt.Ctx, []TrustLine{eurTrustLine, usdTrustLine, richer}))
+
+	pool := MakeTestPool(usd, 15000, xdr.MustNewNativeAsset(), 100)
+	tt.Assert.NoError(q.UpsertLiquidityPools(tt.Ctx, []LiquidityPool{pool}))
+
+	query := AssetHoldersQuery{
+		PageQuery: db2.PageQuery{Order: db2.OrderDescending, Limit: 2},
+		Asset:     usd,
+	}
+	holders, err := q.GetAssetHolders(tt.Ctx, query)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]AssetHolder{
+		{HolderType: AssetHolderAccount, HolderID: richer.AccountID, Balance: 20000, Flags: 1},
+		{HolderType: AssetHolderLiquidityPool, HolderID: pool.PoolID, Balance: 15000},
+	}, holders)
+
+	query.PageQuery.Cursor = holders[1].PagingToken()
+	holders, err = q.GetAssetHolders(tt.Ctx, query)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]AssetHolder{
+		{HolderType: AssetHolderAccount, HolderID: usdTrustLine.AccountID, Balance: 10000},
+	}, holders)
+
+	query.PageQuery = db2.PageQuery{Order: db2.OrderAscending, Limit: 10}
+	query.MinBalance = 12000
+	holders, err = q.GetAssetHolders(tt.Ctx, query)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]AssetHolder{
+		{HolderType: AssetHolderLiquidityPool, HolderID: pool.PoolID, Balance: 15000},
+		{HolderType: AssetHolderAccount, HolderID: richer.AccountID, Balance: 20000, Flags: 1},
+	}, holders)
+
+	query.MinBalance = 0
+	query.Authorization = AuthorizationUnauthorized
+	holders, err = q.GetAssetHolders(tt.Ctx, query)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]AssetHolder{
+		{HolderType: AssetHolderAccount, HolderID: usdTrustLine.AccountID, Balance: 10000},
+	}, holders)
+
+	query.PageQuery.Cursor = "invalid"
+	_, err = q.GetAssetHolders(tt.Ctx, query)
+	tt.Assert.EqualError(err, "Invalid cursor")
+}
@@ -127,6 +127,15 @@ func (q *EffectsQ) ForOperation(id int64) *EffectsQ {
 	return q
 }
 
+// ForOperations filters the query to only effects of the given operations,
+// ordered by operation.
+func (q *EffectsQ) ForOperations(ids []int64) *EffectsQ {
+	q.sql = q.sql.
+		Where(map[string]interface{}{"heff.history_operation_id": ids}).
+		OrderBy("heff.history_operation_id asc, heff.order asc")
+	return q
+}
+
 // ForLiquidityPool filters the query to only effects in a specific liquidity pool,
 // specified by its id.
 func (q *EffectsQ) ForLiquidityPool(ctx context.Context, page db2.PageQuery, id string) *EffectsQ {
@@ -188,6 +197,43 @@ func (q *EffectsQ) ForTransaction(ctx context.Context, hash string) *EffectsQ {
 	return q
 }
 
+// ForLedgerRange filters the query to only effects in ledgers with sequence
+// in [start, end).
+func (q *EffectsQ) ForLedgerRange(start, end int32) *EffectsQ {
+	q.sql = q.sql.Where(
+		"heff.history_operation_id >= ? AND heff.history_operation_id < ?",
+		toid.New(start, 0, 0).ToInt64(),
+		toid.New(end, 0, 0).ToInt64(),
+	)
+	return q
+}
+
+// ForSponsor filters the query to only the sponsorship effects of the ledger
+// entries and signers sponsored, formerly or newly, by an account. The
+// filter is matched by the partial indexes on the sponsor details of
+// sponsorship effects, whose conditions on the types of effects are the
+// range of the sponsorship effect types.
+func (q *EffectsQ) ForSponsor(sponsor string) *EffectsQ {
+	q.sql = q.sql.
+		Where(fmt.Sprintf(
+			"heff.type BETWEEN %d AND %d",
+			EffectAccountSponsorshipCreated,
+			EffectSignerSponsorshipRemoved,
+		)).
+		Where(sq.Or{
+			sq.Expr("heff.details->>'sponsor' = ?", sponsor),
+			sq.Expr("heff.details->>'former_sponsor' = ?", sponsor),
+			sq.Expr("heff.details->>'new_sponsor' = ?", sponsor),
+		})
+	return q
+}
+
+// ForTypes filters the query to only effects of the given types.
+func (q *EffectsQ) ForTypes(types []EffectType) *EffectsQ {
+	q.sql = q.sql.Where(sq.Eq{"heff.type": types})
+	return q
+}
+
 // Page specifies the paging constraints for the query being built by `q`.
 func (q *EffectsQ) Page(page db2.PageQuery) *EffectsQ {
 	if q.Err != nil {
@@ -3,13 +3,17 @@ package history
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/guregu/null"
 	"github.com/hcnet/go/protocols/aurora/effects"
 	"github.com/hcnet/go/services/aurora/internal/db2"
+	"github.com/hcnet/go/services/aurora/internal/db2/schema"
 	"github.com/hcnet/go/services/aurora/internal/test"
 	"github.com/hcnet/go/toid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEffectsForLiquidityPool(t *testing.T) {
@@ -185,3 +189,86 @@ func TestEffectsForTrustlinesSponsorshipEmptyAssetType(t *testing.T) {
 		}
 	}
 }
+
+func TestEffectsLedgerRangeAndTypeFilters(t *testing.T) {
+	tt := test.Start(t)
+	tt.Scenario("base")
+	defer tt.Finish()
+	q := &Q{tt.AuroraSession()}
+
+	var effects []Effect
+	err := q.Effects().ForLedgerRange(3, 4).Select(tt.Ctx, &effects)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(effects, 2)
+
+	effects = nil
+	err = q.Effects().
+		ForLedgerRange(2, 3).
+		ForTypes([]EffectType{EffectAccountCreated}).
+		Select(tt.Ctx, &effects)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(effects, 3)
+
+	effects = nil
+	err = q.Effects().
+		ForTypes([]EffectType{EffectAccountCredited, EffectAccountDebited}).
+		Select(tt.Ctx, &effects)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(effects, 5)
+}
+
+func TestEffectsForSponsor(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{SessionInterface: tt.AuroraSession()}
+
+	address := "GAQAA5L65LSYH7CQ3VTJ7F3HHLGCL3DSLAR2Y47263D56MNNGHSQSTVY"
+	accountIDs, err := q.CreateAccounts(tt.Ctx, []string{address}, 1)
+	tt.Assert.NoError(err)
+
+	otherSponsor := "GDMQUXK7ZUCWM5472ZU3YLDP4BMJLQQ76DEMNYDEY2ODEEGGRKLEWGW2"
+	builder := q.NewEffectBatchInsertBuilder(1)
+	for i, effect := range []struct {
+		effectType EffectType
+		details    map[string]string
+	}{
+		{EffectAccountSponsorshipCreated, map[string]string{"sponsor": sponsor}},
+		{EffectTrustlineSponsorshipCreated, map[string]string{"sponsor": otherSponsor}},
+		{EffectTrustlineSponsorshipUpdated, map[string]string{"former_sponsor": otherSponsor, "new_sponsor": sponsor}},
+		{EffectSignerSponsorshipRemoved, map[string]string{"former_sponsor": sponsor}},
+		{EffectAccountCredited, map[string]string{"sponsor": sponsor}},
+	} {
+		var details []byte
+		details, err = json.Marshal(effect.details)
+		tt.Require.NoError(err)
+		err = builder.Add(tt.Ctx,
+			accountIDs[address],
+			null.String{},
+			toid.New(56, 1, 1).ToInt64(),
+			uint32(i),
+			effect.effectType,
+			details,
+		)
+		tt.Require.NoError(err)
+	}
+	tt.Require.NoError(builder.Exec(tt.Ctx))
+
+	var results []Effect
+	err = q.Effects().ForSponsor(sponsor).Select(tt.Ctx, &results)
+	tt.Require.NoError(err)
+	if tt.Assert.Len(results, 3) {
+		tt.Assert.Equal(EffectAccountSponsorshipCreated, results[0].Type)
+		tt.Assert.Equal(EffectTrustlineSponsorshipUpdated, results[1].Type)
+		tt.Assert.Equal(EffectSignerSponsorshipRemoved, results[2].Type)
+	}
+}
+
+func TestForSponsorMatchesSponsorIndexes(t *testing.T) {
+	// the partial indexes on the sponsors of sponsorship effects are only
+	// used when the filter on the types of ForSponsor matches them
+	migration, err := schema.AssetString("migrations/70_history_effects_sponsor_indexes.sql")
+	require.NoError(t, err)
+	types := fmt.Sprintf("WHERE type BETWEEN %d AND %d;", EffectAccountSponsorshipCreated, EffectSignerSponsorshipRemoved)
+	assert.Equal(t, 3, strings.Count(migration, types))
+}
@@ -0,0 +1,219 @@
+package history
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/hcnet/go/services/aurora/internal/db2"
+	"github.com/hcnet/go/support/db"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/xdr"
+)
+
+// LiquidityPoolSnapshot is a row of data from the
+// `history_liquidity_pool_snapshots` table. Each row records the state of a
+// liquidity pool at the end of a ledger which changed it.
+type LiquidityPoolSnapshot struct {
+	LedgerToid int64  `db:"ledger_toid"`
+	PoolID     string `db:"liquidity_pool_id"`
+	// AssetA and AssetB are the assets of ReserveA and ReserveB, they are
+	// recorded so that the history of deleted pools can be served.
+	AssetA         xdr.Asset `db:"asset_a"`
+	AssetB         xdr.Asset `db:"asset_b"`
+	ReserveA       int64     `db:"reserve_a"`
+	ReserveB       int64     `db:"reserve_b"`
+	ShareCount     int64     `db:"share_count"`
+	TrustlineCount int64     `db:"trustline_count"`
+	Deleted        bool      `db:"deleted"`
+}
+
+// LiquidityPoolHistoryBucket is the last snapshot of a liquidity pool in a
+// bucket of time.
+type LiquidityPoolHistoryBucket struct {
+	// Timestamp is the start of the bucket in milliseconds since epoch.
+	Timestamp int64 `db:"timestamp"`
+	LiquidityPoolSnapshot
+}
+
+// LiquidityPoolTradeVolume aggregates the trades of a liquidity pool in a
+// bucket of time by the asset received by the pool.
+type LiquidityPoolTradeVolume struct {
+	// Timestamp is the start of the bucket in milliseconds since epoch.
+	Timestamp  int64 `db:"timestamp"`
+	AssetID    int64 `db:"asset_id"`
+	TradeCount int64 `db:"trade_count"`
+	// AmountIn is the amount of the asset received by the pool.
+	AmountIn int64 `db:"amount_in"`
+	// AmountOut is the amount of the other asset sold by the pool.
+	AmountOut int64 `db:"amount_out"`
+	// Fees is the part of AmountIn charged as liquidity pool fee.
+	Fees int64 `db:"fees"`
+}
+
+// LiquidityPoolHistoryQuery selects the buckets of the history of a
+// liquidity pool: times are in milliseconds since epoch, EndTime is
+// exclusive and ignored when 0.
+type LiquidityPoolHistoryQuery struct {
+	PoolID     string
+	Resolution int64
+	StartTime  int64
+	EndTime    int64
+	Order      string
+	Limit      uint64
+}
+
+// InsertLiquidityPoolSnapshots inserts the snapshots of liquidity pools,
+// snapshots of the same pool and ledger are replaced.
+func (q *Q) InsertLiquidityPoolSnapshots(ctx context.Context, snapshots []LiquidityPoolSnapshot) error {
+	builder := db.BatchInsertBuilder{
+		Table:        q.GetTable("history_liquidity_pool_snapshots"),
+		MaxBatchSize: 10000,
+		Suffix: "ON CONFLICT (liquidity_pool_id, ledger_toid) DO UPDATE SET " +
+			"reserve_a = excluded.reserve_a, " +
+			"reserve_b = excluded.reserve_b, " +
+			"share_count = excluded.share_count, " +
+			"trustline_count = excluded.trustline_count, " +
+			"deleted = excluded.deleted",
+	}
+	for _, snapshot := range snapshots {
+		if err := builder.RowStruct(ctx, snapshot); err != nil {
+			return errors.Wrap(err, "failed to add liquidity pool snapshot")
+		}
+	}
+	return builder.Exec(ctx)
+}
+
+// bucketTimestamp returns the sql expression of the start of the bucket of
+// a timestamp column in milliseconds since epoch.
+func bucketTimestamp(column string, resolution int64) string {
+	return fmt.Sprintf("(floor(extract(epoch from %s) * 1000)::bigint / %d) * %d", column, resolution, resolution)
+}
+
+func (query LiquidityPoolHistoryQuery) timeRange(column string, sql sq.SelectBuilder) sq.SelectBuilder {
+	sql = sql.Where(fmt.Sprintf("%s >= to_timestamp(?::double precision / 1000)", column), query.StartTime)
+	if query.EndTime > 0 {
+		sql = sql.Where(fmt.Sprintf("%s < to_timestamp(?::double precision / 1000)", column), query.EndTime)
+	}
+	return sql
+}
+
+// GetLiquidityPoolHistory returns the last snapshot of a liquidity pool in
+// every bucket of time in which the pool changed.
+func (q *Q) GetLiquidityPoolHistory(ctx context.Context, query LiquidityPoolHistoryQuery) ([]LiquidityPoolHistoryBucket, error) {
+	if query.Order != db2.OrderAscending && query.Order != db2.OrderDescending {
+		return nil, errors.Errorf("invalid order %s", query.Order)
+	}
+
+	snapshots := sq.Select(
+		"DISTINCT ON (timestamp) "+bucketTimestamp("hl.closed_at", query.Resolution)+" AS timestamp",
+		"s.ledger_toid",
+		"s.liquidity_pool_id",
+		"s.reserve_a",
+		"s.reserve_b",
+		"s.share_count",
+		"s.trustline_count",
+		"s.deleted",
+	).
+		From("history_liquidity_pool_snapshots s").
+		Join("history_ledgers hl ON hl.id = s.ledger_toid").
+		Where(sq.Eq{"s.liquidity_pool_id": query.PoolID}).
+		OrderBy("timestamp", "s.ledger_toid DESC")
+	snapshots = query.timeRange("hl.closed_at", snapshots)
+
+	sql := sq.Select("*").
+		FromSelect(snapshots, "buckets").
+		OrderBy("timestamp " + query.Order).
+		Limit(query.Limit)
+
+	var buckets []LiquidityPoolHistoryBucket
+	err := q.Select(ctx, &buckets, sql)
+	return buckets, err
+}
+
+// selectLiquidityPoolSnapshots selects the snapshots with their assets.
+var selectLiquidityPoolSnapshots = sq.Select(
+	"s.ledger_toid",
+	"s.liquidity_pool_id",
+	"s.asset_a",
+	"s.asset_b",
+	"s.reserve_a",
+	"s.reserve_b",
+	"s.share_count",
+	"s.trustline_count",
+	"s.deleted",
+).From("history_liquidity_pool_snapshots s")
+
+// LatestLiquidityPoolSnapshot returns the last snapshot of a liquidity pool,
+// including pools which were deleted since. It returns sql.ErrNoRows if the
+// pool has no snapshot.
+func (q *Q) LatestLiquidityPoolSnapshot(ctx context.Context, poolID string) (LiquidityPoolSnapshot, error) {
+	sql := selectLiquidityPoolSnapshots.
+		Where(sq.Eq{"s.liquidity_pool_id": poolID}).
+		OrderBy("s.ledger_toid DESC").
+		Limit(1)
+
+	var snapshot LiquidityPoolSnapshot
+	err := q.Get(ctx, &snapshot, sql)
+	return snapshot, err
+}
+
+// LiquidityPoolSnapshotBefore returns the last snapshot of a liquidity pool
+// in a ledger closed before timestamp, in milliseconds since epoch.
+func (q *Q) LiquidityPoolSnapshotBefore(ctx context.Context, poolID string, timestamp int64) (LiquidityPoolSnapshot, error) {
+	sql := selectLiquidityPoolSnapshots.
+		Join("history_ledgers hl ON hl.id = s.ledger_toid").
+		Where(sq.Eq{"s.liquidity_pool_id": poolID}).
+		Where("hl.closed_at < to_timestamp(?::double precision / 1000)", timestamp).
+		OrderBy("s.ledger_toid DESC").
+		Limit(1)
+
+	var snapshot LiquidityPoolSnapshot
+	err := q.Get(ctx, &snapshot, sql)
+	return snapshot, err
+}
+
+// GetLiquidityPoolTradeVolumes aggregates the trades of a liquidity pool by
+// bucket of time and by asset received by the pool. The trades in which the
+// pool is the base and the counter party are selected separately so that
+// each selection uses the index of its liquidity pool column.
+func (q *Q) GetLiquidityPoolTradeVolumes(ctx context.Context, query LiquidityPoolHistoryQuery) ([]LiquidityPoolTradeVolume, error) {
+	poolTrades := func(poolColumn, inAsset, inAmount, outAmount string) sq.SelectBuilder {
+		sql := sq.Select(
+			"ledger_closed_at",
+			inAsset+" AS asset_id",
+			inAmount+" AS amount_in",
+			outAmount+" AS amount_out",
+			"liquidity_pool_fee",
+		).
+			From("history_trades").
+			Where(poolColumn+" = (SELECT id FROM history_liquidity_pools WHERE liquidity_pool_id = ?)", query.PoolID)
+		return query.timeRange("ledger_closed_at", sql)
+	}
+	counterSQL, counterArgs, err := poolTrades(
+		"counter_liquidity_pool_id", "base_asset_id", "base_amount", "counter_amount",
+	).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	trades := poolTrades(
+		"base_liquidity_pool_id", "counter_asset_id", "counter_amount", "base_amount",
+	).Suffix("UNION ALL "+counterSQL, counterArgs...)
+
+	sql := sq.Select(
+		bucketTimestamp("trades.ledger_closed_at", query.Resolution)+" AS timestamp",
+		"trades.asset_id",
+		"count(*) AS trade_count",
+		"sum(trades.amount_in)::bigint AS amount_in",
+		"sum(trades.amount_out)::bigint AS amount_out",
+		"floor(sum(trades.amount_in::numeric * COALESCE(trades.liquidity_pool_fee, 0) / 10000))::bigint AS fees",
+	).
+		FromSelect(trades, "trades").
+		GroupBy("1", "2").
+		OrderBy("1", "2")
+
+	var volumes []LiquidityPoolTradeVolume
+	err = q.Select(ctx, &volumes, sql)
+	return volumes, err
+}
@@ -0,0 +1,113 @@
+package history
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/guregu/null"
+
+	"github.com/hcnet/go/services/aurora/internal/db2"
+	"github.com/hcnet/go/services/aurora/internal/test"
+	"github.com/hcnet/go/toid"
+	"github.com/hcnet/go/xdr"
+)
+
+func TestLiquidityPoolHistory(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{SessionInterface: tt.AuroraSession()}
+
+	usd := xdr.MustNewCreditAsset("USD", "GAXMF43TGZHW3QN3REOUA2U5PW5BTARXGGYJ3JIFHW3YT6QRKRL3CPPU")
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	closeTimes := map[int32]time.Time{
+		10: start.Add(10 * time.Minute),
+		11: start.Add(50 * time.Minute),
+		12: start.Add(2*time.Hour + 5*time.Minute),
+		13: start.Add(2*time.Hour + 30*time.Minute),
+	}
+	var snapshots []LiquidityPoolSnapshot
+	for sequence, closedAt := range closeTimes {
+		ledger := Ledger{
+			Sequence:                   sequence,
+			LedgerHash:                 "4db1e4f145e9ee75162040d26284795e0697e2e84084624e7c6c723ebbf80118",
+			PreviousLedgerHash:         null.NewString("4b0b8bace3b2438b2404776ce57643966855487ba6384724a3c664c7aa4cd9e4", true),
+			TotalOrderID:               TotalOrderID{toid.New(sequence, 0, 0).ToInt64()},
+			SuccessfulTransactionCount: new(int32),
+			FailedTransactionCount:     new(int32),
+			ClosedAt:                   closedAt,
+		}
+		_, err := q.Exec(tt.Ctx, sq.Insert("history_ledgers").SetMap(ledgerToMap(ledger)))
+		tt.Assert.NoError(err)
+
+		snapshots = append(snapshots, LiquidityPoolSnapshot{
+			LedgerToid:     toid.New(sequence, 0, 0).ToInt64(),
+			PoolID:         "cafebabe",
+			AssetA:         xdr.MustNewNativeAsset(),
+			AssetB:         usd,
+			ReserveA:       int64(sequence) * 100,
+			ReserveB:       int64(sequence) * 200,
+			ShareCount:     int64(sequence),
+			TrustlineCount: 1,
+		})
+	}
+	tt.Assert.NoError(q.InsertLiquidityPoolSnapshots(tt.Ctx, snapshots))
+	// snapshots of the same ledger are replaced
+	updated := snapshots[0]
+	updated.Deleted = true
+	tt.Assert.NoError(q.InsertLiquidityPoolSnapshots(tt.Ctx, []LiquidityPoolSnapshot{updated}))
+
+	hour := time.Hour.Milliseconds()
+	query := LiquidityPoolHistoryQuery{
+		PoolID:     "cafebabe",
+		Resolution: hour,
+		Order:      db2.OrderAscending,
+		Limit:      10,
+	}
+	buckets, err := q.GetLiquidityPoolHistory(tt.Ctx, query)
+	tt.Assert.NoError(err)
+	if tt.Assert.Len(buckets, 2) {
+		// the last snapshot of every hour
+		tt.Assert.Equal(start.UnixMilli(), buckets[0].Timestamp)
+		tt.Assert.Equal(toid.New(11, 0, 0).ToInt64(), buckets[0].LedgerToid)
+		tt.Assert.Equal(int64(2200), buckets[0].ReserveB)
+		tt.Assert.Equal(start.Add(2*time.Hour).UnixMilli(), buckets[1].Timestamp)
+		tt.Assert.Equal(toid.New(13, 0, 0).ToInt64(), buckets[1].LedgerToid)
+	}
+
+	query.Order = db2.OrderDescending
+	query.Limit = 1
+	buckets, err = q.GetLiquidityPoolHistory(tt.Ctx, query)
+	tt.Assert.NoError(err)
+	if tt.Assert.Len(buckets, 1) {
+		tt.Assert.Equal(start.Add(2*time.Hour).UnixMilli(), buckets[0].Timestamp)
+	}
+
+	query.Order = db2.OrderAscending
+	query.StartTime = start.Add(time.Hour).UnixMilli()
+	query.EndTime = start.Add(2 * time.Hour).UnixMilli()
+	buckets, err = q.GetLiquidityPoolHistory(tt.Ctx, query)
+	tt.Assert.NoError(err)
+	tt.Assert.Empty(buckets)
+
+	snapshot, err := q.LiquidityPoolSnapshotBefore(tt.Ctx, "cafebabe", start.Add(2*time.Hour).UnixMilli())
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(toid.New(11, 0, 0).ToInt64(), snapshot.LedgerToid)
+
+	_, err = q.LiquidityPoolSnapshotBefore(tt.Ctx, "cafebabe", start.UnixMilli())
+	tt.Assert.Equal(sql.ErrNoRows, err)
+
+	snapshot, err = q.LatestLiquidityPoolSnapshot(tt.Ctx, "cafebabe")
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(toid.New(13, 0, 0).ToInt64(), snapshot.LedgerToid)
+	tt.Assert.True(usd.Equals(snapshot.AssetB))
+
+	_, err = q.LatestLiquidityPoolSnapshot(tt.Ctx, "deadbeef")
+	tt.Assert.Equal(sql.ErrNoRows, err)
+
+	volumes, err := q.GetLiquidityPoolTradeVolumes(tt.Ctx, query)
+	tt.Assert.NoError(err)
+	tt.Assert.Empty(volumes)
+}
@@ -91,6 +91,7 @@ type QLiquidityPools interface {
 	FindLiquidityPoolByID(ctx context.Context, liquidityPoolID string) (LiquidityPool, error)
 	GetUpdatedLiquidityPools(ctx context.Context, newerThanSequence uint32) ([]LiquidityPool, error)
 	CompactLiquidityPools(ctx context.Context, cutOffSequence uint32) (int64, error)
+	InsertLiquidityPoolSnapshots(ctx context.Context, snapshots []LiquidityPoolSnapshot) error
 }
 
 // UpsertLiquidityPools upserts a batch of liquidity pools  in the liquidity_pools table.
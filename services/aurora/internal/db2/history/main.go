@@ -23,6 +23,7 @@ import (
 	"github.com/hcnet/go/support/db"
 	"github.com/hcnet/go/support/errors"
 	strtime "github.com/hcnet/go/support/time"
+	"github.com/hcnet/go/toid"
 	"github.com/hcnet/go/xdr"
 )
 
@@ -241,6 +242,7 @@ type AccountEntry struct {
 
 type IngestionQ interface {
 	QAccounts
+	QAccountBalances
 	QFilter
 	QAssetStats
 	QClaimableBalances
@@ -252,6 +254,7 @@ type IngestionQ interface {
 	QHistoryLiquidityPools
 	QOffers
 	QOperations
+	QOrderBookSnapshots
 	// QParticipants
 	// Copy the small interfaces with shared methods directly, otherwise error:
 	// duplicate method CreateAccounts
@@ -283,6 +286,7 @@ type IngestionQ interface {
 	GetLiquidityPoolCompactionSequence(context.Context) (uint32, error)
 	TruncateIngestStateTables(context.Context) error
 	DeleteRangeAll(ctx context.Context, start, end int64) error
+	CreateHistoryPartitions(ctx context.Context, table string, untilLedger uint32) ([]HistoryPartition, error)
 	DeleteTransactionsFilteredTmpOlderThan(ctx context.Context, howOldInSeconds uint64) (int64, error)
 }
 
@@ -812,6 +816,23 @@ func (q *Q) LatestLedgerSequenceClosedAt(ctx context.Context) (int32, time.Time,
 	return ledger.Sequence, ledger.ClosedAt, err
 }
 
+// LatestLedgerClosedBefore returns the sequence of the latest ledger closed
+// before the given time or 0 if there is no such ledger.
+func (q *Q) LatestLedgerClosedBefore(ctx context.Context, closedAt time.Time) (int32, error) {
+	var sequence int32
+	err := q.GetRaw(ctx, &sequence, `
+		SELECT sequence
+		FROM history_ledgers
+		WHERE closed_at < ?
+		ORDER BY closed_at DESC
+		LIMIT 1
+	`, closedAt)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return sequence, err
+}
+
 // LatestLedgerBaseFeeAndSequence loads the latest known ledger's base fee and
 // sequence number.
 func (q *Q) LatestLedgerBaseFeeAndSequence(ctx context.Context, dest interface{}) error {
@@ -822,6 +843,17 @@ func (q *Q) LatestLedgerBaseFeeAndSequence(ctx context.Context, dest interface{}
 	`)
 }
 
+// LatestLedgerBaseReserve loads the base reserve of the latest known ledger,
+// returns 0 if no ledgers in a DB.
+func (q *Q) LatestLedgerBaseReserve(ctx context.Context) (int32, error) {
+	var baseReserve int32
+	err := q.GetRaw(ctx, &baseReserve, `SELECT base_reserve FROM history_ledgers ORDER BY sequence DESC LIMIT 1`)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return baseReserve, err
+}
+
 // CloneIngestionQ clones underlying db.Session and returns IngestionQ
 func (q *Q) CloneIngestionQ() IngestionQ {
 	return &Q{q.Clone()}
@@ -1019,23 +1051,46 @@ func constructReapLookupTablesQuery(table string, historyTables []tableObjectFie
 	return sb.String(), nil
 }
 
+// historyRangeTables maps history tables to the column containing the toid
+// (ledger, transaction or operation id) of each row. It's used when deleting
+// ranges of history data. The tables are written by the transaction
+// processors, so ranges deleted by DeleteRangeAll are rebuilt by
+// reingestion.
+var historyRangeTables = map[string]string{
+	"history_account_balances":               "ledger_toid",
+	"history_effects":                        "history_operation_id",
+	"history_ledgers":                        "id",
+	"history_operation_claimable_balances":   "history_operation_id",
+	"history_operation_participants":         "history_operation_id",
+	"history_operation_liquidity_pools":      "history_operation_id",
+	"history_operations":                     "id",
+	"history_trades":                         "history_operation_id",
+	"history_trades_60000":                   "open_ledger_toid",
+	"history_transaction_claimable_balances": "history_transaction_id",
+	"history_transaction_participants":       "history_transaction_id",
+	"history_transaction_liquidity_pools":    "history_transaction_id",
+	"history_transactions":                   "id",
+}
+
+// historySnapshotTables maps the history tables written from the ledger entry
+// changes of the ledgers ingested by the live ingestion to the column
+// containing the toid of each row. Reingestion doesn't process ledger entry
+// changes so these tables are not deleted by DeleteRangeAll, their old rows
+// are only removed by the reaper.
+var historySnapshotTables = map[string]string{
+	"history_liquidity_pool_snapshots": "ledger_toid",
+	"history_order_book_depth":         "ledger_toid",
+	"history_order_book_snapshots":     "ledger_toid",
+}
+
 // DeleteRangeAll deletes a range of rows from all history tables between
-// `start` and `end` (exclusive).
+// `start` and `end` (exclusive). Partitions of partitioned history tables
+// within the range are rebuilt instead (see rebuildHistoryPartitions).
 func (q *Q) DeleteRangeAll(ctx context.Context, start, end int64) error {
-	for table, column := range map[string]string{
-		"history_effects":                        "history_operation_id",
-		"history_ledgers":                        "id",
-		"history_operation_claimable_balances":   "history_operation_id",
-		"history_operation_participants":         "history_operation_id",
-		"history_operation_liquidity_pools":      "history_operation_id",
-		"history_operations":                     "id",
-		"history_trades":                         "history_operation_id",
-		"history_trades_60000":                   "open_ledger_toid",
-		"history_transaction_claimable_balances": "history_transaction_id",
-		"history_transaction_participants":       "history_transaction_id",
-		"history_transaction_liquidity_pools":    "history_transaction_id",
-		"history_transactions":                   "id",
-	} {
+	if err := q.rebuildHistoryPartitions(ctx, start, end); err != nil {
+		return err
+	}
+	for table, column := range historyRangeTables {
 		err := q.DeleteRange(ctx, start, end, table, column)
 		if err != nil {
 			return errors.Wrapf(err, "Error clearing %s", table)
@@ -1044,6 +1099,57 @@ func (q *Q) DeleteRangeAll(ctx context.Context, start, end int64) error {
 	return nil
 }
 
+// DeleteRangeBatch deletes the rows of the given history table with toid in
+// [start, end) up to the toid of its batchSize-th row, so that a single query
+// removes about batchSize rows using the index of the toid column. It returns
+// the number of deleted rows and the toid at which the next batch starts,
+// which is end once the whole range has been deleted.
+func (q *Q) DeleteRangeBatch(ctx context.Context, table string, start, end int64, batchSize int) (int64, int64, error) {
+	column, ok := HistoryRangeColumn(table)
+	if !ok {
+		return 0, 0, errors.Errorf("unknown history table %s", table)
+	}
+
+	var bounds []int64
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s >= $1 AND %s < $2 ORDER BY %s OFFSET $3 LIMIT 1",
+		column, table, column, column, column,
+	)
+	if err := q.SelectRaw(ctx, &bounds, query, start, end, batchSize-1); err != nil {
+		return 0, 0, errors.Wrapf(err, "Error finding batch of %s", table)
+	}
+	next := end
+	if len(bounds) > 0 && bounds[0]+1 < end {
+		next = bounds[0] + 1
+	}
+
+	query = fmt.Sprintf("DELETE FROM %s WHERE %s >= $1 AND %s < $2", table, column, column)
+	result, err := q.ExecRaw(ctx, query, start, next)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "Error clearing %s", table)
+	}
+	deleted, err := result.RowsAffected()
+	return deleted, next, err
+}
+
+// HistoryTableElder returns the sequence of the oldest ledger with rows in
+// the given history table, or 0 if the table is empty.
+func (q *Q) HistoryTableElder(ctx context.Context, table string) (int32, error) {
+	column, ok := HistoryRangeColumn(table)
+	if !ok {
+		return 0, errors.Errorf("unknown history table %s", table)
+	}
+
+	var elder sql.NullInt64
+	if err := q.GetRaw(ctx, &elder, fmt.Sprintf("SELECT min(%s) FROM %s", column, table)); err != nil {
+		return 0, errors.Wrapf(err, "Error getting elder of %s", table)
+	}
+	if !elder.Valid {
+		return 0, nil
+	}
+	return toid.Parse(elder.Int64).LedgerSequence, nil
+}
+
 // upsertRows builds and executes an upsert query that allows very fast upserts
 // to a given table. The final query is of form:
 //
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/hcnet/go/services/aurora/internal/test"
+	"github.com/hcnet/go/toid"
+	"github.com/hcnet/go/xdr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -69,6 +71,96 @@ func TestElderLedger(t *testing.T) {
 	}
 }
 
+func TestDeleteRangeBatch(t *testing.T) {
+	tt := test.Start(t)
+	tt.Scenario("base")
+	defer tt.Finish()
+	q := &Q{tt.AuroraSession()}
+
+	elder, err := q.HistoryTableElder(tt.Ctx, "history_ledgers")
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int32(1), elder)
+
+	start := toid.AfterLedger(0).ToInt64()
+	end := toid.AfterLedger(3).ToInt64()
+	deleted, next, err := q.DeleteRangeBatch(tt.Ctx, "history_ledgers", start, end, 2)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int64(2), deleted)
+	tt.Assert.Equal(toid.New(2, 0, 0).ToInt64()+1, next)
+
+	elder, err = q.HistoryTableElder(tt.Ctx, "history_ledgers")
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int32(3), elder)
+
+	// the last batch ends at the end of the range
+	deleted, next, err = q.DeleteRangeBatch(tt.Ctx, "history_ledgers", next, end, 2)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int64(1), deleted)
+	tt.Assert.Equal(end, next)
+
+	elder, err = q.HistoryTableElder(tt.Ctx, "history_ledgers")
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int32(0), elder)
+
+	_, _, err = q.DeleteRangeBatch(tt.Ctx, "history_unknown", start, end, 2)
+	tt.Assert.Error(err)
+}
+
+func TestDeleteRangeAllKeepsSnapshots(t *testing.T) {
+	tt := test.Start(t)
+	tt.Scenario("base")
+	defer tt.Finish()
+	q := &Q{tt.AuroraSession()}
+
+	ledgerToid := toid.New(2, 0, 0).ToInt64()
+	tt.Assert.NoError(q.InsertLiquidityPoolSnapshots(tt.Ctx, []LiquidityPoolSnapshot{{
+		LedgerToid:     ledgerToid,
+		PoolID:         "cafebabe",
+		AssetA:         xdr.MustNewNativeAsset(),
+		AssetB:         xdr.MustNewNativeAsset(),
+		ReserveA:       100,
+		ReserveB:       200,
+		ShareCount:     10,
+		TrustlineCount: 1,
+	}}))
+	tt.Assert.NoError(q.InsertOrderBookSnapshots(tt.Ctx, []OrderBookSnapshot{{
+		LedgerToid:   ledgerToid,
+		BaseAsset:    "native",
+		CounterAsset: "EUR:GAXMF43TGZHW3QN3REOUA2U5PW5BTARXGGYJ3JIFHW3YT6QRKRL3CPPU",
+		Asks:         OrderBookLevels{},
+		Bids:         OrderBookLevels{},
+	}}, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	count := func(table string) int {
+		var rows int
+		tt.Assert.NoError(q.GetRaw(tt.Ctx, &rows, "SELECT COUNT(*) FROM "+table))
+		return rows
+	}
+	snapshotRows := map[string]int{}
+	for table := range historySnapshotTables {
+		snapshotRows[table] = count(table)
+		tt.Assert.NotZero(snapshotRows[table], table)
+	}
+
+	// reingestion only rebuilds the tables written by the transaction
+	// processors, the snapshots of the ledger entries are kept
+	tt.Assert.NoError(q.Begin())
+	tt.Assert.NoError(q.DeleteRangeAll(tt.Ctx, toid.AfterLedger(0).ToInt64(), toid.AfterLedger(3).ToInt64()))
+	tt.Assert.NoError(q.Commit())
+	tt.Assert.Zero(count("history_ledgers"))
+	for table, rows := range snapshotRows {
+		tt.Assert.Equal(rows, count(table), table)
+	}
+
+	// the reaper still removes them
+	elder, err := q.HistoryTableElder(tt.Ctx, "history_order_book_snapshots")
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(int32(2), elder)
+	_, _, err = q.DeleteRangeBatch(tt.Ctx, "history_order_book_snapshots", toid.AfterLedger(0).ToInt64(), toid.AfterLedger(3).ToInt64(), 10)
+	tt.Assert.NoError(err)
+	tt.Assert.Zero(count("history_order_book_snapshots"))
+}
+
 func TestConstructReapLookupTablesQuery(t *testing.T) {
 	query, err := constructReapLookupTablesQuery(
 		"history_accounts",
@@ -0,0 +1,24 @@
+package history
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAccountBalanceBatchInsertBuilder mock AccountBalanceBatchInsertBuilder
+type MockAccountBalanceBatchInsertBuilder struct {
+	mock.Mock
+}
+
+// Add mock
+func (m *MockAccountBalanceBatchInsertBuilder) Add(ctx context.Context, entries ...AccountBalance) error {
+	a := m.Called(ctx, entries)
+	return a.Error(0)
+}
+
+// Exec mock
+func (m *MockAccountBalanceBatchInsertBuilder) Exec(ctx context.Context) error {
+	a := m.Called(ctx)
+	return a.Error(0)
+}
@@ -0,0 +1,15 @@
+package history
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+// MockQAccountBalances is a mock implementation of the QAccountBalances interface
+type MockQAccountBalances struct {
+	mock.Mock
+}
+
+func (m *MockQAccountBalances) NewAccountBalanceBatchInsertBuilder(maxBatchSize int) AccountBalanceBatchInsertBuilder {
+	a := m.Called(maxBatchSize)
+	return a.Get(0).(AccountBalanceBatchInsertBuilder)
+}
@@ -45,3 +45,8 @@ func (m *MockQLiquidityPools) CompactLiquidityPools(ctx context.Context, cutOffS
 	a := m.Called(ctx, cutOffSequence)
 	return a.Get(0).(int64), a.Error(1)
 }
+
+func (m *MockQLiquidityPools) InsertLiquidityPoolSnapshots(ctx context.Context, snapshots []LiquidityPoolSnapshot) error {
+	a := m.Called(ctx, snapshots)
+	return a.Error(0)
+}
@@ -0,0 +1,30 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/hcnet/go/xdr"
+)
+
+// MockQOrderBookSnapshots is a mock implementation of the QOrderBookSnapshots interface
+type MockQOrderBookSnapshots struct {
+	mock.Mock
+}
+
+func (m *MockQOrderBookSnapshots) BuildOrderBookSnapshot(ctx context.Context, base, counter xdr.Asset) (OrderBookSnapshot, error) {
+	a := m.Called(ctx, base, counter)
+	return a.Get(0).(OrderBookSnapshot), a.Error(1)
+}
+
+func (m *MockQOrderBookSnapshots) GetTradingPairsModifiedSince(ctx context.Context, ledger uint32) ([][2]xdr.Asset, error) {
+	a := m.Called(ctx, ledger)
+	return a.Get(0).([][2]xdr.Asset), a.Error(1)
+}
+
+func (m *MockQOrderBookSnapshots) InsertOrderBookSnapshots(ctx context.Context, snapshots []OrderBookSnapshot, closedAt time.Time) error {
+	a := m.Called(ctx, snapshots, closedAt)
+	return a.Error(0)
+}
@@ -234,6 +234,30 @@ func (q *OperationsQ) ForTransaction(ctx context.Context, hash string) *Operatio
 	return q
 }
 
+// ForLedgerRange filters the query to only operations in ledgers with
+// sequence in [start, end).
+func (q *OperationsQ) ForLedgerRange(start, end int32) *OperationsQ {
+	q.sql = q.sql.Where(
+		q.opIdCol+" >= ? AND "+q.opIdCol+" < ?",
+		toid.New(start, 0, 0).ToInt64(),
+		toid.New(end, 0, 0).ToInt64(),
+	)
+	return q
+}
+
+// ForTypes filters the query to only operations of the given types.
+func (q *OperationsQ) ForTypes(types []xdr.OperationType) *OperationsQ {
+	q.sql = q.sql.Where(sq.Eq{"hop.type": types})
+	return q
+}
+
+// ForMemo filters the query to only operations of transactions with the
+// given memo.
+func (q *OperationsQ) ForMemo(memo xdr.Memo) *OperationsQ {
+	q.sql = q.sql.Where(memoPredicate(memo))
+	return q
+}
+
 // OnlyPayments filters the query being built to only include operations that
 // are in the "payment" class of operations:  CreateAccountOps, Payments, and
 // PathPayments.
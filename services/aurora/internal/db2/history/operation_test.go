@@ -486,3 +486,36 @@ func TestValidateTransactionForOperation(t *testing.T) {
 	tt.Assert.Error(err)
 	tt.Assert.EqualError(err, "transaction successful flag false does not match transaction successful flag in operation true")
 }
+
+func TestOperationLedgerRangeAndTypeFilters(t *testing.T) {
+	tt := test.Start(t)
+	tt.Scenario("base")
+	defer tt.Finish()
+	q := &Q{tt.AuroraSession()}
+
+	ops, _, err := q.Operations().ForLedgerRange(2, 3).Fetch(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(ops, 3)
+
+	ops, _, err = q.Operations().ForLedgerRange(3, 4).Fetch(tt.Ctx)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(ops, 1)
+
+	ops, _, err = q.Operations().
+		ForTypes([]xdr.OperationType{xdr.OperationTypePayment}).
+		Fetch(tt.Ctx)
+	tt.Assert.NoError(err)
+	if tt.Assert.Len(ops, 1) {
+		tt.Assert.Equal(int64(12884905985), ops[0].ID)
+	}
+
+	ops, _, err = q.Operations().
+		ForAccount(tt.Ctx, "GBXGQJWVLWOYHFLVTKWV5FGHA3LNYY2JQKM7OAJAUEQFU6LPCSEFVXON").
+		ForLedgerRange(2, 3).
+		ForTypes([]xdr.OperationType{xdr.OperationTypeCreateAccount, xdr.OperationTypePayment}).
+		Fetch(tt.Ctx)
+	tt.Assert.NoError(err)
+	if tt.Assert.Len(ops, 1) {
+		tt.Assert.Equal(int64(8589946881), ops[0].ID)
+	}
+}
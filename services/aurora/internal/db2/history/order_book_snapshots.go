@@ -0,0 +1,522 @@
+package history
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"math"
+	"math/big"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/guregu/null"
+	"github.com/lib/pq"
+
+	"github.com/hcnet/go/amount"
+	"github.com/hcnet/go/services/aurora/internal/db2"
+	"github.com/hcnet/go/support/db"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/toid"
+	"github.com/hcnet/go/xdr"
+)
+
+// OrderBookSnapshotLevels is the number of price levels of each side of an
+// order book recorded in a snapshot.
+const OrderBookSnapshotLevels = 20
+
+// OrderBookDepthBands are the price bands, in basis points around the mid
+// price, at which the cumulative depth of an order book is recorded.
+var OrderBookDepthBands = []int32{50, 100, 200, 500, 1000}
+
+// OrderBookDepthResolutions are the resolutions, in milliseconds, of the
+// order book depth charts: 1 minute, 1 hour and 1 day.
+var OrderBookDepthResolutions = []int64{60000, 3600000, 86400000}
+
+// OrderBookLevels are the price levels of a side of an order book snapshot.
+type OrderBookLevels []PriceLevel
+
+func (l OrderBookLevels) Value() (driver.Value, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(l)
+}
+
+func (l *OrderBookLevels) Scan(src interface{}) error {
+	source, ok := src.([]byte)
+	if !ok {
+		return errors.New("Type assertion .([]byte) failed.")
+	}
+
+	return json.Unmarshal(source, &l)
+}
+
+// OrderBookDepthBand is the cumulative depth of an order book within a price
+// band around its mid price: asks priced at most mid * (1 + band) and bids
+// priced at least mid / (1 + band).
+type OrderBookDepthBand struct {
+	BasisPoints int32 `json:"bps"`
+	// AskAmount is the amount of the base asset sold by the asks and
+	// AskValue the amount of the counter asset they buy.
+	AskAmount string `json:"ask_amount"`
+	AskValue  string `json:"ask_value"`
+	// BidAmount is the amount of the counter asset sold by the bids and
+	// BidValue the amount of the base asset they buy.
+	BidAmount string `json:"bid_amount"`
+	BidValue  string `json:"bid_value"`
+}
+
+// OrderBookDepth is the cumulative depth of an order book snapshot at every
+// price band.
+type OrderBookDepth []OrderBookDepthBand
+
+func (d OrderBookDepth) Value() (driver.Value, error) {
+	if d == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(d)
+}
+
+func (d *OrderBookDepth) Scan(src interface{}) error {
+	source, ok := src.([]byte)
+	if !ok {
+		return errors.New("Type assertion .([]byte) failed.")
+	}
+
+	return json.Unmarshal(source, &d)
+}
+
+// OrderBookSnapshot is a row of data from the `history_order_book_snapshots`
+// table. Each row records the state of the order book of a trading pair at
+// the end of a ledger which changed its offers or liquidity pool. The base
+// asset is the lower asset of the pair, as ordered in liquidity pools.
+type OrderBookSnapshot struct {
+	LedgerToid   int64           `db:"ledger_toid"`
+	BaseAsset    string          `db:"base_asset"`
+	CounterAsset string          `db:"counter_asset"`
+	Asks         OrderBookLevels `db:"asks"`
+	Bids         OrderBookLevels `db:"bids"`
+	// MidPrice is the geometric mean of the best ask and bid prices,
+	// including the liquidity pool. It's null when a side is empty.
+	MidPrice null.Float     `db:"mid_price"`
+	Depth    OrderBookDepth `db:"depth"`
+}
+
+// Reverse returns the snapshot of the order book with the base and counter
+// assets swapped.
+func (s OrderBookSnapshot) Reverse() OrderBookSnapshot {
+	reversed := OrderBookSnapshot{
+		LedgerToid:   s.LedgerToid,
+		BaseAsset:    s.CounterAsset,
+		CounterAsset: s.BaseAsset,
+		Asks:         reversePriceLevels(s.Bids),
+		Bids:         reversePriceLevels(s.Asks),
+	}
+	if s.MidPrice.Valid && s.MidPrice.Float64 > 0 {
+		reversed.MidPrice = null.FloatFrom(1 / s.MidPrice.Float64)
+	}
+	for _, band := range s.Depth {
+		reversed.Depth = append(reversed.Depth, OrderBookDepthBand{
+			BasisPoints: band.BasisPoints,
+			AskAmount:   band.BidAmount,
+			AskValue:    band.BidValue,
+			BidAmount:   band.AskAmount,
+			BidValue:    band.AskValue,
+		})
+	}
+	return reversed
+}
+
+func reversePriceLevels(levels OrderBookLevels) OrderBookLevels {
+	reversed := make(OrderBookLevels, 0, len(levels))
+	for _, level := range levels {
+		price := big.NewRat(int64(level.Priced), int64(level.Pricen))
+		reversed = append(reversed, PriceLevel{
+			Pricen: level.Priced,
+			Priced: level.Pricen,
+			Pricef: price.FloatString(7),
+			Amount: level.Amount,
+		})
+	}
+	return reversed
+}
+
+// OrderBookDepthBucket is the last snapshot of an order book in a bucket of
+// time.
+type OrderBookDepthBucket struct {
+	// Timestamp is the start of the bucket in milliseconds since epoch.
+	Timestamp int64 `db:"timestamp"`
+	OrderBookSnapshot
+}
+
+// OrderBookDepthQuery selects the buckets of the depth chart of an order
+// book: times are in milliseconds since epoch, EndTime is exclusive and
+// ignored when 0.
+type OrderBookDepthQuery struct {
+	BaseAsset    string
+	CounterAsset string
+	Resolution   int64
+	StartTime    int64
+	EndTime      int64
+	Order        string
+	Limit        uint64
+}
+
+// QOrderBookSnapshots defines order book snapshot related queries.
+type QOrderBookSnapshots interface {
+	BuildOrderBookSnapshot(ctx context.Context, base, counter xdr.Asset) (OrderBookSnapshot, error)
+	GetTradingPairsModifiedSince(ctx context.Context, ledger uint32) ([][2]xdr.Asset, error)
+	InsertOrderBookSnapshots(ctx context.Context, snapshots []OrderBookSnapshot, closedAt time.Time) error
+}
+
+// constantProductPool approximates a constant product liquidity pool by its
+// reserves of the base and counter assets and its fee, as a fraction.
+type constantProductPool struct {
+	base    float64
+	counter float64
+	fee     float64
+}
+
+// askPrice is the marginal price, in counter asset per base asset, of buying
+// the base asset from the pool.
+func (p constantProductPool) askPrice() float64 {
+	return p.counter / (p.base * (1 - p.fee))
+}
+
+// bidPrice is the marginal price, in counter asset per base asset, of
+// selling the base asset to the pool.
+func (p constantProductPool) bidPrice() float64 {
+	return p.counter * (1 - p.fee) / p.base
+}
+
+// asks returns the amount of the base asset sold by the pool, and the amount
+// of the counter asset it buys, until its ask price reaches price.
+func (p constantProductPool) asks(price float64) (int64, int64) {
+	k := p.base * p.counter
+	base := math.Sqrt(k / (price * (1 - p.fee)))
+	if base >= p.base {
+		return 0, 0
+	}
+	return int64(math.Floor(p.base - base)), int64(math.Floor((k/base - p.counter) / (1 - p.fee)))
+}
+
+// bids returns the amount of the counter asset sold by the pool, and the
+// amount of the base asset it buys, until its bid price drops to price.
+func (p constantProductPool) bids(price float64) (int64, int64) {
+	k := p.base * p.counter
+	counter := math.Sqrt(price * k / (1 - p.fee))
+	if counter >= p.counter {
+		return 0, 0
+	}
+	return int64(math.Floor(p.counter - counter)), int64(math.Floor((k/counter - p.base) / (1 - p.fee)))
+}
+
+// orderBookMidPrice returns the geometric mean of the best ask and bid prices
+// of an order book and its liquidity pool, which may be nil. It returns false
+// if a side of the order book is empty.
+func orderBookMidPrice(summary OrderBookSummary, pool *constantProductPool) (float64, bool) {
+	var ask, bid float64
+	if len(summary.Asks) > 0 {
+		ask = float64(summary.Asks[0].Pricen) / float64(summary.Asks[0].Priced)
+	}
+	if len(summary.Bids) > 0 {
+		bid = float64(summary.Bids[0].Pricen) / float64(summary.Bids[0].Priced)
+	}
+	if pool != nil {
+		if ask == 0 || pool.askPrice() < ask {
+			ask = pool.askPrice()
+		}
+		if pool.bidPrice() > bid {
+			bid = pool.bidPrice()
+		}
+	}
+	if ask == 0 || bid == 0 {
+		return 0, false
+	}
+	return math.Sqrt(ask * bid), true
+}
+
+type offerDepth struct {
+	Amount string `db:"amount"`
+	Value  string `db:"value"`
+}
+
+// BuildOrderBookSnapshot builds the snapshot of the order book of a trading
+// pair from the current offers and liquidity pool, the assets are ordered so
+// that the base asset is the lower one. It must be called in a transaction.
+func (q *Q) BuildOrderBookSnapshot(ctx context.Context, base, counter xdr.Asset) (OrderBookSnapshot, error) {
+	if counter.LessThan(base) {
+		base, counter = counter, base
+	}
+	snapshot := OrderBookSnapshot{
+		BaseAsset:    base.StringCanonical(),
+		CounterAsset: counter.StringCanonical(),
+	}
+
+	summary, err := q.orderBookSummary(ctx, base, counter, OrderBookSnapshotLevels)
+	if err != nil {
+		return snapshot, err
+	}
+	snapshot.Asks = summary.Asks
+	snapshot.Bids = summary.Bids
+
+	pool, err := q.orderBookPool(ctx, base, counter)
+	if err != nil {
+		return snapshot, err
+	}
+	mid, ok := orderBookMidPrice(summary, pool)
+	if !ok {
+		return snapshot, nil
+	}
+	snapshot.MidPrice = null.FloatFrom(mid)
+
+	askPrices := make([]float64, len(OrderBookDepthBands))
+	bidPrices := make([]float64, len(OrderBookDepthBands))
+	for i, band := range OrderBookDepthBands {
+		factor := 1 + float64(band)/10000
+		askPrices[i] = mid * factor
+		// bids sell the counter asset so their price is in base asset per
+		// counter asset
+		bidPrices[i] = factor / mid
+	}
+	asks, err := q.offerDepth(ctx, base, counter, askPrices)
+	if err != nil {
+		return snapshot, err
+	}
+	bids, err := q.offerDepth(ctx, counter, base, bidPrices)
+	if err != nil {
+		return snapshot, err
+	}
+
+	for i, band := range OrderBookDepthBands {
+		var poolAsks, poolBids [2]int64
+		if pool != nil {
+			poolAsks[0], poolAsks[1] = pool.asks(askPrices[i])
+			poolBids[0], poolBids[1] = pool.bids(1 / bidPrices[i])
+		}
+		depth := OrderBookDepthBand{BasisPoints: band}
+		for _, field := range []struct {
+			dst  *string
+			sum  string
+			pool int64
+		}{
+			{&depth.AskAmount, asks[i].Amount, poolAsks[0]},
+			{&depth.AskValue, asks[i].Value, poolAsks[1]},
+			{&depth.BidAmount, bids[i].Amount, poolBids[0]},
+			{&depth.BidValue, bids[i].Value, poolBids[1]},
+		} {
+			total, ok := new(big.Int).SetString(field.sum, 10)
+			if !ok {
+				return snapshot, errors.Errorf("invalid offer depth %s", field.sum)
+			}
+			total.Add(total, big.NewInt(field.pool))
+			if *field.dst, err = amount.IntStringToAmount(total.String()); err != nil {
+				return snapshot, errors.Wrap(err, "could not determine depth amount")
+			}
+		}
+		snapshot.Depth = append(snapshot.Depth, depth)
+	}
+
+	return snapshot, nil
+}
+
+// GetTradingPairsModifiedSince returns the trading pairs of the offers and
+// liquidity pools modified after the given ledger, including the ones deleted
+// and not compacted yet. The assets of each pair are returned in any order.
+func (q *Q) GetTradingPairsModifiedSince(ctx context.Context, ledger uint32) ([][2]xdr.Asset, error) {
+	var offers []struct {
+		SellingAsset xdr.Asset `db:"selling_asset"`
+		BuyingAsset  xdr.Asset `db:"buying_asset"`
+	}
+	sql := sq.Select("DISTINCT selling_asset, buying_asset").
+		From("offers").
+		Where("last_modified_ledger > ?", ledger)
+	if err := q.Select(ctx, &offers, sql); err != nil {
+		return nil, errors.Wrap(err, "cannot load offers")
+	}
+
+	var pools []LiquidityPool
+	sql = sq.Select("asset_reserves").
+		From("liquidity_pools").
+		Where("last_modified_ledger > ?", ledger)
+	if err := q.Select(ctx, &pools, sql); err != nil {
+		return nil, errors.Wrap(err, "cannot load liquidity pools")
+	}
+
+	pairs := make([][2]xdr.Asset, 0, len(offers)+len(pools))
+	for _, offer := range offers {
+		pairs = append(pairs, [2]xdr.Asset{offer.SellingAsset, offer.BuyingAsset})
+	}
+	for _, pool := range pools {
+		if len(pool.AssetReserves) == 2 {
+			pairs = append(pairs, [2]xdr.Asset{pool.AssetReserves[0].Asset, pool.AssetReserves[1].Asset})
+		}
+	}
+	return pairs, nil
+}
+
+// orderBookPool returns the liquidity pool of a trading pair, or nil if the
+// pool doesn't exist or is empty.
+func (q *Q) orderBookPool(ctx context.Context, base, counter xdr.Asset) (*constantProductPool, error) {
+	id, err := xdr.NewPoolId(base, counter, xdr.LiquidityPoolFeeV18)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot build liquidity pool id")
+	}
+	lps, err := q.GetLiquidityPoolsByID(ctx, []string{xdr.Hash(id).HexString()})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot load liquidity pool")
+	}
+	if len(lps) == 0 || len(lps[0].AssetReserves) != 2 ||
+		lps[0].AssetReserves[0].Reserve == 0 || lps[0].AssetReserves[1].Reserve == 0 {
+		return nil, nil
+	}
+	return &constantProductPool{
+		base:    float64(lps[0].AssetReserves[0].Reserve),
+		counter: float64(lps[0].AssetReserves[1].Reserve),
+		fee:     float64(lps[0].Fee) / 10000,
+	}, nil
+}
+
+// offerDepth sums the amounts of the offers selling an asset for another one
+// priced at most at each of the given prices.
+func (q *Q) offerDepth(ctx context.Context, sellingAsset, buyingAsset xdr.Asset, prices []float64) ([]offerDepth, error) {
+	selling, err := xdr.MarshalBase64(sellingAsset)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal selling asset")
+	}
+	buying, err := xdr.MarshalBase64(buyingAsset)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot marshal buying asset")
+	}
+
+	selectDepth := `
+		SELECT
+			COALESCE(SUM(o.amount), 0)::text AS amount,
+			COALESCE(floor(SUM(o.amount::numeric * o.pricen / o.priced)), 0)::text AS value
+		FROM unnest($3::double precision[]) WITH ORDINALITY AS b(max_price, i)
+		LEFT JOIN offers o ON o.selling_asset = $1 AND o.buying_asset = $2
+			AND o.deleted = false AND o.price <= b.max_price
+		GROUP BY b.i
+		ORDER BY b.i
+	`
+	// Add explicit query type for prometheus metrics, since we use raw sql.
+	ctx = context.WithValue(ctx, &db.QueryTypeContextKey, db.SelectQueryType)
+	var depth []offerDepth
+	if err := q.SelectRaw(ctx, &depth, selectDepth, selling, buying, pq.Array(prices)); err != nil {
+		return nil, errors.Wrap(err, "cannot select offer depth")
+	}
+	if len(depth) != len(prices) {
+		return nil, errors.New("offer depth length does not match price bands length")
+	}
+	return depth, nil
+}
+
+type orderBookDepthRow struct {
+	BaseAsset    string `db:"base_asset"`
+	CounterAsset string `db:"counter_asset"`
+	Resolution   int64  `db:"resolution"`
+	Timestamp    int64  `db:"timestamp"`
+	LedgerToid   int64  `db:"ledger_toid"`
+}
+
+// InsertOrderBookSnapshots inserts the snapshots of order books built at the
+// end of a ledger closed at closedAt, snapshots of the same pair and ledger
+// are replaced. The snapshots become the last ones of their pair in the
+// buckets of the depth chart resolutions.
+func (q *Q) InsertOrderBookSnapshots(ctx context.Context, snapshots []OrderBookSnapshot, closedAt time.Time) error {
+	builder := db.BatchInsertBuilder{
+		Table:        q.GetTable("history_order_book_snapshots"),
+		MaxBatchSize: 10000,
+		Suffix: "ON CONFLICT (base_asset, counter_asset, ledger_toid) DO UPDATE SET " +
+			"asks = excluded.asks, " +
+			"bids = excluded.bids, " +
+			"mid_price = excluded.mid_price, " +
+			"depth = excluded.depth",
+	}
+	for _, snapshot := range snapshots {
+		if err := builder.RowStruct(ctx, snapshot); err != nil {
+			return errors.Wrap(err, "failed to add order book snapshot")
+		}
+	}
+	if err := builder.Exec(ctx); err != nil {
+		return err
+	}
+
+	depthBuilder := db.BatchInsertBuilder{
+		Table:        q.GetTable("history_order_book_depth"),
+		MaxBatchSize: 10000,
+		Suffix: "ON CONFLICT (base_asset, counter_asset, resolution, timestamp) DO UPDATE SET " +
+			"ledger_toid = GREATEST(history_order_book_depth.ledger_toid, excluded.ledger_toid)",
+	}
+	closedAtMillis := closedAt.UnixMilli()
+	for _, snapshot := range snapshots {
+		for _, resolution := range OrderBookDepthResolutions {
+			err := depthBuilder.RowStruct(ctx, orderBookDepthRow{
+				BaseAsset:    snapshot.BaseAsset,
+				CounterAsset: snapshot.CounterAsset,
+				Resolution:   resolution,
+				Timestamp:    closedAtMillis / resolution * resolution,
+				LedgerToid:   snapshot.LedgerToid,
+			})
+			if err != nil {
+				return errors.Wrap(err, "failed to add order book depth bucket")
+			}
+		}
+	}
+	return depthBuilder.Exec(ctx)
+}
+
+var selectOrderBookSnapshots = sq.Select(
+	"s.ledger_toid",
+	"s.base_asset",
+	"s.counter_asset",
+	"s.asks",
+	"s.bids",
+	"s.mid_price",
+	"s.depth",
+).From("history_order_book_snapshots s")
+
+// GetOrderBookSnapshot returns the last snapshot of the order book of a
+// trading pair at the end of the given ledger, or the latest snapshot if the
+// ledger is 0.
+func (q *Q) GetOrderBookSnapshot(ctx context.Context, base, counter string, ledger uint32) (OrderBookSnapshot, error) {
+	sql := selectOrderBookSnapshots.
+		Where(sq.Eq{"s.base_asset": base, "s.counter_asset": counter}).
+		OrderBy("s.ledger_toid DESC").
+		Limit(1)
+	if ledger > 0 {
+		sql = sql.Where("s.ledger_toid <= ?", toid.New(int32(ledger), 0, 0).ToInt64())
+	}
+
+	var snapshot OrderBookSnapshot
+	err := q.Get(ctx, &snapshot, sql)
+	return snapshot, err
+}
+
+// GetOrderBookDepth returns the last snapshot of the order book of a trading
+// pair in every bucket of time in which the order book changed.
+func (q *Q) GetOrderBookDepth(ctx context.Context, query OrderBookDepthQuery) ([]OrderBookDepthBucket, error) {
+	if query.Order != db2.OrderAscending && query.Order != db2.OrderDescending {
+		return nil, errors.Errorf("invalid order %s", query.Order)
+	}
+
+	sql := selectOrderBookSnapshots.
+		Column("d.timestamp").
+		Join("history_order_book_depth d ON d.base_asset = s.base_asset "+
+			"AND d.counter_asset = s.counter_asset AND d.ledger_toid = s.ledger_toid").
+		Where(sq.Eq{
+			"d.base_asset":    query.BaseAsset,
+			"d.counter_asset": query.CounterAsset,
+			"d.resolution":    query.Resolution,
+		}).
+		Where("d.timestamp >= ?", query.StartTime).
+		OrderBy("d.timestamp " + query.Order).
+		Limit(query.Limit)
+	if query.EndTime > 0 {
+		sql = sql.Where("d.timestamp < ?", query.EndTime)
+	}
+
+	var buckets []OrderBookDepthBucket
+	err := q.Select(ctx, &buckets, sql)
+	return buckets, err
+}
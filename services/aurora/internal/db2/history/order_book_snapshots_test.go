@@ -0,0 +1,196 @@
+package history
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hcnet/go/services/aurora/internal/db2"
+	"github.com/hcnet/go/services/aurora/internal/test"
+	"github.com/hcnet/go/toid"
+)
+
+func TestConstantProductPoolDepth(t *testing.T) {
+	pool := constantProductPool{base: 1e10, counter: 2e10, fee: 0.003}
+	assert.InDelta(t, 2.0060181, pool.askPrice(), 1e-7)
+	assert.InDelta(t, 1.994, pool.bidPrice(), 1e-7)
+
+	amount, value := pool.asks(pool.askPrice() / 2)
+	assert.Zero(t, amount)
+	assert.Zero(t, value)
+	amount, value = pool.bids(pool.bidPrice() * 2)
+	assert.Zero(t, amount)
+	assert.Zero(t, value)
+
+	// selling amount moves the marginal ask price of the pool to the price
+	amount, value = pool.asks(pool.askPrice() * 1.1)
+	assert.InDelta(t, 465374107, amount, 1)
+	assert.InDelta(t, 979114306, value, 1)
+	after := constantProductPool{
+		base:    pool.base - float64(amount),
+		counter: pool.counter + float64(value)*(1-pool.fee),
+		fee:     pool.fee,
+	}
+	assert.InDelta(t, pool.askPrice()*1.1, after.askPrice(), 1e-6)
+
+	amount, value = pool.bids(pool.bidPrice() / 1.1)
+	after = constantProductPool{
+		base:    pool.base + float64(value)*(1-pool.fee),
+		counter: pool.counter - float64(amount),
+		fee:     pool.fee,
+	}
+	assert.InDelta(t, pool.bidPrice()/1.1, after.bidPrice(), 1e-6)
+}
+
+func TestOrderBookMidPrice(t *testing.T) {
+	summary := OrderBookSummary{
+		Asks: []PriceLevel{{Pricen: 4, Priced: 1}},
+		Bids: []PriceLevel{{Pricen: 1, Priced: 1}},
+	}
+	mid, ok := orderBookMidPrice(summary, nil)
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, mid)
+
+	// the pool quotes better prices than the offers
+	pool := &constantProductPool{base: 1e10, counter: 2e10}
+	mid, ok = orderBookMidPrice(summary, pool)
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, mid)
+
+	_, ok = orderBookMidPrice(OrderBookSummary{Asks: summary.Asks}, nil)
+	assert.False(t, ok)
+	mid, ok = orderBookMidPrice(OrderBookSummary{Asks: summary.Asks}, pool)
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, mid)
+}
+
+func TestOrderBookSnapshotReverse(t *testing.T) {
+	snapshot := OrderBookSnapshot{
+		LedgerToid:   123,
+		BaseAsset:    "native",
+		CounterAsset: "EUR:GAXMF43TGZHW3QN3REOUA2U5PW5BTARXGGYJ3JIFHW3YT6QRKRL3CPPU",
+		Asks:         OrderBookLevels{{Pricen: 2, Priced: 1, Pricef: "2.0000000", Amount: "1.0000000"}},
+		Bids:         OrderBookLevels{{Pricen: 1, Priced: 4, Pricef: "0.2500000", Amount: "3.0000000"}},
+		MidPrice:     null.FloatFrom(0.5),
+		Depth: OrderBookDepth{{
+			BasisPoints: 100,
+			AskAmount:   "1.0000000",
+			AskValue:    "2.0000000",
+			BidAmount:   "3.0000000",
+			BidValue:    "12.0000000",
+		}},
+	}
+	assert.Equal(t, OrderBookSnapshot{
+		LedgerToid:   123,
+		BaseAsset:    "EUR:GAXMF43TGZHW3QN3REOUA2U5PW5BTARXGGYJ3JIFHW3YT6QRKRL3CPPU",
+		CounterAsset: "native",
+		Asks:         OrderBookLevels{{Pricen: 4, Priced: 1, Pricef: "4.0000000", Amount: "3.0000000"}},
+		Bids:         OrderBookLevels{{Pricen: 1, Priced: 2, Pricef: "0.5000000", Amount: "1.0000000"}},
+		MidPrice:     null.FloatFrom(2),
+		Depth: OrderBookDepth{{
+			BasisPoints: 100,
+			AskAmount:   "3.0000000",
+			AskValue:    "12.0000000",
+			BidAmount:   "1.0000000",
+			BidValue:    "2.0000000",
+		}},
+	}, snapshot.Reverse())
+	assert.Equal(t, snapshot, snapshot.Reverse().Reverse())
+}
+
+func TestOrderBookSnapshots(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{SessionInterface: tt.AuroraSession()}
+
+	// eurOffer and twoEurOffer sell XLM for EUR, they are the asks of the
+	// XLM/EUR order book and sellEurOffer is its bid
+	sellEurOffer := Offer{
+		SellerID:     issuer.Address(),
+		OfferID:      6,
+		SellingAsset: eurAsset,
+		BuyingAsset:  nativeAsset,
+		Amount:       100,
+		Pricen:       1,
+		Priced:       2,
+		Price:        0.5,
+	}
+	tt.Assert.NoError(q.UpsertOffers(tt.Ctx, []Offer{eurOffer, twoEurOffer, sellEurOffer}))
+
+	tt.Assert.NoError(q.Begin())
+	snapshot, err := q.BuildOrderBookSnapshot(tt.Ctx, eurAsset, nativeAsset)
+	tt.Assert.NoError(err)
+	tt.Assert.NoError(q.Rollback())
+
+	tt.Assert.Equal("native", snapshot.BaseAsset)
+	tt.Assert.Equal(eurAsset.StringCanonical(), snapshot.CounterAsset)
+	tt.Assert.Equal(OrderBookLevels{
+		{Pricen: 1, Priced: 1, Pricef: "1.0000000", Amount: "0.0000500"},
+		{Pricen: 2, Priced: 1, Pricef: "2.0000000", Amount: "0.0000500"},
+	}, snapshot.Asks)
+	tt.Assert.Equal(OrderBookLevels{
+		{Pricen: 2, Priced: 1, Pricef: "2.0000000", Amount: "0.0000100"},
+	}, snapshot.Bids)
+	// geometric mean of the best ask (1) and bid (2) prices
+	tt.Assert.InDelta(1.4142136, snapshot.MidPrice.Float64, 1e-7)
+	tt.Assert.Len(snapshot.Depth, len(OrderBookDepthBands))
+	// the crossed book is fully within the narrowest band
+	tt.Assert.Equal(OrderBookDepthBand{
+		BasisPoints: 50,
+		AskAmount:   "0.0000500",
+		AskValue:    "0.0000500",
+		BidAmount:   "0.0000100",
+		BidValue:    "0.0000050",
+	}, snapshot.Depth[0])
+
+	closedAt := time.Date(2022, 1, 1, 0, 10, 0, 0, time.UTC)
+	var snapshots []OrderBookSnapshot
+	for _, sequence := range []int32{10, 11} {
+		snapshot.LedgerToid = toid.New(sequence, 0, 0).ToInt64()
+		snapshots = append(snapshots, snapshot)
+	}
+	snapshots[1].MidPrice = null.Float{}
+	snapshots[1].Depth = nil
+	tt.Assert.NoError(q.InsertOrderBookSnapshots(tt.Ctx, snapshots[:1], closedAt))
+	tt.Assert.NoError(q.InsertOrderBookSnapshots(tt.Ctx, snapshots[1:], closedAt.Add(time.Hour)))
+
+	found, err := q.GetOrderBookSnapshot(tt.Ctx, "native", eurAsset.StringCanonical(), 10)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(snapshots[0], found)
+	found, err = q.GetOrderBookSnapshot(tt.Ctx, "native", eurAsset.StringCanonical(), 0)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal(snapshots[1].LedgerToid, found.LedgerToid)
+	tt.Assert.False(found.MidPrice.Valid)
+	_, err = q.GetOrderBookSnapshot(tt.Ctx, "native", eurAsset.StringCanonical(), 9)
+	tt.Assert.Equal(sql.ErrNoRows, err)
+
+	buckets, err := q.GetOrderBookDepth(tt.Ctx, OrderBookDepthQuery{
+		BaseAsset:    "native",
+		CounterAsset: eurAsset.StringCanonical(),
+		Resolution:   time.Hour.Milliseconds(),
+		Order:        db2.OrderDescending,
+		Limit:        10,
+	})
+	tt.Assert.NoError(err)
+	tt.Assert.Len(buckets, 2)
+	tt.Assert.Equal(closedAt.Add(time.Hour).Truncate(time.Hour).UnixMilli(), buckets[0].Timestamp)
+	tt.Assert.Equal(snapshots[1].LedgerToid, buckets[0].LedgerToid)
+	tt.Assert.Equal(closedAt.Truncate(time.Hour).UnixMilli(), buckets[1].Timestamp)
+	tt.Assert.Equal(snapshots[0], buckets[1].OrderBookSnapshot)
+
+	// the last snapshot of a day is its bucket of the daily depth chart
+	buckets, err = q.GetOrderBookDepth(tt.Ctx, OrderBookDepthQuery{
+		BaseAsset:    "native",
+		CounterAsset: eurAsset.StringCanonical(),
+		Resolution:   24 * time.Hour.Milliseconds(),
+		Order:        db2.OrderAscending,
+		Limit:        10,
+	})
+	tt.Assert.NoError(err)
+	tt.Assert.Len(buckets, 1)
+	tt.Assert.Equal(snapshots[1].LedgerToid, buckets[0].LedgerToid)
+}
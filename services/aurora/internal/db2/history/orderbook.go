@@ -27,10 +27,10 @@ type offerSummary struct {
 // PriceLevel represents an aggregation of offers to trade at a certain
 // price.
 type PriceLevel struct {
-	Pricen int32
-	Priced int32
-	Pricef string
-	Amount string
+	Pricen int32  `json:"n"`
+	Priced int32  `json:"d"`
+	Pricef string `json:"price"`
+	Amount string `json:"amount"`
 }
 
 // OrderBookSummary is a summary of a set of offers for a given base and
@@ -52,6 +52,14 @@ func (q *Q) GetOrderBookSummary(ctx context.Context, sellingAsset, buyingAsset x
 		return result, errors.New("should only be called in a repeatable read transaction")
 	}
 
+	return q.orderBookSummary(ctx, sellingAsset, buyingAsset, maxPriceLevels)
+}
+
+// orderBookSummary builds the OrderBookSummary of a trading pair, it must be
+// called in a transaction so that price levels and amounts are consistent.
+func (q *Q) orderBookSummary(ctx context.Context, sellingAsset, buyingAsset xdr.Asset, maxPriceLevels int) (OrderBookSummary, error) {
+	var result OrderBookSummary
+
 	selling, err := xdr.MarshalBase64(sellingAsset)
 	if err != nil {
 		return result, errors.Wrap(err, "cannot marshal selling asset")
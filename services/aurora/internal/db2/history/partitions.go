@@ -0,0 +1,415 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/hcnet/go/support/errors"
+)
+
+// HistoryPartitionedTables lists history tables which can be range
+// partitioned by ledger sequence. The partition key is the toid column of
+// each table (see HistoryRangeColumn) so every partition contains the rows of
+// a range of ledgers.
+var HistoryPartitionedTables = []string{
+	"history_effects",
+	"history_operation_participants",
+	"history_operations",
+	"history_transaction_participants",
+	"history_transactions",
+}
+
+// HistoryPartition is a partition of a history table.
+type HistoryPartition struct {
+	Table string
+	Name  string
+	// StartLedger and EndLedger define the range [StartLedger, EndLedger) of
+	// ledgers in the partition. StartLedger is 0 for the partition containing
+	// the rows which were in the table before it was partitioned.
+	StartLedger uint32
+	EndLedger   uint32
+	// Default is true for the partition containing rows outside of the
+	// ranges of the other partitions.
+	Default bool
+}
+
+var partitionBoundRegexp = regexp.MustCompile(`^FOR VALUES FROM \((MINVALUE|'?-?\d+'?)\) TO \('?(-?\d+)'?\)$`)
+
+func ledgerToid(ledger uint32) int64 {
+	return int64(ledger) << 32
+}
+
+func isHistoryPartitionedTable(table string) bool {
+	for _, t := range HistoryPartitionedTables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
+
+// HistoryPartitions returns the partitions of a history table ordered by
+// ledger range, the default partition last. It returns an empty slice if the
+// table is not partitioned.
+func (q *Q) HistoryPartitions(ctx context.Context, table string) ([]HistoryPartition, error) {
+	if !isHistoryPartitionedTable(table) {
+		return nil, errors.Errorf("%s cannot be partitioned", table)
+	}
+
+	var rows []struct {
+		Name  string `db:"name"`
+		Bound string `db:"bound"`
+	}
+	err := q.SelectRaw(ctx, &rows, `
+		SELECT c.relname AS name, pg_get_expr(c.relpartbound, c.oid) AS bound
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class p ON p.oid = i.inhparent
+		JOIN pg_partitioned_table pt ON pt.partrelid = p.oid
+		WHERE p.relname = ? AND p.relnamespace = current_schema()::regnamespace
+	`, table)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not load partitions of %s", table)
+	}
+
+	partitions := make([]HistoryPartition, 0, len(rows))
+	for _, row := range rows {
+		partition, err := parseHistoryPartition(table, row.Name, row.Bound)
+		if err != nil {
+			return nil, err
+		}
+		partitions = append(partitions, partition)
+	}
+	sortHistoryPartitions(partitions)
+	return partitions, nil
+}
+
+func parseHistoryPartition(table, name, bound string) (HistoryPartition, error) {
+	partition := HistoryPartition{Table: table, Name: name}
+	if bound == "DEFAULT" {
+		partition.Default = true
+		return partition, nil
+	}
+
+	m := partitionBoundRegexp.FindStringSubmatch(bound)
+	if m == nil {
+		return partition, errors.Errorf("unexpected bound of partition %s: %s", name, bound)
+	}
+	if m[1] != "MINVALUE" {
+		start, err := strconv.ParseInt(strings.Trim(m[1], "'"), 10, 64)
+		if err != nil {
+			return partition, errors.Wrapf(err, "invalid bound of partition %s", name)
+		}
+		partition.StartLedger = uint32(start >> 32)
+	}
+	end, err := strconv.ParseInt(m[2], 10, 64)
+	if err != nil {
+		return partition, errors.Wrapf(err, "invalid bound of partition %s", name)
+	}
+	partition.EndLedger = uint32(end >> 32)
+	return partition, nil
+}
+
+func sortHistoryPartitions(partitions []HistoryPartition) {
+	sort.Slice(partitions, func(i, j int) bool {
+		if partitions[i].Default != partitions[j].Default {
+			return partitions[j].Default
+		}
+		return partitions[i].StartLedger < partitions[j].StartLedger
+	})
+}
+
+// PartitionHistoryTables converts the HistoryPartitionedTables into tables
+// range partitioned by ledger sequence, with partitions of partitionSize
+// ledgers. Existing rows are kept in a `<table>_legacy` partition containing
+// all ledgers up to the next multiple of partitionSize, the following
+// partition is created as well as a `<table>_default` partition. Existing
+// indexes are reused. Every table is locked and scanned once so this is
+// meant to run while Aurora is stopped. It must be called in a transaction.
+func (q *Q) PartitionHistoryTables(ctx context.Context, partitionSize uint32) error {
+	if q.GetTx() == nil {
+		return errors.New("cannot be called outside of a transaction")
+	}
+	if partitionSize == 0 {
+		return errors.New("partition size must be greater than 0")
+	}
+
+	var latest uint32
+	if err := q.GetRaw(ctx, &latest, `SELECT COALESCE(MAX(sequence), 0) FROM history_ledgers`); err != nil {
+		return errors.Wrap(err, "could not load latest ledger")
+	}
+	boundary := (latest/partitionSize + 1) * partitionSize
+
+	for _, table := range HistoryPartitionedTables {
+		if err := q.partitionHistoryTable(ctx, table, boundary, partitionSize); err != nil {
+			return errors.Wrapf(err, "could not partition %s", table)
+		}
+	}
+	return nil
+}
+
+func (q *Q) partitionHistoryTable(ctx context.Context, table string, boundary, partitionSize uint32) error {
+	partitions, err := q.HistoryPartitions(ctx, table)
+	if err != nil {
+		return err
+	}
+	if len(partitions) > 0 {
+		return errors.New("table is already partitioned")
+	}
+
+	column := historyRangeTables[table]
+	legacy := table + "_legacy"
+
+	var indexes []struct {
+		Name       string `db:"name"`
+		Unique     bool   `db:"is_unique"`
+		Definition string `db:"definition"`
+	}
+	err = q.SelectRaw(ctx, &indexes, `
+		SELECT c.relname AS name, i.indisunique AS is_unique, pg_get_indexdef(i.indexrelid) AS definition
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+		WHERE i.indrelid = ?::regclass
+	`, table)
+	if err != nil {
+		return errors.Wrap(err, "could not load indexes")
+	}
+
+	statements := []string{
+		fmt.Sprintf("LOCK TABLE %s IN ACCESS EXCLUSIVE MODE", table),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", table, legacy),
+	}
+	for _, index := range indexes {
+		statements = append(statements, fmt.Sprintf(
+			"ALTER INDEX %s RENAME TO %s",
+			pq.QuoteIdentifier(index.Name), pq.QuoteIdentifier(index.Name+"_legacy"),
+		))
+	}
+	statements = append(statements,
+		fmt.Sprintf(
+			"CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS) PARTITION BY RANGE (%s)",
+			table, legacy, column,
+		),
+		// The constraint allows attaching the partition without scanning it
+		// again.
+		fmt.Sprintf(
+			"ALTER TABLE %s ADD CONSTRAINT %s_partition CHECK (%s < %d)",
+			legacy, legacy, column, ledgerToid(boundary),
+		),
+		fmt.Sprintf(
+			"ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM (MINVALUE) TO (%d)",
+			table, legacy, ledgerToid(boundary),
+		),
+		fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s_partition", legacy, legacy),
+	)
+	for _, index := range indexes {
+		// Indexes of the partitioned table matching indexes of the legacy
+		// partition are attached to them instead of being built again.
+		method, err := partitionedIndexMethod(index.Definition, index.Unique, column)
+		if err != nil {
+			return errors.Wrapf(err, "cannot partition index %s", index.Name)
+		}
+		unique := ""
+		if index.Unique {
+			unique = "UNIQUE "
+		}
+		statements = append(statements, fmt.Sprintf(
+			"CREATE %sINDEX %s ON %s USING %s",
+			unique, pq.QuoteIdentifier(index.Name), table, method,
+		))
+	}
+	statements = append(statements,
+		fmt.Sprintf("CREATE TABLE %s_default PARTITION OF %s DEFAULT", table, table),
+		createHistoryPartitionStatement(table, boundary, boundary+partitionSize),
+	)
+
+	for _, statement := range statements {
+		if _, err := q.ExecRaw(ctx, statement); err != nil {
+			return errors.Wrapf(err, "error executing %q", statement)
+		}
+	}
+	return nil
+}
+
+// partitionedIndexMethod returns the part of an index definition following
+// USING, to create the index on the partitioned table. Postgres rejects unique
+// indexes of partitioned tables which don't contain the partition key. Adding
+// the partition column to such an index would weaken its constraint (ex. a
+// unique hash would only be unique for each id) so an error is returned
+// instead.
+func partitionedIndexMethod(definition string, unique bool, column string) (string, error) {
+	parts := strings.SplitN(definition, " USING ", 2)
+	if len(parts) != 2 {
+		return "", errors.New("missing USING clause")
+	}
+	method := parts[1]
+	if !unique {
+		return method, nil
+	}
+
+	open := strings.Index(method, "(")
+	if open < 0 {
+		return "", errors.New("missing index columns")
+	}
+	// find the parenthesis closing the list of columns, expressions can
+	// contain parenthesis too
+	depth := 0
+	for i := open; i < len(method); i++ {
+		switch method[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth > 0 {
+			continue
+		}
+		for _, indexColumn := range strings.Split(method[open+1:i], ",") {
+			if strings.Trim(strings.TrimSpace(indexColumn), `"`) == column {
+				return method, nil
+			}
+		}
+		return "", errors.Errorf("unique index doesn't contain the partition column %s", column)
+	}
+	return "", errors.New("unbalanced parenthesis")
+}
+
+func createHistoryPartitionStatement(table string, start, end uint32) string {
+	return fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s_p%d PARTITION OF %s FOR VALUES FROM (%d) TO (%d)",
+		table, start, table, ledgerToid(start), ledgerToid(end),
+	)
+}
+
+// CreateHistoryPartitions creates partitions of a partitioned history table,
+// following the newest partition and of the same size, until a partition
+// contains untilLedger. It returns the created partitions. Rows of the default
+// partition in the range of a new partition are moved to it. It must be called
+// in a transaction.
+func (q *Q) CreateHistoryPartitions(ctx context.Context, table string, untilLedger uint32) ([]HistoryPartition, error) {
+	if q.GetTx() == nil {
+		return nil, errors.New("cannot be called outside of a transaction")
+	}
+	partitions, err := q.HistoryPartitions(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+
+	var newest, defaultPartition *HistoryPartition
+	for i := range partitions {
+		if partitions[i].Default {
+			defaultPartition = &partitions[i]
+		} else if partitions[i].StartLedger > 0 {
+			newest = &partitions[i]
+		}
+	}
+	if newest == nil {
+		return nil, nil
+	}
+
+	var created []HistoryPartition
+	size := newest.EndLedger - newest.StartLedger
+	for start := newest.EndLedger; start <= untilLedger; start += size {
+		partition := HistoryPartition{
+			Table:       table,
+			Name:        fmt.Sprintf("%s_p%d", table, start),
+			StartLedger: start,
+			EndLedger:   start + size,
+		}
+		if err := q.createHistoryPartition(ctx, partition, defaultPartition); err != nil {
+			return created, errors.Wrapf(err, "could not create partition of %s", table)
+		}
+		created = append(created, partition)
+	}
+	return created, nil
+}
+
+// createHistoryPartition creates and attaches a partition. A partition can't
+// be attached while the default partition contains rows in its range, these
+// rows are moved to the new partition before attaching it.
+func (q *Q) createHistoryPartition(ctx context.Context, partition HistoryPartition, defaultPartition *HistoryPartition) error {
+	table := partition.Table
+	column := historyRangeTables[table]
+	start, end := ledgerToid(partition.StartLedger), ledgerToid(partition.EndLedger)
+	if defaultPartition == nil {
+		_, err := q.ExecRaw(ctx, createHistoryPartitionStatement(table, partition.StartLedger, partition.EndLedger))
+		return err
+	}
+
+	statements := []string{
+		fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS)", partition.Name, table),
+		fmt.Sprintf(
+			"WITH moved AS (DELETE FROM %s WHERE %s >= %d AND %s < %d RETURNING *) INSERT INTO %s SELECT * FROM moved",
+			pq.QuoteIdentifier(defaultPartition.Name), column, start, column, end, partition.Name,
+		),
+		// The constraint allows attaching the partition without scanning it
+		// again.
+		fmt.Sprintf(
+			"ALTER TABLE %s ADD CONSTRAINT %s_partition CHECK (%s >= %d AND %s < %d)",
+			partition.Name, partition.Name, column, start, column, end,
+		),
+		fmt.Sprintf(
+			"ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM (%d) TO (%d)",
+			table, partition.Name, start, end,
+		),
+		fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s_partition", partition.Name, partition.Name),
+	}
+	for _, statement := range statements {
+		if _, err := q.ExecRaw(ctx, statement); err != nil {
+			return errors.Wrapf(err, "error executing %q", statement)
+		}
+	}
+	return nil
+}
+
+// rebuildHistoryPartitions replaces the partitions of the partitioned history
+// tables containing only ledgers in the toid range [start, end) by empty
+// partitions with the same bounds. It's used by reingestion to rebuild whole
+// partitions instead of deleting their rows one by one: the old partitions
+// are dropped and the reingested rows are written to the new ones, which are
+// swapped in when the transaction commits. The partitioned tables are locked
+// until then. It must be called in a transaction.
+func (q *Q) rebuildHistoryPartitions(ctx context.Context, start, end int64) error {
+	for _, table := range HistoryPartitionedTables {
+		partitions, err := q.HistoryPartitions(ctx, table)
+		if err != nil {
+			return err
+		}
+		for _, partition := range partitions {
+			if partition.Default || partition.StartLedger == 0 ||
+				ledgerToid(partition.StartLedger) < start || ledgerToid(partition.EndLedger) > end {
+				continue
+			}
+			statements := []string{
+				"DROP TABLE " + pq.QuoteIdentifier(partition.Name),
+				createHistoryPartitionStatement(table, partition.StartLedger, partition.EndLedger),
+			}
+			for _, statement := range statements {
+				if _, err := q.ExecRaw(ctx, statement); err != nil {
+					return errors.Wrapf(err, "could not rebuild partition %s", partition.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// DropHistoryPartition drops a partition of a history table, removing all its
+// rows at once.
+func (q *Q) DropHistoryPartition(ctx context.Context, partition HistoryPartition) error {
+	if !isHistoryPartitionedTable(partition.Table) ||
+		!strings.HasPrefix(partition.Name, partition.Table+"_") {
+		return errors.Errorf("%s is not a partition of a history table", partition.Name)
+	}
+	if partition.Default {
+		return errors.New("the default partition cannot be dropped")
+	}
+	_, err := q.ExecRaw(ctx, "DROP TABLE "+pq.QuoteIdentifier(partition.Name))
+	return errors.Wrapf(err, "could not drop partition %s", partition.Name)
+}
@@ -0,0 +1,174 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hcnet/go/services/aurora/internal/test"
+)
+
+func TestParseHistoryPartition(t *testing.T) {
+	partition, err := parseHistoryPartition(
+		"history_effects", "history_effects_legacy", "FOR VALUES FROM (MINVALUE) TO ('4294967296000')",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, HistoryPartition{
+		Table: "history_effects", Name: "history_effects_legacy", StartLedger: 0, EndLedger: 1000,
+	}, partition)
+
+	partition, err = parseHistoryPartition(
+		"history_effects", "history_effects_p1000", "FOR VALUES FROM ('4294967296000') TO ('8589934592000')",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, HistoryPartition{
+		Table: "history_effects", Name: "history_effects_p1000", StartLedger: 1000, EndLedger: 2000,
+	}, partition)
+
+	partition, err = parseHistoryPartition("history_effects", "history_effects_default", "DEFAULT")
+	require.NoError(t, err)
+	assert.True(t, partition.Default)
+
+	_, err = parseHistoryPartition("history_effects", "history_effects_list", "FOR VALUES IN ('1')")
+	assert.EqualError(t, err, "unexpected bound of partition history_effects_list: FOR VALUES IN ('1')")
+
+	partitions := []HistoryPartition{
+		{Name: "default", Default: true},
+		{Name: "p2000", StartLedger: 2000, EndLedger: 3000},
+		{Name: "legacy", EndLedger: 1000},
+		{Name: "p1000", StartLedger: 1000, EndLedger: 2000},
+	}
+	sortHistoryPartitions(partitions)
+	var names []string
+	for _, p := range partitions {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"legacy", "p1000", "p2000", "default"}, names)
+}
+
+func TestPartitionedIndexMethod(t *testing.T) {
+	_, err := partitionedIndexMethod(
+		"CREATE UNIQUE INDEX history_operation_participants_pkey ON public.history_operation_participants USING btree (id)",
+		true, "history_operation_id",
+	)
+	assert.EqualError(t, err, "unique index doesn't contain the partition column history_operation_id")
+
+	method, err := partitionedIndexMethod(
+		"CREATE UNIQUE INDEX hist_op_p_id ON public.history_operation_participants USING btree (history_account_id, history_operation_id)",
+		true, "history_operation_id",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "btree (history_account_id, history_operation_id)", method)
+
+	method, err = partitionedIndexMethod(
+		"CREATE UNIQUE INDEX by_lower_hash ON public.history_transactions USING btree (lower(transaction_hash)) WHERE (id > 0)",
+		true, "id",
+	)
+	assert.EqualError(t, err, "unique index doesn't contain the partition column id")
+
+	method, err = partitionedIndexMethod(
+		"CREATE UNIQUE INDEX by_id_hash ON public.history_transactions USING btree (lower(transaction_hash), id) WHERE (id > 0)",
+		true, "id",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "btree (lower(transaction_hash), id) WHERE (id > 0)", method)
+
+	method, err = partitionedIndexMethod(
+		"CREATE INDEX by_hash ON public.history_transactions USING btree (transaction_hash)",
+		false, "id",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "btree (transaction_hash)", method)
+
+	_, err = partitionedIndexMethod("CREATE INDEX by_hash ON public.history_transactions", false, "id")
+	assert.EqualError(t, err, "missing USING clause")
+}
+
+func TestPartitionHistoryTables(t *testing.T) {
+	tt := test.Start(t)
+	tt.Scenario("kahuna")
+	defer tt.Finish()
+	q := &Q{SessionInterface: tt.AuroraSession()}
+
+	var prevEffects, curEffects int
+	tt.Require.NoError(q.GetRaw(tt.Ctx, &prevEffects, `SELECT COUNT(*) FROM history_effects`))
+
+	// DDL is transactional, rolling back restores the schema for other tests.
+	tt.Require.NoError(q.Begin())
+	defer q.Rollback()
+
+	tt.Require.NoError(q.PartitionHistoryTables(tt.Ctx, 100))
+	tt.Assert.EqualError(
+		q.PartitionHistoryTables(tt.Ctx, 100),
+		"could not partition history_effects: table is already partitioned",
+	)
+
+	partitions, err := q.HistoryPartitions(tt.Ctx, "history_effects")
+	tt.Require.NoError(err)
+	tt.Assert.Equal([]HistoryPartition{
+		{Table: "history_effects", Name: "history_effects_legacy", EndLedger: 100},
+		{Table: "history_effects", Name: "history_effects_p100", StartLedger: 100, EndLedger: 200},
+		{Table: "history_effects", Name: "history_effects_default", Default: true},
+	}, partitions)
+
+	tt.Require.NoError(q.GetRaw(tt.Ctx, &curEffects, `SELECT COUNT(*) FROM history_effects`))
+	tt.Assert.Equal(prevEffects, curEffects)
+
+	created, err := q.CreateHistoryPartitions(tt.Ctx, "history_effects", 350)
+	tt.Require.NoError(err)
+	tt.Assert.Equal([]HistoryPartition{
+		{Table: "history_effects", Name: "history_effects_p200", StartLedger: 200, EndLedger: 300},
+		{Table: "history_effects", Name: "history_effects_p300", StartLedger: 300, EndLedger: 400},
+	}, created)
+
+	// the participants tables are partitioned with a unique index containing
+	// the partition key
+	for _, table := range []string{"history_operation_participants", "history_transaction_participants"} {
+		partitions, err = q.HistoryPartitions(tt.Ctx, table)
+		tt.Require.NoError(err)
+		tt.Assert.Len(partitions, 3)
+
+		var uniqueIndexes []string
+		tt.Require.NoError(q.SelectRaw(tt.Ctx, &uniqueIndexes, `
+			SELECT pg_get_indexdef(i.indexrelid) FROM pg_index i
+			WHERE i.indrelid = ?::regclass AND i.indisunique
+		`, table))
+		tt.Assert.NotEmpty(uniqueIndexes)
+		for _, index := range uniqueIndexes {
+			tt.Assert.Contains(index, historyRangeTables[table])
+		}
+	}
+
+	// rows of the default partition are moved to new partitions
+	var opCount, defaultCount, partitionCount int
+	tt.Require.NoError(q.GetRaw(tt.Ctx, &opCount, `SELECT COUNT(*) FROM history_operations`))
+	_, err = q.ExecRaw(tt.Ctx, `
+		INSERT INTO history_operations (id, transaction_id, application_order, type, details, source_account)
+		VALUES (?, ?, 1, 0, '{}', 'GAQAA5L65LSYH7CQ3VTJ7F3HHLGCL3DSLAR2Y47263D56MNNGHSQSTVY')
+	`, ledgerToid(1100), ledgerToid(1100))
+	tt.Require.NoError(err)
+	_, err = q.CreateHistoryPartitions(tt.Ctx, "history_operations", 1100)
+	tt.Require.NoError(err)
+	tt.Require.NoError(q.GetRaw(tt.Ctx, &defaultCount, `SELECT COUNT(*) FROM history_operations_default`))
+	tt.Assert.Equal(0, defaultCount)
+	tt.Require.NoError(q.GetRaw(tt.Ctx, &partitionCount, `SELECT COUNT(*) FROM history_operations_p1100`))
+	tt.Assert.Equal(1, partitionCount)
+
+	// reingestion rebuilds the partitions within the range
+	tt.Require.NoError(q.DeleteRangeAll(tt.Ctx, ledgerToid(1100), ledgerToid(1200)))
+	tt.Require.NoError(q.GetRaw(tt.Ctx, &partitionCount, `SELECT COUNT(*) FROM history_operations`))
+	tt.Assert.Equal(opCount, partitionCount)
+	partitions, err = q.HistoryPartitions(tt.Ctx, "history_operations")
+	tt.Require.NoError(err)
+	tt.Assert.Contains(partitions, HistoryPartition{
+		Table: "history_operations", Name: "history_operations_p1100", StartLedger: 1100, EndLedger: 1200,
+	})
+
+	partitions, err = q.HistoryPartitions(tt.Ctx, "history_effects")
+	tt.Require.NoError(err)
+	tt.Require.NoError(q.DropHistoryPartition(tt.Ctx, partitions[0]))
+	tt.Require.NoError(q.GetRaw(tt.Ctx, &curEffects, `SELECT COUNT(*) FROM history_effects`))
+	tt.Assert.Equal(0, curEffects)
+	tt.Assert.Error(q.DropHistoryPartition(tt.Ctx, partitions[2]))
+}
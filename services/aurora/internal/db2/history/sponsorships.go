@@ -0,0 +1,212 @@
+package history
+
+import (
+	"context"
+	"strings"
+
+	"github.com/hcnet/go/services/aurora/internal/db2"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/xdr"
+)
+
+// Types of the sponsored ledger entries, sponsored entries are ordered by
+// type first.
+const (
+	SponsoredAccount          = "account"
+	SponsoredClaimableBalance = "claimable_balance"
+	SponsoredData             = "data"
+	SponsoredOffer            = "offer"
+	SponsoredSigner           = "signer"
+	SponsoredTrustLine        = "trustline"
+)
+
+// SponsoredEntryTypes are the types of the ledger entries which can be
+// sponsored, in order.
+var SponsoredEntryTypes = []string{
+	SponsoredAccount,
+	SponsoredClaimableBalance,
+	SponsoredData,
+	SponsoredOffer,
+	SponsoredSigner,
+	SponsoredTrustLine,
+}
+
+// sponsoredEntriesPart selects the entries of a type sponsored by an
+// account.
+type sponsoredEntriesPart struct {
+	entryType string
+	// key is the expression of the key identifying the entries in their type.
+	key string
+	// query selects the entries, it's filtered by the existing index on the
+	// sponsor column of the table and ends with the condition on the sponsor
+	// so that other conditions can be appended.
+	query string
+}
+
+// sponsoredEntriesParts select the entries sponsored by an account in every
+// state table, in the order of SponsoredEntryTypes. reserve_count is the
+// number of base reserves the sponsor has committed to the entry. Every part
+// names its columns since the parts before the cursor are left out of the
+// union, which takes its column names from its first part.
+var sponsoredEntriesParts = []sponsoredEntriesPart{
+	{SponsoredAccount, "a.account_id", `
+	SELECT 'account' AS type, a.account_id AS key, a.account_id AS account_id,
+		'' AS signer, 0 AS asset_type, '' AS asset_code, '' AS asset_issuer,
+		'' AS liquidity_pool_id, 0::bigint AS offer_id, '' AS data_name,
+		'' AS balance_id, 2 AS reserve_count, a.last_modified_ledger AS last_modified_ledger
+	FROM accounts a WHERE a.sponsor = ?`},
+	{SponsoredClaimableBalance, "cb.id", `
+	SELECT 'claimable_balance' AS type, cb.id AS key, '' AS account_id,
+		'' AS signer, 0 AS asset_type, '' AS asset_code, '' AS asset_issuer,
+		'' AS liquidity_pool_id, 0::bigint AS offer_id, '' AS data_name,
+		cb.id AS balance_id, jsonb_array_length(cb.claimants) AS reserve_count,
+		cb.last_modified_ledger AS last_modified_ledger
+	FROM claimable_balances cb WHERE cb.sponsor = ?`},
+	{SponsoredData, "d.ledger_key", `
+	SELECT 'data' AS type, d.ledger_key AS key, d.account_id AS account_id,
+		'' AS signer, 0 AS asset_type, '' AS asset_code, '' AS asset_issuer,
+		'' AS liquidity_pool_id, 0::bigint AS offer_id, d.name AS data_name,
+		'' AS balance_id, 1 AS reserve_count, d.last_modified_ledger AS last_modified_ledger
+	FROM accounts_data d WHERE d.sponsor = ?`},
+	{SponsoredOffer, "lpad(o.offer_id::text, 20, '0')", `
+	SELECT 'offer' AS type, lpad(o.offer_id::text, 20, '0') AS key, o.seller_id AS account_id,
+		'' AS signer, 0 AS asset_type, '' AS asset_code, '' AS asset_issuer,
+		'' AS liquidity_pool_id, o.offer_id AS offer_id, '' AS data_name,
+		'' AS balance_id, 1 AS reserve_count, o.last_modified_ledger AS last_modified_ledger
+	FROM offers o WHERE o.deleted = false AND o.sponsor = ?`},
+	{SponsoredSigner, "s.account_id || ':' || s.signer", `
+	SELECT 'signer' AS type, s.account_id || ':' || s.signer AS key, s.account_id AS account_id,
+		s.signer AS signer, 0 AS asset_type, '' AS asset_code, '' AS asset_issuer,
+		'' AS liquidity_pool_id, 0::bigint AS offer_id, '' AS data_name,
+		'' AS balance_id, 1 AS reserve_count, sa.last_modified_ledger AS last_modified_ledger
+	FROM accounts_signers s JOIN accounts sa ON sa.account_id = s.account_id
+	WHERE s.sponsor = ?`},
+	{SponsoredTrustLine, "tl.ledger_key", `
+	SELECT 'trustline' AS type, tl.ledger_key AS key, tl.account_id AS account_id,
+		'' AS signer, tl.asset_type AS asset_type, tl.asset_code AS asset_code,
+		tl.asset_issuer AS asset_issuer, tl.liquidity_pool_id AS liquidity_pool_id,
+		0::bigint AS offer_id, '' AS data_name, '' AS balance_id,
+		CASE WHEN tl.asset_type = 3 THEN 2 ELSE 1 END AS reserve_count,
+		tl.last_modified_ledger AS last_modified_ledger
+	FROM trust_lines tl WHERE tl.sponsor = ?`},
+}
+
+// SponsoredEntry is a ledger entry sponsored by an account.
+type SponsoredEntry struct {
+	Type string `db:"type"`
+	Key  string `db:"key"`
+	// AccountID is the account owning the entry, it's empty for claimable
+	// balances.
+	AccountID          string        `db:"account_id"`
+	Signer             string        `db:"signer"`
+	AssetType          xdr.AssetType `db:"asset_type"`
+	AssetCode          string        `db:"asset_code"`
+	AssetIssuer        string        `db:"asset_issuer"`
+	LiquidityPoolID    string        `db:"liquidity_pool_id"`
+	OfferID            int64         `db:"offer_id"`
+	DataName           string        `db:"data_name"`
+	BalanceID          string        `db:"balance_id"`
+	ReserveCount       int64         `db:"reserve_count"`
+	LastModifiedLedger uint32        `db:"last_modified_ledger"`
+}
+
+// PagingToken returns the cursor of the entry in the sponsored entries.
+func (e SponsoredEntry) PagingToken() string {
+	return e.Type + ":" + e.Key
+}
+
+// SponsorshipTotal is the number of entries of a type sponsored by an account
+// and the number of base reserves committed to them.
+type SponsorshipTotal struct {
+	Type         string `db:"type"`
+	Count        int64  `db:"count"`
+	ReserveCount int64  `db:"reserve_count"`
+}
+
+// SponsorshipsQuery is a helper struct to configure queries to the entries
+// sponsored by an account.
+type SponsorshipsQuery struct {
+	PageQuery db2.PageQuery
+	Sponsor   string
+}
+
+// Cursor validates and returns the type and key of the query page cursor.
+func (q SponsorshipsQuery) Cursor() (string, string, error) {
+	if q.PageQuery.Cursor == "" {
+		return "", "", nil
+	}
+	parts := strings.SplitN(q.PageQuery.Cursor, ":", 2)
+	if len(parts) != 2 {
+		return "", "", errors.New("Invalid cursor")
+	}
+	for _, entryType := range SponsoredEntryTypes {
+		if parts[0] == entryType {
+			return parts[0], parts[1], nil
+		}
+	}
+	return "", "", errors.Errorf("Invalid cursor - unknown entry type %s", parts[0])
+}
+
+// GetSponsoredEntries loads a page of the ledger entries sponsored by an
+// account ordered by type and key. The cursor and the limit are applied to
+// the entries of each type, the types before the cursor are skipped.
+func (q *Q) GetSponsoredEntries(ctx context.Context, query SponsorshipsQuery) ([]SponsoredEntry, error) {
+	entryType, key, err := query.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	var comparison, order string
+	switch query.PageQuery.Order {
+	case db2.OrderAscending:
+		comparison, order = ">", "asc"
+	case db2.OrderDescending:
+		comparison, order = "<", "desc"
+	default:
+		return nil, errors.Errorf("invalid order: %s", query.PageQuery.Order)
+	}
+
+	var parts []string
+	var args []interface{}
+	for _, part := range sponsoredEntriesParts {
+		if entryType != "" && ((order == "asc" && part.entryType < entryType) || (order == "desc" && part.entryType > entryType)) {
+			continue
+		}
+		sql := part.query
+		args = append(args, query.Sponsor)
+		if entryType != "" {
+			if part.entryType == entryType {
+				sql += " AND " + part.key + " " + comparison + " ?"
+				args = append(args, key)
+			}
+		}
+		parts = append(parts, "("+sql+" ORDER BY "+part.key+" "+order+" LIMIT ?)")
+		args = append(args, query.PageQuery.Limit)
+	}
+
+	sql := "SELECT * FROM (" + strings.Join(parts, " UNION ALL ") + ") entries" +
+		" ORDER BY type " + order + ", key " + order + " LIMIT ?"
+	args = append(args, query.PageQuery.Limit)
+
+	var entries []SponsoredEntry
+	err = q.SelectRaw(ctx, &entries, sql, args...)
+	return entries, err
+}
+
+// GetSponsorshipTotals returns the number of entries sponsored by an account
+// and the number of base reserves committed to them, by type of entry. Types
+// without sponsored entries are omitted.
+func (q *Q) GetSponsorshipTotals(ctx context.Context, sponsor string) ([]SponsorshipTotal, error) {
+	parts := make([]string, len(sponsoredEntriesParts))
+	args := make([]interface{}, len(sponsoredEntriesParts))
+	for i, part := range sponsoredEntriesParts {
+		parts[i] = part.query
+		args[i] = sponsor
+	}
+	sql := "SELECT type, count(*) AS count, sum(reserve_count)::bigint AS reserve_count " +
+		"FROM (" + strings.Join(parts, " UNION ALL ") + ") entries GROUP BY type ORDER BY type"
+
+	var totals []SponsorshipTotal
+	err := q.SelectRaw(ctx, &totals, sql, args...)
+	return totals, err
+}
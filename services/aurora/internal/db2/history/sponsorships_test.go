@@ -0,0 +1,151 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hcnet/go/services/aurora/internal/db2"
+	"github.com/hcnet/go/services/aurora/internal/test"
+	"github.com/hcnet/go/xdr"
+)
+
+func TestSponsoredEntries(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{SessionInterface: tt.AuroraSession()}
+
+	tt.Assert.NoError(q.UpsertAccounts(tt.Ctx, []AccountEntry{account1, account2}))
+	tt.Assert.NoError(q.UpsertTrustLines(tt.Ctx, []TrustLine{eurTrustLine, usdTrustLine}))
+	tt.Assert.NoError(q.UpsertOffers(tt.Ctx, []Offer{eurOffer, twoEurOffer}))
+	_, err := q.CreateAccountSigner(tt.Ctx, account2.AccountID, "GA4NQFHQBTPVJ5MOQ4JRSGR2QVDLGQFIRMZMKAFRFOACZW2CBGUFVGNV", 1, &sponsor)
+	tt.Assert.NoError(err)
+	tt.Assert.NoError(q.UpsertAccountData(tt.Ctx, []Data{{
+		AccountID:          account1.AccountID,
+		Name:               "test data",
+		Value:              AccountDataValue("test"),
+		LastModifiedLedger: 1234,
+		Sponsor:            null.StringFrom(sponsor),
+	}}))
+	balanceID, err := xdr.MarshalHex(xdr.ClaimableBalanceId{
+		Type: xdr.ClaimableBalanceIdTypeClaimableBalanceIdTypeV0,
+		V0:   &xdr.Hash{1, 2, 3},
+	})
+	tt.Assert.NoError(err)
+	unconditional := xdr.ClaimPredicate{Type: xdr.ClaimPredicateTypeClaimPredicateUnconditional}
+	tt.Assert.NoError(q.UpsertClaimableBalances(tt.Ctx, []ClaimableBalance{{
+		BalanceID: balanceID,
+		Claimants: []Claimant{
+			{Destination: account1.AccountID, Predicate: unconditional},
+			{Destination: account2.AccountID, Predicate: unconditional},
+		},
+		Asset:              eurAsset,
+		Amount:             10,
+		Sponsor:            null.StringFrom(sponsor),
+		LastModifiedLedger: 123,
+	}}))
+
+	totals, err := q.GetSponsorshipTotals(tt.Ctx, sponsor)
+	tt.Assert.NoError(err)
+	tt.Assert.Equal([]SponsorshipTotal{
+		{Type: SponsoredAccount, Count: 1, ReserveCount: 2},
+		{Type: SponsoredClaimableBalance, Count: 1, ReserveCount: 2},
+		{Type: SponsoredData, Count: 1, ReserveCount: 1},
+		{Type: SponsoredOffer, Count: 1, ReserveCount: 1},
+		{Type: SponsoredSigner, Count: 1, ReserveCount: 1},
+		{Type: SponsoredTrustLine, Count: 1, ReserveCount: 1},
+	}, totals)
+
+	query := SponsorshipsQuery{
+		PageQuery: db2.PageQuery{Order: db2.OrderAscending, Limit: 4},
+		Sponsor:   sponsor,
+	}
+	entries, err := q.GetSponsoredEntries(tt.Ctx, query)
+	tt.Assert.NoError(err)
+	if tt.Assert.Len(entries, 4) {
+		tt.Assert.Equal(SponsoredAccount, entries[0].Type)
+		tt.Assert.Equal(account2.AccountID, entries[0].AccountID)
+		tt.Assert.Equal(SponsoredClaimableBalance, entries[1].Type)
+		tt.Assert.Equal(balanceID, entries[1].BalanceID)
+		tt.Assert.Equal(int64(2), entries[1].ReserveCount)
+		tt.Assert.Equal(SponsoredData, entries[2].Type)
+		tt.Assert.Equal("test data", entries[2].DataName)
+		tt.Assert.Equal(SponsoredOffer, entries[3].Type)
+		tt.Assert.Equal(eurOffer.OfferID, entries[3].OfferID)
+	}
+
+	query.PageQuery.Cursor = entries[3].PagingToken()
+	entries, err = q.GetSponsoredEntries(tt.Ctx, query)
+	tt.Assert.NoError(err)
+	if tt.Assert.Len(entries, 2) {
+		tt.Assert.Equal(SponsoredSigner, entries[0].Type)
+		tt.Assert.Equal(account2.AccountID, entries[0].AccountID)
+		tt.Assert.Equal(account2.LastModifiedLedger, entries[0].LastModifiedLedger)
+		tt.Assert.Equal(SponsoredTrustLine, entries[1].Type)
+		tt.Assert.Equal("EUR", entries[1].AssetCode)
+	}
+
+	query.PageQuery.Order = db2.OrderDescending
+	query.PageQuery.Limit = 1
+	entries, err = q.GetSponsoredEntries(tt.Ctx, query)
+	tt.Assert.NoError(err)
+	if tt.Assert.Len(entries, 1) {
+		tt.Assert.Equal(SponsoredData, entries[0].Type)
+	}
+
+	// pages of one entry cross every type boundary in both orders
+	for _, order := range []string{db2.OrderAscending, db2.OrderDescending} {
+		query.PageQuery = db2.PageQuery{Order: order, Limit: 1}
+		var types []string
+		for i := 0; i <= len(SponsoredEntryTypes); i++ {
+			entries, err = q.GetSponsoredEntries(tt.Ctx, query)
+			tt.Assert.NoError(err, order)
+			if len(entries) == 0 {
+				break
+			}
+			types = append(types, entries[0].Type)
+			query.PageQuery.Cursor = entries[0].PagingToken()
+		}
+		expected := append([]string{}, SponsoredEntryTypes...)
+		if order == db2.OrderDescending {
+			for i, j := 0, len(expected)-1; i < j; i, j = i+1, j-1 {
+				expected[i], expected[j] = expected[j], expected[i]
+			}
+		}
+		tt.Assert.Equal(expected, types, order)
+	}
+
+	entries, err = q.GetSponsoredEntries(tt.Ctx, SponsorshipsQuery{
+		PageQuery: db2.PageQuery{Order: db2.OrderAscending, Limit: 10},
+		Sponsor:   inflationDest,
+	})
+	tt.Assert.NoError(err)
+	tt.Assert.Empty(entries)
+}
+
+func TestSponsorshipsQueryCursor(t *testing.T) {
+	for _, testCase := range []struct {
+		cursor    string
+		entryType string
+		key       string
+		valid     bool
+	}{
+		{"", "", "", true},
+		{"offer:00000000000000000004", SponsoredOffer, "00000000000000000004", true},
+		{"signer:GA:GB", SponsoredSigner, "GA:GB", true},
+		{"offer", "", "", false},
+		{"pool:abc", "", "", false},
+	} {
+		query := SponsorshipsQuery{PageQuery: db2.PageQuery{Cursor: testCase.cursor}}
+		entryType, key, err := query.Cursor()
+		if !testCase.valid {
+			assert.Error(t, err, testCase.cursor)
+			continue
+		}
+		assert.NoError(t, err, testCase.cursor)
+		assert.Equal(t, testCase.entryType, entryType)
+		assert.Equal(t, testCase.key, key)
+	}
+}
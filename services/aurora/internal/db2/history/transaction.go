@@ -163,6 +163,25 @@ func (q *TransactionsQ) ForLedger(ctx context.Context, seq int32) *TransactionsQ
 	return q
 }
 
+// ForLedgerRange filters the query to only transactions in ledgers with
+// sequence in [start, end).
+func (q *TransactionsQ) ForLedgerRange(start, end int32) *TransactionsQ {
+	q.sql = q.sql.Where(
+		"ht.id >= ? AND ht.id < ?",
+		toid.New(start, 0, 0).ToInt64(),
+		toid.New(end, 0, 0).ToInt64(),
+	)
+	return q
+}
+
+// ForMemo filters the query to only transactions with the given memo. Memos
+// are compared using the same representation as the one stored during
+// ingestion.
+func (q *TransactionsQ) ForMemo(memo xdr.Memo) *TransactionsQ {
+	q.sql = q.sql.Where(memoPredicate(memo))
+	return q
+}
+
 // IncludeFailed changes the query to include failed transactions.
 func (q *TransactionsQ) IncludeFailed() *TransactionsQ {
 	q.includeFailed = true
@@ -278,3 +297,10 @@ func selectTransaction(table string) sq.SelectBuilder {
 
 var selectTransactionHistory = selectTransaction("history_transactions")
 var selectTransactionPreFilteredTmp = selectTransaction("history_transactions_filtered_tmp")
+
+func memoPredicate(memo xdr.Memo) sq.Eq {
+	return sq.Eq{
+		"ht.memo_type": memoType(memo),
+		"ht.memo":      memoValue(memo).String,
+	}
+}
@@ -75,8 +75,8 @@ func signatures(xdrSignatures []xdr.DecoratedSignature) pq.StringArray {
 	return signatures
 }
 
-func memoType(transaction ingest.LedgerTransaction) string {
-	switch transaction.Envelope.Memo().Type {
+func memoType(memo xdr.Memo) string {
+	switch memo.Type {
 	case xdr.MemoTypeMemoNone:
 		return "none"
 	case xdr.MemoTypeMemoText:
@@ -88,16 +88,15 @@ func memoType(transaction ingest.LedgerTransaction) string {
 	case xdr.MemoTypeMemoReturn:
 		return "return"
 	default:
-		panic(fmt.Errorf("invalid memo type: %v", transaction.Envelope.Memo().Type))
+		panic(fmt.Errorf("invalid memo type: %v", memo.Type))
 	}
 }
 
-func memo(transaction ingest.LedgerTransaction) null.String {
+func memoValue(memo xdr.Memo) null.String {
 	var (
 		value string
 		valid bool
 	)
-	memo := transaction.Envelope.Memo()
 	switch memo.Type {
 	case xdr.MemoTypeMemoNone:
 		value, valid = "", false
@@ -203,8 +202,8 @@ func transactionToRow(transaction ingest.LedgerTransaction, sequence uint32, enc
 		MinAccountSequenceAge:       formatDuration(transaction.Envelope.MinSeqAge()),
 		MinAccountSequenceLedgerGap: formatUint32(transaction.Envelope.MinSeqLedgerGap()),
 		ExtraSigners:                formatSigners(transaction.Envelope.ExtraSigners()),
-		MemoType:                    memoType(transaction),
-		Memo:                        memo(transaction),
+		MemoType:                    memoType(transaction.Envelope.Memo()),
+		Memo:                        memoValue(transaction.Envelope.Memo()),
 		CreatedAt:                   time.Now().UTC(),
 		UpdatedAt:                   time.Now().UTC(),
 		Successful:                  transaction.Result.Successful(),
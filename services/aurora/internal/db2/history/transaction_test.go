@@ -913,3 +913,63 @@ func TestHistoryTransactionSchemasMatch(t *testing.T) {
 
 	tt.Assert.ElementsMatch(txColumns, txTmpFilteredTmpColumns)
 }
+
+func TestTransactionLedgerRange(t *testing.T) {
+	tt := test.Start(t)
+	tt.Scenario("base")
+	defer tt.Finish()
+	q := &Q{tt.AuroraSession()}
+
+	var txs []Transaction
+	err := q.Transactions().ForLedgerRange(2, 3).Select(tt.Ctx, &txs)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(txs, 3)
+
+	txs = nil
+	err = q.Transactions().ForLedgerRange(2, 4).Select(tt.Ctx, &txs)
+	tt.Assert.NoError(err)
+	tt.Assert.Len(txs, 4)
+}
+
+func TestTransactionsForMemo(t *testing.T) {
+	tt := test.Start(t)
+	tt.Scenario("kahuna")
+	defer tt.Finish()
+	q := &Q{tt.AuroraSession()}
+
+	var hash, retHash xdr.Hash
+	for i := range hash {
+		hash[i] = 1
+		retHash[i] = 2
+	}
+
+	for _, testCase := range []struct {
+		memo     xdr.Memo
+		memoType string
+		value    string
+	}{
+		{xdr.MemoText("hello"), "text", "hello"},
+		{xdr.MemoID(123), "id", "123"},
+		{xdr.MemoHash(hash), "hash", "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE="},
+		{xdr.MemoRetHash(retHash), "return", "AgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgI="},
+	} {
+		var txs []Transaction
+		err := q.Transactions().ForMemo(testCase.memo).Select(tt.Ctx, &txs)
+		tt.Assert.NoError(err)
+		if tt.Assert.Len(txs, 1, testCase.memoType) {
+			tt.Assert.Equal(testCase.memoType, txs[0].MemoType)
+			tt.Assert.Equal(testCase.value, txs[0].Memo.String)
+		}
+
+		ops, _, err := q.Operations().ForMemo(testCase.memo).Fetch(tt.Ctx)
+		tt.Assert.NoError(err)
+		if tt.Assert.NotEmpty(ops, testCase.memoType) {
+			tt.Assert.Equal(txs[0].TransactionHash, ops[0].TransactionHash)
+		}
+	}
+
+	var txs []Transaction
+	err := q.Transactions().ForMemo(xdr.MemoID(124)).Select(tt.Ctx, &txs)
+	tt.Assert.NoError(err)
+	tt.Assert.Empty(txs)
+}
@@ -0,0 +1,163 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/guregu/null"
+	"github.com/lib/pq"
+
+	"github.com/hcnet/go/support/errors"
+)
+
+const webhookSubscriptionsTableName = "webhook_subscriptions"
+
+// WebhookSubscription is a row of data from the `webhook_subscriptions` table.
+// Operations matching all the filters set on a subscription are delivered to
+// its URL.
+type WebhookSubscription struct {
+	ID     int64  `db:"id"`
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+	// AccountID filters operations by participating account.
+	AccountID null.String `db:"account_id"`
+	// Asset filters operations by asset, in canonical form.
+	Asset null.String `db:"asset"`
+	// OperationTypes filters operations by type.
+	OperationTypes pq.Int64Array `db:"operation_types"`
+	// Cursor is the paging token of the last operation delivered or skipped.
+	Cursor int64 `db:"cursor"`
+	// Failures is the number of consecutive failed deliveries, the next
+	// delivery is attempted after NextAttemptAt.
+	Failures      int         `db:"failures"`
+	NextAttemptAt null.Time   `db:"next_attempt_at"`
+	LastError     null.String `db:"last_error"`
+	CreatedAt     time.Time   `db:"created_at"`
+	UpdatedAt     time.Time   `db:"updated_at"`
+}
+
+var selectWebhookSubscription = sq.Select(
+	"id", "url", "secret", "account_id", "asset", "operation_types", "cursor",
+	"failures", "next_attempt_at", "last_error", "created_at", "updated_at",
+).From(webhookSubscriptionsTableName)
+
+// WebhookSubscriptions returns all webhook subscriptions ordered by id.
+func (q *Q) WebhookSubscriptions(ctx context.Context) ([]WebhookSubscription, error) {
+	var subscriptions []WebhookSubscription
+	err := q.Select(ctx, &subscriptions, selectWebhookSubscription.OrderBy("id asc"))
+	return subscriptions, err
+}
+
+// WebhookSubscriptionByID returns the webhook subscription with the given id,
+// or sql.ErrNoRows if it does not exist.
+func (q *Q) WebhookSubscriptionByID(ctx context.Context, id int64) (WebhookSubscription, error) {
+	var subscription WebhookSubscription
+	err := q.Get(ctx, &subscription, selectWebhookSubscription.Where("id = ?", id))
+	return subscription, err
+}
+
+// InsertWebhookSubscription creates a webhook subscription and returns it.
+func (q *Q) InsertWebhookSubscription(ctx context.Context, subscription WebhookSubscription) (WebhookSubscription, error) {
+	var id int64
+	sql := sq.Insert(webhookSubscriptionsTableName).SetMap(map[string]interface{}{
+		"url":             subscription.URL,
+		"secret":          subscription.Secret,
+		"account_id":      subscription.AccountID,
+		"asset":           subscription.Asset,
+		"operation_types": subscription.OperationTypes,
+		"cursor":          subscription.Cursor,
+	}).Suffix("RETURNING id")
+	if err := q.Get(ctx, &id, sql); err != nil {
+		return WebhookSubscription{}, errors.Wrap(err, "could not insert webhook subscription")
+	}
+	return q.WebhookSubscriptionByID(ctx, id)
+}
+
+// UpdateWebhookSubscription updates the URL, the secret, the filters and the
+// cursor of a webhook subscription and resets its delivery failures. It
+// returns sql.ErrNoRows if the subscription does not exist.
+func (q *Q) UpdateWebhookSubscription(ctx context.Context, subscription WebhookSubscription) (WebhookSubscription, error) {
+	sqlUpdate := sq.Update(webhookSubscriptionsTableName).SetMap(map[string]interface{}{
+		"url":             subscription.URL,
+		"secret":          subscription.Secret,
+		"account_id":      subscription.AccountID,
+		"asset":           subscription.Asset,
+		"operation_types": subscription.OperationTypes,
+		"cursor":          subscription.Cursor,
+		"failures":        0,
+		"next_attempt_at": nil,
+		"last_error":      nil,
+		"updated_at":      sq.Expr("NOW()"),
+	}).Where("id = ?", subscription.ID)
+
+	rowCnt, err := q.checkForError(sqlUpdate, ctx)
+	if err != nil {
+		return WebhookSubscription{}, err
+	}
+	if rowCnt < 1 {
+		return WebhookSubscription{}, sql.ErrNoRows
+	}
+	return q.WebhookSubscriptionByID(ctx, subscription.ID)
+}
+
+// DeleteWebhookSubscription removes a webhook subscription. It returns
+// sql.ErrNoRows if the subscription does not exist.
+func (q *Q) DeleteWebhookSubscription(ctx context.Context, id int64) error {
+	rowCnt, err := q.checkForError(sq.Delete(webhookSubscriptionsTableName).Where("id = ?", id), ctx)
+	if err != nil {
+		return err
+	}
+	if rowCnt < 1 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DueWebhookSubscriptionIDs returns the ids of the webhook subscriptions
+// which are not waiting to retry a failed delivery at the given time.
+func (q *Q) DueWebhookSubscriptionIDs(ctx context.Context, now time.Time) ([]int64, error) {
+	var ids []int64
+	sql := sq.Select("id").From(webhookSubscriptionsTableName).
+		Where("next_attempt_at IS NULL OR next_attempt_at <= ?", now.UTC()).
+		OrderBy("id asc")
+	err := q.Select(ctx, &ids, sql)
+	return ids, err
+}
+
+// LockWebhookSubscription loads a webhook subscription and locks it until the
+// end of the current transaction. It returns sql.ErrNoRows if the
+// subscription does not exist or is locked by another transaction, so that
+// only one Aurora instance delivers a subscription at a time.
+func (q *Q) LockWebhookSubscription(ctx context.Context, id int64) (WebhookSubscription, error) {
+	if q.GetTx() == nil {
+		return WebhookSubscription{}, errors.New("cannot be called outside of a transaction")
+	}
+	var subscription WebhookSubscription
+	err := q.Get(ctx, &subscription, selectWebhookSubscription.Where("id = ?", id).Suffix("FOR UPDATE SKIP LOCKED"))
+	return subscription, err
+}
+
+// UpdateWebhookDelivery stores the outcome of a delivery attempt: the cursor
+// of the last operation delivered and, when the attempt failed, the number of
+// consecutive failures, the time of the next attempt and the error. The
+// subscription is only updated if its cursor is still previousCursor, the
+// cursor it was delivered from, so that a cursor set with
+// UpdateWebhookSubscription during the delivery is kept. It returns
+// sql.ErrNoRows if the subscription was removed or its cursor was changed.
+func (q *Q) UpdateWebhookDelivery(ctx context.Context, previousCursor int64, subscription WebhookSubscription) error {
+	rowCnt, err := q.checkForError(sq.Update(webhookSubscriptionsTableName).SetMap(map[string]interface{}{
+		"cursor":          subscription.Cursor,
+		"failures":        subscription.Failures,
+		"next_attempt_at": subscription.NextAttemptAt,
+		"last_error":      subscription.LastError,
+	}).Where("id = ? AND cursor = ?", subscription.ID, previousCursor), ctx)
+	if err != nil {
+		return errors.Wrap(err, "could not update webhook subscription")
+	}
+	if rowCnt < 1 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
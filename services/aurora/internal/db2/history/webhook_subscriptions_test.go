@@ -0,0 +1,86 @@
+package history
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/lib/pq"
+
+	"github.com/hcnet/go/services/aurora/internal/test"
+)
+
+func TestWebhookSubscriptions(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	test.ResetAuroraDB(t, tt.AuroraDB)
+	q := &Q{SessionInterface: tt.AuroraSession()}
+
+	first, err := q.InsertWebhookSubscription(tt.Ctx, WebhookSubscription{
+		URL:            "https://example.com/first",
+		Secret:         "secret",
+		OperationTypes: pq.Int64Array{1},
+		Cursor:         10,
+	})
+	tt.Require.NoError(err)
+	tt.Assert.Equal("https://example.com/first", first.URL)
+	tt.Assert.Equal(pq.Int64Array{1}, first.OperationTypes)
+	tt.Assert.False(first.AccountID.Valid)
+
+	second, err := q.InsertWebhookSubscription(tt.Ctx, WebhookSubscription{
+		URL:       "https://example.com/second",
+		Secret:    "secret",
+		AccountID: null.StringFrom("GAXMF43TGZHW3QN3REOUA2U5PW5BTARXGGYJ3JIFHW3YT6QRKRL3CPPU"),
+		Asset:     null.StringFrom("native"),
+	})
+	tt.Require.NoError(err)
+
+	subscriptions, err := q.WebhookSubscriptions(tt.Ctx)
+	tt.Require.NoError(err)
+	tt.Assert.Equal([]WebhookSubscription{first, second}, subscriptions)
+
+	now := time.Now()
+	tt.Require.NoError(q.Begin())
+	locked, err := q.LockWebhookSubscription(tt.Ctx, second.ID)
+	tt.Require.NoError(err)
+	locked.Cursor = 20
+	locked.Failures = 1
+	locked.NextAttemptAt = null.TimeFrom(now.Add(time.Minute).UTC())
+	locked.LastError = null.StringFrom("unexpected response status 500")
+	tt.Require.NoError(q.UpdateWebhookDelivery(tt.Ctx, 0, locked))
+	tt.Require.NoError(q.Commit())
+
+	// deliveries from a cursor which was changed in the meantime are discarded
+	stale := locked
+	stale.Cursor = 30
+	tt.Assert.Equal(sql.ErrNoRows, q.UpdateWebhookDelivery(tt.Ctx, 0, stale))
+	reloaded, err := q.WebhookSubscriptionByID(tt.Ctx, second.ID)
+	tt.Require.NoError(err)
+	tt.Assert.Equal(int64(20), reloaded.Cursor)
+
+	ids, err := q.DueWebhookSubscriptionIDs(tt.Ctx, now)
+	tt.Require.NoError(err)
+	tt.Assert.Equal([]int64{first.ID}, ids)
+	ids, err = q.DueWebhookSubscriptionIDs(tt.Ctx, now.Add(2*time.Minute))
+	tt.Require.NoError(err)
+	tt.Assert.Equal([]int64{first.ID, second.ID}, ids)
+
+	second.URL = "https://example.com/updated"
+	second, err = q.UpdateWebhookSubscription(tt.Ctx, second)
+	tt.Require.NoError(err)
+	tt.Assert.Equal("https://example.com/updated", second.URL)
+	tt.Assert.Equal(0, second.Failures)
+	tt.Assert.False(second.NextAttemptAt.Valid)
+	tt.Assert.False(second.LastError.Valid)
+
+	tt.Require.NoError(q.DeleteWebhookSubscription(tt.Ctx, first.ID))
+	_, err = q.WebhookSubscriptionByID(tt.Ctx, first.ID)
+	tt.Assert.Equal(sql.ErrNoRows, err)
+	tt.Assert.Equal(sql.ErrNoRows, q.DeleteWebhookSubscription(tt.Ctx, first.ID))
+	_, err = q.UpdateWebhookSubscription(tt.Ctx, first)
+	tt.Assert.Equal(sql.ErrNoRows, err)
+
+	_, err = q.LockWebhookSubscription(tt.Ctx, second.ID)
+	tt.Assert.EqualError(err, "cannot be called outside of a transaction")
+}
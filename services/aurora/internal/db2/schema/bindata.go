@@ -59,7 +59,16 @@
 // migrations/60_add_asset_id_indexes.sql (289B)
 // migrations/61_trust_lines_by_account_type_code_issuer.sql (383B)
 // migrations/62_claimable_balance_claimants.sql (1.428kB)
+// migrations/63_webhook_subscriptions.sql (700B)
+// migrations/64_history_account_balances.sql (890B)
+// migrations/65_trust_lines_by_type_code_issuer_balance.sql (232B)
+// migrations/66_history_type_indexes.sql (628B)
+// migrations/67_history_transactions_memo_index.sql (208B)
+// migrations/68_api_keys.sql (684B)
+// migrations/69_history_liquidity_pool_snapshots.sql (933B)
 // migrations/6_create_assets_table.sql (366B)
+// migrations/70_history_effects_sponsor_indexes.sql (827B)
+// migrations/71_history_order_book_snapshots.sql (1.45kB)
 // migrations/7_modify_trades_table.sql (2.303kB)
 // migrations/8_add_aggregators.sql (907B)
 // migrations/8_create_asset_stats_table.sql (441B)
@@ -1312,6 +1321,146 @@ func migrations62_claimable_balance_claimantsSql() (*asset, error) {
 	return a, nil
 }
 
+var _migrations63_webhook_subscriptionsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa4\x92\x4d\x6b\xf3\x30\x0c\xc7\xef\xfe\x14\x3a\xb6\x3c\x4f\x60\x97\xed\xd2\x53\xb6\x66\x30\x96\xb5\x25\xa4\x8c\x32\x46\x70\x1d\x35\x31\x4d\x6c\x23\x2b\x7d\xd9\xa7\x1f\x49\x9a\xad\xeb\xa0\x97\xd9\x07\x63\xe9\xa7\xbf\x5e\x50\x10\xc0\xbf\x5a\x17\x24\x19\x61\xe9\x84\x78\x48\xa2\x30\x8d\x20\x0d\xef\xe3\x08\xf6\xb8\x2e\xad\xdd\x66\xbe\x59\x7b\x45\xda\xb1\xb6\xc6\xc3\x48\x00\x00\xe8\x1c\x7e\x9c\xb5\x2e\x3c\x92\x96\x15\x2c\x92\xa7\x97\x30\x59\xc1\x73\xb4\xfa\xdf\xa1\x0d\x55\x03\xd5\x5d\xc6\x03\xc3\x6c\x9e\xc2\x6c\x19\xc7\x3d\xe2\x51\x11\xf2\x55\x44\x2a\x65\x1b\xc3\xd9\x90\x58\x95\x92\xa4\x62\x24\xd8\x49\x3a\x6a\x53\x8c\x6e\xef\xc6\x27\xd4\xfb\x73\xb1\x4e\xad\xf7\x58\x87\x24\xdb\x36\x32\x3e\x3a\xf4\xa0\x0d\x63\x81\xf4\xf6\xde\xbb\x83\x00\xb8\x44\x70\xb2\xd0\xa6\x00\xb6\x5b\x34\x60\x37\x9d\xad\x92\x9e\xbf\xc3\x21\xc7\x4a\xef\x90\x30\x07\x4b\xe0\xb7\xda\x39\xcc\x3b\x09\xd5\x90\xb7\x34\x64\xee\xe6\xa2\xcd\x65\x2f\x1b\xa9\xab\x86\xd0\x0f\xd0\xa9\x8c\x2f\x0a\xa6\xd1\x63\xb8\x8c\x53\xb8\xe9\x79\x83\x07\xce\x24\x33\xd6\xae\x7d\x81\x75\x8d\x9e\x65\xed\x60\xaf\xb9\xb4\x4d\x6f\x81\x0f\x6b\xb0\x0f\x68\xab\xcd\x90\xc8\xd2\xe5\x04\x14\xa1\x64\xcc\x5b\x95\xf6\x7b\x4d\xea\x77\x39\xb3\xf9\xeb\xe8\x34\xe3\xc6\xe5\x7f\xd4\x11\xe3\x89\x10\xe7\xfb\x37\xb5\x7b\x23\xc4\x34\x99\x2f\xae\xee\x9f\x92\x5e\xc9\x1c\x27\xe2\x73\x00\x2c\x71\x48\x45\xbc\x02\x00\x00")
+
+func migrations63_webhook_subscriptionsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations63_webhook_subscriptionsSql,
+		"migrations/63_webhook_subscriptions.sql",
+	)
+}
+
+func migrations63_webhook_subscriptionsSql() (*asset, error) {
+	bytes, err := migrations63_webhook_subscriptionsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/63_webhook_subscriptions.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x48, 0x4c, 0xdb, 0x16, 0xa0, 0x1b, 0x9d, 0xec, 0x60, 0xda, 0x6f, 0xe9, 0x9b, 0x78, 0xca, 0xfa, 0x6c, 0x35, 0x47, 0xc2, 0x9b, 0xdf, 0x98, 0xf6, 0x6b, 0x6c, 0xb3, 0xf7, 0xb4, 0x71, 0xdf, 0x7b}}
+	return a, nil
+}
+
+var _migrations64_history_account_balancesSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x53\x4d\x6f\xe2\x30\x10\xbd\xfb\x57\xcc\x0d\xd0\x26\x87\x5d\x69\xf7\xc2\x89\x6d\xd2\x0a\x29\x0a\x2d\x4d\xa4\xde\x22\xc7\x1e\x82\x25\xb0\x23\x7b\xf8\xc8\xbf\xaf\x48\x62\x9a\x16\x50\xa9\x4f\xa3\x78\xde\x9b\x97\xf7\xc6\x61\x08\xbf\xb6\xaa\xb2\x9c\x10\xf2\x9a\xb1\x87\x65\x3c\xcb\x62\xc8\x66\xff\x93\x18\xd6\xca\x91\xb1\x4d\xc1\x85\x30\x3b\x4d\x45\xc9\x37\x5c\x0b\x74\x30\x66\x00\x00\x1b\x94\x15\xda\x82\x8c\x92\xd0\x9e\x52\x55\x4a\x13\xa4\x8b\x0c\xd2\x3c\x49\x82\xb6\xcb\x83\x7d\x13\x88\x35\xb7\x5c\x10\x5a\xd8\x73\xdb\x28\x5d\x8d\xff\xfe\x9b\x7c\x05\x39\x87\x54\x50\x53\x63\x0f\x52\x9a\xb0\x42\x7b\xb5\x4d\x18\x89\x37\xb9\x7f\xff\xf9\xe0\x86\x28\x7e\x9c\xe5\x49\x06\xa3\xd1\x10\xaf\x9c\xdb\xa1\xbd\x43\xdb\x05\x3e\x0c\xa1\xf7\x04\x38\x01\xad\x11\x50\x4b\x30\xab\xb6\xec\xec\x09\x3a\xa4\x5a\xf5\xd7\x64\x1b\x38\x70\x07\x16\xb7\x66\x8f\xb2\xa5\xf1\x1c\xe7\xd3\x19\x79\x31\xa3\xc4\x95\xb1\x38\x20\x6f\x99\x78\x5d\x6f\x14\xca\x6b\x83\xa4\x92\xa0\x0d\x01\x1e\x95\xa3\x96\xad\xb6\xb8\x57\x66\xe7\x7c\x96\x7d\x66\x6c\x32\x3d\x67\x9f\xa7\xf3\x97\x3c\x86\x79\x1a\xc5\x6f\xa0\xb4\xc4\x63\x71\x6b\x11\x0a\xa3\xcf\xdf\x3a\x2f\xbb\x9f\x6e\x47\x2d\xd2\xdb\x0b\x94\xbf\xce\xd3\x27\x28\xc9\x22\xc2\xd8\xdf\x2a\x19\xf4\x89\x9c\x82\xf7\xf5\x29\x5d\x5f\x77\x49\x05\xc3\xcd\x9b\x4c\xbd\xee\x7b\x05\x0f\xc0\x3f\xd3\xf9\x79\x2a\x1b\x3e\x9d\xc8\x1c\x34\x63\xd1\x72\xf1\xfc\xdd\xd3\x11\xdc\x09\x2e\x71\xca\xde\x07\x00\x12\x1b\xea\x90\x7a\x03\x00\x00")
+
+func migrations64_history_account_balancesSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations64_history_account_balancesSql,
+		"migrations/64_history_account_balances.sql",
+	)
+}
+
+func migrations64_history_account_balancesSql() (*asset, error) {
+	bytes, err := migrations64_history_account_balancesSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/64_history_account_balances.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x8f, 0x95, 0x04, 0x43, 0xde, 0x43, 0x34, 0x99, 0x3f, 0x58, 0x38, 0x5d, 0x05, 0x1b, 0x1a, 0x1e, 0x59, 0xf0, 0x17, 0x34, 0xa1, 0x94, 0xf7, 0x22, 0x78, 0x82, 0x19, 0x08, 0xde, 0x9d, 0x09, 0x38}}
+	return a, nil
+}
+
+var _migrations65_trust_lines_by_type_code_issuer_balanceSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x8e\xb1\xaa\x83\x40\x10\x45\xfb\xf9\x8a\xc1\xea\x3d\xa2\x5f\x60\x15\xa2\x04\x1b\x0d\x26\x42\xba\x61\x5d\x87\xb0\x60\x56\xd9\x99\x25\xf8\xf7\x21\x31\x01\xdb\x74\xa7\xb8\xf7\x70\xb2\x0c\x77\x77\x77\x0b\x46\x19\xbb\x19\xe0\xd0\x96\xfb\x4b\x89\x55\x5d\x94\x57\x4c\x34\x44\x51\x1a\x9d\x67\xa1\x7e\x21\x5d\x66\x26\x3b\x0d\x4c\x4e\x24\x72\xa0\xde\x8c\xc6\x5b\x4e\xb0\xa9\x71\xb3\xc5\xee\x5c\xd5\x47\xec\x35\x30\xe3\x9f\x11\x61\x7d\x7f\x53\x5c\xf9\xa5\xf8\xf2\x6a\x4a\xf1\xa3\x4a\xd1\x58\x3b\x45\xaf\xe4\x86\xff\x1c\x60\xdb\x57\x4c\x0f\x0f\x50\xb4\xcd\xe9\xd7\xbe\x1c\x9e\x03\x00\x84\x57\xb7\xeb\xe8\x00\x00\x00")
+
+func migrations65_trust_lines_by_type_code_issuer_balanceSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations65_trust_lines_by_type_code_issuer_balanceSql,
+		"migrations/65_trust_lines_by_type_code_issuer_balance.sql",
+	)
+}
+
+func migrations65_trust_lines_by_type_code_issuer_balanceSql() (*asset, error) {
+	bytes, err := migrations65_trust_lines_by_type_code_issuer_balanceSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/65_trust_lines_by_type_code_issuer_balance.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x16, 0x4c, 0x0c, 0x2f, 0x14, 0x50, 0xdc, 0x59, 0xb3, 0x63, 0x2a, 0x57, 0x72, 0xeb, 0x43, 0x60, 0x28, 0xed, 0xcd, 0x82, 0x17, 0x31, 0x9b, 0xae, 0xe5, 0x50, 0x83, 0xd6, 0x0c, 0x33, 0x7d, 0x91}}
+	return a, nil
+}
+
+var _migrations66_history_type_indexesSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\xd0\xc1\x6a\x83\x40\x10\x06\xe0\xfb\x3e\xc5\xe0\xa9\xa5\xfa\x04\x7b\x2a\x55\x8a\x17\x2d\xb6\x42\x6f\xc3\xb6\x33\x26\x73\xc8\xae\xac\x0b\x89\x6f\x1f\x02\xc1\x68\x14\xd1\xf3\xfc\xcc\xff\xf1\x27\x09\xbc\x9d\xe4\xe0\x4d\x60\xa8\x5b\xa5\x3e\xaa\xec\xfd\x27\x83\xbc\x48\xb3\x5f\x10\x4b\x7c\xc1\xa3\x74\xc1\xf9\x1e\x5d\xcb\xde\x04\x71\xb6\x43\x67\x31\xf4\x2d\xa3\xb1\x84\x42\x50\x16\x30\x0f\x41\xfd\x9d\x17\x9f\xf0\x17\x3c\x33\xbc\xdc\xe2\x31\x08\xbd\x6a\x95\x56\xe5\xd7\xc6\x02\xbd\x0a\xe2\xa6\xe1\xff\x30\xd5\x0c\x3f\xc6\xa8\x7b\x70\x49\x34\xeb\x46\xa1\x18\x22\xe7\x89\x7d\xb4\x86\x7d\x2a\xd7\x4a\x8d\xa7\x4c\xdd\xd9\xee\xb1\x6f\xd2\xee\xe0\x4c\xb7\x58\x9f\x71\x88\x2d\x6a\x1e\xd7\x7d\xa0\xf9\x57\x34\x96\x50\x48\xab\xeb\x00\x85\xc8\xeb\xb6\x74\x02\x00\x00")
+
+func migrations66_history_type_indexesSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations66_history_type_indexesSql,
+		"migrations/66_history_type_indexes.sql",
+	)
+}
+
+func migrations66_history_type_indexesSql() (*asset, error) {
+	bytes, err := migrations66_history_type_indexesSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/66_history_type_indexes.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xea, 0x7b, 0xe7, 0xda, 0x83, 0xd7, 0x27, 0xad, 0xa9, 0x07, 0xb1, 0xf2, 0xd9, 0x82, 0x9f, 0x2b, 0x04, 0x58, 0x51, 0x3d, 0xaa, 0x2b, 0x81, 0x7c, 0x04, 0xb3, 0xba, 0xf0, 0x53, 0x2e, 0xea, 0x3c}}
+	return a, nil
+}
+
+var _migrations67_history_transactions_memo_indexSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xd2\xd5\x55\xd0\xce\xcd\x4c\x2f\x4a\x2c\x49\x55\x08\x2d\xe0\xe2\x72\x0e\x72\x75\x0c\x71\x55\xf0\xf4\x73\x71\x8d\x50\xc8\xcc\x4b\x49\xad\x88\xcf\xc8\x2c\x2e\xc9\x2f\xaa\x8c\x2f\x29\x4a\xcc\x2b\x4e\x4c\x2e\xc9\xcc\xcf\x2b\x8e\xcf\xcf\x8b\xcf\x4d\xcd\xcd\x57\xf0\xf7\x53\xc0\x26\xaf\x10\x1a\xec\xe9\xe7\xae\x90\x54\x52\x94\x9a\xaa\xa0\x01\x52\xa9\xa3\x00\x22\xe3\x4b\x2a\x0b\x52\x35\x15\xc2\x3d\x5c\x83\x5c\xc1\x02\x0a\x9e\xc1\x0a\x7e\xfe\x21\x0a\x7e\xa1\x3e\x3e\xd6\x5c\x5c\xc8\xce\x71\xc9\x2f\xcf\xe3\xe2\x72\x09\xf2\x0f\x20\xda\x39\xd6\x5c\x80\x01\x00\x4c\x01\xee\x42\xd0\x00\x00\x00")
+
+func migrations67_history_transactions_memo_indexSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations67_history_transactions_memo_indexSql,
+		"migrations/67_history_transactions_memo_index.sql",
+	)
+}
+
+func migrations67_history_transactions_memo_indexSql() (*asset, error) {
+	bytes, err := migrations67_history_transactions_memo_indexSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/67_history_transactions_memo_index.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x36, 0x11, 0x4c, 0x68, 0x57, 0x02, 0x28, 0xbc, 0xd6, 0x30, 0x43, 0xb8, 0x85, 0x4a, 0xb2, 0x57, 0xf6, 0xb4, 0x84, 0xc0, 0xe4, 0xaa, 0x02, 0x5b, 0xb1, 0x33, 0x86, 0x3f, 0xd2, 0x34, 0x20, 0x18}}
+	return a, nil
+}
+
+var _migrations68_api_keysSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa4\x92\x4f\x6f\xd3\x40\x10\xc5\xef\xfb\x29\xde\xad\x89\x48\xa4\x0a\x41\x2f\x3d\x05\x62\xa4\x8a\x90\x94\x28\x16\xea\xc9\x9a\xec\x4e\xbd\x4b\xe2\x5d\x77\x67\xac\x34\x20\xbe\x3b\xc2\xf9\x53\x20\x07\x0e\xd8\x97\xd5\xe8\xbd\xdf\x7b\x23\xcd\x78\x8c\x57\x4d\xa8\x33\x29\xa3\x6c\x8d\x79\xbf\x2c\x26\xab\x02\xab\xc9\xbb\x59\x01\x6a\x43\xb5\xe1\xbd\x60\x60\x00\x20\x38\xfc\xf9\xad\x43\x2d\x9c\x03\x6d\x71\xbf\xbc\xfb\x34\x59\x3e\xe0\x63\xf1\x30\xea\xb5\x91\x1a\x3e\xc9\xfa\x5f\xf9\x59\x31\x5f\xac\x30\x2f\x67\xb3\x83\x66\x3c\x86\xe7\x67\x70\xb4\xc9\xb1\x83\x78\x7a\xfd\xf6\x06\x9e\xc4\x23\x3d\x42\x3d\x63\xc3\xfb\xd1\xe9\x81\xa0\xc2\xdb\x47\x04\x41\x4c\x0a\xd1\x94\xd9\xf5\x9c\x0d\xef\xab\xde\x75\xca\xb2\x9e\x32\x59\xe5\x3c\xb8\x79\x33\x3c\x87\xa2\x9c\xdf\x7d\x2e\x8b\x73\x76\xe6\xa7\x8e\x45\xf1\xd4\x25\x25\x19\xe1\xfa\x17\xba\x8b\xdb\xd0\x04\x3d\x92\x5b\xce\x95\xb0\x4d\xd1\x55\xfd\x18\x21\x2a\xd7\x9c\x5f\x98\xd3\xe2\xc3\xa4\x9c\xad\x70\x3d\x3a\x1b\x7c\xea\xf2\x51\x8e\x7f\x1a\x5e\x7a\x08\x6c\xea\xa2\xb2\x03\xd5\x14\xa2\x68\xbf\xf8\xa1\x1c\xd6\x7b\xe4\xd4\x29\xc3\x6e\x49\xa4\x77\xee\x38\xd4\x5e\xe5\xb4\x33\xf0\x55\x52\x5c\x5f\x06\x5d\x7d\xff\x71\x75\x28\x67\x33\x93\xb2\xab\x48\x8f\x0e\x0d\x0d\x8b\x52\xd3\x62\x17\xd4\xa7\x4e\xfb\x09\xbe\xa5\xc8\x97\x9c\xf9\xe2\xcb\x60\x78\x00\x75\xad\xfb\x5f\x90\x19\xde\x1a\xf3\xfb\xf1\x4d\xd3\x2e\x1a\x33\x5d\x2e\xee\xff\x3e\x3e\x4b\x62\xc9\xf1\xad\xf9\x39\x00\x7b\xbb\x33\xbb\xac\x02\x00\x00")
+
+func migrations68_api_keysSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations68_api_keysSql,
+		"migrations/68_api_keys.sql",
+	)
+}
+
+func migrations68_api_keysSql() (*asset, error) {
+	bytes, err := migrations68_api_keysSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/68_api_keys.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xcb, 0x8c, 0x36, 0xc5, 0xae, 0x46, 0xaf, 0x68, 0x77, 0xf9, 0x56, 0x9a, 0x50, 0x92, 0xd8, 0x71, 0xdc, 0x78, 0xff, 0x75, 0xea, 0x8d, 0x6d, 0x13, 0x83, 0x55, 0xd1, 0x83, 0x93, 0x4e, 0x23, 0xd2}}
+	return a, nil
+}
+
+var _migrations69_history_liquidity_pool_snapshotsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x9d\x93\x5f\x4f\xc2\x30\x10\xc0\xdf\xfb\x29\xee\x11\x23\xfb\x04\x3e\xa1\x9b\x86\x64\x19\x8a\x5b\xe2\x5b\xd3\xd1\x1b\x6b\x1c\xed\x6c\x0f\x84\x6f\x6f\xe9\x10\x06\x12\x50\xef\xa9\x7f\x7e\x77\xfd\xe5\x72\x8d\x22\xb8\x5d\xa8\xb9\x15\x84\x50\xb4\x8c\x3d\x4c\x93\x51\x9e\x40\x3e\xba\x4f\x13\xa8\x95\x23\x63\x37\xbc\x51\x1f\x4b\x25\x15\x6d\x78\x6b\x4c\xc3\x9d\x16\xad\xab\x0d\x39\x18\x30\xf0\xd1\xa0\x9c\xa3\xe5\x64\x94\x84\x2e\x4a\x35\x57\x9a\x20\x9b\xe4\x90\x15\x69\x3a\xec\xb0\xe3\x2a\x1e\x26\x5c\x9f\x42\x51\x04\x16\x1d\xda\x15\x82\x70\x0e\xc9\x0d\xe1\x1d\x5b\x02\x51\x11\x5a\xa0\x1a\x61\x9b\x0c\xca\x79\x6c\x61\x56\x28\xa1\xb2\x66\x71\x52\xdb\x85\x52\x21\x9f\x0b\x38\xc4\x99\xf7\x3a\xa8\xbc\x0c\x1d\xa4\xbc\x8e\xab\x85\xdf\x80\xd0\xde\xdf\x2e\x1d\xf9\xb7\xf5\x76\x4f\xc1\x0e\xfd\xb1\xa9\xc2\xb2\x6b\x4b\x28\xb0\xcb\xee\xc9\x9c\xed\xd0\x37\x56\x5e\xc6\x82\x01\x9f\x99\xa5\xbf\xb9\x80\x05\xbb\xad\xdc\x1e\x3d\x8b\x49\x6c\x90\x50\xf6\x3a\x50\xfa\x16\xa2\xd0\x7b\x0e\xe2\xe4\x71\x54\xa4\x39\x54\xa2\x71\xc8\x6e\xee\xf6\x63\x52\x64\xe3\x97\x22\x81\x71\x16\x27\x6f\xa0\xb4\xc4\x35\xbf\x36\x33\xdc\xe8\xee\xa4\xd7\x9e\x49\x76\x7d\xd4\x8a\xd7\x71\xf6\x04\x25\x59\x44\x18\xfc\x18\xa5\x61\x7f\x08\xbd\xe0\xce\xef\xaf\x62\xbd\x22\xff\x14\x3b\xb2\x60\x51\xef\x77\xc5\xe6\x53\x33\x16\x4f\x27\xcf\xbf\xfd\x5d\x33\xe1\x66\x42\xe2\x1d\xfb\x02\xc6\x2e\xde\x29\xa5\x03\x00\x00")
+
+func migrations69_history_liquidity_pool_snapshotsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations69_history_liquidity_pool_snapshotsSql,
+		"migrations/69_history_liquidity_pool_snapshots.sql",
+	)
+}
+
+func migrations69_history_liquidity_pool_snapshotsSql() (*asset, error) {
+	bytes, err := migrations69_history_liquidity_pool_snapshotsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/69_history_liquidity_pool_snapshots.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xf3, 0x0a, 0xd0, 0x0e, 0xcb, 0xc7, 0xec, 0x2a, 0xe2, 0xbc, 0x82, 0x79, 0xa0, 0x3b, 0x59, 0xba, 0x02, 0x88, 0x46, 0xf1, 0xcf, 0xdb, 0x8c, 0xfb, 0xf3, 0xe1, 0x70, 0x6c, 0xd8, 0x72, 0x69, 0x4d}}
+	return a, nil
+}
+
 var _migrations6_create_assets_tableSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\x90\x3d\x4f\xc3\x30\x18\x84\x77\xff\x8a\x1b\x1d\x91\x0e\x20\xe8\x92\xc9\x34\x16\x58\x18\xa7\xb8\x31\xa2\x53\xe5\x26\x16\x78\x80\x54\xb6\x11\xca\xbf\x47\xaa\x28\xf9\x50\xe6\x7b\xf4\xbc\xef\xdd\x6a\x85\xab\x4f\xff\x1e\x6c\x72\x30\x27\xb2\xd1\x9c\xd5\x1c\x35\xbb\x97\x1c\x1f\x3e\xa6\x2e\xf4\x07\x1b\xa3\x4b\x11\x94\x00\x80\x6f\xb1\xe3\x5a\x30\x89\xad\x16\xcf\x4c\xef\xf1\xc4\xf7\xc8\xcf\xd9\x19\x3c\xa4\xfe\xe4\xf0\xca\xf4\xe6\x91\x69\xba\xbe\xcd\xa0\xaa\x1a\xca\x48\x39\x86\x9a\xae\x1d\xa0\xeb\x9b\x65\xc8\xc7\xf8\xed\xc2\x3f\x76\xb7\x9e\x63\x46\x89\x17\xc3\xe9\xa0\xcc\x47\x3f\xe4\x13\x4b\x46\xb2\x82\x5c\xfa\x09\x55\xf2\xb7\xbf\xf8\xd8\x5f\xee\x54\x6a\x5e\xd9\xec\x84\x7a\xc0\x31\x05\xe7\x40\x27\xb6\x82\x90\xf1\x74\x65\xf7\xf3\x45\x4a\x5d\x6d\x97\xa7\x6b\x6c\x6c\x6c\xeb\x8a\xdf\x00\x00\x00\xff\xff\xfb\x53\x3e\x81\x6e\x01\x00\x00")
 
 func migrations6_create_assets_tableSqlBytes() ([]byte, error) {
@@ -1332,6 +1481,46 @@ func migrations6_create_assets_tableSql() (*asset, error) {
 	return a, nil
 }
 
+var _migrations70_history_effects_sponsor_indexesSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xbc\x92\x4f\x4b\x03\x31\x10\xc5\xef\xf9\x14\x8f\x5e\xda\x62\x57\x3c\x88\x1e\x0a\x85\xea\x06\xed\x65\x2b\xfd\x43\xbd\x2d\x71\x33\xdb\x06\xb6\x99\x65\x12\xa9\xfd\xf6\x52\xba\xca\xd6\x8b\xa5\x82\xd7\xcc\xfb\xbd\xfc\x26\x24\x49\x70\xb5\x75\x6b\x31\x91\xb0\xac\x95\x4a\x12\xcc\x6b\xf6\x81\x25\x6c\x5c\x0d\x2a\x4b\x2a\x62\x80\x11\x82\x50\xc1\x62\xc9\xa2\x64\x41\xdc\x10\xc2\x31\x48\x16\xa6\x28\xf8\xdd\xc7\xc1\xe1\x38\x10\x9c\xb7\xf4\x41\xe1\x50\x66\xaa\x8a\x77\xa8\xd8\x58\xe7\xd7\x6d\xea\xa4\x9e\x4b\x98\xaf\xc1\xb5\x7a\x9c\xe9\xf1\x42\x63\x92\xa5\xfa\xf5\xd8\x95\x6f\x5c\x88\x2c\xfb\xbc\x01\x72\xf6\x79\x13\xc7\x34\xc3\x8f\x29\x96\xf3\x49\xf6\x84\xb7\x28\x44\xe8\xf5\x2c\x45\xe3\xaa\x80\x64\x34\x42\xb7\xa1\xba\xfd\xc1\x37\xc5\x35\x89\x89\x8e\x7d\xee\xec\x00\x9d\xc3\x8e\xd2\xe9\x63\xf5\xac\x67\x1a\x71\x5f\x13\x1e\xf4\x62\xa5\x75\x86\xbb\x1b\x8c\xb3\x14\xf7\xb7\xc3\x33\x1d\x4b\x96\x2d\xc9\x85\xaa\xa7\xf0\x3f\x19\x7b\xda\x5d\xa8\xdb\x22\xff\xec\xaa\xda\xdf\x32\xe5\x9d\x57\x2a\x9d\x4d\x5f\x7e\x93\x6f\xae\x1f\x9e\x15\x3e\x7d\xde\xf3\x98\xd6\x8e\x43\xf5\x39\x00\xef\x9c\xbf\x04\x3b\x03\x00\x00")
+
+func migrations70_history_effects_sponsor_indexesSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations70_history_effects_sponsor_indexesSql,
+		"migrations/70_history_effects_sponsor_indexes.sql",
+	)
+}
+
+func migrations70_history_effects_sponsor_indexesSql() (*asset, error) {
+	bytes, err := migrations70_history_effects_sponsor_indexesSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/70_history_effects_sponsor_indexes.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x70, 0xbc, 0xb6, 0x7c, 0xeb, 0xc9, 0x2a, 0x91, 0xce, 0xd7, 0xfa, 0xcd, 0x6b, 0xca, 0x1b, 0x25, 0x36, 0x7c, 0x53, 0x68, 0x4a, 0x58, 0x14, 0xb5, 0xdd, 0x32, 0xa8, 0xb3, 0xb2, 0xcc, 0x52, 0xd9}}
+	return a, nil
+}
+
+var _migrations71_history_order_book_snapshotsSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xac\x94\xdd\x8e\xda\x3e\x10\xc5\xef\xf3\x14\x73\x19\xf4\x27\x4f\xc0\xd5\xfe\x4b\x54\x21\xa1\xd0\x6e\x89\xd4\x3b\xcb\x1f\x53\xe2\x92\xd8\x91\x67\x42\x77\xdf\xbe\x4a\x0c\x09\xbb\xcb\xf2\x21\x95\x2b\x2c\xff\x3c\x3e\x67\xe6\x38\x59\x06\xff\x35\x76\x17\x24\x23\x94\x6d\x92\x7c\x79\xce\x9f\xb6\x39\x6c\x9f\xfe\x5f\xe7\x50\x59\x62\x1f\x5e\x85\x0f\x06\x83\x50\xde\xef\x05\x39\xd9\x52\xe5\x99\x20\x4d\x00\x00\x6a\x34\x3b\x0c\x82\xbd\x35\x00\xa0\xec\xce\x3a\x86\x62\xb3\x85\xa2\x5c\xaf\xe7\x03\xa2\x24\xa1\x90\x44\xc8\xfd\x8a\xf1\xe5\x3d\xa0\x7d\xe7\x18\xc3\x91\xb9\x00\x64\x19\x28\x24\x86\x36\x58\x8d\x50\xe3\x01\x6b\x02\xc2\xba\xb6\x6e\x07\x5c\xe1\x70\x05\xc4\xe3\xa9\xa4\x3d\xcd\x40\x3a\x33\xec\x1c\x6b\xc7\xcd\x53\xb1\x54\x59\xd3\x33\x3c\x20\xe8\x0c\xf8\x5f\xc3\xdf\xe8\x66\xc0\xfa\x32\x30\xfe\x7e\x93\x77\xea\x9d\xaa\xbe\xc8\x75\xa2\xb1\x46\x44\xcd\xfd\xca\xf8\x4e\xd5\x08\x6d\x40\x6d\xc9\x7a\x37\x7a\xd3\x5d\xd3\xd5\x92\xed\x01\xc1\x60\xcb\x55\x2f\x2c\x1e\x53\xd2\x19\x02\x19\x7c\x77\xb4\xd3\x58\x13\xbb\x30\x9c\x8d\xf4\x65\x05\xc9\x6c\x31\x4e\xb3\x2c\x56\xdf\xcb\x1c\x56\xc5\x32\xff\x09\xd6\x19\x7c\x11\xd7\x46\x2b\xbc\x13\xad\xb4\x41\x9c\xb5\x63\x53\x5c\x4f\x43\xf9\x63\x55\x7c\x05\xc5\x01\x11\xd2\x69\xe2\xf3\xb7\xc3\x9d\x9f\xe7\x65\xb6\x38\xe9\x7b\x44\xd8\x59\x81\xc7\x85\xbd\xbd\x3d\xc9\x32\xa8\x25\x31\x9c\xe8\x3e\x06\x78\xc0\xf0\x0a\xbd\x7d\xb0\xee\xb8\x52\x9d\xde\x23\x9f\x42\x12\xbb\xae\x2b\x19\x18\x02\x92\xaf\x3b\xb6\xde\xd1\xcd\xa7\x13\xcf\xa5\xff\xe8\x4d\x4c\x37\xc3\x67\x0f\x8f\x6d\x83\xc4\xb2\x69\xe1\x53\xe4\xac\x21\x1f\x91\xc7\x23\x34\x58\x1c\xe3\x13\xdb\x76\x65\x4a\x03\x7e\x77\x74\x26\xc7\xf3\xc9\xda\xdd\x21\x1a\xa5\xdd\x17\xa0\x0b\xd2\x3e\x86\x67\xfc\x72\x2e\xfd\x1f\x97\x24\xcb\xe7\xcd\xb7\x5b\xe3\xd7\x92\xb4\x34\xb8\xb8\x01\x4f\xf9\xd5\x92\xb4\x34\xb8\x48\xfe\x0e\x00\x7e\x3c\x66\x0b\xaa\x05\x00\x00")
+
+func migrations71_history_order_book_snapshotsSqlBytes() ([]byte, error) {
+	return bindataRead(
+		_migrations71_history_order_book_snapshotsSql,
+		"migrations/71_history_order_book_snapshots.sql",
+	)
+}
+
+func migrations71_history_order_book_snapshotsSql() (*asset, error) {
+	bytes, err := migrations71_history_order_book_snapshotsSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "migrations/71_history_order_book_snapshots.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x0a, 0xcc, 0x29, 0x4c, 0x77, 0x62, 0xec, 0x77, 0xe9, 0x3b, 0xd8, 0xa0, 0x17, 0x91, 0x54, 0xc0, 0xa8, 0x81, 0xe1, 0xe4, 0xad, 0x73, 0x80, 0xec, 0x37, 0xa2, 0xfb, 0x81, 0xe9, 0xd4, 0x66, 0x18}}
+	return a, nil
+}
+
 var _migrations7_modify_trades_tableSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xc4\x54\x4d\x8f\xda\x30\x14\xbc\xe7\x57\x3c\xed\x29\x51\xc3\xaa\xad\xda\xbd\x6c\x55\x09\x58\x97\x46\x65\xc3\x36\x04\xa9\xb7\xc8\x89\xdf\x06\xab\xc1\x8e\x6c\xa7\x88\x7f\x5f\x05\x08\xcd\x27\xb0\xbb\x87\x5e\x93\x99\x79\x6f\xec\xf1\x8c\x46\xf0\x6e\xc3\x53\x45\x0d\xc2\x2a\xb7\x46\x23\x60\x4a\xe6\x60\xd6\x08\x32\x63\x60\x14\x65\xa8\xc1\xd0\x38\xc3\x5b\xc8\x0b\x03\x14\x04\x6e\x41\x0a\x04\x2e\x20\xcf\x68\x82\xd6\x43\xb0\x78\x82\x70\x3c\x99\x13\x58\x73\x6d\xa4\xda\x45\x07\xde\xbd\x35\x0d\xc8\x38\x24\xbd\x3f\xc1\xb6\x00\xe0\xf4\x51\xe6\xa8\xa8\xe1\x52\x44\x9c\xc1\xc4\x9b\x79\x7e\x08\xfe\x22\x04\x7f\x35\x9f\xbb\x7b\xe4\x8d\x54\x0c\xd5\x0d\x78\x7e\x48\x66\x24\x68\xfd\xcd\x90\xa5\xa8\xa2\x24\x93\x1a\x59\x44\x0d\x84\xde\x23\x59\x86\xe3\xc7\xa7\x16\x50\x3e\x3f\xa3\x1a\x1c\x12\x53\x8d\x11\x4d\x12\x59\x08\xd3\x03\x82\x80\x7c\x23\x01\xf1\xa7\x64\x79\xda\xfc\x88\xd6\x36\x67\x4e\x5d\x44\x6b\xbc\x5a\xa2\xc4\x76\x04\x36\xa5\x6c\x87\x3e\xfd\x4e\xa6\x3f\xc0\xae\x43\xbe\xc2\xfb\x23\x71\xbf\x09\xaa\x37\x3b\x38\xe9\xbc\xc1\xc4\x49\xe3\xac\x8f\x16\xea\x9f\x95\xbd\x41\xae\x23\x8d\x59\x86\x0a\x26\x8b\xc5\x9c\x8c\xfd\xc3\xbf\x3d\xd7\x6e\x1e\xf3\x97\xce\xd2\x8e\xe5\xdc\x5b\x55\x04\x57\xbe\xf7\x73\x45\xc0\xf3\x1f\xc8\x2f\x58\x1b\xc5\xa2\x9c\x33\x58\xf8\xed\x54\xae\x96\x9e\x3f\x83\xd8\x28\x44\xb0\xfb\xc2\xe9\x56\x41\x74\x4e\xf1\xae\x8b\x52\xae\x22\xc3\x37\x18\x65\x52\xfe\x2e\xf2\xc1\x09\x93\x30\x20\xa4\x69\xc1\xed\x38\x70\x3b\xb1\xee\x1d\x5a\xd1\xae\x1a\xd9\x39\xa5\x3e\xc5\xeb\x1d\x5c\xb5\x60\xbc\x8b\xf6\xcf\xee\xd2\x79\x57\x6f\xb3\xbc\x37\xab\x5e\x4d\x0f\x72\x2b\x1a\xe5\x24\x70\x8b\xaa\xea\x25\x85\x5c\x68\x53\xe2\xaa\xde\x92\x02\x6f\x87\x7b\x09\x12\xaa\x13\xca\xf0\xd5\xfd\x14\xf3\x94\x0b\x33\xd0\x4f\x5c\x18\x4c\x51\x0d\xd5\x4e\x2f\xf7\x10\xf2\xc1\xdf\x71\xb1\x3b\x47\x96\x19\x3b\x5e\xa7\xd9\xe5\x08\xc9\x9a\x2a\x9a\x18\x54\xf0\x87\xaa\x1d\x17\xa9\x7d\xf7\xc9\x19\xe6\x70\xad\x0b\x54\x3d\xac\xcf\x77\x67\x58\x89\x64\x7d\x93\x3e\x7c\xec\xe7\x1c\x5e\x77\x6b\xfd\xaa\x03\xea\x90\x5a\x01\xc8\x22\x5d\x9b\x97\x1a\x6b\xb0\x5e\x60\xad\xc1\xbb\xda\x5c\xc5\x3a\x6b\xaf\x09\x2a\x0d\xfe\x87\x62\x7a\xc5\x13\x6c\x8b\x94\x1a\xe5\x55\x5d\x92\x68\xe5\xd1\x6d\xc7\xc6\xed\xa6\x6f\x60\xda\xe1\xe4\x2e\xcd\xeb\x04\xc5\xed\xde\xa6\xdb\x17\x0c\xe7\xfe\x6f\x00\x00\x00\xff\xff\x2a\xff\xe8\x4a\xff\x08\x00\x00")
 
 func migrations7_modify_trades_tableSqlBytes() ([]byte, error) {
@@ -1562,7 +1751,16 @@ var _bindata = map[string]func() (*asset, error){
 	"migrations/60_add_asset_id_indexes.sql":                             migrations60_add_asset_id_indexesSql,
 	"migrations/61_trust_lines_by_account_type_code_issuer.sql":          migrations61_trust_lines_by_account_type_code_issuerSql,
 	"migrations/62_claimable_balance_claimants.sql":                      migrations62_claimable_balance_claimantsSql,
+	"migrations/63_webhook_subscriptions.sql":                            migrations63_webhook_subscriptionsSql,
+	"migrations/64_history_account_balances.sql":                         migrations64_history_account_balancesSql,
+	"migrations/65_trust_lines_by_type_code_issuer_balance.sql":          migrations65_trust_lines_by_type_code_issuer_balanceSql,
+	"migrations/66_history_type_indexes.sql":                             migrations66_history_type_indexesSql,
+	"migrations/67_history_transactions_memo_index.sql":                  migrations67_history_transactions_memo_indexSql,
+	"migrations/68_api_keys.sql":                                         migrations68_api_keysSql,
+	"migrations/69_history_liquidity_pool_snapshots.sql":                 migrations69_history_liquidity_pool_snapshotsSql,
 	"migrations/6_create_assets_table.sql":                               migrations6_create_assets_tableSql,
+	"migrations/70_history_effects_sponsor_indexes.sql":                  migrations70_history_effects_sponsor_indexesSql,
+	"migrations/71_history_order_book_snapshots.sql":                     migrations71_history_order_book_snapshotsSql,
 	"migrations/7_modify_trades_table.sql":                               migrations7_modify_trades_tableSql,
 	"migrations/8_add_aggregators.sql":                                   migrations8_add_aggregatorsSql,
 	"migrations/8_create_asset_stats_table.sql":                          migrations8_create_asset_stats_tableSql,
@@ -1672,7 +1870,16 @@ var _bintree = &bintree{nil, map[string]*bintree{
 		"60_add_asset_id_indexes.sql":                             &bintree{migrations60_add_asset_id_indexesSql, map[string]*bintree{}},
 		"61_trust_lines_by_account_type_code_issuer.sql":          &bintree{migrations61_trust_lines_by_account_type_code_issuerSql, map[string]*bintree{}},
 		"62_claimable_balance_claimants.sql":                      &bintree{migrations62_claimable_balance_claimantsSql, map[string]*bintree{}},
+		"63_webhook_subscriptions.sql":                            &bintree{migrations63_webhook_subscriptionsSql, map[string]*bintree{}},
+		"64_history_account_balances.sql":                         &bintree{migrations64_history_account_balancesSql, map[string]*bintree{}},
+		"65_trust_lines_by_type_code_issuer_balance.sql":          &bintree{migrations65_trust_lines_by_type_code_issuer_balanceSql, map[string]*bintree{}},
+		"66_history_type_indexes.sql":                             &bintree{migrations66_history_type_indexesSql, map[string]*bintree{}},
+		"67_history_transactions_memo_index.sql":                  &bintree{migrations67_history_transactions_memo_indexSql, map[string]*bintree{}},
+		"68_api_keys.sql":                                         &bintree{migrations68_api_keysSql, map[string]*bintree{}},
+		"69_history_liquidity_pool_snapshots.sql":                 &bintree{migrations69_history_liquidity_pool_snapshotsSql, map[string]*bintree{}},
 		"6_create_assets_table.sql":                               &bintree{migrations6_create_assets_tableSql, map[string]*bintree{}},
+		"70_history_effects_sponsor_indexes.sql":                  &bintree{migrations70_history_effects_sponsor_indexesSql, map[string]*bintree{}},
+		"71_history_order_book_snapshots.sql":                     &bintree{migrations71_history_order_book_snapshotsSql, map[string]*bintree{}},
 		"7_modify_trades_table.sql":                               &bintree{migrations7_modify_trades_tableSql, map[string]*bintree{}},
 		"8_add_aggregators.sql":                                   &bintree{migrations8_add_aggregatorsSql, map[string]*bintree{}},
 		"8_create_asset_stats_table.sql":                          &bintree{migrations8_create_asset_stats_tableSql, map[string]*bintree{}},
@@ -47,68 +47,91 @@ var Migrations migrate.MigrationSource = &migrate.AssetMigrationSource{
 // upward back to the current version at the start of the process. If count is
 // 0, a count of 1 will be assumed.
 func Migrate(db *sql.DB, dir MigrateDir, count int) (int, error) {
+	return migrateSource(db, Migrations, dir, count)
+}
+
+func migrateSource(db *sql.DB, source migrate.MigrationSource, dir MigrateDir, count int) (int, error) {
 	if dir == MigrateUp {
-		// The code below locks ingestion to apply DB migrations. This works
-		// for MigrateUp migrations only because it's possible that MigrateDown
-		// can remove `key_value_store` table and it will deadlock the process.
-		txConn, err := db.Conn(context.Background())
+		// This works for MigrateUp migrations only because it's possible that
+		// MigrateDown can remove `key_value_store` table and it will deadlock
+		// the process.
+		unlock, err := lockIngestion(db)
 		if err != nil {
 			return 0, err
 		}
+		defer unlock()
+	}
+	return execMigrations(db, source, dir, count)
+}
 
-		defer txConn.Close()
+// lockIngestion locks ingestion to apply DB migrations, ingestion is unlocked
+// when the returned function is called.
+func lockIngestion(db *sql.DB) (func(), error) {
+	txConn, err := db.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
 
-		tx, err := txConn.BeginTx(context.Background(), nil)
-		if err != nil {
-			return 0, err
-		}
+	tx, err := txConn.BeginTx(context.Background(), nil)
+	if err != nil {
+		txConn.Close()
+		return nil, err
+	}
 
-		// Unlock ingestion when done. DB migrations run in a separate DB connection
-		// so no need to Commit().
-		defer tx.Rollback()
+	// Unlock ingestion when done. DB migrations run in a separate DB connection
+	// so no need to Commit().
+	unlock := func() {
+		tx.Rollback()
+		txConn.Close()
+	}
 
-		// Check if table exists
-		row := tx.QueryRow(`select exists (
-			select from information_schema.tables where table_schema = 'public' and table_name = 'key_value_store'
-		)`)
-		err = row.Err()
-		if err != nil {
-			return 0, err
-		}
+	// Check if table exists
+	row := tx.QueryRow(`select exists (
+		select from information_schema.tables where table_schema = 'public' and table_name = 'key_value_store'
+	)`)
+	err = row.Err()
+	if err != nil {
+		unlock()
+		return nil, err
+	}
 
-		var tableExists bool
-		err = row.Scan(&tableExists)
-		if err != nil {
-			return 0, err
-		}
+	var tableExists bool
+	err = row.Scan(&tableExists)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
 
-		if tableExists {
-			// Lock ingestion
-			row := tx.QueryRow("select value from key_value_store where key = 'exp_ingest_last_ledger' for update")
-			err = row.Err()
-			if err != nil {
-				return 0, err
-			}
+	if tableExists {
+		// Lock ingestion
+		row := tx.QueryRow("select value from key_value_store where key = 'exp_ingest_last_ledger' for update")
+		err = row.Err()
+		if err != nil {
+			unlock()
+			return nil, err
 		}
 	}
+	return unlock, nil
+}
 
+func execMigrations(db *sql.DB, source migrate.MigrationSource, dir MigrateDir, count int) (int, error) {
 	switch dir {
 	case MigrateUp:
-		return migrate.ExecMax(db, "postgres", Migrations, migrate.Up, count)
+		return migrate.ExecMax(db, "postgres", source, migrate.Up, count)
 	case MigrateDown:
-		return migrate.ExecMax(db, "postgres", Migrations, migrate.Down, count)
+		return migrate.ExecMax(db, "postgres", source, migrate.Down, count)
 	case MigrateRedo:
 
 		if count == 0 {
 			count = 1
 		}
 
-		down, err := migrate.ExecMax(db, "postgres", Migrations, migrate.Down, count)
+		down, err := migrate.ExecMax(db, "postgres", source, migrate.Down, count)
 		if err != nil {
 			return down, err
 		}
 
-		return migrate.ExecMax(db, "postgres", Migrations, migrate.Up, down)
+		return migrate.ExecMax(db, "postgres", source, migrate.Up, down)
 	default:
 		return 0, errors.New("Invalid migration direction")
 	}
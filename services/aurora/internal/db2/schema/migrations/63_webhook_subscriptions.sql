@@ -0,0 +1,21 @@
+-- +migrate Up
+
+CREATE TABLE webhook_subscriptions (
+    id              bigserial PRIMARY KEY,
+    url             text NOT NULL,
+    secret          text NOT NULL,
+    account_id      character varying(56),
+    asset           text,
+    operation_types integer[],
+    -- the paging token of the last operation delivered or skipped
+    cursor          bigint NOT NULL,
+    failures        integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamp without time zone,
+    last_error      text,
+    created_at      timestamp without time zone NOT NULL DEFAULT NOW(),
+    updated_at      timestamp without time zone NOT NULL DEFAULT NOW()
+);
+
+-- +migrate Down
+
+DROP TABLE webhook_subscriptions cascade;
@@ -0,0 +1,22 @@
+-- +migrate Up
+
+CREATE TABLE history_account_balances (
+    ledger_toid      bigint NOT NULL,
+    account_id       character varying(56) NOT NULL,
+    asset_type       integer NOT NULL,
+    asset_code       character varying(12) NOT NULL DEFAULT '',
+    asset_issuer     character varying(56) NOT NULL DEFAULT '',
+    -- balance at the end of the ledger, NULL if the entry was removed
+    balance          bigint,
+    -- balance before the ledger was applied, NULL if the entry did not exist
+    previous_balance bigint
+);
+
+CREATE UNIQUE INDEX index_history_account_balances_on_account_asset_ledger
+    ON history_account_balances USING btree (account_id, asset_type, asset_code, asset_issuer, ledger_toid);
+CREATE INDEX index_history_account_balances_on_ledger_toid
+    ON history_account_balances USING btree (ledger_toid);
+
+-- +migrate Down
+
+DROP TABLE history_account_balances cascade;
@@ -0,0 +1,7 @@
+-- +migrate Up
+
+CREATE INDEX "trust_lines_by_type_code_issuer_balance" ON trust_lines USING btree (asset_type, asset_code, asset_issuer, balance, account_id);
+
+-- +migrate Down
+
+DROP INDEX "trust_lines_by_type_code_issuer_balance";
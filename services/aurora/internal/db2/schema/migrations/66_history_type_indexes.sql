@@ -0,0 +1,15 @@
+-- +migrate Up
+
+CREATE INDEX index_history_operations_on_type_and_id ON history_operations USING btree (type, id);
+DROP INDEX index_history_operations_on_type;
+
+CREATE INDEX index_history_effects_on_type_and_operation ON history_effects USING btree (type, history_operation_id, "order");
+DROP INDEX index_history_effects_on_type;
+
+-- +migrate Down
+
+CREATE INDEX index_history_effects_on_type ON history_effects USING btree (type);
+DROP INDEX index_history_effects_on_type_and_operation;
+
+CREATE INDEX index_history_operations_on_type ON history_operations USING btree (type);
+DROP INDEX index_history_operations_on_type_and_id;
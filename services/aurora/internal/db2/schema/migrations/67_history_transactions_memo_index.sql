@@ -0,0 +1,7 @@
+-- +migrate Up
+
+CREATE INDEX index_history_transactions_on_memo ON history_transactions USING btree (memo, memo_type) WHERE memo IS NOT NULL;
+
+-- +migrate Down
+
+DROP INDEX index_history_transactions_on_memo;
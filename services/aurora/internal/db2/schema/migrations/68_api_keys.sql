@@ -0,0 +1,19 @@
+-- +migrate Up
+
+CREATE TABLE api_keys (
+    id               bigserial PRIMARY KEY,
+    name             text NOT NULL,
+    -- hex encoded sha256 hash of the key, the key itself is not stored
+    key_hash         character(64) NOT NULL UNIQUE,
+    -- request quotas, 0 is unlimited
+    per_second_limit integer NOT NULL DEFAULT 0,
+    per_hour_limit   integer NOT NULL DEFAULT 0,
+    -- requests counted against the quotas by route class
+    weights          jsonb NOT NULL DEFAULT '{}',
+    created_at       timestamp without time zone NOT NULL DEFAULT NOW(),
+    updated_at       timestamp without time zone NOT NULL DEFAULT NOW()
+);
+
+-- +migrate Down
+
+DROP TABLE api_keys cascade;
@@ -0,0 +1,24 @@
+-- +migrate Up
+
+CREATE TABLE history_liquidity_pool_snapshots (
+    ledger_toid       bigint NOT NULL,
+    liquidity_pool_id text NOT NULL,
+    -- reserve assets, kept after the pool is removed from liquidity_pools
+    asset_a           text NOT NULL,
+    asset_b           text NOT NULL,
+    -- reserves, shares and trust lines at the end of the ledger
+    reserve_a         bigint NOT NULL,
+    reserve_b         bigint NOT NULL,
+    share_count       bigint NOT NULL,
+    trustline_count   bigint NOT NULL,
+    deleted           boolean NOT NULL DEFAULT false
+);
+
+CREATE UNIQUE INDEX index_history_liquidity_pool_snapshots_on_pool_ledger
+    ON history_liquidity_pool_snapshots USING btree (liquidity_pool_id, ledger_toid);
+CREATE INDEX index_history_liquidity_pool_snapshots_on_ledger_toid
+    ON history_liquidity_pool_snapshots USING btree (ledger_toid);
+
+-- +migrate Down
+
+DROP TABLE history_liquidity_pool_snapshots cascade;
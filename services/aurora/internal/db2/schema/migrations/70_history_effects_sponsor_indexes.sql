@@ -0,0 +1,13 @@
+-- +migrate Up
+
+-- Sponsorship effects are recorded for the sponsored account, these indexes
+-- allow loading the sponsorship effects of a sponsor.
+CREATE INDEX index_history_effects_on_sponsor ON history_effects USING btree ((details ->> 'sponsor'), history_operation_id, "order") WHERE type BETWEEN 60 AND 74;
+CREATE INDEX index_history_effects_on_former_sponsor ON history_effects USING btree ((details ->> 'former_sponsor'), history_operation_id, "order") WHERE type BETWEEN 60 AND 74;
+CREATE INDEX index_history_effects_on_new_sponsor ON history_effects USING btree ((details ->> 'new_sponsor'), history_operation_id, "order") WHERE type BETWEEN 60 AND 74;
+
+-- +migrate Down
+
+DROP INDEX index_history_effects_on_sponsor;
+DROP INDEX index_history_effects_on_former_sponsor;
+DROP INDEX index_history_effects_on_new_sponsor;
@@ -0,0 +1,38 @@
+-- +migrate Up
+
+CREATE TABLE history_order_book_snapshots (
+    ledger_toid   bigint NOT NULL,
+    base_asset    text NOT NULL,
+    counter_asset text NOT NULL,
+    -- best price levels selling the base asset (asks) and the counter asset
+    -- (bids) at the end of the ledger
+    asks          jsonb NOT NULL,
+    bids          jsonb NOT NULL,
+    mid_price     double precision,
+    -- cumulative depth at price bands around the mid price
+    depth         jsonb NOT NULL
+);
+
+CREATE UNIQUE INDEX index_history_order_book_snapshots_on_pair_ledger
+    ON history_order_book_snapshots USING btree (base_asset, counter_asset, ledger_toid);
+CREATE INDEX index_history_order_book_snapshots_on_ledger_toid
+    ON history_order_book_snapshots USING btree (ledger_toid);
+
+-- last snapshot of every pair in every bucket of the depth chart resolutions
+CREATE TABLE history_order_book_depth (
+    base_asset    text NOT NULL,
+    counter_asset text NOT NULL,
+    resolution    bigint NOT NULL,
+    timestamp     bigint NOT NULL,
+    ledger_toid   bigint NOT NULL
+);
+
+CREATE UNIQUE INDEX index_history_order_book_depth_on_pair_bucket
+    ON history_order_book_depth USING btree (base_asset, counter_asset, resolution, timestamp);
+CREATE INDEX index_history_order_book_depth_on_ledger_toid
+    ON history_order_book_depth USING btree (ledger_toid);
+
+-- +migrate Down
+
+DROP TABLE history_order_book_depth cascade;
+DROP TABLE history_order_book_snapshots cascade;
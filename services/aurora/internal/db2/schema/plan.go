@@ -0,0 +1,482 @@
+package schema
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	migrate "github.com/rubenv/sql-migrate"
+
+	"github.com/hcnet/go/support/errors"
+)
+
+// StatementPlan describes a statement of a pending migration.
+type StatementPlan struct {
+	SQL string
+	// Tables lists the tables touched by the statement.
+	Tables []string
+	// Lock is the strongest table lock taken by the statement, empty if it
+	// doesn't lock existing tables.
+	Lock string
+	// Warning is set for lock-heavy statements: statements holding a lock
+	// blocking reads or writes for a time proportional to the table size.
+	Warning string
+	// Concurrent is true for CREATE INDEX statements which can safely run
+	// CONCURRENTLY, outside of the migration transaction.
+	Concurrent bool
+
+	index *indexStatement
+	raw   string
+}
+
+// MigrationPlan describes a pending migration.
+type MigrationPlan struct {
+	Id         string
+	Statements []StatementPlan
+}
+
+// TableInfo describes a table touched by pending migrations.
+type TableInfo struct {
+	Exists      bool
+	Partitioned bool
+	// EstimatedRows is the number of rows estimated by the postgres
+	// planner statistics, -1 if unknown.
+	EstimatedRows int64
+}
+
+// MigrationsPlan describes the pending "up" migrations.
+type MigrationsPlan struct {
+	Migrations []MigrationPlan
+	Tables     map[string]TableInfo
+}
+
+type indexStatement struct {
+	name  string
+	table string
+}
+
+var (
+	identifierPattern = `"?([\w.]+)"?`
+
+	leadingCommentsRegexp = regexp.MustCompile(`^(\s*(/\*(?s:.*?)\*/|--[^\n]*))*`)
+
+	createIndexRegexp = regexp.MustCompile(
+		`(?is)^\s*CREATE\s+(?:UNIQUE\s+)?INDEX\s+(CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?` +
+			identifierPattern + `\s+ON\s+(ONLY\s+)?` + identifierPattern,
+	)
+	alterTableRegexp = regexp.MustCompile(
+		`(?is)^\s*ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?` + identifierPattern,
+	)
+	createTableRegexp = regexp.MustCompile(
+		`(?is)^\s*CREATE\s+(?:UNLOGGED\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?` + identifierPattern,
+	)
+	dropTableRegexp = regexp.MustCompile(
+		`(?is)^\s*DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?(` + `"?[\w.]+"?(?:\s*,\s*"?[\w.]+"?)*` + `)`,
+	)
+	dropIndexRegexp     = regexp.MustCompile(`(?is)^\s*DROP\s+INDEX\s+(CONCURRENTLY\s+)?`)
+	updateRegexp        = regexp.MustCompile(`(?is)^\s*UPDATE\s+(?:ONLY\s+)?` + identifierPattern)
+	deleteRegexp        = regexp.MustCompile(`(?is)^\s*DELETE\s+FROM\s+(?:ONLY\s+)?` + identifierPattern)
+	insertRegexp        = regexp.MustCompile(`(?is)^\s*INSERT\s+INTO\s+` + identifierPattern)
+	truncateRegexp      = regexp.MustCompile(`(?is)^\s*TRUNCATE\s+(?:TABLE\s+)?(?:ONLY\s+)?` + identifierPattern)
+	lockTableRegexp     = regexp.MustCompile(`(?is)^\s*LOCK\s+(?:TABLE\s+)?(?:ONLY\s+)?` + identifierPattern)
+	rewriteRegexp       = regexp.MustCompile(`(?is)^\s*(CLUSTER|VACUUM\s+FULL|REINDEX)\b`)
+	reindexConcurrently = regexp.MustCompile(`(?is)^\s*REINDEX\s+(\(.*\)\s+)?\w+\s+CONCURRENTLY`)
+
+	alterColumnTypeRegexp = regexp.MustCompile(`(?is)\bALTER\s+(COLUMN\s+)?"?\w+"?\s+(SET\s+DATA\s+)?TYPE\b`)
+	setNotNullRegexp      = regexp.MustCompile(`(?is)\bSET\s+NOT\s+NULL\b`)
+	addConstraintRegexp   = regexp.MustCompile(`(?is)\bADD\s+(CONSTRAINT\s+"?\w+"?\s+)?(CHECK|FOREIGN\s+KEY|PRIMARY\s+KEY|UNIQUE|EXCLUDE)\b`)
+	notValidRegexp        = regexp.MustCompile(`(?is)\bNOT\s+VALID\b`)
+	usingIndexRegexp      = regexp.MustCompile(`(?is)\bUSING\s+INDEX\b`)
+	addColumnDefaultRegex = regexp.MustCompile(`(?is)\bADD\s+(COLUMN\s+)?.*\bDEFAULT\s+[^,]*\(`)
+	validateRegexp        = regexp.MustCompile(`(?is)^\s*ALTER\s+TABLE\s+.*\bVALIDATE\s+CONSTRAINT\b`)
+)
+
+func unquote(name string) string {
+	name = strings.Trim(name, `"`)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.ToLower(name)
+}
+
+// analyzeStatement returns the tables touched by a statement, the lock it
+// takes and whether it's lock-heavy. It relies on simple patterns matching
+// the statements used in Aurora migrations rather than a full SQL parser.
+func analyzeStatement(query string) StatementPlan {
+	statement := leadingCommentsRegexp.ReplaceAllString(query, "")
+	plan := StatementPlan{SQL: strings.TrimSpace(statement), raw: strings.TrimSpace(query)}
+
+	if m := createIndexRegexp.FindStringSubmatch(statement); m != nil {
+		plan.Tables = []string{unquote(m[4])}
+		if m[1] != "" {
+			plan.Lock = "SHARE UPDATE EXCLUSIVE"
+			return plan
+		}
+		plan.Lock = "SHARE"
+		plan.Warning = "builds an index while blocking writes"
+		if m[3] == "" {
+			plan.index = &indexStatement{name: unquote(m[2]), table: unquote(m[4])}
+		}
+		return plan
+	}
+
+	if m := dropIndexRegexp.FindStringSubmatch(statement); m != nil {
+		if m[1] != "" {
+			plan.Lock = "SHARE UPDATE EXCLUSIVE"
+		} else {
+			plan.Lock = "ACCESS EXCLUSIVE"
+		}
+		return plan
+	}
+
+	if m := alterTableRegexp.FindStringSubmatch(statement); m != nil {
+		plan.Tables = []string{unquote(m[1])}
+		plan.Lock = "ACCESS EXCLUSIVE"
+		switch {
+		case validateRegexp.MatchString(statement):
+			plan.Lock = "SHARE UPDATE EXCLUSIVE"
+		case alterColumnTypeRegexp.MatchString(statement):
+			plan.Warning = "changing a column type rewrites the table while blocking reads and writes"
+		case setNotNullRegexp.MatchString(statement):
+			plan.Warning = "SET NOT NULL scans the table while blocking reads and writes"
+		case addConstraintRegexp.MatchString(statement) &&
+			!notValidRegexp.MatchString(statement) &&
+			!usingIndexRegexp.MatchString(statement):
+			plan.Warning = "adding a constraint without NOT VALID scans the table while blocking reads and writes"
+		case addColumnDefaultRegex.MatchString(statement):
+			plan.Warning = "adding a column with a volatile default rewrites the table while blocking reads and writes"
+		}
+		return plan
+	}
+
+	if m := createTableRegexp.FindStringSubmatch(statement); m != nil {
+		plan.Tables = []string{unquote(m[1])}
+		return plan
+	}
+
+	if m := dropTableRegexp.FindStringSubmatch(statement); m != nil {
+		for _, name := range strings.Split(m[1], ",") {
+			plan.Tables = append(plan.Tables, unquote(strings.TrimSpace(name)))
+		}
+		plan.Lock = "ACCESS EXCLUSIVE"
+		return plan
+	}
+
+	if m := truncateRegexp.FindStringSubmatch(statement); m != nil {
+		plan.Tables = []string{unquote(m[1])}
+		plan.Lock = "ACCESS EXCLUSIVE"
+		return plan
+	}
+
+	if m := lockTableRegexp.FindStringSubmatch(statement); m != nil {
+		plan.Tables = []string{unquote(m[1])}
+		plan.Lock = "ACCESS EXCLUSIVE"
+		plan.Warning = "explicitly locks the table until the migration completes"
+		return plan
+	}
+
+	if rewriteRegexp.MatchString(statement) {
+		if reindexConcurrently.MatchString(statement) {
+			plan.Lock = "SHARE UPDATE EXCLUSIVE"
+			return plan
+		}
+		plan.Lock = "ACCESS EXCLUSIVE"
+		plan.Warning = "rewrites a table or an index while blocking reads and writes"
+		return plan
+	}
+
+	for _, r := range []*regexp.Regexp{updateRegexp, deleteRegexp} {
+		if m := r.FindStringSubmatch(statement); m != nil {
+			plan.Tables = []string{unquote(m[1])}
+			plan.Lock = "ROW EXCLUSIVE"
+			plan.Warning = "locks the modified rows until the migration completes"
+			return plan
+		}
+	}
+
+	if m := insertRegexp.FindStringSubmatch(statement); m != nil {
+		plan.Tables = []string{unquote(m[1])}
+		plan.Lock = "ROW EXCLUSIVE"
+	}
+	return plan
+}
+
+// clearNewTableWarnings removes the warnings of statements touching only
+// tables created by previous pending statements: these tables are empty and
+// not used yet so locking them is harmless (ex. creating an index of a table
+// created in the same migration).
+func clearNewTableWarnings(migrations []MigrationPlan) {
+	created := map[string]bool{}
+	for i := range migrations {
+		for j := range migrations[i].Statements {
+			statement := &migrations[i].Statements[j]
+			if createTableRegexp.MatchString(statement.SQL) {
+				for _, table := range statement.Tables {
+					created[table] = true
+				}
+				continue
+			}
+			if statement.Warning == "" || len(statement.Tables) == 0 {
+				continue
+			}
+			onlyCreated := true
+			for _, table := range statement.Tables {
+				onlyCreated = onlyCreated && created[table]
+			}
+			if onlyCreated {
+				statement.Warning = ""
+			}
+		}
+	}
+}
+
+// markConcurrentIndexes marks CREATE INDEX statements which can be run
+// CONCURRENTLY before the migrations: the table already exists, is not
+// partitioned and is not touched by any other pending statement, and the
+// index is not referenced by any other pending statement.
+func markConcurrentIndexes(migrations []MigrationPlan, tables map[string]TableInfo) {
+	for i := range migrations {
+		for j := range migrations[i].Statements {
+			statement := &migrations[i].Statements[j]
+			if statement.index == nil {
+				continue
+			}
+			info := tables[statement.index.table]
+			if !info.Exists || info.Partitioned {
+				continue
+			}
+			statement.Concurrent = !referencedElsewhere(migrations, statement)
+		}
+	}
+}
+
+func referencedElsewhere(migrations []MigrationPlan, index *StatementPlan) bool {
+	name := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(index.index.name) + `\b`)
+	for _, migration := range migrations {
+		for k := range migration.Statements {
+			other := &migration.Statements[k]
+			if other == index {
+				continue
+			}
+			if name.MatchString(other.SQL) {
+				return true
+			}
+			if createIndexRegexp.MatchString(other.SQL) {
+				continue
+			}
+			for _, table := range other.Tables {
+				if table == index.index.table {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Plan returns the pending "up" migrations, at most count of them if count
+// is greater than 0, with the tables they touch and their lock-heavy
+// statements.
+func Plan(db *sql.DB, count int) (MigrationsPlan, error) {
+	planned, _, err := migrate.PlanMigration(db, "postgres", Migrations, migrate.Up, count)
+	if err != nil {
+		return MigrationsPlan{}, err
+	}
+
+	plan := MigrationsPlan{Tables: map[string]TableInfo{}}
+	for _, m := range planned {
+		migration := MigrationPlan{Id: m.Id}
+		for _, query := range m.Queries {
+			statement := analyzeStatement(query)
+			for _, table := range statement.Tables {
+				plan.Tables[table] = TableInfo{}
+			}
+			migration.Statements = append(migration.Statements, statement)
+		}
+		plan.Migrations = append(plan.Migrations, migration)
+	}
+
+	for table := range plan.Tables {
+		info, err := loadTableInfo(db, table)
+		if err != nil {
+			return MigrationsPlan{}, err
+		}
+		plan.Tables[table] = info
+	}
+
+	clearNewTableWarnings(plan.Migrations)
+	markConcurrentIndexes(plan.Migrations, plan.Tables)
+	return plan, nil
+}
+
+func loadTableInfo(db *sql.DB, table string) (TableInfo, error) {
+	info := TableInfo{EstimatedRows: -1}
+	var relkind sql.NullString
+	var reltuples sql.NullFloat64
+	err := db.QueryRow(`
+		SELECT c.relkind::text, (
+			SELECT SUM(GREATEST(p.reltuples, 0)) FROM pg_class p
+			WHERE p.oid = c.oid OR p.oid IN (SELECT inhrelid FROM pg_inherits WHERE inhparent = c.oid)
+		)
+		FROM pg_class c WHERE c.oid = to_regclass($1)
+	`, table).Scan(&relkind, &reltuples)
+	if err == sql.ErrNoRows {
+		return info, nil
+	}
+	if err != nil {
+		return info, errors.Wrapf(err, "could not load statistics of %s", table)
+	}
+	info.Exists = true
+	info.Partitioned = relkind.String == "p"
+	if reltuples.Valid {
+		info.EstimatedRows = int64(reltuples.Float64)
+	}
+	return info, nil
+}
+
+// String returns a human readable report of the plan.
+func (p MigrationsPlan) String() string {
+	if len(p.Migrations) == 0 {
+		return "No migrations to apply.\n"
+	}
+
+	buffer := &bytes.Buffer{}
+	for _, migration := range p.Migrations {
+		fmt.Fprintf(buffer, "%s\n", migration.Id)
+		table := tabwriter.NewWriter(buffer, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(table, "  Statement\tTables (estimated rows)\tLock\t")
+		for _, statement := range migration.Statements {
+			var tables []string
+			for _, name := range statement.Tables {
+				info := p.Tables[name]
+				switch {
+				case !info.Exists:
+					tables = append(tables, name+" (new)")
+				case info.EstimatedRows < 0:
+					tables = append(tables, name+" (unknown)")
+				default:
+					tables = append(tables, fmt.Sprintf("%s (%d)", name, info.EstimatedRows))
+				}
+			}
+			fmt.Fprintf(table, "  %s\t%s\t%s\t\n", summarize(statement.SQL), strings.Join(tables, ", "), statement.Lock)
+			if statement.Warning != "" {
+				fmt.Fprintf(table, "    WARNING: %s\t\t\t\n", statement.Warning)
+			}
+			if statement.Concurrent {
+				fmt.Fprintf(table, "    can run CONCURRENTLY with --concurrent-indexes\t\t\t\n")
+			}
+		}
+		table.Flush()
+		fmt.Fprintln(buffer)
+	}
+	return buffer.String()
+}
+
+func summarize(statement string) string {
+	summary := strings.Join(strings.Fields(statement), " ")
+	if len(summary) > 80 {
+		summary = summary[:77] + "..."
+	}
+	return summary
+}
+
+// MigrateUpConcurrentIndexes is like Migrate in the "up" direction but first
+// creates CONCURRENTLY, outside of the migration transactions, the indexes
+// which can safely be created this way (see StatementPlan.Concurrent). It
+// doesn't block writes to the indexed tables while building these indexes.
+func MigrateUpConcurrentIndexes(db *sql.DB, count int) (int, error) {
+	// Ingestion is locked before creating the indexes so that the plan can't
+	// be changed by another migration process. The lock is a row lock on
+	// key_value_store which doesn't conflict with building indexes of other
+	// tables concurrently.
+	unlock, err := lockIngestion(db)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	plan, err := Plan(db, count)
+	if err != nil {
+		return 0, err
+	}
+
+	skip := map[string]map[string]bool{}
+	for _, migration := range plan.Migrations {
+		for _, statement := range migration.Statements {
+			if !statement.Concurrent {
+				continue
+			}
+			if err = createIndexConcurrently(db, statement); err != nil {
+				return 0, err
+			}
+			if skip[migration.Id] == nil {
+				skip[migration.Id] = map[string]bool{}
+			}
+			skip[migration.Id][statement.raw] = true
+		}
+	}
+
+	migrations, err := Migrations.FindMigrations()
+	if err != nil {
+		return 0, err
+	}
+	source := &migrate.MemoryMigrationSource{}
+	for _, m := range migrations {
+		if skip[m.Id] != nil {
+			modified := *m
+			modified.Up = nil
+			for _, query := range m.Up {
+				if !skip[m.Id][strings.TrimSpace(query)] {
+					modified.Up = append(modified.Up, query)
+				}
+			}
+			m = &modified
+		}
+		source.Migrations = append(source.Migrations, m)
+	}
+
+	return execMigrations(db, source, MigrateUp, count)
+}
+
+func createIndexConcurrently(db *sql.DB, statement StatementPlan) error {
+	ctx := context.Background()
+	name := statement.index.name
+
+	// A previous attempt could have created the index, or left an invalid
+	// index behind if it failed.
+	var valid sql.NullBool
+	err := db.QueryRowContext(ctx, `
+		SELECT i.indisvalid FROM pg_index i WHERE i.indexrelid = to_regclass($1)
+	`, name).Scan(&valid)
+	if err != nil && err != sql.ErrNoRows {
+		return errors.Wrapf(err, "could not check index %s", name)
+	}
+	if valid.Valid && valid.Bool {
+		return nil
+	}
+	if valid.Valid {
+		if _, err = db.ExecContext(ctx, fmt.Sprintf(`DROP INDEX CONCURRENTLY "%s"`, name)); err != nil {
+			return errors.Wrapf(err, "could not drop invalid index %s", name)
+		}
+	}
+
+	loc := createIndexRegexp.FindStringSubmatchIndex(statement.SQL)
+	// insert CONCURRENTLY before the (optional IF NOT EXISTS and) index name
+	nameStart := loc[4]
+	if statement.SQL[nameStart-1] == '"' {
+		nameStart--
+	}
+	if ifNotExists := regexp.MustCompile(`(?is)IF\s+NOT\s+EXISTS\s+$`).FindStringIndex(statement.SQL[:nameStart]); ifNotExists != nil {
+		nameStart = ifNotExists[0]
+	}
+	query := statement.SQL[:nameStart] + "CONCURRENTLY " + statement.SQL[nameStart:]
+	if _, err = db.ExecContext(ctx, query); err != nil {
+		_, _ = db.ExecContext(ctx, fmt.Sprintf(`DROP INDEX CONCURRENTLY IF EXISTS "%s"`, name))
+		return errors.Wrapf(err, "could not create index %s concurrently", name)
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hcnet/go/support/db/dbtest"
+)
+
+func TestAnalyzeStatement(t *testing.T) {
+	for _, testCase := range []struct {
+		statement string
+		tables    []string
+		lock      string
+		heavy     bool
+	}{
+		{
+			statement: `/* Supports "select * from claimable_balances where asset = ?" */
+CREATE INDEX "claimable_balances_by_asset" ON claimable_balances USING btree (asset);`,
+			tables: []string{"claimable_balances"},
+			lock:   "SHARE",
+			heavy:  true,
+		},
+		{
+			statement: "CREATE UNIQUE INDEX CONCURRENTLY index_a ON public.history_a USING btree (id);",
+			tables:    []string{"history_a"},
+			lock:      "SHARE UPDATE EXCLUSIVE",
+		},
+		{
+			statement: "DROP INDEX claimable_balances_by_asset;",
+			lock:      "ACCESS EXCLUSIVE",
+		},
+		{
+			statement: "ALTER TABLE history_transactions ADD COLUMN memo_bytea bytea NULL;",
+			tables:    []string{"history_transactions"},
+			lock:      "ACCESS EXCLUSIVE",
+		},
+		{
+			statement: "ALTER TABLE history_transactions ALTER COLUMN max_fee TYPE bigint;",
+			tables:    []string{"history_transactions"},
+			lock:      "ACCESS EXCLUSIVE",
+			heavy:     true,
+		},
+		{
+			statement: "ALTER TABLE history_operations ADD CONSTRAINT valid_application_order CHECK (application_order >= 0) NOT VALID;",
+			tables:    []string{"history_operations"},
+			lock:      "ACCESS EXCLUSIVE",
+		},
+		{
+			statement: "ALTER TABLE ONLY history_trades ADD CONSTRAINT history_trades_fkey FOREIGN KEY (base_account_id) REFERENCES history_accounts(id);",
+			tables:    []string{"history_trades"},
+			lock:      "ACCESS EXCLUSIVE",
+			heavy:     true,
+		},
+		{
+			statement: "ALTER TABLE history_operations VALIDATE CONSTRAINT valid_application_order;",
+			tables:    []string{"history_operations"},
+			lock:      "SHARE UPDATE EXCLUSIVE",
+		},
+		{
+			statement: "CREATE TABLE claimable_balance_claimants (id TEXT NOT NULL);",
+			tables:    []string{"claimable_balance_claimants"},
+		},
+		{
+			statement: "DROP TABLE exp_history_effects, exp_history_ledgers cascade;",
+			tables:    []string{"exp_history_effects", "exp_history_ledgers"},
+			lock:      "ACCESS EXCLUSIVE",
+		},
+		{
+			statement: "UPDATE history_trades SET price_n = base_amount;",
+			tables:    []string{"history_trades"},
+			lock:      "ROW EXCLUSIVE",
+			heavy:     true,
+		},
+		{
+			statement: "INSERT INTO key_value_store (key, value) VALUES ('a', 'b');",
+			tables:    []string{"key_value_store"},
+			lock:      "ROW EXCLUSIVE",
+		},
+		{
+			statement: "CREATE OR REPLACE FUNCTION f() RETURNS integer AS $$ SELECT 1 $$ LANGUAGE SQL;",
+		},
+	} {
+		t.Run(testCase.statement, func(t *testing.T) {
+			plan := analyzeStatement(testCase.statement)
+			assert.Equal(t, testCase.tables, plan.Tables)
+			assert.Equal(t, testCase.lock, plan.Lock)
+			assert.Equal(t, testCase.heavy, plan.Warning != "", plan.Warning)
+		})
+	}
+}
+
+func TestMarkConcurrentIndexes(t *testing.T) {
+	migrations := []MigrationPlan{
+		{
+			Id: "1_indexes.sql",
+			Statements: []StatementPlan{
+				analyzeStatement("CREATE INDEX index_a ON table_a USING btree (a);"),
+				analyzeStatement("CREATE INDEX index_b ON table_b USING btree (b);"),
+				analyzeStatement("CREATE INDEX index_c ON new_table USING btree (c);"),
+				analyzeStatement("CREATE INDEX index_d ON partitioned USING btree (d);"),
+				analyzeStatement("CREATE INDEX index_e ON ONLY table_a USING btree (e);"),
+				analyzeStatement("CREATE INDEX index_f ON table_a USING btree (f);"),
+			},
+		},
+		{
+			Id: "2_alter.sql",
+			Statements: []StatementPlan{
+				// table_b is modified, index_b may use the new column
+				analyzeStatement("ALTER TABLE table_b ADD COLUMN b integer;"),
+				// index_f is referenced
+				analyzeStatement("DROP INDEX index_f;"),
+			},
+		},
+	}
+	markConcurrentIndexes(migrations, map[string]TableInfo{
+		"table_a":     {Exists: true},
+		"table_b":     {Exists: true},
+		"partitioned": {Exists: true, Partitioned: true},
+	})
+
+	var concurrent []bool
+	for _, statement := range migrations[0].Statements {
+		concurrent = append(concurrent, statement.Concurrent)
+	}
+	assert.Equal(t, []bool{true, false, false, false, false, false}, concurrent)
+
+	plan := MigrationsPlan{
+		Migrations: migrations[:1],
+		Tables: map[string]TableInfo{
+			"table_a":     {Exists: true, EstimatedRows: 1000},
+			"table_b":     {Exists: true, EstimatedRows: -1},
+			"partitioned": {Exists: true, EstimatedRows: 10},
+		},
+	}
+	report := plan.String()
+	assert.Contains(t, report, "1_indexes.sql\n")
+	assert.Contains(t, report, "table_a (1000)")
+	assert.Contains(t, report, "table_b (unknown)")
+	assert.Contains(t, report, "new_table (new)")
+	assert.Contains(t, report, "WARNING: builds an index while blocking writes")
+	assert.Contains(t, report, "can run CONCURRENTLY with --concurrent-indexes")
+	assert.Equal(t, "No migrations to apply.\n", MigrationsPlan{}.String())
+}
+
+func TestClearNewTableWarnings(t *testing.T) {
+	migrations := []MigrationPlan{
+		{
+			Id: "1_new_table.sql",
+			Statements: []StatementPlan{
+				analyzeStatement("CREATE INDEX index_a ON new_table USING btree (a);"),
+				analyzeStatement("CREATE TABLE new_table (a integer, b integer);"),
+				analyzeStatement("CREATE INDEX index_b ON new_table USING btree (b);"),
+				analyzeStatement("CREATE INDEX index_c ON table_c USING btree (c);"),
+			},
+		},
+		{
+			Id: "2_alter.sql",
+			Statements: []StatementPlan{
+				analyzeStatement("ALTER TABLE new_table ALTER COLUMN a SET NOT NULL;"),
+			},
+		},
+	}
+	clearNewTableWarnings(migrations)
+
+	var warnings []bool
+	for _, migration := range migrations {
+		for _, statement := range migration.Statements {
+			warnings = append(warnings, statement.Warning != "")
+		}
+	}
+	// the index created before the table refers to another table
+	assert.Equal(t, []bool{true, false, false, true, false}, warnings)
+
+	all, err := Migrations.FindMigrations()
+	require.NoError(t, err)
+	found := false
+	for _, migration := range all {
+		if migration.Id != "71_history_order_book_snapshots.sql" {
+			continue
+		}
+		found = true
+		plan := MigrationPlan{Id: migration.Id}
+		for _, query := range migration.Up {
+			plan.Statements = append(plan.Statements, analyzeStatement(query))
+		}
+		clearNewTableWarnings([]MigrationPlan{plan})
+		for _, statement := range plan.Statements {
+			assert.Empty(t, statement.Warning, statement.SQL)
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestAnalyzeMigrations(t *testing.T) {
+	migrations, err := Migrations.FindMigrations()
+	require.NoError(t, err)
+	for _, migration := range migrations {
+		for _, query := range migration.Up {
+			plan := analyzeStatement(query)
+			if plan.index != nil {
+				assert.NotEmpty(t, plan.index.name, query)
+				assert.NotEmpty(t, plan.index.table, query)
+			}
+		}
+	}
+}
+
+func TestMigrateUpConcurrentIndexes(t *testing.T) {
+	tdb := dbtest.Postgres(t)
+	defer tdb.Close()
+	db := tdb.Open()
+
+	defer db.Close()
+
+	_, err := Migrate(db.DB, MigrateUp, 40)
+	require.NoError(t, err)
+
+	plan, err := Plan(db.DB, 0)
+	require.NoError(t, err)
+	assert.NotEmpty(t, plan.Migrations)
+
+	_, err = MigrateUpConcurrentIndexes(db.DB, 0)
+	require.NoError(t, err)
+
+	plan, err = Plan(db.DB, 0)
+	require.NoError(t, err)
+	assert.Empty(t, plan.Migrations)
+}
@@ -9,12 +9,14 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
 	"github.com/hcnet/go/ingest/ledgerbackend"
 	"github.com/hcnet/go/network"
 	"github.com/hcnet/go/services/aurora/internal/db2/schema"
+	"github.com/hcnet/go/services/aurora/internal/reap"
 	apkg "github.com/hcnet/go/support/app"
 	support "github.com/hcnet/go/support/config"
 	"github.com/hcnet/go/support/db"
@@ -25,6 +27,8 @@ import (
 const (
 	// DatabaseURLFlagName is the command line flag for configuring the Aurora postgres URL
 	DatabaseURLFlagName = "db-url"
+	// HistoryRetentionArchiveURLFlagName is the command line flag for configuring the archive of reaped history
+	HistoryRetentionArchiveURLFlagName = "history-retention-archive-url"
 	// IngestFlagName is the command line flag for enabling ingestion on the Aurora instance
 	IngestFlagName = "ingest"
 	// HcnetCoreDBURLFlagName is the command line flag for configuring the postgres Hcnet Core URL
@@ -317,6 +321,27 @@ func Flags() (*Config, support.ConfigOptions) {
 			CustomSetValue: support.SetDuration,
 			Usage:          "defines how often streams should check if there's a new ledger (in seconds), may need to increase in case of big number of streams",
 		},
+		&support.ConfigOption{
+			Name:        "max-websocket-subscriptions",
+			ConfigKey:   &config.MaxWebSocketSubscriptions,
+			OptType:     types.Uint,
+			FlagDefault: uint(50),
+			Usage:       "maximum number of streams a single connection to the /ws endpoint can subscribe to, 0 disables the endpoint",
+		},
+		&support.ConfigOption{
+			Name:        "enable-webhooks",
+			ConfigKey:   &config.EnableWebhooks,
+			OptType:     types.Bool,
+			FlagDefault: false,
+			Usage:       "delivers operations to the webhook subscriptions managed with the /webhooks admin endpoints after each ingested ledger",
+		},
+		&support.ConfigOption{
+			Name:        "enable-api-keys",
+			ConfigKey:   &config.EnableAPIKeys,
+			OptType:     types.Bool,
+			FlagDefault: false,
+			Usage:       "rate limits requests sending an API key, managed with the /api_keys admin endpoints, by the quotas of the key instead of by remote ip address",
+		},
 		&support.ConfigOption{
 			Name:           "connection-timeout",
 			ConfigKey:      &config.ConnectionTimeout,
@@ -479,6 +504,75 @@ func Flags() (*Config, support.ConfigOptions) {
 			FlagDefault: uint(0),
 			Usage:       "the minimum number of ledgers to maintain within aurora's history tables.  0 signifies an unlimited number of ledgers will be retained",
 		},
+		&support.ConfigOption{
+			Name:        "history-retention-policy",
+			ConfigKey:   &config.HistoryRetentionPolicies,
+			OptType:     types.String,
+			FlagDefault: "",
+			CustomSetValue: func(co *support.ConfigOption) error {
+				policies, err := reap.ParseRetentionPolicies(viper.GetString(co.Name))
+				if err != nil {
+					return err
+				}
+				*(co.ConfigKey.(*map[string]reap.RetentionPolicy)) = policies
+				return nil
+			},
+			Usage: "comma separated list of per table retention policies overriding --history-retention-count, " +
+				"ex. effects=90d,operations=90d. Tables: trades, effects, operations, transactions, balances, ledgers. " +
+				"Retention is a number of ledgers or a duration (ex. 90d, 2160h)",
+		},
+		&support.ConfigOption{
+			Name:        "history-retention-reap-batch-size",
+			ConfigKey:   &config.HistoryRetentionReapBatchSize,
+			OptType:     types.Uint,
+			FlagDefault: uint(reap.DefaultBatchSize),
+			Usage:       "the maximum number of rows removed from a history table in a single query by the reaper",
+		},
+		&support.ConfigOption{
+			Name:        HistoryRetentionArchiveURLFlagName,
+			ConfigKey:   &config.HistoryRetentionArchiveURL,
+			OptType:     types.String,
+			FlagDefault: "",
+			Usage: "[optional] URL of a local directory (file://) or S3 bucket (s3://) where the reaper writes " +
+				"gzip compressed CSV files of history rows before removing them. " +
+				"Archived rows can be restored with the aurora db restore-history command",
+		},
+		&support.ConfigOption{
+			Name:        "history-retention-archive-s3-region",
+			ConfigKey:   &config.HistoryRetentionArchiveS3Region,
+			OptType:     types.String,
+			FlagDefault: "",
+			Usage:       "[optional] region of the S3 bucket of --history-retention-archive-url",
+		},
+		&support.ConfigOption{
+			Name:        "history-retention-archive-s3-endpoint",
+			ConfigKey:   &config.HistoryRetentionArchiveS3Endpoint,
+			OptType:     types.String,
+			FlagDefault: "",
+			Usage:       "[optional] endpoint of an S3-compatible store used by --history-retention-archive-url",
+		},
+		&support.ConfigOption{
+			Name:        "history-retention-archive-s3-acl",
+			ConfigKey:   &config.HistoryRetentionArchiveS3ACL,
+			OptType:     types.String,
+			FlagDefault: "",
+			CustomSetValue: func(co *support.ConfigOption) error {
+				acl := viper.GetString(co.Name)
+				if acl != "" {
+					valid := false
+					for _, value := range s3.ObjectCannedACL_Values() {
+						valid = valid || acl == value
+					}
+					if !valid {
+						return fmt.Errorf("invalid %s value: %s", co.Name, acl)
+					}
+				}
+				*(co.ConfigKey.(*string)) = acl
+				return nil
+			},
+			Usage: "[optional] canned ACL (ex. private) of the files uploaded to the S3 bucket of " +
+				"--history-retention-archive-url, files are uploaded without ACL by default",
+		},
 		&support.ConfigOption{
 			Name:        "history-stale-threshold",
 			ConfigKey:   &config.StaleThreshold,
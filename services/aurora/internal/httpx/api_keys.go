@@ -0,0 +1,379 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stellar/throttled"
+
+	hProtocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/services/aurora/internal/render"
+	hProblem "github.com/hcnet/go/services/aurora/internal/render/problem"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/support/log"
+	"github.com/hcnet/go/support/render/problem"
+)
+
+const (
+	apiKeyHeader = "X-API-Key"
+
+	// apiKeysRefreshInterval is how long API keys are cached before they
+	// are loaded again from the DB, changes made with the admin endpoints
+	// of another Aurora instance take effect after this interval.
+	apiKeysRefreshInterval = 10 * time.Second
+	apiKeysLoadTimeout     = 5 * time.Second
+
+	anonymousKeyPrefix = "ip:"
+)
+
+// defaultRouteClassWeights is the number of requests counted against the
+// quotas of an API key for each request of a route class, unless the key
+// overrides it. Streams are counted for each ledger they send.
+var defaultRouteClassWeights = map[string]int{
+	history.RouteClassDefault: 1,
+	history.RouteClassPaths:   10,
+	history.RouteClassStreams: 5,
+}
+
+// requestAPIKey returns the API key sent in the X-API-Key header. Keys aren't
+// accepted in the query because request URLs are logged.
+func requestAPIKey(r *http.Request) string {
+	return r.Header.Get(apiKeyHeader)
+}
+
+func routeClass(r *http.Request) string {
+	if render.Negotiate(r) == render.MimeEventStream {
+		return history.RouteClassStreams
+	}
+	if r.URL.Path == "/paths" || strings.HasPrefix(r.URL.Path, "/paths/") {
+		return history.RouteClassPaths
+	}
+	return history.RouteClassDefault
+}
+
+type apiKeyLimiters struct {
+	key       history.APIKey
+	perSecond *throttled.GCRARateLimiter
+	perHour   *throttled.GCRARateLimiter
+}
+
+func newAPIKeyLimiters(key history.APIKey) (*apiKeyLimiters, error) {
+	limiters := &apiKeyLimiters{key: key}
+	var err error
+	// the whole quota of a period can be used at once
+	if key.PerSecondLimit > 0 {
+		limiters.perSecond, err = throttled.NewGCRARateLimiter(1, throttled.RateQuota{
+			MaxRate:  throttled.PerSec(key.PerSecondLimit),
+			MaxBurst: key.PerSecondLimit - 1,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if key.PerHourLimit > 0 {
+		limiters.perHour, err = throttled.NewGCRARateLimiter(1, throttled.RateQuota{
+			MaxRate:  throttled.PerHour(key.PerHourLimit),
+			MaxBurst: key.PerHourLimit - 1,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return limiters, nil
+}
+
+func (l *apiKeyLimiters) weight(class string) int {
+	if weight, ok := l.key.Weights[class]; ok {
+		return weight
+	}
+	return defaultRouteClassWeights[class]
+}
+
+// apiKeyQuota is a quota of a key and the quantity of a request counted
+// against it.
+type apiKeyQuota struct {
+	limiter  *throttled.GCRARateLimiter
+	quantity int
+}
+
+// quotaQuantity returns the quantity counted against a quota with the given
+// limit. GCRA limiters refuse any quantity larger than their burst, so a
+// request weighing more than the quota uses all of it instead of being
+// always refused.
+func quotaQuantity(quantity, limit int) int {
+	if quantity > limit {
+		return limit
+	}
+	return quantity
+}
+
+// rateLimit checks the quotas of the key, the result of the most restrictive
+// quota is returned. A request is only counted against the quotas when all of
+// them allow it: the per second quota is checked first so that bursts don't
+// use up the per hour quota, and it's refunded when the per hour quota is
+// exceeded.
+func (l *apiKeyLimiters) rateLimit(quantity int) (bool, throttled.RateLimitResult, error) {
+	result := throttled.RateLimitResult{Limit: -1, Remaining: -1, ResetAfter: -1, RetryAfter: -1}
+	var charged []apiKeyQuota
+	for _, quota := range []apiKeyQuota{
+		{l.perSecond, quotaQuantity(quantity, l.key.PerSecondLimit)},
+		{l.perHour, quotaQuantity(quantity, l.key.PerHourLimit)},
+	} {
+		if quota.limiter == nil {
+			continue
+		}
+		limited, limiterResult, err := quota.limiter.RateLimit("", quota.quantity)
+		if err == nil && limited {
+			err = refund(charged)
+		}
+		if err != nil {
+			return false, result, err
+		}
+		if limited {
+			return true, limiterResult, nil
+		}
+		charged = append(charged, quota)
+		if result.Remaining < 0 || limiterResult.Remaining < result.Remaining {
+			result = limiterResult
+		}
+	}
+	return false, result, nil
+}
+
+// refund gives back the quantities counted against the quotas. GCRA limiters
+// move the theoretical arrival time of the next request by the quantity, so
+// a negative quantity moves it back.
+func refund(quotas []apiKeyQuota) error {
+	for _, quota := range quotas {
+		if _, _, err := quota.limiter.RateLimit("", -quota.quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type apiKeyUsage struct {
+	requests map[string]int64
+	limited  map[string]int64
+}
+
+// apiKeyRateLimiter rate limits requests by the quotas of their API key.
+// Requests without API key are rate limited by remote IP address using the
+// anonymous quota, if there's one.
+//
+// It implements throttled.RateLimiter and the VaryBy interface of
+// throttled.HTTPRateLimiter so that it's used both by the rate limiting
+// middleware and by streams: Key returns the route class and the hash of the
+// API key of a request, RateLimit weights the quantity by the route class.
+type apiKeyRateLimiter struct {
+	loadKeys  func(ctx context.Context) ([]history.APIKey, error)
+	anonymous *throttled.GCRARateLimiter
+	counter   *prometheus.CounterVec
+
+	lock           sync.Mutex
+	loadedAt       time.Time
+	mustReload     bool
+	reloadRequests uint64
+	reloading      bool
+	keys           map[string]*apiKeyLimiters
+	// usage counts the requests of each API key since this instance started,
+	// the counts are neither persisted nor shared with the other instances.
+	// The aurora_http_api_key_requests_total metric can be aggregated across
+	// instances by the monitoring system.
+	usage map[int64]*apiKeyUsage
+}
+
+func newAPIKeyRateLimiter(
+	loadKeys func(ctx context.Context) ([]history.APIKey, error),
+	anonymousQuota *throttled.RateQuota,
+	serverMetrics *ServerMetrics,
+) (*throttled.HTTPRateLimiter, *apiKeyRateLimiter, error) {
+	limiter := &apiKeyRateLimiter{
+		loadKeys: loadKeys,
+		keys:     map[string]*apiKeyLimiters{},
+		usage:    map[int64]*apiKeyUsage{},
+	}
+	if anonymousQuota != nil {
+		var err error
+		limiter.anonymous, err = throttled.NewGCRARateLimiter(lruCacheSize, *anonymousQuota)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if serverMetrics != nil {
+		limiter.counter = serverMetrics.APIKeyRequestsCounter
+	}
+
+	result := &throttled.HTTPRateLimiter{
+		RateLimiter: limiter,
+		DeniedHandler: http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+			problem.Render(request.Context(), w, hProblem.RateLimitExceeded)
+		}),
+		Error: func(w http.ResponseWriter, request *http.Request, err error) {
+			problem.Render(request.Context(), w, err)
+		},
+		VaryBy: limiter,
+	}
+	return result, limiter, nil
+}
+
+// Key implements the VaryBy interface of throttled.HTTPRateLimiter.
+func (l *apiKeyRateLimiter) Key(r *http.Request) string {
+	key := requestAPIKey(r)
+	if key == "" {
+		return anonymousKeyPrefix + remoteAddrIP(r)
+	}
+	return routeClass(r) + ":" + history.HashAPIKey(key)
+}
+
+// RateLimit implements throttled.RateLimiter.
+func (l *apiKeyRateLimiter) RateLimit(key string, quantity int) (bool, throttled.RateLimitResult, error) {
+	if strings.HasPrefix(key, anonymousKeyPrefix) {
+		if l.anonymous == nil {
+			return false, throttled.RateLimitResult{Limit: -1, Remaining: -1, ResetAfter: -1, RetryAfter: -1}, nil
+		}
+		return l.anonymous.RateLimit(strings.TrimPrefix(key, anonymousKeyPrefix), quantity)
+	}
+
+	parts := strings.SplitN(key, ":", 2)
+	if len(parts) != 2 {
+		return false, throttled.RateLimitResult{}, errors.Errorf("invalid rate limit key %s", key)
+	}
+	class, keyHash := parts[0], parts[1]
+	limiters, err := l.limiters(keyHash)
+	if err != nil {
+		return false, throttled.RateLimitResult{}, err
+	}
+
+	limited, result, err := limiters.rateLimit(quantity * limiters.weight(class))
+	if err != nil {
+		return false, result, err
+	}
+	l.count(limiters.key.ID, class, limited)
+	return limited, result, nil
+}
+
+// limiters returns the limiters of the API key with the given hash, the keys
+// are loaded from the DB when they have not been loaded for
+// apiKeysRefreshInterval. The keys are loaded without holding the lock, the
+// other requests keep using the keys previously loaded in the meantime.
+func (l *apiKeyRateLimiter) limiters(keyHash string) (*apiKeyLimiters, error) {
+	l.lock.Lock()
+	due := l.mustReload || time.Since(l.loadedAt) >= apiKeysRefreshInterval
+	loaded := !l.loadedAt.IsZero()
+	reload := due && (!l.reloading || !loaded)
+	requests := l.reloadRequests
+	if reload {
+		l.reloading = true
+	}
+	l.lock.Unlock()
+
+	if reload {
+		if err := l.reload(requests); err != nil {
+			if !loaded {
+				return nil, err
+			}
+			log.WithField("err", err).Warn("could not reload api keys, using the api keys previously loaded")
+		}
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	limiters, ok := l.keys[keyHash]
+	if !ok {
+		return nil, hProblem.InvalidAPIKey
+	}
+	return limiters, nil
+}
+
+// reload loads the API keys from the DB and swaps them in, the state of the
+// limiters of keys which have not been updated is kept. requests is the
+// number of reloads requested with ReloadAPIKeys when the load started, the
+// reloads requested while loading are done by the next request.
+func (l *apiKeyRateLimiter) reload(requests uint64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), apiKeysLoadTimeout)
+	defer cancel()
+	keys, err := l.loadKeys(ctx)
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.reloading = false
+	if err != nil {
+		return errors.Wrap(err, "could not load api keys")
+	}
+
+	loaded := make(map[string]*apiKeyLimiters, len(keys))
+	for _, key := range keys {
+		if existing, ok := l.keys[key.KeyHash]; ok && existing.key.UpdatedAt.Equal(key.UpdatedAt) {
+			loaded[key.KeyHash] = existing
+			continue
+		}
+		limiters, err := newAPIKeyLimiters(key)
+		if err != nil {
+			return errors.Wrapf(err, "invalid quotas for api key %d", key.ID)
+		}
+		loaded[key.KeyHash] = limiters
+	}
+	l.keys = loaded
+	l.loadedAt = time.Now()
+	if l.reloadRequests == requests {
+		l.mustReload = false
+	}
+	return nil
+}
+
+func (l *apiKeyRateLimiter) count(id int64, class string, limited bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	usage, ok := l.usage[id]
+	if !ok {
+		usage = &apiKeyUsage{requests: map[string]int64{}, limited: map[string]int64{}}
+		l.usage[id] = usage
+	}
+	if limited {
+		usage.limited[class]++
+	} else {
+		usage.requests[class]++
+	}
+	if l.counter != nil {
+		l.counter.With(prometheus.Labels{
+			"api_key_id":  strconv.FormatInt(id, 10),
+			"route_class": class,
+			"limited":     strconv.FormatBool(limited),
+		}).Inc()
+	}
+}
+
+// APIKeyUsage implements actions.APIKeyUsageTracker, the usage is counted by
+// this Aurora instance since it started.
+func (l *apiKeyRateLimiter) APIKeyUsage(id int64) hProtocol.APIKeyUsage {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	result := hProtocol.APIKeyUsage{ID: id, Requests: map[string]int64{}, Limited: map[string]int64{}}
+	if usage, ok := l.usage[id]; ok {
+		for class, count := range usage.requests {
+			result.Requests[class] = count
+		}
+		for class, count := range usage.limited {
+			result.Limited[class] = count
+		}
+	}
+	return result
+}
+
+// ReloadAPIKeys implements actions.APIKeyUsageTracker, the API keys are
+// loaded again from the DB by the next request with an API key.
+func (l *apiKeyRateLimiter) ReloadAPIKeys() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.mustReload = true
+	l.reloadRequests++
+}
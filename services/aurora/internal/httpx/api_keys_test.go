@@ -0,0 +1,236 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stellar/throttled"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	hProtocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+)
+
+func newTestAPIKeyRateLimiter(t *testing.T, keys *[]history.APIKey, anonymousQuota *throttled.RateQuota) (*throttled.HTTPRateLimiter, *apiKeyRateLimiter, *ServerMetrics) {
+	metrics := &ServerMetrics{
+		APIKeyRequestsCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: "api_key_requests_total"},
+			[]string{"api_key_id", "route_class", "limited"},
+		),
+	}
+	httpLimiter, limiter, err := newAPIKeyRateLimiter(func(ctx context.Context) ([]history.APIKey, error) {
+		if keys == nil {
+			return nil, errors.New("db is down")
+		}
+		return *keys, nil
+	}, anonymousQuota, metrics)
+	require.NoError(t, err)
+	return httpLimiter, limiter, metrics
+}
+
+func serveRateLimited(httpLimiter *throttled.HTTPRateLimiter, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	httpLimiter.RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(w, r)
+	return w
+}
+
+func TestAPIKeyRateLimiter(t *testing.T) {
+	keys := []history.APIKey{
+		{
+			ID:             1,
+			KeyHash:        history.HashAPIKey("partner"),
+			PerSecondLimit: 100,
+			PerHourLimit:   25,
+			Weights:        history.APIKeyWeights{history.RouteClassPaths: 20},
+		},
+		{ID: 2, KeyHash: history.HashAPIKey("unlimited")},
+	}
+	httpLimiter, limiter, metrics := newTestAPIKeyRateLimiter(t, &keys, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/paths/strict-send", nil)
+	r.Header.Set(apiKeyHeader, "partner")
+	w := serveRateLimited(httpLimiter, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "25", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "5", w.Header().Get("X-RateLimit-Remaining"))
+
+	// the key isn't read from the query, where it would be logged
+	r = httptest.NewRequest(http.MethodGet, "/ledgers?api_key=partner", nil)
+	assert.Equal(t, anonymousKeyPrefix+remoteAddrIP(r), httpLimiter.VaryBy.Key(r))
+
+	r = httptest.NewRequest(http.MethodGet, "/ledgers", nil)
+	r.Header.Set(apiKeyHeader, "partner")
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, serveRateLimited(httpLimiter, r).Code)
+	}
+	w = serveRateLimited(httpLimiter, r)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// streams are limited for each ledger with the default stream weight
+	r = httptest.NewRequest(http.MethodGet, "/ledgers", nil)
+	r.Header.Set(apiKeyHeader, "unlimited")
+	r.Header.Set("Accept", "text/event-stream")
+	limited, _, err := httpLimiter.RateLimiter.RateLimit(httpLimiter.VaryBy.Key(r), 1)
+	require.NoError(t, err)
+	assert.False(t, limited)
+
+	assert.Equal(t, hProtocol.APIKeyUsage{
+		ID:       1,
+		Requests: map[string]int64{history.RouteClassPaths: 1, history.RouteClassDefault: 5},
+		Limited:  map[string]int64{history.RouteClassDefault: 1},
+	}, limiter.APIKeyUsage(1))
+	assert.Equal(t, hProtocol.APIKeyUsage{
+		ID:       2,
+		Requests: map[string]int64{history.RouteClassStreams: 1},
+		Limited:  map[string]int64{},
+	}, limiter.APIKeyUsage(2))
+	assert.Equal(t, float64(1), testutil.ToFloat64(
+		metrics.APIKeyRequestsCounter.WithLabelValues("1", history.RouteClassDefault, "true"),
+	))
+
+	// unknown keys are rejected
+	r = httptest.NewRequest(http.MethodGet, "/ledgers", nil)
+	r.Header.Set(apiKeyHeader, "unknown")
+	assert.Equal(t, http.StatusUnauthorized, serveRateLimited(httpLimiter, r).Code)
+
+	// requests without key are not limited without anonymous quota
+	r = httptest.NewRequest(http.MethodGet, "/ledgers", nil)
+	assert.Equal(t, http.StatusOK, serveRateLimited(httpLimiter, r).Code)
+}
+
+func TestAPIKeyLimitersOnlyCountAllowedRequests(t *testing.T) {
+	limiters, err := newAPIKeyLimiters(history.APIKey{PerSecondLimit: 2, PerHourLimit: 10})
+	require.NoError(t, err)
+	var limitedCount int
+	for i := 0; i < 5; i++ {
+		limited, _, err := limiters.rateLimit(1)
+		require.NoError(t, err)
+		if limited {
+			limitedCount++
+		}
+	}
+	assert.Equal(t, 3, limitedCount)
+	// the requests rejected by the per second quota don't use the per hour quota
+	_, result, err := limiters.perHour.RateLimit("", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 8, result.Remaining)
+
+	limiters, err = newAPIKeyLimiters(history.APIKey{PerSecondLimit: 10, PerHourLimit: 2})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		limited, _, err := limiters.rateLimit(1)
+		require.NoError(t, err)
+		assert.Equal(t, i == 2, limited)
+	}
+	// the per second quota is refunded when the per hour quota is exceeded
+	_, result, err = limiters.perSecond.RateLimit("", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 8, result.Remaining)
+}
+
+func TestAPIKeyLimitersWeightAboveQuota(t *testing.T) {
+	limiters, err := newAPIKeyLimiters(history.APIKey{
+		PerSecondLimit: 5,
+		PerHourLimit:   100,
+		Weights:        history.APIKeyWeights{history.RouteClassPaths: 20},
+	})
+	require.NoError(t, err)
+
+	// a request weighing more than the per second quota uses all of it
+	limited, _, err := limiters.rateLimit(limiters.weight(history.RouteClassPaths))
+	require.NoError(t, err)
+	assert.False(t, limited)
+	limited, _, err = limiters.rateLimit(1)
+	require.NoError(t, err)
+	assert.True(t, limited)
+
+	_, result, err := limiters.perHour.RateLimit("", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 80, result.Remaining)
+}
+
+func TestAPIKeyRateLimiterAnonymousQuota(t *testing.T) {
+	keys := []history.APIKey{}
+	httpLimiter, _, _ := newTestAPIKeyRateLimiter(t, &keys, &throttled.RateQuota{
+		MaxRate:  throttled.PerHour(10),
+		MaxBurst: 1,
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/ledgers", nil)
+	assert.Equal(t, http.StatusOK, serveRateLimited(httpLimiter, r).Code)
+	assert.Equal(t, http.StatusOK, serveRateLimited(httpLimiter, r).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serveRateLimited(httpLimiter, r).Code)
+}
+
+func TestAPIKeyRateLimiterReload(t *testing.T) {
+	keys := []history.APIKey{{ID: 1, KeyHash: history.HashAPIKey("partner"), PerHourLimit: 1}}
+	httpLimiter, limiter, _ := newTestAPIKeyRateLimiter(t, &keys, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/ledgers", nil)
+	r.Header.Set(apiKeyHeader, "partner")
+	assert.Equal(t, http.StatusOK, serveRateLimited(httpLimiter, r).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serveRateLimited(httpLimiter, r).Code)
+
+	// the state of the limiters is kept until the key is updated
+	keys[0].Name = "partner"
+	limiter.ReloadAPIKeys()
+	assert.Equal(t, http.StatusTooManyRequests, serveRateLimited(httpLimiter, r).Code)
+	keys[0].PerHourLimit = 10
+	keys[0].UpdatedAt = time.Now()
+	limiter.ReloadAPIKeys()
+	assert.Equal(t, http.StatusOK, serveRateLimited(httpLimiter, r).Code)
+
+	// the keys previously loaded are used when the keys can't be reloaded
+	*limiter = apiKeyRateLimiter{
+		loadKeys: func(ctx context.Context) ([]history.APIKey, error) {
+			return nil, errors.New("db is down")
+		},
+		keys:       limiter.keys,
+		usage:      limiter.usage,
+		loadedAt:   limiter.loadedAt,
+		mustReload: true,
+	}
+	assert.Equal(t, http.StatusOK, serveRateLimited(httpLimiter, r).Code)
+
+	_, limiter, _ = newTestAPIKeyRateLimiter(t, nil, nil)
+	_, _, err := limiter.RateLimit(limiter.Key(r), 1)
+	assert.EqualError(t, err, "could not load api keys: db is down")
+}
+
+func TestAPIKeyRateLimiterReloadDoesNotBlockRequests(t *testing.T) {
+	keys := []history.APIKey{{ID: 1, KeyHash: history.HashAPIKey("partner"), PerHourLimit: 10}}
+	httpLimiter, limiter, _ := newTestAPIKeyRateLimiter(t, &keys, nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/ledgers", nil)
+	r.Header.Set(apiKeyHeader, "partner")
+	assert.Equal(t, http.StatusOK, serveRateLimited(httpLimiter, r).Code)
+
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	limiter.loadKeys = func(ctx context.Context) ([]history.APIKey, error) {
+		close(loading)
+		<-release
+		return keys, nil
+	}
+	limiter.ReloadAPIKeys()
+
+	reloaded := make(chan int)
+	go func() {
+		reloaded <- serveRateLimited(httpLimiter, r).Code
+	}()
+	<-loading
+
+	// the keys previously loaded are used while the keys are loaded
+	assert.Equal(t, http.StatusOK, serveRateLimited(httpLimiter, r).Code)
+	close(release)
+	assert.Equal(t, http.StatusOK, <-reloaded)
+}
@@ -0,0 +1,286 @@
+package httpx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hcnet/go/services/aurora/internal/actions"
+	auroraContext "github.com/hcnet/go/services/aurora/internal/context"
+	"github.com/hcnet/go/services/aurora/internal/db2"
+	"github.com/hcnet/go/services/aurora/internal/ledger"
+	"github.com/hcnet/go/services/aurora/internal/render"
+	"github.com/hcnet/go/support/render/hal"
+	"github.com/hcnet/go/toid"
+)
+
+const (
+	// shortLivedCacheControl is the Cache-Control header of state resources
+	// and of history resources which can change with the next ledger, their
+	// max-age is about the time it takes to close a ledger.
+	shortLivedCacheControl = "public, max-age=5"
+	// immutableCacheControl is the Cache-Control header of history pages
+	// below the latest ledger.
+	immutableCacheControl = "public, max-age=86400, immutable"
+	// retainedHistoryCacheControl is the Cache-Control header of the same
+	// pages when history retention is configured, they are removed with the
+	// oldest ledgers by the reaper.
+	retainedHistoryCacheControl = "public, max-age=3600"
+)
+
+var conditionalRequestContextKey = auroraContext.CtxKey("conditional_request")
+
+// conditionalRequest is the state of a GET request of a state or history
+// resource. Responses of state resources are identified by an ETag derived
+// from the ledger of the DB snapshot they are loaded from and from the
+// identity of the request, so that requests with a matching If-None-Match
+// header are answered with 304 Not Modified without loading the resource.
+// History resources aren't loaded at a given ledger, their ETag is derived
+// from the response body.
+type conditionalRequest struct {
+	etag string
+	// history is true for history resources, false for state resources.
+	history bool
+	// retention is true when the oldest history is removed by the reaper.
+	retention bool
+	// latestLedger is the latest ingested ledger when the request started.
+	latestLedger uint32
+	// elderLedger is the oldest ledger of the history when the request
+	// started.
+	elderLedger int32
+	// immutable is set by history page handlers when the page can't change
+	// in the following ledgers.
+	immutable bool
+}
+
+func conditionalRequestFromContext(ctx context.Context) *conditionalRequest {
+	found, _ := ctx.Value(&conditionalRequestContextKey).(*conditionalRequest)
+	return found
+}
+
+func (c *conditionalRequest) setHeaders(header http.Header) {
+	header.Set("ETag", c.etag)
+	header.Add("Vary", "Accept")
+	switch {
+	case c.immutable && c.retention:
+		header.Set("Cache-Control", retainedHistoryCacheControl)
+	case c.immutable:
+		header.Set("Cache-Control", immutableCacheControl)
+	default:
+		header.Set("Cache-Control", shortLivedCacheControl)
+	}
+}
+
+// requestETag returns a strong ETag identifying the response to r at the
+// given ledger.
+func requestETag(r *http.Request, latestLedger uint32) string {
+	return `"` + strconv.FormatUint(uint64(latestLedger), 10) + "-" + hex.EncodeToString(requestHash(r, nil)) + `"`
+}
+
+// responseETag returns a strong ETag identifying the response to r with the
+// given body.
+func responseETag(r *http.Request, body []byte) string {
+	return `"` + hex.EncodeToString(requestHash(r, body)) + `"`
+}
+
+func requestHash(r *http.Request, body []byte) []byte {
+	hash := sha256.New()
+	for _, part := range []string{
+		render.Negotiate(r),
+		r.URL.Path,
+		// Encode sorts the parameters
+		r.URL.Query().Encode(),
+		r.Header.Get("Accept-Encoding"),
+	} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	hash.Write(body)
+	return hash.Sum(nil)[:16]
+}
+
+// etagMatches implements the weak comparison of If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// supportsConditionalRequests returns true if h renders a state or history
+// resource which only changes with the ingested ledgers.
+func supportsConditionalRequests(h http.Handler) bool {
+	described, ok := h.(describedHandler)
+	if !ok {
+		return false
+	}
+	switch described.describe().action.(type) {
+	case actions.FindPathsHandler, actions.FindFixedPathsHandler:
+		// paths are found in the in-memory order book graph which can be
+		// behind the latest ingested ledger
+		return false
+	}
+	return true
+}
+
+// conditionalHandler serves the GET requests of state and history resources
+// with an ETag and a Cache-Control header. The latest ledger of state
+// resources is the one of the DB snapshot of the request, set in the
+// Latest-Ledger header by StateMiddleware. History resources are read
+// outside of a snapshot, so their responses are buffered and identified by
+// the hash of their body; CSV and NDJSON exports, which are streamed, are
+// served without ETag.
+type conditionalHandler struct {
+	next        http.Handler
+	ledgerState *ledger.State
+	// retention is true when history retention is configured.
+	retention bool
+}
+
+func (handler conditionalHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet || render.Negotiate(r) == render.MimeEventStream {
+		handler.next.ServeHTTP(w, r)
+		return
+	}
+
+	request := &conditionalRequest{history: handler.ledgerState != nil, retention: handler.retention}
+	if request.history {
+		handler.serveHistory(w, r, request)
+		return
+	}
+
+	latestLedger, err := strconv.ParseUint(w.Header().Get(actions.LastLedgerHeaderName), 10, 32)
+	if err != nil {
+		handler.next.ServeHTTP(w, r)
+		return
+	}
+	request.latestLedger = uint32(latestLedger)
+	request.etag = requestETag(r, request.latestLedger)
+
+	if etagMatches(r.Header.Get("If-None-Match"), request.etag) {
+		request.setHeaders(w.Header())
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	handler.next.ServeHTTP(
+		&conditionalResponseWriter{ResponseWriter: w, request: request},
+		r.WithContext(context.WithValue(r.Context(), &conditionalRequestContextKey, request)),
+	)
+}
+
+func (handler conditionalHandler) serveHistory(w http.ResponseWriter, r *http.Request, request *conditionalRequest) {
+	status := handler.ledgerState.CurrentStatus()
+	latestLedger := status.HistoryLatest
+	if mimeType := render.Negotiate(r); latestLedger <= 0 || mimeType == render.MimeCSV || mimeType == render.MimeNDJSON {
+		handler.next.ServeHTTP(w, r)
+		return
+	}
+	request.latestLedger = uint32(latestLedger)
+	request.elderLedger = status.HistoryElder
+
+	buffered := newBufferedResponseWriter()
+	actions.SetLastLedgerHeader(buffered, request.latestLedger)
+	handler.next.ServeHTTP(
+		buffered,
+		r.WithContext(context.WithValue(r.Context(), &conditionalRequestContextKey, request)),
+	)
+
+	for key, values := range buffered.Header() {
+		w.Header()[key] = values
+	}
+	if buffered.status == http.StatusOK {
+		request.etag = responseETag(r, buffered.body.Bytes())
+		request.setHeaders(w.Header())
+		if etagMatches(r.Header.Get("If-None-Match"), request.etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	if buffered.status != 0 {
+		w.WriteHeader(buffered.status)
+	}
+	w.Write(buffered.body.Bytes())
+}
+
+// conditionalResponseWriter sets the caching headers of successful
+// responses.
+type conditionalResponseWriter struct {
+	http.ResponseWriter
+	request     *conditionalRequest
+	wroteHeader bool
+}
+
+func (w *conditionalResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if status == http.StatusOK {
+			w.request.setHeaders(w.Header())
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Flush implements http.Flusher for the responses streamed in several
+// writes.
+func (w *conditionalResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *conditionalResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// isImmutableHistoryPage returns true if a page of history records can't
+// change in the ledgers following latestLedger: records of new ledgers are
+// after all the existing records so full ascending pages and descending pages
+// starting at or below the latest ledger are immutable. Records of the elder
+// ledger are the next ones removed by the reaper and records after the latest
+// ledger are being reingested, so pages with records outside of
+// (elderLedger, latestLedger] are not immutable.
+func isImmutableHistoryPage(page hal.Page, records []hal.Pageable, elderLedger int32, latestLedger uint32) bool {
+	if len(records) == 0 {
+		return false
+	}
+	for _, record := range records {
+		sequence, ok := pagingTokenLedger(record.PagingToken())
+		if !ok || sequence <= elderLedger || sequence > int32(latestLedger) {
+			return false
+		}
+	}
+
+	switch page.Order {
+	case db2.OrderAscending:
+		return uint64(len(records)) == page.Limit
+	case db2.OrderDescending:
+		if page.Cursor == "" {
+			return false
+		}
+		sequence, ok := pagingTokenLedger(page.Cursor)
+		return ok && sequence <= int32(latestLedger)
+	default:
+		return false
+	}
+}
+
+// pagingTokenLedger returns the ledger of a paging token starting with a
+// total order id, false if it doesn't start with one.
+func pagingTokenLedger(token string) (int32, bool) {
+	pageQuery := db2.PageQuery{Cursor: token}
+	id, _, err := pageQuery.CursorInt64Pair("-")
+	if err != nil {
+		return 0, false
+	}
+	return toid.Parse(id).LedgerSequence, true
+}
@@ -0,0 +1,189 @@
+package httpx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hcnet/go/services/aurora/internal/actions"
+	"github.com/hcnet/go/services/aurora/internal/ledger"
+	"github.com/hcnet/go/support/render/hal"
+	"github.com/hcnet/go/support/render/problem"
+	"github.com/hcnet/go/toid"
+)
+
+func TestRequestETag(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/ledgers?order=desc&limit=2", nil)
+	etag := requestETag(r, 10)
+	assert.Regexp(t, `^"10-[0-9a-f]{32}"$`, etag)
+
+	// the order of the parameters doesn't matter
+	assert.Equal(t, etag, requestETag(httptest.NewRequest(http.MethodGet, "/ledgers?limit=2&order=desc", nil), 10))
+
+	assert.NotEqual(t, etag, requestETag(r, 11))
+	assert.NotEqual(t, etag, requestETag(httptest.NewRequest(http.MethodGet, "/ledgers?order=desc&limit=3", nil), 10))
+	csv := httptest.NewRequest(http.MethodGet, "/ledgers?order=desc&limit=2", nil)
+	csv.Header.Set("Accept", "text/csv")
+	assert.NotEqual(t, etag, requestETag(csv, 10))
+	gzip := httptest.NewRequest(http.MethodGet, "/ledgers?order=desc&limit=2", nil)
+	gzip.Header.Set("Accept-Encoding", "gzip")
+	assert.NotEqual(t, etag, requestETag(gzip, 10))
+}
+
+func TestETagMatches(t *testing.T) {
+	assert.True(t, etagMatches(`"1-a"`, `"1-a"`))
+	assert.True(t, etagMatches(`"0-b", W/"1-a"`, `"1-a"`))
+	assert.True(t, etagMatches(`*`, `"1-a"`))
+	assert.False(t, etagMatches(``, `"1-a"`))
+	assert.False(t, etagMatches(`"2-a"`, `"1-a"`))
+}
+
+type testPageRecord string
+
+func (r testPageRecord) PagingToken() string {
+	return string(r)
+}
+
+func TestIsImmutableHistoryPage(t *testing.T) {
+	cursor := func(ledger int32) string {
+		return toid.New(ledger, 1, 1).String()
+	}
+	records := func(ledgers ...int32) []hal.Pageable {
+		var result []hal.Pageable
+		for _, ledger := range ledgers {
+			result = append(result, testPageRecord(cursor(ledger)))
+		}
+		return result
+	}
+	for _, testCase := range []struct {
+		name      string
+		page      hal.Page
+		records   []hal.Pageable
+		immutable bool
+	}{
+		{"full ascending page", hal.Page{Order: "asc", Limit: 2}, records(4, 5), true},
+		{"partial ascending page", hal.Page{Order: "asc", Limit: 2, Cursor: cursor(3)}, records(4), false},
+		{"empty ascending page", hal.Page{Order: "asc", Limit: 0}, nil, false},
+		{"full ascending page of the elder ledger", hal.Page{Order: "asc", Limit: 2}, records(3, 4), false},
+		{"full ascending page below the elder ledger", hal.Page{Order: "asc", Limit: 2}, records(1, 4), false},
+		{"full ascending page after the latest ledger", hal.Page{Order: "asc", Limit: 2}, records(10, 11), false},
+		{"full ascending page with other paging tokens", hal.Page{Order: "asc", Limit: 1}, []hal.Pageable{testPageRecord("native")}, false},
+		{"latest descending page", hal.Page{Order: "desc", Limit: 2}, records(10, 9), false},
+		{"descending page below the latest ledger", hal.Page{Order: "desc", Limit: 2, Cursor: cursor(10)}, records(9), true},
+		{"descending page reaching the elder ledger", hal.Page{Order: "desc", Limit: 2, Cursor: cursor(5)}, records(4, 3), false},
+		{"descending page after the latest ledger", hal.Page{Order: "desc", Limit: 2, Cursor: cursor(11)}, records(10, 9), false},
+		{"descending page of effects", hal.Page{Order: "desc", Limit: 2, Cursor: cursor(10) + "-3"}, records(10, 9), true},
+		{"descending page with invalid cursor", hal.Page{Order: "desc", Limit: 2, Cursor: "invalid"}, records(10, 9), false},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.immutable, isImmutableHistoryPage(testCase.page, testCase.records, 3, 10))
+		})
+	}
+}
+
+func TestConditionalHandler(t *testing.T) {
+	ledgerState := &ledger.State{}
+	ledgerState.SetAuroraStatus(ledger.AuroraStatus{HistoryLatest: 10})
+
+	served := 0
+	immutable := false
+	body := "{}"
+	handler := conditionalHandler{
+		next: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			served++
+			if r.URL.Query().Get("fail") != "" {
+				problem.Render(r.Context(), w, problem.NotFound)
+				return
+			}
+			if conditional := conditionalRequestFromContext(r.Context()); conditional != nil {
+				conditional.immutable = immutable
+			}
+			w.Write([]byte(body))
+		}),
+		ledgerState: ledgerState,
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ledgers", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, served)
+	assert.Equal(t, "{}", w.Body.String())
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, shortLivedCacheControl, w.Header().Get("Cache-Control"))
+	assert.Equal(t, "10", w.Header().Get(actions.LastLedgerHeaderName))
+
+	// the body isn't sent when it has not changed
+	r := httptest.NewRequest(http.MethodGet, "/ledgers", nil)
+	r.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, 2, served)
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+	assert.Empty(t, w.Body.String())
+
+	// the ETag is derived from the body rather than from the latest ledger,
+	// which can be behind the ledger the records were read at
+	ledgerState.SetAuroraStatus(ledger.AuroraStatus{HistoryLatest: 11})
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	body = `{"id":1}`
+	immutable = true
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, body, w.Body.String())
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	assert.Equal(t, immutableCacheControl, w.Header().Get("Cache-Control"))
+
+	// pages are removed by the reaper when history retention is configured
+	handler.retention = true
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, retainedHistoryCacheControl, w.Header().Get("Cache-Control"))
+
+	// errors are not cached
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ledgers?fail=true", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+	assert.Empty(t, w.Header().Get("Cache-Control"))
+
+	// streams and exports are not conditional
+	for _, accept := range []string{"text/event-stream", "text/csv", "application/x-ndjson"} {
+		r = httptest.NewRequest(http.MethodGet, "/ledgers", nil)
+		r.Header.Set("Accept", accept)
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Empty(t, w.Header().Get("ETag"))
+	}
+}
+
+func TestConditionalHandlerState(t *testing.T) {
+	handler := conditionalHandler{next: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	})}
+
+	// the latest ledger of state resources is set by StateMiddleware
+	w := httptest.NewRecorder()
+	actions.SetLastLedgerHeader(w, 5)
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/accounts", nil))
+	assert.Equal(t, requestETag(httptest.NewRequest(http.MethodGet, "/accounts", nil), 5), w.Header().Get("ETag"))
+	assert.Equal(t, shortLivedCacheControl, w.Header().Get("Cache-Control"))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/accounts", nil))
+	assert.Empty(t, w.Header().Get("ETag"))
+}
+
+func TestSupportsConditionalRequests(t *testing.T) {
+	assert.True(t, supportsConditionalRequests(ObjectActionHandler{actions.GetLedgerByIDHandler{}}))
+	assert.True(t, supportsConditionalRequests(restPageHandler(nil, actions.GetOffersHandler{})))
+	assert.False(t, supportsConditionalRequests(ObjectActionHandler{actions.FindPathsHandler{}}))
+	assert.False(t, supportsConditionalRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+}
@@ -0,0 +1,336 @@
+package httpx
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/hcnet/go/services/aurora/internal/actions"
+	"github.com/hcnet/go/services/aurora/internal/db2"
+	"github.com/hcnet/go/services/aurora/internal/render"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/support/log"
+	"github.com/hcnet/go/support/render/hal"
+	"github.com/hcnet/go/support/render/problem"
+)
+
+// maxExportLimit is the maximum number of records of a CSV or NDJSON export,
+// exports are loaded in pages of at most db2.MaxPageSize records.
+const maxExportLimit = 10000
+
+// exportDetailsColumn is the last column of the CSV exports of operations and
+// effects, it contains the attributes which are specific to the type of the
+// record as a JSON object.
+const exportDetailsColumn = "details"
+
+// exportLayout is the flattened column layout of the CSV export of a
+// resource type. Nested objects are flattened into columns named after the
+// path of their attributes separated by dots, arrays are encoded as JSON.
+type exportLayout struct {
+	columns []string
+	// details is true when the attributes which are not in columns are
+	// exported in the details column.
+	details bool
+}
+
+var (
+	operationExportLayout = exportLayout{
+		columns: []string{
+			"id", "paging_token", "transaction_successful", "transaction_hash",
+			"created_at", "type", "type_i", "source_account", "source_account_muxed",
+			"sponsor", "from", "to", "funder", "account", "amount", "starting_balance",
+			"asset_type", "asset_code", "asset_issuer", "source_amount",
+			"source_asset_type", "source_asset_code", "source_asset_issuer",
+		},
+		details: true,
+	}
+	effectExportLayout = exportLayout{
+		columns: []string{
+			"id", "paging_token", "created_at", "type", "type_i", "account",
+			"account_muxed", "amount", "starting_balance", "asset_type",
+			"asset_code", "asset_issuer",
+		},
+		details: true,
+	}
+	tradeExportLayout = exportLayout{
+		columns: []string{
+			"id", "paging_token", "ledger_close_time", "trade_type", "offer_id",
+			"liquidity_pool_fee_bp", "base_offer_id", "base_liquidity_pool_id",
+			"base_account", "base_amount", "base_asset_type", "base_asset_code",
+			"base_asset_issuer", "counter_offer_id", "counter_liquidity_pool_id",
+			"counter_account", "counter_amount", "counter_asset_type",
+			"counter_asset_code", "counter_asset_issuer", "base_is_seller",
+			"price.n", "price.d",
+		},
+	}
+	assetHolderExportLayout = exportLayout{
+		columns: []string{
+			"id", "paging_token", "type", "balance", "is_authorized",
+			"is_authorized_to_maintain_liabilities", "is_clawback_enabled",
+		},
+	}
+	transactionExportLayout = exportLayout{
+		columns: []string{
+			"id", "paging_token", "successful", "hash", "ledger", "created_at",
+			"source_account", "account_muxed", "source_account_sequence",
+			"fee_account", "fee_account_muxed", "fee_charged", "max_fee",
+			"operation_count", "memo_type", "memo", "memo_bytes", "signatures",
+			"valid_after", "valid_before", "preconditions.timebounds.min_time",
+			"preconditions.timebounds.max_time", "preconditions.ledgerbounds.min_ledger",
+			"preconditions.ledgerbounds.max_ledger", "preconditions.min_account_sequence",
+			"preconditions.min_account_sequence_age", "preconditions.min_account_sequence_ledger_gap",
+			"preconditions.extra_signers", "fee_bump_transaction.hash",
+			"fee_bump_transaction.signatures", "inner_transaction.hash",
+			"inner_transaction.signatures", "inner_transaction.max_fee",
+			"envelope_xdr", "result_xdr", "result_meta_xdr", "fee_meta_xdr",
+		},
+	}
+)
+
+// exportLayoutForAction returns the CSV layout of the records of a page
+// action, the records of the actions without layout can't be exported.
+func exportLayoutForAction(action pageAction) (exportLayout, bool) {
+	switch action.(type) {
+	case actions.GetOperationsHandler:
+		return operationExportLayout, true
+	case actions.GetEffectsHandler:
+		return effectExportLayout, true
+	case actions.GetTradesHandler:
+		return tradeExportLayout, true
+	case actions.GetTransactionsHandler:
+		return transactionExportLayout, true
+	case actions.GetAssetHoldersHandler:
+		return assetHolderExportLayout, true
+	default:
+		return exportLayout{}, false
+	}
+}
+
+func (layout exportLayout) header() []string {
+	if layout.details {
+		return append(append([]string{}, layout.columns...), exportDetailsColumn)
+	}
+	return layout.columns
+}
+
+// row returns the CSV row of record.
+func (layout exportLayout) row(record hal.Pageable) ([]string, error) {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encode record")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var attributes map[string]interface{}
+	if err = decoder.Decode(&attributes); err != nil {
+		return nil, errors.Wrap(err, "could not decode record")
+	}
+	delete(attributes, "_links")
+
+	flattened := map[string]interface{}{}
+	flattenExportAttributes("", attributes, flattened)
+
+	row := make([]string, 0, len(layout.columns)+1)
+	for _, column := range layout.columns {
+		value, err := exportValue(flattened[column])
+		if err != nil {
+			return nil, err
+		}
+		row = append(row, value)
+		delete(flattened, column)
+	}
+
+	if layout.details {
+		details := ""
+		if len(flattened) > 0 {
+			encoded, err = json.Marshal(flattened)
+			if err != nil {
+				return nil, errors.Wrap(err, "could not encode record details")
+			}
+			details = string(encoded)
+		}
+		row = append(row, details)
+	}
+	return row, nil
+}
+
+func flattenExportAttributes(prefix string, attributes map[string]interface{}, flattened map[string]interface{}) {
+	for key, value := range attributes {
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenExportAttributes(prefix+key+".", nested, flattened)
+			continue
+		}
+		flattened[prefix+key] = value
+	}
+}
+
+func exportValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", errors.Wrap(err, "could not encode record attribute")
+		}
+		return string(encoded), nil
+	}
+}
+
+// recordExporter writes the exported records in the negotiated format.
+type recordExporter interface {
+	writeHeader() error
+	writeRecords(records []hal.Pageable) error
+}
+
+type csvRecordExporter struct {
+	writer *csv.Writer
+	layout exportLayout
+}
+
+func (exporter csvRecordExporter) writeHeader() error {
+	exporter.writer.Write(exporter.layout.header())
+	exporter.writer.Flush()
+	return exporter.writer.Error()
+}
+
+func (exporter csvRecordExporter) writeRecords(records []hal.Pageable) error {
+	for _, record := range records {
+		row, err := exporter.layout.row(record)
+		if err != nil {
+			return err
+		}
+		if err = exporter.writer.Write(row); err != nil {
+			return err
+		}
+	}
+	exporter.writer.Flush()
+	return exporter.writer.Error()
+}
+
+type ndjsonRecordExporter struct {
+	encoder *json.Encoder
+}
+
+func (exporter ndjsonRecordExporter) writeHeader() error {
+	return nil
+}
+
+func (exporter ndjsonRecordExporter) writeRecords(records []hal.Pageable) error {
+	for _, record := range records {
+		if err := exporter.encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getExportLimit returns the number of records to export, exports accept
+// larger limits than pages because they are loaded in several pages.
+func getExportLimit(r *http.Request) (uint64, error) {
+	limit := r.URL.Query().Get(actions.ParamLimit)
+	if limit == "" {
+		return db2.DefaultPageSize, nil
+	}
+	parsed, err := strconv.ParseUint(limit, 10, 64)
+	if err != nil || parsed == 0 || parsed > maxExportLimit {
+		return 0, problem.MakeInvalidFieldProblem(
+			actions.ParamLimit,
+			errors.Errorf("invalid limit: value must be between 1 and %d for exports", maxExportLimit),
+		)
+	}
+	return parsed, nil
+}
+
+// exportPageRequest returns a copy of r requesting the page of limit records
+// following cursor.
+func exportPageRequest(r *http.Request, cursor string, limit uint64) *http.Request {
+	pageRequest := r.Clone(r.Context())
+	query := pageRequest.URL.Query()
+	query.Set(actions.ParamLimit, strconv.FormatUint(limit, 10))
+	if cursor != "" {
+		query.Set(actions.ParamCursor, cursor)
+		pageRequest.Header.Del("Last-Event-ID")
+	}
+	pageRequest.URL.RawQuery = query.Encode()
+	pageRequest.Form = nil
+	return pageRequest
+}
+
+// renderExport streams the records of the collection as CSV or NDJSON. The
+// records are loaded in pages which are written and flushed one at a time so
+// that large exports aren't held in memory. When the export stops at the
+// limit, the Link trailer of the response points to the export of the
+// following records, like the next link of a page.
+func (handler pageActionHandler) renderExport(w http.ResponseWriter, r *http.Request, mimeType string, layout exportLayout) {
+	limit, err := getExportLimit(r)
+	if err != nil {
+		problem.Render(r.Context(), w, err)
+		return
+	}
+
+	var exporter recordExporter
+	if mimeType == render.MimeCSV {
+		exporter = csvRecordExporter{writer: csv.NewWriter(w), layout: layout}
+	} else {
+		exporter = ndjsonRecordExporter{encoder: json.NewEncoder(w)}
+	}
+	flusher, _ := w.(http.Flusher)
+
+	cursor := ""
+	for exported := uint64(0); exported < limit; {
+		first := exported == 0
+		pageLimit := limit - exported
+		if pageLimit > db2.MaxPageSize {
+			pageLimit = db2.MaxPageSize
+		}
+
+		records, err := handler.action.GetResourcePage(w, exportPageRequest(r, cursor, pageLimit))
+		if err != nil {
+			if first {
+				problem.Render(r.Context(), w, err)
+			} else {
+				// the status of the response has already been sent
+				log.Ctx(r.Context()).WithField("err", err).Error("could not load exported records")
+			}
+			return
+		}
+
+		if first {
+			w.Header().Set("Content-Type", mimeType)
+			w.Header().Set("Trailer", "Link")
+			if err = exporter.writeHeader(); err != nil {
+				log.Ctx(r.Context()).WithField("err", err).Warn("could not write export")
+				return
+			}
+		}
+		if err = exporter.writeRecords(records); err != nil {
+			log.Ctx(r.Context()).WithField("err", err).Warn("could not write export")
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if uint64(len(records)) < pageLimit {
+			return
+		}
+		exported += uint64(len(records))
+		cursor = records[len(records)-1].PagingToken()
+	}
+
+	next := actions.FullURL(r.Context())
+	query := next.Query()
+	query.Set(actions.ParamCursor, cursor)
+	next.RawQuery = query.Encode()
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+}
@@ -0,0 +1,151 @@
+package httpx
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/protocols/aurora/base"
+	"github.com/hcnet/go/protocols/aurora/operations"
+	"github.com/hcnet/go/services/aurora/internal/actions"
+	auroraContext "github.com/hcnet/go/services/aurora/internal/context"
+	"github.com/hcnet/go/services/aurora/internal/ledger"
+	"github.com/hcnet/go/services/aurora/internal/render"
+)
+
+func TestExportLayoutRow(t *testing.T) {
+	payment := operations.Payment{
+		Base: operations.Base{
+			ID:                    "12884905985",
+			PT:                    "12884905985",
+			TransactionSuccessful: true,
+			SourceAccount:         "GA",
+			Type:                  "payment",
+			TypeI:                 1,
+			LedgerCloseTime:       time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+			TransactionHash:       "abc",
+		},
+		Asset:  base.Asset{Type: "native"},
+		From:   "GA",
+		To:     "GB",
+		Amount: "10.0000000",
+	}
+	payment.Links.Self.Href = "/operations/12884905985"
+
+	row, err := operationExportLayout.row(payment)
+	require.NoError(t, err)
+	require.Len(t, row, len(operationExportLayout.header()))
+	values := map[string]string{}
+	for i, column := range operationExportLayout.header() {
+		values[column] = row[i]
+	}
+	assert.Equal(t, "12884905985", values["paging_token"])
+	assert.Equal(t, "true", values["transaction_successful"])
+	assert.Equal(t, "1", values["type_i"])
+	assert.Equal(t, "2022-01-02T03:04:05Z", values["created_at"])
+	assert.Equal(t, "GB", values["to"])
+	assert.Equal(t, "native", values["asset_type"])
+	assert.Equal(t, "", values["asset_code"])
+	assert.Equal(t, "", values[exportDetailsColumn])
+
+	bumpSequence := operations.BumpSequence{Base: payment.Base, BumpTo: "100"}
+	bumpSequence.Transaction = &aurora.Transaction{Hash: "abc", Signatures: []string{"s1", "s2"}}
+	row, err = operationExportLayout.row(bumpSequence)
+	require.NoError(t, err)
+	var details map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(row[len(row)-1]), &details))
+	assert.Equal(t, "100", details["bump_to"])
+	assert.Equal(t, "abc", details["transaction.hash"])
+	assert.Equal(t, []interface{}{"s1", "s2"}, details["transaction.signatures"])
+
+	trade := aurora.Trade{ID: "1-1", PT: "1-1", Price: aurora.TradePrice{N: 3, D: 2}}
+	row, err = tradeExportLayout.row(trade)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"3", "2"}, row[len(row)-2:])
+
+	isAuthorized := true
+	holder := aurora.AssetHolder{ID: "GA", PT: "10-GA", Type: "account", Balance: "1.0000000", IsAuthorized: &isAuthorized}
+	row, err = assetHolderExportLayout.row(holder)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GA", "10-GA", "account", "1.0000000", "true", "", ""}, row)
+}
+
+func exportRequest(t *testing.T, accept, query string) *http.Request {
+	r := streamRequest(t, query)
+	r.Header.Set("Accept", accept)
+	return r.WithContext(auroraContext.RequestContext(r.Context(), nil, r))
+}
+
+func TestRenderExport(t *testing.T) {
+	objects := make([]string, 450)
+	for i := range objects {
+		objects[i] = fmt.Sprintf("object%d", i)
+	}
+	handler := pageActionHandler{
+		action: &testPageAction{
+			objects:      map[uint32][]string{3: objects},
+			ledgerSource: ledger.NewTestingSource(3),
+		},
+	}
+	layout := exportLayout{columns: []string{"value"}}
+
+	// exports are loaded in several pages
+	w := httptest.NewRecorder()
+	handler.renderExport(w, exportRequest(t, render.MimeCSV, "limit=420"), render.MimeCSV, layout)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, render.MimeCSV, w.Header().Get("Content-Type"))
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 421)
+	assert.Equal(t, []string{"value"}, rows[0])
+	assert.Equal(t, []string{"object0"}, rows[1])
+	assert.Equal(t, []string{"object419"}, rows[420])
+	// the export of the following records is linked in a trailer
+	next := w.Result().Trailer.Get("Link")
+	assert.Contains(t, next, "cursor=420")
+	assert.Contains(t, next, "limit=420")
+	assert.True(t, strings.HasSuffix(next, `>; rel="next"`))
+
+	// exports end with the collection
+	w = httptest.NewRecorder()
+	handler.renderExport(w, exportRequest(t, render.MimeNDJSON, "limit=10000&cursor=440"), render.MimeNDJSON, layout)
+	assert.Equal(t, render.MimeNDJSON, w.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	require.Len(t, lines, 10)
+	assert.Equal(t, `{"value":"object440"}`, lines[0])
+	assert.Equal(t, `{"value":"object449"}`, lines[9])
+	assert.Empty(t, w.Result().Trailer.Get("Link"))
+
+	w = httptest.NewRecorder()
+	handler.renderExport(w, exportRequest(t, render.MimeCSV, "limit=10001"), render.MimeCSV, layout)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestExportableCollections(t *testing.T) {
+	for _, action := range []pageAction{
+		actions.GetOperationsHandler{OnlyPayments: true},
+		actions.GetEffectsHandler{},
+		actions.GetTradesHandler{},
+		actions.GetTransactionsHandler{},
+		actions.GetAssetHoldersHandler{},
+	} {
+		_, ok := exportLayoutForAction(action)
+		assert.True(t, ok)
+	}
+	_, ok := exportLayoutForAction(actions.GetLedgersHandler{})
+	assert.False(t, ok)
+
+	// other collections can't be exported
+	w := httptest.NewRecorder()
+	restPageHandler(nil, actions.GetLedgersHandler{}).ServeHTTP(w, exportRequest(t, render.MimeCSV, ""))
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+}
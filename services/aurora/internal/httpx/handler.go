@@ -4,6 +4,9 @@ import (
 	"database/sql"
 	"io"
 	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
 
 	"github.com/hcnet/go/services/aurora/internal/actions"
 	auroraContext "github.com/hcnet/go/services/aurora/internal/context"
@@ -147,6 +150,97 @@ func (handler streamableObjectActionHandler) renderStream(
 	)
 }
 
+// streamablePathsAction is a path finding action which can tell if the
+// payment paths of a request changed since a given ledger.
+type streamablePathsAction interface {
+	objectAction
+	ChangedSince(r *http.Request, ledger uint32) (bool, error)
+}
+
+// streamablePathsHandler serves the payment paths of a path finding action and
+// streams them whenever they change. After each ledger, the payment paths are
+// only searched again if the offers and liquidity pools they can trade with
+// were updated in the in-memory order book.
+type streamablePathsHandler struct {
+	action        streamablePathsAction
+	streamHandler sse.StreamHandler
+	limit         int
+}
+
+func (handler streamablePathsHandler) ServeHTTP(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	switch render.Negotiate(r) {
+	case render.MimeHal, render.MimeJSON:
+		ObjectActionHandler{Action: handler.action}.ServeHTTP(w, r)
+		return
+	case render.MimeEventStream:
+		handler.renderStream(w, r)
+		return
+	}
+
+	problem.Render(r.Context(), w, hProblem.NotAcceptable)
+}
+
+// pathsHeader collects the headers set by a path finding action while
+// streaming, the Latest-Ledger header is the ledger of the in-memory order book
+// in which the payment paths were found.
+type pathsHeader http.Header
+
+func (h pathsHeader) Header() http.Header {
+	return http.Header(h)
+}
+
+func (handler streamablePathsHandler) renderStream(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	var lastResponse interface{}
+	var lastLedger uint32
+	limit := handler.limit
+	if limit == 0 {
+		limit = defaultObjectStreamLimit
+	}
+
+	findPaths := func() ([]sse.Event, error) {
+		if lastResponse != nil {
+			changed, err := handler.action.ChangedSince(r, lastLedger)
+			if err != nil {
+				return nil, err
+			}
+			if !changed {
+				return []sse.Event{}, nil
+			}
+		}
+
+		header := pathsHeader{}
+		response, err := handler.action.GetResource(header, r)
+		if err != nil {
+			return nil, err
+		}
+		// the paths are searched again after the next ledger if the header
+		// is missing
+		ledger, _ := strconv.ParseUint(http.Header(header).Get(actions.LastLedgerHeaderName), 10, 32)
+		lastLedger = uint32(ledger)
+
+		if lastResponse == nil || !reflect.DeepEqual(lastResponse, response) {
+			lastResponse = response
+			return []sse.Event{{Data: response}}, nil
+		}
+		return []sse.Event{}, nil
+	}
+
+	handler.streamHandler.ServeStream(
+		w,
+		r,
+		limit,
+		// the accounts of the request are loaded in the same transaction
+		// to check if the payment paths changed and to find them again
+		repeatableReadStream(r, findPaths),
+	)
+}
+
 type pageAction interface {
 	GetResourcePage(w actions.HeaderWriter, r *http.Request) ([]hal.Pageable, error)
 }
@@ -196,7 +290,8 @@ func streamableHistoryPageHandler(
 }
 
 func (handler pageActionHandler) renderPage(w http.ResponseWriter, r *http.Request) {
-	records, err := handler.action.GetResourcePage(w, r)
+	ctx, scanned := actions.WithScannedCursor(r.Context())
+	records, err := handler.action.GetResourcePage(w, r.WithContext(ctx))
 	if err != nil {
 		problem.Render(r.Context(), w, err)
 		return
@@ -208,6 +303,13 @@ func (handler pageActionHandler) renderPage(w http.ResponseWriter, r *http.Reque
 		problem.Render(r.Context(), w, err)
 		return
 	}
+	if scanned.PagingToken != "" {
+		continuePageAfter(&page, scanned.PagingToken)
+	}
+
+	if conditional := conditionalRequestFromContext(r.Context()); conditional != nil && conditional.history {
+		conditional.immutable = isImmutableHistoryPage(page, records, conditional.elderLedger, conditional.latestLedger)
+	}
 
 	httpjson.Render(
 		w,
@@ -274,6 +376,11 @@ func (handler pageActionHandler) ServeHTTP(w http.ResponseWriter, r *http.Reques
 			handler.renderStream(w, r)
 			return
 		}
+	case render.MimeCSV, render.MimeNDJSON:
+		if layout, ok := exportLayoutForAction(handler.action); ok {
+			handler.renderExport(w, r, render.Negotiate(r), layout)
+			return
+		}
 	}
 
 	problem.Render(r.Context(), w, hProblem.NotAcceptable)
@@ -306,6 +413,19 @@ func buildPage(ledgerState *ledger.State, r *http.Request, records []hal.Pageabl
 	return page, nil
 }
 
+// continuePageAfter sets the cursor of the next link of page, it's used when
+// the page action stops scanning records before the page is full.
+func continuePageAfter(page *hal.Page, cursor string) {
+	next, err := url.Parse(page.Links.Next.Href)
+	if err != nil {
+		return
+	}
+	query := next.Query()
+	query.Set(actions.ParamCursor, cursor)
+	next.RawQuery = query.Encode()
+	page.Links.Next = hal.NewLink(next.String())
+}
+
 type rawAction interface {
 	WriteRawResponse(w io.Writer, r *http.Request) error
 }
@@ -319,12 +439,19 @@ func HandleRaw(action rawAction) http.HandlerFunc {
 }
 
 func WrapRaw(next http.Handler, action rawAction) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch render.Negotiate(r) {
-		case render.MimeRaw:
-			HandleRaw(action).ServeHTTP(w, r)
-		default:
-			next.ServeHTTP(w, r)
-		}
-	})
+	return rawActionHandler{next: next, action: action}
+}
+
+type rawActionHandler struct {
+	next   http.Handler
+	action rawAction
+}
+
+func (handler rawActionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch render.Negotiate(r) {
+	case render.MimeRaw:
+		HandleRaw(handler.action).ServeHTTP(w, r)
+	default:
+		handler.next.ServeHTTP(w, r)
+	}
 }
@@ -0,0 +1,26 @@
+package httpx
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hcnet/go/support/render/hal"
+)
+
+func TestContinuePageAfter(t *testing.T) {
+	fullURL, err := url.Parse("https://aurora.example/claimable_balances?claimant=GA&claimable_now=true&limit=2")
+	assert.NoError(t, err)
+	page := hal.Page{Order: "asc", Limit: 2}
+	page.FullURL = fullURL
+	page.PopulateLinks()
+
+	continuePageAfter(&page, "10-abc")
+	next, err := url.Parse(page.Links.Next.Href)
+	assert.NoError(t, err)
+	assert.Equal(t, "/claimable_balances", next.Path)
+	assert.Equal(t, "10-abc", next.Query().Get("cursor"))
+	assert.Equal(t, "true", next.Query().Get("claimable_now"))
+	assert.Equal(t, "2", next.Query().Get("limit"))
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
+	"github.com/gorilla/websocket"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/hcnet/go/services/aurora/internal/actions"
@@ -92,6 +93,13 @@ func loggerMiddleware(serverMetrics *ServerMetrics) func(next http.Handler) http
 func timeoutMiddleware(timeout time.Duration) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
+			// WebSocket connections stay open until clients close them,
+			// their subscriptions are subject to the timeout.
+			if websocket.IsWebSocketUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			mw := newWrapResponseWriter(w, r)
 			ctx, cancel := context.WithTimeout(r.Context(), timeout)
 			defer func() {
@@ -231,9 +239,14 @@ func recoverMiddleware(h http.Handler) http.Handler {
 
 // NewHistoryMiddleware adds session to the request context and ensures Aurora
 // is not in a stale state, which is when the difference between latest core
-// ledger and latest history ledger is higher than the given threshold
-func NewHistoryMiddleware(ledgerState *ledger.State, staleThreshold int32, session db.SessionInterface) func(http.Handler) http.Handler {
+// ledger and latest history ledger is higher than the given threshold.
+// historyRetention is true when the oldest history is removed by the reaper,
+// history pages are then cached for a shorter time.
+func NewHistoryMiddleware(ledgerState *ledger.State, staleThreshold int32, historyRetention bool, session db.SessionInterface) func(http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
+		if supportsConditionalRequests(h) {
+			h = conditionalHandler{next: h, ledgerState: ledgerState, retention: historyRetention}
+		}
 
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
@@ -267,6 +280,25 @@ func NewHistoryMiddleware(ledgerState *ledger.State, staleThreshold int32, sessi
 	}
 }
 
+// NewAdminMiddleware adds session to the request context of the internal
+// admin endpoints. Unlike NewHistoryMiddleware it doesn't reject requests when
+// history is stale nor handles conditional requests, admin endpoints manage
+// configuration which doesn't depend on ingestion.
+func NewAdminMiddleware(session db.SessionInterface) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			chiRoute := chi.RouteContext(ctx)
+			if chiRoute != nil {
+				ctx = context.WithValue(ctx, &db.RouteContextKey, sanitizeMetricRoute(chiRoute.RoutePattern()))
+			}
+			h.ServeHTTP(w, r.WithContext(
+				context.WithValue(ctx, &auroraContext.SessionContextKey, session.Clone()),
+			))
+		})
+	}
+}
+
 // StateMiddleware is a middleware which enables a state handler if the state
 // has been initialized.
 // Unless NoStateVerification is set, it ensures that the state (ledger entries)
@@ -381,8 +413,11 @@ func (m *StateMiddleware) WrapFunc(h http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-// WrapFunc executes the middleware on a given HTTP handler function
+// Wrap executes the middleware on a given HTTP handler
 func (m *StateMiddleware) Wrap(h http.Handler) http.Handler {
+	if supportsConditionalRequests(h) {
+		h = conditionalHandler{next: h}
+	}
 	return m.WrapFunc(h.ServeHTTP)
 }
 
@@ -0,0 +1,690 @@
+package httpx
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	protocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/protocols/aurora/effects"
+	"github.com/hcnet/go/protocols/aurora/operations"
+	"github.com/hcnet/go/services/aurora/internal/actions"
+	"github.com/hcnet/go/services/aurora/internal/db2"
+	"github.com/hcnet/go/services/aurora/internal/render"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/support/render/hal"
+	"github.com/hcnet/go/support/render/problem"
+)
+
+// openAPIDocument is the subset of the OpenAPI 3 document used to describe
+// the Aurora API.
+type openAPIDocument struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Info       openAPIInfo                            `json:"info"`
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components struct {
+		Schemas map[string]*openAPISchema `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary"`
+	Tags        []string                   `json:"tags"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Pattern              string                    `json:"pattern,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	MinLength            int                       `json:"minLength,omitempty"`
+	MaxLength            int                       `json:"maxLength,omitempty"`
+	Minimum              *int                      `json:"minimum,omitempty"`
+	Maximum              *int                      `json:"maximum,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+}
+
+// openAPIAction describes the parameters and responses of an action.
+type openAPIAction struct {
+	summary string
+	// query is the query struct decoded by the action. Its fields matching
+	// the parameters in the route pattern are documented as path parameters.
+	query interface{}
+	// paged is true when the action reads the cursor, limit and order
+	// parameters.
+	paged bool
+	// form is a query struct describing the fields of url-encoded request
+	// bodies.
+	form interface{}
+	// body is the JSON request body.
+	body interface{}
+	// response is the resource returned by the action or the type of the
+	// records if page is true.
+	response interface{}
+	page     bool
+}
+
+// describeAction returns the description of the actions served by the
+// router, adding a new action requires adding it here.
+func describeAction(action interface{}) (openAPIAction, bool) {
+	switch action := action.(type) {
+	case actions.GetRootHandler:
+		return openAPIAction{summary: "Get the status of Aurora and the network", response: protocol.Root{}}, true
+	case actions.GetAccountsHandler:
+		return openAPIAction{summary: "List accounts", query: actions.AccountsQuery{}, response: protocol.Account{}}, true
+	case actions.GetAccountsBatchHandler:
+		return openAPIAction{summary: "Get a batch of accounts", body: protocol.AccountsBatchRequest{}, response: protocol.AccountsBatch{}}, true
+	case actions.GetAccountByIDHandler:
+		return openAPIAction{summary: "Get an account", query: actions.AccountByIDQuery{}, response: protocol.Account{}}, true
+	case actions.GetAccountDataHandler:
+		return openAPIAction{summary: "Get a data entry of an account", query: actions.AccountDataQuery{}, response: protocol.AccountData{}}, true
+	case actions.GetAccountOffersHandler:
+		return openAPIAction{summary: "List the offers of an account", query: actions.AccountOffersQuery{}, response: protocol.Offer{}}, true
+	case actions.GetAccountBalancesHandler:
+		return openAPIAction{summary: "Get the balances of an account at a past ledger", query: actions.AccountBalancesQuery{}, response: protocol.AccountBalances{}}, true
+	case actions.GetAccountSponsorshipsHandler:
+		return openAPIAction{summary: "List the ledger entries sponsored by an account", query: actions.AccountSponsorshipsQuery{}, paged: true, response: protocol.AccountSponsorships{}}, true
+	case actions.GetClaimableBalancesHandler:
+		return openAPIAction{summary: "List claimable balances", query: actions.ClaimableBalancesQuery{}, response: protocol.ClaimableBalance{}}, true
+	case actions.GetClaimableBalanceByIDHandler:
+		return openAPIAction{summary: "Get a claimable balance", query: actions.ClaimableBalanceQuery{}, response: protocol.ClaimableBalance{}}, true
+	case actions.GetLiquidityPoolsHandler:
+		return openAPIAction{summary: "List liquidity pools", query: actions.LiquidityPoolsQuery{}, response: protocol.LiquidityPool{}}, true
+	case actions.GetLiquidityPoolByIDHandler:
+		return openAPIAction{summary: "Get a liquidity pool", query: actions.LiquidityPoolQuery{}, response: protocol.LiquidityPool{}}, true
+	case actions.GetLiquidityPoolHistoryHandler:
+		return openAPIAction{summary: "Get the history of a liquidity pool", query: actions.LiquidityPoolHistoryQuery{}, paged: true, response: protocol.LiquidityPoolHistory{}, page: true}, true
+	case actions.GetOffersHandler:
+		return openAPIAction{summary: "List offers", query: actions.OffersQuery{}, response: protocol.Offer{}}, true
+	case actions.GetOfferByID:
+		return openAPIAction{summary: "Get an offer", query: actions.OfferByIDQuery{}, response: protocol.Offer{}}, true
+	case actions.AssetStatsHandler:
+		return openAPIAction{summary: "List assets", query: actions.AssetStatsQuery{}, response: protocol.AssetStat{}}, true
+	case actions.GetAssetHoldersHandler:
+		return openAPIAction{summary: "List the holders of an asset by balance", query: actions.AssetHoldersQuery{}, response: protocol.AssetHolder{}}, true
+	case actions.FindPathsHandler:
+		return openAPIAction{summary: "Find strict receive payment paths, or payments split between several paths", query: actions.StrictReceivePathsQuery{}, response: protocol.Path{}, page: true}, true
+	case actions.FindFixedPathsHandler:
+		return openAPIAction{summary: "Find strict send payment paths, or payments split between several paths", query: actions.FindFixedPathsQuery{}, response: protocol.Path{}, page: true}, true
+	case actions.GetOrderbookHandler:
+		return openAPIAction{summary: "Get an order book", query: actions.OrderBookQuery{}, response: protocol.OrderBookSummary{}}, true
+	case actions.GetOrderBookHistoryHandler:
+		return openAPIAction{summary: "Get the order book of a trading pair at the end of a past ledger", query: actions.OrderBookHistoryQuery{}, response: protocol.OrderBookSnapshot{}}, true
+	case actions.GetOrderBookDepthHandler:
+		return openAPIAction{summary: "Get the depth chart of a trading pair", query: actions.OrderBookDepthQuery{}, paged: true, response: protocol.OrderBookDepth{}, page: true}, true
+	case actions.GetLedgersHandler:
+		return openAPIAction{summary: "List ledgers", response: protocol.Ledger{}}, true
+	case actions.GetLedgerByIDHandler:
+		return openAPIAction{summary: "Get a ledger", query: actions.LedgerByIDQuery{}, response: protocol.Ledger{}}, true
+	case actions.GetTransactionsHandler:
+		return openAPIAction{summary: "List transactions", query: actions.TransactionsQuery{}, response: protocol.Transaction{}}, true
+	case actions.GetTransactionByHashHandler:
+		return openAPIAction{summary: "Get a transaction", query: actions.TransactionQuery{}, response: protocol.Transaction{}}, true
+	case actions.GetTransactionStatusHandler:
+		return openAPIAction{summary: "Get the status of a submitted transaction", query: actions.TransactionQuery{}, response: protocol.TransactionStatus{}}, true
+	case actions.SubmitTransactionHandler:
+		return openAPIAction{summary: "Submit a transaction", form: transactionForm{}, response: protocol.Transaction{}}, true
+	case actions.AsyncSubmitTransactionHandler:
+		return openAPIAction{summary: "Submit a transaction asynchronously", form: transactionForm{}, response: protocol.AsyncTransactionSubmissionResponse{}}, true
+	case actions.SimulateTransactionHandler:
+		return openAPIAction{summary: "Simulate a transaction", form: transactionForm{}, response: protocol.TransactionSimulation{}}, true
+	case actions.GetOperationsHandler:
+		if action.OnlyPayments {
+			return openAPIAction{summary: "List payments", query: actions.OperationsQuery{}, response: operations.Base{}}, true
+		}
+		return openAPIAction{summary: "List operations", query: actions.OperationsQuery{}, response: operations.Base{}}, true
+	case actions.GetOperationByIDHandler:
+		return openAPIAction{summary: "Get an operation", query: actions.OperationQuery{}, response: operations.Base{}}, true
+	case actions.GetEffectsHandler:
+		return openAPIAction{summary: "List effects", query: actions.EffectsQuery{}, response: effects.Base{}}, true
+	case actions.GetTradesHandler:
+		return openAPIAction{summary: "List trades", query: actions.TradesQuery{}, response: protocol.Trade{}}, true
+	case actions.GetTradeAggregationsHandler:
+		return openAPIAction{summary: "List trade aggregations", query: actions.TradeAggregationsQuery{}, paged: true, response: protocol.TradeAggregation{}, page: true}, true
+	case actions.FeeStatsHandler:
+		return openAPIAction{summary: "Get fee statistics", response: protocol.FeeStats{}}, true
+	}
+	return openAPIAction{}, false
+}
+
+type transactionForm struct {
+	Transaction string `schema:"tx" valid:"required"`
+}
+
+// openAPIRoutes describes the routes which are not served by actions.
+var openAPIRoutes = map[string]openAPIOperation{
+	"GET /health": {
+		Summary:   "Check the health of Aurora",
+		Responses: map[string]openAPIResponse{"200": {Description: "Aurora is healthy"}},
+	},
+	"GET /ws": {
+		Summary:   "Subscribe to streams over a WebSocket connection",
+		Responses: map[string]openAPIResponse{"101": {Description: "Switching to the WebSocket protocol"}},
+	},
+	"GET /friendbot": {
+		Summary:   "Fund an account using friendbot",
+		Responses: map[string]openAPIResponse{"307": {Description: "Redirect to friendbot"}},
+	},
+	"POST /friendbot": {
+		Summary:   "Fund an account using friendbot",
+		Responses: map[string]openAPIResponse{"307": {Description: "Redirect to friendbot"}},
+	},
+	"GET /openapi.json": {
+		Summary: "Get the OpenAPI document of the API",
+		Responses: map[string]openAPIResponse{"200": {
+			Description: "OpenAPI 3 document",
+			Content:     map[string]openAPIMediaType{render.MimeJSON: {Schema: &openAPISchema{Type: "object"}}},
+		}},
+	},
+}
+
+// handlerDescription describes how a handler serves an action.
+type handlerDescription struct {
+	action interface{}
+	// mimeTypes are the media types of the responses.
+	mimeTypes []string
+	// page is true when the handler renders pages of the action records.
+	page bool
+}
+
+// describedHandler is implemented by the handlers serving actions.
+type describedHandler interface {
+	describe() handlerDescription
+}
+
+func (handler ObjectActionHandler) describe() handlerDescription {
+	return handlerDescription{action: handler.Action, mimeTypes: []string{render.MimeHal}}
+}
+
+func (handler streamableObjectActionHandler) describe() handlerDescription {
+	return handlerDescription{
+		action:    handler.action,
+		mimeTypes: []string{render.MimeHal, render.MimeEventStream},
+	}
+}
+
+func (handler streamablePathsHandler) describe() handlerDescription {
+	return handlerDescription{
+		action:    handler.action,
+		mimeTypes: []string{render.MimeHal, render.MimeEventStream},
+	}
+}
+
+func (handler pageActionHandler) describe() handlerDescription {
+	description := handlerDescription{
+		action:    handler.action,
+		mimeTypes: []string{render.MimeHal},
+		page:      true,
+	}
+	if handler.streamable {
+		description.mimeTypes = append(description.mimeTypes, render.MimeEventStream)
+	}
+	if _, ok := exportLayoutForAction(handler.action); ok {
+		description.mimeTypes = append(description.mimeTypes, render.MimeCSV, render.MimeNDJSON)
+	}
+	return description
+}
+
+func (handler rawActionHandler) describe() handlerDescription {
+	return describeWrapped(handler.next, render.MimeRaw)
+}
+
+func describeWrapped(next http.Handler, mimeType string) handlerDescription {
+	described, ok := next.(describedHandler)
+	if !ok {
+		return handlerDescription{}
+	}
+	description := described.describe()
+	description.mimeTypes = append(description.mimeTypes, mimeType)
+	return description
+}
+
+var routeParamPattern = regexp.MustCompile(`\{([a-z_]+)(:[^}]*)?\}`)
+
+// newOpenAPIDocument describes all the routes of router, it fails if a route
+// isn't described.
+func newOpenAPIDocument(router chi.Routes, version string) (openAPIDocument, error) {
+	doc := openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Title: "Aurora", Version: version},
+		Paths:   map[string]map[string]openAPIOperation{},
+	}
+	schemas := newOpenAPISchemas()
+
+	var undocumented []string
+	err := chi.Walk(router, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		route = openAPIPath(route)
+		operation, ok := describeRoute(schemas, method, route, handler)
+		if !ok {
+			undocumented = append(undocumented, method+" "+route)
+			return nil
+		}
+		operation.OperationID = openAPIOperationID(method, route)
+		operation.Tags = []string{strings.Split(strings.TrimPrefix(route, "/"), "/")[0]}
+		if operation.Tags[0] == "" {
+			operation.Tags[0] = "root"
+		}
+		if operation.Responses["default"].Description == "" {
+			operation.Responses["default"] = openAPIResponse{
+				Description: "Error",
+				Content: map[string]openAPIMediaType{
+					"application/problem+json": {Schema: schemas.schemaFor(reflect.TypeOf(problem.P{}))},
+				},
+			}
+		}
+
+		if doc.Paths[route] == nil {
+			doc.Paths[route] = map[string]openAPIOperation{}
+		}
+		doc.Paths[route][strings.ToLower(method)] = operation
+		return nil
+	})
+	if err != nil {
+		return doc, err
+	}
+	if len(undocumented) > 0 {
+		sort.Strings(undocumented)
+		return doc, errors.Errorf("routes missing from the OpenAPI document: %s", strings.Join(undocumented, ", "))
+	}
+
+	doc.Components.Schemas = schemas.components
+	return doc, nil
+}
+
+// openAPIPath converts a chi route pattern to an OpenAPI path.
+func openAPIPath(route string) string {
+	route = routeParamPattern.ReplaceAllString(route, "{$1}")
+	route = strings.Replace(route, "/*/", "/", -1)
+	if len(route) > 1 {
+		route = strings.TrimSuffix(route, "/")
+	}
+	return route
+}
+
+// openAPIOperationID returns a camel case identifier of the operation, ex.
+// getAccountsAccountIdPayments for GET /accounts/{account_id}/payments.
+func openAPIOperationID(method, route string) string {
+	id := strings.ToLower(method)
+	for _, word := range regexp.MustCompile(`[a-zA-Z0-9]+`).FindAllString(route, -1) {
+		id += strings.ToUpper(word[:1]) + word[1:]
+	}
+	return id
+}
+
+func describeRoute(schemas *openAPISchemas, method, route string, handler http.Handler) (openAPIOperation, bool) {
+	for {
+		chain, ok := handler.(*chi.ChainHandler)
+		if !ok {
+			break
+		}
+		handler = chain.Endpoint
+	}
+
+	if operation, ok := openAPIRoutes[method+" "+route]; ok {
+		responses := map[string]openAPIResponse{}
+		for status, response := range operation.Responses {
+			responses[status] = response
+		}
+		operation.Responses = responses
+		return operation, true
+	}
+
+	described, ok := handler.(describedHandler)
+	if !ok {
+		return openAPIOperation{}, false
+	}
+	served := described.describe()
+	description, ok := describeAction(served.action)
+	if !ok {
+		return openAPIOperation{}, false
+	}
+	isPage := served.page || description.page
+
+	operation := openAPIOperation{Summary: description.summary}
+
+	var queryParams []actions.QueryParam
+	if description.query != nil {
+		queryParams = actions.QueryParams(description.query)
+	}
+	inPath := map[string]bool{}
+	for _, match := range routeParamPattern.FindAllStringSubmatch(route, -1) {
+		name := match[1]
+		inPath[name] = true
+		param := actions.QueryParam{Name: name, Type: "string"}
+		for _, queryParam := range queryParams {
+			if queryParam.Name == name {
+				param = queryParam
+			}
+		}
+		param.Required = true
+		operation.Parameters = append(operation.Parameters, newOpenAPIParameter("path", param))
+	}
+	for _, param := range queryParams {
+		if !inPath[param.Name] {
+			operation.Parameters = append(operation.Parameters, newOpenAPIParameter("query", param))
+		}
+	}
+	if served.page || description.paged {
+		operation.Parameters = append(operation.Parameters, pagingParameters()...)
+	}
+
+	if description.form != nil {
+		form := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+		for _, param := range actions.QueryParams(description.form) {
+			form.Properties[param.Name] = newOpenAPIParameter("", param).Schema
+			if param.Required {
+				form.Required = append(form.Required, param.Name)
+			}
+		}
+		operation.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content:  map[string]openAPIMediaType{"application/x-www-form-urlencoded": {Schema: form}},
+		}
+	}
+	if description.body != nil {
+		operation.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content: map[string]openAPIMediaType{
+				render.MimeJSON: {Schema: schemas.schemaFor(reflect.TypeOf(description.body))},
+			},
+		}
+	}
+
+	response := schemas.schemaFor(reflect.TypeOf(description.response))
+	if isPage {
+		response = schemas.pageSchemaFor(reflect.TypeOf(description.response))
+	}
+	content := map[string]openAPIMediaType{}
+	for _, mimeType := range served.mimeTypes {
+		switch mimeType {
+		case render.MimeHal:
+			content[mimeType] = openAPIMediaType{Schema: response}
+		case render.MimeEventStream, render.MimeCSV, render.MimeNDJSON:
+			content[mimeType] = openAPIMediaType{Schema: &openAPISchema{Type: "string"}}
+		case render.MimeRaw:
+			content[mimeType] = openAPIMediaType{Schema: &openAPISchema{Type: "string", Format: "binary"}}
+		}
+	}
+	operation.Responses = map[string]openAPIResponse{
+		"200": {Description: "OK", Content: content},
+	}
+	return operation, true
+}
+
+func newOpenAPIParameter(in string, param actions.QueryParam) openAPIParameter {
+	schema := &openAPISchema{
+		Type:      param.Type,
+		Format:    param.Format,
+		Pattern:   param.Pattern,
+		Enum:      param.Enum,
+		MinLength: param.MinLength,
+		MaxLength: param.MaxLength,
+	}
+	if param.Type == "array" {
+		schema = &openAPISchema{Type: "array", Items: &openAPISchema{Type: "string", Enum: param.Enum}}
+	}
+	return openAPIParameter{
+		Name:        param.Name,
+		In:          in,
+		Description: param.Description,
+		Required:    param.Required,
+		Schema:      schema,
+	}
+}
+
+func pagingParameters() []openAPIParameter {
+	minLimit, maxLimit := 1, int(db2.MaxPageSize)
+	return []openAPIParameter{
+		{Name: actions.ParamCursor, In: "query", Schema: &openAPISchema{Type: "string"}},
+		{Name: actions.ParamLimit, In: "query", Schema: &openAPISchema{Type: "integer", Minimum: &minLimit, Maximum: &maxLimit}},
+		{Name: actions.ParamOrder, In: "query", Schema: &openAPISchema{Type: "string", Enum: []string{db2.OrderAscending, db2.OrderDescending}}},
+	}
+}
+
+// openAPISchemas builds the schemas of the JSON resources from the Go types
+// rendered by the actions. Named struct types are added to the document
+// components and referenced.
+type openAPISchemas struct {
+	components map[string]*openAPISchema
+	names      map[reflect.Type]string
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	openAPISchemaName = map[reflect.Type]string{
+		reflect.TypeOf(problem.P{}): "Problem",
+		reflect.TypeOf(hal.Link{}):  "Link",
+	}
+)
+
+func newOpenAPISchemas() *openAPISchemas {
+	return &openAPISchemas{
+		components: map[string]*openAPISchema{},
+		names:      map[reflect.Type]string{},
+	}
+}
+
+func (s *openAPISchemas) schemaFor(t reflect.Type) *openAPISchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	case t.Kind() != reflect.Struct && t.Implements(textMarshalerType):
+		return &openAPISchema{Type: "string"}
+	case t.Implements(jsonMarshalerType) && !strings.HasPrefix(t.PkgPath(), "github.com/hcnet/go/protocols/"):
+		// the resources in protocols keep their shape when marshaled, other
+		// types like the xdr ones can be encoded as anything
+		return &openAPISchema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &openAPISchema{Type: "integer", Format: integerFormat(t)}
+	case reflect.Float32, reflect.Float64:
+		return &openAPISchema{Type: "number"}
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &openAPISchema{Type: "string", Format: "byte"}
+		}
+		return &openAPISchema{Type: "array", Items: s.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: s.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.objectSchema(t)
+		}
+		name, ok := s.names[t]
+		if !ok {
+			name = s.componentName(t)
+			s.names[t] = name
+			s.components[name] = &openAPISchema{}
+			*s.components[name] = *s.objectSchema(t)
+		}
+		return &openAPISchema{Ref: "#/components/schemas/" + name}
+	default:
+		// interfaces can hold any value
+		return &openAPISchema{}
+	}
+}
+
+// pageSchemaFor returns the schema of a HAL page of records of type t.
+func (s *openAPISchemas) pageSchemaFor(t reflect.Type) *openAPISchema {
+	record := s.schemaFor(t)
+	name := strings.TrimPrefix(record.Ref, "#/components/schemas/") + "Page"
+	if _, ok := s.components[name]; !ok {
+		link := s.schemaFor(reflect.TypeOf(hal.Link{}))
+		s.components[name] = &openAPISchema{
+			Type: "object",
+			Properties: map[string]*openAPISchema{
+				"_links": {
+					Type: "object",
+					Properties: map[string]*openAPISchema{
+						"self": link,
+						"next": link,
+						"prev": link,
+					},
+				},
+				"_embedded": {
+					Type: "object",
+					Properties: map[string]*openAPISchema{
+						"records": {Type: "array", Items: record},
+					},
+					Required: []string{"records"},
+				},
+			},
+			Required: []string{"_links", "_embedded"},
+		}
+	}
+	return &openAPISchema{Ref: "#/components/schemas/" + name}
+}
+
+func (s *openAPISchemas) componentName(t reflect.Type) string {
+	if name, ok := openAPISchemaName[t]; ok {
+		return name
+	}
+	pkg := path.Base(t.PkgPath())
+	if pkg == "aurora" {
+		return t.Name()
+	}
+	return strings.ToUpper(pkg[:1]) + pkg[1:] + t.Name()
+}
+
+func (s *openAPISchemas) objectSchema(t reflect.Type) *openAPISchema {
+	schema := &openAPISchema{Type: "object", Properties: map[string]*openAPISchema{}}
+	s.addProperties(schema, t)
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func (s *openAPISchemas) addProperties(schema *openAPISchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		options := strings.Split(tag, ",")
+		name := options[0]
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			for embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.addProperties(schema, embedded)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			// unexported field
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		property := s.schemaFor(f.Type)
+		omitEmpty := false
+		for _, option := range options[1:] {
+			switch option {
+			case "omitempty":
+				omitEmpty = true
+			case "string":
+				property = &openAPISchema{Type: "string"}
+			}
+		}
+		schema.Properties[name] = property
+		if !omitEmpty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+func integerFormat(t reflect.Type) string {
+	if t.Bits() == 64 {
+		return "int64"
+	}
+	return "int32"
+}
+
+// openAPIHandler serves the OpenAPI document of the routes of router, which
+// is generated on the first request once all the routes are registered.
+type openAPIHandler struct {
+	router  chi.Routes
+	version string
+
+	once     sync.Once
+	document []byte
+	err      error
+}
+
+func (handler *openAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler.once.Do(func() {
+		var doc openAPIDocument
+		doc, handler.err = newOpenAPIDocument(handler.router, handler.version)
+		if handler.err == nil {
+			handler.document, handler.err = json.MarshalIndent(doc, "", "  ")
+		}
+	})
+	if handler.err != nil {
+		problem.Render(r.Context(), w, fmt.Errorf("generating OpenAPI document: %v", handler.err))
+		return
+	}
+	w.Header().Set("Content-Type", render.MimeJSON)
+	w.Write(handler.document)
+}
@@ -0,0 +1,142 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hcnet/go/services/aurora/internal/ledger"
+	"github.com/hcnet/go/services/aurora/internal/paths"
+)
+
+func newOpenAPITestRouter(t *testing.T) *Router {
+	friendbotURL, err := url.Parse("https://friendbot.example.com")
+	require.NoError(t, err)
+	router, err := NewRouter(&RouterConfig{
+		HealthCheck:               http.NotFoundHandler(),
+		PathFinder:                &paths.MockFinder{},
+		FriendbotURL:              friendbotURL,
+		MaxWebSocketSubscriptions: 1,
+		AuroraVersion:             "test",
+	}, nil, &ledger.State{})
+	require.NoError(t, err)
+	return router
+}
+
+func TestOpenAPIDocumentMatchesRoutes(t *testing.T) {
+	router := newOpenAPITestRouter(t)
+	doc, err := newOpenAPIDocument(router.Mux, "test")
+	require.NoError(t, err)
+
+	routes := 0
+	err = chi.Walk(router.Mux, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		routes++
+		route = openAPIPath(route)
+		operation, ok := doc.Paths[route][strings.ToLower(method)]
+		if !assert.True(t, ok, "%s %s is missing from the document", method, route) {
+			return nil
+		}
+
+		for _, match := range routeParamPattern.FindAllStringSubmatch(route, -1) {
+			found := false
+			for _, param := range operation.Parameters {
+				found = found || (param.In == "path" && param.Name == match[1] && param.Required)
+			}
+			assert.True(t, found, "path parameter %s of %s %s is not documented", match[1], method, route)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	operations := 0
+	for _, pathItem := range doc.Paths {
+		operations += len(pathItem)
+	}
+	assert.Equal(t, routes, operations)
+
+	// POST /transactions is registered in the /transactions sub-router so
+	// it's found when walking the routes
+	assert.Contains(t, doc.Paths["/transactions"], "post")
+	assert.Contains(t, doc.Paths["/transactions"], "get")
+}
+
+func TestOpenAPIDocumentOperations(t *testing.T) {
+	doc, err := newOpenAPIDocument(newOpenAPITestRouter(t).Mux, "test")
+	require.NoError(t, err)
+
+	payments := doc.Paths["/accounts/{account_id}/payments"]["get"]
+	assert.Equal(t, "getAccountsAccountIdPayments", payments.OperationID)
+	assert.Equal(t, "List payments", payments.Summary)
+	params := map[string]openAPIParameter{}
+	for _, param := range payments.Parameters {
+		params[param.Name] = param
+	}
+	assert.Equal(t, "path", params["account_id"].In)
+	assert.Equal(t, "^G[A-Z2-7]{55}$", params["account_id"].Schema.Pattern)
+	assert.Equal(t, "query", params["include_failed"].In)
+	assert.Equal(t, "boolean", params["include_failed"].Schema.Type)
+	assert.Equal(t, "array", params["type"].Schema.Type)
+	assert.Equal(t, []string{"asc", "desc"}, params["order"].Schema.Enum)
+	assert.Contains(t, params, "cursor")
+	assert.Equal(t,
+		"#/components/schemas/OperationsBasePage",
+		payments.Responses["200"].Content["application/hal+json"].Schema.Ref,
+	)
+	assert.Contains(t, payments.Responses["200"].Content, "text/event-stream")
+	assert.Contains(t, payments.Responses["200"].Content, "text/csv")
+	assert.Contains(t, payments.Responses["200"].Content, "application/x-ndjson")
+
+	submit := doc.Paths["/transactions"]["post"]
+	form := submit.RequestBody.Content["application/x-www-form-urlencoded"].Schema
+	assert.Equal(t, []string{"tx"}, form.Required)
+	assert.Equal(t, "#/components/schemas/Transaction", submit.Responses["200"].Content["application/hal+json"].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/Problem", submit.Responses["default"].Content["application/problem+json"].Schema.Ref)
+
+	holders := doc.Paths["/assets/{asset}/holders"]["get"]
+	assert.Contains(t, holders.Responses["200"].Content, "text/csv")
+
+	account := doc.Components.Schemas["Account"]
+	require.NotNil(t, account)
+	assert.Equal(t, "string", account.Properties["sequence"].Type)
+	assert.Equal(t, "date-time", account.Properties["last_modified_time"].Format)
+	assert.Equal(t, "#/components/schemas/Balance", account.Properties["balances"].Items.Ref)
+	assert.Contains(t, account.Required, "account_id")
+	assert.NotContains(t, account.Required, "home_domain")
+
+	// all the references are defined
+	encoded, err := json.Marshal(doc)
+	require.NoError(t, err)
+	for _, ref := range strings.Split(string(encoded), `"$ref":"#/components/schemas/`)[1:] {
+		name := ref[:strings.Index(ref, `"`)]
+		assert.Contains(t, doc.Components.Schemas, name)
+	}
+}
+
+func TestOpenAPIDocumentUndocumentedRoute(t *testing.T) {
+	router := chi.NewRouter()
+	router.Method(http.MethodGet, "/fee_stats", ObjectActionHandler{})
+	router.Get("/unknown", func(w http.ResponseWriter, r *http.Request) {})
+	_, err := newOpenAPIDocument(router, "test")
+	assert.EqualError(t, err, "routes missing from the OpenAPI document: GET /fee_stats, GET /unknown")
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	router := newOpenAPITestRouter(t)
+	handler := &openAPIHandler{router: router.Mux, version: "test"}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc["openapi"])
+	assert.Equal(t, map[string]interface{}{"title": "Aurora", "version": "test"}, doc["info"])
+}
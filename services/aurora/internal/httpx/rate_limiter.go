@@ -3,26 +3,15 @@ package httpx
 import (
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/stellar/throttled"
 
-	"github.com/hcnet/go/services/aurora/internal/ledger"
 	hProblem "github.com/hcnet/go/services/aurora/internal/render/problem"
 	"github.com/hcnet/go/support/render/problem"
 )
 
 const lruCacheSize = 50000
 
-type historyLedgerSourceFactory struct {
-	updateFrequency time.Duration
-	ledgerState     *ledger.State
-}
-
-func (f historyLedgerSourceFactory) Get() ledger.Source {
-	return ledger.NewHistoryDBSource(f.updateFrequency, f.ledgerState)
-}
-
 func remoteAddrIP(r *http.Request) string {
 	// To support IPv6
 	lastSemicolon := strings.LastIndex(r.RemoteAddr, ":")
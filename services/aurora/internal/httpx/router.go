@@ -48,6 +48,28 @@ type RouterConfig struct {
 	FriendbotURL             *url.URL
 	HealthCheck              http.Handler
 	EnableIngestionFiltering bool
+	Ingester                 actions.IngestionController
+
+	// LedgerNotifier notifies the streams of new ledgers, a notifier polling
+	// every SSEUpdateFrequency is created when it's nil.
+	LedgerNotifier *ledger.Notifier
+
+	// MaxWebSocketSubscriptions is the maximum number of streams a connection
+	// to /ws can subscribe to, the endpoint is disabled if it's 0.
+	MaxWebSocketSubscriptions uint
+
+	// EnableWebhooks mounts the admin endpoints managing webhook
+	// subscriptions.
+	EnableWebhooks bool
+
+	// EnableAPIKeys rate limits requests sending an API key by the quotas of
+	// the key and mounts the admin endpoints managing API keys. Requests
+	// without API key are still rate limited by RateQuota.
+	EnableAPIKeys bool
+
+	// HistoryRetention is true when the oldest history is removed by the
+	// reaper, history pages aren't cached as immutable.
+	HistoryRetention bool
 }
 
 type Router struct {
@@ -61,7 +83,15 @@ func NewRouter(config *RouterConfig, serverMetrics *ServerMetrics, ledgerState *
 		Internal: chi.NewMux(),
 	}
 	var rateLimiter *throttled.HTTPRateLimiter
-	if config.RateQuota != nil {
+	var apiKeys *apiKeyRateLimiter
+	if config.EnableAPIKeys {
+		historyQ := &history.Q{SessionInterface: config.DBSession}
+		var err error
+		rateLimiter, apiKeys, err = newAPIKeyRateLimiter(historyQ.APIKeys, config.RateQuota, serverMetrics)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create RateLimiter: %v", err)
+		}
+	} else if config.RateQuota != nil {
 		var err error
 		rateLimiter, err = newRateLimiter(config.RateQuota)
 		if err != nil {
@@ -69,7 +99,7 @@ func NewRouter(config *RouterConfig, serverMetrics *ServerMetrics, ledgerState *
 		}
 	}
 	result.addMiddleware(config, rateLimiter, serverMetrics)
-	result.addRoutes(config, rateLimiter, ledgerState)
+	result.addRoutes(config, rateLimiter, apiKeys, ledgerState)
 	return &result, nil
 }
 
@@ -94,7 +124,7 @@ func (r *Router) addMiddleware(config *RouterConfig,
 	c := cors.New(cors.Options{
 		AllowedOrigins: []string{"*"},
 		AllowedHeaders: []string{"*"},
-		ExposedHeaders: []string{"Date", "Latest-Ledger"},
+		ExposedHeaders: []string{"Date", "Latest-Ledger", "ETag"},
 	})
 	r.Use(c.Handler)
 
@@ -128,7 +158,7 @@ func (r *Router) addMiddleware(config *RouterConfig,
 	r.Internal.Use(loggerMiddleware(serverMetrics))
 }
 
-func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRateLimiter, ledgerState *ledger.State) {
+func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRateLimiter, apiKeys *apiKeyRateLimiter, ledgerState *ledger.State) {
 	stateMiddleware := StateMiddleware{
 		AuroraSession: config.DBSession,
 	}
@@ -143,16 +173,21 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 		AuroraVersion:    config.AuroraVersion,
 	}})
 
+	ledgerNotifier := config.LedgerNotifier
+	if ledgerNotifier == nil {
+		ledgerNotifier = ledger.NewNotifier(config.SSEUpdateFrequency, ledgerState)
+	}
 	streamHandler := sse.StreamHandler{
 		RateLimiter:         rateLimiter,
-		LedgerSourceFactory: historyLedgerSourceFactory{ledgerState: ledgerState, updateFrequency: config.SSEUpdateFrequency},
+		LedgerSourceFactory: ledgerNotifier,
 	}
 
-	historyMiddleware := NewHistoryMiddleware(ledgerState, int32(config.StaleThreshold), config.DBSession)
+	historyMiddleware := NewHistoryMiddleware(ledgerState, int32(config.StaleThreshold), config.HistoryRetention, config.DBSession)
 	// State endpoints behind stateMiddleware
 	r.Group(func(r chi.Router) {
 		r.Route("/accounts", func(r chi.Router) {
 			r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/", restPageHandler(ledgerState, actions.GetAccountsHandler{LedgerState: ledgerState}))
+			r.With(stateMiddleware.Wrap).Method(http.MethodPost, "/batch", ObjectActionHandler{actions.GetAccountsBatchHandler{}})
 			r.Route("/{account_id}", func(r chi.Router) {
 				r.With(stateMiddleware.Wrap).Method(
 					http.MethodGet,
@@ -168,6 +203,8 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 					accountData,
 				))
 				r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/offers", streamableStatePageHandler(ledgerState, actions.GetAccountOffersHandler{LedgerState: ledgerState}, streamHandler))
+				r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/balances", ObjectActionHandler{actions.GetAccountBalancesHandler{}})
+				r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/sponsorships", ObjectActionHandler{actions.GetAccountSponsorshipsHandler{LedgerState: ledgerState}})
 			})
 		})
 
@@ -187,6 +224,7 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 				r.With(historyMiddleware).Method(http.MethodGet, "/transactions", streamableHistoryPageHandler(ledgerState, actions.GetTransactionsHandler{LedgerState: ledgerState}, streamHandler))
 				r.With(historyMiddleware).Method(http.MethodGet, "/effects", streamableHistoryPageHandler(ledgerState, actions.GetEffectsHandler{LedgerState: ledgerState}, streamHandler))
 				r.With(historyMiddleware).Method(http.MethodGet, "/trades", streamableHistoryPageHandler(ledgerState, actions.GetTradesHandler{LedgerState: ledgerState, CoreStateGetter: config.CoreGetter}, streamHandler))
+				r.With(historyMiddleware).Method(http.MethodGet, "/history", ObjectActionHandler{actions.GetLiquidityPoolHistoryHandler{LedgerState: ledgerState}})
 			})
 		})
 
@@ -196,16 +234,17 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 		})
 
 		r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/assets", restPageHandler(ledgerState, actions.AssetStatsHandler{LedgerState: ledgerState}))
+		r.With(stateMiddleware.Wrap).Method(http.MethodGet, "/assets/{asset}/holders", restPageHandler(ledgerState, actions.GetAssetHoldersHandler{LedgerState: ledgerState}))
 
 		if config.PathFinder != nil {
-			findPaths := ObjectActionHandler{actions.FindPathsHandler{
+			findPaths := streamablePathsHandler{streamHandler: streamHandler, action: actions.FindPathsHandler{
 				StaleThreshold:       config.StaleThreshold,
 				SetLastLedgerHeader:  true,
 				MaxPathLength:        config.MaxPathLength,
 				MaxAssetsParamLength: config.MaxAssetsPerPathRequest,
 				PathFinder:           config.PathFinder,
 			}}
-			findFixedPaths := ObjectActionHandler{actions.FindFixedPathsHandler{
+			findFixedPaths := streamablePathsHandler{streamHandler: streamHandler, action: actions.FindFixedPathsHandler{
 				MaxPathLength:        config.MaxPathLength,
 				SetLastLedgerHeader:  true,
 				MaxAssetsParamLength: config.MaxAssetsPerPathRequest,
@@ -273,8 +312,22 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 	// transaction history actions
 	r.Route("/transactions", func(r chi.Router) {
 		r.With(historyMiddleware).Method(http.MethodGet, "/", streamableHistoryPageHandler(ledgerState, actions.GetTransactionsHandler{LedgerState: ledgerState}, streamHandler))
+		// Transaction submission API, it's served by the /transactions router
+		// because chi doesn't list the handlers registered on the pattern of a
+		// mounted router, which would leave it out of the OpenAPI document.
+		r.Method(http.MethodPost, "/", ObjectActionHandler{actions.SubmitTransactionHandler{
+			Submitter:         config.TxSubmitter,
+			NetworkPassphrase: config.NetworkPassphrase,
+			CoreStateGetter:   config.CoreGetter,
+		}})
+		r.With(stateMiddleware.Wrap).Method(http.MethodPost, "/simulate", ObjectActionHandler{actions.SimulateTransactionHandler{
+			NetworkPassphrase: config.NetworkPassphrase,
+		}})
 		r.Route("/{tx_id}", func(r chi.Router) {
 			r.With(historyMiddleware).Method(http.MethodGet, "/", ObjectActionHandler{actions.GetTransactionByHashHandler{}})
+			r.Method(http.MethodGet, "/status", ObjectActionHandler{actions.GetTransactionStatusHandler{
+				StatusGetter: config.TxSubmitter,
+			}})
 			r.With(historyMiddleware).Method(http.MethodGet, "/effects", streamableHistoryPageHandler(ledgerState, actions.GetEffectsHandler{LedgerState: ledgerState}, streamHandler))
 			r.With(historyMiddleware).Method(http.MethodGet, "/operations", streamableHistoryPageHandler(ledgerState, actions.GetOperationsHandler{
 				LedgerState:  ledgerState,
@@ -310,13 +363,15 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 		// trading related endpoints
 		r.With(historyMiddleware).Method(http.MethodGet, "/trades", streamableHistoryPageHandler(ledgerState, actions.GetTradesHandler{LedgerState: ledgerState, CoreStateGetter: config.CoreGetter}, streamHandler))
 		r.With(historyMiddleware).Method(http.MethodGet, "/trade_aggregations", ObjectActionHandler{actions.GetTradeAggregationsHandler{LedgerState: ledgerState, CoreStateGetter: config.CoreGetter}})
+		r.With(historyMiddleware).Method(http.MethodGet, "/order_book/history", ObjectActionHandler{actions.GetOrderBookHistoryHandler{}})
+		r.With(historyMiddleware).Method(http.MethodGet, "/order_book/depth", ObjectActionHandler{actions.GetOrderBookDepthHandler{LedgerState: ledgerState}})
 		// /offers/{offer_id} has been created above so we need to use absolute
 		// routes here.
 		r.With(historyMiddleware).Method(http.MethodGet, "/offers/{offer_id}/trades", streamableHistoryPageHandler(ledgerState, actions.GetTradesHandler{LedgerState: ledgerState, CoreStateGetter: config.CoreGetter}, streamHandler))
 	})
 
 	// Transaction submission API
-	r.Method(http.MethodPost, "/transactions", ObjectActionHandler{actions.SubmitTransactionHandler{
+	r.Method(http.MethodPost, "/transactions_async", ObjectActionHandler{actions.AsyncSubmitTransactionHandler{
 		Submitter:         config.TxSubmitter,
 		NetworkPassphrase: config.NetworkPassphrase,
 		CoreStateGetter:   config.CoreGetter,
@@ -325,6 +380,17 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 	// Network state related endpoints
 	r.Method(http.MethodGet, "/fee_stats", ObjectActionHandler{actions.FeeStatsHandler{}})
 
+	// OpenAPI document generated from the routes above
+	r.Method(http.MethodGet, "/openapi.json", &openAPIHandler{router: r.Mux, version: config.AuroraVersion})
+
+	// Streams multiplexed over WebSocket connections
+	if config.MaxWebSocketSubscriptions > 0 {
+		r.Method(http.MethodGet, "/ws", webSocketHandler{
+			router:           r.Mux,
+			maxSubscriptions: int(config.MaxWebSocketSubscriptions),
+		})
+	}
+
 	// friendbot
 	if config.FriendbotURL != nil {
 		redirectFriendbot := func(w http.ResponseWriter, r *http.Request) {
@@ -340,6 +406,7 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 	})
 
 	// internal
+	adminMiddleware := NewAdminMiddleware(config.DBSession)
 	r.Internal.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		p, err := staticFiles.ReadFile("static/admin_oapi.yml")
 		if err != nil {
@@ -352,6 +419,13 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 	r.Internal.Get("/metrics", promhttp.HandlerFor(config.PrometheusRegistry, promhttp.HandlerOpts{}).ServeHTTP)
 	r.Internal.Get("/debug/pprof/heap", pprof.Index)
 	r.Internal.Get("/debug/pprof/profile", pprof.Profile)
+	if config.Ingester != nil {
+		handler := actions.IngestionAdminHandler{Ingester: config.Ingester}
+		r.Internal.Get("/ingestion/status", handler.GetStatus)
+		r.Internal.Post("/ingestion/rebuild", handler.TriggerStateRebuild)
+		r.Internal.Post("/ingestion/pause", handler.Pause)
+		r.Internal.Post("/ingestion/resume", handler.Resume)
+	}
 	if config.EnableIngestionFiltering {
 		r.Internal.Route("/ingestion/filters", func(r chi.Router) {
 			handler := actions.FilterConfigHandler{}
@@ -361,4 +435,27 @@ func (r *Router) addRoutes(config *RouterConfig, rateLimiter *throttled.HTTPRate
 			r.With(historyMiddleware).Get("/account", handler.GetAccountConfig)
 		})
 	}
+	if config.EnableWebhooks {
+		r.Internal.Route("/webhooks", func(r chi.Router) {
+			handler := actions.WebhookSubscriptionsHandler{LedgerState: ledgerState}
+			r.Use(adminMiddleware)
+			r.Get("/", handler.GetSubscriptions)
+			r.Post("/", handler.CreateSubscription)
+			r.Get("/{id}", handler.GetSubscription)
+			r.Put("/{id}", handler.UpdateSubscription)
+			r.Delete("/{id}", handler.DeleteSubscription)
+		})
+	}
+	if config.EnableAPIKeys {
+		r.Internal.Route("/api_keys", func(r chi.Router) {
+			handler := actions.APIKeysHandler{UsageTracker: apiKeys}
+			r.Use(adminMiddleware)
+			r.Get("/", handler.GetAPIKeys)
+			r.Post("/", handler.CreateAPIKey)
+			r.Get("/{id}", handler.GetAPIKey)
+			r.Put("/{id}", handler.UpdateAPIKey)
+			r.Delete("/{id}", handler.DeleteAPIKey)
+			r.Get("/{id}/usage", handler.GetAPIKeyUsage)
+		})
+	}
 }
@@ -24,6 +24,7 @@ import (
 type ServerMetrics struct {
 	RequestDurationSummary  *prometheus.SummaryVec
 	ReplicaLagErrorsCounter prometheus.Counter
+	APIKeyRequestsCounter   *prometheus.CounterVec
 }
 
 type TLSConfig struct {
@@ -78,6 +79,13 @@ func NewServer(serverConfig ServerConfig, routerConfig RouterConfig, ledgerState
 				Help: "Count of HTTP errors returned due to replica lag",
 			},
 		),
+		APIKeyRequestsCounter: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "aurora", Subsystem: "http", Name: "api_key_requests_total",
+				Help: "Count of HTTP requests and stream updates made with an API key, limited requests were rejected",
+			},
+			[]string{"api_key_id", "route_class", "limited"},
+		),
 	}
 	router, err := NewRouter(&routerConfig, sm, ledgerState)
 	if err != nil {
@@ -110,6 +118,7 @@ func NewServer(serverConfig ServerConfig, routerConfig RouterConfig, ledgerState
 func (s *Server) RegisterMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(s.Metrics.RequestDurationSummary)
 	registry.MustRegister(s.Metrics.ReplicaLagErrorsCounter)
+	registry.MustRegister(s.Metrics.APIKeyRequestsCounter)
 }
 
 func (s *Server) Serve() error {
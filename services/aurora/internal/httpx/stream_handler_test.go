@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
 
 	"github.com/hcnet/go/services/aurora/internal/actions"
 	auroraContext "github.com/hcnet/go/services/aurora/internal/context"
@@ -547,3 +548,71 @@ func TestRepeatableReadStream(t *testing.T) {
 		session.AssertExpectations(t)
 	})
 }
+
+type testPathsAction struct {
+	objects      map[uint32]stringObject
+	changed      map[uint32]bool
+	searches     []uint32
+	ledgerSource ledger.Source
+}
+
+func (action *testPathsAction) GetResource(
+	w actions.HeaderWriter,
+	r *http.Request,
+) (interface{}, error) {
+	ledger := action.ledgerSource.CurrentLedger()
+	object, ok := action.objects[ledger]
+	if !ok {
+		return nil, fmt.Errorf("unexpected ledger: %v", ledger)
+	}
+
+	action.searches = append(action.searches, ledger)
+	actions.SetLastLedgerHeader(w, ledger)
+	return object, nil
+}
+
+func (action *testPathsAction) ChangedSince(r *http.Request, ledger uint32) (bool, error) {
+	if last := action.searches[len(action.searches)-1]; ledger != last {
+		return false, fmt.Errorf("expected ledger %v but got %v", last, ledger)
+	}
+	return action.changed[action.ledgerSource.CurrentLedger()], nil
+}
+
+func TestPathsStream(t *testing.T) {
+	request := streamRequest(t, "")
+	action := &testPathsAction{
+		objects: map[uint32]stringObject{
+			3: "a",
+			4: "b",
+			5: "b",
+			6: "c",
+			7: "d",
+		},
+		changed: map[uint32]bool{
+			5: true,
+			6: true,
+		},
+	}
+	ledgerSource := ledger.NewTestingSource(3)
+	action.ledgerSource = ledgerSource
+	handler := streamablePathsHandler{
+		action:        action,
+		streamHandler: sse.StreamHandler{LedgerSourceFactory: &testingFactory{ledgerSource}},
+	}
+
+	st := newStreamTest(
+		handler.renderStream,
+		ledgerSource,
+		request,
+		expectResponse(t, unmarashalString, []string{"a", "b", "c"}),
+	)
+
+	st.AddLedger(4)
+	st.AddLedger(5)
+	st.AddLedger(6)
+	st.AddLedger(7)
+	st.Stop()
+
+	// the paths are only searched again when they may have changed
+	assert.Equal(t, []uint32{3, 5, 6}, action.searches)
+}
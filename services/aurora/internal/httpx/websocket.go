@@ -0,0 +1,412 @@
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/gorilla/websocket"
+
+	"github.com/hcnet/go/services/aurora/internal/render"
+	"github.com/hcnet/go/services/aurora/internal/render/sse"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/support/log"
+	"github.com/hcnet/go/support/render/problem"
+)
+
+const (
+	// webSocketPingPeriod is how often pings are sent to clients, which keeps
+	// idle connections open behind load balancers.
+	webSocketPingPeriod = 30 * time.Second
+	// webSocketPongWait is how long to wait for any message from a client
+	// before closing the connection.
+	webSocketPongWait = 2 * webSocketPingPeriod
+	// webSocketMaxRequestSize is the maximum size of messages sent by
+	// clients.
+	webSocketMaxRequestSize = 4096
+)
+
+// Types of the messages exchanged over WebSocket connections.
+const (
+	webSocketSubscribe    = "subscribe"
+	webSocketUnsubscribe  = "unsubscribe"
+	webSocketSubscribed   = "subscribed"
+	webSocketUnsubscribed = "unsubscribed"
+	webSocketEvent        = "event"
+	webSocketError        = "error"
+)
+
+var webSocketUpgrader = websocket.Upgrader{
+	// Streams are public, like CORS requests from any origin are allowed.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// webSocketRequest is a message sent by clients to subscribe to or to
+// unsubscribe from a stream.
+type webSocketRequest struct {
+	Type string `json:"type"`
+	// ID identifies the subscription in the following messages of the
+	// connection.
+	ID string `json:"id"`
+	// Stream is the path and query of a streaming endpoint, for example
+	// /accounts/{account_id}/payments?cursor=now
+	Stream string `json:"stream"`
+}
+
+// webSocketMessage is a message sent to clients.
+type webSocketMessage struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+	// EventID is the paging token of the record in Data, it can be used as
+	// the cursor of a new subscription to resume the stream.
+	EventID string          `json:"event_id,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	// Error is a problem, as rendered by the HTTP endpoints.
+	Error json.RawMessage `json:"error,omitempty"`
+}
+
+// webSocketHandler serves the /ws endpoint which multiplexes streams over a
+// single WebSocket connection. Every subscription is served by dispatching a
+// streaming request to the router, the events of the stream are sent over the
+// connection instead of as server sent events. Streams which reach their limit
+// or the connection timeout are resumed from their last event, so that
+// subscriptions last until clients unsubscribe.
+type webSocketHandler struct {
+	router           http.Handler
+	maxSubscriptions int
+}
+
+func (handler webSocketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := webSocketUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has replied with an HTTP error.
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	c := &webSocketConnection{
+		handler:       handler,
+		conn:          conn,
+		request:       r,
+		ctx:           ctx,
+		cancel:        cancel,
+		messages:      make(chan webSocketMessage),
+		subscriptions: map[string]*webSocketSubscription{},
+	}
+
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		c.writeMessages()
+	}()
+
+	c.readRequests()
+	cancel()
+	c.wg.Wait()
+	<-writerDone
+	conn.Close()
+}
+
+type webSocketConnection struct {
+	handler webSocketHandler
+	conn    *websocket.Conn
+	request *http.Request
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	messages chan webSocketMessage
+
+	lock          sync.Mutex
+	subscriptions map[string]*webSocketSubscription
+	wg            sync.WaitGroup
+}
+
+func (c *webSocketConnection) readRequests() {
+	c.conn.SetReadLimit(webSocketMaxRequestSize)
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(webSocketPongWait))
+		_, message, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var request webSocketRequest
+		if err = json.Unmarshal(message, &request); err != nil {
+			c.sendProblem("", problem.MakeInvalidFieldProblem("message", errors.New("message is not valid JSON")))
+			continue
+		}
+
+		switch request.Type {
+		case webSocketSubscribe:
+			c.subscribe(request)
+		case webSocketUnsubscribe:
+			c.unsubscribe(request)
+		default:
+			c.sendProblem(request.ID, problem.MakeInvalidFieldProblem(
+				"type", errors.Errorf("type must be %s or %s", webSocketSubscribe, webSocketUnsubscribe),
+			))
+		}
+	}
+}
+
+func (c *webSocketConnection) writeMessages() {
+	// Closing the connection makes readRequests return if writing fails.
+	defer c.cancel()
+	defer c.conn.Close()
+
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(webSocketPongWait))
+	})
+	ticker := time.NewTicker(webSocketPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case message := <-c.messages:
+			if err := c.conn.WriteJSON(message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			deadline := time.Now().Add(webSocketPingPeriod)
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				return
+			}
+		case <-c.ctx.Done():
+			c.conn.WriteControl(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+				time.Now().Add(time.Second),
+			)
+			return
+		}
+	}
+}
+
+// send queues a message, it is dropped if the connection is closed.
+func (c *webSocketConnection) send(message webSocketMessage) {
+	select {
+	case c.messages <- message:
+	case <-c.ctx.Done():
+	}
+}
+
+func (c *webSocketConnection) sendProblem(id string, err error) {
+	w := newBufferedResponseWriter()
+	problem.Render(c.ctx, w, err)
+	c.send(webSocketMessage{Type: webSocketError, ID: id, Error: w.body.Bytes()})
+}
+
+func (c *webSocketConnection) subscribe(request webSocketRequest) {
+	if request.ID == "" {
+		c.sendProblem("", problem.MakeInvalidFieldProblem("id", errors.New("id is required")))
+		return
+	}
+	stream, err := url.Parse(request.Stream)
+	if err != nil || !strings.HasPrefix(stream.Path, "/") || stream.Host != "" {
+		c.sendProblem(request.ID, problem.MakeInvalidFieldProblem(
+			"stream", errors.New("stream must be the path of a streaming endpoint"),
+		))
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.subscriptions[request.ID]; ok {
+		c.sendProblem(request.ID, problem.MakeInvalidFieldProblem("id", errors.New("id is already subscribed")))
+		return
+	}
+	if len(c.subscriptions) >= c.handler.maxSubscriptions {
+		c.sendProblem(request.ID, problem.MakeInvalidFieldProblem(
+			"id",
+			errors.Errorf("connections cannot subscribe to more than %d streams", c.handler.maxSubscriptions),
+		))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	subscription := &webSocketSubscription{connection: c, id: request.ID, cancel: cancel}
+	c.subscriptions[request.ID] = subscription
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.serveSubscription(ctx, subscription, stream)
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		cancel()
+		// The id may have been unsubscribed and subscribed again.
+		if c.subscriptions[request.ID] == subscription {
+			delete(c.subscriptions, request.ID)
+		}
+	}()
+}
+
+func (c *webSocketConnection) unsubscribe(request webSocketRequest) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	subscription, ok := c.subscriptions[request.ID]
+	if !ok {
+		c.sendProblem(request.ID, problem.MakeInvalidFieldProblem("id", errors.New("id is not subscribed")))
+		return
+	}
+	subscription.cancel()
+	delete(c.subscriptions, request.ID)
+}
+
+func (c *webSocketConnection) serveSubscription(
+	ctx context.Context,
+	subscription *webSocketSubscription,
+	stream *url.URL,
+) {
+	id := subscription.id
+	for {
+		subscription.opened = false
+		subscription.closed = false
+		w := newBufferedResponseWriter()
+		c.handler.router.ServeHTTP(w, c.streamRequest(ctx, subscription, stream))
+
+		switch {
+		case ctx.Err() != nil:
+			c.send(webSocketMessage{Type: webSocketUnsubscribed, ID: id})
+			return
+		case subscription.failed:
+			return
+		case !subscription.opened && strings.HasPrefix(w.header.Get("Content-Type"), "application/problem+json"):
+			// The request failed before the stream started, w contains the
+			// problem.
+			c.send(webSocketMessage{Type: webSocketError, ID: id, Error: w.body.Bytes()})
+			return
+		case !subscription.opened || !subscription.closed:
+			c.sendProblem(id, problem.MakeInvalidFieldProblem(
+				"stream", errors.New("stream must be the path of a streaming endpoint"),
+			))
+			return
+		}
+
+		// The stream has sent its limit of events or reached the connection
+		// timeout, resume it from the last event.
+		if subscription.cursor != "" {
+			query := stream.Query()
+			query.Set("cursor", subscription.cursor)
+			stream.RawQuery = query.Encode()
+		}
+		log.Ctx(ctx).WithField("stream", stream.String()).Debug("resuming websocket subscription")
+	}
+}
+
+// streamRequest returns a streaming request for stream, sharing the client
+// information of the upgrade request.
+func (c *webSocketConnection) streamRequest(
+	ctx context.Context,
+	subscription *webSocketSubscription,
+	stream *url.URL,
+) *http.Request {
+	// The request must be routed again by the router rather than by the route
+	// of the upgrade request.
+	ctx = context.WithValue(ctx, chi.RouteCtxKey, nil)
+	ctx = sse.WithEventWriter(ctx, subscription)
+
+	r := c.request.Clone(ctx)
+	r.Method = http.MethodGet
+	r.URL = &url.URL{Path: stream.Path, RawQuery: stream.RawQuery}
+	r.RequestURI = r.URL.RequestURI()
+	for _, header := range []string{
+		"Connection", "Upgrade", "Last-Event-ID",
+		"Sec-Websocket-Key", "Sec-Websocket-Version", "Sec-Websocket-Extensions", "Sec-Websocket-Protocol",
+	} {
+		r.Header.Del(header)
+	}
+	r.Header.Set("Accept", render.MimeEventStream)
+	return r
+}
+
+// webSocketSubscription receives the events of the stream of a subscription
+// and sends them over the connection.
+type webSocketSubscription struct {
+	connection *webSocketConnection
+	id         string
+	cancel     context.CancelFunc
+
+	// opened and closed are set when the stream sends its first and last
+	// events, failed is set when it sends an error.
+	opened bool
+	closed bool
+	failed bool
+	// subscribed is set once the client has been notified of the
+	// subscription.
+	subscribed bool
+	cursor     string
+	// lastData is the data of the last event without ID, events of object
+	// streams are not sent again when the stream is resumed.
+	lastData []byte
+}
+
+func (s *webSocketSubscription) WriteEvent(e sse.Event) {
+	switch {
+	case e.Error != nil:
+		s.failed = true
+		err := e.Error
+		if problem.IsKnownError(err) == nil {
+			// The error has been logged by the stream.
+			err = problem.ServerError
+		}
+		s.connection.sendProblem(s.id, err)
+	case e.Event == "open":
+		s.opened = true
+		if !s.subscribed {
+			s.subscribed = true
+			s.connection.send(webSocketMessage{Type: webSocketSubscribed, ID: s.id})
+		}
+	case e.Event == "close":
+		s.closed = true
+	default:
+		data, err := json.Marshal(e.Data)
+		if err != nil {
+			panic(fmt.Sprintf("could not encode event: %v", err))
+		}
+		if e.ID != "" {
+			s.cursor = e.ID
+		} else if bytes.Equal(data, s.lastData) {
+			return
+		} else {
+			s.lastData = data
+		}
+		s.connection.send(webSocketMessage{Type: webSocketEvent, ID: s.id, EventID: e.ID, Data: data})
+	}
+}
+
+// bufferedResponseWriter is a http.ResponseWriter keeping the response in
+// memory.
+type bufferedResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponseWriter() *bufferedResponseWriter {
+	return &bufferedResponseWriter{header: http.Header{}}
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
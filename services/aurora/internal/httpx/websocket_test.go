@@ -0,0 +1,114 @@
+package httpx
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hcnet/go/services/aurora/internal/ledger"
+	"github.com/hcnet/go/services/aurora/internal/render/sse"
+)
+
+func readWebSocketMessages(t *testing.T, conn *websocket.Conn, count int) map[string][]webSocketMessage {
+	messages := map[string][]webSocketMessage{}
+	for i := 0; i < count; i++ {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(10*time.Second)))
+		var message webSocketMessage
+		require.NoError(t, conn.ReadJSON(&message))
+		messages[message.ID] = append(messages[message.ID], message)
+	}
+	return messages
+}
+
+func TestWebSocketSubscriptions(t *testing.T) {
+	state := &ledger.State{}
+	state.SetAuroraStatus(ledger.AuroraStatus{ExpHistoryLatest: 3})
+	notifier := ledger.NewNotifier(time.Millisecond, state)
+	streamHandler := sse.StreamHandler{LedgerSourceFactory: notifier}
+
+	router := chi.NewRouter()
+	router.Method(http.MethodGet, "/pages", streamableHistoryPageHandler(state, &testPageAction{
+		objects: map[uint32][]string{
+			3: {"a", "b", "c"},
+			4: {"a", "b", "c", "d"},
+		},
+		ledgerSource: notifier.Get(),
+	}, streamHandler))
+	router.Method(http.MethodGet, "/object", streamableObjectActionHandler{
+		action: &testObjectAction{
+			objects:      map[uint32]stringObject{3: "x", 4: "y"},
+			ledgerSource: notifier.Get(),
+		},
+		streamHandler: streamHandler,
+	})
+	router.Method(http.MethodGet, "/ws", webSocketHandler{router: router, maxSubscriptions: 2})
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The page stream ends after 2 events and is resumed from its last event.
+	require.NoError(t, conn.WriteJSON(webSocketRequest{Type: "subscribe", ID: "p", Stream: "/pages?limit=2"}))
+	require.NoError(t, conn.WriteJSON(webSocketRequest{Type: "subscribe", ID: "o", Stream: "/object"}))
+	messages := readWebSocketMessages(t, conn, 6)
+	assert.Equal(t, []webSocketMessage{
+		{Type: "subscribed", ID: "p"},
+		{Type: "event", ID: "p", EventID: "1", Data: json.RawMessage(`{"value":"a"}`)},
+		{Type: "event", ID: "p", EventID: "2", Data: json.RawMessage(`{"value":"b"}`)},
+		{Type: "event", ID: "p", EventID: "3", Data: json.RawMessage(`{"value":"c"}`)},
+	}, messages["p"])
+	assert.Equal(t, []webSocketMessage{
+		{Type: "subscribed", ID: "o"},
+		{Type: "event", ID: "o", Data: json.RawMessage(`"x"`)},
+	}, messages["o"])
+
+	require.NoError(t, conn.WriteJSON(webSocketRequest{Type: "subscribe", ID: "p", Stream: "/object"}))
+	messages = readWebSocketMessages(t, conn, 1)
+	require.Len(t, messages["p"], 1)
+	assert.Equal(t, "error", messages["p"][0].Type)
+	assert.Contains(t, string(messages["p"][0].Error), "id is already subscribed")
+
+	require.NoError(t, conn.WriteJSON(webSocketRequest{Type: "subscribe", ID: "n", Stream: "/object"}))
+	messages = readWebSocketMessages(t, conn, 1)
+	require.Len(t, messages["n"], 1)
+	assert.Contains(t, string(messages["n"][0].Error), "connections cannot subscribe to more than 2 streams")
+
+	state.SetAuroraStatus(ledger.AuroraStatus{ExpHistoryLatest: 4})
+	messages = readWebSocketMessages(t, conn, 2)
+	assert.Equal(t, []webSocketMessage{
+		{Type: "event", ID: "p", EventID: "4", Data: json.RawMessage(`{"value":"d"}`)},
+	}, messages["p"])
+	assert.Equal(t, []webSocketMessage{
+		{Type: "event", ID: "o", Data: json.RawMessage(`"y"`)},
+	}, messages["o"])
+
+	require.NoError(t, conn.WriteJSON(webSocketRequest{Type: "unsubscribe", ID: "o"}))
+	messages = readWebSocketMessages(t, conn, 1)
+	assert.Equal(t, []webSocketMessage{{Type: "unsubscribed", ID: "o"}}, messages["o"])
+
+	require.NoError(t, conn.WriteJSON(webSocketRequest{Type: "subscribe", ID: "m", Stream: "/missing"}))
+	messages = readWebSocketMessages(t, conn, 1)
+	require.Len(t, messages["m"], 1)
+	assert.Equal(t, "error", messages["m"][0].Type)
+	assert.Contains(t, string(messages["m"][0].Error), "stream must be the path of a streaming endpoint")
+
+	require.NoError(t, conn.WriteJSON(webSocketRequest{Type: "subscribe", ID: "e", Stream: "/pages?cursor=-1"}))
+	messages = readWebSocketMessages(t, conn, 1)
+	require.Len(t, messages["e"], 1)
+	assert.Contains(t, string(messages["e"][0].Error), `"status":400`)
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("{")))
+	messages = readWebSocketMessages(t, conn, 1)
+	require.Len(t, messages[""], 1)
+	assert.Contains(t, string(messages[""][0].Error), "message is not valid JSON")
+}
@@ -468,6 +468,7 @@ func (r resumeState) run(s *system) (transition, error) {
 	}
 
 	startTime = time.Now()
+	s.status.setCurrentLedger(ingestLedger)
 
 	log.WithFields(logpkg.F{
 		"sequence": ingestLedger,
@@ -512,6 +513,7 @@ func (r resumeState) run(s *system) (transition, error) {
 	tradeStatsMap := stats.tradeStats.Map()
 	r.addLedgerStatsMetricFromMap(s, "trades", tradeStatsMap)
 	r.addProcessorDurationsMetricFromMap(s, stats.transactionDurations)
+	s.status.addLedger(ingestLedger, time.Since(startTime), stats.changeDurations, stats.transactionDurations)
 
 	localLog := log.WithFields(logpkg.F{
 		"sequence": ingestLedger,
@@ -532,6 +534,7 @@ func (r resumeState) run(s *system) (transition, error) {
 
 	s.maybeVerifyState(ingestLedger)
 	s.maybeReapLookupTables(ingestLedger)
+	s.maybeCreateHistoryPartitions(ingestLedger)
 
 	return resumeImmediately(ingestLedger), nil
 }
@@ -626,6 +629,7 @@ func (h historyRangeState) run(s *system) (transition, error) {
 			"duration": time.Since(startTime).Seconds(),
 		}).Info("Ledger returned from the backend")
 
+		s.status.setCurrentLedger(cur)
 		if err = runTransactionProcessorsOnLedger(s, ledgerCloseMeta); err != nil {
 			return start(), err
 		}
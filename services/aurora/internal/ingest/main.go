@@ -178,6 +178,10 @@ type System interface {
 	BuildState(sequence uint32, skipChecks bool) error
 	ReingestRange(ledgerRanges []history.LedgerRange, force bool) error
 	BuildGenesisState() error
+	Status() Status
+	TriggerStateRebuild(ctx context.Context) error
+	Pause()
+	Resume()
 	Shutdown()
 }
 
@@ -211,6 +215,8 @@ type system struct {
 	checkpointManager historyarchive.CheckpointManager
 
 	reapOffsets map[string]int64
+
+	status statusTracker
 }
 
 func NewSystem(config Config) (System, error) {
@@ -605,8 +611,20 @@ func (s *system) runStateMachine(cur stateMachineNode) error {
 	}()
 
 	log.WithFields(logpkg.F{"current_state": cur}).Info("Ingestion system initial state")
+	s.status.enter(cur)
 
 	for {
+		if paused := s.status.pausedChan(); paused != nil {
+			log.WithField("current_state", cur).Info("Ingestion system paused")
+			select {
+			case <-s.ctx.Done():
+				log.Info("Received shut down signal...")
+				return nil
+			case <-paused:
+				log.WithField("current_state", cur).Info("Ingestion system resumed")
+			}
+		}
+
 		// Every node in the state machine is responsible for
 		// creating and disposing its own transaction.
 		// We should never enter a new state with the transaction
@@ -631,6 +649,8 @@ func (s *system) runStateMachine(cur stateMachineNode) error {
 			}
 		}
 
+		s.status.transition(cur, next.node, err)
+
 		// Exit after processing shutdownState
 		if next.node == (stopState{}) {
 			log.Info("Shut down")
@@ -652,6 +672,35 @@ func (s *system) runStateMachine(cur stateMachineNode) error {
 	}
 }
 
+// Status returns the current status of the ingestion state machine.
+func (s *system) Status() Status {
+	return s.status.status()
+}
+
+// TriggerStateRebuild updates the ingestion version in the DB so the state is
+// rebuilt by the leading ingesting instance when it processes the next ledger.
+// It is the equivalent of the `aurora ingest trigger-state-rebuild` command.
+func (s *system) TriggerStateRebuild(ctx context.Context) error {
+	q := s.historyQ.CloneIngestionQ()
+	if err := q.UpdateIngestVersion(ctx, 0); err != nil {
+		return errors.Wrap(err, "Error updating ingestion version")
+	}
+	log.Info("Triggered state rebuild")
+	return nil
+}
+
+// Pause makes the ingestion state machine stop before running the next state.
+// The state currently being run (ex. processing a ledger) is not interrupted.
+func (s *system) Pause() {
+	s.status.pause()
+	log.Info("Pausing ingestion system...")
+}
+
+// Resume resumes ingestion paused with Pause.
+func (s *system) Resume() {
+	s.status.resume()
+}
+
 func (s *system) maybeVerifyState(lastIngestedLedger uint32) {
 	stateInvalid, err := s.historyQ.GetExpStateInvalid(s.ctx)
 	if err != nil {
@@ -693,6 +742,55 @@ func (s *system) maybeVerifyState(lastIngestedLedger uint32) {
 	}
 }
 
+// historyPartitionLookahead is the number of ledgers (roughly a week)
+// following the latest ingested ledger for which partitions of partitioned
+// history tables are created in advance.
+const historyPartitionLookahead = 100_000
+
+// maybeCreateHistoryPartitions creates, on checkpoint ledgers, the partitions
+// of partitioned history tables required to ingest the upcoming ledgers. Rows
+// of ledgers without a partition are stored in the default partition.
+func (s *system) maybeCreateHistoryPartitions(lastIngestedLedger uint32) {
+	if !s.checkpointManager.IsCheckpoint(lastIngestedLedger) {
+		return
+	}
+
+	if err := s.historyQ.Begin(); err != nil {
+		log.WithField("err", err).Error("Error starting a transaction")
+		return
+	}
+	defer s.historyQ.Rollback()
+
+	// Block ingestion in the cluster while creating partitions
+	if _, err := s.historyQ.GetLastLedgerIngest(s.ctx); err != nil {
+		log.WithField("err", err).Error(getLastIngestedErrMsg)
+		return
+	}
+
+	var created []history.HistoryPartition
+	for _, table := range history.HistoryPartitionedTables {
+		partitions, err := s.historyQ.CreateHistoryPartitions(s.ctx, table, lastIngestedLedger+historyPartitionLookahead)
+		if err != nil {
+			log.WithField("err", err).Error("Error creating history partitions")
+			return
+		}
+		created = append(created, partitions...)
+	}
+
+	if err := s.historyQ.Commit(); err != nil {
+		log.WithField("err", err).Error("Error committing a transaction")
+		return
+	}
+
+	for _, partition := range created {
+		log.
+			WithField("partition", partition.Name).
+			WithField("start_ledger", partition.StartLedger).
+			WithField("end_ledger", partition.EndLedger).
+			Info("Created history partition")
+	}
+}
+
 func (s *system) maybeReapLookupTables(lastIngestedLedger uint32) {
 	if !s.config.EnableReapLookupTables {
 		return
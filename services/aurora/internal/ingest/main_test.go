@@ -197,6 +197,39 @@ func TestMaybeVerifyStateGetExpStateInvalidError(t *testing.T) {
 
 	historyQ.AssertExpectations(t)
 }
+
+func TestMaybeCreateHistoryPartitions(t *testing.T) {
+	historyQ := &mockDBQ{}
+	system := &system{
+		historyQ:          historyQ,
+		ctx:               context.Background(),
+		checkpointManager: historyarchive.NewCheckpointManager(64),
+	}
+
+	// partitions are only created on checkpoint ledgers
+	system.maybeCreateHistoryPartitions(64)
+
+	historyQ.On("Begin").Return(nil).Once()
+	historyQ.On("GetLastLedgerIngest", system.ctx).Return(uint32(63), nil).Once()
+	for _, table := range history.HistoryPartitionedTables {
+		historyQ.On("CreateHistoryPartitions", system.ctx, table, uint32(63+historyPartitionLookahead)).
+			Return([]history.HistoryPartition{}, nil).Once()
+	}
+	historyQ.On("Commit").Return(nil).Once()
+	historyQ.On("Rollback").Return(nil).Once()
+	system.maybeCreateHistoryPartitions(63)
+
+	// the transaction is rolled back when a partition can't be created
+	historyQ.On("Begin").Return(nil).Once()
+	historyQ.On("GetLastLedgerIngest", system.ctx).Return(uint32(127), nil).Once()
+	historyQ.On("CreateHistoryPartitions", system.ctx, history.HistoryPartitionedTables[0], uint32(127+historyPartitionLookahead)).
+		Return([]history.HistoryPartition{}, errors.New("my error")).Once()
+	historyQ.On("Rollback").Return(nil).Once()
+	system.maybeCreateHistoryPartitions(127)
+
+	historyQ.AssertExpectations(t)
+}
+
 func TestMaybeVerifyInternalDBErrCancelOrContextCanceled(t *testing.T) {
 	historyQ := &mockDBQ{}
 	system := &system{
@@ -238,6 +271,7 @@ type mockDBQ struct {
 	mock.Mock
 
 	history.MockQAccounts
+	history.MockQAccountBalances
 	history.MockQFilter
 	history.MockQClaimableBalances
 	history.MockQHistoryClaimableBalances
@@ -249,6 +283,7 @@ type mockDBQ struct {
 	history.MockQLedgers
 	history.MockQOffers
 	history.MockQOperations
+	history.MockQOrderBookSnapshots
 	history.MockQSigners
 	history.MockQTransactions
 	history.MockQTrustLines
@@ -374,6 +409,11 @@ func (m *mockDBQ) NewTradeBatchInsertBuilder(maxBatchSize int) history.TradeBatc
 	return args.Get(0).(history.TradeBatchInsertBuilder)
 }
 
+func (m *mockDBQ) CreateHistoryPartitions(ctx context.Context, table string, untilLedger uint32) ([]history.HistoryPartition, error) {
+	args := m.Called(ctx, table, untilLedger)
+	return args.Get(0).([]history.HistoryPartition), args.Error(1)
+}
+
 func (m *mockDBQ) ReapLookupTables(ctx context.Context, offsets map[string]int64) (map[string]int64, map[string]int64, error) {
 	args := m.Called(ctx, offsets)
 	var r1, r2 map[string]int64
@@ -543,6 +583,24 @@ func (m *mockSystem) BuildGenesisState() error {
 	return args.Error(0)
 }
 
+func (m *mockSystem) Status() Status {
+	args := m.Called()
+	return args.Get(0).(Status)
+}
+
+func (m *mockSystem) TriggerStateRebuild(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *mockSystem) Pause() {
+	m.Called()
+}
+
+func (m *mockSystem) Resume() {
+	m.Called()
+}
+
 func (m *mockSystem) Shutdown() {
 	m.Called()
 }
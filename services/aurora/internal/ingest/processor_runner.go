@@ -50,6 +50,18 @@ func (statsLedgerTransactionProcessor) Commit(ctx context.Context) error {
 	return nil
 }
 
+// filteredOutAccountBalancesProcessor collects the balance changes of the
+// transactions dropped by the ingestion filters into the account balances
+// processor of the ledger, which is committed with the other transaction
+// processors, so that the balance history is complete with filtering.
+type filteredOutAccountBalancesProcessor struct {
+	*processors.AccountBalancesProcessor
+}
+
+func (filteredOutAccountBalancesProcessor) Commit(ctx context.Context) error {
+	return nil
+}
+
 type ledgerStats struct {
 	changeStats          ingest.StatsChangeProcessorResults
 	changeDurations      processorsRunDurations
@@ -133,6 +145,7 @@ func buildChangeProcessor(
 func (s *ProcessorRunner) buildTransactionProcessor(
 	ledgerTransactionStats *processors.StatsLedgerTransactionProcessor,
 	tradeProcessor *processors.TradeProcessor,
+	accountBalancesProcessor *processors.AccountBalancesProcessor,
 	ledger xdr.LedgerHeaderHistoryEntry,
 ) *groupTransactionProcessors {
 	statsLedgerTransactionProcessor := &statsLedgerTransactionProcessor{
@@ -140,6 +153,7 @@ func (s *ProcessorRunner) buildTransactionProcessor(
 	}
 	*tradeProcessor = *processors.NewTradeProcessor(s.historyQ, ledger)
 	sequence := uint32(ledger.Header.LedgerSeq)
+	*accountBalancesProcessor = *processors.NewAccountBalancesProcessor(s.historyQ, sequence)
 	return newGroupTransactionProcessors([]auroraTransactionProcessor{
 		statsLedgerTransactionProcessor,
 		processors.NewEffectProcessor(s.historyQ, sequence),
@@ -150,6 +164,7 @@ func (s *ProcessorRunner) buildTransactionProcessor(
 		processors.NewTransactionProcessor(s.historyQ, sequence),
 		processors.NewClaimableBalancesTransactionProcessor(s.historyQ, sequence),
 		processors.NewLiquidityPoolsTransactionProcessor(s.historyQ, sequence),
+		accountBalancesProcessor,
 	})
 }
 
@@ -162,12 +177,17 @@ func (s *ProcessorRunner) buildTransactionFilterer() *groupTransactionFilterers
 	return newGroupTransactionFilterers(f)
 }
 
-func (s *ProcessorRunner) buildFilteredOutProcessor(ledger xdr.LedgerHeaderHistoryEntry) *groupTransactionProcessors {
+func (s *ProcessorRunner) buildFilteredOutProcessor(
+	ledger xdr.LedgerHeaderHistoryEntry,
+	accountBalancesProcessor *processors.AccountBalancesProcessor,
+) *groupTransactionProcessors {
 	// when in online mode, the submission result processor must always run (regardless of filtering)
 	var p []auroraTransactionProcessor
 	if s.config.EnableIngestionFiltering {
 		txSubProc := processors.NewTransactionFilteredTmpProcessor(s.historyQ, uint32(ledger.Header.LedgerSeq))
 		p = append(p, txSubProc)
+		// balances are reconstructed from the changes of all transactions
+		p = append(p, filteredOutAccountBalancesProcessor{accountBalancesProcessor})
 	}
 
 	return newGroupTransactionProcessors(p)
@@ -304,9 +324,10 @@ func (s *ProcessorRunner) RunTransactionProcessorsOnLedger(ledger xdr.LedgerClos
 	err error,
 ) {
 	var (
-		ledgerTransactionStats processors.StatsLedgerTransactionProcessor
-		tradeProcessor         processors.TradeProcessor
-		transactionReader      *ingest.LedgerTransactionReader
+		ledgerTransactionStats   processors.StatsLedgerTransactionProcessor
+		tradeProcessor           processors.TradeProcessor
+		accountBalancesProcessor processors.AccountBalancesProcessor
+		transactionReader        *ingest.LedgerTransactionReader
 	)
 
 	transactionReader, err = ingest.NewLedgerTransactionReaderFromLedgerCloseMeta(s.config.NetworkPassphrase, ledger)
@@ -321,9 +342,9 @@ func (s *ProcessorRunner) RunTransactionProcessorsOnLedger(ledger xdr.LedgerClos
 	}
 	header := transactionReader.GetHeader()
 	groupTransactionFilterers := s.buildTransactionFilterer()
-	groupFilteredOutProcessors := s.buildFilteredOutProcessor(header)
 	groupTransactionProcessors := s.buildTransactionProcessor(
-		&ledgerTransactionStats, &tradeProcessor, header)
+		&ledgerTransactionStats, &tradeProcessor, &accountBalancesProcessor, header)
+	groupFilteredOutProcessors := s.buildFilteredOutProcessor(header, &accountBalancesProcessor)
 	err = processors.StreamLedgerTransactions(s.ctx,
 		groupTransactionFilterers,
 		groupFilteredOutProcessors,
@@ -378,6 +399,13 @@ func (s *ProcessorRunner) RunAllProcessorsOnLedger(ledger xdr.LedgerCloseMeta) (
 	}
 
 	groupChangeProcessors := buildChangeProcessor(s.historyQ, &changeStatsProcessor, ledgerSource, ledger.LedgerSequence())
+	// order book snapshots are built from the offers and liquidity pools
+	// committed by the processors above
+	closedAt := time.Unix(int64(ledger.MustV0().LedgerHeader.Header.ScpValue.CloseTime), 0).UTC()
+	groupChangeProcessors.processors = append(
+		groupChangeProcessors.processors,
+		processors.NewOrderBookSnapshotsProcessor(s.historyQ, ledger.LedgerSequence(), closedAt),
+	)
 	err = s.runChangeProcessorOnLedger(groupChangeProcessors, ledger)
 	if err != nil {
 		return
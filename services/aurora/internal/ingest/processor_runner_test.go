@@ -253,8 +253,9 @@ func TestProcessorRunnerBuildTransactionProcessor(t *testing.T) {
 
 	stats := &processors.StatsLedgerTransactionProcessor{}
 	trades := &processors.TradeProcessor{}
+	accountBalances := &processors.AccountBalancesProcessor{}
 	ledger := xdr.LedgerHeaderHistoryEntry{}
-	processor := runner.buildTransactionProcessor(stats, trades, ledger)
+	processor := runner.buildTransactionProcessor(stats, trades, accountBalances, ledger)
 	assert.IsType(t, &groupTransactionProcessors{}, processor)
 
 	assert.IsType(t, &statsLedgerTransactionProcessor{}, processor.processors[0])
@@ -266,6 +267,32 @@ func TestProcessorRunnerBuildTransactionProcessor(t *testing.T) {
 	assert.IsType(t, &processors.TransactionProcessor{}, processor.processors[6])
 }
 
+func TestProcessorRunnerBuildFilteredOutProcessor(t *testing.T) {
+	q := &mockDBQ{}
+	defer mock.AssertExpectationsForObjects(t, q)
+
+	q.MockQTransactions.On("NewTransactionFilteredTmpBatchInsertBuilder", 100000).
+		Return(&history.MockTransactionsBatchInsertBuilder{}).Once()
+
+	runner := ProcessorRunner{
+		ctx:      context.Background(),
+		config:   Config{EnableIngestionFiltering: true},
+		historyQ: q,
+	}
+
+	// the balance changes of filtered out transactions are collected by the
+	// account balances processor of the ledger
+	accountBalances := &processors.AccountBalancesProcessor{}
+	processor := runner.buildFilteredOutProcessor(xdr.LedgerHeaderHistoryEntry{}, accountBalances)
+	assert.Len(t, processor.processors, 2)
+	assert.IsType(t, &processors.TransactionProcessor{}, processor.processors[0])
+	assert.Equal(t, filteredOutAccountBalancesProcessor{accountBalances}, processor.processors[1])
+
+	runner.config.EnableIngestionFiltering = false
+	processor = runner.buildFilteredOutProcessor(xdr.LedgerHeaderHistoryEntry{}, accountBalances)
+	assert.Empty(t, processor.processors)
+}
+
 func TestProcessorRunnerWithFilterEnabled(t *testing.T) {
 	ctx := context.Background()
 	maxBatchSize := 100000
@@ -319,6 +346,10 @@ func TestProcessorRunnerWithFilterEnabled(t *testing.T) {
 	q.MockQLedgers.On("InsertLedger", ctx, ledger.V0.LedgerHeader, 0, 0, 0, 0, CurrentVersion).
 		Return(int64(1), nil).Once()
 
+	// order books are snapshotted every OrderBookSnapshotsInterval ledgers
+	q.MockQOrderBookSnapshots.On("GetTradingPairsModifiedSince", ctx, uint32(0)).
+		Return([][2]xdr.Asset(nil), nil).Once()
+
 	runner := ProcessorRunner{
 		ctx:      ctx,
 		config:   config,
@@ -375,6 +406,10 @@ func TestProcessorRunnerRunAllProcessorsOnLedger(t *testing.T) {
 	q.MockQLedgers.On("InsertLedger", ctx, ledger.V0.LedgerHeader, 0, 0, 0, 0, CurrentVersion).
 		Return(int64(1), nil).Once()
 
+	// order books are snapshotted every OrderBookSnapshotsInterval ledgers
+	q.MockQOrderBookSnapshots.On("GetTradingPairsModifiedSince", ctx, uint32(0)).
+		Return([][2]xdr.Asset(nil), nil).Once()
+
 	runner := ProcessorRunner{
 		ctx:      ctx,
 		config:   config,
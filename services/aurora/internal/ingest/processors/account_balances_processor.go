@@ -0,0 +1,134 @@
+package processors
+
+import (
+	"context"
+
+	"github.com/guregu/null"
+
+	"github.com/hcnet/go/ingest"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/toid"
+	"github.com/hcnet/go/xdr"
+)
+
+type accountBalanceKey struct {
+	accountID   string
+	assetType   xdr.AssetType
+	assetCode   string
+	assetIssuer string
+}
+
+// AccountBalancesProcessor records the native and trust line balances which
+// changed in a ledger, together with their value before the ledger, so that
+// the balances of an account at a past ledger can be reconstructed.
+// Liquidity pool share trust lines are ignored. The changes of the
+// transactions dropped by the ingestion filters are recorded too, otherwise
+// the balances reconstructed from the recorded ones would be wrong.
+type AccountBalancesProcessor struct {
+	balancesQ   history.QAccountBalances
+	sequence    uint32
+	feeChanges  []ingest.Change
+	metaChanges []ingest.Change
+}
+
+func NewAccountBalancesProcessor(balancesQ history.QAccountBalances, sequence uint32) *AccountBalancesProcessor {
+	return &AccountBalancesProcessor{
+		balancesQ: balancesQ,
+		sequence:  sequence,
+	}
+}
+
+// ProcessTransaction collects the balance changes of the given transaction.
+func (p *AccountBalancesProcessor) ProcessTransaction(ctx context.Context, transaction ingest.LedgerTransaction) error {
+	p.feeChanges = append(p.feeChanges, transaction.GetFeeChanges()...)
+	changes, err := transaction.GetChanges()
+	if err != nil {
+		return errors.Wrap(err, "Error getting transaction changes")
+	}
+	p.metaChanges = append(p.metaChanges, changes...)
+	return nil
+}
+
+// Commit inserts a row for every balance which changed in the ledger.
+func (p *AccountBalancesProcessor) Commit(ctx context.Context) error {
+	// hcnet-core charges the fees of all transactions in the ledger before
+	// applying them so fee changes are replayed first.
+	var keys []accountBalanceKey
+	rows := map[accountBalanceKey]*history.AccountBalance{}
+	for _, changes := range [][]ingest.Change{p.feeChanges, p.metaChanges} {
+		for _, change := range changes {
+			key, pre, post, ok := balanceChange(change)
+			if !ok {
+				continue
+			}
+			row, exists := rows[key]
+			if !exists {
+				row = &history.AccountBalance{
+					LedgerToid:      toid.New(int32(p.sequence), 0, 0).ToInt64(),
+					AccountID:       key.accountID,
+					AssetType:       key.assetType,
+					AssetCode:       key.assetCode,
+					AssetIssuer:     key.assetIssuer,
+					PreviousBalance: pre,
+				}
+				rows[key] = row
+				keys = append(keys, key)
+			}
+			row.Balance = post
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	batch := p.balancesQ.NewAccountBalanceBatchInsertBuilder(maxBatchSize)
+	for _, key := range keys {
+		if err := batch.Add(ctx, *rows[key]); err != nil {
+			return errors.Wrap(err, "Error adding account balance to batch")
+		}
+	}
+	if err := batch.Exec(ctx); err != nil {
+		return errors.Wrap(err, "Error flushing account balance batch")
+	}
+	return nil
+}
+
+// balanceChange returns the balance before and after the change of an account
+// or a trust line entry. ok is false for other entries.
+func balanceChange(change ingest.Change) (key accountBalanceKey, pre, post null.Int, ok bool) {
+	switch change.Type {
+	case xdr.LedgerEntryTypeAccount:
+		if change.Pre != nil {
+			account := change.Pre.Data.MustAccount()
+			key.accountID = account.AccountId.Address()
+			pre = null.IntFrom(int64(account.Balance))
+		}
+		if change.Post != nil {
+			account := change.Post.Data.MustAccount()
+			key.accountID = account.AccountId.Address()
+			post = null.IntFrom(int64(account.Balance))
+		}
+		key.assetType = xdr.AssetTypeAssetTypeNative
+		return key, pre, post, true
+	case xdr.LedgerEntryTypeTrustline:
+		var trustLine xdr.TrustLineEntry
+		if change.Pre != nil {
+			trustLine = change.Pre.Data.MustTrustLine()
+			pre = null.IntFrom(int64(trustLine.Balance))
+		}
+		if change.Post != nil {
+			trustLine = change.Post.Data.MustTrustLine()
+			post = null.IntFrom(int64(trustLine.Balance))
+		}
+		if trustLine.Asset.Type == xdr.AssetTypeAssetTypePoolShare {
+			return key, pre, post, false
+		}
+		key.accountID = trustLine.AccountId.Address()
+		trustLine.Asset.MustExtract(&key.assetType, &key.assetCode, &key.assetIssuer)
+		return key, pre, post, true
+	default:
+		return key, pre, post, false
+	}
+}
@@ -0,0 +1,133 @@
+package processors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hcnet/go/ingest"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/toid"
+	"github.com/hcnet/go/xdr"
+)
+
+func accountBalanceEntry(address string, balance xdr.Int64) xdr.LedgerEntry {
+	return xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeAccount,
+			Account: &xdr.AccountEntry{
+				AccountId: xdr.MustAddress(address),
+				Balance:   balance,
+			},
+		},
+	}
+}
+
+func trustLineBalanceEntry(address string, asset xdr.TrustLineAsset, balance xdr.Int64) xdr.LedgerEntry {
+	return xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeTrustline,
+			TrustLine: &xdr.TrustLineEntry{
+				AccountId: xdr.MustAddress(address),
+				Asset:     asset,
+				Balance:   balance,
+			},
+		},
+	}
+}
+
+func updateChanges(pre, post xdr.LedgerEntry) xdr.LedgerEntryChanges {
+	return xdr.LedgerEntryChanges{
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &pre},
+		{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: &post},
+	}
+}
+
+func balancesTransaction(feeChanges xdr.LedgerEntryChanges, opChanges ...xdr.LedgerEntryChanges) ingest.LedgerTransaction {
+	var operations []xdr.OperationMeta
+	for _, changes := range opChanges {
+		operations = append(operations, xdr.OperationMeta{Changes: changes})
+	}
+	return ingest.LedgerTransaction{
+		FeeChanges: feeChanges,
+		UnsafeMeta: xdr.TransactionMeta{
+			V:  2,
+			V2: &xdr.TransactionMetaV2{Operations: operations},
+		},
+		Result: xdr.TransactionResultPair{
+			Result: xdr.TransactionResult{
+				Result: xdr.TransactionResultResult{
+					Code: xdr.TransactionResultCodeTxSuccess,
+				},
+			},
+		},
+	}
+}
+
+func TestAccountBalancesProcessor(t *testing.T) {
+	ctx := context.Background()
+	source := "GAOQJGUAB7NI7K7I62ORBXMN3J4SSWQUQ7FOEPSDJ322W2HMCNWPHXFB"
+	destination := "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H"
+	usd := xdr.MustNewCreditAsset("USD", source).ToTrustLineAsset()
+	poolShare := xdr.TrustLineAsset{
+		Type:            xdr.AssetTypeAssetTypePoolShare,
+		LiquidityPoolId: &xdr.PoolId{1, 2, 3},
+	}
+
+	first := balancesTransaction(
+		updateChanges(accountBalanceEntry(source, 1000), accountBalanceEntry(source, 900)),
+		updateChanges(accountBalanceEntry(source, 800), accountBalanceEntry(source, 500)),
+	)
+	created := trustLineBalanceEntry(destination, usd, 0)
+	removedPoolShare := trustLineBalanceEntry(destination, poolShare, 0)
+	second := balancesTransaction(
+		updateChanges(accountBalanceEntry(source, 900), accountBalanceEntry(source, 800)),
+		xdr.LedgerEntryChanges{
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &created},
+			{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &removedPoolShare},
+			{
+				Type:    xdr.LedgerEntryChangeTypeLedgerEntryRemoved,
+				Removed: &xdr.LedgerKey{Type: xdr.LedgerEntryTypeTrustline},
+			},
+		},
+		updateChanges(trustLineBalanceEntry(destination, usd, 0), trustLineBalanceEntry(destination, usd, 400)),
+	)
+
+	q := &history.MockQAccountBalances{}
+	batch := &history.MockAccountBalanceBatchInsertBuilder{}
+	processor := NewAccountBalancesProcessor(q, 20)
+	assert.NoError(t, processor.ProcessTransaction(ctx, first))
+	assert.NoError(t, processor.ProcessTransaction(ctx, second))
+
+	ledgerToid := toid.New(20, 0, 0).ToInt64()
+	q.On("NewAccountBalanceBatchInsertBuilder", maxBatchSize).Return(batch).Once()
+	batch.On("Add", ctx, []history.AccountBalance{{
+		LedgerToid:      ledgerToid,
+		AccountID:       source,
+		AssetType:       xdr.AssetTypeAssetTypeNative,
+		Balance:         null.IntFrom(500),
+		PreviousBalance: null.IntFrom(1000),
+	}}).Return(nil).Once()
+	batch.On("Add", ctx, []history.AccountBalance{{
+		LedgerToid:  ledgerToid,
+		AccountID:   destination,
+		AssetType:   xdr.AssetTypeAssetTypeCreditAlphanum4,
+		AssetCode:   "USD",
+		AssetIssuer: source,
+		Balance:     null.IntFrom(400),
+	}}).Return(nil).Once()
+	batch.On("Exec", ctx).Return(nil).Once()
+
+	assert.NoError(t, processor.Commit(ctx))
+	q.AssertExpectations(t)
+	batch.AssertExpectations(t)
+}
+
+func TestAccountBalancesProcessorNoChanges(t *testing.T) {
+	q := &history.MockQAccountBalances{}
+	processor := NewAccountBalancesProcessor(q, 20)
+	assert.NoError(t, processor.Commit(context.Background()))
+	q.AssertExpectations(t)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/hcnet/go/ingest"
 	"github.com/hcnet/go/services/aurora/internal/db2/history"
 	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/toid"
 	"github.com/hcnet/go/xdr"
 )
 
@@ -74,6 +75,9 @@ func (p *LiquidityPoolsChangeProcessor) Commit(ctx context.Context) error {
 		if err := p.qLiquidityPools.UpsertLiquidityPools(ctx, lps); err != nil {
 			return errors.Wrap(err, "error upserting liquidity pools")
 		}
+		if err := p.qLiquidityPools.InsertLiquidityPoolSnapshots(ctx, p.snapshots(lps)); err != nil {
+			return errors.Wrap(err, "error inserting liquidity pool snapshots")
+		}
 	}
 
 	if p.sequence > compactionWindow {
@@ -88,6 +92,30 @@ func (p *LiquidityPoolsChangeProcessor) Commit(ctx context.Context) error {
 	return nil
 }
 
+// snapshots returns the snapshots of the liquidity pools changed in the
+// ledger, they are the source of the liquidity pool history.
+func (p *LiquidityPoolsChangeProcessor) snapshots(lps []history.LiquidityPool) []history.LiquidityPoolSnapshot {
+	ledgerToid := toid.New(int32(p.sequence), 0, 0).ToInt64()
+	snapshots := make([]history.LiquidityPoolSnapshot, 0, len(lps))
+	for _, lp := range lps {
+		snapshot := history.LiquidityPoolSnapshot{
+			LedgerToid: ledgerToid,
+			PoolID:     lp.PoolID,
+			AssetA:     lp.AssetReserves[0].Asset,
+			AssetB:     lp.AssetReserves[1].Asset,
+			Deleted:    lp.Deleted,
+		}
+		if !lp.Deleted {
+			snapshot.ReserveA = int64(lp.AssetReserves[0].Reserve)
+			snapshot.ReserveB = int64(lp.AssetReserves[1].Reserve)
+			snapshot.ShareCount = int64(lp.ShareCount)
+			snapshot.TrustlineCount = int64(lp.TrustlineCount)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots
+}
+
 func (p *LiquidityPoolsChangeProcessor) ledgerEntryToRow(entry *xdr.LedgerEntry) history.LiquidityPool {
 	lPool := entry.Data.MustLiquidityPool()
 	cp := lPool.Body.MustConstantProduct()
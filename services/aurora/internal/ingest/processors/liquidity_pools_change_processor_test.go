@@ -10,6 +10,7 @@ import (
 
 	"github.com/hcnet/go/ingest"
 	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/toid"
 	"github.com/hcnet/go/xdr"
 )
 
@@ -86,6 +87,18 @@ func (s *LiquidityPoolsChangeProcessorTestSuiteState) TestCreatesLiquidityPools(
 		LastModifiedLedger: 123,
 	}
 	s.mockQ.On("UpsertLiquidityPools", s.ctx, []history.LiquidityPool{lp}).Return(nil).Once()
+	s.mockQ.On("InsertLiquidityPoolSnapshots", s.ctx, []history.LiquidityPoolSnapshot{
+		{
+			LedgerToid:     toid.New(int32(s.sequence), 0, 0).ToInt64(),
+			PoolID:         "cafebabedeadbeef000000000000000000000000000000000000000000000000",
+			AssetA:         xdr.MustNewCreditAsset("USD", "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML"),
+			AssetB:         xdr.MustNewNativeAsset(),
+			ReserveA:       450,
+			ReserveB:       500,
+			ShareCount:     412241,
+			TrustlineCount: 52115,
+		},
+	}).Return(nil).Once()
 
 	s.mockQ.On("CompactLiquidityPools", s.ctx, s.sequence-100).Return(int64(0), nil).Once()
 
@@ -219,6 +232,18 @@ func (s *LiquidityPoolsChangeProcessorTestSuiteLedger) TestNewLiquidityPool() {
 		LastModifiedLedger: 123,
 	}
 	s.mockQ.On("UpsertLiquidityPools", s.ctx, []history.LiquidityPool{postLP}).Return(nil).Once()
+	s.mockQ.On("InsertLiquidityPoolSnapshots", s.ctx, []history.LiquidityPoolSnapshot{
+		{
+			LedgerToid:     toid.New(int32(s.sequence), 0, 0).ToInt64(),
+			PoolID:         "cafebabedeadbeef000000000000000000000000000000000000000000000000",
+			AssetA:         xdr.MustNewCreditAsset("USD", "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML"),
+			AssetB:         xdr.MustNewNativeAsset(),
+			ReserveA:       450,
+			ReserveB:       500,
+			ShareCount:     412241,
+			TrustlineCount: 52115,
+		},
+	}).Return(nil).Once()
 	s.mockQ.On("CompactLiquidityPools", s.ctx, s.sequence-100).Return(int64(0), nil).Once()
 }
 
@@ -298,6 +323,18 @@ func (s *LiquidityPoolsChangeProcessorTestSuiteLedger) TestUpdateLiquidityPool()
 	}
 
 	s.mockQ.On("UpsertLiquidityPools", s.ctx, []history.LiquidityPool{postLP}).Return(nil).Once()
+	s.mockQ.On("InsertLiquidityPoolSnapshots", s.ctx, []history.LiquidityPoolSnapshot{
+		{
+			LedgerToid:     toid.New(int32(s.sequence), 0, 0).ToInt64(),
+			PoolID:         "cafebabedeadbeef000000000000000000000000000000000000000000000000",
+			AssetA:         xdr.MustNewCreditAsset("USD", "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML"),
+			AssetB:         xdr.MustNewNativeAsset(),
+			ReserveA:       450,
+			ReserveB:       500,
+			ShareCount:     412241,
+			TrustlineCount: 52115,
+		},
+	}).Return(nil).Once()
 	s.mockQ.On("CompactLiquidityPools", s.ctx, s.sequence-100).Return(int64(0), nil).Once()
 }
 
@@ -344,5 +381,14 @@ func (s *LiquidityPoolsChangeProcessorTestSuiteLedger) TestRemoveLiquidityPool()
 	deleted.Deleted = true
 	deleted.LastModifiedLedger = s.processor.sequence
 	s.mockQ.On("UpsertLiquidityPools", s.ctx, []history.LiquidityPool{deleted}).Return(nil).Once()
+	s.mockQ.On("InsertLiquidityPoolSnapshots", s.ctx, []history.LiquidityPoolSnapshot{
+		{
+			LedgerToid: toid.New(int32(s.sequence), 0, 0).ToInt64(),
+			PoolID:     "cafebabedeadbeef000000000000000000000000000000000000000000000000",
+			AssetA:     xdr.MustNewCreditAsset("USD", "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML"),
+			AssetB:     xdr.MustNewNativeAsset(),
+			Deleted:    true,
+		},
+	}).Return(nil).Once()
 	s.mockQ.On("CompactLiquidityPools", s.ctx, s.sequence-100).Return(int64(0), nil).Once()
 }
@@ -0,0 +1,128 @@
+package processors
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/hcnet/go/ingest"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/toid"
+	"github.com/hcnet/go/xdr"
+)
+
+// OrderBookSnapshotsInterval is the number of ledgers between two snapshots
+// of an order book, about a minute. It's shorter than the compaction window of
+// the offers and liquidity pools so that the ones deleted since the previous
+// snapshots are still found.
+const OrderBookSnapshotsInterval = uint32(12)
+
+// OrderBookSnapshotsProcessor records, every OrderBookSnapshotsInterval
+// ledgers, the order books of the trading pairs whose offers or liquidity pool
+// changed since the previous snapshots. It must run after the offers and
+// liquidity pools processors so that the snapshots are built from the state at
+// the end of the ledger.
+type OrderBookSnapshotsProcessor struct {
+	snapshotsQ history.QOrderBookSnapshots
+	sequence   uint32
+	closedAt   time.Time
+
+	pairs map[string][2]xdr.Asset
+}
+
+func NewOrderBookSnapshotsProcessor(
+	snapshotsQ history.QOrderBookSnapshots,
+	sequence uint32,
+	closedAt time.Time,
+) *OrderBookSnapshotsProcessor {
+	return &OrderBookSnapshotsProcessor{
+		snapshotsQ: snapshotsQ,
+		sequence:   sequence,
+		closedAt:   closedAt,
+		pairs:      map[string][2]xdr.Asset{},
+	}
+}
+
+func (p *OrderBookSnapshotsProcessor) addPair(a, b xdr.Asset) {
+	if a.Equals(b) {
+		return
+	}
+	if b.LessThan(a) {
+		a, b = b, a
+	}
+	p.pairs[a.String()+"/"+b.String()] = [2]xdr.Asset{a, b}
+}
+
+func (p *OrderBookSnapshotsProcessor) sampled() bool {
+	return p.sequence%OrderBookSnapshotsInterval == 0
+}
+
+func (p *OrderBookSnapshotsProcessor) ProcessChange(ctx context.Context, change ingest.Change) error {
+	if !p.sampled() {
+		return nil
+	}
+
+	switch change.Type {
+	case xdr.LedgerEntryTypeOffer:
+		for _, entry := range []*xdr.LedgerEntry{change.Pre, change.Post} {
+			if entry != nil {
+				offer := entry.Data.MustOffer()
+				p.addPair(offer.Selling, offer.Buying)
+			}
+		}
+	case xdr.LedgerEntryTypeLiquidityPool:
+		for _, entry := range []*xdr.LedgerEntry{change.Pre, change.Post} {
+			if entry != nil {
+				params := entry.Data.MustLiquidityPool().Body.MustConstantProduct().Params
+				p.addPair(params.AssetA, params.AssetB)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *OrderBookSnapshotsProcessor) Commit(ctx context.Context) error {
+	if !p.sampled() {
+		return nil
+	}
+
+	// the pairs changed in the previous ledgers of the interval
+	var since uint32
+	if p.sequence > OrderBookSnapshotsInterval {
+		since = p.sequence - OrderBookSnapshotsInterval
+	}
+	pairs, err := p.snapshotsQ.GetTradingPairsModifiedSince(ctx, since)
+	if err != nil {
+		return errors.Wrap(err, "error loading modified trading pairs")
+	}
+	for _, pair := range pairs {
+		p.addPair(pair[0], pair[1])
+	}
+	if len(p.pairs) == 0 {
+		return nil
+	}
+
+	ledgerToid := toid.New(int32(p.sequence), 0, 0).ToInt64()
+	keys := make([]string, 0, len(p.pairs))
+	for key := range p.pairs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	snapshots := make([]history.OrderBookSnapshot, 0, len(keys))
+	for _, key := range keys {
+		pair := p.pairs[key]
+		snapshot, err := p.snapshotsQ.BuildOrderBookSnapshot(ctx, pair[0], pair[1])
+		if err != nil {
+			return errors.Wrap(err, "error building order book snapshot")
+		}
+		snapshot.LedgerToid = ledgerToid
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err := p.snapshotsQ.InsertOrderBookSnapshots(ctx, snapshots, p.closedAt); err != nil {
+		return errors.Wrap(err, "error inserting order book snapshots")
+	}
+	return nil
+}
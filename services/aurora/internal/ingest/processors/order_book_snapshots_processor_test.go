@@ -0,0 +1,142 @@
+//lint:file-ignore U1001 Ignore all unused code, staticcheck doesn't understand testify/suite
+
+package processors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/hcnet/go/ingest"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/toid"
+	"github.com/hcnet/go/xdr"
+)
+
+func TestOrderBookSnapshotsProcessorTestSuite(t *testing.T) {
+	suite.Run(t, new(OrderBookSnapshotsProcessorTestSuite))
+}
+
+type OrderBookSnapshotsProcessorTestSuite struct {
+	suite.Suite
+	ctx       context.Context
+	processor *OrderBookSnapshotsProcessor
+	mockQ     *history.MockQOrderBookSnapshots
+	sequence  uint32
+	closedAt  time.Time
+}
+
+var (
+	snapshotUSD = xdr.MustNewCreditAsset("USD", "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML")
+	snapshotEUR = xdr.MustNewCreditAsset("EUR", "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML")
+	snapshotGBP = xdr.MustNewCreditAsset("GBP", "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML")
+)
+
+func (s *OrderBookSnapshotsProcessorTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.mockQ = &history.MockQOrderBookSnapshots{}
+	s.sequence = 456
+	s.closedAt = time.Unix(1600000000, 0).UTC()
+	s.processor = NewOrderBookSnapshotsProcessor(s.mockQ, s.sequence, s.closedAt)
+}
+
+func (s *OrderBookSnapshotsProcessorTestSuite) TearDownTest() {
+	s.Assert().NoError(s.processor.Commit(s.ctx))
+	s.mockQ.AssertExpectations(s.T())
+}
+
+func offerChange(pre, post *xdr.OfferEntry) ingest.Change {
+	change := ingest.Change{Type: xdr.LedgerEntryTypeOffer}
+	if pre != nil {
+		change.Pre = &xdr.LedgerEntry{Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeOffer, Offer: pre}}
+	}
+	if post != nil {
+		change.Post = &xdr.LedgerEntry{Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeOffer, Offer: post}}
+	}
+	return change
+}
+
+func (s *OrderBookSnapshotsProcessorTestSuite) TestNoEntries() {
+	err := s.processor.ProcessChange(s.ctx, ingest.Change{
+		Type: xdr.LedgerEntryTypeAccount,
+		Post: &xdr.LedgerEntry{
+			Data: xdr.LedgerEntryData{
+				Type:    xdr.LedgerEntryTypeAccount,
+				Account: &xdr.AccountEntry{AccountId: xdr.MustAddress("GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML")},
+			},
+		},
+	})
+	s.Assert().NoError(err)
+	s.mockQ.On("GetTradingPairsModifiedSince", s.ctx, s.sequence-OrderBookSnapshotsInterval).
+		Return([][2]xdr.Asset{}, nil).Once()
+	// Nothing inserted, assertions in TearDownTest.
+}
+
+func (s *OrderBookSnapshotsProcessorTestSuite) TestSkipsLedgersBetweenSnapshots() {
+	s.processor = NewOrderBookSnapshotsProcessor(s.mockQ, s.sequence+1, s.closedAt)
+	err := s.processor.ProcessChange(s.ctx, offerChange(
+		nil, &xdr.OfferEntry{OfferId: 1, Selling: snapshotUSD, Buying: xdr.MustNewNativeAsset()},
+	))
+	s.Assert().NoError(err)
+	// Nothing loaded nor inserted, assertions in TearDownTest.
+}
+
+func (s *OrderBookSnapshotsProcessorTestSuite) TestSnapshotsChangedPairs() {
+	native := xdr.MustNewNativeAsset()
+	changes := []ingest.Change{
+		// offers of both sides of the same pair are snapshotted once
+		offerChange(nil, &xdr.OfferEntry{OfferId: 1, Selling: snapshotUSD, Buying: native}),
+		offerChange(
+			&xdr.OfferEntry{OfferId: 2, Selling: native, Buying: snapshotUSD},
+			&xdr.OfferEntry{OfferId: 2, Selling: native, Buying: snapshotUSD},
+		),
+		offerChange(&xdr.OfferEntry{OfferId: 3, Selling: snapshotEUR, Buying: snapshotUSD}, nil),
+		{
+			Type: xdr.LedgerEntryTypeLiquidityPool,
+			Post: &xdr.LedgerEntry{
+				Data: xdr.LedgerEntryData{
+					Type: xdr.LedgerEntryTypeLiquidityPool,
+					LiquidityPool: &xdr.LiquidityPoolEntry{
+						Body: xdr.LiquidityPoolEntryBody{
+							Type: xdr.LiquidityPoolTypeLiquidityPoolConstantProduct,
+							ConstantProduct: &xdr.LiquidityPoolEntryConstantProduct{
+								Params: xdr.LiquidityPoolConstantProductParameters{
+									AssetA: native,
+									AssetB: snapshotEUR,
+									Fee:    30,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, change := range changes {
+		s.Assert().NoError(s.processor.ProcessChange(s.ctx, change))
+	}
+
+	// the pairs changed since the previous snapshots are snapshotted too
+	s.mockQ.On("GetTradingPairsModifiedSince", s.ctx, s.sequence-OrderBookSnapshotsInterval).
+		Return([][2]xdr.Asset{{snapshotUSD, snapshotEUR}, {snapshotUSD, snapshotGBP}}, nil).Once()
+
+	ledgerToid := toid.New(int32(s.sequence), 0, 0).ToInt64()
+	var expected []history.OrderBookSnapshot
+	for _, pair := range [][2]xdr.Asset{
+		{snapshotEUR, snapshotUSD},
+		{snapshotGBP, snapshotUSD},
+		{native, snapshotEUR},
+		{native, snapshotUSD},
+	} {
+		snapshot := history.OrderBookSnapshot{
+			BaseAsset:    pair[0].StringCanonical(),
+			CounterAsset: pair[1].StringCanonical(),
+		}
+		s.mockQ.On("BuildOrderBookSnapshot", s.ctx, pair[0], pair[1]).Return(snapshot, nil).Once()
+		snapshot.LedgerToid = ledgerToid
+		expected = append(expected, snapshot)
+	}
+	s.mockQ.On("InsertOrderBookSnapshots", s.ctx, expected, s.closedAt).Return(nil).Once()
+}
@@ -0,0 +1,170 @@
+package ingest
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxStatusTransitions is the number of the most recent state machine
+	// transitions kept in memory and exposed in Status.
+	maxStatusTransitions = 50
+	// maxStatusLedgers is the number of the most recent ledgers for which
+	// processor timings are kept in memory and exposed in Status.
+	maxStatusLedgers = 10
+)
+
+// StateTransition describes a single transition of the ingestion state
+// machine.
+type StateTransition struct {
+	From string
+	To   string
+	At   time.Time
+	// Duration is the time spent in the From state.
+	Duration time.Duration
+	// Error is the error returned by the From state, if any.
+	Error string
+}
+
+// LedgerStatus contains timings of a single ledger processed by the
+// ingestion state machine.
+type LedgerStatus struct {
+	Sequence           uint32
+	ProcessedAt        time.Time
+	Duration           time.Duration
+	ProcessorDurations map[string]time.Duration
+}
+
+// Status is a snapshot of the ingestion state machine: the state it is in,
+// the ledger it is processing and its recent history.
+type Status struct {
+	State          string
+	StateEnteredAt time.Time
+	Paused         bool
+	// CurrentLedger is the sequence of the ledger currently (or most
+	// recently) processed by the state machine. 0 if no ledger has been
+	// processed yet.
+	CurrentLedger uint32
+	// Transitions contains the most recent transitions, oldest first.
+	Transitions []StateTransition
+	// Ledgers contains timings of the most recently processed ledgers,
+	// oldest first.
+	Ledgers []LedgerStatus
+}
+
+// statusTracker keeps track of the ingestion state machine status. Zero
+// value is ready to use.
+type statusTracker struct {
+	mutex          sync.Mutex
+	state          string
+	stateEnteredAt time.Time
+	currentLedger  uint32
+	transitions    []StateTransition
+	ledgers        []LedgerStatus
+
+	// paused is a channel closed when ingestion is unpaused. nil when
+	// ingestion is not paused.
+	paused chan struct{}
+}
+
+func (t *statusTracker) enter(node stateMachineNode) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.state = node.String()
+	t.stateEnteredAt = time.Now()
+}
+
+func (t *statusTracker) transition(from, to stateMachineNode, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	transition := StateTransition{
+		From:     from.String(),
+		To:       to.String(),
+		At:       now,
+		Duration: now.Sub(t.stateEnteredAt),
+	}
+	if err != nil {
+		transition.Error = err.Error()
+	}
+
+	t.transitions = append(t.transitions, transition)
+	if len(t.transitions) > maxStatusTransitions {
+		t.transitions = t.transitions[len(t.transitions)-maxStatusTransitions:]
+	}
+
+	t.state = transition.To
+	t.stateEnteredAt = now
+}
+
+func (t *statusTracker) setCurrentLedger(sequence uint32) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.currentLedger = sequence
+}
+
+func (t *statusTracker) addLedger(sequence uint32, duration time.Duration, processorDurations ...map[string]time.Duration) {
+	merged := map[string]time.Duration{}
+	for _, durations := range processorDurations {
+		for name, value := range durations {
+			merged[strings.Replace(name, "*", "", -1)] += value
+		}
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.ledgers = append(t.ledgers, LedgerStatus{
+		Sequence:           sequence,
+		ProcessedAt:        time.Now(),
+		Duration:           duration,
+		ProcessorDurations: merged,
+	})
+	if len(t.ledgers) > maxStatusLedgers {
+		t.ledgers = t.ledgers[len(t.ledgers)-maxStatusLedgers:]
+	}
+}
+
+func (t *statusTracker) pause() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.paused == nil {
+		t.paused = make(chan struct{})
+	}
+}
+
+func (t *statusTracker) resume() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.paused != nil {
+		close(t.paused)
+		t.paused = nil
+	}
+}
+
+// pausedChan returns a channel which is closed when ingestion is unpaused or
+// nil if ingestion is not paused.
+func (t *statusTracker) pausedChan() <-chan struct{} {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.paused
+}
+
+func (t *statusTracker) status() Status {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	status := Status{
+		State:          t.state,
+		StateEnteredAt: t.stateEnteredAt,
+		Paused:         t.paused != nil,
+		CurrentLedger:  t.currentLedger,
+		Transitions:    make([]StateTransition, len(t.transitions)),
+		Ledgers:        make([]LedgerStatus, len(t.ledgers)),
+	}
+	copy(status.Transitions, t.transitions)
+	copy(status.Ledgers, t.ledgers)
+	return status
+}
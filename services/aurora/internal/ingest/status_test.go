@@ -0,0 +1,122 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hcnet/go/support/errors"
+)
+
+func TestStatusTrackerTransitions(t *testing.T) {
+	var tracker statusTracker
+	tracker.enter(startState{})
+
+	status := tracker.status()
+	assert.Equal(t, "start", status.State)
+	assert.Empty(t, status.Transitions)
+
+	tracker.transition(startState{}, resumeState{latestSuccessfullyProcessedLedger: 63}, nil)
+	tracker.transition(
+		resumeState{latestSuccessfullyProcessedLedger: 63},
+		startState{},
+		errors.New("my error"),
+	)
+
+	status = tracker.status()
+	assert.Equal(t, "start", status.State)
+	require.Len(t, status.Transitions, 2)
+	assert.Equal(t, "start", status.Transitions[0].From)
+	assert.Equal(t, "resume(latestSuccessfullyProcessedLedger=63)", status.Transitions[0].To)
+	assert.Empty(t, status.Transitions[0].Error)
+	assert.Equal(t, "resume(latestSuccessfullyProcessedLedger=63)", status.Transitions[1].From)
+	assert.Equal(t, "my error", status.Transitions[1].Error)
+
+	for i := 0; i < 2*maxStatusTransitions; i++ {
+		tracker.transition(startState{}, startState{}, nil)
+	}
+	assert.Len(t, tracker.status().Transitions, maxStatusTransitions)
+}
+
+func TestStatusTrackerLedgers(t *testing.T) {
+	var tracker statusTracker
+	tracker.setCurrentLedger(100)
+	tracker.addLedger(
+		100,
+		time.Second,
+		map[string]time.Duration{"*processors.AccountsProcessor": time.Millisecond},
+		map[string]time.Duration{
+			"*processors.AccountsProcessor": time.Millisecond,
+			"*processors.EffectProcessor":   2 * time.Millisecond,
+		},
+	)
+
+	status := tracker.status()
+	assert.Equal(t, uint32(100), status.CurrentLedger)
+	require.Len(t, status.Ledgers, 1)
+	assert.Equal(t, uint32(100), status.Ledgers[0].Sequence)
+	assert.Equal(t, time.Second, status.Ledgers[0].Duration)
+	assert.Equal(t, map[string]time.Duration{
+		"processors.AccountsProcessor": 2 * time.Millisecond,
+		"processors.EffectProcessor":   2 * time.Millisecond,
+	}, status.Ledgers[0].ProcessorDurations)
+
+	for i := uint32(0); i < 2*maxStatusLedgers; i++ {
+		tracker.addLedger(101+i, time.Second)
+	}
+	status = tracker.status()
+	require.Len(t, status.Ledgers, maxStatusLedgers)
+	assert.Equal(t, uint32(100+2*maxStatusLedgers), status.Ledgers[maxStatusLedgers-1].Sequence)
+}
+
+func TestStateMachinePausedContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	historyQ := &mockDBQ{}
+	system := &system{
+		historyQ: historyQ,
+		ctx:      ctx,
+	}
+
+	system.Pause()
+	assert.True(t, system.Status().Paused)
+
+	cancel()
+	assert.NoError(t, system.runStateMachine(startState{}))
+	historyQ.AssertExpectations(t)
+}
+
+func TestStateMachinePauseResume(t *testing.T) {
+	historyQ := &mockDBQ{}
+	system := &system{
+		historyQ: historyQ,
+		ctx:      context.Background(),
+	}
+
+	historyQ.On("GetTx").Return(nil).Once()
+
+	system.Pause()
+	done := make(chan error)
+	go func() {
+		done <- system.runStateMachine(verifyRangeState{})
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("state machine should be paused")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Equal(t, "verifyRange(fromLedger=0, toLedger=0, verifyState=false)", system.Status().State)
+
+	system.Resume()
+	assert.EqualError(t, <-done, "invalid range: [0, 0]")
+
+	status := system.Status()
+	assert.False(t, status.Paused)
+	assert.Equal(t, "stop", status.State)
+	require.Len(t, status.Transitions, 1)
+	assert.Equal(t, "invalid range: [0, 0]", status.Transitions[0].Error)
+	historyQ.AssertExpectations(t)
+}
@@ -10,11 +10,15 @@ import (
 	"github.com/getsentry/raven-go"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/hcnet/go/exp/orderbook"
+	"github.com/hcnet/go/historyarchive"
 	"github.com/hcnet/go/services/aurora/internal/db2/history"
 	"github.com/hcnet/go/services/aurora/internal/ingest"
+	"github.com/hcnet/go/services/aurora/internal/ledger"
+	"github.com/hcnet/go/services/aurora/internal/reap"
 	"github.com/hcnet/go/services/aurora/internal/simplepath"
 	"github.com/hcnet/go/services/aurora/internal/txsub"
 	"github.com/hcnet/go/services/aurora/internal/txsub/sequence"
+	"github.com/hcnet/go/services/aurora/internal/webhooks"
 	"github.com/hcnet/go/support/db"
 	"github.com/hcnet/go/support/log"
 )
@@ -114,7 +118,7 @@ func initIngester(app *App) {
 		RemoteCaptiveCoreURL:         app.config.RemoteCaptiveCoreURL,
 		EnableCaptiveCore:            app.config.EnableCaptiveCoreIngestion,
 		DisableStateVerification:     app.config.IngestDisableStateVerification,
-		EnableReapLookupTables:       app.config.HistoryRetentionCount > 0,
+		EnableReapLookupTables:       app.config.HistoryRetentionCount > 0 || len(app.config.HistoryRetentionPolicies) > 0,
 		EnableExtendedLogLedgerStats: app.config.IngestEnableExtendedLogLedgerStats,
 		RoundingSlippageFilter:       app.config.RoundingSlippageFilter,
 		EnableIngestionFiltering:     app.config.EnableIngestionFiltering,
@@ -125,6 +129,44 @@ func initIngester(app *App) {
 	}
 }
 
+func initReaper(app *App) {
+	app.reaper = reap.New(app.config.HistoryRetentionCount, app.AuroraSession(), app.ledgerState)
+	app.reaper.RetentionPolicies = app.config.HistoryRetentionPolicies
+	app.reaper.BatchSize = int(app.config.HistoryRetentionReapBatchSize)
+
+	if app.config.HistoryRetentionArchiveURL != "" {
+		archiver, err := NewHistoryRetentionArchiver(app.config)
+		if err != nil {
+			log.Fatal(err)
+		}
+		app.reaper.Archiver = archiver
+	}
+}
+
+// initLedgerNotifier creates the notifier shared by the streams and the
+// webhook delivery system waiting for new ledgers.
+func initLedgerNotifier(app *App) {
+	app.ledgerNotifier = ledger.NewNotifier(app.config.SSEUpdateFrequency, app.ledgerState)
+}
+
+func initWebhooks(app *App) {
+	app.webhooks = webhooks.New(app.AuroraSession(), app.ledgerNotifier)
+}
+
+// NewHistoryRetentionArchiver returns the archiver of reaped history rows
+// configured with --history-retention-archive-url.
+func NewHistoryRetentionArchiver(config Config) (*reap.Archiver, error) {
+	archiver, err := reap.NewArchiver(config.HistoryRetentionArchiveURL, historyarchive.ConnectOptions{
+		S3Region:   config.HistoryRetentionArchiveS3Region,
+		S3Endpoint: config.HistoryRetentionArchiveS3Endpoint,
+	})
+	if err != nil {
+		return nil, err
+	}
+	archiver.S3ACL = config.HistoryRetentionArchiveS3ACL
+	return archiver, nil
+}
+
 func initPathFinder(app *App) {
 	if app.config.DisablePathFinding {
 		return
@@ -231,6 +273,17 @@ func initTxSubMetrics(app *App) {
 	app.submitter.RegisterMetrics(app.prometheusRegistry)
 }
 
+func initReapMetrics(app *App) {
+	app.reaper.RegisterMetrics(app.prometheusRegistry)
+}
+
+func initWebhooksMetrics(app *App) {
+	if app.webhooks == nil {
+		return
+	}
+	app.webhooks.RegisterMetrics(app.prometheusRegistry)
+}
+
 func initWebMetrics(app *App) {
 	app.webServer.RegisterMetrics(app.prometheusRegistry)
 }
@@ -0,0 +1,118 @@
+package ledger
+
+import (
+	"sync"
+	"time"
+)
+
+// minNotifierUpdateFrequency is the polling interval used when Notifier is
+// constructed without an update frequency.
+const minNotifierUpdateFrequency = 10 * time.Millisecond
+
+// Notifier polls the ledger state in a single go routine and notifies every
+// Source it created when a new ledger has been ingested. It allows a large
+// number of streams to wait for the next ledger without each of them polling
+// the state. Notifier implements the sse.LedgerSourceFactory interface.
+type Notifier struct {
+	updateFrequency time.Duration
+	state           *State
+
+	lock    sync.Mutex
+	waiters map[*notifierSource]notifierWaiter
+	running bool
+}
+
+type notifierWaiter struct {
+	currentSequence uint32
+	newLedgers      chan uint32
+}
+
+// NewNotifier constructs a new instance of Notifier. The polling go routine
+// only runs while sources are waiting for a ledger.
+func NewNotifier(updateFrequency time.Duration, state *State) *Notifier {
+	if updateFrequency <= 0 {
+		updateFrequency = minNotifierUpdateFrequency
+	}
+	return &Notifier{
+		updateFrequency: updateFrequency,
+		state:           state,
+		waiters:         map[*notifierSource]notifierWaiter{},
+	}
+}
+
+// Get returns a new Source backed by the notifier.
+func (n *Notifier) Get() Source {
+	return &notifierSource{notifier: n}
+}
+
+func (n *Notifier) wait(source *notifierSource, currentSequence uint32) chan uint32 {
+	// Make sure this is buffered channel of size 1 so that the polling go
+	// routine never blocks on sources which are not read anymore.
+	newLedgers := make(chan uint32, 1)
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if source.closed {
+		return newLedgers
+	}
+	n.waiters[source] = notifierWaiter{
+		currentSequence: currentSequence,
+		newLedgers:      newLedgers,
+	}
+	if !n.running {
+		n.running = true
+		go n.run()
+	}
+	return newLedgers
+}
+
+func (n *Notifier) close(source *notifierSource) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	source.closed = true
+	delete(n.waiters, source)
+}
+
+func (n *Notifier) run() {
+	for {
+		time.Sleep(n.updateFrequency)
+		latest := n.state.CurrentStatus().ExpHistoryLatest
+
+		n.lock.Lock()
+		for source, waiter := range n.waiters {
+			if latest > waiter.currentSequence {
+				waiter.newLedgers <- latest
+				delete(n.waiters, source)
+			}
+		}
+		if len(n.waiters) == 0 {
+			n.running = false
+			n.lock.Unlock()
+			return
+		}
+		n.lock.Unlock()
+	}
+}
+
+// notifierSource is a Source waiting for new ledgers using a Notifier.
+type notifierSource struct {
+	notifier *Notifier
+	// closed is guarded by the lock of the notifier.
+	closed bool
+}
+
+// CurrentLedger returns the current ledger.
+func (source *notifierSource) CurrentLedger() uint32 {
+	return source.notifier.state.CurrentStatus().ExpHistoryLatest
+}
+
+// NextLedger returns a channel which yields once there is a new ledger with a
+// sequence number larger than currentSequence.
+func (source *notifierSource) NextLedger(currentSequence uint32) chan uint32 {
+	return source.notifier.wait(source, currentSequence)
+}
+
+// Close stops waiting for new ledgers.
+func (source *notifierSource) Close() {
+	source.notifier.close(source)
+}
@@ -0,0 +1,49 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_NotifierNextLedger(t *testing.T) {
+	state := &State{}
+	state.SetAuroraStatus(AuroraStatus{ExpHistoryLatest: 3})
+	notifier := NewNotifier(time.Millisecond, state)
+
+	first := notifier.Get()
+	defer first.Close()
+	second := notifier.Get()
+	closed := notifier.Get()
+
+	if currentLedger := first.CurrentLedger(); currentLedger != 3 {
+		t.Errorf("CurrentLedger = %d, want 3", currentLedger)
+	}
+
+	firstChan := first.NextLedger(3)
+	secondChan := second.NextLedger(2)
+	closed.NextLedger(3)
+	closed.Close()
+
+	if nextLedger := <-secondChan; nextLedger != 3 {
+		t.Errorf("NextLedger = %d, want 3", nextLedger)
+	}
+	second.Close()
+
+	select {
+	case nextLedger := <-firstChan:
+		t.Fatalf("unexpected ledger %d", nextLedger)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	state.SetAuroraStatus(AuroraStatus{ExpHistoryLatest: 4})
+	if nextLedger := <-firstChan; nextLedger != 4 {
+		t.Errorf("NextLedger = %d, want 4", nextLedger)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	notifier.lock.Lock()
+	defer notifier.lock.Unlock()
+	if notifier.running || len(notifier.waiters) > 0 {
+		t.Errorf("notifier is still running with %d waiters", len(notifier.waiters))
+	}
+}
@@ -16,6 +16,23 @@ type Query struct {
 	// which require a source asset amount which exceeds the balance present in `SourceAssetBalances`
 	ValidateSourceBalance bool
 	SourceAccount         *xdr.AccountId
+	Constraints           Constraints
+}
+
+// Constraints restricts the assets and venues of the payment paths returned by
+// a path finder. The zero value doesn't restrict the payment paths.
+type Constraints struct {
+	// ExcludedAssets are never part of a payment path.
+	ExcludedAssets []xdr.Asset
+	// IncludedAssets, if not empty, are the only assets which can be
+	// intermediate hops of a payment path.
+	IncludedAssets []xdr.Asset
+	// ExcludedPools are never traded with.
+	ExcludedPools []xdr.PoolId
+	// ExcludeOffers and ExcludePools restrict the payment paths to liquidity
+	// pools and offers respectively.
+	ExcludeOffers bool
+	ExcludePools  bool
 }
 
 // Path is the result returned by a path finder and is tied to the DestinationAmount used in the input query
@@ -27,6 +44,17 @@ type Path struct {
 	DestinationAmount xdr.Int64
 }
 
+// SplitPath is the result returned by a path finder for a payment divided
+// between several payment paths. The sub-payments in Paths are meant to be
+// submitted in order, in a single transaction.
+type SplitPath struct {
+	Source            string
+	SourceAmount      xdr.Int64
+	Destination       string
+	DestinationAmount xdr.Int64
+	Paths             []Path
+}
+
 // Finder finds paths.
 type Finder interface {
 	// Find returns a list of payment paths and the most recent ledger
@@ -43,5 +71,38 @@ type Finder interface {
 		amountToSpend xdr.Int64,
 		destinationAssets []xdr.Asset,
 		maxLength uint,
+		constraints Constraints,
 	) ([]Path, uint32, error)
+	// FindSplitPaths returns, for each source asset of the Query, a payment
+	// divided between payment paths of a maximum length `maxLength` which
+	// minimizes the amount of the source asset spent, and the most recent
+	// ledger.
+	FindSplitPaths(ctx context.Context, q Query, maxLength uint) ([]SplitPath, uint32, error)
+	// FindFixedSplitPaths returns, for each destination asset, a payment of
+	// `amountToSpend` of `sourceAsset` divided between payment paths which
+	// maximizes the amount of the destination asset delivered, and the most
+	// recent ledger.
+	FindFixedSplitPaths(
+		ctx context.Context,
+		sourceAsset xdr.Asset,
+		amountToSpend xdr.Int64,
+		destinationAssets []xdr.Asset,
+		maxLength uint,
+		constraints Constraints,
+	) ([]SplitPath, uint32, error)
+	// PathsChangedSince returns false if the payment paths of a maximum length
+	// `maxLength` found by Find for the Query are the same as in the given
+	// ledger, regardless of the amounts and balances of the Query.
+	PathsChangedSince(ledger uint32, q Query, maxLength uint) bool
+	// FixedPathsChangedSince returns false if the payment paths of a maximum
+	// length `maxLength` found by FindFixedPaths from `sourceAsset` to
+	// `destinationAssets` are the same as in the given ledger, regardless of
+	// the amount to spend.
+	FixedPathsChangedSince(
+		ledger uint32,
+		sourceAsset xdr.Asset,
+		destinationAssets []xdr.Asset,
+		maxLength uint,
+		constraints Constraints,
+	) bool
 }
@@ -26,8 +26,46 @@ func (m *MockFinder) FindFixedPaths(
 	amountToSpend xdr.Int64,
 	destinationAssets []xdr.Asset,
 	maxLength uint,
+	constraints Constraints,
 ) ([]Path, uint32, error) {
-	args := m.Called(ctx, sourceAsset, amountToSpend, destinationAssets, maxLength)
+	args := m.Called(ctx, sourceAsset, amountToSpend, destinationAssets, maxLength, constraints)
 
 	return args.Get(0).([]Path), args.Get(1).(uint32), args.Error(2)
 }
+
+func (m *MockFinder) FindSplitPaths(ctx context.Context, q Query, maxLength uint) ([]SplitPath, uint32, error) {
+	args := m.Called(ctx, q, maxLength)
+
+	return args.Get(0).([]SplitPath), args.Get(1).(uint32), args.Error(2)
+}
+
+func (m *MockFinder) FindFixedSplitPaths(
+	ctx context.Context,
+	sourceAsset xdr.Asset,
+	amountToSpend xdr.Int64,
+	destinationAssets []xdr.Asset,
+	maxLength uint,
+	constraints Constraints,
+) ([]SplitPath, uint32, error) {
+	args := m.Called(ctx, sourceAsset, amountToSpend, destinationAssets, maxLength, constraints)
+
+	return args.Get(0).([]SplitPath), args.Get(1).(uint32), args.Error(2)
+}
+
+func (m *MockFinder) PathsChangedSince(ledger uint32, q Query, maxLength uint) bool {
+	args := m.Called(ledger, q, maxLength)
+
+	return args.Bool(0)
+}
+
+func (m *MockFinder) FixedPathsChangedSince(
+	ledger uint32,
+	sourceAsset xdr.Asset,
+	destinationAssets []xdr.Asset,
+	maxLength uint,
+	constraints Constraints,
+) bool {
+	args := m.Called(ledger, sourceAsset, destinationAssets, maxLength, constraints)
+
+	return args.Bool(0)
+}
@@ -51,9 +51,53 @@ func (f *RateLimitedFinder) FindFixedPaths(
 	amountToSpend xdr.Int64,
 	destinationAssets []xdr.Asset,
 	maxLength uint,
+	constraints Constraints,
 ) ([]Path, uint32, error) {
 	if !f.limiter.Allow() {
 		return nil, 0, ErrRateLimitExceeded
 	}
-	return f.finder.FindFixedPaths(ctx, sourceAsset, amountToSpend, destinationAssets, maxLength)
+	return f.finder.FindFixedPaths(ctx, sourceAsset, amountToSpend, destinationAssets, maxLength, constraints)
+}
+
+// FindSplitPaths implements the Finder interface and returns ErrRateLimitExceeded if the
+// RateLimitedFinder is unable to complete the request due to rate limits.
+func (f *RateLimitedFinder) FindSplitPaths(ctx context.Context, q Query, maxLength uint) ([]SplitPath, uint32, error) {
+	if !f.limiter.Allow() {
+		return nil, 0, ErrRateLimitExceeded
+	}
+	return f.finder.FindSplitPaths(ctx, q, maxLength)
+}
+
+// FindFixedSplitPaths implements the Finder interface and returns ErrRateLimitExceeded if the
+// RateLimitedFinder is unable to complete the request due to rate limits.
+func (f *RateLimitedFinder) FindFixedSplitPaths(
+	ctx context.Context,
+	sourceAsset xdr.Asset,
+	amountToSpend xdr.Int64,
+	destinationAssets []xdr.Asset,
+	maxLength uint,
+	constraints Constraints,
+) ([]SplitPath, uint32, error) {
+	if !f.limiter.Allow() {
+		return nil, 0, ErrRateLimitExceeded
+	}
+	return f.finder.FindFixedSplitPaths(ctx, sourceAsset, amountToSpend, destinationAssets, maxLength, constraints)
+}
+
+// PathsChangedSince implements the Finder interface. It's not rate limited
+// because it doesn't search for payment paths.
+func (f *RateLimitedFinder) PathsChangedSince(ledger uint32, q Query, maxLength uint) bool {
+	return f.finder.PathsChangedSince(ledger, q, maxLength)
+}
+
+// FixedPathsChangedSince implements the Finder interface. It's not rate limited
+// because it doesn't search for payment paths.
+func (f *RateLimitedFinder) FixedPathsChangedSince(
+	ledger uint32,
+	sourceAsset xdr.Asset,
+	destinationAssets []xdr.Asset,
+	maxLength uint,
+	constraints Constraints,
+) bool {
+	return f.finder.FixedPathsChangedSince(ledger, sourceAsset, destinationAssets, maxLength, constraints)
 }
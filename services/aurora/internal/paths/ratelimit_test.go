@@ -27,6 +27,7 @@ func TestRateLimitedFinder(t *testing.T) {
 					10,
 					nil,
 					0,
+					Constraints{},
 				)
 				errorChan <- err
 			}
@@ -39,7 +40,7 @@ func TestRateLimitedFinder(t *testing.T) {
 					wg.Done()
 					wg.Wait()
 				})
-			mockFinder.On("FindFixedPaths", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			mockFinder.On("FindFixedPaths", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 				Return([]Path{}, uint32(0), nil).Maybe().Times(limit).
 				Run(func(args mock.Arguments) {
 					wg.Done()
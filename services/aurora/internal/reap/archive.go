@@ -0,0 +1,429 @@
+package reap
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hcnet/go/historyarchive"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/support/log"
+	"github.com/hcnet/go/toid"
+)
+
+// Archiver writes history rows to cold storage before they are removed by the
+// reaper and restores them back into the history database.
+//
+// The rows of a history table in a reaped range of ledgers are written to a
+// gzip compressed CSV file named:
+//
+//	<table>/<table>-<first ledger>-<last ledger>-<unix nanoseconds>.csv.gz
+//
+// The first line contains the column names. Values are in the postgres text
+// format, NULL is written as \N and values starting with \ are prefixed with
+// an additional \.
+type Archiver struct {
+	// S3ACL is the canned ACL of the files uploaded to S3. When empty, files
+	// are uploaded without ACL and are private unless the bucket policy
+	// allows otherwise.
+	S3ACL string
+
+	backend historyarchive.ArchiveBackend
+	now     func() time.Time
+}
+
+// NewArchiver returns an Archiver writing to the given URL. Supported URL
+// schemes are file and s3.
+func NewArchiver(url string, opts historyarchive.ConnectOptions) (*Archiver, error) {
+	backend, err := historyarchive.ConnectBackend(url, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to history retention archive")
+	}
+	if !backend.CanListFiles() {
+		return nil, errors.Errorf("history retention archive %s does not support listing files", url)
+	}
+	return &Archiver{backend: backend, now: time.Now}, nil
+}
+
+var archiveFileRegexp = regexp.MustCompile(`^([a-z0-9_]+)-(\d+)-(\d+)-(\d+)\.csv\.gz$`)
+
+// archiveFile is an archive of the rows of a history table in the ledger
+// range [start, end].
+type archiveFile struct {
+	path      string
+	table     string
+	start     int32
+	end       int32
+	createdAt int64
+}
+
+func (f archiveFile) name() string {
+	return fmt.Sprintf("%s-%010d-%010d-%019d.csv.gz", f.table, f.start, f.end, f.createdAt)
+}
+
+func parseArchiveFile(pth string) (archiveFile, bool) {
+	m := archiveFileRegexp.FindStringSubmatch(path.Base(pth))
+	if m == nil {
+		return archiveFile{}, false
+	}
+	start, err := strconv.ParseInt(m[2], 10, 32)
+	if err != nil {
+		return archiveFile{}, false
+	}
+	end, err := strconv.ParseInt(m[3], 10, 32)
+	if err != nil {
+		return archiveFile{}, false
+	}
+	createdAt, err := strconv.ParseInt(m[4], 10, 64)
+	if err != nil {
+		return archiveFile{}, false
+	}
+	return archiveFile{
+		path:      path.Join(m[1], m[0]),
+		table:     m[1],
+		start:     int32(start),
+		end:       int32(end),
+		createdAt: createdAt,
+	}, true
+}
+
+// Export writes the rows of a history table in the ledger range
+// [startSeq, endSeq] to the archive and returns the number of written rows.
+func (a *Archiver) Export(ctx context.Context, q *history.Q, table string, startSeq, endSeq int32) (int64, error) {
+	start, end, err := toid.LedgerRangeInclusive(startSeq, endSeq)
+	if err != nil {
+		return 0, err
+	}
+	columns, err := q.HistoryTableColumns(ctx, table)
+	if err != nil {
+		return 0, err
+	}
+
+	// Rows are written to a temporary file first so only complete archives
+	// are uploaded.
+	tmp, err := ioutil.TempFile("", "aurora-reap-")
+	if err != nil {
+		return 0, errors.Wrap(err, "could not create temporary file")
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	w := newArchiveWriter(tmp)
+	if err = w.write(columns); err != nil {
+		return 0, err
+	}
+	var count int64
+	err = q.StreamHistoryRange(ctx, table, columns, start, end, func(row []*string) error {
+		count++
+		return w.writeRow(row)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err = w.close(); err != nil {
+		return 0, err
+	}
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return 0, errors.Wrap(err, "could not rewind temporary file")
+	}
+
+	file := archiveFile{table: table, start: startSeq, end: endSeq, createdAt: a.now().UnixNano()}
+	file.path = path.Join(table, file.name())
+	if err = a.put(file.path, ioutil.NopCloser(tmp)); err != nil {
+		return 0, errors.Wrapf(err, "could not upload %s", file.path)
+	}
+
+	log.
+		WithField("path", file.path).
+		WithField("rows", count).
+		Info("reaper: archived history")
+	return count, nil
+}
+
+// Restore inserts the archived rows of the ledger range [startSeq, endSeq]
+// back into the history database and returns the number of inserted rows.
+// For every archived range, rows already in the history database are removed
+// first. When a ledger was archived more than once, the oldest archive is
+// used. It must be called in a transaction.
+func (a *Archiver) Restore(ctx context.Context, q *history.Q, startSeq, endSeq int32) (int64, error) {
+	var count int64
+	for _, group := range tableGroups {
+		for _, table := range group.tables {
+			files, err := a.list(table)
+			if err != nil {
+				return count, err
+			}
+
+			for _, planned := range planRestore(files, startSeq, endSeq) {
+				restored, err := a.restoreFile(ctx, q, planned.file, planned.ranges)
+				if err != nil {
+					return count, err
+				}
+				count += restored
+			}
+		}
+	}
+	return count, nil
+}
+
+// put uploads a file. Unlike the S3 history archives, which are public, files
+// are uploaded to S3 with the configured ACL.
+func (a *Archiver) put(pth string, in io.ReadCloser) error {
+	if s3Backend, ok := a.backend.(*historyarchive.S3ArchiveBackend); ok {
+		return s3Backend.PutFileWithACL(pth, in, a.S3ACL)
+	}
+	return a.backend.PutFile(pth, in)
+}
+
+func (a *Archiver) list(table string) ([]archiveFile, error) {
+	var files []archiveFile
+	names, errs := a.backend.ListFiles(table)
+	for names != nil || errs != nil {
+		select {
+		case name, ok := <-names:
+			if !ok {
+				names = nil
+				continue
+			}
+			if file, ok := parseArchiveFile(name); ok && file.table == table {
+				files = append(files, file)
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not list archives of %s", table)
+			}
+		}
+	}
+	return files, nil
+}
+
+// ledgerRange is an inclusive range of ledger sequences.
+type ledgerRange struct {
+	start, end int32
+}
+
+func (r ledgerRange) contains(seq int32) bool {
+	return seq >= r.start && seq <= r.end
+}
+
+type plannedRestore struct {
+	file   archiveFile
+	ranges []ledgerRange
+}
+
+// planRestore returns, for each archive file, the ledger ranges within
+// [startSeq, endSeq] which should be restored from it. Files are considered
+// from the oldest to the newest and ranges already restored from an older
+// file are skipped: a range is archived again only when the reaper failed to
+// remove it after archiving, in which case the older archive is complete.
+func planRestore(files []archiveFile, startSeq, endSeq int32) []plannedRestore {
+	sorted := make([]archiveFile, len(files))
+	copy(sorted, files)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].createdAt < sorted[j].createdAt
+	})
+
+	var restored []ledgerRange
+	var plan []plannedRestore
+	for _, file := range sorted {
+		r := ledgerRange{start: file.start, end: file.end}
+		if r.start < startSeq {
+			r.start = startSeq
+		}
+		if r.end > endSeq {
+			r.end = endSeq
+		}
+		if r.start > r.end {
+			continue
+		}
+
+		ranges := []ledgerRange{r}
+		for _, done := range restored {
+			ranges = subtractRange(ranges, done)
+		}
+		if len(ranges) == 0 {
+			continue
+		}
+		plan = append(plan, plannedRestore{file: file, ranges: ranges})
+		restored = append(restored, ranges...)
+	}
+	return plan
+}
+
+func subtractRange(ranges []ledgerRange, sub ledgerRange) []ledgerRange {
+	var result []ledgerRange
+	for _, r := range ranges {
+		if sub.end < r.start || sub.start > r.end {
+			result = append(result, r)
+			continue
+		}
+		if r.start < sub.start {
+			result = append(result, ledgerRange{start: r.start, end: sub.start - 1})
+		}
+		if r.end > sub.end {
+			result = append(result, ledgerRange{start: sub.end + 1, end: r.end})
+		}
+	}
+	return result
+}
+
+func (a *Archiver) restoreFile(ctx context.Context, q *history.Q, file archiveFile, ranges []ledgerRange) (int64, error) {
+	toidColumn, ok := history.HistoryRangeColumn(file.table)
+	if !ok {
+		return 0, errors.Errorf("unknown history table %s", file.table)
+	}
+
+	for _, r := range ranges {
+		start, end, err := toid.LedgerRangeInclusive(r.start, r.end)
+		if err != nil {
+			return 0, err
+		}
+		if err = q.DeleteRange(ctx, start, end, file.table, toidColumn); err != nil {
+			return 0, errors.Wrapf(err, "could not clear %s", file.table)
+		}
+	}
+
+	in, err := a.backend.GetFile(file.path)
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not download %s", file.path)
+	}
+	defer in.Close()
+
+	reader, err := newArchiveReader(in)
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not read %s", file.path)
+	}
+	columns, err := reader.read()
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not read header of %s", file.path)
+	}
+	toidIndex := -1
+	for i, column := range columns {
+		if column == toidColumn {
+			toidIndex = i
+		}
+	}
+	if toidIndex < 0 {
+		return 0, errors.Errorf("column %s not found in %s", toidColumn, file.path)
+	}
+
+	next := func() ([]*string, error) {
+		for {
+			row, err := reader.readRow()
+			if err != nil {
+				return nil, err
+			}
+			if row[toidIndex] == nil {
+				return nil, errors.Errorf("missing %s value in %s", toidColumn, file.path)
+			}
+			id, err := strconv.ParseInt(*row[toidIndex], 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s value in %s", toidColumn, file.path)
+			}
+			seq := toid.Parse(id).LedgerSequence
+			for _, r := range ranges {
+				if r.contains(seq) {
+					return row, nil
+				}
+			}
+		}
+	}
+
+	count, err := q.CopyHistoryRows(ctx, file.table, columns, next)
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not restore %s", file.path)
+	}
+
+	log.
+		WithField("path", file.path).
+		WithField("rows", count).
+		Info("restored archived history")
+	return count, nil
+}
+
+type archiveWriter struct {
+	gzip *gzip.Writer
+	csv  *csv.Writer
+}
+
+func newArchiveWriter(w io.Writer) *archiveWriter {
+	gz := gzip.NewWriter(w)
+	return &archiveWriter{gzip: gz, csv: csv.NewWriter(gz)}
+}
+
+func (w *archiveWriter) write(record []string) error {
+	return errors.Wrap(w.csv.Write(record), "could not write archive")
+}
+
+func (w *archiveWriter) writeRow(row []*string) error {
+	record := make([]string, len(row))
+	for i, value := range row {
+		switch {
+		case value == nil:
+			record[i] = `\N`
+		case strings.HasPrefix(*value, `\`):
+			record[i] = `\` + *value
+		default:
+			record[i] = *value
+		}
+	}
+	return w.write(record)
+}
+
+func (w *archiveWriter) close() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return errors.Wrap(err, "could not write archive")
+	}
+	return errors.Wrap(w.gzip.Close(), "could not write archive")
+}
+
+type archiveReader struct {
+	csv *csv.Reader
+}
+
+func newArchiveReader(r io.Reader) (*archiveReader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &archiveReader{csv: csv.NewReader(gz)}, nil
+}
+
+func (r *archiveReader) read() ([]string, error) {
+	return r.csv.Read()
+}
+
+func (r *archiveReader) readRow() ([]*string, error) {
+	record, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	row := make([]*string, len(record))
+	for i := range record {
+		switch {
+		case record[i] == `\N`:
+		case strings.HasPrefix(record[i], `\`):
+			value := record[i][1:]
+			row[i] = &value
+		default:
+			row[i] = &record[i]
+		}
+	}
+	return row, nil
+}
@@ -0,0 +1,144 @@
+package reap
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hcnet/go/historyarchive"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/services/aurora/internal/ledger"
+	"github.com/hcnet/go/services/aurora/internal/test"
+)
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestArchiveWriterReader(t *testing.T) {
+	rows := [][]*string{
+		{stringPtr("1"), nil, stringPtr(`\x0a0b`)},
+		{stringPtr("2"), stringPtr(`\N`), stringPtr("a,\"b\"\nc")},
+		{stringPtr("3"), stringPtr(""), stringPtr(`{"a": 1}`)},
+	}
+
+	var buf bytes.Buffer
+	w := newArchiveWriter(&buf)
+	require.NoError(t, w.write([]string{"id", "memo", "data"}))
+	for _, row := range rows {
+		require.NoError(t, w.writeRow(row))
+	}
+	require.NoError(t, w.close())
+
+	r, err := newArchiveReader(&buf)
+	require.NoError(t, err)
+	columns, err := r.read()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "memo", "data"}, columns)
+
+	for _, expected := range rows {
+		row, err := r.readRow()
+		require.NoError(t, err)
+		assert.Equal(t, expected, row)
+	}
+	_, err = r.readRow()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestParseArchiveFile(t *testing.T) {
+	file := archiveFile{table: "history_trades_60000", start: 1, end: 100000, createdAt: 42}
+	assert.Equal(t, "history_trades_60000-0000000001-0000100000-0000000000000000042.csv.gz", file.name())
+
+	parsed, ok := parseArchiveFile("/var/lib/aurora/archive/history_trades_60000/" + file.name())
+	assert.True(t, ok)
+	file.path = "history_trades_60000/" + file.name()
+	assert.Equal(t, file, parsed)
+
+	_, ok = parseArchiveFile("history_trades/history_trades-1-2.csv.gz")
+	assert.False(t, ok)
+}
+
+func TestPlanRestore(t *testing.T) {
+	files := []archiveFile{
+		// archived again after the reaper failed to remove ledgers 1-100
+		{table: "history_effects", start: 1, end: 200, createdAt: 2},
+		{table: "history_effects", start: 1, end: 100, createdAt: 1},
+		{table: "history_effects", start: 201, end: 300, createdAt: 3},
+		{table: "history_effects", start: 301, end: 400, createdAt: 4},
+	}
+
+	plan := planRestore(files, 50, 250)
+	assert.Equal(t, []plannedRestore{
+		{file: files[1], ranges: []ledgerRange{{50, 100}}},
+		{file: files[0], ranges: []ledgerRange{{101, 200}}},
+		{file: files[2], ranges: []ledgerRange{{201, 250}}},
+	}, plan)
+
+	assert.Empty(t, planRestore(files, 401, 500))
+	assert.Equal(t, []ledgerRange{{1, 9}, {21, 30}}, subtractRange([]ledgerRange{{1, 30}}, ledgerRange{10, 20}))
+}
+
+func TestArchiverList(t *testing.T) {
+	archiver, err := NewArchiver("mock://test", historyarchive.ConnectOptions{})
+	require.NoError(t, err)
+
+	file := archiveFile{table: "history_trades", start: 1, end: 10, createdAt: 1}
+	for _, pth := range []string{
+		"history_trades/" + file.name(),
+		"history_trades/unrelated.txt",
+		"history_trades_60000/history_trades_60000-0000000001-0000000010-0000000000000000001.csv.gz",
+	} {
+		require.NoError(t, archiver.backend.PutFile(pth, ioutil.NopCloser(&bytes.Buffer{})))
+	}
+
+	files, err := archiver.list("history_trades")
+	require.NoError(t, err)
+	file.path = "history_trades/" + file.name()
+	assert.Equal(t, []archiveFile{file}, files)
+}
+
+func TestDeleteUnretainedHistoryArchiveAndRestore(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	ledgerState := &ledger.State{}
+	ledgerState.SetStatus(tt.Scenario("kahuna"))
+
+	db := tt.AuroraSession()
+	q := &history.Q{SessionInterface: db}
+
+	archiver, err := NewArchiver("mock://test", historyarchive.ConnectOptions{})
+	tt.Require.NoError(err)
+	archiver.now = func() time.Time { return time.Unix(0, 1) }
+
+	sys := New(0, db, ledgerState)
+	sys.Archiver = archiver
+	sys.RetentionPolicies = map[string]RetentionPolicy{"effects": {Ledgers: 1}}
+
+	// Disable sleeps for this.
+	sleep = 0
+
+	var prev, cur int
+	tt.Require.NoError(db.GetRaw(tt.Ctx, &prev, `SELECT COUNT(*) FROM history_effects`))
+
+	tt.Require.NoError(sys.DeleteUnretainedHistory(tt.Ctx))
+	tt.Require.NoError(db.GetRaw(tt.Ctx, &cur, `SELECT COUNT(*) FROM history_effects`))
+	tt.Assert.True(cur < prev)
+
+	files, err := archiver.list("history_effects")
+	tt.Require.NoError(err)
+	tt.Assert.Len(files, 1)
+
+	tt.Require.NoError(q.Begin())
+	restored, err := archiver.Restore(tt.Ctx, q, 1, ledgerState.CurrentStatus().HistoryLatest)
+	tt.Require.NoError(err)
+	tt.Require.NoError(q.Commit())
+	tt.Assert.Equal(int64(prev-cur), restored)
+
+	tt.Require.NoError(db.GetRaw(tt.Ctx, &cur, `SELECT COUNT(*) FROM history_effects`))
+	tt.Assert.Equal(prev, cur)
+}
@@ -1,24 +1,57 @@
 // Package reap contains the history reaping subsystem for aurora.  This system
 // is designed to remove data from the history database such that it does not
 // grow indefinitely.  The system can be configured with a number of ledgers to
-// maintain at a minimum.
+// maintain at a minimum and with per table retention policies expressed in
+// ledgers or wall-clock duration.
 package reap
 
 import (
 	"context"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/hcnet/go/services/aurora/internal/db2/history"
 	"github.com/hcnet/go/services/aurora/internal/ledger"
 	"github.com/hcnet/go/support/db"
 )
 
+// DefaultBatchSize is the default number of rows removed from a history
+// table in a single query.
+const DefaultBatchSize = 10_000
+
 // System represents the history reaping subsystem of aurora.
 type System struct {
 	HistoryQ       *history.Q
 	RetentionCount uint
-	ledgerState    *ledger.State
-	ctx            context.Context
-	cancel         context.CancelFunc
+	// RetentionPolicies contains retention policies of table groups (trades,
+	// effects, operations, transactions, balances and ledgers). Table groups
+	// without a policy use RetentionCount.
+	RetentionPolicies map[string]RetentionPolicy
+	// BatchSize is the number of rows removed from a history table in a
+	// single query, rows sharing the toid of the last row of a batch are
+	// removed with it. DefaultBatchSize is used when 0.
+	BatchSize int
+	// Archiver, when set, writes history rows to cold storage before they
+	// are removed.
+	Archiver    *Archiver
+	ledgerState *ledger.State
+	ctx         context.Context
+	cancel      context.CancelFunc
+	metrics     Metrics
+}
+
+// Metrics contains the reaper metrics.
+type Metrics struct {
+	// DeletedRowsCounter exposes the number of rows removed by the reaper
+	// per history table.
+	DeletedRowsCounter *prometheus.CounterVec
+
+	// ElderLedgerGauge exposes, per table group, the ledger sequence below
+	// which history data has been removed.
+	ElderLedgerGauge *prometheus.GaugeVec
+
+	// RunDurationSummary exposes durations of reaper runs.
+	RunDurationSummary prometheus.Summary
 }
 
 // New initializes the reaper, causing it to begin polling the hcnet-core
@@ -33,6 +66,59 @@ func New(retention uint, dbSession db.SessionInterface, ledgerState *ledger.Stat
 		ctx:            ctx,
 		cancel:         cancel,
 	}
+	r.initMetrics()
 
 	return r
 }
+
+func (r *System) initMetrics() {
+	r.metrics.DeletedRowsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "aurora", Subsystem: "reap", Name: "deleted_rows_total",
+			Help: "number of rows removed from history tables by the reaper",
+		},
+		[]string{"table"},
+	)
+
+	r.metrics.ElderLedgerGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "aurora", Subsystem: "reap", Name: "elder_ledger",
+			Help: "ledger sequence below which history data of a table group has been removed",
+		},
+		[]string{"table"},
+	)
+
+	r.metrics.RunDurationSummary = prometheus.NewSummary(prometheus.SummaryOpts{
+		Namespace: "aurora", Subsystem: "reap", Name: "run_duration_seconds",
+		Help: "reaper run durations, sliding window = 10m",
+	})
+}
+
+// Metrics returns the reaper metrics.
+func (r *System) Metrics() Metrics {
+	return r.metrics
+}
+
+// RegisterMetrics registers the prometheus metrics
+func (r *System) RegisterMetrics(registry *prometheus.Registry) {
+	registry.MustRegister(r.metrics.DeletedRowsCounter)
+	registry.MustRegister(r.metrics.ElderLedgerGauge)
+	registry.MustRegister(r.metrics.RunDurationSummary)
+}
+
+// Enabled returns true if any history data is configured to be removed.
+func (r *System) Enabled() bool {
+	for _, group := range tableGroups {
+		if !r.policy(group.name).RetainsAll() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *System) policy(group string) RetentionPolicy {
+	if policy, ok := r.RetentionPolicies[group]; ok {
+		return policy
+	}
+	return RetentionPolicy{Ledgers: r.RetentionCount}
+}
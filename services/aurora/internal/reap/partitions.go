@@ -0,0 +1,58 @@
+package reap
+
+import (
+	"context"
+
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/support/log"
+)
+
+// dropPartitions drops the partitions of the tables which only contain
+// ledgers older than targetElder, which is much cheaper than removing their
+// rows. Partitions are archived first if an Archiver is configured.
+func (r *System) dropPartitions(ctx context.Context, tables []string, targetElder int32) error {
+	for _, table := range tables {
+		if !isPartitionable(table) {
+			continue
+		}
+		partitions, err := r.HistoryQ.HistoryPartitions(ctx, table)
+		if err != nil {
+			return err
+		}
+
+		for _, partition := range partitions {
+			if partition.Default || int64(partition.EndLedger) > int64(targetElder) {
+				continue
+			}
+
+			if r.Archiver != nil {
+				start := int32(partition.StartLedger)
+				if start < 1 {
+					start = 1
+				}
+				if _, err = r.Archiver.Export(ctx, r.HistoryQ, table, start, int32(partition.EndLedger)-1); err != nil {
+					return errors.Wrapf(err, "Error archiving %s", partition.Name)
+				}
+			}
+
+			if err = r.HistoryQ.DropHistoryPartition(ctx, partition); err != nil {
+				return err
+			}
+			log.
+				WithField("partition", partition.Name).
+				WithField("end_ledger", partition.EndLedger).
+				Info("reaper: dropped partition")
+		}
+	}
+	return nil
+}
+
+func isPartitionable(table string) bool {
+	for _, partitioned := range history.HistoryPartitionedTables {
+		if partitioned == table {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package reap
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hcnet/go/support/errors"
+)
+
+// tableGroups lists groups of history tables which share a retention policy.
+// The order matters: groups are reaped in this order so rows referencing
+// ledgers, transactions and operations are removed before them.
+var tableGroups = []struct {
+	name   string
+	tables []string
+}{
+	{
+		name: "trades",
+		tables: []string{
+			"history_trades",
+			"history_trades_60000",
+			"history_liquidity_pool_snapshots",
+			"history_order_book_snapshots",
+			"history_order_book_depth",
+		},
+	},
+	{
+		name:   "effects",
+		tables: []string{"history_effects"},
+	},
+	{
+		name: "operations",
+		tables: []string{
+			"history_operation_participants",
+			"history_operation_claimable_balances",
+			"history_operation_liquidity_pools",
+			"history_operations",
+		},
+	},
+	{
+		name: "transactions",
+		tables: []string{
+			"history_transaction_participants",
+			"history_transaction_claimable_balances",
+			"history_transaction_liquidity_pools",
+			"history_transactions",
+		},
+	},
+	{
+		name:   "balances",
+		tables: []string{"history_account_balances"},
+	},
+	{
+		name:   "ledgers",
+		tables: []string{"history_ledgers"},
+	},
+}
+
+// RetentionPolicy defines how much history data of a group of tables is
+// retained. Only one of Ledgers and Duration can be set. Zero value means
+// that all history data is retained.
+type RetentionPolicy struct {
+	// Ledgers is the minimum number of the latest ledgers to retain.
+	Ledgers uint
+	// Duration is the minimum age of the data to retain, based on the ledger
+	// close times.
+	Duration time.Duration
+}
+
+// RetainsAll returns true if the policy retains all history data.
+func (p RetentionPolicy) RetainsAll() bool {
+	return p.Ledgers == 0 && p.Duration == 0
+}
+
+func (p RetentionPolicy) String() string {
+	if p.Duration != 0 {
+		return p.Duration.String()
+	}
+	return strconv.FormatUint(uint64(p.Ledgers), 10)
+}
+
+// ParseRetentionPolicies parses a comma separated list of per table group
+// retention policies, ex: `effects=90d,operations=90d,transactions=1000000`.
+// A policy is either a number of ledgers or a duration. Durations accept
+// the time.ParseDuration format and a number of days with a `d` suffix.
+// Table groups are: trades, effects, operations, transactions, balances and
+// ledgers.
+func ParseRetentionPolicies(value string) (map[string]RetentionPolicy, error) {
+	policies := map[string]RetentionPolicy{}
+	if strings.TrimSpace(value) == "" {
+		return policies, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid retention policy %q, expected <table>=<retention>", entry)
+		}
+
+		group := strings.TrimSpace(parts[0])
+		if !isTableGroup(group) {
+			return nil, errors.Errorf(
+				"invalid retention policy %q, unknown table %q (expected one of: %s)",
+				entry, group, strings.Join(tableGroupNames(), ", "),
+			)
+		}
+		if _, ok := policies[group]; ok {
+			return nil, errors.Errorf("duplicate retention policy for table %q", group)
+		}
+
+		policy, err := parseRetentionPolicy(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid retention policy %q", entry)
+		}
+		policies[group] = policy
+	}
+
+	return policies, nil
+}
+
+func parseRetentionPolicy(value string) (RetentionPolicy, error) {
+	if ledgers, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return RetentionPolicy{Ledgers: uint(ledgers)}, nil
+	}
+
+	var duration time.Duration
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.ParseUint(strings.TrimSuffix(value, "d"), 10, 32)
+		if err != nil {
+			return RetentionPolicy{}, errors.Errorf("invalid number of days %q", value)
+		}
+		duration = time.Duration(days) * 24 * time.Hour
+	} else {
+		var err error
+		duration, err = time.ParseDuration(value)
+		if err != nil {
+			return RetentionPolicy{}, errors.Errorf("expected a number of ledgers or a duration, got %q", value)
+		}
+	}
+
+	if duration < 0 {
+		return RetentionPolicy{}, errors.Errorf("negative duration %q", value)
+	}
+	return RetentionPolicy{Duration: duration}, nil
+}
+
+func isTableGroup(name string) bool {
+	for _, group := range tableGroups {
+		if group.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func tableGroupNames() []string {
+	names := make([]string, 0, len(tableGroups))
+	for _, group := range tableGroups {
+		names = append(names, group.name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,58 @@
+package reap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetentionPolicies(t *testing.T) {
+	policies, err := ParseRetentionPolicies("")
+	assert.NoError(t, err)
+	assert.Empty(t, policies)
+
+	policies, err = ParseRetentionPolicies("effects=90d, operations=2160h,transactions=1000000,trades=0")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]RetentionPolicy{
+		"effects":      {Duration: 90 * 24 * time.Hour},
+		"operations":   {Duration: 2160 * time.Hour},
+		"transactions": {Ledgers: 1000000},
+		"trades":       {},
+	}, policies)
+	assert.True(t, policies["trades"].RetainsAll())
+	assert.False(t, policies["effects"].RetainsAll())
+
+	for _, testCase := range []struct {
+		value string
+		err   string
+	}{
+		{"effects", `invalid retention policy "effects", expected <table>=<retention>`},
+		{"history_effects=90d", `invalid retention policy "history_effects=90d", unknown table "history_effects" (expected one of: balances, effects, ledgers, operations, trades, transactions)`},
+		{"effects=90d,effects=10", `duplicate retention policy for table "effects"`},
+		{"effects=ninety", `invalid retention policy "effects=ninety": expected a number of ledgers or a duration, got "ninety"`},
+		{"effects=xd", `invalid retention policy "effects=xd": invalid number of days "xd"`},
+		{"effects=-1h", `invalid retention policy "effects=-1h": negative duration "-1h"`},
+	} {
+		t.Run(testCase.value, func(t *testing.T) {
+			_, err := ParseRetentionPolicies(testCase.value)
+			assert.EqualError(t, err, testCase.err)
+		})
+	}
+}
+
+func TestSystemEnabled(t *testing.T) {
+	sys := &System{}
+	assert.False(t, sys.Enabled())
+
+	sys.RetentionPolicies = map[string]RetentionPolicy{"effects": {}}
+	assert.False(t, sys.Enabled())
+
+	sys.RetentionPolicies = map[string]RetentionPolicy{"effects": {Duration: time.Hour}}
+	assert.True(t, sys.Enabled())
+	assert.Equal(t, RetentionPolicy{Duration: time.Hour}, sys.policy("effects"))
+	assert.Equal(t, RetentionPolicy{}, sys.policy("ledgers"))
+
+	sys.RetentionCount = 10
+	assert.Equal(t, RetentionPolicy{Ledgers: 10}, sys.policy("ledgers"))
+}
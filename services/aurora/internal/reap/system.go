@@ -4,6 +4,8 @@ import (
 	"context"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	herrors "github.com/hcnet/go/services/aurora/internal/errors"
 	"github.com/hcnet/go/support/errors"
 	"github.com/hcnet/go/support/log"
@@ -11,31 +13,99 @@ import (
 )
 
 // DeleteUnretainedHistory removes all data associated with unretained ledgers.
+// Each group of history tables is reaped according to its retention policy.
 func (r *System) DeleteUnretainedHistory(ctx context.Context) error {
-	// RetentionCount of 0 indicates "keep all history"
-	if r.RetentionCount == 0 {
+	// RetentionCount of 0 and no policies indicates "keep all history"
+	if !r.Enabled() {
 		return nil
 	}
 
-	var (
-		latest      = r.ledgerState.CurrentStatus()
-		targetElder = (latest.HistoryLatest - int32(r.RetentionCount)) + 1
-	)
+	startTime := time.Now()
+	defer func() {
+		r.metrics.RunDurationSummary.Observe(time.Since(startTime).Seconds())
+	}()
 
-	if targetElder < latest.HistoryElder {
-		return nil
+	latest := r.ledgerState.CurrentStatus()
+	for _, group := range tableGroups {
+		policy := r.policy(group.name)
+		if policy.RetainsAll() {
+			continue
+		}
+
+		targetElder, err := r.targetElder(ctx, policy, latest.HistoryLatest)
+		if err != nil {
+			return err
+		}
+
+		startSeq, err := r.groupElder(ctx, group.tables)
+		if err != nil {
+			return err
+		}
+		if startSeq == 0 || targetElder <= startSeq {
+			continue
+		}
+
+		err = r.dropPartitions(ctx, group.tables, targetElder)
+		if err != nil {
+			return err
+		}
+
+		err = r.clearBefore(ctx, group.name, group.tables, startSeq, targetElder)
+		if err != nil {
+			return err
+		}
+
+		log.
+			WithField("table", group.name).
+			WithField("retention", policy.String()).
+			WithField("new_elder", targetElder).
+			Info("reaper succeeded")
 	}
 
-	err := r.clearBefore(ctx, latest.HistoryElder, targetElder)
-	if err != nil {
-		return err
+	return nil
+}
+
+// groupElder returns the ledger sequence from which the tables of a group
+// must be reaped, or 0 if they are empty. It's the oldest ledger with rows in
+// any of the tables. It's loaded on every run because rows can be removed or
+// inserted by other instances or commands (ex. reingestion of a range).
+func (r *System) groupElder(ctx context.Context, tables []string) (int32, error) {
+	var elder int32
+	for _, table := range tables {
+		tableElder, err := r.HistoryQ.HistoryTableElder(ctx, table)
+		if err != nil {
+			return 0, err
+		}
+		if tableElder > 0 && (elder == 0 || tableElder < elder) {
+			elder = tableElder
+		}
 	}
+	return elder, nil
+}
 
-	log.
-		WithField("new_elder", targetElder).
-		Info("reaper succeeded")
+// targetElder returns the sequence of the oldest ledger which should be
+// retained according to the policy or 0 if there is nothing to remove.
+func (r *System) targetElder(ctx context.Context, policy RetentionPolicy, historyLatest int32) (int32, error) {
+	var targetElder int32
+	if policy.Duration != 0 {
+		sequence, err := r.HistoryQ.LatestLedgerClosedBefore(ctx, time.Now().Add(-policy.Duration))
+		if err != nil {
+			return 0, errors.Wrap(err, "Error getting ledger closed before retention duration")
+		}
+		if sequence == 0 {
+			return 0, nil
+		}
+		targetElder = sequence + 1
+	} else {
+		targetElder = (historyLatest - int32(policy.Ledgers)) + 1
+	}
 
-	return nil
+	// Never remove the latest ledger, even if it's older than the
+	// retention duration.
+	if targetElder > historyLatest {
+		targetElder = historyLatest
+	}
+	return targetElder, nil
 }
 
 // Run triggers the reaper system to update itself, deleted unretained history
@@ -70,7 +140,9 @@ func (r *System) runOnce(ctx context.Context) {
 	}
 }
 
-// Work backwards in 100k ledger blocks to prevent using all the CPU.
+// Work forwards in 100k ledger blocks to prevent using all the CPU. Working
+// forwards keeps the retained history contiguous if the reaper is
+// interrupted.
 //
 // This runs every hour, so we need to make sure it doesn't
 // run for longer than an hour.
@@ -79,40 +151,68 @@ func (r *System) runOnce(ctx context.Context) {
 // batch/second, that seems like a reasonable balance between running well
 // under an hour, and slowing it down enough to leave some CPU for other
 // processes.
+//
+// Within a block rows are removed in ranges of toids containing about
+// System.BatchSize rows, each in its own short transaction, to avoid locking
+// large numbers of rows.
 var batchSize = int32(100_000)
 var sleep = 1 * time.Second
 
-func (r *System) clearBefore(ctx context.Context, startSeq, endSeq int32) error {
-	for batchEndSeq := endSeq - 1; batchEndSeq >= startSeq; batchEndSeq -= batchSize {
-		batchStartSeq := batchEndSeq - batchSize
-		if batchStartSeq < startSeq {
-			batchStartSeq = startSeq
+func (r *System) clearBefore(ctx context.Context, group string, tables []string, startSeq, endSeq int32) error {
+	rowBatchSize := r.BatchSize
+	if rowBatchSize <= 0 {
+		rowBatchSize = DefaultBatchSize
+	}
+
+	for batchStartSeq := startSeq; batchStartSeq < endSeq; batchStartSeq += batchSize {
+		batchEndSeq := batchStartSeq + batchSize - 1
+		if batchEndSeq >= endSeq {
+			batchEndSeq = endSeq - 1
 		}
-		log.WithField("start_ledger", batchStartSeq).WithField("end_ledger", batchEndSeq).Info("reaper: clearing")
+		log.
+			WithField("table", group).
+			WithField("start_ledger", batchStartSeq).
+			WithField("end_ledger", batchEndSeq).
+			Info("reaper: clearing")
 
 		batchStart, batchEnd, err := toid.LedgerRangeInclusive(batchStartSeq, batchEndSeq)
 		if err != nil {
 			return err
 		}
 
-		err = r.HistoryQ.Begin()
-		if err != nil {
-			return errors.Wrap(err, "Error in begin")
+		if r.Archiver != nil {
+			for _, table := range tables {
+				if _, err = r.Archiver.Export(ctx, r.HistoryQ, table, batchStartSeq, batchEndSeq); err != nil {
+					return errors.Wrapf(err, "Error archiving %s", table)
+				}
+			}
 		}
-		defer r.HistoryQ.Rollback()
 
-		err = r.HistoryQ.DeleteRangeAll(ctx, batchStart, batchEnd)
-		if err != nil {
-			return errors.Wrap(err, "Error in DeleteRangeAll")
+		for _, table := range tables {
+			if err = r.clearTable(ctx, table, batchStart, batchEnd, rowBatchSize); err != nil {
+				return err
+			}
 		}
 
-		err = r.HistoryQ.Commit()
-		if err != nil {
-			return errors.Wrap(err, "Error in commit")
-		}
+		r.metrics.ElderLedgerGauge.
+			With(prometheus.Labels{"table": group}).Set(float64(batchEndSeq + 1))
 
 		time.Sleep(sleep)
 	}
 
 	return nil
 }
+
+func (r *System) clearTable(ctx context.Context, table string, start, end int64, rowBatchSize int) error {
+	for start < end {
+		deleted, next, err := r.HistoryQ.DeleteRangeBatch(ctx, table, start, end, rowBatchSize)
+		if err != nil {
+			return errors.Wrap(err, "Error in DeleteRangeBatch")
+		}
+
+		r.metrics.DeletedRowsCounter.
+			With(prometheus.Labels{"table": table}).Add(float64(deleted))
+		start = next
+	}
+	return nil
+}
@@ -2,9 +2,13 @@ package reap
 
 import (
 	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
 
 	"github.com/hcnet/go/services/aurora/internal/ledger"
 	"github.com/hcnet/go/services/aurora/internal/test"
+	"github.com/hcnet/go/toid"
 )
 
 func TestDeleteUnretainedHistory(t *testing.T) {
@@ -52,3 +56,64 @@ func TestDeleteUnretainedHistory(t *testing.T) {
 		tt.Assert.Equal(1, cur)
 	}
 }
+
+func TestDeleteUnretainedHistoryPerTablePolicies(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	ledgerState := &ledger.State{}
+	ledgerState.SetStatus(tt.Scenario("kahuna"))
+
+	db := tt.AuroraSession()
+
+	sys := New(0, db, ledgerState)
+	sys.BatchSize = 2
+	sys.RetentionPolicies = map[string]RetentionPolicy{
+		"effects":    {Ledgers: 1},
+		"operations": {Duration: time.Nanosecond},
+	}
+
+	// Disable sleeps for this.
+	sleep = 0
+
+	var (
+		prevLedgers int
+		curLedgers  int
+		prevTrades  int
+		curTrades   int
+		count       int
+	)
+	err := db.GetRaw(tt.Ctx, &prevLedgers, `SELECT COUNT(*) FROM history_ledgers`)
+	tt.Require.NoError(err)
+	err = db.GetRaw(tt.Ctx, &prevTrades, `SELECT COUNT(*) FROM history_trades`)
+	tt.Require.NoError(err)
+
+	latest := ledgerState.CurrentStatus().HistoryLatest
+	latestStart, _, err := toid.LedgerRangeInclusive(latest, latest)
+	tt.Require.NoError(err)
+
+	err = sys.DeleteUnretainedHistory(tt.Ctx)
+	tt.Require.NoError(err)
+
+	err = db.GetRaw(tt.Ctx, &curLedgers, `SELECT COUNT(*) FROM history_ledgers`)
+	tt.Require.NoError(err)
+	tt.Assert.Equal(prevLedgers, curLedgers, "Ledgers deleted without a ledgers policy")
+
+	err = db.GetRaw(tt.Ctx, &curTrades, `SELECT COUNT(*) FROM history_trades`)
+	tt.Require.NoError(err)
+	tt.Assert.Equal(prevTrades, curTrades, "Trades deleted without a trades policy")
+
+	err = db.GetRaw(tt.Ctx, &count, `SELECT COUNT(*) FROM history_effects WHERE history_operation_id < $1`, latestStart)
+	tt.Require.NoError(err)
+	tt.Assert.Equal(0, count)
+
+	// The latest ledger is never removed by a duration policy
+	err = db.GetRaw(tt.Ctx, &count, `SELECT COUNT(*) FROM history_operations WHERE id < $1`, latestStart)
+	tt.Require.NoError(err)
+	tt.Assert.Equal(0, count)
+	err = db.GetRaw(tt.Ctx, &count, `SELECT COUNT(*) FROM history_operation_participants WHERE history_operation_id < $1`, latestStart)
+	tt.Require.NoError(err)
+	tt.Assert.Equal(0, count)
+
+	tt.Assert.Equal(float64(latest), testutil.ToFloat64(sys.Metrics().ElderLedgerGauge.WithLabelValues("effects")))
+	tt.Assert.True(testutil.ToFloat64(sys.Metrics().DeletedRowsCounter.WithLabelValues("history_effects")) > 0)
+}
@@ -20,6 +20,8 @@ func TestNegotiate(t *testing.T) {
 		// Obeys the Accept header's prioritization
 		{"application/hal+json", MimeHal},
 		{"text/event-stream,application/hal+json", MimeEventStream},
+		{"text/csv", MimeCSV},
+		{"application/x-ndjson", MimeNDJSON},
 		// Defaults to HAL
 		{"text/event-stream;q=0.5,application/hal+json", MimeHal},
 		{"", MimeHal},
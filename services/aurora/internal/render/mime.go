@@ -10,4 +10,8 @@ const (
 	MimeJSON = "application/json"
 	//MimeRaw is the mime type for "application/octet-stream"
 	MimeRaw = "application/octet-stream"
+	//MimeCSV is the mime type for "text/csv"
+	MimeCSV = "text/csv"
+	//MimeNDJSON is the mime type for "application/x-ndjson"
+	MimeNDJSON = "application/x-ndjson"
 )
@@ -40,6 +40,16 @@ var (
 			"headers.",
 	}
 
+	// InvalidAPIKey is a well-known problem type.  Use it as a shortcut
+	// in your actions.
+	InvalidAPIKey = problem.P{
+		Type:   "invalid_api_key",
+		Title:  "Invalid API Key",
+		Status: http.StatusUnauthorized,
+		Detail: "The API key sent in the 'X-API-Key' header is not valid.  " +
+			"Remove it to make requests limited by IP address.",
+	}
+
 	// NotImplemented is a well-known problem type.  Use it as a shortcut
 	// in your actions.
 	NotImplemented = problem.P{
@@ -17,10 +17,38 @@ type Event struct {
 	Retry int
 }
 
+// EventWriter receives the events of a stream in place of the response
+// writer, it allows delivering streams over another transport than server
+// sent events. The preamble and the goodbye events are sent to the
+// EventWriter as well.
+type EventWriter interface {
+	WriteEvent(e Event)
+}
+
+type eventWriterContextKey struct{}
+
+// WithEventWriter returns a context which makes streams served with it send
+// their events to w instead of the response writer.
+func WithEventWriter(ctx context.Context, w EventWriter) context.Context {
+	return context.WithValue(ctx, eventWriterContextKey{}, w)
+}
+
+// EventWriterFromContext returns the EventWriter set with WithEventWriter or
+// nil.
+func EventWriterFromContext(ctx context.Context) EventWriter {
+	w, _ := ctx.Value(eventWriterContextKey{}).(EventWriter)
+	return w
+}
+
 // WritePreamble prepares this http connection for streaming using Server Sent
 // Events. It sends the initial http response with the appropriate headers to
 // do so.
 func WritePreamble(ctx context.Context, w http.ResponseWriter) bool {
+	if ew := EventWriterFromContext(ctx); ew != nil {
+		ew.WriteEvent(helloEvent)
+		return true
+	}
+
 	_, flushable := w.(http.Flusher)
 	if !flushable {
 		//TODO: render a problem struct instead of simple string
@@ -42,6 +70,11 @@ func WritePreamble(ctx context.Context, w http.ResponseWriter) bool {
 // WriteEvent does the actual work of formatting an SSE compliant message
 // sending it over the provided ResponseWriter and flushing.
 func WriteEvent(ctx context.Context, w http.ResponseWriter, e Event) {
+	if ew := EventWriterFromContext(ctx); ew != nil {
+		ew.WriteEvent(e)
+		return
+	}
+
 	if e.Error != nil {
 		fmt.Fprint(w, "event: error\n")
 		fmt.Fprintf(w, "data: %s\n\n", e.Error.Error())
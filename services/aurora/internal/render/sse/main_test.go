@@ -53,3 +53,26 @@ func TestWritePreamble(t *testing.T) {
 	assert.Equal(t, 200, w.Code)
 	assert.Contains(t, w.Body.String(), "retry: 1000\nevent: open\ndata: \"hello\"\n\n")
 }
+
+type recordingEventWriter []Event
+
+func (w *recordingEventWriter) WriteEvent(e Event) {
+	*w = append(*w, e)
+}
+
+// Tests that events are sent to the EventWriter of the context instead of the
+// response writer.
+func TestWriteEventWriter(t *testing.T) {
+	ctx, _ := test.ContextWithLogBuffer()
+	events := &recordingEventWriter{}
+	ctx = WithEventWriter(ctx, events)
+	w := httptest.NewRecorder()
+
+	stream := NewStream(ctx, w)
+	stream.Send(Event{ID: "1", Data: "test"})
+	stream.Done()
+
+	assert.Equal(t, &recordingEventWriter{helloEvent, {ID: "1", Data: "test"}, goodbyeEvent}, events)
+	assert.Empty(t, w.Body.String())
+	assert.Empty(t, w.Header())
+}
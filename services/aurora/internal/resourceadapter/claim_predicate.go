@@ -0,0 +1,127 @@
+package resourceadapter
+
+import (
+	"math"
+	"time"
+
+	protocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/xdr"
+)
+
+// claimInterval is the interval [start, end) of ledger close times, in
+// seconds since epoch, during which a claim predicate is satisfied.
+type claimInterval struct {
+	start int64
+	end   int64
+}
+
+// claimIntervals are sorted, non overlapping intervals.
+type claimIntervals []claimInterval
+
+var alwaysClaimable = claimIntervals{{start: math.MinInt64, end: math.MaxInt64}}
+
+func (intervals claimIntervals) complement() claimIntervals {
+	var result claimIntervals
+	start := int64(math.MinInt64)
+	for _, interval := range intervals {
+		if interval.start > start {
+			result = append(result, claimInterval{start: start, end: interval.start})
+		}
+		start = interval.end
+	}
+	if start < math.MaxInt64 {
+		result = append(result, claimInterval{start: start, end: math.MaxInt64})
+	}
+	return result
+}
+
+func (intervals claimIntervals) intersect(other claimIntervals) claimIntervals {
+	var result claimIntervals
+	for i, j := 0, 0; i < len(intervals) && j < len(other); {
+		start, end := intervals[i].start, intervals[i].end
+		if other[j].start > start {
+			start = other[j].start
+		}
+		if other[j].end < end {
+			end = other[j].end
+		}
+		if start < end {
+			result = append(result, claimInterval{start: start, end: end})
+		}
+		if intervals[i].end < other[j].end {
+			i++
+		} else {
+			j++
+		}
+	}
+	return result
+}
+
+func (intervals claimIntervals) union(other claimIntervals) claimIntervals {
+	return intervals.complement().intersect(other.complement()).complement()
+}
+
+// claimPredicateIntervals evaluates a claim predicate into the intervals of
+// close times during which it's satisfied. Relative predicates are evaluated
+// from createdAt, they are nil when createdAt is unknown and are never
+// satisfied in that case: hcnet-core converts them to absolute predicates
+// when balances are created so they're not expected in ledger entries.
+func claimPredicateIntervals(predicate xdr.ClaimPredicate, createdAt *time.Time) claimIntervals {
+	switch predicate.Type {
+	case xdr.ClaimPredicateTypeClaimPredicateUnconditional:
+		return alwaysClaimable
+	case xdr.ClaimPredicateTypeClaimPredicateAnd:
+		result := alwaysClaimable
+		for _, and := range *predicate.AndPredicates {
+			result = result.intersect(claimPredicateIntervals(and, createdAt))
+		}
+		return result
+	case xdr.ClaimPredicateTypeClaimPredicateOr:
+		var result claimIntervals
+		for _, or := range *predicate.OrPredicates {
+			result = result.union(claimPredicateIntervals(or, createdAt))
+		}
+		return result
+	case xdr.ClaimPredicateTypeClaimPredicateNot:
+		if predicate.NotPredicate == nil || *predicate.NotPredicate == nil {
+			return nil
+		}
+		return claimPredicateIntervals(**predicate.NotPredicate, createdAt).complement()
+	case xdr.ClaimPredicateTypeClaimPredicateBeforeAbsoluteTime:
+		return claimIntervals{{start: math.MinInt64, end: int64(*predicate.AbsBefore)}}
+	case xdr.ClaimPredicateTypeClaimPredicateBeforeRelativeTime:
+		if createdAt == nil {
+			return nil
+		}
+		end := createdAt.Unix() + int64(*predicate.RelBefore)
+		if end < createdAt.Unix() {
+			// overflow
+			end = math.MaxInt64
+		}
+		return claimIntervals{{start: math.MinInt64, end: end}}
+	default:
+		return nil
+	}
+}
+
+// claimableWindow returns the period of time including or following closedAt
+// during which predicate is satisfied, or nil if the predicate can't be
+// satisfied after closedAt. Relative predicates are evaluated from createdAt.
+func claimableWindow(predicate xdr.ClaimPredicate, createdAt *time.Time, closedAt time.Time) *protocol.ClaimableWindow {
+	for _, interval := range claimPredicateIntervals(predicate, createdAt) {
+		if interval.end <= closedAt.Unix() {
+			continue
+		}
+		window := &protocol.ClaimableWindow{}
+		if interval.start > closedAt.Unix() {
+			start := time.Unix(interval.start, 0).UTC()
+			window.Start = &start
+		}
+		if interval.end < math.MaxInt64 {
+			end := time.Unix(interval.end, 0).UTC()
+			window.End = &end
+		}
+		return window
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package resourceadapter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	protocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/xdr"
+)
+
+func absBefore(seconds int64) xdr.ClaimPredicate {
+	before := xdr.Int64(seconds)
+	return xdr.ClaimPredicate{Type: xdr.ClaimPredicateTypeClaimPredicateBeforeAbsoluteTime, AbsBefore: &before}
+}
+
+func relBefore(seconds int64) xdr.ClaimPredicate {
+	before := xdr.Int64(seconds)
+	return xdr.ClaimPredicate{Type: xdr.ClaimPredicateTypeClaimPredicateBeforeRelativeTime, RelBefore: &before}
+}
+
+func not(predicate xdr.ClaimPredicate) xdr.ClaimPredicate {
+	notPredicate := &predicate
+	return xdr.ClaimPredicate{Type: xdr.ClaimPredicateTypeClaimPredicateNot, NotPredicate: &notPredicate}
+}
+
+func and(predicates ...xdr.ClaimPredicate) xdr.ClaimPredicate {
+	return xdr.ClaimPredicate{Type: xdr.ClaimPredicateTypeClaimPredicateAnd, AndPredicates: &predicates}
+}
+
+func or(predicates ...xdr.ClaimPredicate) xdr.ClaimPredicate {
+	return xdr.ClaimPredicate{Type: xdr.ClaimPredicateTypeClaimPredicateOr, OrPredicates: &predicates}
+}
+
+func TestClaimableWindow(t *testing.T) {
+	at := func(seconds int64) *time.Time {
+		t := time.Unix(seconds, 0).UTC()
+		return &t
+	}
+	createdAt := at(50)
+
+	for _, testCase := range []struct {
+		name      string
+		predicate xdr.ClaimPredicate
+		closedAt  int64
+		expected  *protocol.ClaimableWindow
+	}{
+		{
+			"unconditional",
+			xdr.ClaimPredicate{Type: xdr.ClaimPredicateTypeClaimPredicateUnconditional},
+			100,
+			&protocol.ClaimableWindow{},
+		},
+		{"before absolute time", absBefore(200), 100, &protocol.ClaimableWindow{End: at(200)}},
+		{"expired", absBefore(100), 100, nil},
+		{"not before absolute time", not(absBefore(200)), 100, &protocol.ClaimableWindow{Start: at(200)}},
+		{"before relative time", relBefore(100), 100, &protocol.ClaimableWindow{End: at(150)}},
+		{
+			"between",
+			and(not(absBefore(200)), absBefore(300)),
+			100,
+			&protocol.ClaimableWindow{Start: at(200), End: at(300)},
+		},
+		{
+			"first of two windows",
+			or(absBefore(150), not(absBefore(300))),
+			100,
+			&protocol.ClaimableWindow{End: at(150)},
+		},
+		{
+			"second of two windows",
+			or(absBefore(150), not(absBefore(300))),
+			200,
+			&protocol.ClaimableWindow{Start: at(300)},
+		},
+		{
+			"overlapping windows",
+			or(and(not(absBefore(100)), absBefore(300)), and(not(absBefore(200)), absBefore(400))),
+			250,
+			&protocol.ClaimableWindow{End: at(400)},
+		},
+		{"empty intersection", and(absBefore(100), not(absBefore(200))), 0, nil},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			window := claimableWindow(testCase.predicate, createdAt, time.Unix(testCase.closedAt, 0))
+			assert.Equal(t, testCase.expected, window)
+			if window != nil {
+				assert.Equal(t, window.Start == nil, window.Contains(time.Unix(testCase.closedAt, 0)))
+			}
+		})
+	}
+
+	assert.Nil(t, claimableWindow(relBefore(100), nil, time.Unix(0, 0)))
+}
@@ -3,6 +3,7 @@ package resourceadapter
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/hcnet/go/amount"
 	protocol "github.com/hcnet/go/protocols/aurora"
@@ -12,12 +13,14 @@ import (
 	"github.com/hcnet/go/xdr"
 )
 
-// PopulateClaimableBalance fills out the resource's fields
+// PopulateClaimableBalance fills out the resource's fields, the claimable
+// windows of the claimants are evaluated at latestClosedAt.
 func PopulateClaimableBalance(
 	ctx context.Context,
 	dest *protocol.ClaimableBalance,
 	claimableBalance history.ClaimableBalance,
 	ledger *history.Ledger,
+	latestClosedAt time.Time,
 ) error {
 	dest.BalanceID = claimableBalance.BalanceID
 	dest.Asset = claimableBalance.Asset.StringCanonical()
@@ -26,14 +29,17 @@ func PopulateClaimableBalance(
 		dest.Sponsor = claimableBalance.Sponsor.String
 	}
 	dest.LastModifiedLedger = claimableBalance.LastModifiedLedger
+	if ledger != nil {
+		dest.LastModifiedTime = &ledger.ClosedAt
+	}
+
 	dest.Claimants = make([]protocol.Claimant, len(claimableBalance.Claimants))
 	for i, c := range claimableBalance.Claimants {
 		dest.Claimants[i].Destination = c.Destination
 		dest.Claimants[i].Predicate = c.Predicate
-	}
-
-	if ledger != nil {
-		dest.LastModifiedTime = &ledger.ClosedAt
+		// balances are only modified by sponsorship changes after they're
+		// created so the last modified time is used for relative predicates
+		dest.Claimants[i].ClaimableWindow = claimableWindow(c.Predicate, dest.LastModifiedTime, latestClosedAt)
 	}
 
 	if xdr.ClaimableBalanceFlags(claimableBalance.Flags).IsClawbackEnabled() {
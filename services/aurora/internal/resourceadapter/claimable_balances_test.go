@@ -3,6 +3,7 @@ package resourceadapter
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/guregu/null"
 	. "github.com/hcnet/go/protocols/aurora"
@@ -65,7 +66,7 @@ func TestPopulateClaimableBalance(t *testing.T) {
 		Flags:              uint32(xdr.ClaimableBalanceFlagsClaimableBalanceClawbackEnabledFlag),
 	}
 
-	err = PopulateClaimableBalance(ctx, &resource, claimableBalance, nil)
+	err = PopulateClaimableBalance(ctx, &resource, claimableBalance, nil, time.Unix(1598440000, 0))
 	tt.NoError(err)
 
 	tt.Equal("000000000102030000000000000000000000000000000000000000000000000000000000", resource.BalanceID)
@@ -75,6 +76,8 @@ func TestPopulateClaimableBalance(t *testing.T) {
 	tt.Equal(uint32(123), resource.LastModifiedLedger)
 	tt.Len(resource.Claimants, 1)
 	tt.Equal("GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML", resource.Claimants[0].Destination)
+	// not unconditional can't be satisfied
+	tt.Nil(resource.Claimants[0].ClaimableWindow)
 	tt.Equal("123-000000000102030000000000000000000000000000000000000000000000000000000000", resource.PagingToken())
 	tt.True(resource.Flags.ClawbackEnabled)
 
@@ -0,0 +1,95 @@
+package resourceadapter
+
+import (
+	"context"
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/hcnet/go/amount"
+	protocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/toid"
+	"github.com/hcnet/go/xdr"
+)
+
+// PopulateLiquidityPoolHistory fills out the resource's fields from the last
+// snapshot of the pool in the bucket, the snapshot of the pool at the end of
+// the previous bucket (nil if unknown) and the trade volumes of the bucket.
+// reserveAssets are the assets of the reserves of the pool and assets maps
+// the ids of the reserve assets to their canonical form.
+func PopulateLiquidityPoolHistory(
+	ctx context.Context,
+	dest *protocol.LiquidityPoolHistory,
+	reserveAssets [2]xdr.Asset,
+	bucket history.LiquidityPoolHistoryBucket,
+	open *history.LiquidityPoolSnapshot,
+	volumes []history.LiquidityPoolTradeVolume,
+	assets map[int64]string,
+) error {
+	dest.Timestamp = bucket.Timestamp
+	dest.Ledger = toid.Parse(bucket.LedgerToid).LedgerSequence
+	dest.Deleted = bucket.Deleted
+	dest.TotalTrustlines = uint64(bucket.TrustlineCount)
+	dest.TotalShares = amount.StringFromInt64(bucket.ShareCount)
+	dest.Reserves = []protocol.LiquidityPoolReserve{
+		{
+			Asset:  reserveAssets[0].StringCanonical(),
+			Amount: amount.StringFromInt64(bucket.ReserveA),
+		},
+		{
+			Asset:  reserveAssets[1].StringCanonical(),
+			Amount: amount.StringFromInt64(bucket.ReserveB),
+		},
+	}
+
+	price := liquidityPoolPrice(bucket.ReserveA, bucket.ReserveB)
+	if price != nil {
+		dest.Price = price.FloatString(7)
+	}
+	if open != nil {
+		openPrice := liquidityPoolPrice(open.ReserveA, open.ReserveB)
+		if openPrice != nil {
+			dest.OpenPrice = openPrice.FloatString(7)
+		}
+		if price != nil && openPrice != nil {
+			ratio := new(big.Rat).Quo(price, openPrice)
+			dest.PriceRatio = ratio.FloatString(7)
+			ratioFloat, _ := ratio.Float64()
+			dest.ImpermanentLoss = strconv.FormatFloat(impermanentLoss(ratioFloat), 'f', 7, 64)
+		}
+	}
+
+	dest.Volume = []protocol.LiquidityPoolVolume{}
+	for _, volume := range volumes {
+		asset, ok := assets[volume.AssetID]
+		if !ok {
+			return errors.Errorf("unknown asset id: %d", volume.AssetID)
+		}
+		dest.TradeCount += volume.TradeCount
+		dest.Volume = append(dest.Volume, protocol.LiquidityPoolVolume{
+			Asset:      asset,
+			AmountIn:   amount.StringFromInt64(volume.AmountIn),
+			AmountOut:  amount.StringFromInt64(volume.AmountOut),
+			FeeRevenue: amount.StringFromInt64(volume.Fees),
+		})
+	}
+	return nil
+}
+
+// liquidityPoolPrice returns the price of the first reserve of a pool in
+// units of the second one, or nil if a reserve is empty.
+func liquidityPoolPrice(reserveA, reserveB int64) *big.Rat {
+	if reserveA <= 0 || reserveB <= 0 {
+		return nil
+	}
+	return big.NewRat(reserveB, reserveA)
+}
+
+// impermanentLoss returns the relative loss of value of the shares of a
+// constant product pool compared to holding its reserves when the price
+// changes by ratio.
+func impermanentLoss(ratio float64) float64 {
+	return 2*math.Sqrt(ratio)/(1+ratio) - 1
+}
@@ -0,0 +1,77 @@
+package resourceadapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	protocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/toid"
+	"github.com/hcnet/go/xdr"
+)
+
+func TestPopulateLiquidityPoolHistory(t *testing.T) {
+	usd := xdr.MustNewCreditAsset("USD", "GAXMF43TGZHW3QN3REOUA2U5PW5BTARXGGYJ3JIFHW3YT6QRKRL3CPPU")
+	reserveAssets := [2]xdr.Asset{xdr.MustNewNativeAsset(), usd}
+	bucket := history.LiquidityPoolHistoryBucket{
+		Timestamp: 3600000,
+		LiquidityPoolSnapshot: history.LiquidityPoolSnapshot{
+			LedgerToid:     toid.New(10, 0, 0).ToInt64(),
+			PoolID:         "cafebabe",
+			ReserveA:       2000000000,
+			ReserveB:       2000000000,
+			ShareCount:     2000000000,
+			TrustlineCount: 3,
+		},
+	}
+	open := &history.LiquidityPoolSnapshot{ReserveA: 4000000000, ReserveB: 1000000000}
+	volumes := []history.LiquidityPoolTradeVolume{
+		{Timestamp: 3600000, AssetID: 2, TradeCount: 2, AmountIn: 10000000, AmountOut: 30000000, Fees: 30000},
+		{Timestamp: 3600000, AssetID: 1, TradeCount: 1, AmountIn: 5000000, AmountOut: 1000000, Fees: 15000},
+	}
+	assets := map[int64]string{1: "native", 2: usd.StringCanonical()}
+
+	var resource protocol.LiquidityPoolHistory
+	err := PopulateLiquidityPoolHistory(context.Background(), &resource, reserveAssets, bucket, open, volumes, assets)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3600000), resource.Timestamp)
+	assert.Equal(t, int32(10), resource.Ledger)
+	assert.Equal(t, uint64(3), resource.TotalTrustlines)
+	assert.Equal(t, "200.0000000", resource.TotalShares)
+	assert.Equal(t, []protocol.LiquidityPoolReserve{
+		{Asset: "native", Amount: "200.0000000"},
+		{Asset: usd.StringCanonical(), Amount: "200.0000000"},
+	}, resource.Reserves)
+	assert.Equal(t, "1.0000000", resource.Price)
+	assert.Equal(t, "0.2500000", resource.OpenPrice)
+	assert.Equal(t, "4.0000000", resource.PriceRatio)
+	// 2*sqrt(4)/(1+4)-1
+	assert.Equal(t, "-0.2000000", resource.ImpermanentLoss)
+	assert.Equal(t, int64(3), resource.TradeCount)
+	assert.Equal(t, []protocol.LiquidityPoolVolume{
+		{Asset: usd.StringCanonical(), AmountIn: "1.0000000", AmountOut: "3.0000000", FeeRevenue: "0.0030000"},
+		{Asset: "native", AmountIn: "0.5000000", AmountOut: "0.1000000", FeeRevenue: "0.0015000"},
+	}, resource.Volume)
+
+	// prices are unknown when the pool is empty or the previous state is
+	// unknown
+	bucket.ReserveA, bucket.ReserveB, bucket.Deleted = 0, 0, true
+	resource = protocol.LiquidityPoolHistory{}
+	err = PopulateLiquidityPoolHistory(context.Background(), &resource, reserveAssets, bucket, nil, nil, assets)
+	require.NoError(t, err)
+	assert.True(t, resource.Deleted)
+	assert.Empty(t, resource.Price)
+	assert.Empty(t, resource.OpenPrice)
+	assert.Empty(t, resource.PriceRatio)
+	assert.Empty(t, resource.ImpermanentLoss)
+	assert.Equal(t, []protocol.LiquidityPoolVolume{}, resource.Volume)
+
+	err = PopulateLiquidityPoolHistory(
+		context.Background(), &resource, reserveAssets, bucket, nil,
+		[]history.LiquidityPoolTradeVolume{{AssetID: 3}}, assets,
+	)
+	assert.EqualError(t, err, "unknown asset id: 3")
+}
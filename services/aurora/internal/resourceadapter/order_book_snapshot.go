@@ -0,0 +1,83 @@
+package resourceadapter
+
+import (
+	"context"
+	"strconv"
+
+	protocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/toid"
+	"github.com/hcnet/go/xdr"
+)
+
+// PopulateOrderBookSnapshot fills out the resource's fields from a snapshot
+// of the order book of selling and buying, the snapshot must already be
+// oriented so that selling is its base asset.
+func PopulateOrderBookSnapshot(
+	ctx context.Context,
+	dest *protocol.OrderBookSnapshot,
+	snapshot history.OrderBookSnapshot,
+	selling, buying xdr.Asset,
+) error {
+	if err := PopulateAsset(ctx, &dest.Selling, selling); err != nil {
+		return err
+	}
+	if err := PopulateAsset(ctx, &dest.Buying, buying); err != nil {
+		return err
+	}
+	dest.Ledger = toid.Parse(snapshot.LedgerToid).LedgerSequence
+	dest.Bids = populatePriceLevels(snapshot.Bids)
+	dest.Asks = populatePriceLevels(snapshot.Asks)
+	dest.MidPrice = formatMidPrice(snapshot)
+	dest.Depth = populateDepthBands(snapshot.Depth)
+	return nil
+}
+
+// PopulateOrderBookDepth fills out the resource's fields from the last
+// snapshot of an order book in a bucket of its depth chart.
+func PopulateOrderBookDepth(
+	ctx context.Context,
+	dest *protocol.OrderBookDepth,
+	bucket history.OrderBookDepthBucket,
+) {
+	dest.Timestamp = bucket.Timestamp
+	dest.Ledger = toid.Parse(bucket.LedgerToid).LedgerSequence
+	dest.MidPrice = formatMidPrice(bucket.OrderBookSnapshot)
+	dest.Depth = populateDepthBands(bucket.Depth)
+}
+
+func populatePriceLevels(levels history.OrderBookLevels) []protocol.PriceLevel {
+	result := make([]protocol.PriceLevel, len(levels))
+	for i, level := range levels {
+		result[i] = protocol.PriceLevel{
+			PriceR: protocol.Price{
+				N: level.Pricen,
+				D: level.Priced,
+			},
+			Price:  level.Pricef,
+			Amount: level.Amount,
+		}
+	}
+	return result
+}
+
+func populateDepthBands(depth history.OrderBookDepth) []protocol.OrderBookDepthBand {
+	result := make([]protocol.OrderBookDepthBand, len(depth))
+	for i, band := range depth {
+		result[i] = protocol.OrderBookDepthBand{
+			BasisPoints: band.BasisPoints,
+			AskAmount:   band.AskAmount,
+			AskValue:    band.AskValue,
+			BidAmount:   band.BidAmount,
+			BidValue:    band.BidValue,
+		}
+	}
+	return result
+}
+
+func formatMidPrice(snapshot history.OrderBookSnapshot) string {
+	if !snapshot.MidPrice.Valid {
+		return ""
+	}
+	return strconv.FormatFloat(snapshot.MidPrice.Float64, 'f', 7, 64)
+}
@@ -0,0 +1,76 @@
+package resourceadapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/guregu/null"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	protocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/toid"
+	"github.com/hcnet/go/xdr"
+)
+
+func TestPopulateOrderBookSnapshot(t *testing.T) {
+	usd := xdr.MustNewCreditAsset("USD", "GAXMF43TGZHW3QN3REOUA2U5PW5BTARXGGYJ3JIFHW3YT6QRKRL3CPPU")
+	snapshot := history.OrderBookSnapshot{
+		LedgerToid:   toid.New(10, 0, 0).ToInt64(),
+		BaseAsset:    "native",
+		CounterAsset: usd.StringCanonical(),
+		Asks:         history.OrderBookLevels{{Pricen: 3, Priced: 1, Pricef: "3.0000000", Amount: "10.0000000"}},
+		Bids:         history.OrderBookLevels{{Pricen: 1, Priced: 3, Pricef: "0.3333333", Amount: "5.0000000"}},
+		MidPrice:     null.FloatFrom(1),
+		Depth: history.OrderBookDepth{{
+			BasisPoints: 50,
+			AskAmount:   "1.0000000",
+			AskValue:    "1.0000000",
+			BidAmount:   "2.0000000",
+			BidValue:    "2.0000000",
+		}},
+	}
+	depth := []protocol.OrderBookDepthBand{{
+		BasisPoints: 50,
+		AskAmount:   "1.0000000",
+		AskValue:    "1.0000000",
+		BidAmount:   "2.0000000",
+		BidValue:    "2.0000000",
+	}}
+
+	var res protocol.OrderBookSnapshot
+	require.NoError(t, PopulateOrderBookSnapshot(context.Background(), &res, snapshot, xdr.MustNewNativeAsset(), usd))
+	assert.Equal(t, protocol.OrderBookSnapshot{
+		Ledger:   10,
+		Asks:     []protocol.PriceLevel{{PriceR: protocol.Price{N: 3, D: 1}, Price: "3.0000000", Amount: "10.0000000"}},
+		Bids:     []protocol.PriceLevel{{PriceR: protocol.Price{N: 1, D: 3}, Price: "0.3333333", Amount: "5.0000000"}},
+		Selling:  protocol.Asset{Type: "native"},
+		Buying:   protocol.Asset{Type: "credit_alphanum4", Code: "USD", Issuer: "GAXMF43TGZHW3QN3REOUA2U5PW5BTARXGGYJ3JIFHW3YT6QRKRL3CPPU"},
+		MidPrice: "1.0000000",
+		Depth:    depth,
+	}, res)
+
+	var bucket protocol.OrderBookDepth
+	PopulateOrderBookDepth(context.Background(), &bucket, history.OrderBookDepthBucket{
+		Timestamp:         3600000,
+		OrderBookSnapshot: snapshot,
+	})
+	assert.Equal(t, protocol.OrderBookDepth{
+		Timestamp: 3600000,
+		Ledger:    10,
+		MidPrice:  "1.0000000",
+		Depth:     depth,
+	}, bucket)
+	assert.Equal(t, "3600000", bucket.PagingToken())
+
+	// one side of the order book is empty
+	snapshot.Asks = nil
+	snapshot.MidPrice = null.Float{}
+	snapshot.Depth = nil
+	res = protocol.OrderBookSnapshot{}
+	require.NoError(t, PopulateOrderBookSnapshot(context.Background(), &res, snapshot, xdr.MustNewNativeAsset(), usd))
+	assert.Empty(t, res.MidPrice)
+	assert.Equal(t, []protocol.PriceLevel{}, res.Asks)
+	assert.Equal(t, []protocol.OrderBookDepthBand{}, res.Depth)
+}
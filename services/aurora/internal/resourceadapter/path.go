@@ -61,3 +61,36 @@ func PopulatePath(ctx context.Context, dest *aurora.Path, p paths.Path) (err err
 	}
 	return
 }
+
+// PopulateSplitPath converts the paths.SplitPath into a SplitPath
+func PopulateSplitPath(ctx context.Context, dest *aurora.SplitPath, p paths.SplitPath) (err error) {
+	dest.DestinationAmount = amount.String(p.DestinationAmount)
+	dest.SourceAmount = amount.String(p.SourceAmount)
+
+	err = extractAsset(
+		p.Source,
+		&dest.SourceAssetType,
+		&dest.SourceAssetCode,
+		&dest.SourceAssetIssuer)
+	if err != nil {
+		return
+	}
+
+	err = extractAsset(
+		p.Destination,
+		&dest.DestinationAssetType,
+		&dest.DestinationAssetCode,
+		&dest.DestinationAssetIssuer)
+	if err != nil {
+		return
+	}
+
+	dest.Paths = make([]aurora.Path, len(p.Paths))
+	for i, path := range p.Paths {
+		err = PopulatePath(ctx, &dest.Paths[i], path)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
@@ -48,3 +48,49 @@ func TestPopulatePath(t *testing.T) {
 		},
 	}, dest)
 }
+
+func TestPopulateSplitPath(t *testing.T) {
+	native := xdr.MustNewNativeAsset()
+	usdc := xdr.MustNewCreditAsset("USDC", "GC3C4AKRBQLHOJ45U4XG35ESVWRDECWO5XLDGYADO6DPR3L7KIDVUMML")
+	bingo := xdr.MustNewCreditAsset("BINGO", "GBZ35ZJRIKJGYH5PBKLKOZ5L6EXCNTO7BKIL7DAVVDFQ2ODJEEHHJXIM")
+	p := paths.SplitPath{
+		Source:            native.String(),
+		SourceAmount:      300,
+		Destination:       usdc.String(),
+		DestinationAmount: 500,
+		Paths: []paths.Path{
+			{
+				Path:              []string{},
+				Source:            native.String(),
+				SourceAmount:      100,
+				Destination:       usdc.String(),
+				DestinationAmount: 200,
+			},
+			{
+				Path:              []string{bingo.String()},
+				Source:            native.String(),
+				SourceAmount:      200,
+				Destination:       usdc.String(),
+				DestinationAmount: 300,
+			},
+		},
+	}
+
+	var dest aurora.SplitPath
+	assert.NoError(t, PopulateSplitPath(context.Background(), &dest, p))
+
+	assert.Equal(t, "native", dest.SourceAssetType)
+	assert.Equal(t, "0.0000300", dest.SourceAmount)
+	assert.Equal(t, "USDC", dest.DestinationAssetCode)
+	assert.Equal(t, "0.0000500", dest.DestinationAmount)
+	if assert.Len(t, dest.Paths, 2) {
+		assert.Equal(t, "0.0000100", dest.Paths[0].SourceAmount)
+		assert.Empty(t, dest.Paths[0].Path)
+		assert.Equal(t, "0.0000300", dest.Paths[1].DestinationAmount)
+		assert.Equal(t, []aurora.Asset{{
+			Type:   "credit_alphanum12",
+			Code:   "BINGO",
+			Issuer: "GBZ35ZJRIKJGYH5PBKLKOZ5L6EXCNTO7BKIL7DAVVDFQ2ODJEEHHJXIM",
+		}}, dest.Paths[1].Path)
+	}
+}
@@ -0,0 +1,52 @@
+package resourceadapter
+
+import (
+	"context"
+
+	"github.com/hcnet/go/amount"
+	protocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/xdr"
+)
+
+// PopulateSponsorship fills out the resource's fields, reserves are computed
+// with baseReserve in stroops.
+func PopulateSponsorship(
+	ctx context.Context,
+	dest *protocol.Sponsorship,
+	entry history.SponsoredEntry,
+	baseReserve int32,
+) {
+	dest.PT = entry.PagingToken()
+	dest.Type = entry.Type
+	dest.AccountID = entry.AccountID
+	dest.ReserveCount = entry.ReserveCount
+	dest.Reserve = amount.StringFromInt64(entry.ReserveCount * int64(baseReserve))
+	dest.LastModifiedLedger = entry.LastModifiedLedger
+
+	switch entry.Type {
+	case history.SponsoredSigner:
+		dest.Signer = entry.Signer
+	case history.SponsoredTrustLine:
+		if entry.AssetType == xdr.AssetTypeAssetTypePoolShare {
+			dest.LiquidityPoolID = entry.LiquidityPoolID
+		} else {
+			dest.Asset = entry.AssetCode + ":" + entry.AssetIssuer
+		}
+	case history.SponsoredOffer:
+		dest.OfferID = entry.OfferID
+	case history.SponsoredData:
+		dest.DataName = entry.DataName
+	case history.SponsoredClaimableBalance:
+		dest.BalanceID = entry.BalanceID
+	}
+}
+
+// PopulateSponsorshipTotal fills out the resource's fields, reserves are
+// computed with baseReserve in stroops.
+func PopulateSponsorshipTotal(dest *protocol.SponsorshipTotal, total history.SponsorshipTotal, baseReserve int32) {
+	dest.Type = total.Type
+	dest.Count = total.Count
+	dest.ReserveCount = total.ReserveCount
+	dest.Reserve = amount.StringFromInt64(total.ReserveCount * int64(baseReserve))
+}
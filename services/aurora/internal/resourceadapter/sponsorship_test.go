@@ -0,0 +1,80 @@
+package resourceadapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	protocol "github.com/hcnet/go/protocols/aurora"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/xdr"
+)
+
+func TestPopulateSponsorship(t *testing.T) {
+	issuer := "GAXMF43TGZHW3QN3REOUA2U5PW5BTARXGGYJ3JIFHW3YT6QRKRL3CPPU"
+	for _, testCase := range []struct {
+		entry    history.SponsoredEntry
+		expected protocol.Sponsorship
+	}{
+		{
+			history.SponsoredEntry{
+				Type: history.SponsoredAccount, Key: issuer, AccountID: issuer,
+				ReserveCount: 2, LastModifiedLedger: 10,
+			},
+			protocol.Sponsorship{
+				PT: "account:" + issuer, Type: "account", AccountID: issuer,
+				ReserveCount: 2, Reserve: "1.0000000", LastModifiedLedger: 10,
+			},
+		},
+		{
+			history.SponsoredEntry{
+				Type: history.SponsoredTrustLine, Key: "key", AccountID: issuer,
+				AssetType: xdr.AssetTypeAssetTypeCreditAlphanum4, AssetCode: "USD", AssetIssuer: issuer,
+				ReserveCount: 1,
+			},
+			protocol.Sponsorship{
+				PT: "trustline:key", Type: "trustline", AccountID: issuer,
+				Asset: "USD:" + issuer, ReserveCount: 1, Reserve: "0.5000000",
+			},
+		},
+		{
+			history.SponsoredEntry{
+				Type: history.SponsoredTrustLine, Key: "key", AccountID: issuer,
+				AssetType: xdr.AssetTypeAssetTypePoolShare, LiquidityPoolID: "cafebabe",
+				ReserveCount: 2,
+			},
+			protocol.Sponsorship{
+				PT: "trustline:key", Type: "trustline", AccountID: issuer,
+				LiquidityPoolID: "cafebabe", ReserveCount: 2, Reserve: "1.0000000",
+			},
+		},
+		{
+			history.SponsoredEntry{
+				Type: history.SponsoredOffer, Key: "00000000000000000004", AccountID: issuer,
+				OfferID: 4, ReserveCount: 1,
+			},
+			protocol.Sponsorship{
+				PT: "offer:00000000000000000004", Type: "offer", AccountID: issuer,
+				OfferID: 4, ReserveCount: 1, Reserve: "0.5000000",
+			},
+		},
+		{
+			history.SponsoredEntry{
+				Type: history.SponsoredClaimableBalance, Key: "00ab", BalanceID: "00ab", ReserveCount: 3,
+			},
+			protocol.Sponsorship{
+				PT: "claimable_balance:00ab", Type: "claimable_balance", BalanceID: "00ab",
+				ReserveCount: 3, Reserve: "1.5000000",
+			},
+		},
+	} {
+		var sponsorship protocol.Sponsorship
+		PopulateSponsorship(context.Background(), &sponsorship, testCase.entry, 5000000)
+		assert.Equal(t, testCase.expected, sponsorship)
+	}
+
+	var total protocol.SponsorshipTotal
+	PopulateSponsorshipTotal(&total, history.SponsorshipTotal{Type: "signer", Count: 3, ReserveCount: 3}, 5000000)
+	assert.Equal(t, protocol.SponsorshipTotal{Type: "signer", Count: 3, ReserveCount: 3, Reserve: "1.5000000"}, total)
+}
@@ -48,7 +48,7 @@ func (finder InMemoryFinder) Find(ctx context.Context, q paths.Query, maxLength
 		return nil, 0, errors.New("invalid value of maxLength")
 	}
 
-	orderbookPaths, lastLedger, err := finder.graph.FindPaths(
+	orderbookPaths, lastLedger, err := finder.graph.FindPathsWithConstraints(
 		ctx,
 		int(maxLength),
 		q.DestinationAsset,
@@ -58,7 +58,8 @@ func (finder InMemoryFinder) Find(ctx context.Context, q paths.Query, maxLength
 		q.SourceAssetBalances,
 		q.ValidateSourceBalance,
 		maxAssetsPerPath,
-		finder.includePools,
+		finder.includePools && !q.Constraints.ExcludePools,
+		orderBookConstraints(q.Constraints),
 	)
 	results := make([]paths.Path, len(orderbookPaths))
 	for i, path := range orderbookPaths {
@@ -84,6 +85,7 @@ func (finder InMemoryFinder) FindFixedPaths(
 	amountToSpend xdr.Int64,
 	destinationAssets []xdr.Asset,
 	maxLength uint,
+	constraints paths.Constraints,
 ) ([]paths.Path, uint32, error) {
 	if finder.graph.IsEmpty() {
 		return nil, 0, ErrEmptyInMemoryOrderBook
@@ -96,14 +98,15 @@ func (finder InMemoryFinder) FindFixedPaths(
 		return nil, 0, errors.New("invalid value of maxLength")
 	}
 
-	orderbookPaths, lastLedger, err := finder.graph.FindFixedPaths(
+	orderbookPaths, lastLedger, err := finder.graph.FindFixedPathsWithConstraints(
 		ctx,
 		int(maxLength),
 		sourceAsset,
 		amountToSpend,
 		destinationAssets,
 		maxAssetsPerPath,
-		finder.includePools,
+		finder.includePools && !constraints.ExcludePools,
+		orderBookConstraints(constraints),
 	)
 	results := make([]paths.Path, len(orderbookPaths))
 	for i, path := range orderbookPaths {
@@ -117,3 +120,137 @@ func (finder InMemoryFinder) FindFixedPaths(
 	}
 	return results, lastLedger, err
 }
+
+// FindSplitPaths implements the path payments finder interface
+func (finder InMemoryFinder) FindSplitPaths(ctx context.Context, q paths.Query, maxLength uint) ([]paths.SplitPath, uint32, error) {
+	if finder.graph.IsEmpty() {
+		return nil, 0, ErrEmptyInMemoryOrderBook
+	}
+
+	if maxLength == 0 {
+		maxLength = MaxInMemoryPathLength
+	}
+	if maxLength > MaxInMemoryPathLength {
+		return nil, 0, errors.New("invalid value of maxLength")
+	}
+
+	splitPaths, lastLedger, err := finder.graph.FindSplitPaths(
+		ctx,
+		int(maxLength),
+		q.DestinationAsset,
+		q.DestinationAmount,
+		q.SourceAccount,
+		q.SourceAssets,
+		q.SourceAssetBalances,
+		q.ValidateSourceBalance,
+		finder.includePools && !q.Constraints.ExcludePools,
+		orderBookConstraints(q.Constraints),
+	)
+	return convertSplitPaths(splitPaths), lastLedger, err
+}
+
+// FindFixedSplitPaths returns, for each destination asset, a payment of
+// `amountToSpend` of `sourceAsset` divided between several payment paths.
+func (finder InMemoryFinder) FindFixedSplitPaths(
+	ctx context.Context,
+	sourceAsset xdr.Asset,
+	amountToSpend xdr.Int64,
+	destinationAssets []xdr.Asset,
+	maxLength uint,
+	constraints paths.Constraints,
+) ([]paths.SplitPath, uint32, error) {
+	if finder.graph.IsEmpty() {
+		return nil, 0, ErrEmptyInMemoryOrderBook
+	}
+
+	if maxLength == 0 {
+		maxLength = MaxInMemoryPathLength
+	}
+	if maxLength > MaxInMemoryPathLength {
+		return nil, 0, errors.New("invalid value of maxLength")
+	}
+
+	splitPaths, lastLedger, err := finder.graph.FindFixedSplitPaths(
+		ctx,
+		int(maxLength),
+		sourceAsset,
+		amountToSpend,
+		destinationAssets,
+		finder.includePools && !constraints.ExcludePools,
+		orderBookConstraints(constraints),
+	)
+	return convertSplitPaths(splitPaths), lastLedger, err
+}
+
+// PathsChangedSince returns false if none of the offers and liquidity pools
+// which can be part of the payment paths of the query were updated in the in
+// memory orderbook after the given ledger.
+func (finder InMemoryFinder) PathsChangedSince(ledger uint32, q paths.Query, maxLength uint) bool {
+	if maxLength == 0 {
+		maxLength = MaxInMemoryPathLength
+	}
+	return finder.graph.PathsChangedSince(
+		ledger,
+		int(maxLength),
+		q.DestinationAsset,
+		q.SourceAssets,
+		finder.includePools && !q.Constraints.ExcludePools,
+		orderBookConstraints(q.Constraints),
+	)
+}
+
+// FixedPathsChangedSince returns false if none of the offers and liquidity
+// pools which can be part of the payment paths from `sourceAsset` to
+// `destinationAssets` were updated in the in memory orderbook after the given
+// ledger.
+func (finder InMemoryFinder) FixedPathsChangedSince(
+	ledger uint32,
+	sourceAsset xdr.Asset,
+	destinationAssets []xdr.Asset,
+	maxLength uint,
+	constraints paths.Constraints,
+) bool {
+	if maxLength == 0 {
+		maxLength = MaxInMemoryPathLength
+	}
+	return finder.graph.FixedPathsChangedSince(
+		ledger,
+		int(maxLength),
+		sourceAsset,
+		destinationAssets,
+		finder.includePools && !constraints.ExcludePools,
+		orderBookConstraints(constraints),
+	)
+}
+
+func orderBookConstraints(constraints paths.Constraints) orderbook.PathConstraints {
+	return orderbook.PathConstraints{
+		ExcludedAssets: constraints.ExcludedAssets,
+		IncludedAssets: constraints.IncludedAssets,
+		ExcludedPools:  constraints.ExcludedPools,
+		ExcludeOffers:  constraints.ExcludeOffers,
+	}
+}
+
+func convertSplitPaths(splitPaths []orderbook.SplitPath) []paths.SplitPath {
+	results := make([]paths.SplitPath, len(splitPaths))
+	for i, splitPath := range splitPaths {
+		results[i] = paths.SplitPath{
+			Source:            splitPath.SourceAsset,
+			SourceAmount:      splitPath.SourceAmount,
+			Destination:       splitPath.DestinationAsset,
+			DestinationAmount: splitPath.DestinationAmount,
+			Paths:             make([]paths.Path, len(splitPath.Paths)),
+		}
+		for j, path := range splitPath.Paths {
+			results[i].Paths[j] = paths.Path{
+				Path:              path.InteriorNodes,
+				Source:            path.SourceAsset,
+				SourceAmount:      path.SourceAmount,
+				Destination:       path.DestinationAsset,
+				DestinationAmount: path.DestinationAmount,
+			}
+		}
+	}
+	return results
+}
@@ -10,3 +10,5 @@ package txsub
 // - system.go: txsub.System, the struct that ties all the interfaces together
 // - open_submission_list.go: A default implementation of the OpenSubmissionList interface
 // - submitter.go: A default implementation of the Submitter interface
+// - status.go: asynchronous submissions and the status of submitted transactions
+// - simulation.go: predicts the result of a transaction using the ingested ledger state
@@ -65,6 +65,11 @@ type SubmissionResult struct {
 	// inclusion in the ledger (i.e. A successful submission).
 	Err error
 
+	// Status is the status of the submission returned by hcnet-core: PENDING,
+	// DUPLICATE, TRY_AGAIN_LATER or ERROR. It is empty when hcnet-core could
+	// not be reached.
+	Status string
+
 	// Duration records the time it took to submit a transaction
 	// to hcnet-core
 	Duration time.Duration
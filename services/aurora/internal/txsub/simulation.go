@@ -0,0 +1,1048 @@
+package txsub
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"math"
+
+	"github.com/hcnet/go/keypair"
+	"github.com/hcnet/go/network"
+	"github.com/hcnet/go/services/aurora/internal/codes"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/strkey"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/xdr"
+)
+
+// SimulationState provides the ingested ledger state transactions are
+// simulated against.
+type SimulationState interface {
+	GetAccountsByIDs(ctx context.Context, ids []string) ([]history.AccountEntry, error)
+	SignersForAccounts(ctx context.Context, accounts []string) ([]history.AccountSigner, error)
+	GetSortedTrustLinesByAccountIDs(ctx context.Context, id []string) ([]history.TrustLine, error)
+	GetAccountDataByKeys(ctx context.Context, keys []history.AccountDataKey) ([]history.Data, error)
+}
+
+// SimulationResult is the predicted result of submitting a transaction. The
+// result codes are the ones surfaced by a failed submission.
+type SimulationResult struct {
+	ResultCodes
+	OperationCodes []string
+	// FeeCharged is the fee the transaction would be charged when the
+	// network is not in surge pricing.
+	FeeCharged int64
+}
+
+// Successful returns true if the transaction is predicted to succeed.
+func (r SimulationResult) Successful() bool {
+	return r.Code == "tx_success" || r.Code == "tx_fee_bump_inner_success"
+}
+
+// SimulateTransaction predicts the result of submitting a transaction in the
+// ledger following the given one, using the ledger state ingested up to it.
+// The transaction is validated like hcnet-core does (sequence number,
+// preconditions, fee, signatures) and its operations are applied to an in
+// memory copy of the accounts, trust lines and data entries they touch,
+// checking balances, trust line authorization and reserves. Offers, liquidity
+// pools and claimable balances are not loaded: path payments and offers are
+// assumed to cross without running out of liquidity and operations on these
+// entries are only checked for their source account and signatures.
+func SimulateTransaction(
+	ctx context.Context,
+	state SimulationState,
+	ledger history.Ledger,
+	networkPassphrase string,
+	envelope xdr.TransactionEnvelope,
+) (SimulationResult, error) {
+	s := &simulation{
+		ctx:      ctx,
+		state:    state,
+		ledger:   ledger,
+		accounts: map[string]*simulatedAccount{},
+	}
+	if err := s.load(envelope); err != nil {
+		return SimulationResult{}, err
+	}
+
+	if !envelope.IsFeeBump() {
+		hash, err := network.HashTransactionInEnvelope(envelope, networkPassphrase)
+		if err != nil {
+			return SimulationResult{}, err
+		}
+		code, operationCodes := s.simulateTransaction(envelope, hash, true)
+		result := SimulationResult{
+			OperationCodes: operationCodes,
+			FeeCharged:     s.feeCharged(int64(envelope.Fee()), len(envelope.Operations())),
+		}
+		result.Code, err = codes.String(code)
+		return result, err
+	}
+
+	outerHash, err := network.HashTransactionInEnvelope(envelope, networkPassphrase)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+	innerHash, err := network.HashTransaction(envelope.FeeBump.Tx.InnerTx.V1.Tx, networkPassphrase)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+	result := SimulationResult{
+		FeeCharged: s.feeCharged(envelope.FeeBumpFee(), len(envelope.Operations())+1),
+	}
+
+	code, innerCode, operationCodes := s.simulateFeeBump(envelope, outerHash, innerHash)
+	result.OperationCodes = operationCodes
+	if result.Code, err = codes.String(code); err != nil {
+		return result, err
+	}
+	if innerCode != nil {
+		result.InnerCode, err = codes.String(*innerCode)
+	}
+	return result, err
+}
+
+type simulatedAccount struct {
+	history.AccountEntry
+	signers []history.AccountSigner
+	// trustLines are keyed by canonical asset, or liquidity pool id for pool
+	// share trust lines.
+	trustLines map[string]*history.TrustLine
+	data       map[string]bool
+}
+
+type simulation struct {
+	ctx    context.Context
+	state  SimulationState
+	ledger history.Ledger
+	// accounts contains the accounts referenced by the transaction, a nil
+	// value means the account does not exist.
+	accounts map[string]*simulatedAccount
+}
+
+// load fetches the state of the accounts referenced by the transaction.
+func (s *simulation) load(envelope xdr.TransactionEnvelope) error {
+	sourceAccount := envelope.SourceAccount().ToAccountId()
+	ids := map[string]bool{sourceAccount.Address(): true}
+	if envelope.IsFeeBump() {
+		ids[envelope.FeeBumpAccount().ToAccountId().Address()] = true
+	}
+	var dataKeys []history.AccountDataKey
+	for _, op := range envelope.Operations() {
+		source := operationSource(op, sourceAccount)
+		ids[source] = true
+		for _, id := range referencedAccounts(op) {
+			ids[id] = true
+		}
+		if op.Body.Type == xdr.OperationTypeManageData {
+			dataKeys = append(dataKeys, history.AccountDataKey{
+				AccountID: source,
+				DataName:  string(op.Body.MustManageDataOp().DataName),
+			})
+		}
+	}
+
+	accountIDs := make([]string, 0, len(ids))
+	for id := range ids {
+		accountIDs = append(accountIDs, id)
+		s.accounts[id] = nil
+	}
+
+	accounts, err := s.state.GetAccountsByIDs(s.ctx, accountIDs)
+	if err != nil {
+		return errors.Wrap(err, "could not load accounts")
+	}
+	for _, account := range accounts {
+		s.accounts[account.AccountID] = &simulatedAccount{
+			AccountEntry: account,
+			trustLines:   map[string]*history.TrustLine{},
+			data:         map[string]bool{},
+		}
+	}
+
+	signers, err := s.state.SignersForAccounts(s.ctx, accountIDs)
+	if err != nil {
+		return errors.Wrap(err, "could not load signers")
+	}
+	for _, signer := range signers {
+		if account := s.accounts[signer.Account]; account != nil {
+			account.signers = append(account.signers, signer)
+		}
+	}
+
+	trustLines, err := s.state.GetSortedTrustLinesByAccountIDs(s.ctx, accountIDs)
+	if err != nil {
+		return errors.Wrap(err, "could not load trust lines")
+	}
+	for i := range trustLines {
+		trustLine := trustLines[i]
+		if account := s.accounts[trustLine.AccountID]; account != nil {
+			account.trustLines[trustLineKey(trustLine)] = &trustLine
+		}
+	}
+
+	if len(dataKeys) > 0 {
+		data, err := s.state.GetAccountDataByKeys(s.ctx, dataKeys)
+		if err != nil {
+			return errors.Wrap(err, "could not load account data")
+		}
+		for _, entry := range data {
+			if account := s.accounts[entry.AccountID]; account != nil {
+				account.data[entry.Name] = true
+			}
+		}
+	}
+	return nil
+}
+
+func (s *simulation) simulateFeeBump(
+	envelope xdr.TransactionEnvelope,
+	outerHash, innerHash [32]byte,
+) (xdr.TransactionResultCode, *xdr.TransactionResultCode, []string) {
+	feeSource := s.accounts[envelope.FeeBumpAccount().ToAccountId().Address()]
+	fee := envelope.FeeBumpFee()
+
+	if fee < int64(s.ledger.BaseFee)*int64(len(envelope.Operations())+1) {
+		return xdr.TransactionResultCodeTxInsufficientFee, nil, nil
+	}
+	if feeSource == nil {
+		return xdr.TransactionResultCodeTxNoAccount, nil, nil
+	}
+	checker := newSignatureChecker(outerHash, envelope.FeeBumpSignatures())
+	if !checker.check(feeSource.signers, feeSource.ThresholdLow) {
+		return xdr.TransactionResultCodeTxBadAuth, nil, nil
+	}
+	if s.availableNativeBalance(feeSource) < fee {
+		return xdr.TransactionResultCodeTxInsufficientBalance, nil, nil
+	}
+	if !checker.allUsed() {
+		return xdr.TransactionResultCodeTxBadAuthExtra, nil, nil
+	}
+	feeSource.Balance -= fee
+
+	innerCode, operationCodes := s.simulateTransaction(envelope, innerHash, false)
+	if innerCode == xdr.TransactionResultCodeTxSuccess {
+		return xdr.TransactionResultCodeTxFeeBumpInnerSuccess, &innerCode, operationCodes
+	}
+	return xdr.TransactionResultCodeTxFeeBumpInnerFailed, &innerCode, operationCodes
+}
+
+// simulateTransaction validates and applies a transaction, chargeFee is false
+// for the inner transaction of fee bump transactions.
+func (s *simulation) simulateTransaction(
+	envelope xdr.TransactionEnvelope,
+	hash [32]byte,
+	chargeFee bool,
+) (xdr.TransactionResultCode, []string) {
+	operations := envelope.Operations()
+	closeTime := s.ledger.ClosedAt.Unix()
+	nextLedger := uint32(s.ledger.Sequence) + 1
+
+	if len(operations) == 0 {
+		return xdr.TransactionResultCodeTxMissingOperation, nil
+	}
+	if timeBounds := envelope.TimeBounds(); timeBounds != nil {
+		if int64(timeBounds.MinTime) > closeTime {
+			return xdr.TransactionResultCodeTxTooEarly, nil
+		}
+		if timeBounds.MaxTime != 0 && int64(timeBounds.MaxTime) < closeTime {
+			return xdr.TransactionResultCodeTxTooLate, nil
+		}
+	}
+	if ledgerBounds := envelope.LedgerBounds(); ledgerBounds != nil {
+		if uint32(ledgerBounds.MinLedger) > nextLedger {
+			return xdr.TransactionResultCodeTxTooEarly, nil
+		}
+		if ledgerBounds.MaxLedger != 0 && uint32(ledgerBounds.MaxLedger) <= nextLedger {
+			return xdr.TransactionResultCodeTxTooLate, nil
+		}
+	}
+	fee := int64(envelope.Fee())
+	if chargeFee && fee < int64(s.ledger.BaseFee)*int64(len(operations)) {
+		return xdr.TransactionResultCodeTxInsufficientFee, nil
+	}
+
+	sourceAccountID := envelope.SourceAccount().ToAccountId()
+	source := s.accounts[sourceAccountID.Address()]
+	if source == nil {
+		return xdr.TransactionResultCodeTxNoAccount, nil
+	}
+	seqNum := envelope.SeqNum()
+	if minSeqNum := envelope.MinSeqNum(); minSeqNum != nil {
+		if source.SequenceNumber < *minSeqNum || source.SequenceNumber >= seqNum {
+			return xdr.TransactionResultCodeTxBadSeq, nil
+		}
+	} else if source.SequenceNumber == math.MaxInt64 || seqNum != source.SequenceNumber+1 {
+		return xdr.TransactionResultCodeTxBadSeq, nil
+	}
+	if minSeqAge := envelope.MinSeqAge(); minSeqAge != nil && *minSeqAge > 0 {
+		if source.SequenceTime.Int64+int64(*minSeqAge) > closeTime {
+			return xdr.TransactionResultCodeTxBadMinSeqAgeOrGap, nil
+		}
+	}
+	if minSeqLedgerGap := envelope.MinSeqLedgerGap(); minSeqLedgerGap != nil && *minSeqLedgerGap > 0 {
+		if source.SequenceLedger.Int64+int64(*minSeqLedgerGap) > int64(nextLedger) {
+			return xdr.TransactionResultCodeTxBadMinSeqAgeOrGap, nil
+		}
+	}
+
+	checker := newSignatureChecker(hash, envelope.Signatures())
+	if !checker.check(source.signers, source.ThresholdLow) {
+		return xdr.TransactionResultCodeTxBadAuth, nil
+	}
+	for _, extraSigner := range envelope.ExtraSigners() {
+		if !checker.signed(extraSigner.Address()) {
+			return xdr.TransactionResultCodeTxBadAuth, nil
+		}
+	}
+	if chargeFee && s.availableNativeBalance(source) < fee {
+		return xdr.TransactionResultCodeTxInsufficientBalance, nil
+	}
+
+	// Operation signatures are checked against the state before the
+	// transaction is applied.
+	operationCodes := make([]string, len(operations))
+	failed := false
+	for i, op := range operations {
+		opSource := s.accounts[operationSource(op, sourceAccountID)]
+		var signed bool
+		if opSource == nil {
+			// The source account may be created by a previous operation, it
+			// must have signed the transaction.
+			signed = checker.signed(operationSource(op, sourceAccountID))
+		} else {
+			signed = checker.check(opSource.signers, thresholdFor(opSource, op))
+		}
+		if !signed {
+			operationCodes[i] = mustCodeString(xdr.OperationResultCodeOpBadAuth)
+			failed = true
+		}
+	}
+	if failed {
+		for i, op := range operations {
+			if operationCodes[i] == "" {
+				operationCodes[i] = mustCodeString(successCode(op.Body.Type))
+			}
+		}
+		return xdr.TransactionResultCodeTxFailed, operationCodes
+	}
+	if !checker.allUsed() {
+		return xdr.TransactionResultCodeTxBadAuthExtra, nil
+	}
+
+	if chargeFee {
+		source.Balance -= fee
+	}
+	source.SequenceNumber = seqNum
+
+	for i, op := range operations {
+		var code interface{}
+		if opSource := s.accounts[operationSource(op, sourceAccountID)]; opSource == nil {
+			code = xdr.OperationResultCodeOpNoAccount
+		} else {
+			code = s.apply(opSource, op)
+		}
+		operationCodes[i] = mustCodeString(code)
+		if code != successCode(op.Body.Type) {
+			failed = true
+		}
+	}
+	if failed {
+		return xdr.TransactionResultCodeTxFailed, operationCodes
+	}
+	return xdr.TransactionResultCodeTxSuccess, operationCodes
+}
+
+// apply applies an operation to the simulated state and returns its result
+// code.
+func (s *simulation) apply(source *simulatedAccount, op xdr.Operation) interface{} {
+	switch op.Body.Type {
+	case xdr.OperationTypeCreateAccount:
+		return s.createAccount(source, op.Body.MustCreateAccountOp())
+	case xdr.OperationTypePayment:
+		return s.payment(source, op.Body.MustPaymentOp())
+	case xdr.OperationTypePathPaymentStrictReceive:
+		return s.pathPaymentStrictReceive(source, op.Body.MustPathPaymentStrictReceiveOp())
+	case xdr.OperationTypePathPaymentStrictSend:
+		return s.pathPaymentStrictSend(source, op.Body.MustPathPaymentStrictSendOp())
+	case xdr.OperationTypeManageSellOffer:
+		offer := op.Body.MustManageSellOfferOp()
+		return s.manageSellOffer(source, offer.Selling, offer.Buying, offer.Amount, offer.OfferId)
+	case xdr.OperationTypeCreatePassiveSellOffer:
+		offer := op.Body.MustCreatePassiveSellOfferOp()
+		return s.manageSellOffer(source, offer.Selling, offer.Buying, offer.Amount, 0)
+	case xdr.OperationTypeManageBuyOffer:
+		return s.manageBuyOffer(source, op.Body.MustManageBuyOfferOp())
+	case xdr.OperationTypeChangeTrust:
+		return s.changeTrust(source, op.Body.MustChangeTrustOp())
+	case xdr.OperationTypeAccountMerge:
+		return s.accountMerge(source, op.Body.MustDestination())
+	case xdr.OperationTypeManageData:
+		return s.manageData(source, op.Body.MustManageDataOp())
+	case xdr.OperationTypeBumpSequence:
+		return s.bumpSequence(source, op.Body.MustBumpSequenceOp())
+	case xdr.OperationTypeCreateClaimableBalance:
+		return s.createClaimableBalance(source, op.Body.MustCreateClaimableBalanceOp())
+	default:
+		return successCode(op.Body.Type)
+	}
+}
+
+func (s *simulation) createAccount(source *simulatedAccount, op xdr.CreateAccountOp) interface{} {
+	destination := op.Destination.Address()
+	if op.StartingBalance < 0 || destination == source.AccountID {
+		return xdr.CreateAccountResultCodeCreateAccountMalformed
+	}
+	if s.accounts[destination] != nil {
+		return xdr.CreateAccountResultCodeCreateAccountAlreadyExist
+	}
+	if int64(op.StartingBalance) < 2*int64(s.ledger.BaseReserve) {
+		return xdr.CreateAccountResultCodeCreateAccountLowReserve
+	}
+	if s.availableNativeBalance(source) < int64(op.StartingBalance) {
+		return xdr.CreateAccountResultCodeCreateAccountUnderfunded
+	}
+
+	source.Balance -= int64(op.StartingBalance)
+	s.accounts[destination] = &simulatedAccount{
+		AccountEntry: history.AccountEntry{
+			AccountID:      destination,
+			Balance:        int64(op.StartingBalance),
+			SequenceNumber: int64(s.ledger.Sequence+1) << 32,
+			MasterWeight:   1,
+		},
+		signers:    []history.AccountSigner{{Account: destination, Signer: destination, Weight: 1}},
+		trustLines: map[string]*history.TrustLine{},
+		data:       map[string]bool{},
+	}
+	return xdr.CreateAccountResultCodeCreateAccountSuccess
+}
+
+// paymentCodes lists the result codes shared by payment operations.
+type paymentCodes struct {
+	success, malformed, underfunded, srcNoTrust, srcNotAuthorized,
+	noDestination, noTrust, notAuthorized, lineFull interface{}
+}
+
+var (
+	paymentResultCodes = paymentCodes{
+		success:          xdr.PaymentResultCodePaymentSuccess,
+		malformed:        xdr.PaymentResultCodePaymentMalformed,
+		underfunded:      xdr.PaymentResultCodePaymentUnderfunded,
+		srcNoTrust:       xdr.PaymentResultCodePaymentSrcNoTrust,
+		srcNotAuthorized: xdr.PaymentResultCodePaymentSrcNotAuthorized,
+		noDestination:    xdr.PaymentResultCodePaymentNoDestination,
+		noTrust:          xdr.PaymentResultCodePaymentNoTrust,
+		notAuthorized:    xdr.PaymentResultCodePaymentNotAuthorized,
+		lineFull:         xdr.PaymentResultCodePaymentLineFull,
+	}
+	pathPaymentStrictReceiveResultCodes = paymentCodes{
+		success:          xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveSuccess,
+		malformed:        xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveMalformed,
+		underfunded:      xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveUnderfunded,
+		srcNoTrust:       xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveSrcNoTrust,
+		srcNotAuthorized: xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveSrcNotAuthorized,
+		noDestination:    xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveNoDestination,
+		noTrust:          xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveNoTrust,
+		notAuthorized:    xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveNotAuthorized,
+		lineFull:         xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveLineFull,
+	}
+	pathPaymentStrictSendResultCodes = paymentCodes{
+		success:          xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSuccess,
+		malformed:        xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendMalformed,
+		underfunded:      xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendUnderfunded,
+		srcNoTrust:       xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSrcNoTrust,
+		srcNotAuthorized: xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSrcNotAuthorized,
+		noDestination:    xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendNoDestination,
+		noTrust:          xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendNoTrust,
+		notAuthorized:    xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendNotAuthorized,
+		lineFull:         xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendLineFull,
+	}
+)
+
+func (s *simulation) payment(source *simulatedAccount, op xdr.PaymentOp) interface{} {
+	if op.Amount <= 0 || !validAsset(op.Asset) {
+		return xdr.PaymentResultCodePaymentMalformed
+	}
+	return s.transfer(source, op.Destination.ToAccountId().Address(), op.Asset, int64(op.Amount), op.Asset, int64(op.Amount), paymentResultCodes)
+}
+
+func (s *simulation) pathPaymentStrictReceive(source *simulatedAccount, op xdr.PathPaymentStrictReceiveOp) interface{} {
+	if op.SendMax <= 0 || op.DestAmount <= 0 || !validAsset(op.SendAsset) || !validAsset(op.DestAsset) {
+		return xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveMalformed
+	}
+	// The amount sent is only known once the path is crossed, the maximum
+	// amount is reserved.
+	return s.transfer(source, op.Destination.ToAccountId().Address(), op.SendAsset, int64(op.SendMax), op.DestAsset, int64(op.DestAmount), pathPaymentStrictReceiveResultCodes)
+}
+
+func (s *simulation) pathPaymentStrictSend(source *simulatedAccount, op xdr.PathPaymentStrictSendOp) interface{} {
+	if op.SendAmount <= 0 || op.DestMin <= 0 || !validAsset(op.SendAsset) || !validAsset(op.DestAsset) {
+		return xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendMalformed
+	}
+	// The amount received is only known once the path is crossed, the
+	// minimum amount is credited.
+	return s.transfer(source, op.Destination.ToAccountId().Address(), op.SendAsset, int64(op.SendAmount), op.DestAsset, int64(op.DestMin), pathPaymentStrictSendResultCodes)
+}
+
+// transfer debits the source account and credits the destination account,
+// the sent and received assets differ for path payments.
+func (s *simulation) transfer(
+	source *simulatedAccount,
+	destinationID string,
+	sendAsset xdr.Asset,
+	sendAmount int64,
+	destAsset xdr.Asset,
+	destAmount int64,
+	resultCodes paymentCodes,
+) interface{} {
+	destination := s.accounts[destinationID]
+	if destination == nil {
+		return resultCodes.noDestination
+	}
+
+	available, trustLine := s.availableBalance(source, sendAsset)
+	if trustLine == nil && !isNative(sendAsset) && !isIssuer(source, sendAsset) {
+		return resultCodes.srcNoTrust
+	}
+	if trustLine != nil && !isAuthorized(trustLine) {
+		return resultCodes.srcNotAuthorized
+	}
+	if available < sendAmount {
+		return resultCodes.underfunded
+	}
+
+	capacity, destTrustLine := s.availableCapacity(destination, destAsset)
+	if destTrustLine == nil && !isNative(destAsset) && !isIssuer(destination, destAsset) {
+		return resultCodes.noTrust
+	}
+	if destTrustLine != nil && !isAuthorized(destTrustLine) {
+		return resultCodes.notAuthorized
+	}
+	if capacity < destAmount {
+		return resultCodes.lineFull
+	}
+
+	s.debit(source, sendAsset, sendAmount)
+	s.credit(destination, destAsset, destAmount)
+	return resultCodes.success
+}
+
+func (s *simulation) manageSellOffer(source *simulatedAccount, selling, buying xdr.Asset, amount xdr.Int64, offerID xdr.Int64) interface{} {
+	if amount < 0 || !validAsset(selling) || !validAsset(buying) || selling.Equals(buying) {
+		return xdr.ManageSellOfferResultCodeManageSellOfferMalformed
+	}
+	if amount == 0 || offerID != 0 {
+		// Updated and deleted offers are not loaded.
+		return xdr.ManageSellOfferResultCodeManageSellOfferSuccess
+	}
+
+	available, sellingTrustLine := s.availableBalance(source, selling)
+	if sellingTrustLine == nil && !isNative(selling) && !isIssuer(source, selling) {
+		return xdr.ManageSellOfferResultCodeManageSellOfferSellNoTrust
+	}
+	if sellingTrustLine != nil && !isAuthorized(sellingTrustLine) {
+		return xdr.ManageSellOfferResultCodeManageSellOfferSellNotAuthorized
+	}
+	_, buyingTrustLine := s.availableCapacity(source, buying)
+	if buyingTrustLine == nil && !isNative(buying) && !isIssuer(source, buying) {
+		return xdr.ManageSellOfferResultCodeManageSellOfferBuyNoTrust
+	}
+	if buyingTrustLine != nil && !isAuthorized(buyingTrustLine) {
+		return xdr.ManageSellOfferResultCodeManageSellOfferBuyNotAuthorized
+	}
+	if available <= 0 {
+		return xdr.ManageSellOfferResultCodeManageSellOfferUnderfunded
+	}
+	if !s.canAddSubentries(source, 1) {
+		return xdr.ManageSellOfferResultCodeManageSellOfferLowReserve
+	}
+	source.NumSubEntries++
+	return xdr.ManageSellOfferResultCodeManageSellOfferSuccess
+}
+
+func (s *simulation) manageBuyOffer(source *simulatedAccount, op xdr.ManageBuyOfferOp) interface{} {
+	if op.BuyAmount < 0 || !validAsset(op.Selling) || !validAsset(op.Buying) || op.Selling.Equals(op.Buying) {
+		return xdr.ManageBuyOfferResultCodeManageBuyOfferMalformed
+	}
+	if op.BuyAmount == 0 || op.OfferId != 0 {
+		// Updated and deleted offers are not loaded.
+		return xdr.ManageBuyOfferResultCodeManageBuyOfferSuccess
+	}
+
+	available, sellingTrustLine := s.availableBalance(source, op.Selling)
+	if sellingTrustLine == nil && !isNative(op.Selling) && !isIssuer(source, op.Selling) {
+		return xdr.ManageBuyOfferResultCodeManageBuyOfferSellNoTrust
+	}
+	if sellingTrustLine != nil && !isAuthorized(sellingTrustLine) {
+		return xdr.ManageBuyOfferResultCodeManageBuyOfferSellNotAuthorized
+	}
+	_, buyingTrustLine := s.availableCapacity(source, op.Buying)
+	if buyingTrustLine == nil && !isNative(op.Buying) && !isIssuer(source, op.Buying) {
+		return xdr.ManageBuyOfferResultCodeManageBuyOfferBuyNoTrust
+	}
+	if buyingTrustLine != nil && !isAuthorized(buyingTrustLine) {
+		return xdr.ManageBuyOfferResultCodeManageBuyOfferBuyNotAuthorized
+	}
+	if available <= 0 {
+		return xdr.ManageBuyOfferResultCodeManageBuyOfferUnderfunded
+	}
+	if !s.canAddSubentries(source, 1) {
+		return xdr.ManageBuyOfferResultCodeManageBuyOfferLowReserve
+	}
+	source.NumSubEntries++
+	return xdr.ManageBuyOfferResultCodeManageBuyOfferSuccess
+}
+
+func (s *simulation) changeTrust(source *simulatedAccount, op xdr.ChangeTrustOp) interface{} {
+	if op.Limit < 0 {
+		return xdr.ChangeTrustResultCodeChangeTrustMalformed
+	}
+	if op.Line.Type == xdr.AssetTypeAssetTypePoolShare {
+		// Liquidity pools are not loaded.
+		return xdr.ChangeTrustResultCodeChangeTrustSuccess
+	}
+	asset := op.Line.ToAsset()
+	if !validAsset(asset) || isNative(asset) || isIssuer(source, asset) {
+		return xdr.ChangeTrustResultCodeChangeTrustMalformed
+	}
+
+	key := asset.StringCanonical()
+	if trustLine := source.trustLines[key]; trustLine != nil {
+		if op.Limit == 0 {
+			if trustLine.Balance > 0 || trustLine.BuyingLiabilities > 0 {
+				return xdr.ChangeTrustResultCodeChangeTrustInvalidLimit
+			}
+			delete(source.trustLines, key)
+			source.NumSubEntries--
+			return xdr.ChangeTrustResultCodeChangeTrustSuccess
+		}
+		if int64(op.Limit) < trustLine.Balance+trustLine.BuyingLiabilities {
+			return xdr.ChangeTrustResultCodeChangeTrustInvalidLimit
+		}
+		trustLine.Limit = int64(op.Limit)
+		return xdr.ChangeTrustResultCodeChangeTrustSuccess
+	}
+
+	if op.Limit == 0 {
+		return xdr.ChangeTrustResultCodeChangeTrustInvalidLimit
+	}
+	var assetType xdr.AssetType
+	var code, issuer string
+	if err := asset.Extract(&assetType, &code, &issuer); err != nil {
+		return xdr.ChangeTrustResultCodeChangeTrustMalformed
+	}
+	issuerAccount := s.accounts[issuer]
+	if issuerAccount == nil {
+		return xdr.ChangeTrustResultCodeChangeTrustNoIssuer
+	}
+	if !s.canAddSubentries(source, 1) {
+		return xdr.ChangeTrustResultCodeChangeTrustLowReserve
+	}
+
+	trustLine := &history.TrustLine{
+		AccountID:   source.AccountID,
+		AssetType:   assetType,
+		AssetCode:   code,
+		AssetIssuer: issuer,
+		Limit:       int64(op.Limit),
+	}
+	if xdr.AccountFlags(issuerAccount.Flags)&xdr.AccountFlagsAuthRequiredFlag == 0 {
+		trustLine.Flags = uint32(xdr.TrustLineFlagsAuthorizedFlag)
+	}
+	source.trustLines[key] = trustLine
+	source.NumSubEntries++
+	return xdr.ChangeTrustResultCodeChangeTrustSuccess
+}
+
+func (s *simulation) accountMerge(source *simulatedAccount, destinationAccount xdr.MuxedAccount) interface{} {
+	destinationID := destinationAccount.ToAccountId().Address()
+	if destinationID == source.AccountID {
+		return xdr.AccountMergeResultCodeAccountMergeMalformed
+	}
+	destination := s.accounts[destinationID]
+	if destination == nil {
+		return xdr.AccountMergeResultCodeAccountMergeNoAccount
+	}
+	if xdr.AccountFlags(source.Flags)&xdr.AccountFlagsAuthImmutableFlag != 0 {
+		return xdr.AccountMergeResultCodeAccountMergeImmutableSet
+	}
+	signers := uint32(0)
+	for _, signer := range source.signers {
+		if signer.Signer != source.AccountID {
+			signers++
+		}
+	}
+	if source.NumSubEntries != signers {
+		return xdr.AccountMergeResultCodeAccountMergeHasSubEntries
+	}
+	if source.SequenceNumber >= int64(s.ledger.Sequence+1)<<32 {
+		return xdr.AccountMergeResultCodeAccountMergeSeqnumTooFar
+	}
+	if source.NumSponsoring > 0 {
+		return xdr.AccountMergeResultCodeAccountMergeIsSponsor
+	}
+	if destination.Balance > math.MaxInt64-destination.BuyingLiabilities-source.Balance {
+		return xdr.AccountMergeResultCodeAccountMergeDestFull
+	}
+
+	destination.Balance += source.Balance
+	s.accounts[source.AccountID] = nil
+	return xdr.AccountMergeResultCodeAccountMergeSuccess
+}
+
+func (s *simulation) manageData(source *simulatedAccount, op xdr.ManageDataOp) interface{} {
+	name := string(op.DataName)
+	if len(name) == 0 || len(name) > 64 {
+		return xdr.ManageDataResultCodeManageDataInvalidName
+	}
+	exists := source.data[name]
+	if op.DataValue == nil {
+		if !exists {
+			return xdr.ManageDataResultCodeManageDataNameNotFound
+		}
+		delete(source.data, name)
+		source.NumSubEntries--
+		return xdr.ManageDataResultCodeManageDataSuccess
+	}
+	if !exists {
+		if !s.canAddSubentries(source, 1) {
+			return xdr.ManageDataResultCodeManageDataLowReserve
+		}
+		source.data[name] = true
+		source.NumSubEntries++
+	}
+	return xdr.ManageDataResultCodeManageDataSuccess
+}
+
+func (s *simulation) bumpSequence(source *simulatedAccount, op xdr.BumpSequenceOp) interface{} {
+	if op.BumpTo < 0 {
+		return xdr.BumpSequenceResultCodeBumpSequenceBadSeq
+	}
+	if int64(op.BumpTo) > source.SequenceNumber {
+		source.SequenceNumber = int64(op.BumpTo)
+	}
+	return xdr.BumpSequenceResultCodeBumpSequenceSuccess
+}
+
+func (s *simulation) createClaimableBalance(source *simulatedAccount, op xdr.CreateClaimableBalanceOp) interface{} {
+	if op.Amount <= 0 || len(op.Claimants) == 0 || !validAsset(op.Asset) {
+		return xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceMalformed
+	}
+
+	// The claimable balance reserve is paid by the source account, before
+	// the amount is debited.
+	reserve := int64(len(op.Claimants)) * int64(s.ledger.BaseReserve)
+	if s.availableNativeBalance(source) < reserve {
+		return xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceLowReserve
+	}
+	source.NumSponsoring += uint32(len(op.Claimants))
+
+	available, trustLine := s.availableBalance(source, op.Asset)
+	if trustLine == nil && !isNative(op.Asset) && !isIssuer(source, op.Asset) {
+		source.NumSponsoring -= uint32(len(op.Claimants))
+		return xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceNoTrust
+	}
+	if trustLine != nil && !isAuthorized(trustLine) {
+		source.NumSponsoring -= uint32(len(op.Claimants))
+		return xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceNotAuthorized
+	}
+	if available < int64(op.Amount) {
+		source.NumSponsoring -= uint32(len(op.Claimants))
+		return xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceUnderfunded
+	}
+	s.debit(source, op.Asset, int64(op.Amount))
+	return xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceSuccess
+}
+
+func (s *simulation) feeCharged(fee int64, operations int) int64 {
+	minFee := int64(s.ledger.BaseFee) * int64(operations)
+	if fee < minFee {
+		return fee
+	}
+	return minFee
+}
+
+func (s *simulation) minimumBalance(account *simulatedAccount) int64 {
+	entries := 2 + int64(account.NumSubEntries) + int64(account.NumSponsoring) - int64(account.NumSponsored)
+	return entries * int64(s.ledger.BaseReserve)
+}
+
+func (s *simulation) availableNativeBalance(account *simulatedAccount) int64 {
+	return account.Balance - s.minimumBalance(account) - account.SellingLiabilities
+}
+
+func (s *simulation) canAddSubentries(account *simulatedAccount, count uint32) bool {
+	return account.Balance-account.SellingLiabilities >= s.minimumBalance(account)+int64(count)*int64(s.ledger.BaseReserve)
+}
+
+// availableBalance returns the amount of an asset an account can send and its
+// trust line, which is nil for native assets and issuers.
+func (s *simulation) availableBalance(account *simulatedAccount, asset xdr.Asset) (int64, *history.TrustLine) {
+	if isNative(asset) {
+		return s.availableNativeBalance(account), nil
+	}
+	if isIssuer(account, asset) {
+		return math.MaxInt64, nil
+	}
+	trustLine := account.trustLines[asset.StringCanonical()]
+	if trustLine == nil {
+		return 0, nil
+	}
+	return trustLine.Balance - trustLine.SellingLiabilities, trustLine
+}
+
+// availableCapacity returns the amount of an asset an account can receive and
+// its trust line, which is nil for native assets and issuers.
+func (s *simulation) availableCapacity(account *simulatedAccount, asset xdr.Asset) (int64, *history.TrustLine) {
+	if isNative(asset) {
+		return math.MaxInt64 - account.Balance - account.BuyingLiabilities, nil
+	}
+	if isIssuer(account, asset) {
+		return math.MaxInt64, nil
+	}
+	trustLine := account.trustLines[asset.StringCanonical()]
+	if trustLine == nil {
+		return 0, nil
+	}
+	return trustLine.Limit - trustLine.Balance - trustLine.BuyingLiabilities, trustLine
+}
+
+func (s *simulation) debit(account *simulatedAccount, asset xdr.Asset, amount int64) {
+	if isNative(asset) {
+		account.Balance -= amount
+	} else if trustLine := account.trustLines[asset.StringCanonical()]; trustLine != nil {
+		trustLine.Balance -= amount
+	}
+}
+
+func (s *simulation) credit(account *simulatedAccount, asset xdr.Asset, amount int64) {
+	if isNative(asset) {
+		account.Balance += amount
+	} else if trustLine := account.trustLines[asset.StringCanonical()]; trustLine != nil {
+		trustLine.Balance += amount
+	}
+}
+
+func isNative(asset xdr.Asset) bool {
+	return asset.Type == xdr.AssetTypeAssetTypeNative
+}
+
+func validAsset(asset xdr.Asset) bool {
+	if isNative(asset) {
+		return true
+	}
+	var assetType xdr.AssetType
+	var code string
+	if err := asset.Extract(&assetType, &code, nil); err != nil {
+		return false
+	}
+	return code != "" && asset.GetIssuer() != ""
+}
+
+func isIssuer(account *simulatedAccount, asset xdr.Asset) bool {
+	return !isNative(asset) && asset.GetIssuer() == account.AccountID
+}
+
+func isAuthorized(trustLine *history.TrustLine) bool {
+	return xdr.TrustLineFlags(trustLine.Flags)&xdr.TrustLineFlagsAuthorizedFlag != 0
+}
+
+func trustLineKey(trustLine history.TrustLine) string {
+	if trustLine.AssetType == xdr.AssetTypeAssetTypePoolShare {
+		return trustLine.LiquidityPoolID
+	}
+	return trustLine.AssetCode + ":" + trustLine.AssetIssuer
+}
+
+func operationSource(op xdr.Operation, transactionSource xdr.AccountId) string {
+	if op.SourceAccount != nil {
+		return op.SourceAccount.ToAccountId().Address()
+	}
+	return transactionSource.Address()
+}
+
+// referencedAccounts returns the accounts, other than the source account,
+// whose state is needed to apply an operation.
+func referencedAccounts(op xdr.Operation) []string {
+	var accounts []string
+	addIssuer := func(asset xdr.Asset) {
+		if issuer := asset.GetIssuer(); issuer != "" {
+			accounts = append(accounts, issuer)
+		}
+	}
+	switch op.Body.Type {
+	case xdr.OperationTypeCreateAccount:
+		accounts = append(accounts, op.Body.MustCreateAccountOp().Destination.Address())
+	case xdr.OperationTypePayment:
+		accounts = append(accounts, op.Body.MustPaymentOp().Destination.ToAccountId().Address())
+	case xdr.OperationTypePathPaymentStrictReceive:
+		accounts = append(accounts, op.Body.MustPathPaymentStrictReceiveOp().Destination.ToAccountId().Address())
+	case xdr.OperationTypePathPaymentStrictSend:
+		accounts = append(accounts, op.Body.MustPathPaymentStrictSendOp().Destination.ToAccountId().Address())
+	case xdr.OperationTypeAccountMerge:
+		accounts = append(accounts, op.Body.MustDestination().ToAccountId().Address())
+	case xdr.OperationTypeChangeTrust:
+		if line := op.Body.MustChangeTrustOp().Line; line.Type != xdr.AssetTypeAssetTypePoolShare {
+			addIssuer(line.ToAsset())
+		}
+	}
+	return accounts
+}
+
+// thresholdFor returns the signature threshold of an operation, see
+// https://developers.hcnet.org/docs/encyclopedia/signatures-multisig#thresholds
+func thresholdFor(source *simulatedAccount, op xdr.Operation) byte {
+	switch op.Body.Type {
+	case xdr.OperationTypeAllowTrust, xdr.OperationTypeSetTrustLineFlags,
+		xdr.OperationTypeBumpSequence, xdr.OperationTypeClaimClaimableBalance,
+		xdr.OperationTypeInflation:
+		return source.ThresholdLow
+	case xdr.OperationTypeAccountMerge:
+		return source.ThresholdHigh
+	case xdr.OperationTypeSetOptions:
+		setOptions := op.Body.MustSetOptionsOp()
+		if setOptions.MasterWeight != nil || setOptions.LowThreshold != nil ||
+			setOptions.MedThreshold != nil || setOptions.HighThreshold != nil ||
+			setOptions.Signer != nil {
+			return source.ThresholdHigh
+		}
+	}
+	return source.ThresholdMedium
+}
+
+// successCode returns the success result code of an operation type.
+func successCode(operationType xdr.OperationType) interface{} {
+	switch operationType {
+	case xdr.OperationTypeCreateAccount:
+		return xdr.CreateAccountResultCodeCreateAccountSuccess
+	case xdr.OperationTypePayment:
+		return xdr.PaymentResultCodePaymentSuccess
+	case xdr.OperationTypePathPaymentStrictReceive:
+		return xdr.PathPaymentStrictReceiveResultCodePathPaymentStrictReceiveSuccess
+	case xdr.OperationTypePathPaymentStrictSend:
+		return xdr.PathPaymentStrictSendResultCodePathPaymentStrictSendSuccess
+	case xdr.OperationTypeManageSellOffer, xdr.OperationTypeCreatePassiveSellOffer:
+		return xdr.ManageSellOfferResultCodeManageSellOfferSuccess
+	case xdr.OperationTypeManageBuyOffer:
+		return xdr.ManageBuyOfferResultCodeManageBuyOfferSuccess
+	case xdr.OperationTypeChangeTrust:
+		return xdr.ChangeTrustResultCodeChangeTrustSuccess
+	case xdr.OperationTypeAccountMerge:
+		return xdr.AccountMergeResultCodeAccountMergeSuccess
+	case xdr.OperationTypeManageData:
+		return xdr.ManageDataResultCodeManageDataSuccess
+	case xdr.OperationTypeBumpSequence:
+		return xdr.BumpSequenceResultCodeBumpSequenceSuccess
+	case xdr.OperationTypeCreateClaimableBalance:
+		return xdr.CreateClaimableBalanceResultCodeCreateClaimableBalanceSuccess
+	default:
+		// All the other operation result codes are rendered as op_success
+		// on success.
+		return xdr.SetOptionsResultCodeSetOptionsSuccess
+	}
+}
+
+func mustCodeString(code interface{}) string {
+	str, err := codes.String(code)
+	if err != nil {
+		panic(err)
+	}
+	return str
+}
+
+// signatureChecker verifies the signatures of a transaction against the
+// signers of accounts and keeps track of the signatures used.
+type signatureChecker struct {
+	hash       [32]byte
+	signatures []xdr.DecoratedSignature
+	used       []bool
+}
+
+func newSignatureChecker(hash [32]byte, signatures []xdr.DecoratedSignature) *signatureChecker {
+	return &signatureChecker{
+		hash:       hash,
+		signatures: signatures,
+		used:       make([]bool, len(signatures)),
+	}
+}
+
+// check returns true if the signers with a signature reach the threshold.
+func (c *signatureChecker) check(signers []history.AccountSigner, threshold byte) bool {
+	weight := int32(0)
+	for _, signer := range signers {
+		if signer.Weight > 0 && c.signed(signer.Signer) {
+			if signer.Weight > math.MaxUint8 {
+				weight += math.MaxUint8
+			} else {
+				weight += signer.Weight
+			}
+		}
+	}
+	return weight > 0 && weight >= int32(threshold)
+}
+
+// signed returns true if the transaction is signed by the signer key.
+func (c *signatureChecker) signed(signer string) bool {
+	var key xdr.SignerKey
+	if err := key.SetAddress(signer); err != nil {
+		return false
+	}
+
+	switch key.Type {
+	case xdr.SignerKeyTypeSignerKeyTypePreAuthTx:
+		preAuthTx := key.MustPreAuthTx()
+		return bytes.Equal(preAuthTx[:], c.hash[:])
+	case xdr.SignerKeyTypeSignerKeyTypeHashX:
+		hashX := key.MustHashX()
+		return c.verify(hashX[28:], func(signature []byte) bool {
+			hash := sha256.Sum256(signature)
+			return bytes.Equal(hash[:], hashX[:])
+		})
+	case xdr.SignerKeyTypeSignerKeyTypeEd25519:
+		kp, err := keypair.ParseAddress(signer)
+		if err != nil {
+			return false
+		}
+		hint := kp.Hint()
+		return c.verify(hint[:], func(signature []byte) bool {
+			return kp.Verify(c.hash[:], signature) == nil
+		})
+	case xdr.SignerKeyTypeSignerKeyTypeEd25519SignedPayload:
+		payload := key.MustEd25519SignedPayload()
+		address, err := strkey.Encode(strkey.VersionByteAccountID, payload.Ed25519[:])
+		if err != nil {
+			return false
+		}
+		kp, err := keypair.ParseAddress(address)
+		if err != nil {
+			return false
+		}
+		hint := xdr.NewDecoratedSignatureForPayload(nil, kp.Hint(), payload.Payload).Hint
+		return c.verify(hint[:], func(signature []byte) bool {
+			return kp.Verify(payload.Payload, signature) == nil
+		})
+	}
+	return false
+}
+
+func (c *signatureChecker) verify(hint []byte, verify func(signature []byte) bool) bool {
+	found := false
+	for i, signature := range c.signatures {
+		if bytes.Equal(signature.Hint[:], hint) && verify(signature.Signature) {
+			c.used[i] = true
+			found = true
+		}
+	}
+	return found
+}
+
+// allUsed returns true if all the signatures were used by a signer.
+func (c *signatureChecker) allUsed() bool {
+	for _, used := range c.used {
+		if !used {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,440 @@
+package txsub
+
+import (
+	"context"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hcnet/go/keypair"
+	"github.com/hcnet/go/network"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/strkey"
+	"github.com/hcnet/go/txnbuild"
+	"github.com/hcnet/go/xdr"
+)
+
+type mockSimulationState struct {
+	accounts   []history.AccountEntry
+	signers    []history.AccountSigner
+	trustLines []history.TrustLine
+	data       []history.Data
+}
+
+func (m *mockSimulationState) GetAccountsByIDs(ctx context.Context, ids []string) ([]history.AccountEntry, error) {
+	var accounts []history.AccountEntry
+	for _, account := range m.accounts {
+		if containsString(ids, account.AccountID) {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, nil
+}
+
+func (m *mockSimulationState) SignersForAccounts(ctx context.Context, ids []string) ([]history.AccountSigner, error) {
+	var signers []history.AccountSigner
+	for _, signer := range m.signers {
+		if containsString(ids, signer.Account) {
+			signers = append(signers, signer)
+		}
+	}
+	return signers, nil
+}
+
+func (m *mockSimulationState) GetSortedTrustLinesByAccountIDs(ctx context.Context, ids []string) ([]history.TrustLine, error) {
+	var trustLines []history.TrustLine
+	for _, trustLine := range m.trustLines {
+		if containsString(ids, trustLine.AccountID) {
+			trustLines = append(trustLines, trustLine)
+		}
+	}
+	return trustLines, nil
+}
+
+func (m *mockSimulationState) GetAccountDataByKeys(ctx context.Context, keys []history.AccountDataKey) ([]history.Data, error) {
+	var data []history.Data
+	for _, entry := range m.data {
+		for _, key := range keys {
+			if key.AccountID == entry.AccountID && key.DataName == entry.Name {
+				data = append(data, entry)
+			}
+		}
+	}
+	return data, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type simulationTest struct {
+	t      *testing.T
+	state  *mockSimulationState
+	ledger history.Ledger
+	source *keypair.Full
+	other  *keypair.Full
+	issuer *keypair.Full
+	usd    txnbuild.CreditAsset
+}
+
+func newSimulationTest(t *testing.T) *simulationTest {
+	st := &simulationTest{
+		t:     t,
+		state: &mockSimulationState{},
+		ledger: history.Ledger{
+			Sequence:    100,
+			ClosedAt:    time.Unix(1600000000, 0).UTC(),
+			BaseFee:     100,
+			BaseReserve: 5000000,
+		},
+		source: keypair.MustRandom(),
+		other:  keypair.MustRandom(),
+		issuer: keypair.MustRandom(),
+	}
+	st.usd = txnbuild.CreditAsset{Code: "USD", Issuer: st.issuer.Address()}
+	st.addAccount(st.source, 1000000000)
+	st.addAccount(st.other, 100000000)
+	st.addAccount(st.issuer, 100000000)
+	return st
+}
+
+func (st *simulationTest) addAccount(kp *keypair.Full, balance int64) *history.AccountEntry {
+	st.state.accounts = append(st.state.accounts, history.AccountEntry{
+		AccountID:      kp.Address(),
+		Balance:        balance,
+		SequenceNumber: 10,
+		MasterWeight:   1,
+	})
+	st.state.signers = append(st.state.signers, history.AccountSigner{
+		Account: kp.Address(),
+		Signer:  kp.Address(),
+		Weight:  1,
+	})
+	return &st.state.accounts[len(st.state.accounts)-1]
+}
+
+func (st *simulationTest) addTrustLine(kp *keypair.Full, balance, limit int64, flags xdr.TrustLineFlags) {
+	st.state.trustLines = append(st.state.trustLines, history.TrustLine{
+		AccountID:   kp.Address(),
+		AssetType:   xdr.AssetTypeAssetTypeCreditAlphanum4,
+		AssetCode:   st.usd.Code,
+		AssetIssuer: st.usd.Issuer,
+		Balance:     balance,
+		Limit:       limit,
+		Flags:       uint32(flags),
+	})
+	for i := range st.state.accounts {
+		if st.state.accounts[i].AccountID == kp.Address() {
+			st.state.accounts[i].NumSubEntries++
+		}
+	}
+}
+
+func (st *simulationTest) transaction(sequence int64, preconditions txnbuild.Preconditions, ops ...txnbuild.Operation) *txnbuild.Transaction {
+	if preconditions.TimeBounds == (txnbuild.TimeBounds{}) {
+		preconditions.TimeBounds = txnbuild.NewInfiniteTimeout()
+	}
+	tx, err := txnbuild.NewTransaction(txnbuild.TransactionParams{
+		SourceAccount:        &txnbuild.SimpleAccount{AccountID: st.source.Address(), Sequence: sequence},
+		IncrementSequenceNum: true,
+		Operations:           ops,
+		BaseFee:              txnbuild.MinBaseFee,
+		Preconditions:        preconditions,
+	})
+	require.NoError(st.t, err)
+	return tx
+}
+
+func (st *simulationTest) sign(tx *txnbuild.Transaction, kps ...*keypair.Full) xdr.TransactionEnvelope {
+	tx, err := tx.Sign(network.TestNetworkPassphrase, kps...)
+	require.NoError(st.t, err)
+	return tx.ToXDR()
+}
+
+func (st *simulationTest) simulate(envelope xdr.TransactionEnvelope) SimulationResult {
+	result, err := SimulateTransaction(context.Background(), st.state, st.ledger, network.TestNetworkPassphrase, envelope)
+	require.NoError(st.t, err)
+	return result
+}
+
+func TestSimulateTransactionValidation(t *testing.T) {
+	st := newSimulationTest(t)
+	payment := &txnbuild.Payment{Destination: st.other.Address(), Amount: "1", Asset: txnbuild.NativeAsset{}}
+
+	result := st.simulate(st.sign(st.transaction(10, txnbuild.Preconditions{}, payment), st.source))
+	assert.True(t, result.Successful())
+	assert.Equal(t, "tx_success", result.Code)
+	assert.Equal(t, []string{"op_success"}, result.OperationCodes)
+	assert.Equal(t, int64(100), result.FeeCharged)
+
+	for _, testCase := range []struct {
+		name     string
+		envelope xdr.TransactionEnvelope
+		expected string
+	}{
+		{
+			name:     "bad sequence",
+			envelope: st.sign(st.transaction(11, txnbuild.Preconditions{}, payment), st.source),
+			expected: "tx_bad_seq",
+		},
+		{
+			name:     "missing signature",
+			envelope: st.sign(st.transaction(10, txnbuild.Preconditions{}, payment)),
+			expected: "tx_bad_auth",
+		},
+		{
+			name:     "wrong signature",
+			envelope: st.sign(st.transaction(10, txnbuild.Preconditions{}, payment), st.other),
+			expected: "tx_bad_auth",
+		},
+		{
+			name:     "extra signature",
+			envelope: st.sign(st.transaction(10, txnbuild.Preconditions{}, payment), st.source, st.other),
+			expected: "tx_bad_auth_extra",
+		},
+		{
+			name: "too late",
+			envelope: st.sign(st.transaction(10, txnbuild.Preconditions{
+				TimeBounds: txnbuild.NewTimebounds(0, st.ledger.ClosedAt.Unix()-1),
+			}, payment), st.source),
+			expected: "tx_too_late",
+		},
+		{
+			name: "ledger bounds too early",
+			envelope: st.sign(st.transaction(10, txnbuild.Preconditions{
+				LedgerBounds: &txnbuild.LedgerBounds{MinLedger: 200},
+			}, payment), st.source),
+			expected: "tx_too_early",
+		},
+		{
+			name: "min sequence age",
+			envelope: st.sign(st.transaction(10, txnbuild.Preconditions{
+				MinSequenceNumberAge: 1,
+			}, payment), st.source),
+			expected: "tx_success",
+		},
+		{
+			name: "min sequence ledger gap",
+			envelope: st.sign(st.transaction(10, txnbuild.Preconditions{
+				MinSequenceNumberLedgerGap: 200,
+			}, payment), st.source),
+			expected: "tx_bad_minseq_age_or_gap",
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, st.simulate(testCase.envelope).Code)
+		})
+	}
+
+	// the source account cannot pay the fee without going below the reserve
+	st.state.accounts[0].Balance = 2*int64(st.ledger.BaseReserve) + 50
+	result = st.simulate(st.sign(st.transaction(10, txnbuild.Preconditions{}, payment), st.source))
+	assert.Equal(t, "tx_insufficient_balance", result.Code)
+}
+
+func TestSimulateTransactionSignatures(t *testing.T) {
+	st := newSimulationTest(t)
+	st.state.accounts[0].ThresholdLow = 1
+	st.state.accounts[0].ThresholdMedium = 2
+	st.state.accounts[0].ThresholdHigh = 3
+	st.state.signers = append(st.state.signers, history.AccountSigner{
+		Account: st.source.Address(),
+		Signer:  st.other.Address(),
+		Weight:  1,
+	})
+	preimage := []byte("preimage")
+	hashX := sha256.Sum256(preimage)
+	hashXSigner, err := strkey.Encode(strkey.VersionByteHashX, hashX[:])
+	require.NoError(t, err)
+	st.state.signers = append(st.state.signers, history.AccountSigner{
+		Account: st.source.Address(),
+		Signer:  hashXSigner,
+		Weight:  1,
+	})
+
+	payment := &txnbuild.Payment{Destination: st.other.Address(), Amount: "1", Asset: txnbuild.NativeAsset{}}
+	bump := &txnbuild.BumpSequence{BumpTo: 100}
+	setOptions := &txnbuild.SetOptions{MasterWeight: txnbuild.NewThreshold(2)}
+
+	// the medium threshold is not reached by a single signer
+	result := st.simulate(st.sign(st.transaction(10, txnbuild.Preconditions{}, bump, payment), st.source))
+	assert.Equal(t, "tx_failed", result.Code)
+	assert.Equal(t, []string{"op_success", "op_bad_auth"}, result.OperationCodes)
+
+	result = st.simulate(st.sign(st.transaction(10, txnbuild.Preconditions{}, bump, payment), st.source, st.other))
+	assert.Equal(t, "tx_success", result.Code)
+
+	result = st.simulate(st.sign(st.transaction(10, txnbuild.Preconditions{}, setOptions), st.source, st.other))
+	assert.Equal(t, "tx_failed", result.Code)
+	assert.Equal(t, []string{"op_bad_auth"}, result.OperationCodes)
+
+	tx, err := st.transaction(10, txnbuild.Preconditions{}, setOptions).Sign(network.TestNetworkPassphrase, st.source, st.other)
+	require.NoError(t, err)
+	tx, err = tx.SignHashX(preimage)
+	require.NoError(t, err)
+	result = st.simulate(tx.ToXDR())
+	assert.Equal(t, "tx_success", result.Code)
+
+	// signatures are checked before operations are applied
+	tx, err = st.transaction(10, txnbuild.Preconditions{}, payment).SignHashX([]byte("other"))
+	require.NoError(t, err)
+	assert.Equal(t, "tx_bad_auth", st.simulate(tx.ToXDR()).Code)
+}
+
+func TestSimulateTransactionOperations(t *testing.T) {
+	st := newSimulationTest(t)
+	st.addTrustLine(st.source, 500, 1000, xdr.TrustLineFlagsAuthorizedFlag)
+	st.addTrustLine(st.other, 900, 1000, 0)
+	newAccount := keypair.MustRandom()
+
+	for _, testCase := range []struct {
+		name     string
+		ops      []txnbuild.Operation
+		expected []string
+	}{
+		{
+			name: "create account and pay it",
+			ops: []txnbuild.Operation{
+				&txnbuild.CreateAccount{Destination: newAccount.Address(), Amount: "1"},
+				&txnbuild.Payment{Destination: newAccount.Address(), Amount: "10", Asset: txnbuild.NativeAsset{}},
+			},
+			expected: []string{"op_success", "op_success"},
+		},
+		{
+			name: "create account below reserve",
+			ops: []txnbuild.Operation{
+				&txnbuild.CreateAccount{Destination: newAccount.Address(), Amount: "0.5"},
+			},
+			expected: []string{"op_low_reserve"},
+		},
+		{
+			name: "create existing account",
+			ops: []txnbuild.Operation{
+				&txnbuild.CreateAccount{Destination: st.other.Address(), Amount: "1"},
+			},
+			expected: []string{"op_already_exists"},
+		},
+		{
+			name: "payment to missing account",
+			ops: []txnbuild.Operation{
+				&txnbuild.Payment{Destination: newAccount.Address(), Amount: "1", Asset: txnbuild.NativeAsset{}},
+			},
+			expected: []string{"op_no_destination"},
+		},
+		{
+			name: "payment above available balance",
+			ops: []txnbuild.Operation{
+				&txnbuild.Payment{Destination: st.other.Address(), Amount: "99.5", Asset: txnbuild.NativeAsset{}},
+			},
+			expected: []string{"op_underfunded"},
+		},
+		{
+			name: "payment to unauthorized trust line",
+			ops: []txnbuild.Operation{
+				&txnbuild.Payment{Destination: st.other.Address(), Amount: "0.00001", Asset: st.usd},
+			},
+			expected: []string{"op_not_authorized"},
+		},
+		{
+			name: "payment without trust line",
+			ops: []txnbuild.Operation{
+				&txnbuild.Payment{Destination: st.issuer.Address(), Amount: "1", Asset: txnbuild.CreditAsset{Code: "EUR", Issuer: st.other.Address()}},
+			},
+			expected: []string{"op_src_no_trust"},
+		},
+		{
+			name: "payment to issuer",
+			ops: []txnbuild.Operation{
+				&txnbuild.Payment{Destination: st.issuer.Address(), Amount: "0.00005", Asset: st.usd},
+				&txnbuild.Payment{Destination: st.issuer.Address(), Amount: "0.00001", Asset: st.usd},
+			},
+			expected: []string{"op_success", "op_underfunded"},
+		},
+		{
+			name: "change trust",
+			ops: []txnbuild.Operation{
+				&txnbuild.ChangeTrust{Line: st.usd.MustToChangeTrustAsset(), Limit: "0.00001"},
+				&txnbuild.ChangeTrust{Line: txnbuild.CreditAsset{Code: "EUR", Issuer: newAccount.Address()}.MustToChangeTrustAsset()},
+				&txnbuild.ChangeTrust{Line: txnbuild.CreditAsset{Code: "EUR", Issuer: st.other.Address()}.MustToChangeTrustAsset()},
+			},
+			expected: []string{"op_invalid_limit", "op_no_issuer", "op_success"},
+		},
+		{
+			name: "manage data",
+			ops: []txnbuild.Operation{
+				&txnbuild.ManageData{Name: "missing"},
+				&txnbuild.ManageData{Name: "key", Value: []byte("value")},
+				&txnbuild.ManageData{Name: "key"},
+			},
+			expected: []string{"op_data_name_not_found", "op_success", "op_success"},
+		},
+		{
+			name: "account merge with sub entries",
+			ops: []txnbuild.Operation{
+				&txnbuild.AccountMerge{Destination: st.other.Address()},
+			},
+			expected: []string{"op_has_sub_entries"},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := st.simulate(st.sign(st.transaction(10, txnbuild.Preconditions{}, testCase.ops...), st.source))
+			assert.Equal(t, testCase.expected, result.OperationCodes)
+			if result.Successful() {
+				assert.Equal(t, "tx_success", result.Code)
+			} else {
+				assert.Equal(t, "tx_failed", result.Code)
+			}
+		})
+	}
+
+	// the simulation does not modify the state
+	result := st.simulate(st.sign(st.transaction(10, txnbuild.Preconditions{},
+		&txnbuild.ChangeTrust{Line: st.usd.MustToChangeTrustAsset(), Limit: "0"},
+	), st.source))
+	assert.Equal(t, []string{"op_invalid_limit"}, result.OperationCodes)
+	assert.Equal(t, int64(500), st.state.trustLines[0].Balance)
+}
+
+func TestSimulateFeeBumpTransaction(t *testing.T) {
+	st := newSimulationTest(t)
+	payment := &txnbuild.Payment{Destination: st.other.Address(), Amount: "1", Asset: txnbuild.NativeAsset{}}
+	inner, err := st.transaction(10, txnbuild.Preconditions{}, payment).Sign(network.TestNetworkPassphrase, st.source)
+	require.NoError(t, err)
+
+	feeBump := func(baseFee int64, kps ...*keypair.Full) xdr.TransactionEnvelope {
+		tx, err := txnbuild.NewFeeBumpTransaction(txnbuild.FeeBumpTransactionParams{
+			Inner:      inner,
+			FeeAccount: st.issuer.Address(),
+			BaseFee:    baseFee,
+		})
+		require.NoError(t, err)
+		tx, err = tx.Sign(network.TestNetworkPassphrase, kps...)
+		require.NoError(t, err)
+		return tx.ToXDR()
+	}
+
+	result := st.simulate(feeBump(200, st.issuer))
+	assert.Equal(t, "tx_fee_bump_inner_success", result.Code)
+	assert.Equal(t, "tx_success", result.InnerCode)
+	assert.Equal(t, []string{"op_success"}, result.OperationCodes)
+	assert.Equal(t, int64(200), result.FeeCharged)
+	assert.True(t, result.Successful())
+
+	result = st.simulate(feeBump(200, st.source))
+	assert.Equal(t, "tx_bad_auth", result.Code)
+	assert.Empty(t, result.InnerCode)
+
+	st.state.accounts[0].SequenceNumber = 11
+	result = st.simulate(feeBump(200, st.issuer))
+	assert.Equal(t, "tx_fee_bump_inner_failed", result.Code)
+	assert.Equal(t, "tx_bad_seq", result.InnerCode)
+	assert.False(t, result.Successful())
+}
@@ -0,0 +1,152 @@
+package txsub
+
+import (
+	"context"
+	"time"
+
+	proto "github.com/hcnet/go/protocols/hcnetcore"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/support/log"
+	"github.com/hcnet/go/xdr"
+)
+
+// TransactionStatus is the state of a submitted transaction.
+type TransactionStatus string
+
+const (
+	// TransactionStatusPending means the transaction was accepted by
+	// hcnet-core and is waiting to be included in a ledger.
+	TransactionStatusPending TransactionStatus = "pending"
+	// TransactionStatusIngested means the transaction was included in a
+	// ledger and succeeded.
+	TransactionStatusIngested TransactionStatus = "ingested"
+	// TransactionStatusFailed means the transaction was included in a ledger
+	// and failed.
+	TransactionStatusFailed TransactionStatus = "failed"
+	// TransactionStatusExpired means the transaction was submitted
+	// asynchronously but was not included in a ledger before the submission
+	// timeout.
+	TransactionStatusExpired TransactionStatus = "expired"
+)
+
+// Asynchronous submissions are remembered in memory by the Aurora instance
+// which received them, they are lost on restart and other instances report
+// the status of the transactions which were never included in a ledger as
+// unknown instead of expired.
+const (
+	// asyncSubmissionRetention is how long asynchronous submissions are
+	// remembered, after this period the status of transactions which were
+	// never included in a ledger is unknown.
+	asyncSubmissionRetention = time.Hour
+	// maxAsyncSubmissions is the maximum number of asynchronous submissions
+	// remembered, the oldest ones are forgotten first.
+	maxAsyncSubmissions = 100_000
+)
+
+// asyncSubmission is an asynchronous submission, in the order of
+// submission.
+type asyncSubmission struct {
+	hash        string
+	submittedAt time.Time
+}
+
+// SubmitAsync submits the provided base64 encoded transaction envelope to
+// hcnet-core and returns as soon as hcnet-core responded. Unlike Submit, it
+// does not wait for the transaction to be included in a ledger and does not
+// queue transactions by sequence number. Transactions accepted by
+// hcnet-core are added to the open submission list so that their status can
+// be polled with Status.
+func (sys *System) SubmitAsync(
+	ctx context.Context,
+	rawTx string,
+	envelope xdr.TransactionEnvelope,
+	hash string,
+) SubmissionResult {
+	sys.Init()
+
+	sys.Log.Ctx(ctx).WithFields(log.F{
+		"hash":    hash,
+		"tx_type": envelope.Type.String(),
+		"tx":      rawTx,
+	}).Info("Processing asynchronous transaction")
+
+	sr := sys.submitOnce(ctx, rawTx)
+	sys.updateTransactionTypeMetrics(envelope)
+
+	if sr.Err == nil && sr.Status != proto.TXStatusTryAgainLater {
+		// The listener is never read, it only keeps the submission open
+		// until its result is ingested or the submission times out.
+		if err := sys.Pending.Add(ctx, hash, make(chan Result, 1)); err != nil {
+			sys.Log.Ctx(ctx).WithError(err).WithField("hash", hash).Error("could not add open submission")
+		}
+
+		sys.addAsyncSubmission(hash, time.Now())
+	}
+	return sr
+}
+
+// Status returns the status of a transaction and, once it was included in a
+// ledger, its history row. ErrNoResults is returned for transactions which are
+// neither in the history database nor known to this submission system.
+func (sys *System) Status(ctx context.Context, hash string) (TransactionStatus, history.Transaction, error) {
+	sys.Init()
+
+	tx, err := txResultByHash(ctx, sys.DB(ctx), hash)
+	switch err.(type) {
+	case nil:
+		return TransactionStatusIngested, tx, nil
+	case *FailedTransactionError:
+		return TransactionStatusFailed, tx, nil
+	}
+	if err != ErrNoResults {
+		return "", tx, err
+	}
+
+	for _, pending := range sys.Pending.Pending(ctx) {
+		if pending == hash {
+			return TransactionStatusPending, tx, nil
+		}
+	}
+
+	sys.asyncMutex.Lock()
+	_, submitted := sys.asyncSubmissions[hash]
+	sys.asyncMutex.Unlock()
+	if submitted {
+		return TransactionStatusExpired, tx, nil
+	}
+	return "", tx, ErrNoResults
+}
+
+// addAsyncSubmission remembers an asynchronous submission, forgetting the
+// oldest ones beyond maxAsyncSubmissions.
+func (sys *System) addAsyncSubmission(hash string, now time.Time) {
+	sys.asyncMutex.Lock()
+	defer sys.asyncMutex.Unlock()
+
+	sys.asyncSubmissions[hash] = now
+	sys.asyncOrder = append(sys.asyncOrder, asyncSubmission{hash: hash, submittedAt: now})
+	for len(sys.asyncOrder) > maxAsyncSubmissions {
+		sys.forgetOldestAsyncSubmission()
+	}
+}
+
+// pruneAsyncSubmissions forgets the asynchronous submissions older than
+// asyncSubmissionRetention.
+func (sys *System) pruneAsyncSubmissions(now time.Time) {
+	sys.asyncMutex.Lock()
+	defer sys.asyncMutex.Unlock()
+
+	for len(sys.asyncOrder) > 0 && now.Sub(sys.asyncOrder[0].submittedAt) > asyncSubmissionRetention {
+		sys.forgetOldestAsyncSubmission()
+	}
+}
+
+// forgetOldestAsyncSubmission must be called with asyncMutex held. A
+// transaction submitted again is only forgotten with its last submission.
+func (sys *System) forgetOldestAsyncSubmission() {
+	oldest := sys.asyncOrder[0]
+	sys.asyncOrder = sys.asyncOrder[1:]
+	if submittedAt, ok := sys.asyncSubmissions[oldest.hash]; ok && submittedAt.Equal(oldest.submittedAt) {
+		delete(sys.asyncSubmissions, oldest.hash)
+	}
+}
@@ -0,0 +1,98 @@
+package txsub
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+)
+
+func (suite *SystemTestSuite) mockTransactionNotFound(hash string) {
+	suite.db.On("PreFilteredTransactionByHash", suite.ctx, mock.Anything, hash).
+		Return(sql.ErrNoRows).Once()
+	suite.db.On("TransactionByHash", suite.ctx, mock.Anything, hash).
+		Return(sql.ErrNoRows).Once()
+	suite.db.On("NoRows", sql.ErrNoRows).Return(true).Twice()
+}
+
+func (suite *SystemTestSuite) TestSubmitAsync_Status() {
+	hash := suite.successTx.Transaction.TransactionHash
+	suite.submitter.R = SubmissionResult{Status: "PENDING"}
+
+	sr := suite.system.SubmitAsync(suite.ctx, suite.successTx.Transaction.TxEnvelope, suite.successXDR, hash)
+	suite.Assert().NoError(sr.Err)
+	suite.Assert().Equal("PENDING", sr.Status)
+	suite.Assert().True(suite.submitter.WasSubmittedTo)
+	suite.Assert().Equal([]string{hash}, suite.system.Pending.Pending(suite.ctx))
+
+	suite.mockTransactionNotFound(hash)
+	status, _, err := suite.system.Status(suite.ctx, hash)
+	suite.Assert().NoError(err)
+	suite.Assert().Equal(TransactionStatusPending, status)
+
+	// the submission timed out without being ingested
+	_, err = suite.system.Pending.Clean(suite.ctx, 0)
+	suite.Assert().NoError(err)
+	suite.mockTransactionNotFound(hash)
+	status, _, err = suite.system.Status(suite.ctx, hash)
+	suite.Assert().NoError(err)
+	suite.Assert().Equal(TransactionStatusExpired, status)
+
+	suite.db.On("PreFilteredTransactionByHash", suite.ctx, mock.Anything, hash).
+		Run(func(args mock.Arguments) {
+			ptr := args.Get(1).(*history.Transaction)
+			*ptr = suite.successTx.Transaction
+		}).
+		Return(nil).Once()
+	status, tx, err := suite.system.Status(suite.ctx, hash)
+	suite.Assert().NoError(err)
+	suite.Assert().Equal(TransactionStatusIngested, status)
+	suite.Assert().Equal(suite.successTx.Transaction, tx)
+
+	// asynchronous submissions are forgotten after the retention period
+	suite.system.pruneAsyncSubmissions(time.Now().Add(asyncSubmissionRetention + time.Second))
+	suite.mockTransactionNotFound(hash)
+	_, _, err = suite.system.Status(suite.ctx, hash)
+	suite.Assert().Equal(ErrNoResults, err)
+}
+
+func (suite *SystemTestSuite) TestAsyncSubmissionsBounded() {
+	suite.system.Init()
+	now := time.Now()
+	for i := 0; i < maxAsyncSubmissions; i++ {
+		suite.system.addAsyncSubmission(strconv.Itoa(i), now)
+	}
+	// submitting a transaction again keeps it until its last submission
+	suite.system.addAsyncSubmission("0", now.Add(time.Minute))
+	suite.Assert().Len(suite.system.asyncSubmissions, maxAsyncSubmissions)
+	suite.Assert().Contains(suite.system.asyncSubmissions, "0")
+
+	suite.system.addAsyncSubmission("new", now.Add(time.Minute))
+	suite.Assert().Len(suite.system.asyncSubmissions, maxAsyncSubmissions)
+	suite.Assert().NotContains(suite.system.asyncSubmissions, "1")
+	suite.Assert().Contains(suite.system.asyncSubmissions, "new")
+
+	suite.system.pruneAsyncSubmissions(now.Add(asyncSubmissionRetention + time.Second))
+	suite.Assert().Len(suite.system.asyncSubmissions, 2)
+	suite.Assert().Len(suite.system.asyncOrder, 2)
+}
+
+func (suite *SystemTestSuite) TestSubmitAsync_NotAccepted() {
+	hash := suite.successTx.Transaction.TransactionHash
+	for _, sr := range []SubmissionResult{
+		{Status: "TRY_AGAIN_LATER"},
+		{Status: "ERROR", Err: ErrBadSequence},
+	} {
+		suite.submitter.R = sr
+		result := suite.system.SubmitAsync(suite.ctx, suite.successTx.Transaction.TxEnvelope, suite.successXDR, hash)
+		suite.Assert().Equal(sr, result)
+		suite.Assert().Empty(suite.system.Pending.Pending(suite.ctx))
+	}
+
+	suite.mockTransactionNotFound(hash)
+	_, _, err := suite.system.Status(suite.ctx, hash)
+	suite.Assert().Equal(ErrNoResults, err)
+}
@@ -36,6 +36,10 @@ type System struct {
 
 	accountSeqPollInterval time.Duration
 
+	asyncMutex       sync.Mutex
+	asyncSubmissions map[string]time.Time // hash => submission time
+	asyncOrder       []asyncSubmission    // in the order of submission
+
 	DB                func(context.Context) AuroraDB
 	Pending           OpenSubmissionList
 	Submitter         Submitter
@@ -311,6 +315,8 @@ func (sys *System) Tick(ctx context.Context) {
 
 	defer sys.unsetTickInProgress()
 
+	sys.pruneAsyncSubmissions(time.Now())
+
 	logger.
 		WithField("queued", sys.SubmissionQueue.String()).
 		Debug("ticking txsub system")
@@ -434,6 +440,7 @@ func (sys *System) Init() {
 		})
 
 		sys.accountSeqPollInterval = time.Second
+		sys.asyncSubmissions = map[string]time.Time{}
 
 		if sys.SubmissionTimeout == 0 {
 			// HTTP clients in SDKs usually timeout in 60 seconds. We want SubmissionTimeout
@@ -0,0 +1,90 @@
+package webhooks
+
+import (
+	"strings"
+
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/xdr"
+)
+
+// operationFilter matches operations against the operation type and asset
+// filters of a subscription. The account filter is applied when querying the
+// operations.
+type operationFilter struct {
+	operationTypes map[xdr.OperationType]bool
+	asset          string
+}
+
+func newOperationFilter(subscription history.WebhookSubscription) operationFilter {
+	filter := operationFilter{}
+	if len(subscription.OperationTypes) > 0 {
+		filter.operationTypes = map[xdr.OperationType]bool{}
+		for _, operationType := range subscription.OperationTypes {
+			filter.operationTypes[xdr.OperationType(operationType)] = true
+		}
+	}
+	if subscription.Asset.Valid {
+		filter.asset = subscription.Asset.String
+	}
+	return filter
+}
+
+func (f operationFilter) match(operation history.Operation) (bool, error) {
+	if f.operationTypes != nil && !f.operationTypes[operation.Type] {
+		return false, nil
+	}
+	if f.asset == "" {
+		return true, nil
+	}
+
+	var details map[string]interface{}
+	if err := operation.UnmarshalDetails(&details); err != nil {
+		return false, err
+	}
+	return detailsContainAsset(details, f.asset), nil
+}
+
+// detailsContainAsset reports whether the details of an operation reference
+// the given asset, in canonical form. Assets appear in the details either as
+// `<prefix>asset_type`, `<prefix>asset_code` and `<prefix>asset_issuer` fields
+// (e.g. `selling_asset_type`) or as canonical `asset` fields, possibly nested
+// in arrays like the `path` of path payments or the `reserves_max` of
+// liquidity pool deposits.
+func detailsContainAsset(details interface{}, asset string) bool {
+	switch value := details.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if key == "asset" {
+				if canonical, ok := field.(string); ok && canonical == asset {
+					return true
+				}
+			}
+			if strings.HasSuffix(key, "asset_type") {
+				prefix := strings.TrimSuffix(key, "asset_type")
+				if canonicalAsset(value, prefix) == asset {
+					return true
+				}
+			}
+			if detailsContainAsset(field, asset) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range value {
+			if detailsContainAsset(item, asset) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func canonicalAsset(details map[string]interface{}, prefix string) string {
+	assetType, _ := details[prefix+"asset_type"].(string)
+	if assetType == "native" {
+		return "native"
+	}
+	code, _ := details[prefix+"asset_code"].(string)
+	issuer, _ := details[prefix+"asset_issuer"].(string)
+	return code + ":" + issuer
+}
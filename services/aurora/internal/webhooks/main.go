@@ -0,0 +1,136 @@
+// Package webhooks contains the webhook delivery subsystem of aurora. After
+// each ingested ledger, the operations (and their effects) matching the filters
+// of each webhook subscription are sent to the subscription URL in signed HTTP
+// POST requests. Failed deliveries are retried with an exponential backoff and
+// the delivery cursor of each subscription is persisted in the database.
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/services/aurora/internal/ledger"
+	"github.com/hcnet/go/support/db"
+)
+
+const (
+	// DefaultBatchSize is the default maximum number of operations scanned
+	// for a single delivery.
+	DefaultBatchSize = 200
+	// maxBatchesPerRun is the maximum number of deliveries sent to a
+	// subscription after a ledger, a subscription catches up on following
+	// ledgers if it is further behind.
+	maxBatchesPerRun = 50
+	// requestTimeout is the timeout of delivery requests.
+	requestTimeout = 10 * time.Second
+	// maxConcurrentDeliveries is the maximum number of subscriptions
+	// delivered concurrently.
+	maxConcurrentDeliveries = 10
+	// claimDuration is the maximum duration of the delivery of a
+	// subscription, other Aurora instances don't deliver it in the meantime
+	// even if the instance delivering it is stopped.
+	claimDuration = 5 * time.Minute
+	// minRetryDelay and maxRetryDelay bound the delay after which failed
+	// deliveries are retried, it doubles after every consecutive failure.
+	minRetryDelay = 10 * time.Second
+	maxRetryDelay = time.Hour
+)
+
+// System represents the webhook delivery subsystem of aurora.
+type System struct {
+	session db.SessionInterface
+	// BatchSize is the maximum number of operations scanned for a single
+	// delivery. DefaultBatchSize is used when 0.
+	BatchSize int
+
+	client       *http.Client
+	ledgerSource ledger.Source
+	now          func() time.Time
+	ctx          context.Context
+	cancel       context.CancelFunc
+	metrics      Metrics
+}
+
+// Metrics contains the webhook delivery metrics.
+type Metrics struct {
+	// DeliveriesCounter exposes the number of delivery attempts by result
+	// (success or failure).
+	DeliveriesCounter *prometheus.CounterVec
+
+	// DeliveredOperationsCounter exposes the number of operations delivered.
+	DeliveredOperationsCounter prometheus.Counter
+}
+
+// New initializes the webhook delivery system, deliveries are attempted every
+// time the notifier reports a new ledger.
+func New(dbSession db.SessionInterface, notifier *ledger.Notifier) *System {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &System{
+		session:      dbSession.Clone(),
+		client:       &http.Client{Timeout: requestTimeout},
+		ledgerSource: notifier.Get(),
+		now:          time.Now,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+	s.initMetrics()
+
+	return s
+}
+
+func (s *System) initMetrics() {
+	s.metrics.DeliveriesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "aurora", Subsystem: "webhooks", Name: "deliveries_total",
+			Help: "number of webhook delivery attempts by result",
+		},
+		[]string{"result"},
+	)
+
+	s.metrics.DeliveredOperationsCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "aurora", Subsystem: "webhooks", Name: "delivered_operations_total",
+			Help: "number of operations delivered to webhook subscriptions",
+		},
+	)
+}
+
+// Metrics returns the webhook delivery metrics.
+func (s *System) Metrics() Metrics {
+	return s.metrics
+}
+
+// RegisterMetrics registers the prometheus metrics
+func (s *System) RegisterMetrics(registry *prometheus.Registry) {
+	registry.MustRegister(s.metrics.DeliveriesCounter)
+	registry.MustRegister(s.metrics.DeliveredOperationsCounter)
+}
+
+func (s *System) historyQ() *history.Q {
+	return &history.Q{SessionInterface: s.session.Clone()}
+}
+
+func (s *System) batchSize() int {
+	if s.BatchSize > 0 {
+		return s.BatchSize
+	}
+	return DefaultBatchSize
+}
+
+// retryDelay returns the delay after which a delivery is retried after the
+// given number of consecutive failures.
+func retryDelay(failures int) time.Duration {
+	delay := minRetryDelay
+	for i := 1; i < failures && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,70 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hcnet/go/support/errors"
+)
+
+const (
+	// SignatureHeader is the header containing the signature of deliveries,
+	// in the form `t=<unix timestamp>,v1=<hex encoded signature>`.
+	SignatureHeader = "X-Aurora-Signature"
+	// SubscriptionIDHeader is the header containing the id of the
+	// subscription a delivery is sent to.
+	SubscriptionIDHeader = "X-Aurora-Subscription-Id"
+)
+
+// Sign returns the value of the signature header of a delivery body sent at
+// the given time. The signature is the HMAC-SHA256, keyed with the secret of
+// the subscription, of the timestamp and the body joined by a dot.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + ",v1=" + hex.EncodeToString(signature(secret, t, body))
+}
+
+// VerifySignature checks the signature header of a delivery body. Receivers
+// should also reject deliveries with old timestamps to prevent replays.
+func VerifySignature(secret, header string, body []byte) (time.Time, error) {
+	var t string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			t = kv[1]
+		case "v1":
+			if sig, err := hex.DecodeString(kv[1]); err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return time.Time{}, errors.New("signature header has no valid timestamp")
+	}
+	expected := signature(secret, t, body)
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			return time.Unix(unix, 0), nil
+		}
+	}
+	return time.Time{}, errors.New("signature does not match")
+}
+
+func signature(secret, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
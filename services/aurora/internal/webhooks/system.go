@@ -0,0 +1,340 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/guregu/null"
+
+	"github.com/hcnet/go/services/aurora/internal/db2"
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	herrors "github.com/hcnet/go/services/aurora/internal/errors"
+	"github.com/hcnet/go/services/aurora/internal/resourceadapter"
+	"github.com/hcnet/go/support/errors"
+	"github.com/hcnet/go/support/log"
+	"github.com/hcnet/go/support/render/hal"
+)
+
+// Delivery is the JSON body of the requests sent to webhook subscriptions.
+type Delivery struct {
+	SubscriptionID int64 `json:"subscription_id"`
+	// Cursor is the paging token of the last operation of the delivery.
+	Cursor     string         `json:"cursor"`
+	Operations []hal.Pageable `json:"operations"`
+	// Effects contains the effects of the delivered operations.
+	Effects []hal.Pageable `json:"effects"`
+}
+
+// Run delivers webhooks every time a new ledger is ingested until Shutdown
+// is called.
+func (s *System) Run() {
+	defer s.ledgerSource.Close()
+
+	currentLedger := s.ledgerSource.CurrentLedger()
+	for {
+		s.runOnce(s.ctx)
+
+		select {
+		case currentLedger = <-s.ledgerSource.NextLedger(currentLedger):
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// Shutdown stops the delivery of webhooks.
+func (s *System) Shutdown() {
+	s.cancel()
+}
+
+func (s *System) runOnce(ctx context.Context) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err := herrors.FromPanic(rec)
+			log.Errorf("webhook delivery panicked: %s", err)
+			herrors.ReportToSentry(err, nil)
+		}
+	}()
+
+	if err := s.DeliverAll(ctx); err != nil {
+		log.Errorf("webhook delivery failed: %s", err)
+	}
+}
+
+// DeliverAll sends the operations ingested since the last delivery to every
+// subscription which is not waiting to retry a failed delivery. Up to
+// maxConcurrentDeliveries subscriptions are delivered concurrently.
+func (s *System) DeliverAll(ctx context.Context) error {
+	ids, err := s.historyQ().DueWebhookSubscriptionIDs(ctx, s.now())
+	if err != nil {
+		return errors.Wrap(err, "could not load webhook subscriptions")
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentDeliveries)
+	for _, id := range ids {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+		}
+		wg.Add(1)
+		go func(id int64) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := s.deliver(ctx, id); err != nil {
+				log.WithField("subscription_id", id).WithError(err).Error("could not deliver webhook")
+			}
+		}(id)
+	}
+	wg.Wait()
+	return nil
+}
+
+// claim locks a due subscription in a short transaction and pushes its next
+// attempt to the end of the claim so that no other Aurora instance delivers
+// it in the meantime. It returns false if the subscription was removed, is
+// not due or was claimed by another instance.
+func (s *System) claim(ctx context.Context, q *history.Q, id int64) (history.WebhookSubscription, bool, error) {
+	if err := q.Begin(); err != nil {
+		return history.WebhookSubscription{}, false, errors.Wrap(err, "could not begin transaction")
+	}
+	defer q.Rollback()
+
+	subscription, err := q.LockWebhookSubscription(ctx, id)
+	if q.NoRows(err) {
+		// The subscription was removed or is claimed by another instance.
+		return subscription, false, nil
+	} else if err != nil {
+		return subscription, false, errors.Wrap(err, "could not lock webhook subscription")
+	}
+	now := s.now().UTC()
+	if subscription.NextAttemptAt.Valid && subscription.NextAttemptAt.Time.After(now) {
+		return subscription, false, nil
+	}
+
+	claimed := subscription
+	claimed.NextAttemptAt = null.TimeFrom(now.Add(claimDuration))
+	if err = q.UpdateWebhookDelivery(ctx, subscription.Cursor, claimed); err != nil {
+		return subscription, false, err
+	}
+	if err = q.Commit(); err != nil {
+		return subscription, false, errors.Wrap(err, "could not commit transaction")
+	}
+	return subscription, true, nil
+}
+
+// deliver sends the operations ingested since the last delivery to a
+// subscription. The subscription is claimed first so that webhooks are
+// delivered by a single Aurora instance, no transaction is open while the
+// requests are sent and the outcome is recorded once they are done.
+func (s *System) deliver(ctx context.Context, id int64) error {
+	q := s.historyQ()
+	subscription, ok, err := s.claim(ctx, q, id)
+	if err != nil || !ok {
+		return err
+	}
+	claimedCursor := subscription.Cursor
+
+	// The delivery must end before the claim expires.
+	ctx, cancel := context.WithTimeout(ctx, claimDuration)
+	defer cancel()
+
+	now := s.now()
+	for batch := 0; batch < maxBatchesPerRun && ctx.Err() == nil; batch++ {
+		delivery, scanned, err := s.nextDelivery(ctx, q, subscription)
+		if err != nil {
+			s.release(q, claimedCursor, subscription)
+			return err
+		}
+		if scanned == 0 {
+			break
+		}
+
+		if len(delivery.Operations) > 0 {
+			if err = s.post(ctx, subscription, delivery); err != nil {
+				s.metrics.DeliveriesCounter.WithLabelValues("failure").Inc()
+				subscription.Failures++
+				subscription.NextAttemptAt = null.TimeFrom(now.UTC().Add(retryDelay(subscription.Failures)))
+				subscription.LastError = null.StringFrom(err.Error())
+				log.
+					WithField("subscription_id", subscription.ID).
+					WithField("failures", subscription.Failures).
+					WithError(err).
+					Warn("webhook delivery failed")
+				break
+			}
+			s.metrics.DeliveriesCounter.WithLabelValues("success").Inc()
+			s.metrics.DeliveredOperationsCounter.Add(float64(len(delivery.Operations)))
+		}
+
+		subscription.Cursor, err = strconv.ParseInt(delivery.Cursor, 10, 64)
+		if err != nil {
+			s.release(q, claimedCursor, subscription)
+			return errors.Wrap(err, "invalid cursor")
+		}
+		subscription.Failures = 0
+		subscription.NextAttemptAt = null.Time{}
+		subscription.LastError = null.String{}
+		if scanned < s.batchSize() {
+			break
+		}
+	}
+
+	// The outcome is recorded even if ctx is done, the deliveries were sent.
+	return s.record(q, claimedCursor, subscription)
+}
+
+// release records the deliveries sent before an error so that the
+// subscription can be delivered again after the next ledger.
+func (s *System) release(q *history.Q, claimedCursor int64, subscription history.WebhookSubscription) {
+	if err := s.record(q, claimedCursor, subscription); err != nil {
+		log.WithField("subscription_id", subscription.ID).WithError(err).Error("could not release webhook subscription")
+	}
+}
+
+// record stores the outcome of a delivery. It's discarded if the subscription
+// was removed or its cursor was changed during the delivery.
+func (s *System) record(q *history.Q, claimedCursor int64, subscription history.WebhookSubscription) error {
+	err := q.UpdateWebhookDelivery(context.Background(), claimedCursor, subscription)
+	if q.NoRows(err) {
+		log.
+			WithField("subscription_id", subscription.ID).
+			Info("webhook subscription changed during delivery, discarding its outcome")
+		return nil
+	}
+	return err
+}
+
+// nextDelivery returns the delivery of the operations following the cursor of
+// the subscription matching its filters and the number of operations scanned.
+// The cursor of the delivery is the last operation scanned.
+func (s *System) nextDelivery(
+	ctx context.Context,
+	q *history.Q,
+	subscription history.WebhookSubscription,
+) (Delivery, int, error) {
+	delivery := Delivery{
+		SubscriptionID: subscription.ID,
+		Operations:     []hal.Pageable{},
+		Effects:        []hal.Pageable{},
+	}
+
+	query := q.Operations()
+	if subscription.AccountID.Valid {
+		query.ForAccount(ctx, subscription.AccountID.String)
+		if q.NoRows(errors.Cause(query.Err)) {
+			// The account has not been involved in any operation yet.
+			return delivery, 0, nil
+		}
+	}
+	operations, _, err := query.Page(db2.PageQuery{
+		Cursor: strconv.FormatInt(subscription.Cursor, 10),
+		Order:  db2.OrderAscending,
+		Limit:  uint64(s.batchSize()),
+	}).Fetch(ctx)
+	if err != nil {
+		return delivery, 0, errors.Wrap(err, "could not load operations")
+	}
+	if len(operations) == 0 {
+		return delivery, 0, nil
+	}
+	delivery.Cursor = operations[len(operations)-1].PagingToken()
+
+	filter := newOperationFilter(subscription)
+	var matched []history.Operation
+	for _, operation := range operations {
+		ok, err := filter.match(operation)
+		if err != nil {
+			return delivery, 0, err
+		}
+		if ok {
+			matched = append(matched, operation)
+		}
+	}
+	if len(matched) == 0 {
+		return delivery, len(operations), nil
+	}
+
+	ids := make([]int64, 0, len(matched))
+	for _, operation := range matched {
+		ids = append(ids, operation.ID)
+	}
+	var effects []history.Effect
+	if err = q.Effects().ForOperations(ids).Select(ctx, &effects); err != nil {
+		return delivery, 0, errors.Wrap(err, "could not load effects")
+	}
+
+	ledgerCache := history.LedgerCache{}
+	for _, operation := range matched {
+		ledgerCache.Queue(operation.LedgerSequence())
+	}
+	if err = ledgerCache.Load(ctx, q); err != nil {
+		return delivery, 0, errors.Wrap(err, "failed to load ledger batch")
+	}
+
+	for _, operation := range matched {
+		ledger, found := ledgerCache.Records[operation.LedgerSequence()]
+		if !found {
+			return delivery, 0, errors.Errorf("could not find ledger data for sequence %d", operation.LedgerSequence())
+		}
+		resource, err := resourceadapter.NewOperation(ctx, operation, operation.TransactionHash, nil, ledger)
+		if err != nil {
+			return delivery, 0, err
+		}
+		delivery.Operations = append(delivery.Operations, resource)
+	}
+	for _, effect := range effects {
+		ledger, found := ledgerCache.Records[effect.LedgerSequence()]
+		if !found {
+			return delivery, 0, errors.Errorf("could not find ledger data for sequence %d", effect.LedgerSequence())
+		}
+		resource, err := resourceadapter.NewEffect(ctx, effect, ledger)
+		if err != nil {
+			return delivery, 0, err
+		}
+		delivery.Effects = append(delivery.Effects, resource)
+	}
+
+	return delivery, len(operations), nil
+}
+
+// post sends a delivery to the URL of the subscription, any response status
+// other than 2xx is a failure.
+func (s *System) post(ctx context.Context, subscription history.WebhookSubscription, delivery Delivery) error {
+	body, err := json.Marshal(delivery)
+	if err != nil {
+		return errors.Wrap(err, "could not encode delivery")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "could not create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SubscriptionIDHeader, strconv.FormatInt(subscription.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(subscription.Secret, s.now(), body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Read the body so that the connection can be reused.
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return nil
+}
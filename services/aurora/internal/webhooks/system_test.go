@@ -0,0 +1,213 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/guregu/null"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hcnet/go/services/aurora/internal/db2/history"
+	"github.com/hcnet/go/services/aurora/internal/test"
+	"github.com/hcnet/go/xdr"
+)
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, 10*time.Second, retryDelay(1))
+	assert.Equal(t, 20*time.Second, retryDelay(2))
+	assert.Equal(t, 80*time.Second, retryDelay(4))
+	assert.Equal(t, time.Hour, retryDelay(20))
+	assert.Equal(t, time.Hour, retryDelay(1000))
+}
+
+func TestSignature(t *testing.T) {
+	body := []byte(`{"subscription_id":1}`)
+	now := time.Unix(1600000000, 0)
+	header := Sign("secret", now, body)
+	assert.Regexp(t, "^t=1600000000,v1=[0-9a-f]{64}$", header)
+
+	timestamp, err := VerifySignature("secret", header, body)
+	assert.NoError(t, err)
+	assert.Equal(t, now, timestamp)
+
+	_, err = VerifySignature("other", header, body)
+	assert.EqualError(t, err, "signature does not match")
+	_, err = VerifySignature("secret", header, []byte(`{"subscription_id":2}`))
+	assert.EqualError(t, err, "signature does not match")
+	_, err = VerifySignature("secret", "v1=00", body)
+	assert.EqualError(t, err, "signature header has no valid timestamp")
+}
+
+func TestOperationFilter(t *testing.T) {
+	usd := "USD:GDUKMGUGDZQK6YHYA5Z6AY2G4XDSZPSZ3SW5UN3ARVMO6QSRDWP5YLEX"
+	payment := history.Operation{
+		Type: xdr.OperationTypePayment,
+		DetailsString: null.StringFrom(`{"amount": "10.0000000", "asset_type": "credit_alphanum4", "asset_code": "USD", ` +
+			`"asset_issuer": "GDUKMGUGDZQK6YHYA5Z6AY2G4XDSZPSZ3SW5UN3ARVMO6QSRDWP5YLEX"}`),
+	}
+	pathPayment := history.Operation{
+		Type: xdr.OperationTypePathPaymentStrictSend,
+		DetailsString: null.StringFrom(`{"asset_type": "native", "source_asset_type": "native", ` +
+			`"path": [{"asset_type": "credit_alphanum4", "asset_code": "USD", "asset_issuer": "GDUKMGUGDZQK6YHYA5Z6AY2G4XDSZPSZ3SW5UN3ARVMO6QSRDWP5YLEX"}]}`),
+	}
+	offer := history.Operation{
+		Type: xdr.OperationTypeManageSellOffer,
+		DetailsString: null.StringFrom(`{"buying_asset_type": "native", "selling_asset_type": "credit_alphanum4", ` +
+			`"selling_asset_code": "EUR", "selling_asset_issuer": "GDUKMGUGDZQK6YHYA5Z6AY2G4XDSZPSZ3SW5UN3ARVMO6QSRDWP5YLEX"}`),
+	}
+	deposit := history.Operation{
+		Type: xdr.OperationTypeLiquidityPoolDeposit,
+		DetailsString: null.StringFrom(`{"reserves_max": [{"asset": "native", "amount": "1.0000000"}, ` +
+			`{"asset": "` + usd + `", "amount": "1.0000000"}]}`),
+	}
+
+	for _, testCase := range []struct {
+		name         string
+		subscription history.WebhookSubscription
+		expected     []bool
+	}{
+		{
+			name:         "no filters",
+			subscription: history.WebhookSubscription{},
+			expected:     []bool{true, true, true, true},
+		},
+		{
+			name: "operation types",
+			subscription: history.WebhookSubscription{
+				OperationTypes: pq.Int64Array{int64(xdr.OperationTypePayment), int64(xdr.OperationTypeManageSellOffer)},
+			},
+			expected: []bool{true, false, true, false},
+		},
+		{
+			name:         "credit asset",
+			subscription: history.WebhookSubscription{Asset: null.StringFrom(usd)},
+			expected:     []bool{true, true, false, true},
+		},
+		{
+			name:         "native asset",
+			subscription: history.WebhookSubscription{Asset: null.StringFrom("native")},
+			expected:     []bool{false, true, true, true},
+		},
+		{
+			name: "operation types and asset",
+			subscription: history.WebhookSubscription{
+				Asset:          null.StringFrom(usd),
+				OperationTypes: pq.Int64Array{int64(xdr.OperationTypeManageSellOffer)},
+			},
+			expected: []bool{false, false, false, false},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			filter := newOperationFilter(testCase.subscription)
+			for i, operation := range []history.Operation{payment, pathPayment, offer, deposit} {
+				matched, err := filter.match(operation)
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expected[i], matched, "operation %d", i)
+			}
+		})
+	}
+}
+
+func TestDeliverAll(t *testing.T) {
+	tt := test.Start(t)
+	defer tt.Finish()
+	tt.Scenario("base")
+
+	var deliveries []Delivery
+	status := http.StatusOK
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		_, err = VerifySignature("secret", r.Header.Get(SignatureHeader), body)
+		require.NoError(t, err)
+
+		var delivery Delivery
+		require.NoError(t, json.Unmarshal(body, &delivery))
+		deliveries = append(deliveries, delivery)
+		w.WriteHeader(status)
+	}))
+	defer receiver.Close()
+
+	q := &history.Q{SessionInterface: tt.AuroraSession()}
+	var operationCount int
+	tt.Require.NoError(q.GetRaw(tt.Ctx, &operationCount, `
+		SELECT COUNT(*) FROM history_operations hop
+		JOIN history_transactions ht ON ht.id = hop.transaction_id
+		WHERE ht.successful IS NOT false`))
+	tt.Require.True(operationCount > 2)
+
+	subscription, err := q.InsertWebhookSubscription(tt.Ctx, history.WebhookSubscription{
+		URL:    receiver.URL,
+		Secret: "secret",
+	})
+	tt.Require.NoError(err)
+
+	system := &System{
+		session:   tt.AuroraSession(),
+		BatchSize: 2,
+		client:    receiver.Client(),
+		now:       time.Now,
+	}
+	system.initMetrics()
+
+	// Claimed subscriptions are not delivered by other instances.
+	claimed, ok, err := system.claim(tt.Ctx, &history.Q{SessionInterface: tt.AuroraSession()}, subscription.ID)
+	tt.Require.NoError(err)
+	tt.Require.True(ok)
+	tt.Assert.Equal(subscription.ID, claimed.ID)
+	_, ok, err = system.claim(tt.Ctx, &history.Q{SessionInterface: tt.AuroraSession()}, subscription.ID)
+	tt.Require.NoError(err)
+	tt.Assert.False(ok)
+	tt.Require.NoError(system.DeliverAll(tt.Ctx))
+	tt.Assert.Empty(deliveries)
+	tt.Require.NoError(q.UpdateWebhookDelivery(tt.Ctx, claimed.Cursor, claimed))
+
+	// Failed deliveries are retried after a delay.
+	status = http.StatusInternalServerError
+	tt.Require.NoError(system.DeliverAll(tt.Ctx))
+	tt.Assert.Len(deliveries, 1)
+	subscription, err = q.WebhookSubscriptionByID(tt.Ctx, subscription.ID)
+	tt.Require.NoError(err)
+	tt.Assert.Equal(int64(0), subscription.Cursor)
+	tt.Assert.Equal(1, subscription.Failures)
+	tt.Assert.True(subscription.NextAttemptAt.Valid)
+	tt.Assert.Equal("unexpected response status 500", subscription.LastError.String)
+	tt.Assert.Equal(1.0, testutil.ToFloat64(system.Metrics().DeliveriesCounter.WithLabelValues("failure")))
+
+	tt.Require.NoError(system.DeliverAll(tt.Ctx))
+	tt.Assert.Len(deliveries, 1)
+
+	system.now = func() time.Time { return time.Now().Add(time.Minute) }
+	status = http.StatusOK
+	deliveries = nil
+	tt.Require.NoError(system.DeliverAll(tt.Ctx))
+
+	delivered := 0
+	for _, delivery := range deliveries {
+		tt.Assert.Equal(subscription.ID, delivery.SubscriptionID)
+		tt.Assert.LessOrEqual(len(delivery.Operations), 2)
+		delivered += len(delivery.Operations)
+	}
+	tt.Assert.Equal(operationCount, delivered)
+	tt.Assert.Equal(float64(operationCount), testutil.ToFloat64(system.Metrics().DeliveredOperationsCounter))
+
+	subscription, err = q.WebhookSubscriptionByID(tt.Ctx, subscription.ID)
+	tt.Require.NoError(err)
+	tt.Assert.Equal(deliveries[len(deliveries)-1].Cursor, strconv.FormatInt(subscription.Cursor, 10))
+	tt.Assert.Equal(0, subscription.Failures)
+	tt.Assert.False(subscription.NextAttemptAt.Valid)
+	tt.Assert.False(subscription.LastError.Valid)
+
+	// Nothing is delivered until new operations are ingested.
+	deliveries = nil
+	tt.Require.NoError(system.DeliverAll(tt.Ctx))
+	tt.Assert.Empty(deliveries)
+}